@@ -60,6 +60,7 @@ func init() {
 	metrics.RegisterMetaMetrics(Registry.GoRegistry)
 	metrics.RegisterMsgStreamMetrics(Registry.GoRegistry)
 	metrics.RegisterStorageMetrics(Registry.GoRegistry)
+	metrics.RegisterConfigMetrics(Registry.GoRegistry)
 }
 
 func stopRocksmq() {
@@ -342,6 +343,7 @@ func (mr *MilvusRoles) Run() {
 
 	expr.Init()
 	expr.Register("param", paramtable.Get())
+	healthz.Register(healthz.NewConfigIndicator())
 	http.ServeHTTP()
 	setupPrometheusHTTPServer(Registry)
 
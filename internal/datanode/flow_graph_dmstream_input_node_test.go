@@ -87,6 +87,10 @@ func (mtm *mockTtMsgStream) Produce(*msgstream.MsgPack) error {
 	return nil
 }
 
+func (mtm *mockTtMsgStream) ProduceMark(*msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+	return nil, nil
+}
+
 func (mtm *mockTtMsgStream) Broadcast(*msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
 	return nil, nil
 }
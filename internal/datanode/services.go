@@ -484,6 +484,10 @@ func (node *DataNode) Import(ctx context.Context, req *datapb.ImportTaskRequest)
 	if err != nil {
 		return returnFailFunc("invalid collection info to import", err)
 	}
+	// route the primary keys like the insert and delete paths of the collection, or the imported entities can't be deleted
+	if err = collectionInfo.SetShardHash(common.GetShardHashName(colInfo.GetProperties()...)); err != nil {
+		return returnFailFunc("invalid shard hash to import", err)
+	}
 
 	// parse files and generate segments
 	segmentSize := Params.DataCoordCfg.SegmentMaxSize.GetAsInt64() * 1024 * 1024
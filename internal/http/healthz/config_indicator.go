@@ -0,0 +1,66 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthz
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+	"github.com/milvus-io/milvus/pkg/config"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// ConfigIndicator reports the config sources abnormal once any of them is stale, see config.SourceHealth.Stale
+type ConfigIndicator struct {
+	health    func() map[string]config.SourceHealth
+	staleTime func() time.Duration
+}
+
+var _ Indicator = (*ConfigIndicator)(nil)
+
+// NewConfigIndicator returns the indicator of the config sources of paramtable, which are stale after etcd.configStaleTime
+func NewConfigIndicator() *ConfigIndicator {
+	return &ConfigIndicator{
+		health: func() map[string]config.SourceHealth {
+			return paramtable.GetBaseTable().ConfigHealth()
+		},
+		staleTime: func() time.Duration {
+			return paramtable.Get().EtcdCfg.ConfigStaleTime.GetAsDuration(time.Second)
+		},
+	}
+}
+
+func (c *ConfigIndicator) GetName() string {
+	return "config"
+}
+
+func (c *ConfigIndicator) Health(ctx context.Context) commonpb.StateCode {
+	staleTime := c.staleTime()
+	code := commonpb.StateCode_Healthy
+	for name, health := range c.health() {
+		if health.Stale(staleTime) {
+			log.Warn("config source is stale", zap.String("source", name), zap.Time("lastSuccess", health.LastSuccessTime),
+				zap.Int("keyCount", health.KeyCount), zap.Error(health.LastError))
+			code = commonpb.StateCode_Abnormal
+		}
+	}
+	return code
+}
@@ -0,0 +1,57 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+	"github.com/milvus-io/milvus/pkg/config"
+)
+
+func TestConfigIndicator(t *testing.T) {
+	health := config.SourceHealth{LastSuccessTime: time.Now(), KeyCount: 2, Connected: true}
+	indicator := &ConfigIndicator{
+		health: func() map[string]config.SourceHealth {
+			return map[string]config.SourceHealth{"EtcdSource": health}
+		},
+		staleTime: func() time.Duration { return time.Minute },
+	}
+	assert.Equal(t, "config", indicator.GetName())
+	assert.Equal(t, commonpb.StateCode_Healthy, indicator.Health(context.Background()))
+
+	// failing but not stale yet
+	health.Connected = false
+	health.LastError = errors.New("mock error")
+	assert.Equal(t, commonpb.StateCode_Healthy, indicator.Health(context.Background()))
+
+	health.LastSuccessTime = time.Now().Add(-time.Hour)
+	assert.Equal(t, commonpb.StateCode_Abnormal, indicator.Health(context.Background()))
+
+	// never loaded
+	health.LastSuccessTime = time.Time{}
+	assert.Equal(t, commonpb.StateCode_Abnormal, indicator.Health(context.Background()))
+
+	// recovered
+	health = config.SourceHealth{LastSuccessTime: time.Now(), KeyCount: 2, Connected: true}
+	assert.Equal(t, commonpb.StateCode_Healthy, indicator.Health(context.Background()))
+}
@@ -27,3 +27,15 @@ const EventLogRouterPath = "/eventlog"
 
 // ExprPath is path for expression.
 const ExprPath = "/expr"
+
+// ConfigRouterPath is path for describing the effective configs and their sources.
+const ConfigRouterPath = "/config"
+
+// ConfigHistoryRouterPath is path for listing the recent changes of configs.
+const ConfigHistoryRouterPath = "/config/history"
+
+// ConfigRefreshRouterPath is path for refreshing the configs from the remote sources at once, e.g. etcd.
+const ConfigRefreshRouterPath = "/config/refresh"
+
+// ConfigFreezeRouterPath is path for freezing the configs during maintenance, and for unfreezing them.
+const ConfigFreezeRouterPath = "/config/freeze"
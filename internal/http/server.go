@@ -17,6 +17,7 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -71,6 +72,22 @@ func registerDefaults() {
 		Path:    EventLogRouterPath,
 		Handler: eventlog.Handler(),
 	})
+	Register(&Handler{
+		Path:        ConfigRouterPath,
+		HandlerFunc: describeConfigs,
+	})
+	Register(&Handler{
+		Path:        ConfigHistoryRouterPath,
+		HandlerFunc: listConfigHistory,
+	})
+	Register(&Handler{
+		Path:        ConfigRefreshRouterPath,
+		HandlerFunc: refreshConfigs,
+	})
+	Register(&Handler{
+		Path:        ConfigFreezeRouterPath,
+		HandlerFunc: freezeConfigs,
+	})
 	Register(&Handler{
 		Path: ExprPath,
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -88,6 +105,99 @@ func registerDefaults() {
 	})
 }
 
+// describeConfigs writes the descriptions of the effective configs, or the one of the key in the query if any.
+func describeConfigs(w http.ResponseWriter, req *http.Request) {
+	var descriptions any
+	if key := req.URL.Query().Get("key"); key != "" {
+		description, ok := paramtable.GetBaseTable().DescribeConfig(key)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(fmt.Sprintf(`{"msg": "config %s not found"}`, key)))
+			return
+		}
+		descriptions = description
+	} else {
+		descriptions = paramtable.GetBaseTable().DescribeConfigs()
+	}
+	output, err := json.Marshal(descriptions)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "failed to describe configs, %s"}`, err.Error())))
+		return
+	}
+	w.Header().Set(healthz.ContentTypeHeader, healthz.ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(output)
+}
+
+// listConfigHistory writes the recent changes of configs, since the RFC3339 time and of the key prefix in the query if any.
+func listConfigHistory(w http.ResponseWriter, req *http.Request) {
+	var since time.Time
+	if value := req.URL.Query().Get("since"); value != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"msg": "invalid since time %s, %s"}`, value, err.Error())))
+			return
+		}
+	}
+	output, err := json.Marshal(paramtable.GetBaseTable().ConfigHistory(since, req.URL.Query().Get("prefix")))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "failed to list config history, %s"}`, err.Error())))
+		return
+	}
+	w.Header().Set(healthz.ContentTypeHeader, healthz.ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(output)
+}
+
+// refreshConfigs refreshes the configs from the remote sources of this node at once, rather than waiting for the refresh interval.
+func refreshConfigs(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "method %s not allowed, use POST"}`, req.Method)))
+		return
+	}
+	if err := paramtable.GetBaseTable().ForceRefreshConfigs(req.Context()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "failed to refresh configs, %s"}`, err.Error())))
+		return
+	}
+	w.Header().Set(healthz.ContentTypeHeader, healthz.ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"msg": "OK"}`))
+}
+
+// freezeConfigs freezes the configs of this node by POST, unfreezes them by DELETE, and writes the status of freezing.
+func freezeConfigs(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		paramtable.GetBaseTable().FreezeConfigs()
+	case http.MethodDelete:
+		if err := paramtable.GetBaseTable().UnfreezeConfigs(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fmt.Sprintf(`{"msg": "failed to unfreeze configs, %s"}`, err.Error())))
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "method %s not allowed, use GET, POST or DELETE"}`, req.Method)))
+		return
+	}
+	output, err := json.Marshal(paramtable.GetBaseTable().ConfigFreezeStatus())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "failed to get config freeze status, %s"}`, err.Error())))
+		return
+	}
+	w.Header().Set(healthz.ContentTypeHeader, healthz.ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(output)
+}
+
 func Register(h *Handler) {
 	if metricsServer == nil {
 		if paramtable.Get().HTTPCfg.EnablePprof.GetAsBool() {
@@ -24,6 +24,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -34,6 +35,7 @@ import (
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus/internal/http/healthz"
+	"github.com/milvus-io/milvus/pkg/config"
 	"github.com/milvus-io/milvus/pkg/log"
 	"github.com/milvus-io/milvus/pkg/util/expr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
@@ -218,6 +220,100 @@ func (suite *HTTPServerTestSuite) TestExprHandler() {
 	})
 }
 
+func (suite *HTTPServerTestSuite) TestConfigHandler() {
+	paramtable.Get().Save("describe.key", "10")
+	defer paramtable.Get().Reset("describe.key")
+	client := http.Client{}
+	get := func(query string) (int, []byte) {
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost:"+DefaultListenPort+ConfigRouterPath+query, nil)
+		resp, err := client.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	code, body := get("")
+	suite.Equal(http.StatusOK, code)
+	var descriptions map[string]config.ConfigDescription
+	suite.NoError(json.Unmarshal(body, &descriptions))
+	suite.Equal("10", descriptions["describekey"].Value)
+	suite.Equal(config.OverlaySourceName, descriptions["describekey"].Source)
+
+	code, body = get("?key=describe.key")
+	suite.Equal(http.StatusOK, code)
+	var description config.ConfigDescription
+	suite.NoError(json.Unmarshal(body, &description))
+	suite.Equal(descriptions["describekey"], description)
+
+	code, _ = get("?key=describe.none")
+	suite.Equal(http.StatusNotFound, code)
+}
+
+func (suite *HTTPServerTestSuite) TestConfigHistoryHandler() {
+	client := http.Client{}
+	get := func(query string) (int, []byte) {
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost:"+DefaultListenPort+ConfigHistoryRouterPath+query, nil)
+		resp, err := client.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	code, body := get("?prefix=proxy&since=" + url.QueryEscape(time.Now().Format(time.RFC3339)))
+	suite.Equal(http.StatusOK, code)
+	var records []config.ChangeRecord
+	suite.NoError(json.Unmarshal(body, &records))
+	suite.Empty(records)
+
+	code, _ = get("?since=yesterday")
+	suite.Equal(http.StatusBadRequest, code)
+}
+
+func (suite *HTTPServerTestSuite) TestConfigRefreshHandler() {
+	client := http.Client{}
+	do := func(method string) int {
+		req, _ := http.NewRequest(method, "http://localhost:"+DefaultListenPort+ConfigRefreshRouterPath, nil)
+		resp, err := client.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	suite.Equal(http.StatusMethodNotAllowed, do(http.MethodGet))
+	// fails only if etcd is not available
+	suite.Contains([]int{http.StatusOK, http.StatusInternalServerError}, do(http.MethodPost))
+}
+
+func (suite *HTTPServerTestSuite) TestConfigFreezeHandler() {
+	client := http.Client{}
+	do := func(method string) (int, config.FreezeStatus) {
+		req, _ := http.NewRequest(method, "http://localhost:"+DefaultListenPort+ConfigFreezeRouterPath, nil)
+		resp, err := client.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		var status config.FreezeStatus
+		if resp.StatusCode == http.StatusOK {
+			suite.NoError(json.Unmarshal(body, &status))
+		}
+		return resp.StatusCode, status
+	}
+
+	code, status := do(http.MethodPost)
+	suite.Equal(http.StatusOK, code)
+	suite.True(status.Frozen)
+	code, status = do(http.MethodGet)
+	suite.Equal(http.StatusOK, code)
+	suite.True(status.Frozen)
+	code, status = do(http.MethodDelete)
+	suite.Equal(http.StatusOK, code)
+	suite.False(status.Frozen)
+	code, _ = do(http.MethodPut)
+	suite.Equal(http.StatusMethodNotAllowed, code)
+}
+
 func TestHTTPServerSuite(t *testing.T) {
 	suite.Run(t, new(HTTPServerTestSuite))
 }
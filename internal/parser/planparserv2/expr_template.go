@@ -0,0 +1,148 @@
+package planparserv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BindExprTemplate replaces the placeholders like {name} of template with the literals of values.
+// Strings are always rendered as quoted literals, so they are never interpreted as expression syntax.
+// Every placeholder must have a value and every value must be referenced by a placeholder.
+func BindExprTemplate(template string, values map[string]interface{}) (string, error) {
+	var b strings.Builder
+	b.Grow(len(template))
+	used := make(map[string]struct{}, len(values))
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch c {
+		case '"', '\'':
+			// copy string literal as is, placeholders inside it are not bound
+			end, err := skipStringLiteral(template, i)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(template[i:end])
+			i = end - 1
+		case '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed placeholder at position %d of expression template", i)
+			}
+			name := template[i+1 : i+end]
+			if !isTemplateIdentifier(name) {
+				return "", fmt.Errorf("invalid placeholder {%s} in expression template", name)
+			}
+			value, ok := values[name]
+			if !ok {
+				return "", fmt.Errorf("no value provided for placeholder {%s} in expression template", name)
+			}
+			literal, err := templateValueLiteral(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid value of placeholder {%s}: %w", name, err)
+			}
+			used[name] = struct{}{}
+			b.WriteString(literal)
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if len(used) != len(values) {
+		unused := make([]string, 0, len(values)-len(used))
+		for name := range values {
+			if _, ok := used[name]; !ok {
+				unused = append(unused, name)
+			}
+		}
+		sort.Strings(unused)
+		return "", fmt.Errorf("values %v are not referenced by any placeholder in expression template", unused)
+	}
+	return b.String(), nil
+}
+
+// skipStringLiteral returns the end position of the string literal starting at start.
+func skipStringLiteral(expr string, start int) (int, error) {
+	quote := expr[start]
+	for i := start + 1; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unclosed string literal at position %d of expression template", start)
+}
+
+func isTemplateIdentifier(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// templateValueLiteral renders value as a literal of expression,
+// value is a bool, number, string or list of them, as decoded from json with numbers kept.
+func templateValueLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		return strconv.Quote(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return strconv.FormatInt(i, 10), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return "", fmt.Errorf("invalid number %s", v.String())
+		}
+		return formatTemplateFloat(f)
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float32:
+		return formatTemplateFloat(float64(v))
+	case float64:
+		return formatTemplateFloat(v)
+	case []interface{}:
+		elements := make([]string, 0, len(v))
+		for _, element := range v {
+			literal, err := templateValueLiteral(element)
+			if err != nil {
+				return "", err
+			}
+			elements = append(elements, literal)
+		}
+		return "[" + strings.Join(elements, ", ") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// formatTemplateFloat keeps the literal a float even if it's integral, so it's not taken as an integer.
+func formatTemplateFloat(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("invalid number %v", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s, nil
+}
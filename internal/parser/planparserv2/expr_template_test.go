@@ -0,0 +1,82 @@
+package planparserv2
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeTemplateValues(t *testing.T, data string) map[string]interface{} {
+	values := make(map[string]interface{})
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&values))
+	return values
+}
+
+func TestBindExprTemplate(t *testing.T) {
+	t.Run("bind values", func(t *testing.T) {
+		values := decodeTemplateValues(t, `{"pks": [1, -2, 3], "name": "a\"b'c\\", "score": 2.0, "ok": true}`)
+		expr, err := BindExprTemplate(`Int64Field in {pks} && VarCharField == {name} && FloatField > {score} || BoolField == {ok}`, values)
+		assert.NoError(t, err)
+		assert.Equal(t, `Int64Field in [1, -2, 3] && VarCharField == "a\"b'c\\" && FloatField > 2.0 || BoolField == true`, expr)
+	})
+
+	t.Run("placeholder in string literal", func(t *testing.T) {
+		expr, err := BindExprTemplate(`VarCharField in ["{v}", '\'{v}'] or Int64Field == {v}`, map[string]interface{}{"v": int64(1)})
+		assert.NoError(t, err)
+		assert.Equal(t, `VarCharField in ["{v}", '\'{v}'] or Int64Field == 1`, expr)
+	})
+
+	t.Run("missing value", func(t *testing.T) {
+		_, err := BindExprTemplate(`Int64Field in {pks} && FloatField > {score}`, map[string]interface{}{"pks": []interface{}{int64(1)}})
+		assert.ErrorContains(t, err, "no value provided for placeholder {score}")
+	})
+
+	t.Run("extra value", func(t *testing.T) {
+		_, err := BindExprTemplate(`Int64Field in {pks}`, map[string]interface{}{"pks": []interface{}{int64(1)}, "b": int64(1), "a": int64(1)})
+		assert.ErrorContains(t, err, "values [a b] are not referenced")
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		for _, template := range []string{`Int64Field in {pks`, `Int64Field in {}`, `Int64Field in {1pk}`, `Int64Field in {a b}`, `VarCharField == "{v}`} {
+			_, err := BindExprTemplate(template, map[string]interface{}{"pks": int64(1)})
+			assert.Error(t, err, template)
+		}
+	})
+
+	t.Run("unsupported value", func(t *testing.T) {
+		for _, value := range []interface{}{nil, map[string]interface{}{"a": int64(1)}, []interface{}{[]string{"a"}}} {
+			_, err := BindExprTemplate(`Int64Field == {v}`, map[string]interface{}{"v": value})
+			assert.ErrorContains(t, err, "invalid value of placeholder {v}")
+		}
+	})
+}
+
+func TestBindExprTemplate_Plan(t *testing.T) {
+	schema := newTestSchema()
+
+	// string values are literals, never part of the expression syntax
+	expr, err := BindExprTemplate(`VarCharField == {name}`, map[string]interface{}{"name": `a" || Int64Field > "0`})
+	assert.NoError(t, err)
+	plan, err := CreateRetrievePlan(schema, expr)
+	assert.NoError(t, err)
+	unaryRange := plan.GetQuery().GetPredicates().GetUnaryRangeExpr()
+	assert.NotNil(t, unaryRange)
+	assert.Equal(t, `a" || Int64Field > "0`, unaryRange.GetValue().GetStringVal())
+
+	expr, err = BindExprTemplate(`Int64Field in {pks}`, decodeTemplateValues(t, `{"pks": [1, 2, 3]}`))
+	assert.NoError(t, err)
+	plan, err = CreateRetrievePlan(schema, expr)
+	assert.NoError(t, err)
+	assert.Len(t, plan.GetQuery().GetPredicates().GetTermExpr().GetValues(), 3)
+
+	// type mismatch is rejected by the parser
+	expr, err = BindExprTemplate(`Int64Field in {pks}`, decodeTemplateValues(t, `{"pks": ["1", "2"]}`))
+	assert.NoError(t, err)
+	_, err = CreateRetrievePlan(schema, expr)
+	assert.Error(t, err)
+}
@@ -48,6 +48,9 @@ var metricFuncMap = map[string]getMetricFunc{
 	"$time_start":      getTimeStart,
 	"$time_end":        getTimeEnd,
 	"$method_expr":     getExpr,
+	"$expr_hash":       getExprHash,
+	"$delete_path":     getDeletePath,
+	"$delete_cnt":      getDeleteCnt,
 	"$sdk_version":     getSdkVersion,
 	"$cluster_prefix":  getClusterPrefix,
 }
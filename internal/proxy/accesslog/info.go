@@ -18,6 +18,8 @@ package accesslog
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"path"
 	"time"
@@ -50,6 +52,18 @@ type GrpcAccessInfo struct {
 	grpcInfo *grpc.UnaryServerInfo
 	start    time.Time
 	end      time.Time
+
+	// detail of the delete, set by the handler of Delete
+	deleteInfo *DeleteInfo
+}
+
+// DeleteInfo is the detail of a delete known only after it's executed, written with the access log of it.
+type DeleteInfo struct {
+	// the partitions the rows are deleted from, the partition of the request is written if it's empty
+	PartitionNames []string
+	// simple if the primary keys are known without query, or complex, empty if the delete isn't executed
+	Path      string
+	DeleteCnt int64
 }
 
 func NewGrpcAccessInfo(ctx context.Context, grpcInfo *grpc.UnaryServerInfo, req interface{}) *GrpcAccessInfo {
@@ -87,6 +101,16 @@ func (i *GrpcAccessInfo) SetResult(resp interface{}, err error) {
 	}
 }
 
+// SetDeleteInfo sets the detail of the delete to the access info carried by ctx, which is written once
+// the rpc returns, so it's written once per rpc however many times it's set.
+func SetDeleteInfo(ctx context.Context, info *DeleteInfo) {
+	accessInfo, ok := ctx.Value(AccessKey{}).(*GrpcAccessInfo)
+	if !ok {
+		return
+	}
+	accessInfo.deleteInfo = info
+}
+
 func (i *GrpcAccessInfo) Get(keys ...string) []string {
 	result := []string{}
 	for _, key := range keys {
@@ -240,6 +264,10 @@ func getCollectionName(i *GrpcAccessInfo) string {
 }
 
 func getPartitionName(i *GrpcAccessInfo) string {
+	if i.deleteInfo != nil && len(i.deleteInfo.PartitionNames) > 0 {
+		return fmt.Sprint(i.deleteInfo.PartitionNames)
+	}
+
 	name, ok := requestutil.GetPartitionNameFromRequest(i.req)
 	if ok {
 		return name.(string)
@@ -266,6 +294,29 @@ func getExpr(i *GrpcAccessInfo) string {
 	return unknownString
 }
 
+func getExprHash(i *GrpcAccessInfo) string {
+	expr, ok := requestutil.GetExprFromRequest(i.req)
+	if !ok || len(expr.(string)) == 0 {
+		return unknownString
+	}
+	hash := sha256.Sum256([]byte(expr.(string)))
+	return hex.EncodeToString(hash[:])
+}
+
+func getDeletePath(i *GrpcAccessInfo) string {
+	if i.deleteInfo == nil || len(i.deleteInfo.Path) == 0 {
+		return unknownString
+	}
+	return i.deleteInfo.Path
+}
+
+func getDeleteCnt(i *GrpcAccessInfo) string {
+	if i.deleteInfo == nil {
+		return unknownString
+	}
+	return fmt.Sprint(i.deleteInfo.DeleteCnt)
+}
+
 func getSdkVersion(i *GrpcAccessInfo) string {
 	clientInfo := connection.GetManager().Get(i.ctx)
 	if clientInfo == nil {
@@ -149,6 +149,29 @@ func (s *GrpcAccessInfoSuite) TestExpression() {
 	s.Equal(testExpr, result[0])
 }
 
+func (s *GrpcAccessInfoSuite) TestDeleteInfo() {
+	s.info.req = &milvuspb.DeleteRequest{
+		PartitionName: "test-partition",
+	}
+	result := s.info.Get("$partition_name", "$expr_hash", "$delete_path", "$delete_cnt")
+	s.Equal([]string{"test-partition", unknownString, unknownString, unknownString}, result)
+
+	// noop without the access info
+	SetDeleteInfo(s.info.ctx, &DeleteInfo{Path: "simple"})
+	s.Nil(s.info.deleteInfo)
+
+	ctx := context.WithValue(s.info.ctx, AccessKey{}, s.info)
+	SetDeleteInfo(ctx, &DeleteInfo{PartitionNames: []string{"p0"}, Path: "simple", DeleteCnt: 10})
+	s.info.req = &milvuspb.DeleteRequest{
+		PartitionName: "test-partition",
+		Expr:          "pk in [1]",
+	}
+	result = s.info.Get("$partition_name", "$expr_hash", "$delete_path", "$delete_cnt")
+	s.Equal("[p0]", result[0])
+	s.Len(result[1], 64)
+	s.Equal([]string{"simple", "10"}, result[2:])
+}
+
 func (s *GrpcAccessInfoSuite) TestClusterPrefix() {
 	cluster := "instance-test"
 	paramtable.Init()
@@ -17,12 +17,94 @@
 package accesslog
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/pkg/util"
+	"github.com/milvus-io/milvus/pkg/util/crypto"
+	"github.com/milvus-io/milvus/pkg/util/merr"
 )
 
 func TestJoin(t *testing.T) {
 	assert.Equal(t, "a/b", join("a", "b"))
 	assert.Equal(t, "a/b", join("a/", "b"))
 }
+
+func TestUnaryAccessLogInterceptor_Delete(t *testing.T) {
+	w, f := _globalW, _globalF
+	defer func() { _globalW, _globalF = w, f }()
+	buf := &bytes.Buffer{}
+	_globalW = buf
+	_globalF = NewFormatterManger()
+	_globalF.Add("delete", "<$user_name> $method_name [code: $error_code] [timeCost: $time_cost] [database: $database_name] "+
+		"[collection: $collection_name] [partitions: $partition_name] [exprHash: $expr_hash] [path: $delete_path] [deleteCnt: $delete_cnt]")
+	_globalF.SetMethod("delete", "Delete")
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(util.HeaderAuthorize, crypto.Base64Encode("mockUser:mockPass")))
+	serverInfo := &grpc.UnaryServerInfo{FullMethod: "/milvus.proto.milvus.MilvusService/Delete"}
+	req := &milvuspb.DeleteRequest{
+		DbName:         "test-db",
+		CollectionName: "test-collection",
+		PartitionName:  "test-partition",
+		Expr:           "pk > 0",
+	}
+	hash := sha256.Sum256([]byte(req.GetExpr()))
+	exprHash := hex.EncodeToString(hash[:])
+
+	intercept := func(handler grpc.UnaryHandler) string {
+		buf.Reset()
+		_, err := UnaryAccessLogInterceptor(ctx, req, serverInfo, handler)
+		assert.NoError(t, err)
+		// written once per rpc
+		assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+		return buf.String()
+	}
+
+	t.Run("success", func(t *testing.T) {
+		record := intercept(func(ctx context.Context, req any) (any, error) {
+			SetDeleteInfo(ctx, &DeleteInfo{Path: "complex", DeleteCnt: 1})
+			// set again as the delete goes on, only the last one is written
+			SetDeleteInfo(ctx, &DeleteInfo{PartitionNames: []string{"p0", "p1"}, Path: "complex", DeleteCnt: 3})
+			return &milvuspb.MutationResult{Status: merr.Success(), DeleteCnt: 3}, nil
+		})
+		assert.True(t, strings.HasPrefix(record, "<mockUser> Delete [code: 0]"))
+		assert.NotContains(t, record, "[timeCost: "+unknownString+"]")
+		assert.Contains(t, record, "[database: test-db] [collection: test-collection] [partitions: [p0 p1]]")
+		assert.Contains(t, record, fmt.Sprintf("[exprHash: %s] [path: complex] [deleteCnt: 3]", exprHash))
+		assert.NotContains(t, record, req.GetExpr())
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		record := intercept(func(ctx context.Context, req any) (any, error) {
+			SetDeleteInfo(ctx, &DeleteInfo{Path: "complex", DeleteCnt: 2})
+			err := merr.WrapErrDeletePartial(2, merr.WrapErrServiceInternal("mock"))
+			return &milvuspb.MutationResult{Status: merr.Status(err), DeleteCnt: 2}, nil
+		})
+		assert.Contains(t, record, fmt.Sprintf("[code: %d]", merr.Code(merr.ErrDeletePartial)))
+		// the partition of the request if the partitions deleted from are unknown
+		assert.Contains(t, record, "[partitions: test-partition]")
+		assert.Contains(t, record, fmt.Sprintf("[exprHash: %s] [path: complex] [deleteCnt: 2]", exprHash))
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		record := intercept(func(ctx context.Context, req any) (any, error) {
+			SetDeleteInfo(ctx, &DeleteInfo{})
+			err := merr.WrapErrParameterInvalidMsg("invalid expr")
+			return &milvuspb.MutationResult{Status: merr.Status(err)}, nil
+		})
+		assert.Contains(t, record, fmt.Sprintf("[code: %d]", merr.Code(merr.ErrParameterInvalid)))
+		assert.Contains(t, record, "[database: test-db] [collection: test-collection] [partitions: test-partition]")
+		assert.Contains(t, record, fmt.Sprintf("[exprHash: %s] [path: %s] [deleteCnt: 0]", exprHash, unknownString))
+	})
+}
@@ -0,0 +1,221 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/allocator"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// the breakers of the allocations of delete, shared by all the requests.
+var (
+	idAllocatorBreaker  = newAllocatorBreaker("id")
+	tsoAllocatorBreaker = newAllocatorBreaker("tso")
+)
+
+// allocatorBreaker is a circuit breaker of the allocations from rootcoord.
+// It opens after proxy.allocatorBreaker.failureThreshold consecutive failures, the allocations fail fast
+// without issuing rpcs for proxy.allocatorBreaker.coolDown then, after which it's half-open to let a probe through,
+// the breaker closes if the probe succeeds, or opens again otherwise.
+type allocatorBreaker struct {
+	name string
+
+	mu       sync.Mutex
+	state    string
+	failures int64
+	openedAt time.Time
+	// whether a probe is in flight in the half-open state
+	probing bool
+}
+
+func newAllocatorBreaker(name string) *allocatorBreaker {
+	return &allocatorBreaker{
+		name:  name,
+		state: metrics.BreakerClosedLabel,
+	}
+}
+
+// call calls fn unless the breaker is open, the errors of fn canceled by ctx are not counted as failures.
+func (b *allocatorBreaker) call(ctx context.Context, fn func() error) error {
+	params := &paramtable.Get().ProxyCfg
+	threshold := getDeleteParamInt64(&params.AllocatorBreakerThreshold)
+	if threshold <= 0 {
+		return fn()
+	}
+
+	probe, err := b.allow(getDeleteParamDuration(&params.AllocatorBreakerCoolDown, time.Millisecond))
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if err != nil && ctx.Err() != nil {
+		if probe {
+			b.mu.Lock()
+			b.probing = false
+			b.mu.Unlock()
+		}
+		return err
+	}
+	b.done(err, threshold)
+	return err
+}
+
+// allow returns whether the call is a probe of the half-open breaker, or the error to fail fast with.
+func (b *allocatorBreaker) allow(coolDown time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case metrics.BreakerOpenLabel:
+		if time.Since(b.openedAt) < coolDown {
+			return false, b.unavailableErr()
+		}
+		b.transitLocked(metrics.BreakerHalfOpenLabel)
+		fallthrough
+	case metrics.BreakerHalfOpenLabel:
+		if b.probing {
+			return false, b.unavailableErr()
+		}
+		b.probing = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// done records the result of a call let through.
+func (b *allocatorBreaker) done(err error, threshold int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		b.probing = false
+		if b.state != metrics.BreakerClosedLabel {
+			b.transitLocked(metrics.BreakerClosedLabel)
+		}
+		return
+	}
+
+	b.failures++
+	switch b.state {
+	case metrics.BreakerHalfOpenLabel:
+		b.probing = false
+		b.openLocked(err)
+	case metrics.BreakerClosedLabel:
+		if b.failures >= threshold {
+			b.openLocked(err)
+		}
+	}
+}
+
+func (b *allocatorBreaker) openLocked(err error) {
+	b.openedAt = time.Now()
+	b.transitLocked(metrics.BreakerOpenLabel)
+	log.Warn("allocations from rootcoord fail fast as the breaker opens",
+		zap.String("allocator", b.name),
+		zap.Int64("failures", b.failures),
+		zap.Error(err))
+}
+
+func (b *allocatorBreaker) transitLocked(state string) {
+	log.Info("allocator breaker transits",
+		zap.String("allocator", b.name),
+		zap.String("from", b.state),
+		zap.String("to", state))
+	b.state = state
+	metrics.ProxyAllocatorBreakerTransitionCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), b.name, state).Inc()
+}
+
+func (b *allocatorBreaker) unavailableErr() error {
+	return merr.WrapErrServiceUnavailable("allocator unavailable", b.name+" allocations fail fast as the allocator breaker is open")
+}
+
+func (b *allocatorBreaker) getState() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// make sure breakerIDAllocator implements allocator.Interface.
+var _ allocator.Interface = (*breakerIDAllocator)(nil)
+
+// breakerIDAllocator allocates the ids from the inner allocator through the breaker.
+type breakerIDAllocator struct {
+	inner   allocator.Interface
+	breaker *allocatorBreaker
+}
+
+func newBreakerIDAllocator(inner allocator.Interface, breaker *allocatorBreaker) *breakerIDAllocator {
+	return &breakerIDAllocator{inner: inner, breaker: breaker}
+}
+
+// AllocOne allocates one id.
+func (a *breakerIDAllocator) AllocOne() (UniqueID, error) {
+	var id UniqueID
+	err := a.breaker.call(context.Background(), func() error {
+		var err error
+		id, err = a.inner.AllocOne()
+		return err
+	})
+	return id, err
+}
+
+// Alloc allocates the ids [start, end) of the count number.
+func (a *breakerIDAllocator) Alloc(count uint32) (UniqueID, UniqueID, error) {
+	var start, end UniqueID
+	err := a.breaker.call(context.Background(), func() error {
+		var err error
+		start, end, err = a.inner.Alloc(count)
+		return err
+	})
+	return start, end, err
+}
+
+// make sure breakerTsoAllocator implements tsoAllocator.
+var _ tsoAllocator = (*breakerTsoAllocator)(nil)
+
+// breakerTsoAllocator allocates the timestamps from the inner allocator through the breaker.
+type breakerTsoAllocator struct {
+	inner   tsoAllocator
+	breaker *allocatorBreaker
+}
+
+func newBreakerTsoAllocator(inner tsoAllocator, breaker *allocatorBreaker) *breakerTsoAllocator {
+	return &breakerTsoAllocator{inner: inner, breaker: breaker}
+}
+
+// AllocOne allocates one timestamp.
+func (a *breakerTsoAllocator) AllocOne(ctx context.Context) (Timestamp, error) {
+	var ts Timestamp
+	err := a.breaker.call(ctx, func() error {
+		var err error
+		ts, err = a.inner.AllocOne(ctx)
+		return err
+	})
+	return ts, err
+}
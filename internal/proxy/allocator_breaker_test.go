@@ -0,0 +1,182 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// mockTsoAllocatorFunc allocates the timestamps by the func.
+type mockTsoAllocatorFunc func(ctx context.Context) (Timestamp, error)
+
+func (f mockTsoAllocatorFunc) AllocOne(ctx context.Context) (Timestamp, error) {
+	return f(ctx)
+}
+
+func TestAllocatorBreaker(t *testing.T) {
+	paramtable.Init()
+	params := paramtable.Get()
+	params.Save(params.ProxyCfg.AllocatorBreakerThreshold.Key, "3")
+	defer params.Reset(params.ProxyCfg.AllocatorBreakerThreshold.Key)
+	params.Save(params.ProxyCfg.AllocatorBreakerCoolDown.Key, "50")
+	defer params.Reset(params.ProxyCfg.AllocatorBreakerCoolDown.Key)
+
+	transitions := func(name string, state string) float64 {
+		return testutil.ToFloat64(metrics.ProxyAllocatorBreakerTransitionCount.WithLabelValues(
+			strconv.FormatInt(paramtable.GetNodeID(), 10), name, state))
+	}
+
+	t.Run("closed, open and half-open", func(t *testing.T) {
+		breaker := newAllocatorBreaker("test")
+		opened := transitions("test", metrics.BreakerOpenLabel)
+		halfOpened := transitions("test", metrics.BreakerHalfOpenLabel)
+		closed := transitions("test", metrics.BreakerClosedLabel)
+		inner := &mockCountingIDAllocator{next: 1, err: errors.New("mock rpc error")}
+		a := newBreakerIDAllocator(inner, breaker)
+
+		// failures below the threshold are returned as they are
+		for i := 0; i < 2; i++ {
+			_, err := a.AllocOne()
+			assert.EqualError(t, err, "mock rpc error")
+		}
+		assert.Equal(t, metrics.BreakerClosedLabel, breaker.getState())
+
+		// opens at the threshold
+		_, err := a.AllocOne()
+		assert.EqualError(t, err, "mock rpc error")
+		assert.Equal(t, metrics.BreakerOpenLabel, breaker.getState())
+		assert.Equal(t, opened+1, transitions("test", metrics.BreakerOpenLabel))
+
+		// fails fast without rpcs while open
+		for i := 0; i < 10; i++ {
+			_, _, err = a.Alloc(10)
+			assert.ErrorIs(t, err, merr.ErrServiceUnavailable)
+			assert.Contains(t, err.Error(), "allocator unavailable")
+		}
+		assert.Equal(t, 3, inner.getCalls())
+
+		// the probe after the cool-down fails, opens again
+		time.Sleep(60 * time.Millisecond)
+		_, err = a.AllocOne()
+		assert.EqualError(t, err, "mock rpc error")
+		assert.Equal(t, 4, inner.getCalls())
+		assert.Equal(t, metrics.BreakerOpenLabel, breaker.getState())
+		assert.Equal(t, halfOpened+1, transitions("test", metrics.BreakerHalfOpenLabel))
+		assert.Equal(t, opened+2, transitions("test", metrics.BreakerOpenLabel))
+		_, err = a.AllocOne()
+		assert.ErrorIs(t, err, merr.ErrServiceUnavailable)
+
+		// the probe succeeds, closes
+		time.Sleep(60 * time.Millisecond)
+		inner.mu.Lock()
+		inner.err = nil
+		inner.mu.Unlock()
+		id, err := a.AllocOne()
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, id)
+		assert.Equal(t, metrics.BreakerClosedLabel, breaker.getState())
+		assert.Equal(t, closed+1, transitions("test", metrics.BreakerClosedLabel))
+
+		// the failures are counted from scratch
+		inner.mu.Lock()
+		inner.err = errors.New("mock rpc error")
+		inner.mu.Unlock()
+		for i := 0; i < 2; i++ {
+			_, err = a.AllocOne()
+			assert.EqualError(t, err, "mock rpc error")
+		}
+		assert.Equal(t, metrics.BreakerClosedLabel, breaker.getState())
+	})
+
+	t.Run("one probe at a time", func(t *testing.T) {
+		breaker := newAllocatorBreaker("test_probe")
+		probing := make(chan struct{})
+		unblock := make(chan struct{})
+		calls := 0
+		a := newBreakerTsoAllocator(mockTsoAllocatorFunc(func(ctx context.Context) (Timestamp, error) {
+			calls++
+			if calls > 3 {
+				close(probing)
+				<-unblock
+				return 100, nil
+			}
+			return 0, errors.New("mock rpc error")
+		}), breaker)
+		for i := 0; i < 3; i++ {
+			_, err := a.AllocOne(context.Background())
+			assert.Error(t, err)
+		}
+		assert.Equal(t, metrics.BreakerOpenLabel, breaker.getState())
+
+		time.Sleep(60 * time.Millisecond)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			ts, err := a.AllocOne(context.Background())
+			assert.NoError(t, err)
+			assert.EqualValues(t, 100, ts)
+		}()
+		<-probing
+		assert.Equal(t, metrics.BreakerHalfOpenLabel, breaker.getState())
+		_, err := a.AllocOne(context.Background())
+		assert.ErrorIs(t, err, merr.ErrServiceUnavailable)
+
+		close(unblock)
+		<-done
+		assert.Equal(t, metrics.BreakerClosedLabel, breaker.getState())
+	})
+
+	t.Run("canceled not counted", func(t *testing.T) {
+		breaker := newAllocatorBreaker("test_canceled")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		a := newBreakerTsoAllocator(mockTsoAllocatorFunc(func(ctx context.Context) (Timestamp, error) {
+			return 0, ctx.Err()
+		}), breaker)
+		for i := 0; i < 10; i++ {
+			_, err := a.AllocOne(ctx)
+			assert.ErrorIs(t, err, context.Canceled)
+		}
+		assert.Equal(t, metrics.BreakerClosedLabel, breaker.getState())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		params.Save(params.ProxyCfg.AllocatorBreakerThreshold.Key, "0")
+		defer params.Save(params.ProxyCfg.AllocatorBreakerThreshold.Key, "3")
+
+		breaker := newAllocatorBreaker("test_disabled")
+		inner := &mockCountingIDAllocator{err: errors.New("mock rpc error")}
+		a := newBreakerIDAllocator(inner, breaker)
+		for i := 0; i < 10; i++ {
+			_, err := a.AllocOne()
+			assert.EqualError(t, err, "mock rpc error")
+		}
+		assert.Equal(t, 10, inner.getCalls())
+		assert.Equal(t, metrics.BreakerClosedLabel, breaker.getState())
+	})
+}
@@ -0,0 +1,133 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+)
+
+type dmlChannelState struct {
+	lastSuccess time.Time
+	lastFailure time.Time
+	// the error of the last produce, nil if it succeeded
+	lastErr error
+}
+
+// failingDmlChannel is a pchan the last produce to which failed.
+type failingDmlChannel struct {
+	channel     pChan
+	err         error
+	lastFailure time.Time
+	// zero if no produce ever succeeded
+	lastSuccess time.Time
+}
+
+// dmlChannelHealth tracks the last produce error and the last successful produce of each pchan,
+// so that the broken producers are surfaced by the health check rather than only failing the dml requests.
+type dmlChannelHealth struct {
+	mu       sync.RWMutex
+	channels map[pChan]*dmlChannelState
+}
+
+func newDmlChannelHealth() *dmlChannelHealth {
+	return &dmlChannelHealth{
+		channels: make(map[pChan]*dmlChannelState),
+	}
+}
+
+// report records the result of producing msgPack to the pchans of a stream, ids are the msgs produced to each pchan.
+// The pchans got fewer msgs than they're hashed failed if err is not nil, the others succeeded.
+func (h *dmlChannelHealth) report(pchans []pChan, msgPack *msgstream.MsgPack, ids map[string][]msgstream.MessageID, err error) {
+	if h == nil || len(pchans) == 0 || msgPack == nil || len(msgPack.Msgs) == 0 {
+		return
+	}
+	// denied by the backup instance, the producers are not to blame
+	if errors.Is(err, merr.ErrDenyProduceMsg) {
+		return
+	}
+
+	expected := make(map[pChan]int)
+	for _, msg := range msgPack.Msgs {
+		keys := msg.HashKeys()
+		if len(keys) == 0 {
+			continue
+		}
+		expected[pchans[keys[0]%uint32(len(pchans))]]++
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for pchan, count := range expected {
+		state, ok := h.channels[pchan]
+		if !ok {
+			state = &dmlChannelState{}
+			h.channels[pchan] = state
+		}
+		if err != nil && len(ids[pchan]) < count {
+			if state.lastErr == nil {
+				log.Warn("produce to physical channel failed", zap.String("channel", pchan), zap.Error(err))
+			}
+			state.lastFailure = now
+			state.lastErr = err
+			continue
+		}
+		if state.lastErr != nil {
+			log.Info("produce to physical channel recovered", zap.String("channel", pchan),
+				zap.Time("lastFailure", state.lastFailure))
+		}
+		state.lastSuccess = now
+		state.lastErr = nil
+	}
+}
+
+// failingChannels returns the pchans failed within window with no produce succeeded since then, sorted by the name,
+// a non-positive window means no failure expires.
+func (h *dmlChannelHealth) failingChannels(window time.Duration) []failingDmlChannel {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	now := time.Now()
+	ret := make([]failingDmlChannel, 0)
+	for pchan, state := range h.channels {
+		if state.lastErr == nil || (window > 0 && now.Sub(state.lastFailure) > window) {
+			continue
+		}
+		ret = append(ret, failingDmlChannel{
+			channel:     pchan,
+			err:         state.lastErr,
+			lastFailure: state.lastFailure,
+			lastSuccess: state.lastSuccess,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].channel < ret[j].channel
+	})
+	return ret
+}
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
@@ -42,6 +44,8 @@ type channelsMgr interface {
 	getOrCreateDmlStream(collectionID UniqueID) (msgstream.MsgStream, error)
 	removeDMLStream(collectionID UniqueID)
 	removeAllDMLStream()
+	removeIdleDMLStream(ttl time.Duration)
+	getFailingDmlChannels() []failingDmlChannel
 }
 
 type channelInfos struct {
@@ -55,6 +59,96 @@ type streamInfos struct {
 	stream       msgstream.MsgStream
 }
 
+// dmlStream wraps the dml stream of a collection to track when it's produced last,
+// it's not closed while producing, and the produce after it's closed for idleness goes to the recreated stream.
+type dmlStream struct {
+	msgstream.MsgStream
+	mgr          *singleTypeChannelsMgr
+	collectionID UniqueID
+	// in the order of the producers of the stream
+	pchans   []pChan
+	lastUsed *atomic.Time
+	metrics  *dmlProduceMetrics
+
+	mu         sync.RWMutex
+	closed     bool
+	idleClosed bool
+}
+
+func newDmlStream(mgr *singleTypeChannelsMgr, collectionID UniqueID, pchans []pChan, stream msgstream.MsgStream) *dmlStream {
+	return &dmlStream{
+		MsgStream:    stream,
+		mgr:          mgr,
+		collectionID: collectionID,
+		pchans:       pchans,
+		lastUsed:     atomic.NewTime(time.Now()),
+		metrics:      newDmlProduceMetrics(collectionID),
+	}
+}
+
+func (s *dmlStream) Produce(msgPack *msgstream.MsgPack) error {
+	err := s.produce(func(stream msgstream.MsgStream) error {
+		return s.metrics.produce(s.pchans, msgPack, stream.Produce)
+	})
+	s.mgr.health.report(s.pchans, msgPack, nil, err)
+	return err
+}
+
+func (s *dmlStream) ProduceMark(msgPack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+	var ids map[string][]msgstream.MessageID
+	err := s.produce(func(stream msgstream.MsgStream) error {
+		return s.metrics.produce(s.pchans, msgPack, func(pack *msgstream.MsgPack) error {
+			packIDs, err := stream.ProduceMark(pack)
+			if ids == nil {
+				ids = packIDs
+				return err
+			}
+			for pchan, pchanIDs := range packIDs {
+				ids[pchan] = append(ids[pchan], pchanIDs...)
+			}
+			return err
+		})
+	})
+	s.mgr.health.report(s.pchans, msgPack, ids, err)
+	return ids, err
+}
+
+func (s *dmlStream) produce(produce func(stream msgstream.MsgStream) error) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		if !s.idleClosed {
+			return fmt.Errorf("dml stream of collection %d is closed", s.collectionID)
+		}
+		// closed after acquired, it's removed from the manager before closing, so it's recreated here
+		stream, err := s.mgr.getOrCreateStream(s.collectionID)
+		if err != nil {
+			return err
+		}
+		return produce(stream)
+	}
+	defer s.mu.RUnlock()
+	s.lastUsed.Store(time.Now())
+	return produce(s.MsgStream)
+}
+
+func (s *dmlStream) Close() {
+	s.close(false)
+}
+
+// close closes the stream after the producing ones finish.
+func (s *dmlStream) close(idle bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.idleClosed = idle
+	s.MsgStream.Close()
+	s.metrics.cleanup()
+}
+
 func removeDuplicate(ss []string) []string {
 	m := make(map[string]struct{})
 	filtered := make([]string, 0, len(ss))
@@ -117,6 +211,9 @@ type singleTypeChannelsMgr struct {
 	getChannelsFunc  getChannelsFuncType
 	repackFunc       repackFuncType
 	msgStreamFactory msgstream.Factory
+
+	// the results of producing to the pchans, shared by the streams of all the collections
+	health *dmlChannelHealth
 }
 
 func (mgr *singleTypeChannelsMgr) getAllChannels(collectionID UniqueID) (channelInfos, error) {
@@ -233,8 +330,9 @@ func (mgr *singleTypeChannelsMgr) createMsgStream(collectionID UniqueID) (msgstr
 		log.Info("create message stream", zap.Int64("collection", collectionID),
 			zap.Strings("virtual_channels", channelInfos.vchans),
 			zap.Strings("physical_channels", channelInfos.pchans))
-		mgr.infos[collectionID] = streamInfos{channelInfos: channelInfos, stream: stream}
+		mgr.infos[collectionID] = streamInfos{channelInfos: channelInfos, stream: newDmlStream(mgr, collectionID, channelInfos.pchans, stream)}
 		incPChansMetrics(channelInfos.pchans)
+		metrics.ProxyDmlStreamNum.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10)).Inc()
 	} else {
 		stream.Close()
 	}
@@ -269,6 +367,7 @@ func (mgr *singleTypeChannelsMgr) removeStream(collectionID UniqueID) {
 	defer mgr.mu.Unlock()
 	if info, ok := mgr.infos[collectionID]; ok {
 		decPChanMetrics(info.channelInfos.pchans)
+		metrics.ProxyDmlStreamNum.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10)).Dec()
 		info.stream.Close()
 		delete(mgr.infos, collectionID)
 	}
@@ -283,10 +382,35 @@ func (mgr *singleTypeChannelsMgr) removeAllStream() {
 		info.stream.Close()
 		decPChanMetrics(info.channelInfos.pchans)
 	}
+	metrics.ProxyDmlStreamNum.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10)).Sub(float64(len(mgr.infos)))
 	mgr.infos = make(map[UniqueID]streamInfos)
 	log.Info("all dml stream removed")
 }
 
+// removeIdleStream removes the streams not produced for longer than ttl, they're recreated on next use.
+func (mgr *singleTypeChannelsMgr) removeIdleStream(ttl time.Duration) {
+	// removed first, so the ones acquiring them afterwards get the recreated ones
+	idleStreams := make([]*dmlStream, 0)
+	mgr.mu.Lock()
+	for collectionID, info := range mgr.infos {
+		stream, ok := info.stream.(*dmlStream)
+		if !ok || time.Since(stream.lastUsed.Load()) < ttl {
+			continue
+		}
+		decPChanMetrics(info.channelInfos.pchans)
+		delete(mgr.infos, collectionID)
+		idleStreams = append(idleStreams, stream)
+	}
+	metrics.ProxyDmlStreamNum.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10)).Sub(float64(len(idleStreams)))
+	mgr.mu.Unlock()
+
+	for _, stream := range idleStreams {
+		stream.close(true)
+		log.Info("idle dml stream removed", zap.Int64("collection_id", stream.collectionID),
+			zap.Time("lastUsed", stream.lastUsed.Load()))
+	}
+}
+
 func newSingleTypeChannelsMgr(
 	getChannelsFunc getChannelsFuncType,
 	msgStreamFactory msgstream.Factory,
@@ -297,6 +421,7 @@ func newSingleTypeChannelsMgr(
 		getChannelsFunc:  getChannelsFunc,
 		repackFunc:       repackFunc,
 		msgStreamFactory: msgStreamFactory,
+		health:           newDmlChannelHealth(),
 	}
 }
 
@@ -328,6 +453,16 @@ func (mgr *channelsMgrImpl) removeAllDMLStream() {
 	mgr.dmlChannelsMgr.removeAllStream()
 }
 
+func (mgr *channelsMgrImpl) removeIdleDMLStream(ttl time.Duration) {
+	mgr.dmlChannelsMgr.removeIdleStream(ttl)
+}
+
+// getFailingDmlChannels returns the pchans the last produce to which failed within proxy.dmlChannelHealth.failureWindow.
+func (mgr *channelsMgrImpl) getFailingDmlChannels() []failingDmlChannel {
+	window := paramtable.Get().ProxyCfg.DmlChannelFailureWindow.GetAsDuration(time.Second)
+	return mgr.dmlChannelsMgr.health.failingChannels(window)
+}
+
 // newChannelsMgrImpl constructs a channels manager.
 func newChannelsMgrImpl(
 	getDmlChannelsFunc getChannelsFuncType,
@@ -20,14 +20,18 @@ import (
 	"context"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream/mqwrapper/nmq"
+	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 )
 
@@ -425,3 +429,249 @@ func Test_singleTypeChannelsMgr_removeAllStream(t *testing.T) {
 	_, err := m.lockGetStream(100)
 	assert.Error(t, err)
 }
+
+// mockProduceStream counts the msg packs produced, and records the close while producing.
+type mockProduceStream struct {
+	msgstream.MsgStream
+	producing       atomic.Int64
+	produced        atomic.Int64
+	closed          atomic.Bool
+	closedProducing atomic.Bool
+}
+
+func (s *mockProduceStream) AsProducer(channels []string) {}
+
+func (s *mockProduceStream) Produce(msgPack *msgstream.MsgPack) error {
+	s.producing.Inc()
+	defer s.producing.Dec()
+	if s.closed.Load() {
+		return errors.New("produce on closed stream")
+	}
+	s.produced.Inc()
+	return nil
+}
+
+func (s *mockProduceStream) ProduceMark(msgPack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+	if err := s.Produce(msgPack); err != nil {
+		return nil, err
+	}
+	return map[string][]msgstream.MessageID{"111": {nmq.NewNmqID(uint64(s.produced.Load()))}}, nil
+}
+
+func (s *mockProduceStream) Close() {
+	if s.producing.Load() > 0 {
+		s.closedProducing.Store(true)
+	}
+	s.closed.Store(true)
+}
+
+func Test_singleTypeChannelsMgr_removeIdleStream(t *testing.T) {
+	paramtable.Init()
+	newMgr := func() (*singleTypeChannelsMgr, func() []*mockProduceStream) {
+		mu := sync.Mutex{}
+		var streams []*mockProduceStream
+		factory := newMockMsgStreamFactory()
+		factory.f = func(ctx context.Context) (msgstream.MsgStream, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			stream := &mockProduceStream{}
+			streams = append(streams, stream)
+			return stream, nil
+		}
+		m := newSingleTypeChannelsMgr(func(collectionID UniqueID) (channelInfos, error) {
+			return channelInfos{vchans: []string{"111", "222"}, pchans: []string{"111"}}, nil
+		}, factory, nil)
+		return m, func() []*mockProduceStream {
+			mu.Lock()
+			defer mu.Unlock()
+			return streams
+		}
+	}
+
+	t.Run("close then reuse", func(t *testing.T) {
+		m, created := newMgr()
+		stream, err := m.getOrCreateStream(100)
+		assert.NoError(t, err)
+		assert.NoError(t, stream.Produce(&msgstream.MsgPack{}))
+
+		// not idle yet
+		m.removeIdleStream(time.Minute)
+		_, err = m.lockGetStream(100)
+		assert.NoError(t, err)
+
+		stream.(*dmlStream).lastUsed.Store(time.Now().Add(-time.Hour))
+		m.removeIdleStream(time.Minute)
+		_, err = m.lockGetStream(100)
+		assert.Error(t, err)
+		assert.Len(t, created(), 1)
+		assert.True(t, created()[0].closed.Load())
+
+		// the stream acquired before closed produces by the recreated one
+		assert.NoError(t, stream.Produce(&msgstream.MsgPack{}))
+		assert.Len(t, created(), 2)
+		assert.EqualValues(t, 1, created()[0].produced.Load())
+		assert.EqualValues(t, 1, created()[1].produced.Load())
+
+		recreated, err := m.getOrCreateStream(100)
+		assert.NoError(t, err)
+		assert.NotSame(t, stream, recreated)
+		assert.NoError(t, recreated.Produce(&msgstream.MsgPack{}))
+		assert.Len(t, created(), 2)
+		assert.EqualValues(t, 2, created()[1].produced.Load())
+
+		// so are the msgs produced with ids returned
+		ids, err := stream.ProduceMark(&msgstream.MsgPack{})
+		assert.NoError(t, err)
+		assert.Len(t, ids["111"], 1)
+		assert.Len(t, created(), 2)
+		assert.EqualValues(t, 3, created()[1].produced.Load())
+	})
+
+	t.Run("produce after removed", func(t *testing.T) {
+		m, created := newMgr()
+		stream, err := m.getOrCreateStream(100)
+		assert.NoError(t, err)
+		m.removeStream(100)
+		// removed explicitly, not recreated
+		assert.Error(t, stream.Produce(&msgstream.MsgPack{}))
+		assert.Len(t, created(), 1)
+	})
+
+	t.Run("produce and close race", func(t *testing.T) {
+		m, created := newMgr()
+		stop := atomic.NewBool(false)
+		produced := atomic.NewInt64(0)
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for !stop.Load() {
+					stream, err := m.getOrCreateStream(UniqueID(100 + i%2))
+					assert.NoError(t, err)
+					assert.NoError(t, stream.Produce(&msgstream.MsgPack{}))
+					produced.Inc()
+				}
+			}(i)
+		}
+		// the streams are closed and recreated while producing
+		assert.Eventually(t, func() bool {
+			m.removeIdleStream(0)
+			return len(created()) > 20
+		}, 10*time.Second, time.Millisecond)
+		stop.Store(true)
+		wg.Wait()
+
+		streamProduced := int64(0)
+		for _, stream := range created() {
+			streamProduced += stream.produced.Load()
+			assert.False(t, stream.closedProducing.Load())
+		}
+		assert.Equal(t, produced.Load(), streamProduced)
+	})
+}
+
+// mockBrokenChannelStream fails to produce the msgs hashed to the broken channel.
+type mockBrokenChannelStream struct {
+	msgstream.MsgStream
+	pchans []pChan
+	broken atomic.String
+}
+
+func (s *mockBrokenChannelStream) AsProducer(channels []string) {}
+
+func (s *mockBrokenChannelStream) Produce(msgPack *msgstream.MsgPack) error {
+	_, err := s.ProduceMark(msgPack)
+	return err
+}
+
+func (s *mockBrokenChannelStream) ProduceMark(msgPack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+	ids := make(map[string][]msgstream.MessageID)
+	for i, msg := range msgPack.Msgs {
+		pchan := s.pchans[msg.HashKeys()[0]%uint32(len(s.pchans))]
+		if pchan == s.broken.Load() {
+			return ids, errors.Newf("producer of %s is broken", pchan)
+		}
+		ids[pchan] = append(ids[pchan], nmq.NewNmqID(uint64(i)))
+	}
+	return ids, nil
+}
+
+func (s *mockBrokenChannelStream) Close() {}
+
+func Test_singleTypeChannelsMgr_dmlChannelHealth(t *testing.T) {
+	paramtable.Init()
+	pchans := []pChan{"pchan0", "pchan1"}
+	stream := &mockBrokenChannelStream{pchans: pchans}
+	factory := newMockMsgStreamFactory()
+	factory.f = func(ctx context.Context) (msgstream.MsgStream, error) {
+		return stream, nil
+	}
+	m := newSingleTypeChannelsMgr(func(collectionID UniqueID) (channelInfos, error) {
+		return channelInfos{vchans: []vChan{"vchan0", "vchan1"}, pchans: pchans}, nil
+	}, factory, nil)
+	dml, err := m.getOrCreateStream(100)
+	assert.NoError(t, err)
+
+	msgPack := func(hashes ...uint32) *msgstream.MsgPack {
+		pack := &msgstream.MsgPack{}
+		for _, hash := range hashes {
+			pack.Msgs = append(pack.Msgs, &msgstream.DeleteMsg{BaseMsg: msgstream.BaseMsg{HashValues: []uint32{hash}}})
+		}
+		return pack
+	}
+	failingChannels := func() []pChan {
+		channels := make([]pChan, 0)
+		for _, failing := range m.health.failingChannels(time.Minute) {
+			channels = append(channels, failing.channel)
+		}
+		return channels
+	}
+
+	// healthy
+	_, err = dml.ProduceMark(msgPack(0, 1))
+	assert.NoError(t, err)
+	assert.Empty(t, failingChannels())
+
+	// only the broken channel is failing, the msgs to the other one are produced before
+	stream.broken.Store("pchan1")
+	_, err = dml.ProduceMark(msgPack(0, 2, 1))
+	assert.Error(t, err)
+	failing := m.health.failingChannels(time.Minute)
+	assert.Len(t, failing, 1)
+	assert.Equal(t, "pchan1", failing[0].channel)
+	assert.EqualError(t, failing[0].err, "producer of pchan1 is broken")
+	assert.False(t, failing[0].lastSuccess.IsZero())
+	assert.False(t, failing[0].lastSuccess.After(failing[0].lastFailure))
+
+	// the other channel keeps healthy
+	assert.NoError(t, dml.Produce(msgPack(0)))
+	assert.Equal(t, []pChan{"pchan1"}, failingChannels())
+
+	// the failures of the produce without ids are reported on all the channels hashed
+	stream.broken.Store("pchan0")
+	assert.Error(t, dml.Produce(msgPack(0, 1)))
+	assert.Equal(t, []pChan{"pchan0", "pchan1"}, failingChannels())
+
+	// recovered
+	stream.broken.Store("")
+	_, err = dml.ProduceMark(msgPack(0, 1))
+	assert.NoError(t, err)
+	assert.Empty(t, failingChannels())
+
+	// denied by the backup instance, not a failure of the channels
+	m.health.report(pchans, msgPack(0, 1), nil, merr.ErrDenyProduceMsg)
+	assert.Empty(t, failingChannels())
+
+	// the failures expire after the window
+	stream.broken.Store("pchan0")
+	assert.Error(t, dml.Produce(msgPack(0)))
+	assert.Equal(t, []pChan{"pchan0"}, failingChannels())
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, m.health.failingChannels(10*time.Millisecond))
+	assert.Len(t, m.health.failingChannels(0), 1)
+
+	// exposed by the channels manager
+	mgr := &channelsMgrImpl{dmlChannelsMgr: m}
+	assert.Len(t, mgr.getFailingDmlChannels(), 1)
+}
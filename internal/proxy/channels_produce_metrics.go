@@ -0,0 +1,139 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// dmlProduceMetrics measures the produces of the dml msgs of a collection to each pchan,
+// and rolls the max latency of the slowest pchan over proxy.dmlProduceMetrics.maxLatencyWindow.
+type dmlProduceMetrics struct {
+	collectionID UniqueID
+
+	mu          sync.Mutex
+	windowStart time.Time
+	// the max latency within the current window and the one before
+	curMax  time.Duration
+	prevMax time.Duration
+}
+
+func newDmlProduceMetrics(collectionID UniqueID) *dmlProduceMetrics {
+	return &dmlProduceMetrics{
+		collectionID: collectionID,
+		windowStart:  time.Now(),
+	}
+}
+
+// produce produces msgPack to the pchans one by one by fn, measuring each of them,
+// the msgPack is produced as a whole unmeasured if disabled or any msg of it is hashed to more than one pchan.
+func (m *dmlProduceMetrics) produce(pchans []pChan, msgPack *msgstream.MsgPack, fn func(pack *msgstream.MsgPack) error) error {
+	if !paramtable.Get().ProxyCfg.DmlProduceMetricsEnabled.GetAsBool() {
+		return fn(msgPack)
+	}
+	packs := splitMsgPackByChannel(len(pchans), msgPack)
+	if packs == nil {
+		return fn(msgPack)
+	}
+	for i, pack := range packs {
+		if pack == nil {
+			continue
+		}
+		start := time.Now()
+		err := fn(pack)
+		m.observe(pchans[i], time.Since(start), err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *dmlProduceMetrics) observe(pchan pChan, latency time.Duration, err error) {
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	if err != nil {
+		metrics.ProxyDmlChannelProduceFailureCount.WithLabelValues(nodeID, pchan).Inc()
+		return
+	}
+	metrics.ProxyDmlChannelProduceLatency.WithLabelValues(nodeID, pchan).Observe(float64(latency.Milliseconds()))
+
+	window := paramtable.Get().ProxyCfg.DmlProduceMaxLatencyWindow.GetAsDuration(time.Second)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elapsed := time.Since(m.windowStart); window > 0 && elapsed >= window {
+		m.prevMax = m.curMax
+		if elapsed >= 2*window {
+			m.prevMax = 0
+		}
+		m.curMax = 0
+		m.windowStart = time.Now()
+	}
+	if latency > m.curMax {
+		m.curMax = latency
+	}
+	metrics.ProxyDmlChannelProduceMaxLatency.WithLabelValues(nodeID, strconv.FormatInt(m.collectionID, 10)).
+		Set(float64(m.maxLatencyLocked().Milliseconds()))
+}
+
+func (m *dmlProduceMetrics) maxLatencyLocked() time.Duration {
+	if m.prevMax > m.curMax {
+		return m.prevMax
+	}
+	return m.curMax
+}
+
+// cleanup removes the max latency of the collection, the ones of the pchans are kept as they're shared by the collections.
+func (m *dmlProduceMetrics) cleanup() {
+	metrics.ProxyDmlChannelProduceMaxLatency.DeleteLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), strconv.FormatInt(m.collectionID, 10))
+}
+
+// splitMsgPackByChannel splits msgPack by the pchans the msgs are hashed to, indexed in the order of the pchans,
+// nil if any msg is hashed to more than one pchan or none.
+func splitMsgPackByChannel(channelNum int, msgPack *msgstream.MsgPack) []*msgstream.MsgPack {
+	if channelNum == 0 || msgPack == nil || len(msgPack.Msgs) == 0 {
+		return nil
+	}
+	packs := make([]*msgstream.MsgPack, channelNum)
+	for _, msg := range msgPack.Msgs {
+		keys := msg.HashKeys()
+		if len(keys) == 0 {
+			return nil
+		}
+		idx := keys[0] % uint32(channelNum)
+		for _, key := range keys[1:] {
+			if key%uint32(channelNum) != idx {
+				return nil
+			}
+		}
+		if packs[idx] == nil {
+			packs[idx] = &msgstream.MsgPack{
+				BeginTs:        msgPack.BeginTs,
+				EndTs:          msgPack.EndTs,
+				StartPositions: msgPack.StartPositions,
+				EndPositions:   msgPack.EndPositions,
+			}
+		}
+		packs[idx].Msgs = append(packs[idx].Msgs, msg)
+	}
+	return packs
+}
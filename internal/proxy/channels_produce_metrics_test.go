@@ -0,0 +1,198 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream/mqwrapper/nmq"
+	"github.com/milvus-io/milvus/pkg/util/funcutil"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// mockSlowChannelStream delays the produce to each channel, and fails the ones to the broken channel.
+type mockSlowChannelStream struct {
+	msgstream.MsgStream
+	pchans []pChan
+	delays map[pChan]time.Duration
+	broken pChan
+	// the number of produce calls
+	calls int
+}
+
+func (s *mockSlowChannelStream) AsProducer(channels []string) {}
+
+func (s *mockSlowChannelStream) Produce(msgPack *msgstream.MsgPack) error {
+	_, err := s.ProduceMark(msgPack)
+	return err
+}
+
+func (s *mockSlowChannelStream) ProduceMark(msgPack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+	s.calls++
+	ids := make(map[string][]msgstream.MessageID)
+	delayed := make(map[pChan]bool)
+	for i, msg := range msgPack.Msgs {
+		pchan := s.pchans[msg.HashKeys()[0]%uint32(len(s.pchans))]
+		if !delayed[pchan] {
+			time.Sleep(s.delays[pchan])
+			delayed[pchan] = true
+		}
+		if pchan == s.broken {
+			return ids, errors.Newf("producer of %s is broken", pchan)
+		}
+		ids[pchan] = append(ids[pchan], nmq.NewNmqID(uint64(i)))
+	}
+	return ids, nil
+}
+
+func (s *mockSlowChannelStream) Close() {}
+
+func Test_dmlStream_produceMetrics(t *testing.T) {
+	paramtable.Init()
+	params := paramtable.Get()
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	collectionID := UniqueID(1000)
+
+	suffix := funcutil.GenRandomStr()
+	pchans := []pChan{"slow_" + suffix, "fast_" + suffix, "unused_" + suffix}
+	stream := &mockSlowChannelStream{
+		pchans: pchans,
+		delays: map[pChan]time.Duration{pchans[0]: 50 * time.Millisecond},
+	}
+	factory := newMockMsgStreamFactory()
+	factory.f = func(ctx context.Context) (msgstream.MsgStream, error) {
+		return stream, nil
+	}
+	m := newSingleTypeChannelsMgr(func(collectionID UniqueID) (channelInfos, error) {
+		return channelInfos{vchans: []vChan{"vchan0", "vchan1", "vchan2"}, pchans: pchans}, nil
+	}, factory, nil)
+	dml, err := m.getOrCreateStream(collectionID)
+	require.NoError(t, err)
+
+	msgPack := func(hashes ...uint32) *msgstream.MsgPack {
+		pack := &msgstream.MsgPack{}
+		for _, hash := range hashes {
+			pack.Msgs = append(pack.Msgs, &msgstream.DeleteMsg{BaseMsg: msgstream.BaseMsg{HashValues: []uint32{hash}}})
+		}
+		return pack
+	}
+	latencySample := func(pchan pChan) (uint64, float64) {
+		m := &dto.Metric{}
+		observer := metrics.ProxyDmlChannelProduceLatency.WithLabelValues(nodeID, pchan)
+		require.NoError(t, observer.(prometheus.Metric).Write(m))
+		return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+	}
+	maxLatency := func() float64 {
+		return testutil.ToFloat64(metrics.ProxyDmlChannelProduceMaxLatency.WithLabelValues(nodeID, strconv.FormatInt(collectionID, 10)))
+	}
+
+	t.Run("per channel", func(t *testing.T) {
+		series := testutil.CollectAndCount(metrics.ProxyDmlChannelProduceLatency)
+		ids, err := dml.ProduceMark(msgPack(0, 1, 0, 1, 4))
+		assert.NoError(t, err)
+		assert.Len(t, ids[pchans[0]], 2)
+		assert.Len(t, ids[pchans[1]], 3)
+		assert.NoError(t, dml.Produce(msgPack(1)))
+		// produced to each channel alone
+		assert.Equal(t, 3, stream.calls)
+
+		// only the channels produced to are labeled
+		assert.Equal(t, series+2, testutil.CollectAndCount(metrics.ProxyDmlChannelProduceLatency))
+		slowCount, slowSum := latencySample(pchans[0])
+		fastCount, fastSum := latencySample(pchans[1])
+		assert.EqualValues(t, 1, slowCount)
+		assert.EqualValues(t, 2, fastCount)
+		assert.GreaterOrEqual(t, slowSum, float64(50))
+		assert.Less(t, fastSum, slowSum)
+		assert.GreaterOrEqual(t, maxLatency(), float64(50))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		stream.calls = 0
+		stream.broken = pchans[1]
+		defer func() { stream.broken = "" }()
+		failures := testutil.ToFloat64(metrics.ProxyDmlChannelProduceFailureCount.WithLabelValues(nodeID, pchans[1]))
+		slowCount, _ := latencySample(pchans[0])
+
+		ids, err := dml.ProduceMark(msgPack(0, 1))
+		assert.Error(t, err)
+		assert.Len(t, ids[pchans[0]], 1)
+		assert.Equal(t, failures+1, testutil.ToFloat64(metrics.ProxyDmlChannelProduceFailureCount.WithLabelValues(nodeID, pchans[1])))
+		count, _ := latencySample(pchans[0])
+		assert.Equal(t, slowCount+1, count)
+		assert.Equal(t, 2, stream.calls)
+	})
+
+	t.Run("rolling max", func(t *testing.T) {
+		params.Save(params.ProxyCfg.DmlProduceMaxLatencyWindow.Key, "0.1")
+		defer params.Reset(params.ProxyCfg.DmlProduceMaxLatencyWindow.Key)
+
+		assert.NoError(t, dml.Produce(msgPack(0)))
+		assert.GreaterOrEqual(t, maxLatency(), float64(50))
+		// the slow one is kept in the window after
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, dml.Produce(msgPack(1)))
+		assert.GreaterOrEqual(t, maxLatency(), float64(50))
+		// and rolled out after two windows
+		time.Sleep(200 * time.Millisecond)
+		assert.NoError(t, dml.Produce(msgPack(1)))
+		assert.Less(t, maxLatency(), float64(50))
+	})
+
+	t.Run("unmeasured", func(t *testing.T) {
+		stream.calls = 0
+		slowCount, _ := latencySample(pchans[0])
+		fastCount, _ := latencySample(pchans[1])
+
+		// hashed to more than one channel
+		pack := &msgstream.MsgPack{Msgs: []msgstream.TsMsg{
+			&msgstream.DeleteMsg{BaseMsg: msgstream.BaseMsg{HashValues: []uint32{0, 1}}},
+		}}
+		assert.NoError(t, dml.Produce(pack))
+		assert.Equal(t, 1, stream.calls)
+
+		// disabled
+		params.Save(params.ProxyCfg.DmlProduceMetricsEnabled.Key, "false")
+		defer params.Reset(params.ProxyCfg.DmlProduceMetricsEnabled.Key)
+		_, err := dml.ProduceMark(msgPack(0, 1))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, stream.calls)
+
+		count, _ := latencySample(pchans[0])
+		assert.Equal(t, slowCount, count)
+		count, _ = latencySample(pchans[1])
+		assert.Equal(t, fastCount, count)
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		gauges := testutil.CollectAndCount(metrics.ProxyDmlChannelProduceMaxLatency)
+		m.removeStream(collectionID)
+		assert.Equal(t, gauges-1, testutil.CollectAndCount(metrics.ProxyDmlChannelProduceMaxLatency))
+	})
+}
@@ -18,12 +18,17 @@ package proxy
 
 import (
 	"context"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/tsoutil"
 	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
@@ -42,6 +47,8 @@ type channelsTimeTicker interface {
 	getMinTsStatistics() (map[pChan]Timestamp, Timestamp, error)
 	// getMinTick returns the minimum last write timestamp between all pchans.
 	getMinTick() Timestamp
+	// StalledChannels returns the pchans whose time tick lags longer than proxy.timeTickStallThreshold.
+	StalledChannels() []pChan
 }
 
 // make sure channelsTimeTickerImpl implements channelsTimeTicker.
@@ -60,6 +67,8 @@ type channelsTimeTickerImpl struct {
 	cancel            context.CancelFunc
 	defaultTimestamp  Timestamp
 	minTimestamp      Timestamp
+	lags              map[pChan]time.Duration // pchan -> lag of the time tick behind now
+	stalled           []pChan
 }
 
 func (ticker *channelsTimeTickerImpl) getMinTsStatistics() (map[pChan]Timestamp, Timestamp, error) {
@@ -148,10 +157,48 @@ func (ticker *channelsTimeTickerImpl) tick() error {
 		}
 	}
 	ticker.minTimestamp = minTs
+	ticker.updateLagsLocked(now)
 
 	return nil
 }
 
+// updateLagsLocked exports the lag of the time tick of each pchan, and detects the stalled pchans.
+func (ticker *channelsTimeTickerImpl) updateLagsLocked(now Timestamp) {
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	threshold := Params.ProxyCfg.TimeTickStallThreshold.GetAsDuration(time.Millisecond)
+
+	for pchan := range ticker.lags {
+		if _, ok := ticker.minTsStatistics[pchan]; !ok {
+			delete(ticker.lags, pchan)
+			metrics.ProxyChannelTimeTickLag.DeleteLabelValues(nodeID, pchan)
+		}
+	}
+
+	stalled := make([]pChan, 0)
+	for pchan, ts := range ticker.minTsStatistics {
+		if ts == 0 {
+			continue
+		}
+		lag := tsoutil.PhysicalTime(now).Sub(tsoutil.PhysicalTime(ts))
+		if lag < 0 {
+			lag = 0
+		}
+		ticker.lags[pchan] = lag
+		metrics.ProxyChannelTimeTickLag.WithLabelValues(nodeID, pchan).Set(float64(lag.Milliseconds()))
+		if threshold > 0 && lag > threshold {
+			stalled = append(stalled, pchan)
+		}
+	}
+	sort.Strings(stalled)
+	ticker.stalled = stalled
+
+	if len(stalled) > 0 {
+		log.RatedWarn(60, "time tick of physical channels stalled",
+			zap.Strings("pchans", stalled),
+			zap.Duration("threshold", threshold))
+	}
+}
+
 func (ticker *channelsTimeTickerImpl) tickLoop() {
 	defer ticker.wg.Done()
 
@@ -211,6 +258,12 @@ func (ticker *channelsTimeTickerImpl) getMinTick() Timestamp {
 	return ticker.minTimestamp
 }
 
+func (ticker *channelsTimeTickerImpl) StalledChannels() []pChan {
+	ticker.statisticsMtx.RLock()
+	defer ticker.statisticsMtx.RUnlock()
+	return append([]pChan{}, ticker.stalled...)
+}
+
 // newChannelsTimeTicker returns a channels time ticker.
 func newChannelsTimeTicker(
 	ctx context.Context,
@@ -227,6 +280,7 @@ func newChannelsTimeTicker(
 		getStatisticsFunc: getStatisticsFunc,
 		tso:               tso,
 		currents:          make(map[pChan]Timestamp),
+		lags:              make(map[pChan]time.Duration),
 		ctx:               ctx1,
 		cancel:            cancel,
 	}
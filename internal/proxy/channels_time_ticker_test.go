@@ -19,15 +19,21 @@ package proxy
 import (
 	"context"
 	"math/rand"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/util/funcutil"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/tsoutil"
 	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
@@ -236,3 +242,57 @@ func TestChannelsTimeTickerImpl_getMinTick(t *testing.T) {
 
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestChannelsTimeTickerImpl_StalledChannels(t *testing.T) {
+	paramtable.Get().Save(Params.ProxyCfg.TimeTickStallThreshold.Key, "100")
+	defer paramtable.Get().Reset(Params.ProxyCfg.TimeTickStallThreshold.Key)
+
+	// the time tick of the frozen pchan stops advancing since a second ago
+	frozenTs := tsoutil.ComposeTSByTime(time.Now().Add(-time.Second), 0)
+	frozen := atomic.NewBool(true)
+	pchans := atomic.NewPointer(&[]pChan{"alive", "frozen"})
+	getStatistics := func() (map[pChan]*pChanStatistics, error) {
+		now := tsoutil.ComposeTSByTime(time.Now(), 0)
+		ret := make(map[pChan]*pChanStatistics)
+		for _, pchan := range *pchans.Load() {
+			ret[pchan] = &pChanStatistics{minTs: now, maxTs: now}
+		}
+		if _, ok := ret["frozen"]; ok && frozen.Load() {
+			ret["frozen"] = &pChanStatistics{minTs: frozenTs, maxTs: frozenTs}
+		}
+		return ret, nil
+	}
+
+	ticker := newChannelsTimeTicker(context.Background(), time.Millisecond*10, nil, getStatistics, newMockTsoAllocator())
+	assert.NoError(t, ticker.start())
+	defer ticker.close()
+
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	lag := func(pchan pChan) float64 {
+		return testutil.ToFloat64(metrics.ProxyChannelTimeTickLag.WithLabelValues(nodeID, pchan))
+	}
+	assert.Eventually(t, func() bool {
+		return len(ticker.StalledChannels()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []pChan{"frozen"}, ticker.StalledChannels())
+	assert.GreaterOrEqual(t, lag("frozen"), float64(1000))
+	assert.Less(t, lag("alive"), float64(100))
+
+	// recovered once the time tick advances again
+	frozen.Store(false)
+	assert.Eventually(t, func() bool {
+		return len(ticker.StalledChannels()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Less(t, lag("frozen"), float64(100))
+
+	// the lag is not exported once the pchan has no pending dml
+	pchans.Store(&[]pChan{"alive"})
+	assert.Eventually(t, func() bool {
+		ticker.statisticsMtx.RLock()
+		defer ticker.statisticsMtx.RUnlock()
+		_, ok := ticker.lags["frozen"]
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, ticker.StalledChannels())
+	assert.False(t, metrics.ProxyChannelTimeTickLag.DeleteLabelValues(nodeID, "frozen"))
+}
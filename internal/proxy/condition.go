@@ -18,15 +18,73 @@ package proxy
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
+	"go.uber.org/atomic"
 )
 
+// TaskState is the state of a task scheduled by proxy, which only moves forward:
+// Pending -> Scheduled -> Executing -> Done/Failed, any of them could be skipped.
+type TaskState int32
+
+const (
+	// TaskStatePending means the task is created but not enqueued yet.
+	TaskStatePending TaskState = iota
+	// TaskStateScheduled means the task is waiting in the queue.
+	TaskStateScheduled
+	// TaskStateExecuting means the task is popped from the queue and executing.
+	TaskStateExecuting
+	// TaskStateDone means the task finished successfully.
+	TaskStateDone
+	// TaskStateFailed means the task finished with an error.
+	TaskStateFailed
+)
+
+func (s TaskState) String() string {
+	switch s {
+	case TaskStatePending:
+		return "Pending"
+	case TaskStateScheduled:
+		return "Scheduled"
+	case TaskStateExecuting:
+		return "Executing"
+	case TaskStateDone:
+		return "Done"
+	case TaskStateFailed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+// IsFinished returns whether the task finished, successfully or not.
+func (s TaskState) IsFinished() bool {
+	return s == TaskStateDone || s == TaskStateFailed
+}
+
+// TaskStateTransition is a transition of the state of a task.
+type TaskStateTransition struct {
+	State TaskState
+	Time  time.Time
+}
+
 // Condition defines the interface of variable condition.
 type Condition interface {
 	WaitToFinish() error
+	WaitToFinishWithTimeout(d time.Duration) error
 	Notify(err error)
 	Ctx() context.Context
+	EnqueueTime() time.Time
+	GetState() TaskState
+	StateTransitions() []TaskStateTransition
+
+	// markEnqueued and markExecuting are called by the task scheduler.
+	markEnqueued()
+	markExecuting()
+	// markState moves the task to state, it's ignored unless the state is after the current one.
+	markState(state TaskState)
 }
 
 // make sure interface implementation
@@ -36,6 +94,14 @@ var _ Condition = (*TaskCondition)(nil)
 type TaskCondition struct {
 	done chan error
 	ctx  context.Context
+
+	// the time the task was enqueued and started executing, zero if not yet
+	enqueueTime   *atomic.Time
+	executingTime *atomic.Time
+
+	stateMu sync.RWMutex
+	// the transitions of the state in order, the first one is Pending at the creation
+	transitions []TaskStateTransition
 }
 
 // WaitToFinish waits until the TaskCondition is notified or context done or canceled
@@ -48,8 +114,86 @@ func (tc *TaskCondition) WaitToFinish() error {
 	}
 }
 
-// Notify sends a signal into the done channel
+// WaitToFinishWithTimeout waits like WaitToFinish, but no longer than d.
+// The error returned on timeout tells how long the task sat unscheduled and how long it has been executing.
+func (tc *TaskCondition) WaitToFinishWithTimeout(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-tc.ctx.Done():
+		return errors.Wrap(tc.ctx.Err(), "proxy TaskCondition context Done")
+	case err := <-tc.done:
+		return err
+	case <-timer.C:
+		unscheduled, executing := tc.queueSpans(time.Now())
+		return errors.Wrapf(context.DeadlineExceeded, "proxy task not finished in %s, unscheduled for %s, executing for %s",
+			d, unscheduled, executing)
+	}
+}
+
+// queueSpans returns how long the task sat unscheduled and how long it has been executing until now.
+func (tc *TaskCondition) queueSpans(now time.Time) (unscheduled time.Duration, executing time.Duration) {
+	enqueueTime, executingTime := tc.enqueueTime.Load(), tc.executingTime.Load()
+	if enqueueTime.IsZero() {
+		return 0, 0
+	}
+	if executingTime.IsZero() {
+		return now.Sub(enqueueTime), 0
+	}
+	return executingTime.Sub(enqueueTime), now.Sub(executingTime)
+}
+
+// EnqueueTime returns the time the task was enqueued, zero if it's not enqueued yet.
+func (tc *TaskCondition) EnqueueTime() time.Time {
+	return tc.enqueueTime.Load()
+}
+
+func (tc *TaskCondition) markEnqueued() {
+	tc.enqueueTime.Store(time.Now())
+	tc.markState(TaskStateScheduled)
+}
+
+func (tc *TaskCondition) markExecuting() {
+	tc.executingTime.Store(time.Now())
+	tc.markState(TaskStateExecuting)
+}
+
+func (tc *TaskCondition) markState(state TaskState) {
+	tc.stateMu.Lock()
+	defer tc.stateMu.Unlock()
+
+	current := tc.transitions[len(tc.transitions)-1].State
+	if state <= current || current.IsFinished() {
+		return
+	}
+	tc.transitions = append(tc.transitions, TaskStateTransition{State: state, Time: time.Now()})
+}
+
+// GetState returns the current state of the task.
+func (tc *TaskCondition) GetState() TaskState {
+	tc.stateMu.RLock()
+	defer tc.stateMu.RUnlock()
+
+	return tc.transitions[len(tc.transitions)-1].State
+}
+
+// StateTransitions returns the transitions of the state of the task in order.
+func (tc *TaskCondition) StateTransitions() []TaskStateTransition {
+	tc.stateMu.RLock()
+	defer tc.stateMu.RUnlock()
+
+	transitions := make([]TaskStateTransition, len(tc.transitions))
+	copy(transitions, tc.transitions)
+	return transitions
+}
+
+// Notify sends a signal into the done channel, the task is Done if err is nil, or Failed otherwise.
 func (tc *TaskCondition) Notify(err error) {
+	if err != nil {
+		tc.markState(TaskStateFailed)
+	} else {
+		tc.markState(TaskStateDone)
+	}
 	tc.done <- err
 }
 
@@ -61,7 +205,10 @@ func (tc *TaskCondition) Ctx() context.Context {
 // NewTaskCondition creates a TaskCondition with provided context
 func NewTaskCondition(ctx context.Context) *TaskCondition {
 	return &TaskCondition{
-		done: make(chan error, 1),
-		ctx:  ctx,
+		done:          make(chan error, 1),
+		ctx:           ctx,
+		enqueueTime:   atomic.NewTime(time.Time{}),
+		executingTime: atomic.NewTime(time.Time{}),
+		transitions:   []TaskStateTransition{{State: TaskStatePending, Time: time.Now()}},
 	}
 }
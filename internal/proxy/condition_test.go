@@ -23,10 +23,12 @@ import (
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/merr"
 )
 
 func TestTaskCondition_Ctx(t *testing.T) {
@@ -121,3 +123,104 @@ func TestTaskCondition_Notify(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestTaskCondition_WaitToFinishWithTimeout(t *testing.T) {
+	t.Run("never completed", func(t *testing.T) {
+		c := NewTaskCondition(context.Background())
+		c.markEnqueued()
+		time.Sleep(20 * time.Millisecond)
+		c.markExecuting()
+
+		start := time.Now()
+		err := c.WaitToFinishWithTimeout(50 * time.Millisecond)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, merr.TimeoutCode, merr.Code(err))
+		assert.Contains(t, err.Error(), "unscheduled for")
+		assert.Contains(t, err.Error(), "executing for")
+	})
+
+	t.Run("completed before deadline", func(t *testing.T) {
+		c := NewTaskCondition(context.Background())
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			c.Notify(errors.New("mock error"))
+		}()
+		err := c.WaitToFinishWithTimeout(time.Second)
+		assert.EqualError(t, err, "mock error")
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		c := NewTaskCondition(ctx)
+		err := c.WaitToFinishWithTimeout(time.Minute)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestTaskCondition_QueueSpans(t *testing.T) {
+	c := NewTaskCondition(context.Background())
+	now := time.Now()
+	assert.True(t, c.EnqueueTime().IsZero())
+	unscheduled, executing := c.queueSpans(now)
+	assert.Zero(t, unscheduled)
+	assert.Zero(t, executing)
+
+	c.enqueueTime.Store(now.Add(-3 * time.Second))
+	assert.Equal(t, now.Add(-3*time.Second), c.EnqueueTime())
+	unscheduled, executing = c.queueSpans(now)
+	assert.Equal(t, 3*time.Second, unscheduled)
+	assert.Zero(t, executing)
+
+	c.executingTime.Store(now.Add(-time.Second))
+	unscheduled, executing = c.queueSpans(now)
+	assert.Equal(t, 2*time.Second, unscheduled)
+	assert.Equal(t, time.Second, executing)
+}
+
+func TestTaskCondition_State(t *testing.T) {
+	states := func(c *TaskCondition) []TaskState {
+		return lo.Map(c.StateTransitions(), func(transition TaskStateTransition, _ int) TaskState { return transition.State })
+	}
+
+	t.Run("done", func(t *testing.T) {
+		c := NewTaskCondition(context.Background())
+		assert.Equal(t, TaskStatePending, c.GetState())
+		c.markEnqueued()
+		assert.Equal(t, TaskStateScheduled, c.GetState())
+		c.markExecuting()
+		// moving backward or staying is ignored
+		c.markState(TaskStateScheduled)
+		c.markState(TaskStateExecuting)
+		assert.Equal(t, TaskStateExecuting, c.GetState())
+		c.Notify(nil)
+		assert.Equal(t, TaskStateDone, c.GetState())
+		assert.NoError(t, c.WaitToFinish())
+
+		assert.Equal(t, []TaskState{TaskStatePending, TaskStateScheduled, TaskStateExecuting, TaskStateDone}, states(c))
+		transitions := c.StateTransitions()
+		for i := 1; i < len(transitions); i++ {
+			assert.False(t, transitions[i].Time.Before(transitions[i-1].Time))
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		c := NewTaskCondition(context.Background())
+		c.markEnqueued()
+		c.Notify(errors.New("mock error"))
+		assert.Equal(t, TaskStateFailed, c.GetState())
+		assert.Equal(t, []TaskState{TaskStatePending, TaskStateScheduled, TaskStateFailed}, states(c))
+	})
+
+	t.Run("finished is final", func(t *testing.T) {
+		c := NewTaskCondition(context.Background())
+		c.markState(TaskStateDone)
+		c.markState(TaskStateFailed)
+		assert.Equal(t, TaskStateDone, c.GetState())
+		assert.True(t, c.GetState().IsFinished())
+		assert.False(t, TaskStateExecuting.IsFinished())
+		assert.Equal(t, "Done", c.GetState().String())
+		assert.Equal(t, "Unknown", TaskState(100).String())
+	})
+}
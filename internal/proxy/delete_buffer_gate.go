@@ -0,0 +1,149 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// deleteBufferGate bounds the memory of primary keys received from query results but not deleted yet.
+// Acquire blocks once the buffered bytes exceed the limit, until finished tasks release their bytes.
+// A single request is always admitted when nothing is buffered, so an oversized task won't block forever.
+// Admit holds back new complex deletes while the limit is reached, rather than letting them query and block midway.
+type deleteBufferGate struct {
+	mu sync.Mutex
+	// closed and replaced on each release to wake up waiters
+	released chan struct{}
+	buffered atomic.Int64
+	limit    func() int64
+}
+
+var (
+	globalDeleteBufferGate     *deleteBufferGate
+	globalDeleteBufferGateOnce sync.Once
+)
+
+func getDeleteBufferGate() *deleteBufferGate {
+	globalDeleteBufferGateOnce.Do(func() {
+		globalDeleteBufferGate = newDeleteBufferGate(func() int64 {
+			return getDeleteParamInt64(&paramtable.Get().ProxyCfg.DeleteBufferMemoryLimit)
+		})
+	})
+	return globalDeleteBufferGate
+}
+
+func newDeleteBufferGate(limit func() int64) *deleteBufferGate {
+	return &deleteBufferGate{
+		released: make(chan struct{}),
+		limit:    limit,
+	}
+}
+
+// Acquire waits until size bytes could be buffered, or ctx is done.
+func (g *deleteBufferGate) Acquire(ctx context.Context, size int64) error {
+	for {
+		g.mu.Lock()
+		buffered := g.buffered.Load()
+		limit := g.limit()
+		if buffered == 0 || limit <= 0 || buffered+size <= limit {
+			g.buffered.Add(size)
+			g.mu.Unlock()
+			g.updateMetric()
+			return nil
+		}
+		released := g.released
+		g.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Admit waits until the buffered bytes drop below the limit, the delete is rejected if it doesn't within timeout.
+func (g *deleteBufferGate) Admit(ctx context.Context, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		g.mu.Lock()
+		buffered := g.buffered.Load()
+		limit := g.limit()
+		if limit <= 0 || buffered < limit {
+			g.mu.Unlock()
+			return nil
+		}
+		released := g.released
+		g.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-timer.C:
+			metrics.ProxyDeleteRejectedCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Inc()
+			return merr.WrapErrServiceMemoryLimitExceeded(float32(buffered), float32(limit), "delete buffer of proxy is full")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release returns size bytes acquired before.
+func (g *deleteBufferGate) Release(size int64) {
+	g.mu.Lock()
+	g.buffered.Sub(size)
+	close(g.released)
+	g.released = make(chan struct{})
+	g.mu.Unlock()
+	g.updateMetric()
+}
+
+// Buffered returns the bytes buffered now.
+func (g *deleteBufferGate) Buffered() int64 {
+	return g.buffered.Load()
+}
+
+func (g *deleteBufferGate) updateMetric() {
+	metrics.ProxyDeleteBufferedBytes.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Set(float64(g.buffered.Load()))
+}
+
+// estimateDeleteIDsSize estimates the memory of primary keys in ids.
+func estimateDeleteIDsSize(ids *schemapb.IDs) int64 {
+	switch ids.GetIdField().(type) {
+	case *schemapb.IDs_IntId:
+		return int64(len(ids.GetIntId().GetData())) * 8
+	case *schemapb.IDs_StrId:
+		// string header takes 16 bytes
+		size := int64(len(ids.GetStrId().GetData())) * 16
+		for _, pk := range ids.GetStrId().GetData() {
+			size += int64(len(pk))
+		}
+		return size
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,138 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+)
+
+func TestDeleteBufferGate(t *testing.T) {
+	t.Run("block and unblock", func(t *testing.T) {
+		gate := newDeleteBufferGate(func() int64 { return 100 })
+		ctx := context.Background()
+
+		assert.NoError(t, gate.Acquire(ctx, 60))
+		assert.EqualValues(t, 60, gate.Buffered())
+
+		acquired := make(chan struct{})
+		go func() {
+			assert.NoError(t, gate.Acquire(ctx, 60))
+			close(acquired)
+		}()
+
+		// 120 bytes exceeds the limit, the second acquire waits until it fits
+		select {
+		case <-acquired:
+			t.Fatal("acquire should be blocked by the limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+		assert.EqualValues(t, 60, gate.Buffered())
+
+		gate.Release(60)
+		select {
+		case <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("acquire should be unblocked after release")
+		}
+		assert.EqualValues(t, 60, gate.Buffered())
+
+		gate.Release(60)
+		assert.EqualValues(t, 0, gate.Buffered())
+	})
+
+	t.Run("oversized request admitted when empty", func(t *testing.T) {
+		gate := newDeleteBufferGate(func() int64 { return 100 })
+		assert.NoError(t, gate.Acquire(context.Background(), 1000))
+		assert.EqualValues(t, 1000, gate.Buffered())
+		gate.Release(1000)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		gate := newDeleteBufferGate(func() int64 { return 0 })
+		assert.NoError(t, gate.Acquire(context.Background(), 1000))
+		assert.NoError(t, gate.Acquire(context.Background(), 1000))
+		assert.EqualValues(t, 2000, gate.Buffered())
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		gate := newDeleteBufferGate(func() int64 { return 100 })
+		assert.NoError(t, gate.Acquire(context.Background(), 100))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, gate.Acquire(ctx, 1), context.Canceled)
+		assert.EqualValues(t, 100, gate.Buffered())
+	})
+
+	t.Run("admit", func(t *testing.T) {
+		gate := newDeleteBufferGate(func() int64 { return 100 })
+		ctx := context.Background()
+
+		// admitted until the limit is reached
+		assert.NoError(t, gate.Admit(ctx, 0))
+		assert.NoError(t, gate.Acquire(ctx, 60))
+		assert.NoError(t, gate.Admit(ctx, 0))
+		assert.NoError(t, gate.Acquire(ctx, 40))
+		assert.ErrorIs(t, gate.Admit(ctx, 0), merr.ErrServiceMemoryLimitExceeded)
+		assert.ErrorIs(t, gate.Admit(ctx, 50*time.Millisecond), merr.ErrServiceMemoryLimitExceeded)
+
+		// admitted once drained below the limit
+		admitted := make(chan error)
+		go func() {
+			admitted <- gate.Admit(ctx, time.Minute)
+		}()
+		select {
+		case <-admitted:
+			t.Fatal("admit should wait for the buffer to drain")
+		case <-time.After(50 * time.Millisecond):
+		}
+		gate.Release(40)
+		select {
+		case err := <-admitted:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("admit should be unblocked after release")
+		}
+		gate.Release(60)
+	})
+
+	t.Run("admit canceled", func(t *testing.T) {
+		gate := newDeleteBufferGate(func() int64 { return 100 })
+		assert.NoError(t, gate.Acquire(context.Background(), 100))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, gate.Admit(ctx, time.Minute), context.Canceled)
+	})
+}
+
+func TestEstimateDeleteIDsSize(t *testing.T) {
+	assert.EqualValues(t, 0, estimateDeleteIDsSize(&schemapb.IDs{}))
+	assert.EqualValues(t, 24, estimateDeleteIDsSize(&schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2, 3}}},
+	}))
+	assert.EqualValues(t, 16*2+3, estimateDeleteIDsSize(&schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "bc"}}},
+	}))
+}
@@ -0,0 +1,331 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/samber/lo"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/parser/planparserv2"
+	"github.com/milvus-io/milvus/internal/proto/planpb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+const deleteContinuationVersion = 1
+
+// deleteContinuation is the progress of a complex delete failed midway, encoded as the continuation token.
+// A delete with the token skips the channels already done, and only retrieves primary keys greater than
+// the last deleted one of the other channels if known.
+type deleteContinuation struct {
+	Version      int   `json:"v"`
+	CollectionID int64 `json:"collection"`
+	PartitionID  int64 `json:"partition"`
+	// fingerprints of the fields and the expr, the token is only valid for the same delete
+	Schema   uint32                            `json:"schema"`
+	Expr     uint32                            `json:"expr"`
+	Channels map[string]*deleteChannelProgress `json:"channels"`
+}
+
+// deleteChannelProgress is the progress of a channel in complex delete.
+type deleteChannelProgress struct {
+	// Done is set once all rows matched in the channel are deleted.
+	Done bool `json:"done,omitempty"`
+	// All rows matched in the channel with primary key not greater than LastIntPK or LastStrPK are deleted.
+	LastIntPK *int64  `json:"last_int_pk,omitempty"`
+	LastStrPK *string `json:"last_str_pk,omitempty"`
+
+	// states of the current run, the primary keys received are only known to be ordered
+	// if they are strictly increasing and the query stream is fully received
+	run          int
+	ordered      bool
+	finished     bool
+	lastReceived interface{}
+	lastDeleted  interface{}
+}
+
+func (p *deleteChannelProgress) lastPK() interface{} {
+	if p.LastIntPK != nil {
+		return *p.LastIntPK
+	}
+	if p.LastStrPK != nil {
+		return *p.LastStrPK
+	}
+	return nil
+}
+
+// deleteProgress tracks the progress of channels in complex delete, nil if not tracked.
+type deleteProgress struct {
+	mu           sync.Mutex
+	continuation *deleteContinuation
+}
+
+// newDeleteProgress returns the progress of the delete, resumed from the continuation token if not empty.
+func newDeleteProgress(token string, collectionID, partitionID int64, schema *schemapb.CollectionSchema, expr string, vChannels []string) (*deleteProgress, error) {
+	continuation := &deleteContinuation{
+		Version:      deleteContinuationVersion,
+		CollectionID: collectionID,
+		PartitionID:  partitionID,
+		Schema:       fieldsFingerprint(schema),
+		Expr:         crc32.ChecksumIEEE([]byte(expr)),
+		Channels:     make(map[string]*deleteChannelProgress),
+	}
+	if len(token) > 0 {
+		resumed, err := decodeDeleteContinuation(token)
+		if err != nil {
+			return nil, err
+		}
+		if resumed.Version != continuation.Version {
+			return nil, merr.WrapErrParameterInvalid(continuation.Version, resumed.Version, "unsupported version of continuation token")
+		}
+		if resumed.CollectionID != continuation.CollectionID || resumed.PartitionID != continuation.PartitionID ||
+			resumed.Schema != continuation.Schema || resumed.Expr != continuation.Expr {
+			return nil, merr.WrapErrParameterInvalidMsg("continuation token is not issued for the delete, collection, partition, schema or expr mismatched")
+		}
+		for channel := range resumed.Channels {
+			if !lo.Contains(vChannels, channel) {
+				return nil, merr.WrapErrParameterInvalidMsg("continuation token contains unknown channel %s", channel)
+			}
+		}
+		continuation.Channels = resumed.Channels
+	}
+	for _, channel := range vChannels {
+		if _, ok := continuation.Channels[channel]; !ok {
+			continuation.Channels[channel] = &deleteChannelProgress{}
+		}
+	}
+	return &deleteProgress{continuation: continuation}, nil
+}
+
+func decodeDeleteContinuation(token string) (*deleteContinuation, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, merr.WrapErrParameterInvalidMsg("failed to decode continuation token: %s", err.Error())
+	}
+	continuation := &deleteContinuation{}
+	if err := json.Unmarshal(bytes, continuation); err != nil {
+		return nil, merr.WrapErrParameterInvalidMsg("failed to decode continuation token: %s", err.Error())
+	}
+	for channel, progress := range continuation.Channels {
+		if progress == nil || (progress.LastIntPK != nil && progress.LastStrPK != nil) {
+			return nil, merr.WrapErrParameterInvalidMsg("invalid progress of channel %s in continuation token", channel)
+		}
+	}
+	return continuation, nil
+}
+
+// fieldsFingerprint identifies the fields of schema, which stays the same across proxy restarts.
+func fieldsFingerprint(schema *schemapb.CollectionSchema) uint32 {
+	hash := crc32.NewIEEE()
+	for _, field := range schema.GetFields() {
+		fmt.Fprintf(hash, "%d:%d:%t;", field.GetFieldID(), field.GetDataType(), field.GetIsPrimaryKey())
+	}
+	return hash.Sum32()
+}
+
+func (p *deleteProgress) get(channel string) *deleteChannelProgress {
+	progress, ok := p.continuation.Channels[channel]
+	if !ok {
+		progress = &deleteChannelProgress{}
+		p.continuation.Channels[channel] = progress
+	}
+	return progress
+}
+
+// deleteChannelRun is a run of a channel in complex delete, the states of a channel are reset
+// by each run, as a failed one is retried on other replicas from the beginning.
+type deleteChannelRun struct {
+	progress *deleteProgress
+	channel  string
+	id       int
+}
+
+// start starts a new run of channel, returns skip if the channel is done already,
+// and the last deleted primary key of it if known.
+func (p *deleteProgress) start(channel string) (run *deleteChannelRun, skip bool, lastPK interface{}) {
+	if p == nil {
+		return nil, false, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	progress := p.get(channel)
+	if progress.Done {
+		return nil, true, nil
+	}
+	progress.run++
+	progress.ordered = true
+	progress.finished = false
+	progress.lastReceived = nil
+	progress.lastDeleted = nil
+	return &deleteChannelRun{progress: p, channel: channel, id: progress.run}, false, progress.lastPK()
+}
+
+// update applies fn to the progress of channel, unless a newer run is started.
+func (r *deleteChannelRun) update(fn func(progress *deleteChannelProgress)) {
+	if r == nil {
+		return
+	}
+	r.progress.mu.Lock()
+	defer r.progress.mu.Unlock()
+	progress := r.progress.get(r.channel)
+	if progress.run == r.id {
+		fn(progress)
+	}
+}
+
+// receive checks if the primary keys received are still strictly increasing.
+func (r *deleteChannelRun) receive(ids *schemapb.IDs) {
+	r.update(func(progress *deleteChannelProgress) {
+		if !progress.ordered || typeutil.GetSizeOfIDs(ids) == 0 {
+			return
+		}
+		switch ids.GetIdField().(type) {
+		case *schemapb.IDs_IntId:
+			progress.ordered, progress.lastReceived = isIncreasingAfter(ids.GetIntId().GetData(), progress.lastReceived)
+		case *schemapb.IDs_StrId:
+			progress.ordered, progress.lastReceived = isIncreasingAfter(ids.GetStrId().GetData(), progress.lastReceived)
+		}
+	})
+}
+
+// isIncreasingAfter returns whether pks are strictly increasing and greater than last, and the last one of pks.
+func isIncreasingAfter[T int64 | string](pks []T, last interface{}) (bool, interface{}) {
+	prev, ok := last.(T)
+	for i, pk := range pks {
+		if (i > 0 || ok) && pk <= prev {
+			return false, nil
+		}
+		prev = pk
+	}
+	return true, prev
+}
+
+// finishReceive marks the query stream fully received.
+func (r *deleteChannelRun) finishReceive() {
+	r.update(func(progress *deleteChannelProgress) {
+		progress.finished = true
+	})
+}
+
+// delete records the primary keys deleted, in the order they are received.
+func (r *deleteChannelRun) delete(ids *schemapb.IDs) {
+	if typeutil.GetSizeOfIDs(ids) == 0 {
+		return
+	}
+	r.update(func(progress *deleteChannelProgress) {
+		// primary keys of a task are reordered by repack, take the greatest one
+		switch ids.GetIdField().(type) {
+		case *schemapb.IDs_IntId:
+			last := lo.Max(ids.GetIntId().GetData())
+			if prev, ok := progress.lastDeleted.(int64); !ok || last > prev {
+				progress.lastDeleted = last
+			}
+		case *schemapb.IDs_StrId:
+			last := lo.Max(ids.GetStrId().GetData())
+			if prev, ok := progress.lastDeleted.(string); !ok || last > prev {
+				progress.lastDeleted = last
+			}
+		}
+	})
+}
+
+// done marks all rows matched in the channel deleted.
+func (r *deleteChannelRun) done() {
+	r.update(func(progress *deleteChannelProgress) {
+		progress.Done = true
+	})
+}
+
+// token returns the continuation token to resume the delete from, false if there is no progress to resume from.
+func (p *deleteProgress) token() (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resumable := false
+	for _, progress := range p.continuation.Channels {
+		if !progress.Done && progress.ordered && progress.finished && progress.lastDeleted != nil {
+			switch pk := progress.lastDeleted.(type) {
+			case int64:
+				progress.LastIntPK = &pk
+			case string:
+				progress.LastStrPK = &pk
+			}
+		}
+		resumable = resumable || progress.Done || progress.lastPK() != nil
+	}
+	if !resumable {
+		return "", false
+	}
+	bytes, err := json.Marshal(p.continuation)
+	if err != nil {
+		return "", false
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), true
+}
+
+// withPKGreaterThan returns a copy of plan only retrieving the rows with primary key greater than pk.
+func withPKGreaterThan(plan *planpb.PlanNode, pkField *schemapb.FieldSchema, pk interface{}) *planpb.PlanNode {
+	var value *planpb.GenericValue
+	switch v := pk.(type) {
+	case int64:
+		value = planparserv2.NewInt(v)
+	case string:
+		value = planparserv2.NewString(v)
+	default:
+		return plan
+	}
+
+	if plan.GetQuery() == nil {
+		return plan
+	}
+	cloned := proto.Clone(plan).(*planpb.PlanNode)
+	query := cloned.GetQuery()
+	query.Predicates = &planpb.Expr{
+		Expr: &planpb.Expr_BinaryExpr{
+			BinaryExpr: &planpb.BinaryExpr{
+				Left: query.GetPredicates(),
+				Right: &planpb.Expr{
+					Expr: &planpb.Expr_UnaryRangeExpr{
+						UnaryRangeExpr: &planpb.UnaryRangeExpr{
+							ColumnInfo: &planpb.ColumnInfo{
+								FieldId:        pkField.GetFieldID(),
+								DataType:       pkField.GetDataType(),
+								IsPrimaryKey:   true,
+								IsAutoID:       pkField.GetAutoID(),
+								IsPartitionKey: pkField.GetIsPartitionKey(),
+							},
+							Op:    planpb.OpType_GreaterThan,
+							Value: value,
+						},
+					},
+				},
+				Op: planpb.BinaryExpr_LogicalAnd,
+			},
+		},
+	}
+	return cloned
+}
@@ -0,0 +1,225 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/planpb"
+	"github.com/milvus-io/milvus/pkg/common"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+)
+
+func newContinuationTestSchema() *schemapb.CollectionSchema {
+	return &schemapb.CollectionSchema{
+		Name: "test_delete",
+		Fields: []*schemapb.FieldSchema{
+			{FieldID: common.StartOfUserFieldID, Name: "pk", IsPrimaryKey: true, DataType: schemapb.DataType_Int64},
+			{FieldID: common.StartOfUserFieldID + 1, Name: "non_pk", DataType: schemapb.DataType_Int64},
+		},
+	}
+}
+
+func int64IDs(pks ...int64) *schemapb.IDs {
+	return &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: pks}}}
+}
+
+func TestDeleteProgress(t *testing.T) {
+	schema := newContinuationTestSchema()
+	channels := []string{"ch0", "ch1", "ch2", "ch3"}
+	expr := "non_pk > 10"
+
+	progress, err := newDeleteProgress("", 111, common.InvalidPartitionID, schema, expr, channels)
+	require.NoError(t, err)
+	_, ok := progress.token()
+	assert.False(t, ok)
+
+	// ch0 is done
+	run, skip, lastPK := progress.start("ch0")
+	assert.False(t, skip)
+	assert.Nil(t, lastPK)
+	run.receive(int64IDs(1, 2))
+	run.finishReceive()
+	run.delete(int64IDs(2, 1))
+	run.done()
+
+	// ch1 receives ordered primary keys, and fails after deleting some of them
+	run, _, _ = progress.start("ch1")
+	run.receive(int64IDs(1, 3))
+	run.receive(int64IDs(5, 7))
+	run.finishReceive()
+	run.delete(int64IDs(3, 1))
+
+	// ch2 receives unordered primary keys
+	run, _, _ = progress.start("ch2")
+	run.receive(int64IDs(1, 3))
+	run.receive(int64IDs(2, 7))
+	run.finishReceive()
+	run.delete(int64IDs(1, 3))
+
+	// the query stream of ch3 is broken, the primary keys not received yet are unknown
+	run, _, _ = progress.start("ch3")
+	run.receive(int64IDs(1, 3))
+	run.delete(int64IDs(1, 3))
+
+	token, ok := progress.token()
+	assert.True(t, ok)
+
+	resumed, err := newDeleteProgress(token, 111, common.InvalidPartitionID, schema, expr, channels)
+	require.NoError(t, err)
+	_, skip, _ = resumed.start("ch0")
+	assert.True(t, skip)
+	_, skip, lastPK = resumed.start("ch1")
+	assert.False(t, skip)
+	assert.Equal(t, int64(3), lastPK)
+	for _, channel := range []string{"ch2", "ch3"} {
+		_, skip, lastPK = resumed.start(channel)
+		assert.False(t, skip)
+		assert.Nil(t, lastPK)
+	}
+
+	t.Run("last primary key kept if no progress", func(t *testing.T) {
+		progress, err := newDeleteProgress(token, 111, common.InvalidPartitionID, schema, expr, channels)
+		require.NoError(t, err)
+		run, _, _ := progress.start("ch1")
+		run.receive(int64IDs(9, 8))
+		run.finishReceive()
+		run.delete(int64IDs(9))
+
+		token, ok := progress.token()
+		assert.True(t, ok)
+		resumed, err := newDeleteProgress(token, 111, common.InvalidPartitionID, schema, expr, channels)
+		require.NoError(t, err)
+		_, _, lastPK := resumed.start("ch1")
+		assert.Equal(t, int64(3), lastPK)
+	})
+
+	t.Run("stale run", func(t *testing.T) {
+		progress, err := newDeleteProgress("", 111, common.InvalidPartitionID, schema, expr, channels)
+		require.NoError(t, err)
+		stale, _, _ := progress.start("ch0")
+		run, _, _ := progress.start("ch0")
+		run.receive(int64IDs(1, 2))
+		stale.finishReceive()
+		stale.done()
+		run.delete(int64IDs(1, 2))
+
+		// the stream of the retried run is not fully received
+		_, ok := progress.token()
+		assert.False(t, ok)
+	})
+
+	t.Run("string primary keys", func(t *testing.T) {
+		progress, err := newDeleteProgress("", 111, common.InvalidPartitionID, schema, expr, channels)
+		require.NoError(t, err)
+		run, _, _ := progress.start("ch0")
+		strIDs := &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b", "c"}}}}
+		run.receive(strIDs)
+		run.finishReceive()
+		run.delete(strIDs)
+
+		token, ok := progress.token()
+		assert.True(t, ok)
+		resumed, err := newDeleteProgress(token, 111, common.InvalidPartitionID, schema, expr, channels)
+		require.NoError(t, err)
+		_, _, lastPK := resumed.start("ch0")
+		assert.Equal(t, "c", lastPK)
+	})
+
+	t.Run("not tracked", func(t *testing.T) {
+		var progress *deleteProgress
+		run, skip, lastPK := progress.start("ch0")
+		assert.False(t, skip)
+		assert.Nil(t, lastPK)
+		run.receive(int64IDs(1))
+		run.finishReceive()
+		run.delete(int64IDs(1))
+		run.done()
+		_, ok := progress.token()
+		assert.False(t, ok)
+	})
+}
+
+func TestDeleteProgress_InvalidToken(t *testing.T) {
+	schema := newContinuationTestSchema()
+	channels := []string{"ch0", "ch1"}
+	expr := "non_pk > 10"
+
+	progress, err := newDeleteProgress("", 111, common.InvalidPartitionID, schema, expr, channels)
+	require.NoError(t, err)
+	run, _, _ := progress.start("ch0")
+	run.done()
+	token, ok := progress.token()
+	require.True(t, ok)
+
+	otherSchema := newContinuationTestSchema()
+	otherSchema.Fields[1].DataType = schemapb.DataType_VarChar
+
+	cases := []struct {
+		name         string
+		token        string
+		collectionID int64
+		partitionID  int64
+		schema       *schemapb.CollectionSchema
+		expr         string
+		channels     []string
+	}{
+		{"malformed", "???", 111, common.InvalidPartitionID, schema, expr, channels},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("[]")), 111, common.InvalidPartitionID, schema, expr, channels},
+		{"unsupported version", base64.RawURLEncoding.EncodeToString([]byte(`{"v":2}`)), 111, common.InvalidPartitionID, schema, expr, channels},
+		{"collection mismatched", token, 112, common.InvalidPartitionID, schema, expr, channels},
+		{"partition mismatched", token, 111, 222, schema, expr, channels},
+		{"schema mismatched", token, 111, common.InvalidPartitionID, otherSchema, expr, channels},
+		{"expr mismatched", token, 111, common.InvalidPartitionID, schema, "non_pk > 11", channels},
+		{"unknown channel", token, 111, common.InvalidPartitionID, schema, expr, []string{"ch1"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := newDeleteProgress(c.token, c.collectionID, c.partitionID, c.schema, c.expr, c.channels)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		})
+	}
+}
+
+func Test_withPKGreaterThan(t *testing.T) {
+	schema := newContinuationTestSchema()
+	predicates := &planpb.Expr{Expr: &planpb.Expr_AlwaysTrueExpr{AlwaysTrueExpr: &planpb.AlwaysTrueExpr{}}}
+	plan := &planpb.PlanNode{
+		Node: &planpb.PlanNode_Query{Query: &planpb.QueryPlanNode{Predicates: predicates}},
+	}
+
+	resumed := withPKGreaterThan(plan, schema.Fields[0], int64(100))
+	// the original plan is untouched
+	assert.Equal(t, predicates, plan.GetQuery().GetPredicates())
+
+	binaryExpr := resumed.GetQuery().GetPredicates().GetBinaryExpr()
+	assert.Equal(t, planpb.BinaryExpr_LogicalAnd, binaryExpr.GetOp())
+	assert.NotNil(t, binaryExpr.GetLeft().GetAlwaysTrueExpr())
+	unaryRange := binaryExpr.GetRight().GetUnaryRangeExpr()
+	assert.Equal(t, planpb.OpType_GreaterThan, unaryRange.GetOp())
+	assert.Equal(t, int64(common.StartOfUserFieldID), unaryRange.GetColumnInfo().GetFieldId())
+	assert.True(t, unaryRange.GetColumnInfo().GetIsPrimaryKey())
+	assert.Equal(t, int64(100), unaryRange.GetValue().GetInt64Val())
+
+	assert.Equal(t, "a", withPKGreaterThan(plan, schema.Fields[0], "a").GetQuery().GetPredicates().
+		GetBinaryExpr().GetRight().GetUnaryRangeExpr().GetValue().GetStringVal())
+}
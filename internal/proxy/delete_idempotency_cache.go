@@ -0,0 +1,116 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/cache"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+const deleteIdempotencyCacheName = "DeleteIdempotency"
+
+type deleteIdempotencyEntry struct {
+	// fingerprint of the request which produced the result
+	fingerprint string
+	result      *milvuspb.MutationResult
+}
+
+// deleteIdempotencyCache keeps the results of completed deletes by their idempotency key,
+// so a retried delete returns the first result instead of being applied again.
+// The cache is local to each proxy, a retry routed to another proxy is executed again,
+// so are retries issued while the first attempt is still running.
+type deleteIdempotencyCache struct {
+	results cache.Cache[string, *deleteIdempotencyEntry]
+}
+
+var (
+	globalDeleteIdempotencyCache     *deleteIdempotencyCache
+	globalDeleteIdempotencyCacheOnce sync.Once
+)
+
+// getDeleteIdempotencyCache returns the proxy-wide delete idempotency cache,
+// a nil cache is returned if the cache is disabled.
+func getDeleteIdempotencyCache() *deleteIdempotencyCache {
+	globalDeleteIdempotencyCacheOnce.Do(func() {
+		params := paramtable.Get()
+		globalDeleteIdempotencyCache = newDeleteIdempotencyCache(
+			getDeleteParamInt64(&params.ProxyCfg.DeleteIdempotencyCacheSize),
+			getDeleteParamDuration(&params.ProxyCfg.DeleteIdempotencyTTL, time.Second),
+		)
+	})
+	return globalDeleteIdempotencyCache
+}
+
+func newDeleteIdempotencyCache(size int64, ttl time.Duration) *deleteIdempotencyCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &deleteIdempotencyCache{
+		results: cache.NewCache[string, *deleteIdempotencyEntry](
+			cache.WithMaximumSize[string, *deleteIdempotencyEntry](size),
+			cache.WithExpireAfterWrite[string, *deleteIdempotencyEntry](ttl),
+			cache.WithPolicy[string, *deleteIdempotencyEntry]("lru"),
+		),
+	}
+}
+
+// deleteRequestFingerprint identifies what a delete request deletes, ignoring its base
+// except the primary keys of delete by primary keys.
+func deleteRequestFingerprint(req *milvuspb.DeleteRequest) string {
+	ids, _ := getDeleteOption(req, DeleteIDsKey)
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s\x00%d",
+		req.GetDbName(), req.GetCollectionName(), req.GetPartitionName(), req.GetExpr(), ids, req.GetConsistencyLevel())
+}
+
+// Get returns a copy of the result kept for key.
+// An error is returned if the key was used by a request deleting something else.
+func (c *deleteIdempotencyCache) Get(key string, req *milvuspb.DeleteRequest) (*milvuspb.MutationResult, bool, error) {
+	if c == nil {
+		return nil, false, nil
+	}
+	nodeID := fmt.Sprint(paramtable.GetNodeID())
+	entry, ok := c.results.GetIfPresent(key)
+	if !ok {
+		metrics.ProxyCacheStatsCounter.WithLabelValues(nodeID, deleteIdempotencyCacheName, metrics.CacheMissLabel).Inc()
+		return nil, false, nil
+	}
+	if entry.fingerprint != deleteRequestFingerprint(req) {
+		return nil, false, merr.WrapErrParameterInvalidMsg("idempotency key %s was used by a different delete request", key)
+	}
+	metrics.ProxyCacheStatsCounter.WithLabelValues(nodeID, deleteIdempotencyCacheName, metrics.CacheHitLabel).Inc()
+	return proto.Clone(entry.result).(*milvuspb.MutationResult), true, nil
+}
+
+// Put keeps the result of a completed delete for key.
+func (c *deleteIdempotencyCache) Put(key string, req *milvuspb.DeleteRequest, result *milvuspb.MutationResult) {
+	if c == nil {
+		return
+	}
+	c.results.Put(key, &deleteIdempotencyEntry{
+		fingerprint: deleteRequestFingerprint(req),
+		result:      proto.Clone(result).(*milvuspb.MutationResult),
+	})
+}
@@ -0,0 +1,138 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+)
+
+func TestDeleteIdempotencyCache(t *testing.T) {
+	req := &milvuspb.DeleteRequest{
+		DbName:         "db",
+		CollectionName: "coll",
+		Expr:           "pk < 10",
+	}
+	result := &milvuspb.MutationResult{
+		Status:    merr.Success(),
+		DeleteCnt: 10,
+		Timestamp: 100,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		c := newDeleteIdempotencyCache(0, time.Minute)
+		assert.Nil(t, c)
+		c.Put("key", req, result)
+		_, ok, err := c.Get("key", req)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		c := newDeleteIdempotencyCache(16, time.Minute)
+		_, ok, err := c.Get("key", req)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		c.Put("key", req, result)
+		cached, ok, err := c.Get("key", req)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(10), cached.GetDeleteCnt())
+		assert.Equal(t, uint64(100), cached.GetTimestamp())
+
+		// modify the returned result, cached one must stay untouched
+		cached.DeleteCnt = 0
+		cached, ok, err = c.Get("key", req)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(10), cached.GetDeleteCnt())
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		c := newDeleteIdempotencyCache(16, 100*time.Millisecond)
+		c.Put("key", req, result)
+		_, ok, err := c.Get("key", req)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		assert.Eventually(t, func() bool {
+			_, ok, err := c.Get("key", req)
+			return err == nil && !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("differing request under same key", func(t *testing.T) {
+		c := newDeleteIdempotencyCache(16, time.Minute)
+		c.Put("key", req, result)
+
+		other := &milvuspb.DeleteRequest{
+			DbName:         "db",
+			CollectionName: "coll",
+			Expr:           "pk < 20",
+		}
+		_, ok, err := c.Get("key", other)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.False(t, ok)
+
+		// base of the request is not part of what it deletes
+		replay := &milvuspb.DeleteRequest{
+			Base:           &commonpb.MsgBase{MsgID: 1},
+			DbName:         "db",
+			CollectionName: "coll",
+			Expr:           "pk < 10",
+		}
+		_, ok, err = c.Get("key", replay)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
+
+func TestDeleteRunner_RunWithIdempotencyKey(t *testing.T) {
+	req := &milvuspb.DeleteRequest{
+		Base: &commonpb.MsgBase{
+			Properties: map[string]string{DeleteIdempotencyKey: "TestDeleteRunner_RunWithIdempotencyKey"},
+		},
+		DbName:         "db",
+		CollectionName: "coll",
+		Expr:           "pk < 10",
+	}
+	getDeleteIdempotencyCache().Put("TestDeleteRunner_RunWithIdempotencyKey", req, &milvuspb.MutationResult{
+		Status:    merr.Success(),
+		DeleteCnt: 10,
+	})
+
+	// the replayed delete must return without touching the cluster
+	dr := deleteRunner{req: req}
+	assert.NoError(t, dr.Run(context.Background()))
+	assert.Equal(t, int64(10), dr.result.GetDeleteCnt())
+
+	dr = deleteRunner{req: &milvuspb.DeleteRequest{
+		Base:           req.GetBase(),
+		DbName:         "db",
+		CollectionName: "coll",
+		Expr:           "pk < 20",
+	}}
+	assert.ErrorIs(t, dr.Run(context.Background()), merr.ErrParameterInvalid)
+}
@@ -0,0 +1,114 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/internal/proto/planpb"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// DeleteInterceptor intercepts the deletes executed by proxy, for auditing and policy enforcement.
+type DeleteInterceptor interface {
+	// BeforeDelete is called before any row is deleted, returning an error aborts the delete with it.
+	// plan is nil if the primary keys to delete are carried by the request.
+	BeforeDelete(ctx context.Context, req *milvuspb.DeleteRequest, plan *planpb.PlanNode) error
+	// AfterDelete is called once the delete is executed, even if it failed midway,
+	// the DeleteCnt of result is the number of rows deleted.
+	AfterDelete(ctx context.Context, req *milvuspb.DeleteRequest, result *milvuspb.MutationResult)
+}
+
+// deleteInterceptorRegistry holds the interceptors invoked in registration order.
+type deleteInterceptorRegistry struct {
+	mu           sync.RWMutex
+	interceptors []DeleteInterceptor
+}
+
+var globalDeleteInterceptors = newDeleteInterceptorRegistry(&auditDeleteInterceptor{})
+
+func newDeleteInterceptorRegistry(interceptors ...DeleteInterceptor) *deleteInterceptorRegistry {
+	return &deleteInterceptorRegistry{
+		interceptors: interceptors,
+	}
+}
+
+// RegisterDeleteInterceptor registers an interceptor invoked on every delete of proxy.
+func RegisterDeleteInterceptor(interceptor DeleteInterceptor) {
+	globalDeleteInterceptors.register(interceptor)
+}
+
+func (r *deleteInterceptorRegistry) register(interceptor DeleteInterceptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interceptors = append(r.interceptors, interceptor)
+}
+
+func (r *deleteInterceptorRegistry) list() []DeleteInterceptor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.interceptors
+}
+
+// beforeDelete stops at the first interceptor vetoing the delete.
+func (r *deleteInterceptorRegistry) beforeDelete(ctx context.Context, req *milvuspb.DeleteRequest, plan *planpb.PlanNode) error {
+	for _, interceptor := range r.list() {
+		if err := interceptor.BeforeDelete(ctx, req, plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *deleteInterceptorRegistry) afterDelete(ctx context.Context, req *milvuspb.DeleteRequest, result *milvuspb.MutationResult) {
+	for _, interceptor := range r.list() {
+		interceptor.AfterDelete(ctx, req, result)
+	}
+}
+
+// auditDeleteInterceptor writes an audit log for each delete if proxy.deleteAuditEnabled is set.
+type auditDeleteInterceptor struct{}
+
+func (i *auditDeleteInterceptor) BeforeDelete(ctx context.Context, req *milvuspb.DeleteRequest, plan *planpb.PlanNode) error {
+	if !getDeleteParamBool(&paramtable.Get().ProxyCfg.DeleteAuditEnabled) {
+		return nil
+	}
+	i.logger(ctx, req).Info("delete audit: start", zap.Bool("byPrimaryKeys", plan == nil))
+	return nil
+}
+
+func (i *auditDeleteInterceptor) AfterDelete(ctx context.Context, req *milvuspb.DeleteRequest, result *milvuspb.MutationResult) {
+	if !getDeleteParamBool(&paramtable.Get().ProxyCfg.DeleteAuditEnabled) {
+		return
+	}
+	i.logger(ctx, req).Info("delete audit: finish", zap.Int64("deleteCnt", result.GetDeleteCnt()))
+}
+
+func (i *auditDeleteInterceptor) logger(ctx context.Context, req *milvuspb.DeleteRequest) *log.MLogger {
+	user, _ := GetCurUserFromContext(ctx)
+	return log.Ctx(ctx).With(
+		zap.String("user", user),
+		zap.String("db", req.GetDbName()),
+		zap.String("collection", req.GetCollectionName()),
+		zap.String("partition", req.GetPartitionName()),
+		zap.String("expr", req.GetExpr()))
+}
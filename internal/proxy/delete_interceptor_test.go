@@ -0,0 +1,103 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/internal/proto/planpb"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+type testDeleteInterceptor struct {
+	err       error
+	beforeCnt int
+	afterCnt  int
+	plan      *planpb.PlanNode
+	result    *milvuspb.MutationResult
+}
+
+func (i *testDeleteInterceptor) BeforeDelete(ctx context.Context, req *milvuspb.DeleteRequest, plan *planpb.PlanNode) error {
+	i.beforeCnt++
+	i.plan = plan
+	return i.err
+}
+
+func (i *testDeleteInterceptor) AfterDelete(ctx context.Context, req *milvuspb.DeleteRequest, result *milvuspb.MutationResult) {
+	i.afterCnt++
+	i.result = result
+}
+
+// withDeleteInterceptors replaces the global interceptors until the test finished.
+func withDeleteInterceptors(t *testing.T, interceptors ...DeleteInterceptor) {
+	origin := globalDeleteInterceptors
+	globalDeleteInterceptors = newDeleteInterceptorRegistry(interceptors...)
+	t.Cleanup(func() {
+		globalDeleteInterceptors = origin
+	})
+}
+
+func TestDeleteInterceptorRegistry(t *testing.T) {
+	ctx := context.Background()
+	req := &milvuspb.DeleteRequest{CollectionName: "test_delete", Expr: "pk > 1"}
+
+	t.Run("pass through", func(t *testing.T) {
+		first, second := &testDeleteInterceptor{}, &testDeleteInterceptor{}
+		withDeleteInterceptors(t, first)
+		RegisterDeleteInterceptor(second)
+
+		plan := &planpb.PlanNode{}
+		assert.NoError(t, globalDeleteInterceptors.beforeDelete(ctx, req, plan))
+		globalDeleteInterceptors.afterDelete(ctx, req, &milvuspb.MutationResult{DeleteCnt: 3})
+		for _, interceptor := range []*testDeleteInterceptor{first, second} {
+			assert.Equal(t, 1, interceptor.beforeCnt)
+			assert.Same(t, plan, interceptor.plan)
+			assert.Equal(t, 1, interceptor.afterCnt)
+			assert.EqualValues(t, 3, interceptor.result.GetDeleteCnt())
+		}
+	})
+
+	t.Run("veto", func(t *testing.T) {
+		vetoErr := errors.New("delete is not allowed")
+		first, second := &testDeleteInterceptor{err: vetoErr}, &testDeleteInterceptor{}
+		withDeleteInterceptors(t, first, second)
+
+		assert.ErrorIs(t, globalDeleteInterceptors.beforeDelete(ctx, req, nil), vetoErr)
+		assert.Equal(t, 1, first.beforeCnt)
+		assert.Equal(t, 0, second.beforeCnt)
+	})
+}
+
+func TestAuditDeleteInterceptor(t *testing.T) {
+	paramtable.Init()
+	ctx := context.Background()
+	req := &milvuspb.DeleteRequest{CollectionName: "test_delete", Expr: "pk > 1"}
+	interceptor := &auditDeleteInterceptor{}
+
+	assert.NoError(t, interceptor.BeforeDelete(ctx, req, nil))
+	interceptor.AfterDelete(ctx, req, &milvuspb.MutationResult{DeleteCnt: 3})
+
+	paramtable.Get().Save(Params.ProxyCfg.DeleteAuditEnabled.Key, "true")
+	defer paramtable.Get().Reset(Params.ProxyCfg.DeleteAuditEnabled.Key)
+	assert.NoError(t, interceptor.BeforeDelete(ctx, req, &planpb.PlanNode{}))
+	interceptor.AfterDelete(ctx, req, &milvuspb.MutationResult{DeleteCnt: 3})
+}
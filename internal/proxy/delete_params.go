@@ -0,0 +1,145 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"golang.org/x/exp/constraints"
+
+	"github.com/milvus-io/milvus/pkg/config"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// The delete params of proxy are read by the typed getters of the config manager, so an invalid value set is logged
+// with the key and the value, and the default value of the param is used instead, rather than parsed as zero silently.
+
+func getDeleteParamInt64(item *paramtable.ParamItem) int64 {
+	def, _ := config.ParseInt64(item.DefaultValue)
+	value, err := paramtable.GetBaseTable().Manager().GetInt64(item.Key, def)
+	warnInvalidDeleteParam(item, err)
+	return value
+}
+
+func getDeleteParamBool(item *paramtable.ParamItem) bool {
+	def, _ := config.ParseBool(item.DefaultValue)
+	value, err := paramtable.GetBaseTable().Manager().GetBool(item.Key, def)
+	warnInvalidDeleteParam(item, err)
+	return value
+}
+
+func getDeleteParamFloat64(item *paramtable.ParamItem) float64 {
+	def, _ := config.ParseFloat64(item.DefaultValue)
+	value, err := paramtable.GetBaseTable().Manager().GetFloat64(item.Key, def)
+	warnInvalidDeleteParam(item, err)
+	return value
+}
+
+func getDeleteParamDuration(item *paramtable.ParamItem, unit time.Duration) time.Duration {
+	def, _ := config.ParseDuration(item.DefaultValue, unit)
+	value, err := paramtable.GetBaseTable().Manager().GetDuration(item.Key, def, unit)
+	warnInvalidDeleteParam(item, err)
+	return value
+}
+
+func warnInvalidDeleteParam(item *paramtable.ParamItem, err error) {
+	if err != nil {
+		log.RatedWarn(60, "invalid delete param of proxy, use the default value", zap.String("default", item.DefaultValue), zap.Error(err))
+	}
+}
+
+// The safe ranges of the tuning knobs of the delete path, the values beyond them are clamped.
+const (
+	maxDeleteChunkSize            = 1000000
+	maxDeleteTaskBufferSize       = 65536
+	maxDeleteRetries              = 10
+	maxDeleteProduceRetryInterval = 5 * time.Second
+)
+
+// deleteKnobs are the tuning knobs of the delete path, which are read by each delete at the start of it,
+// so the changes apply to the deletes started since, rather than the ones ongoing.
+type deleteKnobs struct {
+	// bumped on every change of the knobs, see watchDeleteKnobs
+	version               int64
+	chunkSize             int
+	taskBufferSize        int
+	produceMaxRetries     int
+	produceRetryInterval  time.Duration
+	queryStreamMaxRetries int
+}
+
+var (
+	watchDeleteKnobsOnce sync.Once
+	deleteKnobsVersion   atomic.Int64
+)
+
+// loadDeleteKnobs reads the current values of the delete knobs.
+func loadDeleteKnobs() *deleteKnobs {
+	watchDeleteKnobsOnce.Do(watchDeleteKnobs)
+	params := &paramtable.Get().ProxyCfg
+	knobs := &deleteKnobs{
+		version:               deleteKnobsVersion.Load(),
+		chunkSize:             int(clampDeleteParam(&params.DeleteChunkSize, getDeleteParamInt64(&params.DeleteChunkSize), 0, maxDeleteChunkSize)),
+		taskBufferSize:        int(clampDeleteParam(&params.DeleteTaskBufferSize, getDeleteParamInt64(&params.DeleteTaskBufferSize), 0, maxDeleteTaskBufferSize)),
+		produceMaxRetries:     int(clampDeleteParam(&params.DeleteProduceMaxRetries, getDeleteParamInt64(&params.DeleteProduceMaxRetries), 0, maxDeleteRetries)),
+		produceRetryInterval:  clampDeleteParam(&params.DeleteProduceRetryInterval, getDeleteParamDuration(&params.DeleteProduceRetryInterval, time.Millisecond), 0, maxDeleteProduceRetryInterval),
+		queryStreamMaxRetries: int(clampDeleteParam(&params.DeleteQueryStreamMaxRetries, getDeleteParamInt64(&params.DeleteQueryStreamMaxRetries), 0, maxDeleteRetries)),
+	}
+	if knobs.taskBufferSize == 0 {
+		knobs.taskBufferSize = params.MaxTaskNum.GetAsInt()
+	}
+	return knobs
+}
+
+func clampDeleteParam[T constraints.Integer](item *paramtable.ParamItem, value, min, max T) T {
+	clamped := lo.Clamp(value, min, max)
+	if clamped != value {
+		log.RatedWarn(60, "delete param of proxy is beyond the safe range, clamped into it",
+			zap.String("key", item.Key), zap.Int64("value", int64(value)), zap.Int64("clamped", int64(clamped)))
+	}
+	return clamped
+}
+
+// watchDeleteKnobs watches the changes of the delete knobs, which are logged with the version bumped,
+// so the deletes could be told apart by the knobs they read. The configs are not read by the handler,
+// as it's called by the sources holding the locks of their configs.
+func watchDeleteKnobs() {
+	params := paramtable.Get()
+	for _, item := range []*paramtable.ParamItem{
+		&params.ProxyCfg.DeleteChunkSize,
+		&params.ProxyCfg.DeleteTaskBufferSize,
+		&params.ProxyCfg.DeleteProduceMaxRetries,
+		&params.ProxyCfg.DeleteProduceRetryInterval,
+		&params.ProxyCfg.DeleteQueryStreamMaxRetries,
+	} {
+		params.Watch(item.Key, config.NewHandler("proxy.deleteKnobs."+item.Key, onDeleteKnobChanged))
+	}
+}
+
+func onDeleteKnobChanged(event *config.Event) {
+	if !event.HasUpdated {
+		return
+	}
+	version := deleteKnobsVersion.Inc()
+	log.Info("delete knob of proxy changed, applies to the deletes started since",
+		zap.String("key", event.Key), zap.String("value", event.Value), zap.Int64("version", version))
+}
@@ -0,0 +1,114 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/pkg/config"
+	"github.com/milvus-io/milvus/pkg/util/funcutil"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+func TestDeleteParams(t *testing.T) {
+	paramtable.Init()
+	params := &paramtable.Get().ProxyCfg
+
+	assert.EqualValues(t, 1024, getDeleteParamInt64(&params.DeleteIdempotencyCacheSize))
+	assert.Equal(t, time.Minute, getDeleteParamDuration(&params.DeleteIdempotencyTTL, time.Second))
+	assert.False(t, getDeleteParamBool(&params.DeleteAuditEnabled))
+	assert.Equal(t, 1.0, getDeleteParamFloat64(&params.MutationAuditSampleRate))
+
+	paramtable.Get().Save(params.DeleteIdempotencyCacheSize.Key, "16")
+	defer paramtable.Get().Reset(params.DeleteIdempotencyCacheSize.Key)
+	paramtable.Get().Save(params.DeleteIdempotencyTTL.Key, "1h")
+	defer paramtable.Get().Reset(params.DeleteIdempotencyTTL.Key)
+	paramtable.Get().Save(params.DeleteAuditEnabled.Key, "True")
+	defer paramtable.Get().Reset(params.DeleteAuditEnabled.Key)
+	assert.EqualValues(t, 16, getDeleteParamInt64(&params.DeleteIdempotencyCacheSize))
+	assert.Equal(t, time.Hour, getDeleteParamDuration(&params.DeleteIdempotencyTTL, time.Second))
+	assert.True(t, getDeleteParamBool(&params.DeleteAuditEnabled))
+	paramtable.Get().Save(params.MutationAuditSampleRate.Key, "0.25")
+	defer paramtable.Get().Reset(params.MutationAuditSampleRate.Key)
+	assert.Equal(t, 0.25, getDeleteParamFloat64(&params.MutationAuditSampleRate))
+
+	// the invalid values fall back to the defaults rather than zero
+	paramtable.Get().Save(params.DeleteIdempotencyCacheSize.Key, "16k")
+	paramtable.Get().Save(params.DeleteIdempotencyTTL.Key, "1d")
+	paramtable.Get().Save(params.DeleteAuditEnabled.Key, "yes")
+	paramtable.Get().Save(params.MutationAuditSampleRate.Key, "half")
+	assert.EqualValues(t, 1024, getDeleteParamInt64(&params.DeleteIdempotencyCacheSize))
+	assert.Equal(t, time.Minute, getDeleteParamDuration(&params.DeleteIdempotencyTTL, time.Second))
+	assert.False(t, getDeleteParamBool(&params.DeleteAuditEnabled))
+	assert.Equal(t, 1.0, getDeleteParamFloat64(&params.MutationAuditSampleRate))
+}
+
+func TestDeleteKnobs(t *testing.T) {
+	paramtable.Init()
+	params := &paramtable.Get().ProxyCfg
+
+	knobs := loadDeleteKnobs()
+	assert.Equal(t, 50000, knobs.chunkSize)
+	assert.Equal(t, params.MaxTaskNum.GetAsInt(), knobs.taskBufferSize)
+	assert.Equal(t, 3, knobs.produceMaxRetries)
+	assert.Equal(t, 100*time.Millisecond, knobs.produceRetryInterval)
+	assert.Equal(t, 3, knobs.queryStreamMaxRetries)
+
+	t.Run("clamped", func(t *testing.T) {
+		for key, value := range map[string]string{
+			params.DeleteChunkSize.Key:             "2000000",
+			params.DeleteTaskBufferSize.Key:        "-1",
+			params.DeleteProduceMaxRetries.Key:     "100",
+			params.DeleteProduceRetryInterval.Key:  "60000",
+			params.DeleteQueryStreamMaxRetries.Key: "-3",
+		} {
+			paramtable.Get().Save(key, value)
+			defer paramtable.Get().Reset(key)
+		}
+		knobs := loadDeleteKnobs()
+		assert.Equal(t, maxDeleteChunkSize, knobs.chunkSize)
+		assert.Equal(t, params.MaxTaskNum.GetAsInt(), knobs.taskBufferSize)
+		assert.Equal(t, maxDeleteRetries, knobs.produceMaxRetries)
+		assert.Equal(t, maxDeleteProduceRetryInterval, knobs.produceRetryInterval)
+		assert.Equal(t, 0, knobs.queryStreamMaxRetries)
+
+		paramtable.Get().Save(params.DeleteTaskBufferSize.Key, "16")
+		assert.Equal(t, 16, loadDeleteKnobs().taskBufferSize)
+	})
+
+	t.Run("config event", func(t *testing.T) {
+		source := config.NewMemorySource("delete-knobs-"+funcutil.GenRandomStr(), config.HighPriority)
+		require.NoError(t, paramtable.GetBaseTable().Manager().AddSource(source))
+		defer source.Delete(params.DeleteChunkSize.Key)
+
+		before := loadDeleteKnobs()
+		source.Set(params.DeleteChunkSize.Key, "100")
+		after := loadDeleteKnobs()
+		assert.Equal(t, 100, after.chunkSize)
+		assert.Greater(t, after.version, before.version)
+		// the knobs read before the change are kept
+		assert.Equal(t, 50000, before.chunkSize)
+
+		// the values beyond the safe ranges are clamped too
+		source.Set(params.DeleteChunkSize.Key, "-1")
+		assert.Equal(t, 0, loadDeleteKnobs().chunkSize)
+	})
+}
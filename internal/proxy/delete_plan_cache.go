@@ -0,0 +1,112 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/milvus-io/milvus/internal/parser/planparserv2"
+	"github.com/milvus-io/milvus/internal/proto/planpb"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/cache"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+const deletePlanCacheName = "DeletePlan"
+
+// deletePlanCacheKey identifies a compiled delete plan.
+// The schema version is part of the key, so entries compiled against an older schema
+// are never hit again once the meta cache reloads the collection, and age out by LRU.
+type deletePlanCacheKey struct {
+	collectionID  UniqueID
+	schemaVersion uint64
+	expr          string
+}
+
+// deletePlanCache caches retrieve plans created from delete expressions.
+type deletePlanCache struct {
+	plans cache.Cache[deletePlanCacheKey, *planpb.PlanNode]
+}
+
+var (
+	globalDeletePlanCache     *deletePlanCache
+	globalDeletePlanCacheOnce sync.Once
+)
+
+// getDeletePlanCache returns the proxy-wide delete plan cache,
+// a nil cache is returned if the cache is disabled.
+func getDeletePlanCache() *deletePlanCache {
+	globalDeletePlanCacheOnce.Do(func() {
+		globalDeletePlanCache = newDeletePlanCache(getDeleteParamInt64(&paramtable.Get().ProxyCfg.DeletePlanCacheSize))
+	})
+	return globalDeletePlanCache
+}
+
+func newDeletePlanCache(size int64) *deletePlanCache {
+	if size <= 0 {
+		return nil
+	}
+	return &deletePlanCache{
+		plans: cache.NewCache[deletePlanCacheKey, *planpb.PlanNode](
+			cache.WithMaximumSize[deletePlanCacheKey, *planpb.PlanNode](size),
+			cache.WithPolicy[deletePlanCacheKey, *planpb.PlanNode]("lru"),
+		),
+	}
+}
+
+// GetOrCreate returns the retrieve plan of expr, parsing it only on cache miss.
+// The returned plan is a private copy which the caller is free to modify.
+func (c *deletePlanCache) GetOrCreate(collectionID UniqueID, schema *schemaInfo, expr string) (*planpb.PlanNode, error) {
+	if c == nil {
+		return planparserv2.CreateRetrievePlan(schema.CollectionSchema, expr)
+	}
+
+	key := deletePlanCacheKey{
+		collectionID:  collectionID,
+		schemaVersion: schema.Version(),
+		expr:          expr,
+	}
+	nodeID := fmt.Sprint(paramtable.GetNodeID())
+	if plan, ok := c.plans.GetIfPresent(key); ok {
+		metrics.ProxyCacheStatsCounter.WithLabelValues(nodeID, deletePlanCacheName, metrics.CacheHitLabel).Inc()
+		return proto.Clone(plan).(*planpb.PlanNode), nil
+	}
+	metrics.ProxyCacheStatsCounter.WithLabelValues(nodeID, deletePlanCacheName, metrics.CacheMissLabel).Inc()
+
+	plan, err := planparserv2.CreateRetrievePlan(schema.CollectionSchema, expr)
+	if err != nil {
+		return nil, err
+	}
+	c.plans.Put(key, proto.Clone(plan).(*planpb.PlanNode))
+	return plan, nil
+}
+
+// InvalidateCollection drops all cached plans of the collection.
+func (c *deletePlanCache) InvalidateCollection(collectionID UniqueID) {
+	if c == nil {
+		return
+	}
+	keys := c.plans.Scan(func(key deletePlanCacheKey, _ *planpb.PlanNode) bool {
+		return key.collectionID == collectionID
+	})
+	for key := range keys {
+		c.plans.Invalidate(key)
+	}
+}
@@ -0,0 +1,144 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/parser/planparserv2"
+	"github.com/milvus-io/milvus/pkg/common"
+)
+
+func newDeletePlanCacheTestSchema() *schemaInfo {
+	return newSchemaInfo(&schemapb.CollectionSchema{
+		Name: "test_delete_plan_cache",
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_Int64,
+			},
+			{
+				FieldID:  common.StartOfUserFieldID + 1,
+				Name:     "non_pk",
+				DataType: schemapb.DataType_Int64,
+			},
+		},
+	})
+}
+
+func TestDeletePlanCache(t *testing.T) {
+	schema := newDeletePlanCacheTestSchema()
+	collectionID := int64(1)
+
+	t.Run("disabled", func(t *testing.T) {
+		c := newDeletePlanCache(0)
+		assert.Nil(t, c)
+
+		plan, err := c.GetOrCreate(collectionID, schema, "pk in [1, 2]")
+		assert.NoError(t, err)
+		assert.NotNil(t, plan)
+		c.InvalidateCollection(collectionID)
+	})
+
+	t.Run("hit returns private copy", func(t *testing.T) {
+		c := newDeletePlanCache(16)
+		plan, err := c.GetOrCreate(collectionID, schema, "non_pk > 1")
+		assert.NoError(t, err)
+		expected, err := planparserv2.CreateRetrievePlan(schema.CollectionSchema, "non_pk > 1")
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(expected, plan))
+
+		// modify the returned plan, cached one must stay untouched
+		plan.OutputFieldIds = []int64{common.StartOfUserFieldID}
+		cached, err := c.GetOrCreate(collectionID, schema, "non_pk > 1")
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(expected, cached))
+		assert.Equal(t, uint64(1), c.plans.Stats().HitCount)
+	})
+
+	t.Run("invalid expr not cached", func(t *testing.T) {
+		c := newDeletePlanCache(16)
+		_, err := c.GetOrCreate(collectionID, schema, "????")
+		assert.Error(t, err)
+		_, err = c.GetOrCreate(collectionID, schema, "????")
+		assert.Error(t, err)
+		assert.Equal(t, uint64(0), c.plans.Stats().HitCount)
+	})
+
+	t.Run("schema reload misses", func(t *testing.T) {
+		c := newDeletePlanCache(16)
+		_, err := c.GetOrCreate(collectionID, schema, "non_pk > 1")
+		assert.NoError(t, err)
+
+		reloaded := newDeletePlanCacheTestSchema()
+		assert.NotEqual(t, schema.Version(), reloaded.Version())
+		_, err = c.GetOrCreate(collectionID, reloaded, "non_pk > 1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), c.plans.Stats().HitCount)
+	})
+
+	t.Run("invalidate collection", func(t *testing.T) {
+		c := newDeletePlanCache(16)
+		_, err := c.GetOrCreate(collectionID, schema, "non_pk > 1")
+		assert.NoError(t, err)
+		_, err = c.GetOrCreate(collectionID+1, schema, "non_pk > 1")
+		assert.NoError(t, err)
+
+		c.InvalidateCollection(collectionID)
+		assert.Eventually(t, func() bool {
+			_, ok := c.plans.GetIfPresent(deletePlanCacheKey{collectionID, schema.Version(), "non_pk > 1"})
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+		_, ok := c.plans.GetIfPresent(deletePlanCacheKey{collectionID + 1, schema.Version(), "non_pk > 1"})
+		assert.True(t, ok)
+	})
+}
+
+func BenchmarkDeletePlanCache(b *testing.B) {
+	schema := newDeletePlanCacheTestSchema()
+	expr := fmt.Sprintf("non_pk > %d and non_pk < %d", 10, 100)
+
+	b.Run("parse", func(b *testing.B) {
+		c := newDeletePlanCache(0)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := c.GetOrCreate(1, schema, expr)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		c := newDeletePlanCache(16)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := c.GetOrCreate(1, schema, expr)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
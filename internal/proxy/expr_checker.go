@@ -112,3 +112,36 @@ func ParsePartitionKeys(expr *planpb.Expr) []*planpb.GenericValue {
 
 	return res
 }
+
+// ParseColumnInfosFromExpr returns the infos of all columns referenced by expr.
+func ParseColumnInfosFromExpr(expr *planpb.Expr) []*planpb.ColumnInfo {
+	var res []*planpb.ColumnInfo
+	switch expr := expr.GetExpr().(type) {
+	case *planpb.Expr_TermExpr:
+		res = append(res, expr.TermExpr.GetColumnInfo())
+	case *planpb.Expr_UnaryExpr:
+		res = append(res, ParseColumnInfosFromExpr(expr.UnaryExpr.GetChild())...)
+	case *planpb.Expr_BinaryExpr:
+		res = append(res, ParseColumnInfosFromExpr(expr.BinaryExpr.GetLeft())...)
+		res = append(res, ParseColumnInfosFromExpr(expr.BinaryExpr.GetRight())...)
+	case *planpb.Expr_CompareExpr:
+		res = append(res, expr.CompareExpr.GetLeftColumnInfo(), expr.CompareExpr.GetRightColumnInfo())
+	case *planpb.Expr_UnaryRangeExpr:
+		res = append(res, expr.UnaryRangeExpr.GetColumnInfo())
+	case *planpb.Expr_BinaryRangeExpr:
+		res = append(res, expr.BinaryRangeExpr.GetColumnInfo())
+	case *planpb.Expr_BinaryArithOpEvalRangeExpr:
+		res = append(res, expr.BinaryArithOpEvalRangeExpr.GetColumnInfo())
+	case *planpb.Expr_BinaryArithExpr:
+		res = append(res, ParseColumnInfosFromExpr(expr.BinaryArithExpr.GetLeft())...)
+		res = append(res, ParseColumnInfosFromExpr(expr.BinaryArithExpr.GetRight())...)
+	case *planpb.Expr_ColumnExpr:
+		res = append(res, expr.ColumnExpr.GetInfo())
+	case *planpb.Expr_ExistsExpr:
+		res = append(res, expr.ExistsExpr.GetInfo())
+	case *planpb.Expr_JsonContainsExpr:
+		res = append(res, expr.JsonContainsExpr.GetColumnInfo())
+	}
+
+	return res
+}
@@ -136,3 +136,29 @@ func TestParsePartitionKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestParseColumnInfosFromExpr(t *testing.T) {
+	schema := &schemapb.CollectionSchema{
+		Name: "TestParseColumnInfosFromExpr",
+		Fields: []*schemapb.FieldSchema{
+			{FieldID: common.StartOfUserFieldID, Name: "pk", IsPrimaryKey: true, DataType: schemapb.DataType_Int64},
+			{FieldID: common.StartOfUserFieldID + 1, Name: "a", DataType: schemapb.DataType_Int64},
+			{FieldID: common.StartOfUserFieldID + 2, Name: "b", DataType: schemapb.DataType_VarChar},
+		},
+	}
+	plan, err := planparserv2.CreateRetrievePlan(schema, `(pk in [1, 2] or a > pk) and not (b == "x")`)
+	assert.NoError(t, err)
+	expr, err := ParseExprFromPlan(plan)
+	assert.NoError(t, err)
+
+	fieldIDs := make([]int64, 0)
+	for _, info := range ParseColumnInfosFromExpr(expr) {
+		fieldIDs = append(fieldIDs, info.GetFieldId())
+	}
+	assert.ElementsMatch(t, []int64{
+		common.StartOfUserFieldID,
+		common.StartOfUserFieldID + 1,
+		common.StartOfUserFieldID,
+		common.StartOfUserFieldID + 2,
+	}, fieldIDs)
+}
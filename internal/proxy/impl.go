@@ -41,6 +41,7 @@ import (
 	"github.com/milvus-io/milvus/internal/proto/internalpb"
 	"github.com/milvus-io/milvus/internal/proto/proxypb"
 	"github.com/milvus-io/milvus/internal/proto/querypb"
+	"github.com/milvus-io/milvus/internal/proxy/accesslog"
 	"github.com/milvus-io/milvus/internal/proxy/connection"
 	"github.com/milvus-io/milvus/internal/util/importutil"
 	"github.com/milvus-io/milvus/pkg/common"
@@ -123,6 +124,9 @@ func (node *Proxy) InvalidateCollectionMetaCache(ctx context.Context, request *p
 			aliasName = globalMetaCache.RemoveCollectionsByID(ctx, collectionID)
 		}
 	}
+	if request.CollectionID != UniqueID(0) {
+		getDeletePlanCache().InvalidateCollection(collectionID)
+	}
 	if request.GetBase().GetMsgType() == commonpb.MsgType_DropCollection {
 		// no need to handle error, since this Proxy may not create dml stream for the collection.
 		node.chMgr.removeDMLStream(request.GetCollectionID())
@@ -2303,7 +2307,7 @@ func (node *Proxy) Insert(ctx context.Context, request *milvuspb.InsertRequest)
 				Version:        msgpb.InsertDataVersion_ColumnBased,
 			},
 		},
-		idAllocator:   node.rowIDAllocator,
+		idAllocator:   node.idAllocator,
 		segIDAssigner: node.segAssigner,
 		chMgr:         node.chMgr,
 		chTicker:      node.chTicker,
@@ -2400,19 +2404,24 @@ func (node *Proxy) Delete(ctx context.Context, request *milvuspb.DeleteRequest)
 
 	dr := &deleteRunner{
 		req:             request,
-		idAllocator:     node.rowIDAllocator,
-		tsoAllocatorIns: node.tsoAllocator,
+		idAllocator:     newBreakerIDAllocator(node.idAllocator, idAllocatorBreaker),
+		tsoAllocatorIns: newBreakerTsoAllocator(node.tsoAllocator, tsoAllocatorBreaker),
 		chMgr:           node.chMgr,
 		chTicker:        node.chTicker,
 		queue:           node.sched.dmQueue,
 		lb:              node.lbPolicy,
 	}
+	// written by the access log interceptor once Delete returns
+	defer func() {
+		accesslog.SetDeleteInfo(ctx, dr.accessDeleteInfo())
+	}()
 
 	log.Debug("init delete runner in Proxy")
 	if err := dr.Init(ctx); err != nil {
 		log.Error("Failed to enqueue delete task: " + err.Error())
 		metrics.ProxyFunctionCall.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), method,
 			metrics.AbandonLabel).Inc()
+		auditDelete(ctx, request, 0, err)
 
 		return &milvuspb.MutationResult{
 			Status: merr.Status(err),
@@ -2426,9 +2435,15 @@ func (node *Proxy) Delete(ctx context.Context, request *milvuspb.DeleteRequest)
 		metrics.ProxyFunctionCall.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), method,
 			metrics.FailLabel).Inc()
 
-		return &milvuspb.MutationResult{
+		result := &milvuspb.MutationResult{
 			Status: merr.Status(err),
-		}, nil
+		}
+		// rows deleted before the failure are not rolled back, let the client know how many
+		if errors.Is(err, merr.ErrDeletePartial) || errors.Is(err, merr.ErrDeleteRowsExceeded) {
+			result.DeleteCnt = dr.result.GetDeleteCnt()
+		}
+		auditDelete(ctx, request, dr.result.GetDeleteCnt(), err)
+		return result, nil
 	}
 
 	receiveSize := proto.Size(dr.req)
@@ -2438,6 +2453,7 @@ func (node *Proxy) Delete(ctx context.Context, request *milvuspb.DeleteRequest)
 		metrics.SuccessLabel).Inc()
 	metrics.ProxyMutationLatency.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), metrics.DeleteLabel).Observe(float64(tr.ElapseSpan().Milliseconds()))
 	metrics.ProxyCollectionMutationLatency.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), metrics.DeleteLabel, request.CollectionName).Observe(float64(tr.ElapseSpan().Milliseconds()))
+	auditDelete(ctx, request, dr.result.GetDeleteCnt(), nil)
 	return dr.result, nil
 }
 
@@ -2487,7 +2503,7 @@ func (node *Proxy) Upsert(ctx context.Context, request *milvuspb.UpsertRequest)
 			},
 		},
 
-		idAllocator:   node.rowIDAllocator,
+		idAllocator:   node.idAllocator,
 		segIDAssigner: node.segAssigner,
 		chMgr:         node.chMgr,
 		chTicker:      node.chTicker,
@@ -4863,7 +4879,34 @@ func (node *Proxy) CheckHealth(ctx context.Context, request *milvuspb.CheckHealt
 		}, nil
 	}
 
+	// the dml requests fail at producing to the broken channels
+	if node.chMgr != nil {
+		age := func(t time.Time) time.Duration {
+			return time.Since(t).Truncate(time.Millisecond)
+		}
+		for _, failing := range node.chMgr.getFailingDmlChannels() {
+			lastSuccess := "never"
+			if !failing.lastSuccess.IsZero() {
+				lastSuccess = age(failing.lastSuccess).String() + " ago"
+			}
+			errReasons = append(errReasons, fmt.Sprintf("produce to physical channel %s failed %s ago, last succeeded %s: %s",
+				failing.channel, age(failing.lastFailure), lastSuccess, failing.err.Error()))
+		}
+	}
+	if len(errReasons) != 0 {
+		return &milvuspb.CheckHealthResponse{
+			Status:    merr.Success(),
+			IsHealthy: false,
+			Reasons:   errReasons,
+		}, nil
+	}
+
 	states, reasons := node.multiRateLimiter.GetQuotaStates()
+	if node.chTicker != nil {
+		for _, pchan := range node.chTicker.StalledChannels() {
+			reasons = append(reasons, fmt.Sprintf("time tick of physical channel %s stalled", pchan))
+		}
+	}
 	return &milvuspb.CheckHealthResponse{
 		Status:      merr.Success(),
 		QuotaStates: states,
@@ -168,6 +168,57 @@ func TestProxy_CheckHealth(t *testing.T) {
 		assert.Equal(t, 2, len(resp.GetQuotaStates()))
 		assert.Equal(t, 2, len(resp.GetReasons()))
 	})
+
+	t.Run("stalled channels", func(t *testing.T) {
+		qc := &mocks.MockQueryCoordClient{}
+		qc.EXPECT().CheckHealth(mock.Anything, mock.Anything).Return(&milvuspb.CheckHealthResponse{IsHealthy: true}, nil)
+		ticker := newChannelsTimeTicker(context.Background(), time.Second, nil, nil, newMockTsoAllocator())
+		ticker.stalled = []pChan{"pchan"}
+		node := &Proxy{
+			rootCoord:  NewRootCoordMock(),
+			dataCoord:  NewDataCoordMock(),
+			queryCoord: qc,
+			chTicker:   ticker,
+		}
+		node.multiRateLimiter = NewMultiRateLimiter()
+		node.UpdateStateCode(commonpb.StateCode_Healthy)
+		resp, err := node.CheckHealth(context.Background(), &milvuspb.CheckHealthRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, true, resp.IsHealthy)
+		assert.Equal(t, []string{"time tick of physical channel pchan stalled"}, resp.GetReasons())
+	})
+
+	t.Run("failing dml channels", func(t *testing.T) {
+		qc := &mocks.MockQueryCoordClient{}
+		qc.EXPECT().CheckHealth(mock.Anything, mock.Anything).Return(&milvuspb.CheckHealthResponse{IsHealthy: true}, nil)
+		chMgr := NewMockChannelsMgr(t)
+		node := &Proxy{
+			rootCoord:  NewRootCoordMock(),
+			dataCoord:  NewDataCoordMock(),
+			queryCoord: qc,
+			chMgr:      chMgr,
+		}
+		node.multiRateLimiter = NewMultiRateLimiter()
+		node.UpdateStateCode(commonpb.StateCode_Healthy)
+
+		chMgr.EXPECT().getFailingDmlChannels().Return([]failingDmlChannel{
+			{channel: "pchan0", err: errors.New("mock produce error"), lastFailure: time.Now().Add(-time.Second)},
+			{channel: "pchan1", err: errors.New("mock produce error"), lastFailure: time.Now(), lastSuccess: time.Now().Add(-time.Minute)},
+		}).Once()
+		resp, err := node.CheckHealth(context.Background(), &milvuspb.CheckHealthRequest{})
+		assert.NoError(t, err)
+		assert.False(t, resp.GetIsHealthy())
+		assert.Len(t, resp.GetReasons(), 2)
+		assert.Regexp(t, `^produce to physical channel pchan0 failed 1(\.\d+)?s ago, last succeeded never: mock produce error$`, resp.GetReasons()[0])
+		assert.Regexp(t, `^produce to physical channel pchan1 failed \d+m?s ago, last succeeded 1m0(\.\d+)?s ago: mock produce error$`, resp.GetReasons()[1])
+
+		// recovered
+		chMgr.EXPECT().getFailingDmlChannels().Return(nil).Once()
+		resp, err = node.CheckHealth(context.Background(), &milvuspb.CheckHealthRequest{})
+		assert.NoError(t, err)
+		assert.True(t, resp.GetIsHealthy())
+		assert.Empty(t, resp.GetReasons())
+	})
 }
 
 func TestProxyRenameCollection(t *testing.T) {
@@ -1193,7 +1244,7 @@ func TestProxy_Delete(t *testing.T) {
 		queue, err := newTaskScheduler(ctx, tsoAllocator, nil)
 		assert.NoError(t, err)
 
-		node := &Proxy{chMgr: chMgr, rowIDAllocator: idAllocator, sched: queue}
+		node := &Proxy{chMgr: chMgr, rowIDAllocator: idAllocator, idAllocator: newPrefetchIDAllocator(idAllocator), sched: queue}
 		node.UpdateStateCode(commonpb.StateCode_Healthy)
 		resp, err := node.Delete(ctx, req)
 		assert.NoError(t, err)
@@ -0,0 +1,87 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+const channelAffinityCacheName = "ChannelAffinity"
+
+type channelAffinityKey struct {
+	collectionID int64
+	channel      string
+}
+
+type channelAffinityEntry struct {
+	nodeID int64
+	usedAt time.Time
+}
+
+// channelAffinityCache remembers the delegator last succeeded on each channel,
+// so that the workloads sticky to the delegators keep hitting the same one rather than spreading over the replicas.
+type channelAffinityCache struct {
+	mu      sync.Mutex
+	entries map[channelAffinityKey]channelAffinityEntry
+}
+
+func newChannelAffinityCache() *channelAffinityCache {
+	return &channelAffinityCache{
+		entries: make(map[channelAffinityKey]channelAffinityEntry),
+	}
+}
+
+// get returns the node last succeeded on the channel, the entry used longer than ttl ago is removed.
+func (c *channelAffinityCache) get(collectionID int64, channel string, ttl time.Duration) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := channelAffinityKey{collectionID: collectionID, channel: channel}
+	entry, ok := c.entries[key]
+	if !ok {
+		return -1, false
+	}
+	if time.Since(entry.usedAt) >= ttl {
+		delete(c.entries, key)
+		return -1, false
+	}
+	return entry.nodeID, true
+}
+
+// set records the node succeeded on the channel.
+func (c *channelAffinityCache) set(collectionID int64, channel string, nodeID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[channelAffinityKey{collectionID: collectionID, channel: channel}] = channelAffinityEntry{
+		nodeID: nodeID,
+		usedAt: time.Now(),
+	}
+}
+
+// invalidate removes the entry of the channel if it's still the node,
+// the entry replaced by another node succeeded in the meantime is kept.
+func (c *channelAffinityCache) invalidate(collectionID int64, channel string, nodeID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := channelAffinityKey{collectionID: collectionID, channel: channel}
+	if entry, ok := c.entries[key]; ok && entry.nodeID == nodeID {
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,101 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+)
+
+type nodeLoad struct {
+	// the number of the workloads executing on the node from this proxy
+	executing int64
+	// the total nq of the tasks in the queue of the node, reported by the latest search/query result
+	reportedNQ int64
+	reportedAt time.Time
+}
+
+// nodeLoadCache tracks the recent load signals of the query nodes, which are the workloads executing on them
+// and the cost metrics they report, so the workloads could prefer the least loaded node of each channel.
+type nodeLoadCache struct {
+	mu    sync.Mutex
+	loads map[int64]*nodeLoad
+}
+
+func newNodeLoadCache() *nodeLoadCache {
+	return &nodeLoadCache{
+		loads: make(map[int64]*nodeLoad),
+	}
+}
+
+func (c *nodeLoadCache) getOrCreateLocked(node int64) *nodeLoad {
+	load, ok := c.loads[node]
+	if !ok {
+		load = &nodeLoad{}
+		c.loads[node] = load
+	}
+	return load
+}
+
+// update records the cost metrics reported by the node.
+func (c *nodeLoadCache) update(node int64, cost *internalpb.CostAggregation) {
+	if cost == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	load := c.getOrCreateLocked(node)
+	load.reportedNQ = cost.GetTotalNQ()
+	load.reportedAt = time.Now()
+}
+
+// begin counts a workload starting to execute on the node.
+func (c *nodeLoadCache) begin(node int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.getOrCreateLocked(node).executing++
+}
+
+// end counts a workload finished on the node.
+func (c *nodeLoadCache) end(node int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if load, ok := c.loads[node]; ok && load.executing > 0 {
+		load.executing--
+	}
+}
+
+// get returns the load of the node, the reported nq older than expire is ignored.
+func (c *nodeLoadCache) get(node int64, expire time.Duration) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	load, ok := c.loads[node]
+	if !ok {
+		return 0
+	}
+	value := load.executing
+	if load.reportedNQ > 0 && time.Since(load.reportedAt) < expire {
+		value += load.reportedNQ
+	}
+	return value
+}
@@ -17,9 +17,13 @@ package proxy
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
@@ -27,7 +31,8 @@ import (
 	"github.com/milvus-io/milvus/internal/querycoordv2/params"
 	"github.com/milvus-io/milvus/internal/types"
 	"github.com/milvus-io/milvus/pkg/log"
-	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
 	"github.com/milvus-io/milvus/pkg/util/retry"
 	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
@@ -43,6 +48,12 @@ type ChannelWorkload struct {
 	nq             int64
 	exec           executeFunc
 	retryTimes     uint
+	// the nodes failed on any channel of the same collection workload, shared by the channels of it
+	failedNodes *typeutil.ConcurrentSet[int64]
+	// whether to reuse the node last succeeded on the channel, see proxy.channelAffinity.enabled
+	affinity bool
+	// whether to prefer the least loaded node of the channel, see proxy.loadAwareSelection.enabled
+	loadAware bool
 }
 
 type CollectionWorkLoad struct {
@@ -51,6 +62,12 @@ type CollectionWorkLoad struct {
 	collectionID   int64
 	nq             int64
 	exec           executeFunc
+	// the maximum number of channels executed in parallel, proxy.channelExecuteConcurrency is used if it's not positive
+	concurrency int
+	// whether to reuse the node last succeeded on each channel, see proxy.channelAffinity.enabled
+	affinity bool
+	// whether to prefer the least loaded node of each channel, see proxy.loadAwareSelection.enabled
+	loadAware bool
 }
 
 type LBPolicy interface {
@@ -64,6 +81,8 @@ type LBPolicy interface {
 type LBPolicyImpl struct {
 	balancer  LBBalancer
 	clientMgr shardClientMgr
+	affinity  *channelAffinityCache
+	loads     *nodeLoadCache
 }
 
 func NewLBPolicyImpl(clientMgr shardClientMgr) *LBPolicyImpl {
@@ -82,6 +101,8 @@ func NewLBPolicyImpl(clientMgr shardClientMgr) *LBPolicyImpl {
 	return &LBPolicyImpl{
 		balancer:  balancer,
 		clientMgr: clientMgr,
+		affinity:  newChannelAffinityCache(),
+		loads:     newNodeLoadCache(),
 	}
 }
 
@@ -97,8 +118,11 @@ func (lb *LBPolicyImpl) selectNode(ctx context.Context, workload ChannelWorkload
 		zap.String("channelName", workload.channel),
 	)
 
+	isExcluded := func(node int64) bool {
+		return excludeNodes.Contain(node) || (workload.failedNodes != nil && workload.failedNodes.Contain(node))
+	}
 	filterAvailableNodes := func(node int64, _ int) bool {
-		return !excludeNodes.Contain(node)
+		return !isExcluded(node)
 	}
 
 	getShardLeaders := func() ([]int64, error) {
@@ -110,6 +134,18 @@ func (lb *LBPolicyImpl) selectNode(ctx context.Context, workload ChannelWorkload
 		return lo.Map(shardLeaders[workload.channel], func(node nodeInfo, _ int) int64 { return node.nodeID }), nil
 	}
 
+	if lb.loadAwareEnabled(workload) {
+		if targetNode, ok := lb.selectLeastLoadedNode(ctx, workload, isExcluded); ok {
+			return targetNode, nil
+		}
+	}
+
+	if lb.affinityEnabled(workload) {
+		if targetNode, ok := lb.selectAffinityNode(ctx, workload, isExcluded); ok {
+			return targetNode, nil
+		}
+	}
+
 	availableNodes := lo.Filter(workload.shardLeaders, filterAvailableNodes)
 	targetNode, err := lb.balancer.SelectNode(ctx, availableNodes, workload.nq)
 	if err != nil {
@@ -123,10 +159,11 @@ func (lb *LBPolicyImpl) selectNode(ctx context.Context, workload ChannelWorkload
 
 		availableNodes := lo.Filter(nodes, filterAvailableNodes)
 		if len(availableNodes) == 0 {
-			log.Warn("no available shard delegator found",
+			// all the delegators have failed, retry on them rather than giving up
+			log.Debug("no shard delegator available except the excluded ones, fall back to them",
 				zap.Int64s("nodes", nodes),
-				zap.Int64s("excluded", excludeNodes.Collect()))
-			return -1, merr.WrapErrChannelNotAvailable("no available shard delegator found")
+				zap.Int64s("excluded", lo.Filter(nodes, func(node int64, _ int) bool { return isExcluded(node) })))
+			availableNodes = nodes
 		}
 
 		targetNode, err = lb.balancer.SelectNode(ctx, availableNodes, workload.nq)
@@ -141,6 +178,77 @@ func (lb *LBPolicyImpl) selectNode(ctx context.Context, workload ChannelWorkload
 	return targetNode, nil
 }
 
+func (lb *LBPolicyImpl) affinityEnabled(workload ChannelWorkload) bool {
+	return workload.affinity && lb.affinity != nil && paramtable.Get().ProxyCfg.ChannelAffinityEnabled.GetAsBool()
+}
+
+// selectAffinityNode selects the node last succeeded on the channel if it's still a shard leader of the channel,
+// it's still selected through the balancer to account the workload and to skip the unreachable node.
+func (lb *LBPolicyImpl) selectAffinityNode(ctx context.Context, workload ChannelWorkload, isExcluded func(int64) bool) (int64, bool) {
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	ttl := paramtable.Get().ProxyCfg.ChannelAffinityTTL.GetAsDuration(time.Second)
+	node, ok := lb.affinity.get(workload.collectionID, workload.channel, ttl)
+	if ok && !isExcluded(node) && lo.Contains(workload.shardLeaders, node) {
+		targetNode, err := lb.balancer.SelectNode(ctx, []int64{node}, workload.nq)
+		if err == nil {
+			metrics.ProxyCacheStatsCounter.WithLabelValues(nodeID, channelAffinityCacheName, metrics.CacheHitLabel).Inc()
+			return targetNode, true
+		}
+	}
+	if ok {
+		// the node failed, or it's not the shard leader any more
+		log.Ctx(ctx).Debug("channel affinity invalidated",
+			zap.Int64("collectionID", workload.collectionID),
+			zap.String("channelName", workload.channel),
+			zap.Int64("nodeID", node))
+		lb.affinity.invalidate(workload.collectionID, workload.channel, node)
+	}
+	metrics.ProxyCacheStatsCounter.WithLabelValues(nodeID, channelAffinityCacheName, metrics.CacheMissLabel).Inc()
+	return -1, false
+}
+
+func (lb *LBPolicyImpl) loadAwareEnabled(workload ChannelWorkload) bool {
+	return workload.loadAware && lb.loads != nil && paramtable.Get().ProxyCfg.LoadAwareSelectionEnabled.GetAsBool()
+}
+
+// selectLeastLoadedNode selects the least loaded node of the channel by the recent load signals of the nodes,
+// the node last succeeded on the channel is preferred among the least loaded ones if it's sticky to it.
+// Nothing is selected if the loads don't tell the nodes apart, so that the workload is balanced as before.
+func (lb *LBPolicyImpl) selectLeastLoadedNode(ctx context.Context, workload ChannelWorkload, isExcluded func(int64) bool) (int64, bool) {
+	nodes := lo.Filter(workload.shardLeaders, func(node int64, _ int) bool { return !isExcluded(node) })
+	if len(nodes) < 2 {
+		return -1, false
+	}
+	expire := paramtable.Get().ProxyCfg.CostMetricsExpireTime.GetAsDuration(time.Millisecond)
+	loads := make(map[int64]int64, len(nodes))
+	for _, node := range nodes {
+		loads[node] = lb.loads.get(node, expire)
+	}
+	minLoad, maxLoad := lo.Min(lo.Values(loads)), lo.Max(lo.Values(loads))
+	if minLoad == maxLoad {
+		return -1, false
+	}
+
+	candidates := lo.Filter(nodes, func(node int64, _ int) bool { return loads[node] == minLoad })
+	if lb.affinityEnabled(workload) {
+		ttl := paramtable.Get().ProxyCfg.ChannelAffinityTTL.GetAsDuration(time.Second)
+		if node, ok := lb.affinity.get(workload.collectionID, workload.channel, ttl); ok && lo.Contains(candidates, node) {
+			candidates = []int64{node}
+		}
+	}
+	// still selected through the balancer to account the workload and to skip the unreachable nodes
+	targetNode, err := lb.balancer.SelectNode(ctx, candidates, workload.nq)
+	if err != nil {
+		return -1, false
+	}
+	log.Ctx(ctx).Debug("select the least loaded node",
+		zap.Int64("collectionID", workload.collectionID),
+		zap.String("channelName", workload.channel),
+		zap.Int64("nodeID", targetNode),
+		zap.Any("loads", loads))
+	return targetNode, true
+}
+
 // ExecuteWithRetry will choose a qn to execute the workload, and retry if failed, until reach the max retryTimes.
 func (lb *LBPolicyImpl) ExecuteWithRetry(ctx context.Context, workload ChannelWorkload) error {
 	excludeNodes := typeutil.NewUniqueSet()
@@ -153,6 +261,12 @@ func (lb *LBPolicyImpl) ExecuteWithRetry(ctx context.Context, workload ChannelWo
 	var lastErr error
 	err := retry.Do(ctx, func() error {
 		targetNode, err := lb.selectNode(ctx, workload, excludeNodes)
+		if err == nil && lastErr != nil {
+			log.Debug("retry search/query channel",
+				zap.Int64("nodeID", targetNode),
+				zap.Int64s("excluded", excludeNodes.Collect()),
+				zap.Error(lastErr))
+		}
 		if err != nil {
 			log.Warn("failed to select node for shard",
 				zap.Int64("nodeID", targetNode),
@@ -169,7 +283,8 @@ func (lb *LBPolicyImpl) ExecuteWithRetry(ctx context.Context, workload ChannelWo
 			log.Warn("search/query channel failed, node not available",
 				zap.Int64("nodeID", targetNode),
 				zap.Error(err))
-			excludeNodes.Insert(targetNode)
+			lb.excludeNode(ctx, workload, excludeNodes, targetNode)
+			lb.invalidateAffinity(workload, targetNode)
 
 			// cancel work load which assign to the target node
 			lb.balancer.CancelWorkload(targetNode, workload.nq)
@@ -177,18 +292,24 @@ func (lb *LBPolicyImpl) ExecuteWithRetry(ctx context.Context, workload ChannelWo
 			return lastErr
 		}
 
+		lb.loads.begin(targetNode)
 		err = workload.exec(ctx, targetNode, client, workload.channel)
+		lb.loads.end(targetNode)
 		if err != nil {
 			log.Warn("search/query channel failed",
 				zap.Int64("nodeID", targetNode),
 				zap.Error(err))
-			excludeNodes.Insert(targetNode)
+			lb.excludeNode(ctx, workload, excludeNodes, targetNode)
+			lb.invalidateAffinity(workload, targetNode)
 			lb.balancer.CancelWorkload(targetNode, workload.nq)
 
 			lastErr = errors.Wrapf(err, "failed to search/query delegator %d for channel %s", targetNode, workload.channel)
 			return lastErr
 		}
 
+		if lb.affinityEnabled(workload) {
+			lb.affinity.set(workload.collectionID, workload.channel, targetNode)
+		}
 		lb.balancer.CancelWorkload(targetNode, workload.nq)
 		return nil
 	}, retry.Attempts(workload.retryTimes))
@@ -196,6 +317,26 @@ func (lb *LBPolicyImpl) ExecuteWithRetry(ctx context.Context, workload ChannelWo
 	return err
 }
 
+// excludeNode excludes the failed node from the retries of the channel,
+// and from the other channels of the same collection workload
+func (lb *LBPolicyImpl) excludeNode(ctx context.Context, workload ChannelWorkload, excludeNodes typeutil.UniqueSet, node int64) {
+	excludeNodes.Insert(node)
+	if workload.failedNodes != nil {
+		workload.failedNodes.Insert(node)
+	}
+	log.Ctx(ctx).Debug("exclude the failed node from the retries",
+		zap.Int64("collectionID", workload.collectionID),
+		zap.String("channelName", workload.channel),
+		zap.Int64("nodeID", node))
+}
+
+// invalidateAffinity stops reusing the failed node on the channel.
+func (lb *LBPolicyImpl) invalidateAffinity(workload ChannelWorkload, node int64) {
+	if workload.affinity && lb.affinity != nil {
+		lb.affinity.invalidate(workload.collectionID, workload.channel, node)
+	}
+}
+
 // Execute will execute collection workload in parallel
 func (lb *LBPolicyImpl) Execute(ctx context.Context, workload CollectionWorkLoad) error {
 	dml2leaders, err := globalMetaCache.GetShards(ctx, true, workload.db, workload.collectionName, workload.collectionID)
@@ -204,12 +345,38 @@ func (lb *LBPolicyImpl) Execute(ctx context.Context, workload CollectionWorkLoad
 		return err
 	}
 
+	concurrency := workload.concurrency
+	if concurrency <= 0 {
+		concurrency = Params.ProxyCfg.ChannelExecuteConcurrency.GetAsInt()
+	}
+	if concurrency <= 0 || concurrency > len(dml2leaders) {
+		concurrency = len(dml2leaders)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("channelNum", len(dml2leaders)),
+		attribute.Int("channelConcurrency", concurrency),
+	)
+	log.Ctx(ctx).Debug("execute collection workload",
+		zap.Int64("collectionID", workload.collectionID),
+		zap.Int("channelNum", len(dml2leaders)),
+		zap.Int("concurrency", concurrency))
+
+	// the nodes failed on one channel are avoided by the retries of the others
+	failedNodes := typeutil.NewConcurrentSet[int64]()
+	sem := make(chan struct{}, concurrency)
 	wg, ctx := errgroup.WithContext(ctx)
 	for channel, nodes := range dml2leaders {
 		channel := channel
 		nodes := lo.Map(nodes, func(node nodeInfo, _ int) int64 { return node.nodeID })
 		retryOnReplica := Params.ProxyCfg.RetryTimesOnReplica.GetAsInt()
 		wg.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			defer func() { <-sem }()
+
 			return lb.ExecuteWithRetry(ctx, ChannelWorkload{
 				db:             workload.db,
 				collectionName: workload.collectionName,
@@ -219,6 +386,9 @@ func (lb *LBPolicyImpl) Execute(ctx context.Context, workload CollectionWorkLoad
 				nq:             workload.nq,
 				exec:           workload.exec,
 				retryTimes:     uint(len(nodes) * retryOnReplica),
+				failedNodes:    failedNodes,
+				affinity:       workload.affinity,
+				loadAware:      workload.loadAware,
 			})
 		})
 	}
@@ -227,6 +397,7 @@ func (lb *LBPolicyImpl) Execute(ctx context.Context, workload CollectionWorkLoad
 }
 
 func (lb *LBPolicyImpl) UpdateCostMetrics(node int64, cost *internalpb.CostAggregation) {
+	lb.loads.update(node, cost)
 	lb.balancer.UpdateCostMetrics(node, cost)
 }
 
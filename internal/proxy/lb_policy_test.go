@@ -18,10 +18,15 @@ package proxy
 import (
 	"context"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/atomic"
@@ -34,6 +39,7 @@ import (
 	"github.com/milvus-io/milvus/internal/proto/querypb"
 	"github.com/milvus-io/milvus/internal/types"
 	"github.com/milvus-io/milvus/pkg/common"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 	"github.com/milvus-io/milvus/pkg/util/typeutil"
@@ -202,9 +208,10 @@ func (s *LBPolicySuite) TestSelectNode() {
 	s.ErrorIs(err, merr.ErrNodeNotAvailable)
 	s.Equal(int64(-1), targetNode)
 
-	// test all nodes has been excluded, expected failure
+	// test all nodes has been excluded, expected fall back to the excluded nodes
 	s.lbBalancer.ExpectedCalls = nil
-	s.lbBalancer.EXPECT().SelectNode(mock.Anything, mock.Anything, mock.Anything).Return(-1, merr.ErrNodeNotAvailable)
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{}, mock.Anything).Return(-1, merr.ErrNodeNotAvailable)
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, s.nodes, mock.Anything).Return(3, nil)
 	targetNode, err = s.lbPolicy.selectNode(ctx, ChannelWorkload{
 		db:             dbName,
 		collectionName: s.collectionName,
@@ -213,8 +220,25 @@ func (s *LBPolicySuite) TestSelectNode() {
 		shardLeaders:   s.nodes,
 		nq:             1,
 	}, typeutil.NewUniqueSet(s.nodes...))
-	s.ErrorIs(err, merr.ErrChannelNotAvailable)
-	s.Equal(int64(-1), targetNode)
+	s.NoError(err)
+	s.Equal(int64(3), targetNode)
+
+	// test nodes failed on the other channels of the workload are excluded
+	s.lbBalancer.ExpectedCalls = nil
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{4, 5}, mock.Anything).Return(4, nil)
+	failedNodes := typeutil.NewConcurrentSet[int64]()
+	failedNodes.Upsert(2, 3)
+	targetNode, err = s.lbPolicy.selectNode(ctx, ChannelWorkload{
+		db:             dbName,
+		collectionName: s.collectionName,
+		collectionID:   s.collectionID,
+		channel:        s.channels[0],
+		shardLeaders:   s.nodes,
+		nq:             1,
+		failedNodes:    failedNodes,
+	}, typeutil.NewUniqueSet(1))
+	s.NoError(err)
+	s.Equal(int64(4), targetNode)
 
 	// test get shard leaders failed, retry to select node failed
 	s.lbBalancer.ExpectedCalls = nil
@@ -411,6 +435,341 @@ func (s *LBPolicySuite) TestExecute() {
 	s.ErrorIs(err, mockErr)
 }
 
+func (s *LBPolicySuite) TestExecuteWithFailedNode() {
+	ctx := context.Background()
+	// the balancer always picks the first available node, and the first node always fails
+	selectFirst := func(ctx context.Context, nodes []int64, nq int64) (int64, error) {
+		if len(nodes) == 0 {
+			return -1, merr.ErrNodeNotAvailable
+		}
+		return nodes[0], nil
+	}
+	mockErr := errors.New("mock error")
+
+	// test retry prefers the nodes not failed yet
+	s.mgr.EXPECT().GetClient(mock.Anything, mock.Anything).Return(s.qn, nil)
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(selectFirst)
+	s.lbBalancer.EXPECT().CancelWorkload(mock.Anything, mock.Anything)
+	executed := make([]int64, 0)
+	err := s.lbPolicy.ExecuteWithRetry(ctx, ChannelWorkload{
+		db:             dbName,
+		collectionName: s.collectionName,
+		collectionID:   s.collectionID,
+		channel:        s.channels[0],
+		shardLeaders:   s.nodes,
+		nq:             1,
+		exec: func(ctx context.Context, node UniqueID, qn types.QueryNodeClient, channel string) error {
+			executed = append(executed, node)
+			if node == s.nodes[0] {
+				return mockErr
+			}
+			return nil
+		},
+		retryTimes: 3,
+	})
+	s.NoError(err)
+	s.Equal([]int64{1, 2}, executed)
+
+	// test all the nodes failed, retry falls back to the failed nodes
+	executed = make([]int64, 0)
+	err = s.lbPolicy.ExecuteWithRetry(ctx, ChannelWorkload{
+		db:             dbName,
+		collectionName: s.collectionName,
+		collectionID:   s.collectionID,
+		channel:        s.channels[0],
+		shardLeaders:   s.nodes,
+		nq:             1,
+		exec: func(ctx context.Context, node UniqueID, qn types.QueryNodeClient, channel string) error {
+			executed = append(executed, node)
+			if len(executed) <= len(s.nodes) {
+				return mockErr
+			}
+			return nil
+		},
+		retryTimes: uint(len(s.nodes) + 1),
+	})
+	s.NoError(err)
+	s.Equal([]int64{1, 2, 3, 4, 5, 1}, executed)
+
+	// test the node failed on one channel is avoided by the other channels
+	mu := sync.Mutex{}
+	executedOn := make(map[string][]int64)
+	err = s.lbPolicy.Execute(ctx, CollectionWorkLoad{
+		db:             dbName,
+		collectionName: s.collectionName,
+		collectionID:   s.collectionID,
+		nq:             1,
+		exec: func(ctx context.Context, node UniqueID, qn types.QueryNodeClient, channel string) error {
+			mu.Lock()
+			executedOn[channel] = append(executedOn[channel], node)
+			mu.Unlock()
+			if node == s.nodes[0] {
+				return mockErr
+			}
+			return nil
+		},
+	})
+	s.NoError(err)
+	s.Len(executedOn, len(s.channels))
+	failures := 0
+	for _, nodes := range executedOn {
+		s.Equal(s.nodes[1], nodes[len(nodes)-1])
+		failures += lo.Count(nodes, s.nodes[0])
+	}
+	// the first node is never retried, and is tried by both channels only if they ran concurrently
+	s.LessOrEqual(failures, len(s.channels))
+	s.GreaterOrEqual(failures, 1)
+}
+
+func (s *LBPolicySuite) TestExecuteConcurrency() {
+	ctx := context.Background()
+	channels := []string{"channel1", "channel2", "channel3", "channel4", "channel5", "channel6"}
+	s.qc.ExpectedCalls = nil
+	s.qc.EXPECT().GetShardLeaders(mock.Anything, mock.Anything).Return(&querypb.GetShardLeadersResponse{
+		Status: merr.Success(),
+		Shards: lo.Map(channels, func(channel string, _ int) *querypb.ShardLeadersList {
+			return &querypb.ShardLeadersList{
+				ChannelName: channel,
+				NodeIds:     s.nodes,
+				NodeAddrs:   []string{"localhost:9000", "localhost:9001", "localhost:9002", "localhost:9003", "localhost:9004"},
+			}
+		}),
+	}, nil)
+	globalMetaCache.DeprecateShardCache(dbName, s.collectionName)
+
+	s.mgr.EXPECT().GetClient(mock.Anything, mock.Anything).Return(s.qn, nil)
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+	s.lbBalancer.EXPECT().CancelWorkload(mock.Anything, mock.Anything)
+
+	execute := func(concurrency int) (int64, error) {
+		running := atomic.NewInt64(0)
+		maxRunning := atomic.NewInt64(0)
+		executed := atomic.NewInt64(0)
+		err := s.lbPolicy.Execute(ctx, CollectionWorkLoad{
+			db:             dbName,
+			collectionName: s.collectionName,
+			collectionID:   s.collectionID,
+			nq:             1,
+			exec: func(ctx context.Context, ui UniqueID, qn types.QueryNodeClient, channel string) error {
+				current := running.Inc()
+				defer running.Dec()
+				for {
+					prev := maxRunning.Load()
+					if current <= prev || maxRunning.CompareAndSwap(prev, current) {
+						break
+					}
+				}
+				executed.Inc()
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			},
+			concurrency: concurrency,
+		})
+		s.Equal(int64(len(channels)), executed.Load())
+		return maxRunning.Load(), err
+	}
+
+	// test the concurrency of the workload
+	maxRunning, err := execute(2)
+	s.NoError(err)
+	s.LessOrEqual(maxRunning, int64(2))
+
+	// test the concurrency defaults to the config
+	Params.Save(Params.ProxyCfg.ChannelExecuteConcurrency.Key, "3")
+	defer Params.Reset(Params.ProxyCfg.ChannelExecuteConcurrency.Key)
+	maxRunning, err = execute(0)
+	s.NoError(err)
+	s.LessOrEqual(maxRunning, int64(3))
+
+	// test the config is reloaded
+	Params.Save(Params.ProxyCfg.ChannelExecuteConcurrency.Key, "1")
+	maxRunning, err = execute(0)
+	s.NoError(err)
+	s.Equal(int64(1), maxRunning)
+
+	// test the failure of a channel cancels the channels waiting for the semaphore
+	executed := atomic.NewInt64(0)
+	err = s.lbPolicy.Execute(ctx, CollectionWorkLoad{
+		db:             dbName,
+		collectionName: s.collectionName,
+		collectionID:   s.collectionID,
+		nq:             1,
+		exec: func(ctx context.Context, ui UniqueID, qn types.QueryNodeClient, channel string) error {
+			executed.Inc()
+			return merr.WrapErrServiceInternal("mock error")
+		},
+		concurrency: 1,
+	})
+	s.Error(err)
+	s.Less(executed.Load(), int64(len(channels)*len(s.nodes)*Params.ProxyCfg.RetryTimesOnReplica.GetAsInt()))
+}
+
+func (s *LBPolicySuite) TestChannelAffinity() {
+	ctx := context.Background()
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	counter := func(label string) float64 {
+		return testutil.ToFloat64(metrics.ProxyCacheStatsCounter.WithLabelValues(nodeID, channelAffinityCacheName, label))
+	}
+	cached := func() int64 {
+		node, ok := s.lbPolicy.affinity.get(s.collectionID, s.channels[0], time.Minute)
+		if !ok {
+			return -1
+		}
+		return node
+	}
+
+	executed := make([]int64, 0)
+	failedNode := int64(-1)
+	workload := ChannelWorkload{
+		db:             dbName,
+		collectionName: s.collectionName,
+		collectionID:   s.collectionID,
+		channel:        s.channels[0],
+		shardLeaders:   s.nodes,
+		nq:             1,
+		exec: func(ctx context.Context, node UniqueID, qn types.QueryNodeClient, channel string) error {
+			executed = append(executed, node)
+			if node == failedNode {
+				return errors.New("fake error")
+			}
+			return nil
+		},
+		retryTimes: 2,
+		affinity:   true,
+	}
+	s.mgr.EXPECT().GetClient(mock.Anything, mock.Anything).Return(s.qn, nil)
+	s.lbBalancer.EXPECT().CancelWorkload(mock.Anything, mock.Anything)
+	hits, misses := counter(metrics.CacheHitLabel), counter(metrics.CacheMissLabel)
+
+	// miss, the node selected by the balancer is remembered
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, s.nodes, int64(1)).Return(3, nil).Once()
+	s.NoError(s.lbPolicy.ExecuteWithRetry(ctx, workload))
+	s.Equal(hits, counter(metrics.CacheHitLabel))
+	s.Equal(misses+1, counter(metrics.CacheMissLabel))
+	s.EqualValues(3, cached())
+
+	// hit, the remembered node is selected again
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{3}, int64(1)).Return(3, nil).Once()
+	s.NoError(s.lbPolicy.ExecuteWithRetry(ctx, workload))
+	s.Equal(hits+1, counter(metrics.CacheHitLabel))
+	s.Equal(misses+1, counter(metrics.CacheMissLabel))
+	s.Equal([]int64{3, 3}, executed)
+
+	// invalidated on error, the retry selects another node which is remembered then
+	failedNode = 3
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{3}, int64(1)).Return(3, nil).Once()
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{1, 2, 4, 5}, int64(1)).Return(2, nil).Once()
+	s.NoError(s.lbPolicy.ExecuteWithRetry(ctx, workload))
+	s.Equal(hits+2, counter(metrics.CacheHitLabel))
+	s.Equal(misses+2, counter(metrics.CacheMissLabel))
+	s.Equal([]int64{3, 3, 3, 2}, executed)
+	s.EqualValues(2, cached())
+
+	// the node which is not the shard leader any more is not selected
+	workload.shardLeaders = []int64{1, 3, 4, 5}
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{1, 3, 4, 5}, int64(1)).Return(4, nil).Once()
+	s.NoError(s.lbPolicy.ExecuteWithRetry(ctx, workload))
+	s.Equal(misses+3, counter(metrics.CacheMissLabel))
+	s.EqualValues(4, cached())
+
+	// expired after the ttl
+	Params.Save(Params.ProxyCfg.ChannelAffinityTTL.Key, "0.05")
+	defer Params.Reset(Params.ProxyCfg.ChannelAffinityTTL.Key)
+	time.Sleep(100 * time.Millisecond)
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{1, 3, 4, 5}, int64(1)).Return(5, nil).Once()
+	s.NoError(s.lbPolicy.ExecuteWithRetry(ctx, workload))
+	s.Equal(hits+2, counter(metrics.CacheHitLabel))
+	s.Equal(misses+4, counter(metrics.CacheMissLabel))
+	s.EqualValues(5, cached())
+
+	// neither consulted nor updated if disabled
+	Params.Save(Params.ProxyCfg.ChannelAffinityEnabled.Key, "false")
+	defer Params.Reset(Params.ProxyCfg.ChannelAffinityEnabled.Key)
+	s.lbBalancer.EXPECT().SelectNode(mock.Anything, []int64{1, 3, 4, 5}, int64(1)).Return(1, nil).Once()
+	s.NoError(s.lbPolicy.ExecuteWithRetry(ctx, workload))
+	s.Equal(hits+2, counter(metrics.CacheHitLabel))
+	s.Equal(misses+4, counter(metrics.CacheMissLabel))
+	s.EqualValues(5, cached())
+	s.Equal([]int64{3, 3, 3, 2, 4, 5, 1}, executed)
+}
+
+func (s *LBPolicySuite) TestLoadAwareSelection() {
+	ctx := context.Background()
+	// the delegators are balanced by the workloads from this proxy alone
+	s.lbPolicy.balancer = NewRoundRobinBalancer()
+	s.mgr.EXPECT().GetClient(mock.Anything, mock.Anything).Return(s.qn, nil)
+	Params.Save(Params.ProxyCfg.CostMetricsExpireTime.Key, "60000")
+	defer Params.Reset(Params.ProxyCfg.CostMetricsExpireTime.Key)
+
+	executed := typeutil.NewConcurrentMap[string, int64]()
+	workload := CollectionWorkLoad{
+		db:             dbName,
+		collectionName: s.collectionName,
+		collectionID:   s.collectionID,
+		nq:             1,
+		exec: func(ctx context.Context, node UniqueID, qn types.QueryNodeClient, channel string) error {
+			executed.Insert(channel, node)
+			return nil
+		},
+		affinity:  true,
+		loadAware: true,
+	}
+	executedOn := func() []int64 {
+		return lo.Map(s.channels, func(channel string, _ int) int64 {
+			node, _ := executed.Get(channel)
+			return node
+		})
+	}
+	stickTo := func(node int64) {
+		for _, channel := range s.channels {
+			s.lbPolicy.affinity.set(s.collectionID, channel, node)
+		}
+	}
+
+	// the delegators warmed up by the previous deletes are reused while no load tells the nodes apart
+	stickTo(1)
+	s.NoError(s.lbPolicy.Execute(ctx, workload))
+	s.Equal([]int64{1, 1}, executedOn())
+
+	// node 1 is saturated by searches, the channels are routed to the other replicas
+	for _, node := range s.nodes {
+		s.lbPolicy.UpdateCostMetrics(node, &internalpb.CostAggregation{TotalNQ: 10})
+	}
+	s.lbPolicy.UpdateCostMetrics(1, &internalpb.CostAggregation{TotalNQ: 1000})
+	s.NoError(s.lbPolicy.Execute(ctx, workload))
+	for _, node := range executedOn() {
+		s.NotEqual(int64(1), node)
+	}
+
+	// the sticky node is kept among the least loaded ones
+	stickTo(3)
+	s.NoError(s.lbPolicy.Execute(ctx, workload))
+	s.Equal([]int64{3, 3}, executedOn())
+
+	// the workloads executing on the nodes count too
+	s.lbPolicy.UpdateCostMetrics(1, &internalpb.CostAggregation{TotalNQ: 10})
+	s.lbPolicy.loads.begin(3)
+	s.NoError(s.lbPolicy.Execute(ctx, workload))
+	for _, node := range executedOn() {
+		s.NotEqual(int64(3), node)
+	}
+	s.lbPolicy.loads.end(3)
+
+	// fall back to the sticky node if disabled
+	stickTo(1)
+	s.lbPolicy.UpdateCostMetrics(1, &internalpb.CostAggregation{TotalNQ: 1000})
+	Params.Save(Params.ProxyCfg.LoadAwareSelectionEnabled.Key, "false")
+	s.NoError(s.lbPolicy.Execute(ctx, workload))
+	s.Equal([]int64{1, 1}, executedOn())
+	Params.Reset(Params.ProxyCfg.LoadAwareSelectionEnabled.Key)
+
+	// and once the reported loads expired
+	Params.Save(Params.ProxyCfg.CostMetricsExpireTime.Key, "50")
+	time.Sleep(100 * time.Millisecond)
+	s.NoError(s.lbPolicy.Execute(ctx, workload))
+	s.Equal([]int64{1, 1}, executedOn())
+}
+
 func (s *LBPolicySuite) TestUpdateCostMetrics() {
 	s.lbBalancer.EXPECT().UpdateCostMetrics(mock.Anything, mock.Anything)
 	s.lbPolicy.UpdateCostMetrics(1, &internalpb.CostAggregation{})
@@ -17,6 +17,7 @@
 package proxy
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
@@ -32,6 +33,7 @@ import (
 const (
 	mgrRouteGcPause  = `/management/datacoord/garbage_collection/pause`
 	mgrRouteGcResume = `/management/datacoord/garbage_collection/resume`
+	mgrRouteTasks    = `/management/proxy/tasks`
 )
 
 var mgrRouteRegisterOnce sync.Once
@@ -46,6 +48,10 @@ func RegisterMgrRoute(proxy *Proxy) {
 			Path:        mgrRouteGcResume,
 			HandlerFunc: proxy.ResumeDatacoordGC,
 		})
+		management.Register(&management.Handler{
+			Path:        mgrRouteTasks,
+			HandlerFunc: proxy.ListTasks,
+		})
 	})
 }
 
@@ -91,3 +97,25 @@ func (node *Proxy) ResumeDatacoordGC(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"msg": "OK"}`))
 }
+
+// ListTasks lists the tasks scheduled by the proxy with their states, the in flight ones
+// and the ones finished within proxy.taskRegistry.retention, the oldest first.
+func (node *Proxy) ListTasks(w http.ResponseWriter, req *http.Request) {
+	if node.sched == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"msg": "task scheduler is not started"}`))
+		return
+	}
+	tasks := node.sched.registry.list()
+	if tasks == nil {
+		tasks = []taskInfo{}
+	}
+	body, err := json.Marshal(tasks)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf(`{"msg": "failed to list tasks, %s"}`, err.Error())))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -70,6 +70,9 @@ type Cache interface {
 	GetPartitionsIndex(ctx context.Context, database, collectionName string) ([]string, error)
 	// GetCollectionSchema get collection's schema.
 	GetCollectionSchema(ctx context.Context, database, collectionName string) (*schemaInfo, error)
+	// GetCollectionSchemaVersion get the collection id and the version of the schema cached, without reloading them,
+	// ok is false if the collection is not cached.
+	GetCollectionSchemaVersion(database, collectionName string) (collectionID typeutil.UniqueID, version uint64, ok bool)
 	GetShards(ctx context.Context, withCache bool, database, collectionName string, collectionID int64) (map[string][]nodeInfo, error)
 	DeprecateShardCache(database, collectionName string)
 	expireShardLeaderCache(ctx context.Context)
@@ -109,6 +112,10 @@ type collectionInfo struct {
 	consistencyLevel    commonpb.ConsistencyLevel
 }
 
+// schemaVersionAllocator hands out process-local schema versions,
+// every reload of a schema from rootcoord gets a new one.
+var schemaVersionAllocator = atomic.NewUint64(0)
+
 // schemaInfo is a helper function wraps *schemapb.CollectionSchema
 // with extra fields mapping and methods
 type schemaInfo struct {
@@ -116,6 +123,9 @@ type schemaInfo struct {
 	fieldMap             *typeutil.ConcurrentMap[string, int64] // field name to id mapping
 	hasPartitionKeyField bool
 	pkField              *schemapb.FieldSchema
+	version              uint64
+	// the shard hash declared by the collection properties, empty for the default one
+	shardHashName string
 }
 
 func newSchemaInfo(schema *schemapb.CollectionSchema) *schemaInfo {
@@ -136,9 +146,16 @@ func newSchemaInfo(schema *schemapb.CollectionSchema) *schemaInfo {
 		fieldMap:             fieldMap,
 		hasPartitionKeyField: hasPartitionkey,
 		pkField:              pkField,
+		version:              schemaVersionAllocator.Inc(),
 	}
 }
 
+// Version returns the process-local version of the schema,
+// it changes whenever the schema is reloaded into the meta cache.
+func (s *schemaInfo) Version() uint64 {
+	return s.version
+}
+
 func (s *schemaInfo) MapFieldID(name string) (int64, bool) {
 	return s.fieldMap.Get(name)
 }
@@ -154,6 +171,16 @@ func (s *schemaInfo) GetPkField() (*schemapb.FieldSchema, error) {
 	return s.pkField, nil
 }
 
+// GetShardHash returns the hash routing the primary keys of the collection to the shards,
+// it fails if the collection declares a shard hash not registered.
+func (s *schemaInfo) GetShardHash() (typeutil.ShardHash, error) {
+	hash, err := typeutil.GetShardHash(s.shardHashName)
+	if err != nil {
+		return nil, merr.WrapErrCollectionIllegalSchema(s.GetName(), err.Error())
+	}
+	return hash, nil
+}
+
 // partitionInfos contains the cached collection partition informations.
 type partitionInfos struct {
 	partitionInfos        []*partitionInfo
@@ -256,6 +283,7 @@ type MetaCache struct {
 	credMut        sync.RWMutex
 	privilegeMut   sync.RWMutex
 	shardMgr       shardClientMgr
+	notFound       *collectionNotFoundCache // collection names not found lately
 }
 
 // globalMetaCache is singleton instance of Cache
@@ -291,6 +319,7 @@ func NewMetaCache(rootCoord types.RootCoordClient, queryCoord types.QueryCoordCl
 		shardMgr:       shardMgr,
 		privilegeInfos: map[string]struct{}{},
 		userToRoles:    map[string]map[string]struct{}{},
+		notFound:       newCollectionNotFoundCache(),
 	}, nil
 }
 
@@ -308,10 +337,13 @@ func (m *MetaCache) GetCollectionID(ctx context.Context, database, collectionNam
 
 	method := "GetCollectionID"
 	if !ok || !collInfo.isCollectionCached() {
+		m.mu.RUnlock()
+		if err := m.getCollectionNotFound(database, collectionName); err != nil {
+			return 0, err
+		}
 		metrics.ProxyCacheStatsCounter.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), method, metrics.CacheMissLabel).Inc()
 		tr := timerecord.NewTimeRecorder("UpdateCache")
-		m.mu.RUnlock()
-		coll, err := m.describeCollection(ctx, database, collectionName, 0)
+		coll, err := m.describeCollectionByName(ctx, database, collectionName)
 		if err != nil {
 			return 0, err
 		}
@@ -452,10 +484,13 @@ func (m *MetaCache) GetCollectionSchema(ctx context.Context, database, collectio
 
 	method := "GetCollectionSchema"
 	if !ok || !collInfo.isCollectionCached() {
+		m.mu.RUnlock()
+		if err := m.getCollectionNotFound(database, collectionName); err != nil {
+			return nil, err
+		}
 		metrics.ProxyCacheStatsCounter.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), method, metrics.CacheMissLabel).Inc()
 		tr := timerecord.NewTimeRecorder("UpdateCache")
-		m.mu.RUnlock()
-		coll, err := m.describeCollection(ctx, database, collectionName, 0)
+		coll, err := m.describeCollectionByName(ctx, database, collectionName)
 		if err != nil {
 			log.Warn("Failed to load collection from rootcoord ",
 				zap.String("collection name ", collectionName),
@@ -479,6 +514,17 @@ func (m *MetaCache) GetCollectionSchema(ctx context.Context, database, collectio
 	return collInfo.schema, nil
 }
 
+func (m *MetaCache) GetCollectionSchemaVersion(database, collectionName string) (typeutil.UniqueID, uint64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	collInfo, ok := m.collInfo[database][collectionName]
+	if !ok || !collInfo.isCollectionCached() {
+		return 0, 0, false
+	}
+	return collInfo.collID, collInfo.schema.Version(), true
+}
+
 func (m *MetaCache) updateCollection(coll *milvuspb.DescribeCollectionResponse, database, collectionName string) {
 	_, dbOk := m.collInfo[database]
 	if !dbOk {
@@ -489,7 +535,9 @@ func (m *MetaCache) updateCollection(coll *milvuspb.DescribeCollectionResponse,
 	if !ok {
 		m.collInfo[database][collectionName] = &collectionInfo{}
 	}
-	m.collInfo[database][collectionName].schema = newSchemaInfo(coll.Schema)
+	schema := newSchemaInfo(coll.Schema)
+	schema.shardHashName = common.GetShardHashName(coll.GetProperties()...)
+	m.collInfo[database][collectionName].schema = schema
 	m.collInfo[database][collectionName].collID = coll.CollectionID
 	m.collInfo[database][collectionName].createdTimestamp = coll.CreatedTimestamp
 	m.collInfo[database][collectionName].createdUtcTimestamp = coll.CreatedUtcTimestamp
@@ -618,6 +666,7 @@ func (m *MetaCache) describeCollection(ctx context.Context, database, collection
 		CreatedUtcTimestamp:  coll.CreatedUtcTimestamp,
 		ConsistencyLevel:     coll.ConsistencyLevel,
 		DbName:               coll.GetDbName(),
+		Properties:           coll.GetProperties(),
 	}
 	for _, field := range coll.Schema.Fields {
 		if field.FieldID >= common.StartOfUserFieldID {
@@ -627,6 +676,33 @@ func (m *MetaCache) describeCollection(ctx context.Context, database, collection
 	return resp, nil
 }
 
+// getCollectionNotFound returns the error the collection was not found with lately, nil if none.
+func (m *MetaCache) getCollectionNotFound(database, collectionName string) error {
+	if m.notFound == nil {
+		return nil
+	}
+	err := m.notFound.get(database, collectionName)
+	label := metrics.CacheMissLabel
+	if err != nil {
+		label = metrics.CacheHitLabel
+	}
+	metrics.ProxyCacheStatsCounter.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), collectionNotFoundCacheName, label).Inc()
+	return err
+}
+
+// describeCollectionByName describes the collection from rootcoord, the collection not found is remembered for a while.
+func (m *MetaCache) describeCollectionByName(ctx context.Context, database, collectionName string) (*milvuspb.DescribeCollectionResponse, error) {
+	if m.notFound == nil {
+		return m.describeCollection(ctx, database, collectionName, 0)
+	}
+	version := m.notFound.getVersion()
+	coll, err := m.describeCollection(ctx, database, collectionName, 0)
+	if err != nil {
+		m.notFound.put(database, collectionName, err, version)
+	}
+	return coll, err
+}
+
 func (m *MetaCache) showPartitions(ctx context.Context, dbName string, collectionName string) (*milvuspb.ShowPartitionsResponse, error) {
 	req := &milvuspb.ShowPartitionsRequest{
 		Base: commonpbutil.NewMsgBase(
@@ -720,6 +796,9 @@ func (m *MetaCache) updatePartitions(partitions *milvuspb.ShowPartitionsResponse
 }
 
 func (m *MetaCache) RemoveCollection(ctx context.Context, database, collectionName string) {
+	if m.notFound != nil {
+		m.notFound.remove(database, collectionName)
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	_, dbOk := m.collInfo[database]
@@ -1060,6 +1139,9 @@ func (m *MetaCache) RefreshPolicyInfo(op typeutil.CacheOp) (err error) {
 }
 
 func (m *MetaCache) RemoveDatabase(ctx context.Context, database string) {
+	if m.notFound != nil {
+		m.notFound.removeDatabase(database)
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.collInfo, database)
@@ -0,0 +1,142 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+const collectionNotFoundCacheName = "CollectionNotFound"
+
+type collectionNotFoundKey struct {
+	database       string
+	collectionName string
+}
+
+type collectionNotFoundEntry struct {
+	err      error
+	expireAt time.Time
+}
+
+// collectionNotFoundCache remembers the collection names rootcoord reported not found for a short while,
+// so that the repeated lookups of a missing collection fail fast without describing it again and again.
+// The entries are removed once the collections are created, as rootcoord expires the meta cache of the names then.
+type collectionNotFoundCache struct {
+	mu      sync.Mutex
+	entries map[collectionNotFoundKey]collectionNotFoundEntry
+	// bumped on every removal, an error got by a describe issued before is not cached,
+	// as the collection may have been created in the meantime
+	version uint64
+}
+
+func newCollectionNotFoundCache() *collectionNotFoundCache {
+	return &collectionNotFoundCache{
+		entries: make(map[collectionNotFoundKey]collectionNotFoundEntry),
+	}
+}
+
+// getVersion returns the version to put the result of a describe issued after it.
+func (c *collectionNotFoundCache) getVersion() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// get returns the error the collection was not found with, nil if it's not cached or expired.
+func (c *collectionNotFoundCache) get(database, collectionName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := collectionNotFoundKey{database: database, collectionName: collectionName}
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !time.Now().Before(entry.expireAt) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry.err
+}
+
+// put caches err if it's the collection not found and nothing was removed since version,
+// the entries beyond proxy.collectionNotFoundCache.size are dropped.
+func (c *collectionNotFoundCache) put(database, collectionName string, err error, version uint64) {
+	if !errors.Is(err, merr.ErrCollectionNotFound) {
+		return
+	}
+	params := &paramtable.Get().ProxyCfg
+	size := params.CollectionNotFoundCacheSize.GetAsInt()
+	ttl := params.CollectionNotFoundCacheTTL.GetAsDuration(time.Millisecond)
+	if size <= 0 || ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return
+	}
+	key := collectionNotFoundKey{database: database, collectionName: collectionName}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= size {
+		c.purgeExpiredLocked()
+		if len(c.entries) >= size {
+			return
+		}
+	}
+	c.entries[key] = collectionNotFoundEntry{
+		err:      err,
+		expireAt: time.Now().Add(ttl),
+	}
+}
+
+func (c *collectionNotFoundCache) purgeExpiredLocked() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expireAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// remove removes the entry of the collection name.
+func (c *collectionNotFoundCache) remove(database, collectionName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	delete(c.entries, collectionNotFoundKey{database: database, collectionName: collectionName})
+}
+
+// removeDatabase removes the entries of the database.
+func (c *collectionNotFoundCache) removeDatabase(database string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	for key := range c.entries {
+		if key.database == database {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/milvus-io/milvus/internal/proto/querypb"
 	"github.com/milvus-io/milvus/internal/proto/rootcoordpb"
 	"github.com/milvus-io/milvus/internal/types"
+	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/util/crypto"
 	"github.com/milvus-io/milvus/pkg/util/funcutil"
 	"github.com/milvus-io/milvus/pkg/util/merr"
@@ -134,6 +135,19 @@ func (m *MockRootCoordClientInterface) DescribeCollection(ctx context.Context, i
 			DbName: dbName,
 		}, nil
 	}
+	if in.CollectionName == "shardHashCollection" {
+		return &milvuspb.DescribeCollectionResponse{
+			Status:       merr.Success(),
+			CollectionID: typeutil.UniqueID(4),
+			Schema: &schemapb.CollectionSchema{
+				Name: "shardHashCollection",
+			},
+			Properties: []*commonpb.KeyValuePair{
+				{Key: common.CollectionShardHashKey, Value: typeutil.Murmur3ShardHash},
+			},
+			DbName: dbName,
+		}, nil
+	}
 	if in.CollectionName == "errorCollection" {
 		return &milvuspb.DescribeCollectionResponse{
 			Status:       merr.Success(),
@@ -372,6 +386,143 @@ func TestMetaCache_GetNonExistCollection(t *testing.T) {
 	assert.Nil(t, schema)
 }
 
+func TestMetaCache_CollectionNotFoundCache(t *testing.T) {
+	ctx := context.Background()
+	params := paramtable.Get()
+	params.Save(params.ProxyCfg.CollectionNotFoundCacheTTL.Key, "100")
+	defer params.Reset(params.ProxyCfg.CollectionNotFoundCacheTTL.Key)
+
+	lookup := func(cache *MetaCache, collectionName string) {
+		_, err := cache.GetCollectionID(ctx, dbName, collectionName)
+		assert.ErrorIs(t, err, merr.ErrCollectionNotFound)
+		_, err = cache.GetCollectionSchema(ctx, dbName, collectionName)
+		assert.ErrorIs(t, err, merr.ErrCollectionNotFound)
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		rootCoord := &MockRootCoordClientInterface{}
+		cache, err := NewMetaCache(rootCoord, &mocks.MockQueryCoordClient{}, newShardClientMgr())
+		assert.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			lookup(cache, "collection3")
+		}
+		assert.Equal(t, 1, rootCoord.GetAccessCount())
+
+		// described again once expired
+		time.Sleep(150 * time.Millisecond)
+		lookup(cache, "collection3")
+		assert.Equal(t, 2, rootCoord.GetAccessCount())
+
+		// the other names and databases are not affected
+		lookup(cache, "collection4")
+		assert.Equal(t, 3, rootCoord.GetAccessCount())
+		_, err = cache.GetCollectionID(ctx, "db2", "collection3")
+		assert.ErrorIs(t, err, merr.ErrCollectionNotFound)
+		assert.Equal(t, 4, rootCoord.GetAccessCount())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		params.Save(params.ProxyCfg.CollectionNotFoundCacheSize.Key, "0")
+		defer params.Reset(params.ProxyCfg.CollectionNotFoundCacheSize.Key)
+
+		rootCoord := &MockRootCoordClientInterface{}
+		cache, err := NewMetaCache(rootCoord, &mocks.MockQueryCoordClient{}, newShardClientMgr())
+		assert.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			lookup(cache, "collection3")
+		}
+		assert.Equal(t, 20, rootCoord.GetAccessCount())
+	})
+
+	t.Run("invalidated", func(t *testing.T) {
+		rootCoord := &MockRootCoordClientInterface{}
+		cache, err := NewMetaCache(rootCoord, &mocks.MockQueryCoordClient{}, newShardClientMgr())
+		assert.NoError(t, err)
+
+		lookup(cache, "collection3")
+		assert.Equal(t, 1, rootCoord.GetAccessCount())
+
+		// notified by the creation
+		cache.RemoveCollection(ctx, dbName, "collection3")
+		lookup(cache, "collection3")
+		assert.Equal(t, 2, rootCoord.GetAccessCount())
+
+		cache.RemoveDatabase(ctx, dbName)
+		lookup(cache, "collection3")
+		assert.Equal(t, 3, rootCoord.GetAccessCount())
+	})
+
+	t.Run("not found only", func(t *testing.T) {
+		rootCoord := &MockRootCoordClientInterface{Error: true}
+		cache, err := NewMetaCache(rootCoord, &mocks.MockQueryCoordClient{}, newShardClientMgr())
+		assert.NoError(t, err)
+
+		_, err = cache.GetCollectionID(ctx, dbName, "collection1")
+		assert.Error(t, err)
+
+		rootCoord.Error = false
+		id, err := cache.GetCollectionID(ctx, dbName, "collection1")
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, id)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		params.Save(params.ProxyCfg.CollectionNotFoundCacheSize.Key, "2")
+		defer params.Reset(params.ProxyCfg.CollectionNotFoundCacheSize.Key)
+
+		rootCoord := &MockRootCoordClientInterface{}
+		cache, err := NewMetaCache(rootCoord, &mocks.MockQueryCoordClient{}, newShardClientMgr())
+		assert.NoError(t, err)
+
+		for _, name := range []string{"collection3", "collection4", "collection5"} {
+			lookup(cache, name)
+		}
+		assert.Equal(t, 4, rootCoord.GetAccessCount())
+		assert.Len(t, cache.notFound.entries, 2)
+
+		// the expired ones make room
+		time.Sleep(150 * time.Millisecond)
+		lookup(cache, "collection5")
+		assert.Equal(t, 5, rootCoord.GetAccessCount())
+		assert.Len(t, cache.notFound.entries, 1)
+	})
+
+	t.Run("removed while describing", func(t *testing.T) {
+		c := newCollectionNotFoundCache()
+		version := c.getVersion()
+		c.remove(dbName, "collection3")
+		c.put(dbName, "collection3", merr.WrapErrCollectionNotFound("collection3"), version)
+		assert.NoError(t, c.get(dbName, "collection3"))
+
+		c.put(dbName, "collection3", merr.WrapErrCollectionNotFound("collection3"), c.getVersion())
+		assert.ErrorIs(t, c.get(dbName, "collection3"), merr.ErrCollectionNotFound)
+	})
+}
+
+func TestMetaCache_GetShardHash(t *testing.T) {
+	ctx := context.Background()
+	rootCoord := &MockRootCoordClientInterface{}
+	cache, err := NewMetaCache(rootCoord, &mocks.MockQueryCoordClient{}, newShardClientMgr())
+	assert.NoError(t, err)
+
+	schema, err := cache.GetCollectionSchema(ctx, dbName, "shardHashCollection")
+	assert.NoError(t, err)
+	hash, err := schema.GetShardHash()
+	assert.NoError(t, err)
+	expected, _ := typeutil.GetShardHash(typeutil.Murmur3ShardHash)
+	assert.Equal(t, expected, hash)
+
+	// the legacy routing if not declared
+	schema, err = cache.GetCollectionSchema(ctx, dbName, "collection1")
+	assert.NoError(t, err)
+	hash, err = schema.GetShardHash()
+	assert.NoError(t, err)
+	expected, _ = typeutil.GetShardHash("")
+	assert.Equal(t, expected, hash)
+}
+
 func TestMetaCache_GetPartitionID(t *testing.T) {
 	ctx := context.Background()
 	rootCoord := &MockRootCoordClientInterface{}
@@ -762,6 +913,39 @@ func TestMetaCache_RemoveCollection(t *testing.T) {
 	assert.Equal(t, rootCoord.GetAccessCount(), 4)
 }
 
+func TestMetaCache_GetCollectionSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	rootCoord := &MockRootCoordClientInterface{}
+	queryCoord := &mocks.MockQueryCoordClient{}
+	shardMgr := newShardClientMgr()
+	err := InitMetaCache(ctx, rootCoord, queryCoord, shardMgr)
+	assert.NoError(t, err)
+
+	// not cached, and not loaded by the version check
+	_, _, ok := globalMetaCache.GetCollectionSchemaVersion(dbName, "collection1")
+	assert.False(t, ok)
+	assert.Equal(t, rootCoord.GetAccessCount(), 0)
+
+	schema, err := globalMetaCache.GetCollectionSchema(ctx, dbName, "collection1")
+	assert.NoError(t, err)
+	collectionID, version, ok := globalMetaCache.GetCollectionSchemaVersion(dbName, "collection1")
+	assert.True(t, ok)
+	assert.Equal(t, UniqueID(1), collectionID)
+	assert.Equal(t, schema.Version(), version)
+	assert.Equal(t, rootCoord.GetAccessCount(), 1)
+
+	// the version changes once the schema is reloaded
+	globalMetaCache.RemoveCollection(ctx, dbName, "collection1")
+	_, _, ok = globalMetaCache.GetCollectionSchemaVersion(dbName, "collection1")
+	assert.False(t, ok)
+	reloaded, err := globalMetaCache.GetCollectionSchema(ctx, dbName, "collection1")
+	assert.NoError(t, err)
+	_, version, ok = globalMetaCache.GetCollectionSchemaVersion(dbName, "collection1")
+	assert.True(t, ok)
+	assert.Equal(t, reloaded.Version(), version)
+	assert.NotEqual(t, schema.Version(), version)
+}
+
 func TestMetaCache_ExpireShardLeaderCache(t *testing.T) {
 	paramtable.Init()
 	paramtable.Get().Save(Params.ProxyCfg.ShardLeaderCacheInterval.Key, "1")
@@ -279,6 +279,66 @@ func (_c *MockCache_GetCollectionSchema_Call) RunAndReturn(run func(context.Cont
 	return _c
 }
 
+// GetCollectionSchemaVersion provides a mock function with given fields: database, collectionName
+func (_m *MockCache) GetCollectionSchemaVersion(database string, collectionName string) (int64, uint64, bool) {
+	ret := _m.Called(database, collectionName)
+
+	var r0 int64
+	var r1 uint64
+	var r2 bool
+	if rf, ok := ret.Get(0).(func(string, string) (int64, uint64, bool)); ok {
+		return rf(database, collectionName)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
+		r0 = rf(database, collectionName)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) uint64); ok {
+		r1 = rf(database, collectionName)
+	} else {
+		r1 = ret.Get(1).(uint64)
+	}
+
+	if rf, ok := ret.Get(2).(func(string, string) bool); ok {
+		r2 = rf(database, collectionName)
+	} else {
+		r2 = ret.Get(2).(bool)
+	}
+
+	return r0, r1, r2
+}
+
+// MockCache_GetCollectionSchemaVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCollectionSchemaVersion'
+type MockCache_GetCollectionSchemaVersion_Call struct {
+	*mock.Call
+}
+
+// GetCollectionSchemaVersion is a helper method to define mock.On call
+//   - database string
+//   - collectionName string
+func (_e *MockCache_Expecter) GetCollectionSchemaVersion(database interface{}, collectionName interface{}) *MockCache_GetCollectionSchemaVersion_Call {
+	return &MockCache_GetCollectionSchemaVersion_Call{Call: _e.mock.On("GetCollectionSchemaVersion", database, collectionName)}
+}
+
+func (_c *MockCache_GetCollectionSchemaVersion_Call) Run(run func(database string, collectionName string)) *MockCache_GetCollectionSchemaVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockCache_GetCollectionSchemaVersion_Call) Return(collectionID int64, version uint64, ok bool) *MockCache_GetCollectionSchemaVersion_Call {
+	_c.Call.Return(collectionID, version, ok)
+	return _c
+}
+
+func (_c *MockCache_GetCollectionSchemaVersion_Call) RunAndReturn(run func(string, string) (int64, uint64, bool)) *MockCache_GetCollectionSchemaVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetCredentialInfo provides a mock function with given fields: ctx, username
 func (_m *MockCache) GetCredentialInfo(ctx context.Context, username string) (*internalpb.CredentialInfo, error) {
 	ret := _m.Called(ctx, username)
@@ -3,6 +3,8 @@
 package proxy
 
 import (
+	time "time"
+
 	msgstream "github.com/milvus-io/milvus/pkg/mq/msgstream"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -74,6 +76,49 @@ func (_c *MockChannelsMgr_getChannels_Call) RunAndReturn(run func(int64) ([]stri
 	return _c
 }
 
+// getFailingDmlChannels provides a mock function with given fields:
+func (_m *MockChannelsMgr) getFailingDmlChannels() []failingDmlChannel {
+	ret := _m.Called()
+
+	var r0 []failingDmlChannel
+	if rf, ok := ret.Get(0).(func() []failingDmlChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]failingDmlChannel)
+		}
+	}
+
+	return r0
+}
+
+// MockChannelsMgr_getFailingDmlChannels_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'getFailingDmlChannels'
+type MockChannelsMgr_getFailingDmlChannels_Call struct {
+	*mock.Call
+}
+
+// getFailingDmlChannels is a helper method to define mock.On call
+func (_e *MockChannelsMgr_Expecter) getFailingDmlChannels() *MockChannelsMgr_getFailingDmlChannels_Call {
+	return &MockChannelsMgr_getFailingDmlChannels_Call{Call: _e.mock.On("getFailingDmlChannels")}
+}
+
+func (_c *MockChannelsMgr_getFailingDmlChannels_Call) Run(run func()) *MockChannelsMgr_getFailingDmlChannels_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockChannelsMgr_getFailingDmlChannels_Call) Return(_a0 []failingDmlChannel) *MockChannelsMgr_getFailingDmlChannels_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockChannelsMgr_getFailingDmlChannels_Call) RunAndReturn(run func() []failingDmlChannel) *MockChannelsMgr_getFailingDmlChannels_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // getOrCreateDmlStream provides a mock function with given fields: collectionID
 func (_m *MockChannelsMgr) getOrCreateDmlStream(collectionID int64) (msgstream.MsgStream, error) {
 	ret := _m.Called(collectionID)
@@ -247,6 +292,39 @@ func (_c *MockChannelsMgr_removeDMLStream_Call) RunAndReturn(run func(int64)) *M
 	return _c
 }
 
+// removeIdleDMLStream provides a mock function with given fields: ttl
+func (_m *MockChannelsMgr) removeIdleDMLStream(ttl time.Duration) {
+	_m.Called(ttl)
+}
+
+// MockChannelsMgr_removeIdleDMLStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'removeIdleDMLStream'
+type MockChannelsMgr_removeIdleDMLStream_Call struct {
+	*mock.Call
+}
+
+// removeIdleDMLStream is a helper method to define mock.On call
+//   - ttl time.Duration
+func (_e *MockChannelsMgr_Expecter) removeIdleDMLStream(ttl interface{}) *MockChannelsMgr_removeIdleDMLStream_Call {
+	return &MockChannelsMgr_removeIdleDMLStream_Call{Call: _e.mock.On("removeIdleDMLStream", ttl)}
+}
+
+func (_c *MockChannelsMgr_removeIdleDMLStream_Call) Run(run func(ttl time.Duration)) *MockChannelsMgr_removeIdleDMLStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *MockChannelsMgr_removeIdleDMLStream_Call) Return() *MockChannelsMgr_removeIdleDMLStream_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockChannelsMgr_removeIdleDMLStream_Call) RunAndReturn(run func(time.Duration)) *MockChannelsMgr_removeIdleDMLStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockChannelsMgr creates a new instance of MockChannelsMgr. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockChannelsMgr(t interface {
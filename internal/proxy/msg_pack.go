@@ -157,7 +157,7 @@ func repackInsertDataByPartition(ctx context.Context,
 
 func setMsgID(ctx context.Context,
 	msgs []msgstream.TsMsg,
-	idAllocator *allocator.IDAllocator,
+	idAllocator allocator.Interface,
 ) error {
 	var idBegin int64
 	var err error
@@ -180,9 +180,10 @@ func setMsgID(ctx context.Context,
 
 func repackInsertData(ctx context.Context,
 	channelNames []string,
+	shardHash typeutil.ShardHash,
 	insertMsg *msgstream.InsertMsg,
 	result *milvuspb.MutationResult,
-	idAllocator *allocator.IDAllocator,
+	idAllocator allocator.Interface,
 	segIDAssigner *segIDAssigner,
 ) (*msgstream.MsgPack, error) {
 	msgPack := &msgstream.MsgPack{
@@ -190,7 +191,7 @@ func repackInsertData(ctx context.Context,
 		EndTs:   insertMsg.EndTs(),
 	}
 
-	channel2RowOffsets := assignChannelsByPK(result.IDs, channelNames, insertMsg)
+	channel2RowOffsets := assignChannelsByPK(shardHash, result.IDs, channelNames, insertMsg)
 	for channel, rowOffsets := range channel2RowOffsets {
 		partitionName := insertMsg.PartitionName
 		msgs, err := repackInsertDataByPartition(ctx, partitionName, rowOffsets, channel, insertMsg, segIDAssigner)
@@ -219,10 +220,11 @@ func repackInsertData(ctx context.Context,
 
 func repackInsertDataWithPartitionKey(ctx context.Context,
 	channelNames []string,
+	shardHash typeutil.ShardHash,
 	partitionKeys *schemapb.FieldData,
 	insertMsg *msgstream.InsertMsg,
 	result *milvuspb.MutationResult,
-	idAllocator *allocator.IDAllocator,
+	idAllocator allocator.Interface,
 	segIDAssigner *segIDAssigner,
 ) (*msgstream.MsgPack, error) {
 	msgPack := &msgstream.MsgPack{
@@ -230,7 +232,7 @@ func repackInsertDataWithPartitionKey(ctx context.Context,
 		EndTs:   insertMsg.EndTs(),
 	}
 
-	channel2RowOffsets := assignChannelsByPK(result.IDs, channelNames, insertMsg)
+	channel2RowOffsets := assignChannelsByPK(shardHash, result.IDs, channelNames, insertMsg)
 	partitionNames, err := getDefaultPartitionNames(ctx, insertMsg.GetDbName(), insertMsg.CollectionName)
 	if err != nil {
 		log.Warn("get default partition names failed in partition key mode",
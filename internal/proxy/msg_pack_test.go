@@ -125,7 +125,7 @@ func TestRepackInsertData(t *testing.T) {
 		_ = fakeSegAllocator.Start()
 		defer fakeSegAllocator.Close()
 
-		_, err = repackInsertData(ctx, []string{"test_dml_channel"}, insertMsg,
+		_, err = repackInsertData(ctx, []string{"test_dml_channel"}, nil, insertMsg,
 			result, idAllocator, fakeSegAllocator)
 		assert.Error(t, err)
 	})
@@ -136,7 +136,7 @@ func TestRepackInsertData(t *testing.T) {
 	defer segAllocator.Close()
 
 	t.Run("repack insert data success", func(t *testing.T) {
-		_, err = repackInsertData(ctx, []string{"test_dml_channel"}, insertMsg, result, idAllocator, segAllocator)
+		_, err = repackInsertData(ctx, []string{"test_dml_channel"}, nil, insertMsg, result, idAllocator, segAllocator)
 		assert.NoError(t, err)
 	})
 }
@@ -230,7 +230,7 @@ func TestRepackInsertDataWithPartitionKey(t *testing.T) {
 
 	t.Run("repack insert data success", func(t *testing.T) {
 		partitionKeys := generateFieldData(schemapb.DataType_VarChar, testVarCharField, nb)
-		_, err = repackInsertDataWithPartitionKey(ctx, []string{"test_dml_channel"}, partitionKeys,
+		_, err = repackInsertDataWithPartitionKey(ctx, []string{"test_dml_channel"}, nil, partitionKeys,
 			insertMsg, result, idAllocator, segAllocator)
 		assert.NoError(t, err)
 	})
@@ -0,0 +1,177 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+const (
+	// mutationAuditLoggerName is the name of the logger the audit records are written to,
+	// so they could be routed apart from the debug logs.
+	mutationAuditLoggerName = "mutation.audit"
+	// mutationAuditExprMaxLen is the max length of the expr kept in an audit record, the hash covers the whole expr.
+	mutationAuditExprMaxLen = 256
+
+	mutationAuditOutcomeSuccess = "success"
+	mutationAuditOutcomeFailure = "failure"
+)
+
+// MutationAuditRecord is the audit record of a destructive operation.
+type MutationAuditRecord struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Operation   string    `json:"operation"`
+	User        string    `json:"user"`
+	Database    string    `json:"db"`
+	Collection  string    `json:"collection"`
+	Partition   string    `json:"partition,omitempty"`
+	Expr        string    `json:"expr"`
+	ExprHash    string    `json:"exprHash"`
+	RowsDeleted int64     `json:"rowsDeleted"`
+	Outcome     string    `json:"outcome"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// MutationAuditSink receives the audit records of proxy.
+// Write is called on the request path, it must not block.
+type MutationAuditSink interface {
+	Write(record *MutationAuditRecord)
+}
+
+var (
+	globalMutationAuditSink     MutationAuditSink
+	globalMutationAuditSinkOnce sync.Once
+	globalMutationAuditSinkMu   sync.RWMutex
+)
+
+// SetMutationAuditSink replaces the sink the audit records of proxy are written to.
+func SetMutationAuditSink(sink MutationAuditSink) {
+	getMutationAuditSink()
+	globalMutationAuditSinkMu.Lock()
+	defer globalMutationAuditSinkMu.Unlock()
+	globalMutationAuditSink = sink
+}
+
+// getMutationAuditSink returns the sink of proxy, which writes to the mutation.audit logger by default.
+func getMutationAuditSink() MutationAuditSink {
+	globalMutationAuditSinkOnce.Do(func() {
+		size := getDeleteParamInt64(&paramtable.Get().ProxyCfg.MutationAuditBufferSize)
+		globalMutationAuditSink = newLogMutationAuditSink(int(size), log.L().Named(mutationAuditLoggerName))
+	})
+	globalMutationAuditSinkMu.RLock()
+	defer globalMutationAuditSinkMu.RUnlock()
+	return globalMutationAuditSink
+}
+
+// auditDelete writes the audit record of a completed delete if proxy.mutationAudit.enabled is set,
+// the deletes are sampled by proxy.mutationAudit.sampleRate.
+func auditDelete(ctx context.Context, req *milvuspb.DeleteRequest, rowsDeleted int64, err error) {
+	params := &paramtable.Get().ProxyCfg
+	if !getDeleteParamBool(&params.MutationAuditEnabled) {
+		return
+	}
+	if rate := getDeleteParamFloat64(&params.MutationAuditSampleRate); rate < 1 && rand.Float64() >= rate {
+		return
+	}
+	getMutationAuditSink().Write(newDeleteAuditRecord(ctx, req, rowsDeleted, err))
+}
+
+func newDeleteAuditRecord(ctx context.Context, req *milvuspb.DeleteRequest, rowsDeleted int64, err error) *MutationAuditRecord {
+	user, _ := GetCurUserFromContext(ctx)
+	db := req.GetDbName()
+	if db == "" {
+		db = GetCurDBNameFromContextOrDefault(ctx)
+	}
+	expr := req.GetExpr()
+	hash := sha256.Sum256([]byte(expr))
+	if len(expr) > mutationAuditExprMaxLen {
+		expr = expr[:mutationAuditExprMaxLen]
+	}
+	record := &MutationAuditRecord{
+		Timestamp:   time.Now(),
+		Operation:   "delete",
+		User:        user,
+		Database:    db,
+		Collection:  req.GetCollectionName(),
+		Partition:   req.GetPartitionName(),
+		Expr:        expr,
+		ExprHash:    hex.EncodeToString(hash[:]),
+		RowsDeleted: rowsDeleted,
+		Outcome:     mutationAuditOutcomeSuccess,
+	}
+	if err != nil {
+		record.Outcome = mutationAuditOutcomeFailure
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// logMutationAuditSink writes the audit records as JSON lines to a logger asynchronously,
+// the records are dropped rather than waited for if the buffer is full.
+type logMutationAuditSink struct {
+	logger  *zap.Logger
+	records chan *MutationAuditRecord
+	dropped *atomic.Int64
+}
+
+func newLogMutationAuditSink(bufferSize int, logger *zap.Logger) *logMutationAuditSink {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	sink := &logMutationAuditSink{
+		logger:  logger,
+		records: make(chan *MutationAuditRecord, bufferSize),
+		dropped: atomic.NewInt64(0),
+	}
+	go sink.loop()
+	return sink
+}
+
+func (s *logMutationAuditSink) Write(record *MutationAuditRecord) {
+	select {
+	case s.records <- record:
+	default:
+		s.dropped.Inc()
+		metrics.ProxyMutationAuditDroppedCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10)).Inc()
+	}
+}
+
+func (s *logMutationAuditSink) loop() {
+	for record := range s.records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			log.Warn("failed to marshal mutation audit record", zap.Error(err))
+			continue
+		}
+		s.logger.Info(string(line))
+	}
+}
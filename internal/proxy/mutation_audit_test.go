@@ -0,0 +1,180 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus/pkg/util"
+	"github.com/milvus-io/milvus/pkg/util/crypto"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// mockMutationAuditSink collects the records written.
+type mockMutationAuditSink struct {
+	mu      sync.Mutex
+	records []*MutationAuditRecord
+}
+
+func (s *mockMutationAuditSink) Write(record *MutationAuditRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+}
+
+func (s *mockMutationAuditSink) list() []*MutationAuditRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records
+}
+
+func TestMutationAudit(t *testing.T) {
+	paramtable.Init()
+	params := paramtable.Get()
+
+	md := metadata.Pairs(util.HeaderAuthorize, crypto.Base64Encode("alice:passwd"))
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	req := &milvuspb.DeleteRequest{
+		CollectionName: "collection",
+		PartitionName:  "partition",
+		Expr:           "pk in [" + strings.Repeat("1, ", 200) + "1]",
+	}
+
+	t.Run("record", func(t *testing.T) {
+		record := newDeleteAuditRecord(ctx, req, 10, nil)
+		assert.Equal(t, "delete", record.Operation)
+		assert.Equal(t, "alice", record.User)
+		assert.Equal(t, "default", record.Database)
+		assert.Equal(t, "collection", record.Collection)
+		assert.Equal(t, "partition", record.Partition)
+		assert.Len(t, record.Expr, mutationAuditExprMaxLen)
+		assert.True(t, strings.HasPrefix(req.GetExpr(), record.Expr))
+		assert.Len(t, record.ExprHash, 64)
+		assert.EqualValues(t, 10, record.RowsDeleted)
+		assert.Equal(t, mutationAuditOutcomeSuccess, record.Outcome)
+		assert.Empty(t, record.Error)
+		assert.WithinDuration(t, time.Now(), record.Timestamp, time.Minute)
+
+		// the hash tells the exprs apart even if their truncations are the same
+		other := newDeleteAuditRecord(ctx, &milvuspb.DeleteRequest{Expr: req.GetExpr() + " or pk == 2"}, 0, nil)
+		assert.Equal(t, record.Expr, other.Expr)
+		assert.NotEqual(t, record.ExprHash, other.ExprHash)
+
+		record = newDeleteAuditRecord(context.Background(), &milvuspb.DeleteRequest{DbName: "db"}, 3, errors.New("mock error"))
+		assert.Empty(t, record.User)
+		assert.Equal(t, "db", record.Database)
+		assert.EqualValues(t, 3, record.RowsDeleted)
+		assert.Equal(t, mutationAuditOutcomeFailure, record.Outcome)
+		assert.Equal(t, "mock error", record.Error)
+	})
+
+	t.Run("written as json lines", func(t *testing.T) {
+		core, logs := observer.New(zapcore.InfoLevel)
+		sink := newLogMutationAuditSink(16, zap.New(core).Named(mutationAuditLoggerName))
+		sink.Write(newDeleteAuditRecord(ctx, req, 10, nil))
+		sink.Write(newDeleteAuditRecord(ctx, req, 3, errors.New("mock error")))
+
+		assert.Eventually(t, func() bool {
+			return logs.Len() == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		entries := logs.All()
+		assert.Equal(t, mutationAuditLoggerName, entries[0].LoggerName)
+
+		var record MutationAuditRecord
+		require.NoError(t, json.Unmarshal([]byte(entries[0].Message), &record))
+		assert.Equal(t, "alice", record.User)
+		assert.Equal(t, "collection", record.Collection)
+		assert.EqualValues(t, 10, record.RowsDeleted)
+		assert.Equal(t, mutationAuditOutcomeSuccess, record.Outcome)
+		require.NoError(t, json.Unmarshal([]byte(entries[1].Message), &record))
+		assert.EqualValues(t, 3, record.RowsDeleted)
+		assert.Equal(t, mutationAuditOutcomeFailure, record.Outcome)
+		assert.Equal(t, "mock error", record.Error)
+	})
+
+	t.Run("dropped if buffer full", func(t *testing.T) {
+		unblock := make(chan struct{})
+		core, logs := observer.New(zapcore.InfoLevel)
+		// the writer is stuck at the first record
+		blocking := zapcore.RegisterHooks(core, func(zapcore.Entry) error {
+			<-unblock
+			return nil
+		})
+		sink := newLogMutationAuditSink(2, zap.New(blocking))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 10; i++ {
+				sink.Write(newDeleteAuditRecord(ctx, req, int64(i), nil))
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("write is blocked by the sink")
+		}
+		// one is being written, two are buffered
+		assert.GreaterOrEqual(t, sink.dropped.Load(), int64(7))
+
+		close(unblock)
+		assert.Eventually(t, func() bool {
+			return int64(logs.Len())+sink.dropped.Load() == 10
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("enabled and sampled", func(t *testing.T) {
+		sink := &mockMutationAuditSink{}
+		SetMutationAuditSink(sink)
+		defer SetMutationAuditSink(newLogMutationAuditSink(16, zap.NewNop()))
+
+		auditDelete(ctx, req, 1, nil)
+		assert.Empty(t, sink.list())
+
+		params.Save(params.ProxyCfg.MutationAuditEnabled.Key, "true")
+		defer params.Reset(params.ProxyCfg.MutationAuditEnabled.Key)
+		auditDelete(ctx, req, 1, nil)
+		assert.Len(t, sink.list(), 1)
+
+		params.Save(params.ProxyCfg.MutationAuditSampleRate.Key, "0")
+		defer params.Reset(params.ProxyCfg.MutationAuditSampleRate.Key)
+		for i := 0; i < 100; i++ {
+			auditDelete(ctx, req, 1, nil)
+		}
+		assert.Len(t, sink.list(), 1)
+
+		params.Save(params.ProxyCfg.MutationAuditSampleRate.Key, "0.5")
+		for i := 0; i < 1000; i++ {
+			auditDelete(ctx, req, 1, nil)
+		}
+		assert.InDelta(t, 501, len(sink.list()), 150)
+	})
+}
@@ -0,0 +1,180 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/allocator"
+	"github.com/milvus-io/milvus/pkg/log"
+)
+
+// make sure prefetchIDAllocator implements allocator.Interface.
+var _ allocator.Interface = (*prefetchIDAllocator)(nil)
+
+// idRange is the ids [start, end) reserved.
+type idRange struct {
+	start UniqueID
+	end   UniqueID
+}
+
+// prefetchIDAllocator allocates the ids reserved from the inner allocator locally,
+// the reservation is sized to cover the allocations of proxy.idPrefetch.window at the recent allocation rate,
+// and refilled asynchronously before exhaustion.
+// The ids allocated are unique and monotonically increasing, the ids reserved but not allocated are abandoned.
+type prefetchIDAllocator struct {
+	inner allocator.Interface
+
+	// serializes the allocations from the inner allocator, so that the reserved ranges are in ascending order
+	fetchMu sync.Mutex
+
+	mu       sync.Mutex
+	reserved []idRange
+	fetching bool
+	// the smoothed allocation rate in ids per ms, sampled every window
+	rate        float64
+	sampleStart time.Time
+	sampleCount int64
+}
+
+func newPrefetchIDAllocator(inner allocator.Interface) *prefetchIDAllocator {
+	return &prefetchIDAllocator{
+		inner:       inner,
+		sampleStart: time.Now(),
+	}
+}
+
+// AllocOne allocates one id.
+func (a *prefetchIDAllocator) AllocOne() (UniqueID, error) {
+	start, _, err := a.Alloc(1)
+	return start, err
+}
+
+// Alloc allocates the ids [start, end) of the count number.
+func (a *prefetchIDAllocator) Alloc(count uint32) (UniqueID, UniqueID, error) {
+	if Params.ProxyCfg.IDPrefetchMaxCount.GetAsInt64() <= 0 {
+		return a.inner.Alloc(count)
+	}
+
+	a.mu.Lock()
+	a.sampleCount += int64(count)
+	if start, end, ok := a.allocLocked(count); ok {
+		a.prefetchLocked()
+		a.mu.Unlock()
+		return start, end, nil
+	}
+	a.mu.Unlock()
+
+	// the reservation is exhausted, refill it synchronously
+	a.fetchMu.Lock()
+	defer a.fetchMu.Unlock()
+
+	a.mu.Lock()
+	// refilled by the others while waiting
+	if start, end, ok := a.allocLocked(count); ok {
+		a.mu.Unlock()
+		return start, end, nil
+	}
+	size := a.windowSizeLocked()
+	a.mu.Unlock()
+
+	if size < int64(count) {
+		size = int64(count)
+	}
+	start, end, err := a.inner.Alloc(uint32(size))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reserved = append(a.reserved, idRange{start: start, end: end})
+	start, end, _ = a.allocLocked(count)
+	return start, end, nil
+}
+
+// allocLocked allocates the ids from the first reserved range enough for them,
+// the ranges before it are abandoned to keep the ids monotonic.
+func (a *prefetchIDAllocator) allocLocked(count uint32) (UniqueID, UniqueID, bool) {
+	for len(a.reserved) > 0 {
+		r := &a.reserved[0]
+		if r.end-r.start >= int64(count) {
+			start := r.start
+			r.start += int64(count)
+			return start, r.start, true
+		}
+		a.reserved = a.reserved[1:]
+	}
+	return 0, 0, false
+}
+
+// windowSizeLocked returns the number of ids to cover the allocations of the window.
+func (a *prefetchIDAllocator) windowSizeLocked() int64 {
+	window := Params.ProxyCfg.IDPrefetchWindow.GetAsDuration(time.Millisecond)
+	if elapsed := time.Since(a.sampleStart); elapsed >= window && elapsed > 0 {
+		rate := float64(a.sampleCount) / (float64(elapsed) / float64(time.Millisecond))
+		a.rate = (a.rate + rate) / 2
+		a.sampleStart = time.Now()
+		a.sampleCount = 0
+	}
+
+	size := int64(a.rate * float64(window.Milliseconds()))
+	if minCount := Params.ProxyCfg.IDPrefetchMinCount.GetAsInt64(); size < minCount {
+		size = minCount
+	}
+	if maxCount := Params.ProxyCfg.IDPrefetchMaxCount.GetAsInt64(); size > maxCount {
+		size = maxCount
+	}
+	return size
+}
+
+// prefetchLocked refills the reservation asynchronously once less than half of the window is left.
+func (a *prefetchIDAllocator) prefetchLocked() {
+	if a.fetching {
+		return
+	}
+	size := a.windowSizeLocked()
+	if size <= 0 {
+		return
+	}
+	remaining := int64(0)
+	for _, r := range a.reserved {
+		remaining += r.end - r.start
+	}
+	if remaining > size/2 {
+		return
+	}
+
+	a.fetching = true
+	go func() {
+		a.fetchMu.Lock()
+		defer a.fetchMu.Unlock()
+		start, end, err := a.inner.Alloc(uint32(size))
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.fetching = false
+		if err != nil {
+			log.Warn("failed to prefetch ids", zap.Int64("count", size), zap.Error(err))
+			return
+		}
+		a.reserved = append(a.reserved, idRange{start: start, end: end})
+	}()
+}
@@ -0,0 +1,200 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/internal/allocator"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// mockCountingIDAllocator allocates the ids in ascending order, and counts the allocations.
+type mockCountingIDAllocator struct {
+	mu    sync.Mutex
+	next  UniqueID
+	calls int
+	err   error
+}
+
+func (m *mockCountingIDAllocator) Alloc(count uint32) (UniqueID, UniqueID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.err != nil {
+		return 0, 0, m.err
+	}
+	start := m.next
+	m.next += int64(count)
+	return start, m.next, nil
+}
+
+func (m *mockCountingIDAllocator) AllocOne() (UniqueID, error) {
+	start, _, err := m.Alloc(1)
+	return start, err
+}
+
+func (m *mockCountingIDAllocator) getCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+func TestPrefetchIDAllocator(t *testing.T) {
+	paramtable.Init()
+	params := paramtable.Get()
+
+	t.Run("allocate", func(t *testing.T) {
+		params.Save(params.ProxyCfg.IDPrefetchMinCount.Key, "100")
+		defer params.Reset(params.ProxyCfg.IDPrefetchMinCount.Key)
+
+		inner := &mockCountingIDAllocator{next: 1}
+		a := newPrefetchIDAllocator(inner)
+		last := UniqueID(0)
+		for i := 0; i < 1000; i++ {
+			id, err := a.AllocOne()
+			assert.NoError(t, err)
+			assert.Greater(t, id, last)
+			last = id
+		}
+		start, end, err := a.Alloc(500)
+		assert.NoError(t, err)
+		assert.Greater(t, start, last)
+		assert.EqualValues(t, 500, end-start)
+
+		// the ids are prefetched rather than allocated one by one
+		assert.Less(t, inner.getCalls(), 100)
+	})
+
+	t.Run("prefetch disabled", func(t *testing.T) {
+		params.Save(params.ProxyCfg.IDPrefetchMaxCount.Key, "0")
+		defer params.Reset(params.ProxyCfg.IDPrefetchMaxCount.Key)
+
+		inner := &mockCountingIDAllocator{next: 1}
+		a := newPrefetchIDAllocator(inner)
+		for i := 0; i < 10; i++ {
+			id, err := a.AllocOne()
+			assert.NoError(t, err)
+			assert.EqualValues(t, i+1, id)
+		}
+		assert.Equal(t, 10, inner.getCalls())
+	})
+
+	t.Run("window bounds", func(t *testing.T) {
+		params.Save(params.ProxyCfg.IDPrefetchWindow.Key, "10")
+		defer params.Reset(params.ProxyCfg.IDPrefetchWindow.Key)
+		params.Save(params.ProxyCfg.IDPrefetchMinCount.Key, "10")
+		defer params.Reset(params.ProxyCfg.IDPrefetchMinCount.Key)
+		params.Save(params.ProxyCfg.IDPrefetchMaxCount.Key, "1000")
+		defer params.Reset(params.ProxyCfg.IDPrefetchMaxCount.Key)
+
+		a := newPrefetchIDAllocator(&mockCountingIDAllocator{next: 1})
+		a.mu.Lock()
+		assert.EqualValues(t, 10, a.windowSizeLocked())
+		a.sampleStart = time.Now().Add(-10 * time.Millisecond)
+		a.sampleCount = 1000000
+		assert.EqualValues(t, 1000, a.windowSizeLocked())
+		a.mu.Unlock()
+	})
+
+	t.Run("allocate failed", func(t *testing.T) {
+		inner := &mockCountingIDAllocator{next: 1, err: errors.New("mock error")}
+		a := newPrefetchIDAllocator(inner)
+		_, err := a.AllocOne()
+		assert.Error(t, err)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		params.Save(params.ProxyCfg.IDPrefetchMinCount.Key, "10")
+		defer params.Reset(params.ProxyCfg.IDPrefetchMinCount.Key)
+
+		a := newPrefetchIDAllocator(&mockCountingIDAllocator{next: 1})
+		concurrency, allocations := 16, 1000
+		ids := make([][]UniqueID, concurrency)
+		wg := sync.WaitGroup{}
+		for i := 0; i < concurrency; i++ {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < allocations; j++ {
+					count := uint32(rand.Intn(20) + 1)
+					start, end, err := a.Alloc(count)
+					assert.NoError(t, err)
+					assert.EqualValues(t, count, end-start)
+					for id := start; id < end; id++ {
+						ids[i] = append(ids[i], id)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		// the ids are unique, and monotonically increasing in each goroutine
+		allocated := make(map[UniqueID]struct{})
+		for _, goroutineIDs := range ids {
+			for j, id := range goroutineIDs {
+				if j > 0 {
+					assert.Greater(t, id, goroutineIDs[j-1])
+				}
+				_, ok := allocated[id]
+				assert.False(t, ok)
+				allocated[id] = struct{}{}
+			}
+		}
+	})
+}
+
+func BenchmarkPrefetchIDAllocator(b *testing.B) {
+	paramtable.Init()
+	ctx := context.Background()
+	idAllocator, err := allocator.NewIDAllocator(ctx, NewRootCoordMock(), paramtable.GetNodeID())
+	require.NoError(b, err)
+	require.NoError(b, idAllocator.Start())
+	defer idAllocator.Close()
+
+	b.Run("per call", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_, err := idAllocator.AllocOne()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+
+	b.Run("prefetch", func(b *testing.B) {
+		a := newPrefetchIDAllocator(idAllocator)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_, err := a.AllocOne()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
@@ -99,8 +99,10 @@ type Proxy struct {
 	chTicker channelsTimeTicker
 
 	rowIDAllocator *allocator.IDAllocator
-	tsoAllocator   *timestampAllocator
-	segAssigner    *segIDAssigner
+	// allocates the ids prefetched from rowIDAllocator
+	idAllocator  allocator.Interface
+	tsoAllocator *timestampAllocator
+	segAssigner  *segIDAssigner
 
 	metricsCacheManager *metricsinfo.MetricsCacheManager
 
@@ -237,6 +239,7 @@ func (node *Proxy) Init() error {
 		return err
 	}
 	node.rowIDAllocator = idAllocator
+	node.idAllocator = newPrefetchIDAllocator(idAllocator)
 	log.Debug("create id allocator done", zap.String("role", typeutil.ProxyRole), zap.Int64("ProxyID", paramtable.GetNodeID()))
 
 	tsoAllocator, err := newTimestampAllocator(node.rootCoord, paramtable.GetNodeID())
@@ -383,6 +386,30 @@ func (node *Proxy) sendChannelsTimeTickLoop() {
 	}()
 }
 
+// sweepIdleDmlStreamLoop starts a goroutine that closes the dml streams idle longer than proxy.dmlStream.idleTTL.
+func (node *Proxy) sweepIdleDmlStreamLoop() {
+	node.wg.Add(1)
+	go func() {
+		defer node.wg.Done()
+
+		ticker := time.NewTicker(Params.ProxyCfg.DmlStreamSweepInterval.GetAsDuration(time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-node.ctx.Done():
+				log.Info("sweep idle dml stream loop exit")
+				return
+			case <-ticker.C:
+				ttl := Params.ProxyCfg.DmlStreamIdleTTL.GetAsDuration(time.Second)
+				if ttl <= 0 {
+					continue
+				}
+				node.chMgr.removeIdleDMLStream(ttl)
+			}
+		}
+	}()
+}
+
 // Start starts a proxy node.
 func (node *Proxy) Start() error {
 	if err := node.sched.Start(); err != nil {
@@ -410,6 +437,7 @@ func (node *Proxy) Start() error {
 	log.Debug("start channels time ticker done", zap.String("role", typeutil.ProxyRole))
 
 	node.sendChannelsTimeTickLoop()
+	node.sweepIdleDmlStreamLoop()
 
 	// Start callbacks
 	for _, cb := range node.startCallbacks {
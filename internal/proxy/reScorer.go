@@ -3,16 +3,31 @@ package proxy
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/conc"
 	"github.com/milvus-io/milvus/pkg/util/funcutil"
+	"github.com/milvus-io/milvus/pkg/util/hardware"
 	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/metric"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 type rankType int
@@ -22,65 +37,1194 @@ const (
 	rrfRankType                      // rrfRankType = 1
 	weightedRankType                 // weightedRankType = 2
 	udfExprRankType                  // udfExprRankType = 3
+	maxRankType                      // maxRankType = 4
+	minRankType                      // minRankType = 5
+	bordaRankType                    // bordaRankType = 6
+	decayRankType                    // decayRankType = 7
+	boostRankType                    // boostRankType = 8
+	sigmoidRankType                  // sigmoidRankType = 9
+	multiplyRankType                 // multiplyRankType = 10
 )
 
+// rankTypeMap maps the names of the built-in rank types to them, the rank types registered by the others are invalidRankType,
+// see RegisterScorerFactory
 var rankTypeMap = map[string]rankType{
 	"invalid":  invalidRankType,
 	"rrf":      rrfRankType,
 	"weighted": weightedRankType,
 	"expr":     udfExprRankType,
+	"max":      maxRankType,
+	"min":      minRankType,
+	"borda":    bordaRankType,
+	"decay":    decayRankType,
+	"boost":    boostRankType,
+	"sigmoid":  sigmoidRankType,
+	"multiply": multiplyRankType,
 }
 
 type reScorer interface {
 	name() string
 	scorerType() rankType
-	reScore(input *milvuspb.SearchResults)
+	reScore(input *milvuspb.SearchResults) error
+	setBounds(bounds *scoreBounds)
+	// boundScores bounds the scores rescored, see scoreBounds
+	boundScores(input *milvuspb.SearchResults) ([]int64, error)
 }
 
 type baseScorer struct {
 	scorerName string
+	bounds     *scoreBounds
 }
 
 func (bs *baseScorer) name() string {
 	return bs.scorerName
 }
 
+func (bs *baseScorer) setBounds(bounds *scoreBounds) {
+	bs.bounds = bounds
+}
+
+func (bs *baseScorer) boundScores(input *milvuspb.SearchResults) ([]int64, error) {
+	if bs.bounds == nil {
+		return nil, nil
+	}
+	return bs.bounds.bound(input.GetResults())
+}
+
 type rrfScorer struct {
 	baseScorer
 	k float32
+	// k of each query of the request if set, which wins over k
+	queryKs []float32
+}
+
+func (rs *rrfScorer) reScore(input *milvuspb.SearchResults) error {
+	buckets, err := rankBuckets(input.GetResults())
+	if err != nil {
+		return err
+	}
+	// the query of each result, to pick k of the query
+	var queries []int
+	if rs.queryKs != nil {
+		topks := input.GetResults().GetTopks()
+		if len(topks) != len(rs.queryKs) {
+			return merr.WrapErrServiceInternal(fmt.Sprintf("%d queries of the search results mismatch with %d k of rrf", len(topks), len(rs.queryKs)))
+		}
+		queries = make([]int, 0, len(input.GetResults().GetScores()))
+		for query, topk := range topks {
+			for j := int64(0); j < topk; j++ {
+				queries = append(queries, query)
+			}
+		}
+	}
+	// the ranks are reset by each query, or each group of it
+	scores := input.Results.GetScores()
+	return runChunks(len(buckets), len(scores), func(chunk int) error {
+		bucket := buckets[chunk]
+		k := rs.k
+		if len(bucket) > 0 && queries != nil {
+			k = rs.queryKs[queries[bucket[0]]]
+		}
+		for rank, i := range bucket {
+			scores[i] = 1 / (k + float32(rank+1))
+		}
+		return nil
+	})
+}
+
+// parallelReScoreThreshold is the number of the results of a request over which they're rescored by the chunks
+// in parallel, see runChunks
+var parallelReScoreThreshold = 16384
+
+var (
+	reScorePool     *conc.Pool[struct{}]
+	reScorePoolOnce sync.Once
+)
+
+func getReScorePool() *conc.Pool[struct{}] {
+	reScorePoolOnce.Do(func() {
+		reScorePool = conc.NewPool[struct{}](hardware.GetCPUNum())
+	})
+	return reScorePool
+}
+
+// runChunks calls fn with each of the n chunks of the results, e.g. the queries, serially if the total results are not more than
+// parallelReScoreThreshold, or in parallel by the pool of rescoring otherwise. fn must only touch the results of its chunk,
+// in the same order either way, so the scores are bit-identical however they're run.
+func runChunks(n int, total int, fn func(chunk int) error) error {
+	if total <= parallelReScoreThreshold || n < 2 {
+		for chunk := 0; chunk < n; chunk++ {
+			if err := fn(chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	pool := getReScorePool()
+	futures := make([]*conc.Future[struct{}], n)
+	for chunk := 0; chunk < n; chunk++ {
+		chunk := chunk
+		futures[chunk] = pool.Submit(func() (struct{}, error) {
+			return struct{}{}, fn(chunk)
+		})
+	}
+	return conc.AwaitAll(futures...)
+}
+
+// forEachQuery calls fn with the scores of each query of the results and the offset of them, see runChunks
+func forEachQuery(results *schemapb.SearchResultData, fn func(offset int, scores []float32) error) error {
+	queries, err := queryScores(results)
+	if err != nil {
+		return err
+	}
+	offsets := make([]int, len(queries))
+	for i := 1; i < len(queries); i++ {
+		offsets[i] = offsets[i-1] + len(queries[i-1])
+	}
+	return runChunks(len(queries), len(results.GetScores()), func(chunk int) error {
+		return fn(offsets[chunk], queries[chunk])
+	})
+}
+
+// queryScores splits the scores of the results by the queries by the topks of them, which are of a single query if no topks,
+// the scores of each query are ordered, so the position in the slice is the rank of a result of the query.
+func queryScores(results *schemapb.SearchResultData) ([][]float32, error) {
+	scores := results.GetScores()
+	if len(results.GetTopks()) == 0 {
+		return [][]float32{scores}, nil
+	}
+	queries := make([][]float32, 0, len(results.GetTopks()))
+	start := int64(0)
+	for _, topk := range results.GetTopks() {
+		if topk < 0 || start+topk > int64(len(scores)) {
+			break
+		}
+		queries = append(queries, scores[start:start+topk])
+		start += topk
+	}
+	if start != int64(len(scores)) || len(queries) != len(results.GetTopks()) {
+		return nil, merr.WrapErrServiceInternal(fmt.Sprintf("the topks %v of the search results mismatch with %d scores", results.GetTopks(), len(scores)))
+	}
+	return queries, nil
+}
+
+func (rs *rrfScorer) scorerType() rankType {
+	return rrfRankType
+}
+
+// rankBuckets returns the indexes of the results of each query in order, so the position of an index in its bucket is the rank
+// of the result. The results of each query are bucketed by the groups further if grouped by a field, see GroupByFieldValue,
+// where the results of the groups interleave, e.g. the second result of a group is ranked 2nd even if it's the 5th of the query.
+func rankBuckets(results *schemapb.SearchResultData) ([][]int, error) {
+	queries, err := queryScores(results)
+	if err != nil {
+		return nil, err
+	}
+	var groups []interface{}
+	if results.GetGroupByFieldValue() != nil {
+		groups, err = groupByValues(results.GetGroupByFieldValue())
+		if err != nil {
+			return nil, err
+		}
+		if len(groups) != len(results.GetScores()) {
+			return nil, merr.WrapErrServiceInternal(fmt.Sprintf("the %d group by values of the search results mismatch with %d scores", len(groups), len(results.GetScores())))
+		}
+	}
+
+	buckets := make([][]int, 0, len(queries))
+	start := 0
+	for _, scores := range queries {
+		if groups == nil {
+			bucket := make([]int, len(scores))
+			for i := range bucket {
+				bucket[i] = start + i
+			}
+			buckets = append(buckets, bucket)
+			start += len(scores)
+			continue
+		}
+		// the index of the bucket of each group of the query
+		indexes := make(map[interface{}]int)
+		for i := start; i < start+len(scores); i++ {
+			index, ok := indexes[groups[i]]
+			if !ok {
+				index = len(buckets)
+				indexes[groups[i]] = index
+				buckets = append(buckets, nil)
+			}
+			buckets[index] = append(buckets[index], i)
+		}
+		start += len(scores)
+	}
+	return buckets, nil
+}
+
+// groupByValues returns the values of the group by field of the results, which are of the types could be grouped by.
+func groupByValues(fieldData *schemapb.FieldData) ([]interface{}, error) {
+	var values []interface{}
+	setValues := func(n int, get func(int) interface{}) {
+		values = make([]interface{}, n)
+		for i := range values {
+			values[i] = get(i)
+		}
+	}
+	scalars := fieldData.GetScalars()
+	switch fieldData.GetType() {
+	case schemapb.DataType_Bool:
+		data := scalars.GetBoolData().GetData()
+		setValues(len(data), func(i int) interface{} { return data[i] })
+	case schemapb.DataType_Int8, schemapb.DataType_Int16, schemapb.DataType_Int32:
+		data := scalars.GetIntData().GetData()
+		setValues(len(data), func(i int) interface{} { return data[i] })
+	case schemapb.DataType_Int64:
+		data := scalars.GetLongData().GetData()
+		setValues(len(data), func(i int) interface{} { return data[i] })
+	case schemapb.DataType_VarChar:
+		data := scalars.GetStringData().GetData()
+		setValues(len(data), func(i int) interface{} { return data[i] })
+	default:
+		return nil, merr.WrapErrParameterInvalidMsg("unsupported group by field %s of %s", fieldData.GetFieldName(), fieldData.GetType())
+	}
+	return values, nil
+}
+
+// scoreNormalizer rescales the scores of each query of a request into [0, 1] before they're weighted,
+// so the scores of the requests of different metrics, e.g. IP and L2, are comparable
+type scoreNormalizer int
+
+const (
+	noScoreNormalizer     scoreNormalizer = iota
+	minMaxScoreNormalizer                 // (score - min) / (max - min)
+	zScoreNormalizer                      // sigmoid((score - mean) / std)
+)
+
+var scoreNormalizerMap = map[string]scoreNormalizer{
+	"min_max": minMaxScoreNormalizer,
+	"z_score": zScoreNormalizer,
+}
+
+// normalize rescales the scores in place, the larger ones are the closer ones, see distanceConverter.
+// The scores all equal, including a single one, are 1 by min-max and 0.5 by z-score.
+func (sn scoreNormalizer) normalize(scores []float32) {
+	if sn == noScoreNormalizer || len(scores) == 0 {
+		return
+	}
+	minScore, maxScore, sum := math.Inf(1), math.Inf(-1), float64(0)
+	for _, score := range scores {
+		minScore = math.Min(minScore, float64(score))
+		maxScore = math.Max(maxScore, float64(score))
+		sum += float64(score)
+	}
+	mean, std := sum/float64(len(scores)), float64(0)
+	for _, score := range scores {
+		std += (float64(score) - mean) * (float64(score) - mean)
+	}
+	std = math.Sqrt(std / float64(len(scores)))
+
+	for i, score := range scores {
+		var normalized float64
+		switch sn {
+		case minMaxScoreNormalizer:
+			normalized = 1
+			if maxScore > minScore {
+				normalized = (float64(score) - minScore) / (maxScore - minScore)
+			}
+		case zScoreNormalizer:
+			z := float64(0)
+			if std > 0 {
+				z = (float64(score) - mean) / std
+			}
+			normalized = 1 / (1 + math.Exp(-z))
+		}
+		scores[i] = float32(normalized)
+	}
+}
+
+// distanceConverter converts the distances of the metrics the smaller scores of which are the closer ones, e.g. L2,
+// into similarities, so the larger scores are the closer ones of all the requests fused
+type distanceConverter int
+
+const (
+	reciprocalDistanceConverter distanceConverter = iota // 1 / (1 + distance)
+	negativeDistanceConverter                            // -distance
+)
+
+var distanceConverterMap = map[string]distanceConverter{
+	"reciprocal": reciprocalDistanceConverter,
+	"negate":     negativeDistanceConverter,
+}
+
+func (dc distanceConverter) convert(distance float32) float32 {
+	if dc == negativeDistanceConverter {
+		return -distance
+	}
+	return 1 / (1 + distance)
+}
+
+// bordaScorer scores the results by their positions like rrfScorer, each of the results of a query gets topk - rank points,
+// where topk is the number of the results of the query, or of its group, e.g. 3, 2 and 1 of 3 results, which are summed by the requests.
+type bordaScorer struct {
+	baseScorer
+	weight float32
+}
+
+func (bs *bordaScorer) reScore(input *milvuspb.SearchResults) error {
+	buckets, err := rankBuckets(input.GetResults())
+	if err != nil {
+		return err
+	}
+	// the positions are reset by each query, or each group of it
+	scores := input.Results.GetScores()
+	return runChunks(len(buckets), len(scores), func(chunk int) error {
+		bucket := buckets[chunk]
+		for rank, i := range bucket {
+			scores[i] = bs.weight * float32(len(bucket)-rank)
+		}
+		return nil
+	})
+}
+
+func (bs *bordaScorer) scorerType() rankType {
+	return bordaRankType
+}
+
+// sigmoidTransform squashes the scores into (0, 1) by 1 / (1 + exp(-(a*score + b))), so the thresholds of the scores
+// are portable across the collections
+type sigmoidTransform struct {
+	a float64
+	b float64
+}
+
+func (st *sigmoidTransform) transform(score float32) float32 {
+	x := st.a*float64(score) + st.b
+	// exp of the large positive ones overflows, which are computed by exp(x) / (1 + exp(x)) instead
+	if x >= 0 {
+		return float32(1 / (1 + math.Exp(-x)))
+	}
+	e := math.Exp(x)
+	return float32(e / (1 + e))
+}
+
+// parseSigmoidTransform parses a and b of the sigmoid, 1 and 0 if not set, which must be finite.
+func parseSigmoidTransform(params map[string]interface{}) (*sigmoidTransform, error) {
+	st := &sigmoidTransform{a: 1}
+	for key, value := range map[string]*float64{
+		SigmoidScaleKey: &st.a,
+		SigmoidBiasKey:  &st.b,
+	} {
+		if param, ok := params[key]; ok {
+			var err error
+			if *value, err = rankParamFloat(param, key); err != nil {
+				return nil, err
+			}
+			if math.IsNaN(*value) || math.IsInf(*value, 0) {
+				return nil, merr.WrapErrParameterInvalidMsg("rank param %s of sigmoid should be a finite float, got %v", key, param)
+			}
+		}
+	}
+	return st, nil
+}
+
+// sigmoidScorer transforms the scores by the sigmoid, which are summed by the requests
+type sigmoidScorer struct {
+	baseScorer
+	sigmoid *sigmoidTransform
+}
+
+func (ss *sigmoidScorer) reScore(input *milvuspb.SearchResults) error {
+	return forEachQuery(input.GetResults(), func(_ int, scores []float32) error {
+		for i, score := range scores {
+			scores[i] = ss.sigmoid.transform(score)
+		}
+		return nil
+	})
+}
+
+func (ss *sigmoidScorer) scorerType() rankType {
+	return sigmoidRankType
+}
+
+type weightedScorer struct {
+	baseScorer
+	weight     float32
+	normalizer scoreNormalizer
+	// the scores are transformed by it before normalized if set
+	sigmoid *sigmoidTransform
+	// whether the larger scores are the closer ones by the metric of the request, see metric.PositivelyRelated,
+	// or the scores are converted by converter first
+	positivelyRelated bool
+	converter         distanceConverter
+}
+
+func (ws *weightedScorer) reScore(input *milvuspb.SearchResults) error {
+	// the scores of each query are normalized separately
+	return forEachQuery(input.GetResults(), func(_ int, scores []float32) error {
+		if !ws.positivelyRelated {
+			for i, score := range scores {
+				scores[i] = ws.converter.convert(score)
+			}
+		}
+		if ws.sigmoid != nil {
+			for i, score := range scores {
+				scores[i] = ws.sigmoid.transform(score)
+			}
+		}
+		ws.normalizer.normalize(scores)
+		for i, score := range scores {
+			scores[i] = ws.weight * score
+		}
+		return nil
+	})
+}
+
+func (ws *weightedScorer) scorerType() rankType {
+	return weightedRankType
+}
+
+// exprScorer keeps the scores of its request as they are, which are fused with the scores of the other requests
+// by the expression shared by the scorers of all the requests, see scoreExpr
+type exprScorer struct {
+	baseScorer
+	expr *scoreExpr
+}
+
+func (es *exprScorer) reScore(input *milvuspb.SearchResults) error {
+	return nil
+}
+
+func (es *exprScorer) scorerType() rankType {
+	return udfExprRankType
+}
+
+// extremumScorer keeps the scores of its request as they are, the max or min of which by the requests recalling
+// an entity is its score, see extremumFuser. The scores of the requests should be of the same metric.
+type extremumScorer struct {
+	baseScorer
+	rank rankType
+}
+
+func (es *extremumScorer) reScore(input *milvuspb.SearchResults) error {
+	return nil
+}
+
+func (es *extremumScorer) scorerType() rankType {
+	return es.rank
+}
+
+// extremumFuser fuses the scores of an entity into the max of them, or the min if min is true,
+// only the requests recalling the entity count.
+type extremumFuser struct {
+	min bool
+}
+
+func (ef extremumFuser) fuse(scores []float32, recalled []bool) (float32, error) {
+	var fused float32
+	found := false
+	for i, score := range scores {
+		if !recalled[i] {
+			continue
+		}
+		if !found || (ef.min && score < fused) || (!ef.min && score > fused) {
+			fused = score
+			found = true
+		}
+	}
+	return fused, nil
+}
+
+// multiplyScorer converts the distances into similarities and normalizes the scores of its request if asked,
+// the product of which by the requests is the score of an entity, see productFuser. The negative scores are
+// clamped to 0 before multiplied, or the product of an even number of them would rank the entity higher.
+type multiplyScorer struct {
+	baseScorer
+	normalizer scoreNormalizer
+	// whether the larger scores are the closer ones by the metric of the request, see metric.PositivelyRelated
+	positivelyRelated bool
+	// the score of the requests not recalling an entity, see productFuser
+	missing float32
+}
+
+func (ms *multiplyScorer) reScore(input *milvuspb.SearchResults) error {
+	return forEachQuery(input.GetResults(), func(_ int, scores []float32) error {
+		if !ms.positivelyRelated {
+			for i, score := range scores {
+				scores[i] = reciprocalDistanceConverter.convert(score)
+			}
+		}
+		ms.normalizer.normalize(scores)
+		for i, score := range scores {
+			if score < 0 {
+				scores[i] = 0
+			}
+		}
+		return nil
+	})
+}
+
+func (ms *multiplyScorer) scorerType() rankType {
+	return multiplyRankType
+}
+
+// productFuser fuses the scores of an entity into the product of them, the requests not recalling it
+// count as missing, e.g. 1 to be neutral, or 0 to rank only the entities recalled by all the requests first.
+type productFuser struct {
+	missing float32
+}
+
+func (pf productFuser) fuse(scores []float32, recalled []bool) (float32, error) {
+	product := float32(1)
+	for i, score := range scores {
+		if !recalled[i] {
+			score = pf.missing
+		}
+		product *= score
+	}
+	return product, nil
+}
+
+// parseMissingValue parses the score of the requests not recalling an entity by the multiply rank,
+// 1 if not set, which must be finite and not negative.
+func parseMissingValue(params map[string]interface{}) (float32, error) {
+	value, ok := params[MissingValueKey]
+	if !ok {
+		return 1, nil
+	}
+	missing, err := rankParamFloat(value, MissingValueKey)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(missing) || math.IsInf(missing, 0) || missing < 0 || missing > math.MaxFloat32 {
+		return 0, merr.WrapErrParameterInvalidMsg("rank param %s should be a finite float not less than 0, got %v", MissingValueKey, value)
+	}
+	return float32(missing), nil
+}
+
+// decayFunc is how the scores decay by the distance of the value of a field from the origin, the same as
+// the decay functions of elasticsearch, all of which are 1 within offset from the origin, and decay at offset+scale from it
+type decayFunc int
+
+const (
+	gaussDecayFunc  decayFunc = iota // decay^(distance^2 / scale^2)
+	expDecayFunc                     // decay^(distance / scale)
+	linearDecayFunc                  // max(0, 1 - distance * (1 - decay) / scale)
+)
+
+var decayFuncMap = map[string]decayFunc{
+	"gauss":  gaussDecayFunc,
+	"exp":    expDecayFunc,
+	"linear": linearDecayFunc,
+}
+
+// decayScorer multiplies the scores by the decay of the values of a numeric field, e.g. boosting the fresher documents
+// by how long ago they're published. The field is output by the request, see scorerOutputFields.
+type decayScorer struct {
+	baseScorer
+	field  string
+	fn     decayFunc
+	origin float64
+	scale  float64
+	offset float64
+	decay  float64
+}
+
+// multiplier returns the decay of the value of the field
+func (ds *decayScorer) multiplier(value float64) float64 {
+	distance := math.Max(0, math.Abs(value-ds.origin)-ds.offset)
+	switch ds.fn {
+	case expDecayFunc:
+		return math.Pow(ds.decay, distance/ds.scale)
+	case linearDecayFunc:
+		return math.Max(0, 1-distance*(1-ds.decay)/ds.scale)
+	default:
+		return math.Pow(ds.decay, distance*distance/(ds.scale*ds.scale))
+	}
+}
+
+func (ds *decayScorer) reScore(input *milvuspb.SearchResults) error {
+	scores := input.Results.GetScores()
+	values, err := numericFieldValues(input.Results.GetFieldsData(), ds.field)
+	if err != nil {
+		return err
+	}
+	if len(values) != len(scores) {
+		return merr.WrapErrParameterInvalidMsg("the field %s to decay the scores has %d values of %d results", ds.field, len(values), len(scores))
+	}
+	return forEachQuery(input.GetResults(), func(offset int, scores []float32) error {
+		for i := range scores {
+			scores[i] *= float32(ds.multiplier(values[offset+i]))
+		}
+		return nil
+	})
+}
+
+func (ds *decayScorer) scorerType() rankType {
+	return decayRankType
+}
+
+// numericFieldValues returns the values of the numeric field by the name in the fields data.
+func numericFieldValues(fieldsData []*schemapb.FieldData, name string) ([]float64, error) {
+	for _, fieldData := range fieldsData {
+		if fieldData.GetFieldName() != name {
+			continue
+		}
+		switch fieldData.GetType() {
+		case schemapb.DataType_Int8, schemapb.DataType_Int16, schemapb.DataType_Int32:
+			return toFloat64s(fieldData.GetScalars().GetIntData().GetData()), nil
+		case schemapb.DataType_Int64:
+			return toFloat64s(fieldData.GetScalars().GetLongData().GetData()), nil
+		case schemapb.DataType_Float:
+			return toFloat64s(fieldData.GetScalars().GetFloatData().GetData()), nil
+		case schemapb.DataType_Double:
+			return fieldData.GetScalars().GetDoubleData().GetData(), nil
+		default:
+			return nil, merr.WrapErrParameterInvalidMsg("the field %s to decay the scores is of %s rather than numeric", name, fieldData.GetType())
+		}
+	}
+	return nil, merr.WrapErrFieldNotFound(name, "the field to decay the scores is missing in the search results")
+}
+
+func toFloat64s[T int32 | int64 | float32](data []T) []float64 {
+	values := make([]float64, len(data))
+	for i, v := range data {
+		values[i] = float64(v)
+	}
+	return values
+}
+
+// parseDecayScorer parses the rank params of decay, the numeric field and the scale are required,
+// the origin is now in seconds if not set.
+func parseDecayScorer(params map[string]interface{}, schema *schemapb.CollectionSchema) (*decayScorer, error) {
+	ds := &decayScorer{
+		baseScorer: baseScorer{
+			scorerName: "decay",
+		},
+		origin: float64(time.Now().Unix()),
+		decay:  0.5,
+	}
+	ds.field, _ = params[DecayFieldKey].(string)
+	if ds.field == "" {
+		return nil, merr.WrapErrParameterInvalidMsg("%s of string not found in rank_params", DecayFieldKey)
+	}
+	field := schemaField(schema, ds.field)
+	if field == nil {
+		return nil, merr.WrapErrFieldNotFound(ds.field, "the field to decay the scores not found in the collection")
+	}
+	if !typeutil.IsArithmetic(field.GetDataType()) {
+		return nil, merr.WrapErrParameterInvalidMsg("the field %s to decay the scores is of %s rather than numeric", ds.field, field.GetDataType())
+	}
+
+	fn, _ := params[DecayFuncKey].(string)
+	ok := true
+	if fn != "" {
+		ds.fn, ok = decayFuncMap[fn]
+	}
+	if !ok {
+		return nil, merr.WrapErrParameterInvalidMsg("unsupported rank param %s %s, should be gauss, exp or linear", DecayFuncKey, fn)
+	}
+
+	for key, value := range map[string]*float64{
+		DecayOriginKey: &ds.origin,
+		DecayScaleKey:  &ds.scale,
+		DecayOffsetKey: &ds.offset,
+		DecayFactorKey: &ds.decay,
+	} {
+		if param, ok := params[key]; ok {
+			var err error
+			if *value, err = rankParamFloat(param, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if ds.scale <= 0 {
+		return nil, merr.WrapErrParameterInvalidMsg("rank param %s should be greater than 0, got %v", DecayScaleKey, ds.scale)
+	}
+	if ds.offset < 0 {
+		return nil, merr.WrapErrParameterInvalidMsg("rank param %s should not be negative, got %v", DecayOffsetKey, ds.offset)
+	}
+	if ds.decay <= 0 || ds.decay >= 1 {
+		return nil, merr.WrapErrParameterInvalidMsg("rank param %s should be in range (0, 1), got %v", DecayFactorKey, ds.decay)
+	}
+	return ds, nil
+}
+
+// schemaField returns the field of the schema by the name, nil if not found.
+func schemaField(schema *schemapb.CollectionSchema, name string) *schemapb.FieldSchema {
+	for _, field := range schema.GetFields() {
+		if field.GetName() == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// maxBoostRules is the max number of the rules of the boost rank
+const maxBoostRules = 16
+
+// boostRule multiplies the scores of the results whose values of the field equal to value,
+// or in range [min, max] if value is nil, by multiplier
+type boostRule struct {
+	Field      string      `json:"field"`
+	Value      interface{} `json:"value"`
+	Range      []rankFloat `json:"range"`
+	Multiplier *rankFloat  `json:"multiplier"`
+}
+
+func (br *boostRule) match(value interface{}) bool {
+	if br.Value == nil {
+		v, ok := value.(float64)
+		return ok && v >= float64(br.Range[0]) && v <= float64(br.Range[1])
+	}
+	return value == br.Value
+}
+
+// boostScorer multiplies the score of each result by the product of the multipliers of the rules it matches, e.g. 1.2
+// if category == "premium". The fields of the rules are output by the request, see scorerOutputFields.
+type boostScorer struct {
+	baseScorer
+	rules []*boostRule
+}
+
+func (bs *boostScorer) reScore(input *milvuspb.SearchResults) error {
+	scores := input.Results.GetScores()
+	values := make(map[string][]interface{})
+	for _, rule := range bs.rules {
+		if _, ok := values[rule.Field]; !ok {
+			fieldValues, err := scalarFieldValues(input.Results.GetFieldsData(), rule.Field)
+			if err != nil {
+				return err
+			}
+			if len(fieldValues) != len(scores) {
+				return merr.WrapErrParameterInvalidMsg("the field %s to boost the scores has %d values of %d results", rule.Field, len(fieldValues), len(scores))
+			}
+			values[rule.Field] = fieldValues
+		}
+	}
+	// the multipliers of the rules are applied in order
+	return forEachQuery(input.GetResults(), func(offset int, scores []float32) error {
+		for _, rule := range bs.rules {
+			for i := range scores {
+				if rule.match(values[rule.Field][offset+i]) {
+					scores[i] *= float32(*rule.Multiplier)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (bs *boostScorer) scorerType() rankType {
+	return boostRankType
+}
+
+// scalarFieldValues returns the values of the scalar field by the name in the fields data, the numbers are float64,
+// see numericFieldValues.
+func scalarFieldValues(fieldsData []*schemapb.FieldData, name string) ([]interface{}, error) {
+	for _, fieldData := range fieldsData {
+		if fieldData.GetFieldName() != name {
+			continue
+		}
+		var values []interface{}
+		switch fieldData.GetType() {
+		case schemapb.DataType_Bool:
+			values = lo.ToAnySlice(fieldData.GetScalars().GetBoolData().GetData())
+		case schemapb.DataType_VarChar, schemapb.DataType_String:
+			values = lo.ToAnySlice(fieldData.GetScalars().GetStringData().GetData())
+		default:
+			numbers, err := numericFieldValues(fieldsData, name)
+			if err != nil {
+				return nil, err
+			}
+			values = lo.ToAnySlice(numbers)
+		}
+		return values, nil
+	}
+	return nil, merr.WrapErrFieldNotFound(name, "the field to boost the scores is missing in the search results")
+}
+
+// parseBoostRules parses the rules of the boost rank, at most maxBoostRules, the fields of which must be scalar,
+// and the values must be of the types of the fields.
+func parseBoostRules(params map[string]interface{}, schema *schemapb.CollectionSchema) ([]*boostRule, error) {
+	value, ok := params[BoostRulesKey].([]interface{})
+	if !ok {
+		return nil, merr.WrapErrParameterInvalidMsg("%s of array not found in rank_params", BoostRulesKey)
+	}
+	if len(value) == 0 || len(value) > maxBoostRules {
+		return nil, merr.WrapErrParameterInvalidMsg("the number of %s should be in range [1, %d], got %d", BoostRulesKey, maxBoostRules, len(value))
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*boostRule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, merr.WrapErrParameterInvalidMsg("invalid %s of rank_params, %s", BoostRulesKey, err.Error())
+	}
+
+	for i, rule := range rules {
+		field := schemaField(schema, rule.Field)
+		if field == nil {
+			return nil, merr.WrapErrFieldNotFound(rule.Field, fmt.Sprintf("the field of %s[%d] not found in the collection", BoostRulesKey, i))
+		}
+		dataType := field.GetDataType()
+		numeric := typeutil.IsArithmetic(dataType)
+		if !numeric && !typeutil.IsBoolType(dataType) && !typeutil.IsStringType(dataType) {
+			return nil, merr.WrapErrParameterInvalidMsg("the field %s of %s[%d] is of %s rather than scalar", rule.Field, BoostRulesKey, i, dataType)
+		}
+		if rule.Multiplier == nil || *rule.Multiplier < 0 || math.IsInf(float64(*rule.Multiplier), 0) {
+			return nil, merr.WrapErrParameterInvalidMsg("the multiplier of %s[%d] should be a non-negative number", BoostRulesKey, i)
+		}
+
+		switch {
+		case rule.Value != nil && rule.Range != nil:
+			return nil, merr.WrapErrParameterInvalidMsg("either value or range of %s[%d] should be set rather than both", BoostRulesKey, i)
+		case rule.Range != nil:
+			if !numeric || len(rule.Range) != 2 || rule.Range[0] > rule.Range[1] {
+				return nil, merr.WrapErrParameterInvalidMsg("the range of %s[%d] should be [min, max] of the numeric field", BoostRulesKey, i)
+			}
+		case rule.Value != nil:
+			var typed bool
+			switch rule.Value.(type) {
+			case float64:
+				typed = numeric
+			case string:
+				typed = typeutil.IsStringType(dataType)
+			case bool:
+				typed = typeutil.IsBoolType(dataType)
+			}
+			if !typed {
+				return nil, merr.WrapErrParameterInvalidMsg("the value %v of %s[%d] mismatches with the field %s of %s", rule.Value, BoostRulesKey, i, rule.Field, dataType)
+			}
+		default:
+			return nil, merr.WrapErrParameterInvalidMsg("either value or range of %s[%d] should be set", BoostRulesKey, i)
+		}
+	}
+	return rules, nil
+}
+
+// scorerOutputFields returns the fields the scorer reads from the results of its request, which must be output by the request.
+func scorerOutputFields(scorer reScorer) []string {
+	switch scorer := scorer.(type) {
+	case *decayScorer:
+		return []string{scorer.field}
+	case *boostScorer:
+		return lo.Uniq(lo.Map(scorer.rules, func(rule *boostRule, _ int) string { return rule.Field }))
+	}
+	return nil
+}
+
+// scoreExprPrefix is the prefix of the variables of the scores in the expression, e.g. score_0 of the first request
+const scoreExprPrefix = "score_"
+
+// scoreExprFuncs are the math functions available in the expression besides the builtin ones, e.g. abs, max and min
+var scoreExprFuncs = map[string]any{
+	"log":   math.Log,
+	"log2":  math.Log2,
+	"log10": math.Log10,
+	"exp":   math.Exp,
+	"sqrt":  math.Sqrt,
+	"pow":   math.Pow,
+}
+
+// scoreExpr is the expression compiled once to fuse the scores of each entity recalled by the requests, e.g.
+// 0.7*score_0 + 0.3*log(1+score_1), the score of the request not recalling the entity is 0.
+type scoreExpr struct {
+	code    string
+	program *vm.Program
+	machine vm.VM
+	env     map[string]any
+	names   []string
+}
+
+// newScoreExpr compiles the expression of the scores of nReqs requests, which must evaluate to a number,
+// the references to the requests not exist are rejected.
+func newScoreExpr(code string, nReqs int) (*scoreExpr, error) {
+	env := make(map[string]any, nReqs+len(scoreExprFuncs))
+	for name, fn := range scoreExprFuncs {
+		env[name] = fn
+	}
+	names := make([]string, nReqs)
+	for i := range names {
+		names[i] = scoreExprPrefix + strconv.Itoa(i)
+		env[names[i]] = float64(0)
+	}
+	program, err := expr.Compile(code, expr.Env(env), expr.AsFloat64())
+	if err != nil {
+		return nil, merr.WrapErrParameterInvalidMsg("invalid rank expr %s of %d ann search requests, %s", code, nReqs, err.Error())
+	}
+	return &scoreExpr{code: code, program: program, env: env, names: names}, nil
+}
+
+// fuse evaluates the expression with the scores of the requests, which is not concurrent safe.
+func (se *scoreExpr) fuse(scores []float32, recalled []bool) (float32, error) {
+	for i, name := range se.names {
+		se.env[name] = float64(scores[i])
+	}
+	output, err := se.machine.Run(se.program, se.env)
+	if err != nil {
+		return 0, merr.WrapErrParameterInvalidMsg("failed to evaluate rank expr %s, %s", se.code, err.Error())
+	}
+	return float32(output.(float64)), nil
+}
+
+// parseRRFParamK parses the rank param k of rrf, which is named in the errors, e.g. k[1] of the second request.
+func parseRRFParamK(value interface{}, name string) (float64, error) {
+	k, err := rankParamFloat(value, name)
+	if err != nil {
+		return 0, err
+	}
+	if k <= 0 || k >= maxRRFParamsValue {
+		return 0, merr.WrapErrParameterInvalidMsg("rank param %s should be in range (0, %d), got %v", name, maxRRFParamsValue, k)
+	}
+	return k, nil
+}
+
+// rankParamFloat parses the number of the rank param, which is named in the errors. The strings of finite floats are
+// accepted as well, e.g. "0.3" of the clients encoding all the values as strings, which are validated the same afterwards.
+func rankParamFloat(value interface{}, name string) (float64, error) {
+	if str, ok := value.(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, merr.WrapErrParameterInvalidMsg("rank param %s should be a number or a string of finite float, got %q", name, str)
+		}
+		return f, nil
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || !v.CanFloat() {
+		return 0, merr.WrapErrParameterInvalidMsg("the type of rank param %s should be float, got %v", name, value)
+	}
+	return v.Float(), nil
+}
+
+// rankFloat is the number of the boost rules decoded from JSON, either a number or a string of it, see rankParamFloat
+type rankFloat float64
+
+func (rf *rankFloat) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	f, err := rankParamFloat(value, BoostRulesKey)
+	if err != nil {
+		return err
+	}
+	*rf = rankFloat(f)
+	return nil
 }
 
-func (rs *rrfScorer) reScore(input *milvuspb.SearchResults) {
-	for i := range input.Results.GetScores() {
-		input.Results.Scores[i] = 1 / (rs.k + float32(i+1))
+// parseRankWeights parses the rank param weights of each request, which are in range [0, 1] unless unbounded,
+// e.g. 3 to boost a request, or negative to demote the results of a request. NaN and Inf are always rejected.
+// The length of the weights is checked before each of them.
+func parseRankWeights(value interface{}, nReqs int, unbounded bool) ([]float32, error) {
+	if value == nil || reflect.TypeOf(value).Kind() != reflect.Slice {
+		return nil, merr.WrapErrParameterInvalidMsg("rank param %s should be an array, got %v", WeightsParamsKey, value)
+	}
+	rs := reflect.ValueOf(value)
+	if nReqs != rs.Len() {
+		return nil, merr.WrapErrParameterInvalid(fmt.Sprint(nReqs), fmt.Sprint(rs.Len()), "the length of weights param mismatch with ann search requests")
+	}
+	weights := make([]float32, 0, rs.Len())
+	for i := 0; i < rs.Len(); i++ {
+		weight, err := rankParamFloat(rs.Index(i).Interface(), fmt.Sprintf("%s[%d]", WeightsParamsKey, i))
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, merr.WrapErrParameterInvalidMsg("rank param %s[%d] should be finite, got %v", WeightsParamsKey, i, weight)
+		}
+		if !unbounded && (weight < 0 || weight > 1) {
+			return nil, merr.WrapErrParameterInvalidMsg("rank param %s[%d] should be in range [0, 1], or set %s to true, got %v",
+				WeightsParamsKey, i, UnboundedWeightsKey, weight)
+		}
+		weights = append(weights, float32(weight))
 	}
+	return weights, nil
 }
 
-func (rs *rrfScorer) scorerType() rankType {
-	return rrfRankType
+// unsupportedRankType returns the error of the rank type unsupported, which lists the registered ones
+func unsupportedRankType(rankTypeStr string) error {
+	scorerFactoriesMu.RLock()
+	supported := lo.Keys(scorerFactories)
+	scorerFactoriesMu.RUnlock()
+	sort.Strings(supported)
+	return merr.WrapErrParameterInvalidMsg("unsupported rank type %s, should be one of %v", rankTypeStr, supported)
 }
 
-type weightedScorer struct {
-	baseScorer
-	weight float32
+// scoreBounds bounds the scores of the results of a request after rescored, before merged with the other requests.
+// The results scored below dropBelow are dropped first, then the scores are clipped into [clipMin, clipMax],
+// each of which is not bounded if nil.
+type scoreBounds struct {
+	clipMin   *float32
+	clipMax   *float32
+	dropBelow *float32
 }
 
-func (ws *weightedScorer) reScore(input *milvuspb.SearchResults) {
-	for i, score := range input.Results.GetScores() {
-		input.Results.Scores[i] = ws.weight * score
+// parseScoreBounds parses the bounds of the scores by the rank params, nil if none of them set.
+// They must be finite, and clip_min must not be greater than clip_max.
+func parseScoreBounds(params map[string]interface{}) (*scoreBounds, error) {
+	sb := &scoreBounds{}
+	for key, bound := range map[string]**float32{
+		ClipMinKey:   &sb.clipMin,
+		ClipMaxKey:   &sb.clipMax,
+		DropBelowKey: &sb.dropBelow,
+	} {
+		param, ok := params[key]
+		if !ok {
+			continue
+		}
+		value, err := rankParamFloat(param, key)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) || math.Abs(value) > math.MaxFloat32 {
+			return nil, merr.WrapErrParameterInvalidMsg("rank param %s should be a finite float, got %v", key, param)
+		}
+		*bound = lo.ToPtr(float32(value))
+	}
+	if sb.clipMin != nil && sb.clipMax != nil && *sb.clipMin > *sb.clipMax {
+		return nil, merr.WrapErrParameterInvalidMsg("rank param %s should not be greater than %s, got %v > %v",
+			ClipMinKey, ClipMaxKey, *sb.clipMin, *sb.clipMax)
 	}
+	if sb.clipMin == nil && sb.clipMax == nil && sb.dropBelow == nil {
+		return nil, nil
+	}
+	return sb, nil
 }
 
-func (ws *weightedScorer) scorerType() rankType {
-	return weightedRankType
+// bound drops the results scored below dropBelow along with their ids, fields and group by values,
+// the topks of the queries are updated by the results left, then clips the scores in place.
+// It returns the indexes of the results left, nil if none dropped.
+func (sb *scoreBounds) bound(results *schemapb.SearchResultData) ([]int64, error) {
+	var kept []int64
+	if sb.dropBelow != nil {
+		queries, err := queryScores(results)
+		if err != nil {
+			return nil, err
+		}
+		dropped := false
+		topks := make([]int64, len(queries))
+		kept = make([]int64, 0, len(results.GetScores()))
+		index := int64(0)
+		for i, query := range queries {
+			for _, score := range query {
+				if score >= *sb.dropBelow {
+					kept = append(kept, index)
+					topks[i]++
+				} else {
+					dropped = true
+				}
+				index++
+			}
+		}
+		if dropped {
+			if err := keepResults(results, kept, topks); err != nil {
+				return nil, err
+			}
+		} else {
+			kept = nil
+		}
+	}
+
+	scores := results.GetScores()
+	for i, score := range scores {
+		if sb.clipMin != nil && score < *sb.clipMin {
+			scores[i] = *sb.clipMin
+		}
+		if sb.clipMax != nil && score > *sb.clipMax {
+			scores[i] = *sb.clipMax
+		}
+	}
+	return kept, nil
+}
+
+// keepResults keeps the results of the indexes only, in place, topks are of the results kept by each query.
+func keepResults(results *schemapb.SearchResultData, kept []int64, topks []int64) error {
+	ids := &schemapb.IDs{}
+	switch results.GetIds().GetIdField().(type) {
+	case *schemapb.IDs_IntId:
+		ids.IdField = &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: make([]int64, 0, len(kept))}}
+	case *schemapb.IDs_StrId:
+		ids.IdField = &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, len(kept))}}
+	}
+	scores := make([]float32, 0, len(kept))
+	fieldsData := typeutil.PrepareResultFieldData(results.GetFieldsData(), int64(len(kept)))
+	groupBy := results.GetGroupByFieldValue()
+	groupByValues := &schemapb.SearchResultData{}
+	if groupBy != nil {
+		groupByValues.GroupByFieldValue = &schemapb.FieldData{
+			Type:      groupBy.GetType(),
+			FieldName: groupBy.GetFieldName(),
+			FieldId:   groupBy.GetFieldId(),
+			Field:     &schemapb.FieldData_Scalars{Scalars: &schemapb.ScalarField{}},
+		}
+	}
+	for _, i := range kept {
+		if results.GetIds().GetIdField() != nil {
+			typeutil.AppendPKs(ids, typeutil.GetPK(results.GetIds(), i))
+		}
+		scores = append(scores, results.GetScores()[i])
+		typeutil.AppendFieldData(fieldsData, results.GetFieldsData(), i)
+		if groupBy != nil {
+			if err := typeutil.AppendGroupByValue(groupByValues, typeutil.GetData(groupBy, int(i)), groupBy.GetType()); err != nil {
+				return err
+			}
+		}
+	}
+	if results.GetIds() != nil {
+		results.Ids = ids
+	}
+	results.Scores = scores
+	results.FieldsData = fieldsData
+	if groupBy != nil {
+		results.GroupByFieldValue = groupByValues.GetGroupByFieldValue()
+	}
+	if len(results.GetTopks()) > 0 {
+		results.Topks = topks
+	}
+	return nil
 }
 
-func NewReScorer(reqs []*milvuspb.SearchRequest, rankParams []*commonpb.KeyValuePair) ([]reScorer, error) {
+// unboundedWeights returns whether the weights out of range [0, 1] are allowed by the rank params.
+func unboundedWeights(params map[string]interface{}) bool {
+	unbounded, _ := params[UnboundedWeightsKey].(bool)
+	return unbounded
+}
+
+// scoreFuser fuses the scores of an entity by the requests rather than summing them, recalled tells
+// which requests recalled it, the scores of the others are 0
+type scoreFuser interface {
+	fuse(scores []float32, recalled []bool) (float32, error)
+}
+
+// scoreFusion returns the fuser of the scores of the requests by the type of the scorers,
+// or nil if the scores rescored are summed.
+func scoreFusion(scorers []reScorer) scoreFuser {
+	if len(scorers) == 0 {
+		return nil
+	}
+	switch scorer := scorers[0].(type) {
+	case *exprScorer:
+		return scorer.expr
+	case *extremumScorer:
+		return extremumFuser{min: scorer.rank == minRankType}
+	case *multiplyScorer:
+		return productFuser{missing: scorer.missing}
+	}
+	return nil
+}
+
+// NewReScorer creates the scorers of the requests by the rank params, the schema of the collection is to validate
+// the fields the scorers read, see scorerOutputFields.
+func NewReScorer(reqs []*milvuspb.SearchRequest, rankParams []*commonpb.KeyValuePair, schema *schemapb.CollectionSchema) ([]reScorer, error) {
 	res := make([]reScorer, len(reqs))
 	rankTypeStr, err := funcutil.GetAttrByKeyFromRepeatedKV(RankTypeKey, rankParams)
 	if err != nil {
-		log.Info("rank strategy not specified, use rrf instead")
-		// if not set rank strategy, use rrf rank as default
+		// if not set rank strategy, use the configured one as default
+		res, err := defaultReScorers(reqs, schema)
+		if err == nil {
+			return res, nil
+		}
+		log.Warn("invalid default rank strategy configured, use rrf instead", zap.Error(err))
+		res = make([]reScorer, len(reqs))
 		for i := range reqs {
 			res[i] = &rrfScorer{
 				baseScorer: baseScorer{
@@ -92,84 +1236,613 @@ func NewReScorer(reqs []*milvuspb.SearchRequest, rankParams []*commonpb.KeyValue
 		return res, nil
 	}
 
-	if _, ok := rankTypeMap[rankTypeStr]; !ok {
-		return nil, errors.Errorf("unsupported rank type %s", rankTypeStr)
+	if _, ok := scorerFactory(rankTypeStr); !ok {
+		return nil, unsupportedRankType(rankTypeStr)
 	}
 
+	var params map[string]interface{}
 	paramStr, err := funcutil.GetAttrByKeyFromRepeatedKV(RankParamsKey, rankParams)
-	if err != nil {
-		return nil, errors.New(RankParamsKey + " not found in rank_params")
+	if err == nil {
+		err = json.Unmarshal([]byte(paramStr), &params)
+		if err != nil {
+			return nil, merr.WrapErrParameterInvalidMsg("%s of rank_params should be a JSON object, %s", RankParamsKey, err.Error())
+		}
+	} else if rank := rankTypeMap[rankTypeStr]; rank != maxRankType && rank != minRankType && rank != bordaRankType &&
+		rank != sigmoidRankType && rank != multiplyRankType {
+		// the params of the max, min, borda, sigmoid and multiply ranks are optional
+		return nil, merr.WrapErrParameterInvalidMsg("%s not found in rank_params", RankParamsKey)
 	}
 
-	var params map[string]interface{}
-	err = json.Unmarshal([]byte(paramStr), &params)
+	// the strict mode of the params wins over the configured one
+	strict, ok := params[StrictRankParamsKey].(bool)
+	if !ok {
+		strict = paramtable.Get().ProxyCfg.HybridSearchStrictRankParams.GetAsBool()
+	}
+	if strict {
+		if err := checkRankParamKeys(rankTypeStr, params, RankParamsKey); err != nil {
+			return nil, err
+		}
+	}
+	perRequest, err := perRequestRankParams(rankTypeStr, params, len(reqs), strict)
 	if err != nil {
 		return nil, err
 	}
+	if perRequest == nil {
+		return newReScorers(reqs, rankTypeStr, params, schema)
+	}
+
+	// the scorer of each request is created from the params merged with its overrides, the scorers of
+	// the requests of the same params merged are created at once
+	scorers := make(map[string][]reScorer)
+	for i := range reqs {
+		merged := make(map[string]interface{}, len(params)+len(perRequest[i]))
+		for key, value := range params {
+			merged[key] = value
+		}
+		for key, value := range perRequest[i] {
+			merged[key] = value
+		}
+		// the keys are sorted by the encoding
+		b, err := json.Marshal(merged)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := scorers[string(b)]; !ok {
+			scorers[string(b)], err = newReScorers(reqs, rankTypeStr, merged, schema)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid rank params of request %d", i)
+			}
+		}
+		res[i] = scorers[string(b)][i]
+	}
+	return res, nil
+}
 
+// defaultReScorers creates the scorers of the requests by the default rank strategy configured,
+// which is read each time to pick up the changes.
+func defaultReScorers(reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	cfg := &paramtable.Get().ProxyCfg
+	rankTypeStr := cfg.HybridSearchDefaultRankType.GetValue()
+	if _, ok := scorerFactory(rankTypeStr); !ok {
+		return nil, unsupportedRankType(rankTypeStr)
+	}
+	params := make(map[string]interface{})
 	switch rankTypeMap[rankTypeStr] {
 	case rrfRankType:
-		_, ok := params[RRFParamsKey]
-		if !ok {
-			return nil, errors.New(RRFParamsKey + " not found in rank_params")
+		params[RRFParamsKey] = cfg.HybridSearchDefaultRRFK.GetAsFloat()
+	case weightedRankType:
+		// weighted by 1 equally if not configured
+		weights := make([]interface{}, len(reqs))
+		for i := range weights {
+			weights[i] = float64(1)
 		}
-		var k float64
-		if reflect.ValueOf(params[RRFParamsKey]).CanFloat() {
-			k = reflect.ValueOf(params[RRFParamsKey]).Float()
-		} else {
-			return nil, errors.New("The type of rank param k should be float")
+		if strings.TrimSpace(cfg.HybridSearchDefaultWeights.GetValue()) != "" {
+			values := cfg.HybridSearchDefaultWeights.GetAsStrings()
+			weights = make([]interface{}, len(values))
+			for i, value := range values {
+				weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid default weight %s", value)
+				}
+				weights[i] = weight
+			}
+		}
+		params[WeightsParamsKey] = weights
+	}
+	return newReScorers(reqs, rankTypeStr, params, schema)
+}
+
+// fusedBefore is the order of the results fused, by the scores descending, then by the pks ascending if the scores tie,
+// e.g. 1 before 2 and "a" before "b", so the order is the same however the results are fed. NaN scores are ordered
+// after all the others, which would break the order otherwise.
+func fusedBefore(scoreA, scoreB float32, pkA, pkB interface{}) bool {
+	nanA, nanB := math.IsNaN(float64(scoreA)), math.IsNaN(float64(scoreB))
+	if nanA != nanB {
+		return nanB
+	}
+	if !nanA && scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	return typeutil.ComparePK(pkA, pkB)
+}
+
+// sortFusedPKs sorts the pks of the results of a query by the scores fused of them, see fusedBefore
+func sortFusedPKs(pks []interface{}, scores map[interface{}]float32) {
+	sort.Slice(pks, func(i, j int) bool {
+		return fusedBefore(scores[pks[i]], scores[pks[j]], pks[i], pks[j])
+	})
+}
+
+// scoreExplainer explains the score of each hit of the hybrid search by the raw scores of the requests, the ones rescored
+// and how they're fused, which is serialized into the detail of the status of the results, see ExplainRankParamsKey.
+// The hits explained are the ones returned, at most topk of each query.
+type scoreExplainer struct {
+	// the raw scores of the results of each request before rescored
+	raw [][]float32
+
+	// sum, max, min or the expression, see scoreFusion
+	Fusion string            `json:"fusion"`
+	Hits   []*hitExplanation `json:"hits"`
+}
+
+// hitExplanation is the explanation of a hit, the scores of the requests not recalling it are null
+type hitExplanation struct {
+	ID       interface{} `json:"id"`
+	Score    float32     `json:"score"`
+	Raw      []*float32  `json:"raw"`
+	Rescored []*float32  `json:"rescored"`
+}
+
+// explainRank returns whether the hybrid search is explained by the rank params, see scoreExplainer.
+func explainRank(rankParams []*commonpb.KeyValuePair) bool {
+	paramStr, err := funcutil.GetAttrByKeyFromRepeatedKV(RankParamsKey, rankParams)
+	if err != nil {
+		return false
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(paramStr), &params); err != nil {
+		return false
+	}
+	explain, _ := params[ExplainRankParamsKey].(bool)
+	return explain
+}
+
+// addRawScores adds the raw scores of the results of the next request, before they're rescored
+func (se *scoreExplainer) addRawScores(scores []float32) {
+	se.raw = append(se.raw, append([]float32(nil), scores...))
+}
+
+// keepRawScores keeps the raw scores of the results of the request left by the indexes, see scoreBounds
+func (se *scoreExplainer) keepRawScores(request int, kept []int64) {
+	raw := make([]float32, 0, len(kept))
+	for _, i := range kept {
+		raw = append(raw, se.rawScore(request, i))
+	}
+	se.raw[request] = raw
+}
+
+func (se *scoreExplainer) rawScore(request int, index int64) float32 {
+	if request >= len(se.raw) || index >= int64(len(se.raw[request])) {
+		return 0
+	}
+	return se.raw[request][index]
+}
+
+// explain adds the explanation of the hit, score is the one fused before rounded
+func (se *scoreExplainer) explain(id interface{}, score float32, raw []float32, rescored []float32, recalled []bool) {
+	hit := &hitExplanation{
+		ID:       id,
+		Score:    score,
+		Raw:      make([]*float32, len(recalled)),
+		Rescored: make([]*float32, len(recalled)),
+	}
+	for i := range recalled {
+		if recalled[i] {
+			hit.Raw[i], hit.Rescored[i] = &raw[i], &rescored[i]
+		}
+	}
+	se.Hits = append(se.Hits, hit)
+}
+
+// fusionName returns how the scores are fused by the fuser, see scoreFusion
+func fusionName(fusion scoreFuser) string {
+	switch fusion := fusion.(type) {
+	case *scoreExpr:
+		return fusion.code
+	case extremumFuser:
+		if fusion.min {
+			return "min"
 		}
-		if k <= 0 || k >= maxRRFParamsValue {
-			return nil, errors.New("The rank params k should be in range (0, 16384)")
+		return "max"
+	case productFuser:
+		return "multiply"
+	}
+	return "sum"
+}
+
+// commonRankParamKeys are the keys of the rank params accepted by all the rank types
+var commonRankParamKeys = []string{StrictRankParamsKey, PerRequestRankParamsKey, ExplainRankParamsKey, ClipMinKey, ClipMaxKey, DropBelowKey}
+
+// rankParamKeys are the keys of the rank params of each rank type besides commonRankParamKeys,
+// the unknown ones are rejected in the strict mode.
+var rankParamKeys = map[rankType][]string{
+	rrfRankType:      {RRFParamsKey},
+	weightedRankType: {WeightsParamsKey, NormScoreKey, SimilarityKey, UnboundedWeightsKey, TransformKey, SigmoidScaleKey, SigmoidBiasKey},
+	udfExprRankType:  {ExprParamsKey},
+	bordaRankType:    {WeightsParamsKey, UnboundedWeightsKey},
+	decayRankType:    {DecayFieldKey, DecayFuncKey, DecayOriginKey, DecayScaleKey, DecayOffsetKey, DecayFactorKey},
+	boostRankType:    {BoostRulesKey},
+	sigmoidRankType:  {SigmoidScaleKey, SigmoidBiasKey},
+	multiplyRankType: {NormScoreKey, SimilarityKey, MissingValueKey},
+}
+
+// checkRankParamKeys rejects the keys of the params unknown by the rank type, where is where the params are for the errors,
+// which list all the unknown keys and the accepted ones.
+func checkRankParamKeys(rankTypeStr string, params map[string]interface{}, where string) error {
+	accepted := append(append([]string{}, commonRankParamKeys...), rankParamKeys[rankTypeMap[rankTypeStr]]...)
+	var unknown []string
+	for key := range params {
+		if !lo.Contains(accepted, key) {
+			unknown = append(unknown, key)
 		}
-		log.Debug("rrf params", zap.Float64("k", k))
-		for i := range reqs {
-			res[i] = &rrfScorer{
-				baseScorer: baseScorer{
-					scorerName: "rrf",
-				},
-				k: float32(k),
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	sort.Strings(accepted)
+	return merr.WrapErrParameterInvalidMsg("unknown keys %v in %s of rank %s, the accepted keys are %v", unknown, where, rankTypeStr, accepted)
+}
+
+// perRequestRankParams returns the overrides of the rank params of each request, nil if not set. The ranks fusing
+// the scores of all the requests by the same params, e.g. expr, don't support them.
+func perRequestRankParams(rankTypeStr string, params map[string]interface{}, nReqs int, strict bool) ([]map[string]interface{}, error) {
+	value, ok := params[PerRequestRankParamsKey]
+	if !ok {
+		return nil, nil
+	}
+	switch rankTypeMap[rankTypeStr] {
+	case udfExprRankType, maxRankType, minRankType, multiplyRankType:
+		return nil, merr.WrapErrParameterInvalidMsg("%s is not supported by rank %s", PerRequestRankParamsKey, rankTypeStr)
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, merr.WrapErrParameterInvalidMsg("%s of rank_params should be an array", PerRequestRankParamsKey)
+	}
+	if len(values) != nReqs {
+		return nil, merr.WrapErrParameterInvalid(fmt.Sprint(nReqs), fmt.Sprint(len(values)), "the length of "+PerRequestRankParamsKey+" mismatch with ann search requests")
+	}
+	perRequest := make([]map[string]interface{}, nReqs)
+	for i, value := range values {
+		overrides, ok := value.(map[string]interface{})
+		// null as no overrides
+		if !ok && value != nil {
+			return nil, merr.WrapErrParameterInvalidMsg("%s[%d] of rank_params should be an object", PerRequestRankParamsKey, i)
+		}
+		if _, ok := overrides[PerRequestRankParamsKey]; ok {
+			return nil, merr.WrapErrParameterInvalidMsg("%s[%d] of rank_params should not be nested", PerRequestRankParamsKey, i)
+		}
+		if strict {
+			if err := checkRankParamKeys(rankTypeStr, overrides, fmt.Sprintf("%s[%d]", PerRequestRankParamsKey, i)); err != nil {
+				return nil, err
 			}
 		}
-	case weightedRankType:
-		if _, ok := params[WeightsParamsKey]; !ok {
-			return nil, errors.New(WeightsParamsKey + " not found in rank_params")
-		}
-		weights := make([]float32, 0)
-		switch reflect.TypeOf(params[WeightsParamsKey]).Kind() {
-		case reflect.Slice:
-			rs := reflect.ValueOf(params[WeightsParamsKey])
-			for i := 0; i < rs.Len(); i++ {
-				v := rs.Index(i).Elem()
-				if v.CanFloat() {
-					weight := v.Float()
-					if weight < 0 || weight > 1 {
-						return nil, errors.New("rank param weight should be in range [0, 1]")
+		perRequest[i] = overrides
+	}
+	return perRequest, nil
+}
+
+// ScorerFactory creates the scorers of the requests of a rank type by the rank params, the schema of the collection
+// is to validate the fields the scorers read, see RegisterScorerFactory.
+type ScorerFactory func(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error)
+
+var (
+	scorerFactoriesMu sync.RWMutex
+	scorerFactories   = make(map[string]ScorerFactory)
+)
+
+func init() {
+	RegisterScorerFactory("rrf", newRRFScorers)
+	RegisterScorerFactory("weighted", newWeightedScorers)
+	RegisterScorerFactory("expr", newExprScorers)
+	RegisterScorerFactory("max", newExtremumScorers(maxRankType, "max"))
+	RegisterScorerFactory("min", newExtremumScorers(minRankType, "min"))
+	RegisterScorerFactory("borda", newBordaScorers)
+	RegisterScorerFactory("decay", newDecayScorers)
+	RegisterScorerFactory("boost", newBoostScorers)
+	RegisterScorerFactory("sigmoid", newSigmoidScorers)
+	RegisterScorerFactory("multiply", newMultiplyScorers)
+}
+
+// RegisterScorerFactory registers the factory of the scorers of the rank type named, which is looked up by NewReScorer,
+// it's called by init() of the built-in and the custom scorers, and panics if the rank type is registered twice.
+func RegisterScorerFactory(name string, factory ScorerFactory) {
+	if name == "" || factory == nil {
+		panic("the name and the factory of a rank type must be set to register it")
+	}
+	scorerFactoriesMu.Lock()
+	defer scorerFactoriesMu.Unlock()
+	if _, ok := scorerFactories[name]; ok {
+		panic(fmt.Sprintf("the scorer factory of rank type %s is registered twice", name))
+	}
+	scorerFactories[name] = factory
+}
+
+// scorerFactory returns the factory of the scorers of the rank type, false if not registered
+func scorerFactory(name string) (ScorerFactory, bool) {
+	scorerFactoriesMu.RLock()
+	defer scorerFactoriesMu.RUnlock()
+	factory, ok := scorerFactories[name]
+	return factory, ok
+}
+
+// newReScorers creates the scorers of the requests of the rank type by the params, see RegisterScorerFactory.
+func newReScorers(reqs []*milvuspb.SearchRequest, rankTypeStr string, params map[string]interface{}, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	factory, ok := scorerFactory(rankTypeStr)
+	if !ok {
+		return nil, unsupportedRankType(rankTypeStr)
+	}
+	res, err := factory(params, reqs, schema)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != len(reqs) {
+		return nil, merr.WrapErrServiceInternal(fmt.Sprintf("%d scorers of rank type %s created for %d requests", len(res), rankTypeStr, len(reqs)))
+	}
+
+	bounds, err := parseScoreBounds(params)
+	if err != nil {
+		return nil, err
+	}
+	for _, scorer := range res {
+		scorer.setBounds(bounds)
+	}
+	return res, nil
+}
+
+// newRRFScorers creates the rrf scorers of the requests, by k of all the requests or of each request
+func newRRFScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	_, ok := params[RRFParamsKey]
+	if !ok {
+		return nil, merr.WrapErrParameterInvalidMsg("%s not found in rank_params", RRFParamsKey)
+	}
+	// either k of all the requests, or an array of k of each request, each of which is either k of all the queries
+	// of the request, or an array of k of each query of it
+	ks := make([]float64, len(reqs))
+	queryKs := make([][]float32, len(reqs))
+	if values, ok := params[RRFParamsKey].([]interface{}); ok {
+		if len(reqs) != len(values) {
+			return nil, merr.WrapErrParameterInvalid(fmt.Sprint(len(reqs)), fmt.Sprint(len(values)), "the length of rank param k mismatch with ann search requests")
+		}
+		for i, value := range values {
+			if perQuery, ok := value.([]interface{}); ok {
+				nq := reqs[i].GetNq()
+				if int64(len(perQuery)) != nq {
+					return nil, merr.WrapErrParameterInvalid(fmt.Sprint(nq), fmt.Sprint(len(perQuery)),
+						fmt.Sprintf("the length of rank param k[%d] mismatch with nq of ann search request %d", i, i))
+				}
+				queryKs[i] = make([]float32, len(perQuery))
+				for j, value := range perQuery {
+					k, err := parseRRFParamK(value, fmt.Sprintf("k[%d][%d]", i, j))
+					if err != nil {
+						return nil, err
 					}
-					weights = append(weights, float32(weight))
-				} else {
-					return nil, errors.New("The type of rank param weight should be float")
+					queryKs[i][j] = float32(k)
 				}
+				continue
 			}
-		default:
-			return nil, errors.New("The weights param should be an array")
+			k, err := parseRRFParamK(value, fmt.Sprintf("k[%d]", i))
+			if err != nil {
+				return nil, err
+			}
+			ks[i] = k
+		}
+	} else {
+		k, err := parseRRFParamK(params[RRFParamsKey], RRFParamsKey)
+		if err != nil {
+			return nil, err
+		}
+		for i := range ks {
+			ks[i] = k
+		}
+	}
+	log.Debug("rrf params", zap.Float64s("k", ks), zap.Any("k of queries", queryKs))
+	for i := range reqs {
+		res[i] = &rrfScorer{
+			baseScorer: baseScorer{
+				scorerName: "rrf",
+			},
+			k:       float32(ks[i]),
+			queryKs: queryKs[i],
+		}
+	}
+	return res, nil
+}
+
+// newWeightedScorers creates the weighted scorers of the requests, the scores of which are normalized and converted by the params
+func newWeightedScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	if _, ok := params[WeightsParamsKey]; !ok {
+		return nil, merr.WrapErrParameterInvalidMsg("%s not found in rank_params", WeightsParamsKey)
+	}
+	weights, err := parseRankWeights(params[WeightsParamsKey], len(reqs), unboundedWeights(params))
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("weights params", zap.Any("weights", weights))
+	normalizer := noScoreNormalizer
+	if value, ok := params[NormScoreKey]; ok {
+		name, _ := value.(string)
+		if normalizer, ok = scoreNormalizerMap[name]; !ok {
+			return nil, merr.WrapErrParameterInvalidMsg("unsupported rank param %s %v, should be min_max or z_score", NormScoreKey, value)
+		}
+	}
+	converter := reciprocalDistanceConverter
+	if value, ok := params[SimilarityKey]; ok {
+		name, _ := value.(string)
+		if converter, ok = distanceConverterMap[name]; !ok {
+			return nil, merr.WrapErrParameterInvalidMsg("unsupported rank param %s %v, should be reciprocal or negate", SimilarityKey, value)
+		}
+	}
+	var sigmoid *sigmoidTransform
+	if value, ok := params[TransformKey]; ok {
+		if value != "sigmoid" {
+			return nil, merr.WrapErrParameterInvalidMsg("unsupported rank param %s %v, should be sigmoid", TransformKey, value)
+		}
+		sigmoid, err = parseSigmoidTransform(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, req := range reqs {
+		// positively related if the metric is not specified
+		metricType, err := funcutil.GetAttrByKeyFromRepeatedKV(MetricTypeKey, req.GetSearchParams())
+		res[i] = &weightedScorer{
+			baseScorer: baseScorer{
+				scorerName: "weighted",
+			},
+			weight:            weights[i],
+			normalizer:        normalizer,
+			positivelyRelated: err != nil || metric.PositivelyRelated(metricType),
+			converter:         converter,
+			sigmoid:           sigmoid,
+		}
+	}
+	return res, nil
+}
+
+// newExprScorers creates the scorers of the requests sharing the expression fusing their scores, see scoreExpr
+func newExprScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	code, ok := params[ExprParamsKey].(string)
+	if !ok {
+		return nil, merr.WrapErrParameterInvalidMsg("%s of string not found in rank_params", ExprParamsKey)
+	}
+	fusion, err := newScoreExpr(code, len(reqs))
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("expr params", zap.String("expr", code))
+	for i := range reqs {
+		res[i] = &exprScorer{
+			baseScorer: baseScorer{
+				scorerName: "expr",
+			},
+			expr: fusion,
+		}
+	}
+	return res, nil
+}
+
+// newBordaScorers creates the borda scorers of the requests, which are weighted equally if no weights
+func newBordaScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	// the requests are weighted equally if no weights
+	weights := make([]float32, len(reqs))
+	if _, ok := params[WeightsParamsKey]; ok {
+		var err error
+		weights, err = parseRankWeights(params[WeightsParamsKey], len(reqs), unboundedWeights(params))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	log.Debug("borda params", zap.Any("weights", weights))
+	for i := range reqs {
+		res[i] = &bordaScorer{
+			baseScorer: baseScorer{
+				scorerName: "borda",
+			},
+			weight: weights[i],
+		}
+	}
+	return res, nil
+}
+
+// newDecayScorers creates the scorers of the requests decaying the scores by the field of the schema
+func newDecayScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	ds, err := parseDecayScorer(params, schema)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("decay params", zap.String("field", ds.field), zap.Float64("origin", ds.origin),
+		zap.Float64("scale", ds.scale), zap.Float64("offset", ds.offset), zap.Float64("decay", ds.decay))
+	for i := range reqs {
+		scorer := *ds
+		res[i] = &scorer
+	}
+	return res, nil
+}
+
+// newSigmoidScorers creates the scorers of the requests transforming the scores by the sigmoid
+func newSigmoidScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	sigmoid, err := parseSigmoidTransform(params)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("sigmoid params", zap.Float64("a", sigmoid.a), zap.Float64("b", sigmoid.b))
+	for i := range reqs {
+		res[i] = &sigmoidScorer{
+			baseScorer: baseScorer{
+				scorerName: "sigmoid",
+			},
+			sigmoid: sigmoid,
+		}
+	}
+	return res, nil
+}
+
+// newBoostScorers creates the scorers of the requests boosting the scores by the rules of the fields of the schema
+func newBoostScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	rules, err := parseBoostRules(params, schema)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("boost params", zap.Any("rules", rules))
+	for i := range reqs {
+		res[i] = &boostScorer{
+			baseScorer: baseScorer{
+				scorerName: "boost",
+			},
+			rules: rules,
 		}
+	}
+	return res, nil
+}
 
-		log.Debug("weights params", zap.Any("weights", weights))
-		if len(reqs) != len(weights) {
-			return nil, merr.WrapErrParameterInvalid(fmt.Sprint(len(reqs)), fmt.Sprint(len(weights)), "the length of weights param mismatch with ann search requests")
+// newMultiplyScorers creates the scorers of the requests the scores of which are multiplied, see productFuser
+func newMultiplyScorers(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+	res := make([]reScorer, len(reqs))
+	normalizer := noScoreNormalizer
+	if value, ok := params[NormScoreKey]; ok {
+		name, _ := value.(string)
+		if normalizer, ok = scoreNormalizerMap[name]; !ok {
+			return nil, merr.WrapErrParameterInvalidMsg("unsupported rank param %s %v, should be min_max or z_score", NormScoreKey, value)
+		}
+	}
+	// the distances negated are never positive, the product of which makes no sense
+	if value, ok := params[SimilarityKey]; ok && value != "reciprocal" {
+		return nil, merr.WrapErrParameterInvalidMsg("unsupported rank param %s %v of rank multiply, should be reciprocal", SimilarityKey, value)
+	}
+	missing, err := parseMissingValue(params)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("multiply params", zap.Float32("missing value", missing))
+	for i, req := range reqs {
+		metricType, err := funcutil.GetAttrByKeyFromRepeatedKV(MetricTypeKey, req.GetSearchParams())
+		res[i] = &multiplyScorer{
+			baseScorer: baseScorer{
+				scorerName: "multiply",
+			},
+			normalizer:        normalizer,
+			positivelyRelated: err != nil || metric.PositivelyRelated(metricType),
+			missing:           missing,
 		}
+	}
+	return res, nil
+}
+
+// newExtremumScorers returns the factory of the scorers of the requests, the max or min of the scores of which
+// is the score of an entity, see extremumFuser
+func newExtremumScorers(rank rankType, name string) ScorerFactory {
+	return func(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+		res := make([]reScorer, len(reqs))
 		for i := range reqs {
-			res[i] = &weightedScorer{
+			res[i] = &extremumScorer{
 				baseScorer: baseScorer{
-					scorerName: "weighted",
+					scorerName: name,
 				},
-				weight: weights[i],
+				rank: rank,
 			}
 		}
-	default:
-		return nil, errors.Errorf("unsupported rank type %s", rankTypeStr)
+		return res, nil
 	}
-
-	return res, nil
 }
@@ -1,18 +1,27 @@
 package proxy
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 func TestRescorer(t *testing.T) {
 	t.Run("default scorer", func(t *testing.T) {
-		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, nil)
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, nil, nil)
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(rescorers))
 		assert.Equal(t, rrfRankType, rescorers[0].scorerType())
@@ -27,7 +36,7 @@ func TestRescorer(t *testing.T) {
 			{Key: RankParamsKey, Value: string(b)},
 		}
 
-		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "k not found in rank_params")
 	})
@@ -42,7 +51,7 @@ func TestRescorer(t *testing.T) {
 			{Key: RankParamsKey, Value: string(b)},
 		}
 
-		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
 		assert.Error(t, err)
 
 		params[RRFParamsKey] = maxRRFParamsValue + 1
@@ -53,7 +62,7 @@ func TestRescorer(t *testing.T) {
 			{Key: RankParamsKey, Value: string(b)},
 		}
 
-		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
 		assert.Error(t, err)
 	})
 
@@ -67,13 +76,80 @@ func TestRescorer(t *testing.T) {
 			{Key: RankParamsKey, Value: string(b)},
 		}
 
-		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams)
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(rescorers))
 		assert.Equal(t, rrfRankType, rescorers[0].scorerType())
 		assert.Equal(t, float32(61), rescorers[0].(*rrfScorer).k)
 	})
 
+	t.Run("rrf of each request", func(t *testing.T) {
+		rankParams := func(k any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(map[string]any{RRFParamsKey: k})
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "rrf"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams([]float64{60, 10}), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(rescorers))
+		assert.Equal(t, float32(60), rescorers[0].(*rrfScorer).k)
+		assert.Equal(t, float32(10), rescorers[1].(*rrfScorer).k)
+		result := &milvuspb.SearchResults{Results: &schemapb.SearchResultData{Scores: []float32{0.9, 0.5}}}
+		rescorers[1].reScore(result)
+		assert.Equal(t, []float32{1.0 / 11, 1.0 / 12}, result.GetResults().GetScores())
+
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams([]float64{60}), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "the length of rank param k mismatch")
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams([]any{60, -1}), nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "k[1] should be in range (0, 16384)")
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams([]any{"a", 10}), nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `k[0] should be a number or a string of finite float, got "a"`)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams(nil), nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "k should be float")
+	})
+
+	t.Run("rrf of each query", func(t *testing.T) {
+		newRankParams := func(k any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(map[string]any{RRFParamsKey: k})
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "rrf"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		reqs := []*milvuspb.SearchRequest{{Nq: 2}, {Nq: 2}}
+
+		rescorers, err := NewReScorer(reqs, newRankParams([]any{[]any{1, "9"}, 60}), nil)
+		assert.NoError(t, err)
+		result := &milvuspb.SearchResults{Results: &schemapb.SearchResultData{Scores: make([]float32, 4), Topks: []int64{2, 2}}}
+		assert.NoError(t, rescorers[0].reScore(result))
+		assert.InDeltaSlice(t, []float32{1.0 / 2, 1.0 / 3, 1.0 / 10, 1.0 / 11}, result.GetResults().GetScores(), 1e-6)
+		result = &milvuspb.SearchResults{Results: &schemapb.SearchResultData{Scores: make([]float32, 3), Topks: []int64{1, 2}}}
+		assert.NoError(t, rescorers[1].reScore(result))
+		assert.InDeltaSlice(t, []float32{1.0 / 61, 1.0 / 61, 1.0 / 62}, result.GetResults().GetScores(), 1e-6)
+		// the results of other queries than nq
+		result = &milvuspb.SearchResults{Results: &schemapb.SearchResultData{Scores: make([]float32, 3), Topks: []int64{1, 1, 1}}}
+		assert.ErrorIs(t, rescorers[0].reScore(result), merr.ErrServiceInternal)
+
+		_, err = NewReScorer(reqs, newRankParams([]any{60, []any{1, 9, 10}}), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.ErrorContains(t, err, "the length of rank param k[1] mismatch with nq of ann search request 1")
+		assert.ErrorContains(t, err, "expected=2")
+		_, err = NewReScorer(reqs, newRankParams([]any{[]any{1, 0}, 60}), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.ErrorContains(t, err, "rank param k[0][1] should be in range (0, 16384)")
+		_, err = NewReScorer(reqs, newRankParams([]any{[]any{[]any{1}, 1}, 60}), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+	})
+
 	t.Run("weights without param", func(t *testing.T) {
 		params := make(map[string][]float64)
 		b, err := json.Marshal(params)
@@ -83,7 +159,7 @@ func TestRescorer(t *testing.T) {
 			{Key: RankParamsKey, Value: string(b)},
 		}
 
-		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "not found in rank_params")
 	})
@@ -99,9 +175,9 @@ func TestRescorer(t *testing.T) {
 			{Key: RankParamsKey, Value: string(b)},
 		}
 
-		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "rank param weight should be in range [0, 1]")
+		assert.Contains(t, err.Error(), "rank param weights[0] should be in range [0, 1]")
 	})
 
 	t.Run("weights", func(t *testing.T) {
@@ -115,10 +191,1459 @@ func TestRescorer(t *testing.T) {
 			{Key: RankParamsKey, Value: string(b)},
 		}
 
-		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams)
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(rescorers))
 		assert.Equal(t, weightedRankType, rescorers[0].scorerType())
 		assert.Equal(t, float32(weights[0]), rescorers[0].(*weightedScorer).weight)
 	})
+
+	t.Run("unbounded weights", func(t *testing.T) {
+		rankParams := func(params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "weighted"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+
+		_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams(map[string]any{WeightsParamsKey: []float64{3, -0.5}}), nil)
+		assert.ErrorContains(t, err, "rank param weights[0] should be in range [0, 1], or set allow_unbounded_weights to true, got 3")
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams(map[string]any{
+			WeightsParamsKey:    []float64{3, -0.5},
+			UnboundedWeightsKey: true,
+		}), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(3), rescorers[0].(*weightedScorer).weight)
+		assert.Equal(t, float32(-0.5), rescorers[1].(*weightedScorer).weight)
+
+		// NaN and Inf are not valid JSON, but rejected anyway
+		for _, unbounded := range []bool{false, true} {
+			_, err = parseRankWeights([]interface{}{math.NaN()}, 1, unbounded)
+			assert.ErrorContains(t, err, "rank param weights[0] should be finite")
+			_, err = parseRankWeights([]interface{}{math.Inf(1)}, 1, unbounded)
+			assert.ErrorContains(t, err, "rank param weights[0] should be finite")
+		}
+	})
+
+	t.Run("weights with norm score", func(t *testing.T) {
+		rankParams := func(norm any) []*commonpb.KeyValuePair {
+			// the distances negated are normalized linearly
+			b, err := json.Marshal(map[string]any{WeightsParamsKey: []float64{0.5, 0.5}, NormScoreKey: norm, SimilarityKey: "negate"})
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "weighted"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		reqs := []*milvuspb.SearchRequest{
+			{SearchParams: []*commonpb.KeyValuePair{{Key: MetricTypeKey, Value: "IP"}}},
+			{SearchParams: []*commonpb.KeyValuePair{{Key: MetricTypeKey, Value: "L2"}}},
+		}
+		reScore := func(rescorer reScorer, topks []int64, scores ...float32) []float32 {
+			result := &milvuspb.SearchResults{Results: &schemapb.SearchResultData{Topks: topks, Scores: scores}}
+			rescorer.reScore(result)
+			return result.GetResults().GetScores()
+		}
+
+		_, err := NewReScorer(reqs, rankParams("l1"), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		_, err = NewReScorer(reqs, rankParams(1), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+
+		t.Run("min max", func(t *testing.T) {
+			rescorers, err := NewReScorer(reqs, rankParams("min_max"), nil)
+			assert.NoError(t, err)
+			assert.InDeltaSlice(t, []float32{0.5, 0.25, 0, 0.5}, reScore(rescorers[0], []int64{3, 1}, 0.9, 0.8, 0.7, 0.3), 1e-6)
+			// the closer the higher by L2
+			assert.InDeltaSlice(t, []float32{0.5, 0.25, 0}, reScore(rescorers[1], []int64{3}, 100, 200, 300), 1e-6)
+			// all equal, or a single one
+			assert.Equal(t, []float32{0.5, 0.5}, reScore(rescorers[1], []int64{2}, 300, 300))
+			assert.Equal(t, []float32{0.5}, reScore(rescorers[0], []int64{1}, 0.9))
+			assert.Empty(t, reScore(rescorers[0], []int64{0}))
+		})
+
+		t.Run("z score", func(t *testing.T) {
+			rescorers, err := NewReScorer(reqs, rankParams("z_score"), nil)
+			assert.NoError(t, err)
+			scores := reScore(rescorers[0], []int64{3}, 0.9, 0.8, 0.7)
+			assert.InDelta(t, 0.5/(1+math.Exp(-math.Sqrt(1.5))), scores[0], 1e-6)
+			assert.InDelta(t, 0.25, scores[1], 1e-6)
+			assert.InDelta(t, 0.5/(1+math.Exp(math.Sqrt(1.5))), scores[2], 1e-6)
+			scores = reScore(rescorers[1], []int64{3}, 100, 200, 300)
+			assert.InDelta(t, 0.5/(1+math.Exp(-math.Sqrt(1.5))), scores[0], 1e-6)
+			assert.InDelta(t, 0.5/(1+math.Exp(math.Sqrt(1.5))), scores[2], 1e-6)
+			// all equal, or a single one
+			assert.Equal(t, []float32{0.25, 0.25}, reScore(rescorers[1], []int64{2}, 300, 300))
+			assert.Equal(t, []float32{0.25}, reScore(rescorers[0], []int64{1}, 0.9))
+		})
+	})
+
+	t.Run("weights of L2 and IP", func(t *testing.T) {
+		weightedRankParams := func(params map[string]any) []*commonpb.KeyValuePair {
+			params[WeightsParamsKey] = []float64{0.5, 0.5}
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "weighted"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		reqs := []*milvuspb.SearchRequest{
+			{SearchParams: []*commonpb.KeyValuePair{{Key: MetricTypeKey, Value: "IP"}}},
+			{SearchParams: []*commonpb.KeyValuePair{{Key: MetricTypeKey, Value: "L2"}}},
+		}
+		newResult := func(ids []int64, scores []float32) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+				Scores: scores,
+				Topks:  []int64{int64(len(ids))},
+			}}
+		}
+
+		_, err := NewReScorer(reqs, weightedRankParams(map[string]any{SimilarityKey: "inverse"}), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+
+		rescorers, err := NewReScorer(reqs, weightedRankParams(map[string]any{}), nil)
+		assert.NoError(t, err)
+		assert.True(t, rescorers[0].(*weightedScorer).positivelyRelated)
+		assert.False(t, rescorers[1].(*weightedScorer).positivelyRelated)
+		results := []*milvuspb.SearchResults{
+			newResult([]int64{1, 2, 3}, []float32{0.9, 0.5, 0.1}),
+			newResult([]int64{3, 4, 5}, []float32{0.2, 0.5, 3}),
+		}
+		for i, result := range results {
+			rescorers[i].reScore(result)
+		}
+		ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: 5, roundDecimal: -1},
+			schemapb.DataType_Int64, results, scoreFusion(rescorers), nil)
+		assert.NoError(t, err)
+		// the closest by L2 is the highest rather than the farthest, 3: 0.5*0.1 + 0.5/(1+0.2), 4: 0.5/(1+0.5), 5: 0.5/(1+3)
+		assert.Equal(t, []int64{3, 1, 4, 2, 5}, ranked.GetResults().GetIds().GetIntId().GetData())
+		assert.InDeltaSlice(t, []float32{0.05 + 0.5/1.2, 0.45, 0.5 / 1.5, 0.25, 0.125}, ranked.GetResults().GetScores(), 1e-6)
+
+		rescorers, err = NewReScorer(reqs, weightedRankParams(map[string]any{SimilarityKey: "negate"}), nil)
+		assert.NoError(t, err)
+		result := newResult([]int64{3, 4}, []float32{0.2, 0.5})
+		rescorers[1].reScore(result)
+		assert.InDeltaSlice(t, []float32{-0.1, -0.25}, result.GetResults().GetScores(), 1e-6)
+	})
+
+	exprRankParams := func(code any) []*commonpb.KeyValuePair {
+		b, err := json.Marshal(map[string]any{ExprParamsKey: code})
+		assert.NoError(t, err)
+		return []*commonpb.KeyValuePair{
+			{Key: RankTypeKey, Value: "expr"},
+			{Key: RankParamsKey, Value: string(b)},
+		}
+	}
+
+	t.Run("expr without param", func(t *testing.T) {
+		_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, exprRankParams(1), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "expr of string not found in rank_params")
+	})
+
+	t.Run("invalid expr", func(t *testing.T) {
+		for _, code := range []string{"score_0 +", "score_0 > 1", "unknown(score_0)", `"score"`} {
+			_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, exprRankParams(code), nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid, code)
+		}
+		// only 2 requests
+		_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, exprRankParams("score_0 + score_2"), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "score_2")
+	})
+
+	t.Run("expr", func(t *testing.T) {
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, exprRankParams("0.7*score_0 + 0.3*log(1+score_1)"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(rescorers))
+		assert.Equal(t, udfExprRankType, rescorers[0].scorerType())
+		fusion := scoreFusion(rescorers)
+		assert.NotNil(t, fusion)
+
+		// the scores are kept as they are, and fused later
+		result := &milvuspb.SearchResults{Results: &schemapb.SearchResultData{Scores: []float32{0.5, 0.2}}}
+		rescorers[1].reScore(result)
+		assert.Equal(t, []float32{0.5, 0.2}, result.GetResults().GetScores())
+
+		score, err := fusion.fuse([]float32{0.5, 2}, []bool{true, true})
+		assert.NoError(t, err)
+		assert.InDelta(t, 0.7*0.5+0.3*math.Log(3), score, 1e-6)
+		score, err = fusion.fuse([]float32{0, 0}, []bool{false, false})
+		assert.NoError(t, err)
+		assert.InDelta(t, 0, score, 1e-6)
+	})
+
+	t.Run("rank by expr", func(t *testing.T) {
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, exprRankParams("max(score_0, 2*score_1)"), nil)
+		assert.NoError(t, err)
+		newResult := func(ids []int64, scores []float32) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+				Scores: scores,
+				Topks:  []int64{int64(len(ids))},
+			}}
+		}
+		results := []*milvuspb.SearchResults{
+			newResult([]int64{1, 2, 3}, []float32{0.9, 0.5, 0.1}),
+			newResult([]int64{3, 4}, []float32{0.6, 0.3}),
+		}
+		ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: 3, roundDecimal: -1},
+			schemapb.DataType_Int64, results, scoreFusion(rescorers), nil)
+		assert.NoError(t, err)
+		// 1: max(0.9, 0), 2: max(0.5, 0), 3: max(0.1, 1.2), 4: max(0, 0.6)
+		assert.Equal(t, []int64{3, 1, 4}, ranked.GetResults().GetIds().GetIntId().GetData())
+		assert.InDeltaSlice(t, []float32{1.2, 0.9, 0.6}, ranked.GetResults().GetScores(), 1e-6)
+		assert.Equal(t, []int64{3}, ranked.GetResults().GetTopks())
+	})
+
+	t.Run("rank by max and min", func(t *testing.T) {
+		newResult := func(ids []string, scores []float32) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: ids}}},
+				Scores: scores,
+				Topks:  []int64{int64(len(ids))},
+			}}
+		}
+		rank := func(rankType string, results ...*milvuspb.SearchResults) *milvuspb.SearchResults {
+			// no params are needed
+			rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: rankType}}, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, rankTypeMap[rankType], rescorers[0].scorerType())
+			for i, result := range results {
+				rescorers[i].reScore(result)
+			}
+			ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: 10, roundDecimal: -1},
+				schemapb.DataType_VarChar, results, scoreFusion(rescorers), nil)
+			assert.NoError(t, err)
+			return ranked
+		}
+
+		// overlapping, b and c are recalled by both
+		overlapping := func() []*milvuspb.SearchResults {
+			return []*milvuspb.SearchResults{
+				newResult([]string{"a", "b", "c"}, []float32{0.9, 0.6, 0.3}),
+				newResult([]string{"c", "b", "d"}, []float32{0.8, 0.5, 0.6}),
+			}
+		}
+		ranked := rank("max", overlapping()...)
+		assert.Equal(t, []string{"a", "c", "b", "d"}, ranked.GetResults().GetIds().GetStrId().GetData())
+		assert.InDeltaSlice(t, []float32{0.9, 0.8, 0.6, 0.6}, ranked.GetResults().GetScores(), 1e-6)
+		ranked = rank("min", overlapping()...)
+		assert.Equal(t, []string{"a", "d", "b", "c"}, ranked.GetResults().GetIds().GetStrId().GetData())
+		assert.InDeltaSlice(t, []float32{0.9, 0.6, 0.5, 0.3}, ranked.GetResults().GetScores(), 1e-6)
+
+		// disjoint, the ties are broken by the ids
+		disjoint := func() []*milvuspb.SearchResults {
+			return []*milvuspb.SearchResults{
+				newResult([]string{"c", "a"}, []float32{0.7, 0.5}),
+				newResult([]string{"d", "b"}, []float32{0.7, 0.5}),
+			}
+		}
+		for _, rankType := range []string{"max", "min"} {
+			ranked = rank(rankType, disjoint()...)
+			assert.Equal(t, []string{"c", "d", "a", "b"}, ranked.GetResults().GetIds().GetStrId().GetData(), rankType)
+			assert.InDeltaSlice(t, []float32{0.7, 0.7, 0.5, 0.5}, ranked.GetResults().GetScores(), 1e-6, rankType)
+		}
+	})
+
+	t.Run("borda", func(t *testing.T) {
+		newResult := func(topks []int64, ids ...string) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: ids}}},
+				Scores: make([]float32, len(ids)),
+				Topks:  topks,
+			}}
+		}
+		rank := func(nq int64, params []*commonpb.KeyValuePair, results ...*milvuspb.SearchResults) *milvuspb.SearchResults {
+			rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, params, nil)
+			assert.NoError(t, err)
+			for i, result := range results {
+				rescorers[i].reScore(result)
+			}
+			ranked, err := rankSearchResultData(context.Background(), nq, &rankParams{limit: 10, roundDecimal: -1},
+				schemapb.DataType_VarChar, results, scoreFusion(rescorers), nil)
+			assert.NoError(t, err)
+			return ranked
+		}
+		bordaRankParams := func(params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "borda"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+
+		_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, bordaRankParams(map[string]any{WeightsParamsKey: []float64{1}}), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, bordaRankParams(map[string]any{WeightsParamsKey: 1}), nil)
+		assert.ErrorContains(t, err, "rank param weights should be an array, got 1")
+
+		// d is the last of the first request, but the only one of the second request
+		results := func() []*milvuspb.SearchResults {
+			return []*milvuspb.SearchResults{
+				newResult([]int64{4}, "a", "b", "c", "d"),
+				newResult([]int64{1}, "d"),
+			}
+		}
+		// a: 4, b: 3, c: 2, d: 1 + 1, the ties are broken by the ids
+		ranked := rank(1, []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: "borda"}}, results()...)
+		assert.Equal(t, []string{"a", "b", "c", "d"}, ranked.GetResults().GetIds().GetStrId().GetData())
+		assert.Equal(t, []float32{4, 3, 2, 2}, ranked.GetResults().GetScores())
+		// d: 1/64 + 1/61 wins by rrf
+		ranked = rank(1, nil, results()...)
+		assert.Equal(t, []string{"d", "a", "b", "c"}, ranked.GetResults().GetIds().GetStrId().GetData())
+
+		// a: 4*0.5, b: 3*0.5, c: 2*0.5, d: 0.5 + 1
+		ranked = rank(1, bordaRankParams(map[string]any{WeightsParamsKey: []float64{0.5, 1}}), results()...)
+		assert.Equal(t, []string{"a", "b", "d", "c"}, ranked.GetResults().GetIds().GetStrId().GetData())
+		assert.Equal(t, []float32{2, 1.5, 1.5, 1}, ranked.GetResults().GetScores())
+
+		// the positions are reset by each query
+		ranked = rank(2, bordaRankParams(map[string]any{}),
+			newResult([]int64{2, 3}, "a", "b", "c", "d", "e"),
+			newResult([]int64{1, 1}, "b", "e"))
+		assert.Equal(t, []int64{2, 3}, ranked.GetResults().GetTopks())
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ranked.GetResults().GetIds().GetStrId().GetData())
+		assert.Equal(t, []float32{2, 2, 3, 2, 2}, ranked.GetResults().GetScores())
+	})
+
+	t.Run("decay", func(t *testing.T) {
+		schema := &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{
+			{Name: "publish_ts", DataType: schemapb.DataType_Int64},
+			{Name: "title", DataType: schemapb.DataType_VarChar},
+		}}
+		decayRankParams := func(params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "decay"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		newResult := func(scores []float32, fieldsData ...*schemapb.FieldData) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Scores:     scores,
+				Topks:      []int64{int64(len(scores))},
+				FieldsData: fieldsData,
+			}}
+		}
+		publishTs := func(values ...int64) *schemapb.FieldData {
+			return &schemapb.FieldData{
+				FieldName: "publish_ts",
+				Type:      schemapb.DataType_Int64,
+				Field: &schemapb.FieldData_Scalars{Scalars: &schemapb.ScalarField{
+					Data: &schemapb.ScalarField_LongData{LongData: &schemapb.LongArray{Data: values}},
+				}},
+			}
+		}
+
+		for _, params := range []map[string]any{
+			{DecayScaleKey: 10},
+			{DecayFieldKey: "author", DecayScaleKey: 10},
+			{DecayFieldKey: "title", DecayScaleKey: 10},
+			{DecayFieldKey: "publish_ts", DecayFuncKey: "sigmoid", DecayScaleKey: 10},
+			{DecayFieldKey: "publish_ts"},
+			{DecayFieldKey: "publish_ts", DecayScaleKey: "ten"},
+			{DecayFieldKey: "publish_ts", DecayScaleKey: 10, DecayOffsetKey: -1},
+			{DecayFieldKey: "publish_ts", DecayScaleKey: 10, DecayFactorKey: 1},
+		} {
+			_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, decayRankParams(params), schema)
+			assert.Error(t, err, params)
+		}
+
+		for fn, expected := range map[string][]float32{
+			// 1 within offset, decay at offset+scale, and decay^4, decay^2 or 0 at offset+2*scale, i.e. 5, 15 and 25 from origin
+			"gauss":  {0.8, 0.4, 0.8 * float32(math.Pow(0.5, 4))},
+			"exp":    {0.8, 0.4, 0.2},
+			"linear": {0.8, 0.4, 0},
+		} {
+			rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, decayRankParams(map[string]any{
+				DecayFieldKey:  "publish_ts",
+				DecayFuncKey:   fn,
+				DecayOriginKey: 100,
+				DecayScaleKey:  10,
+				DecayOffsetKey: 5,
+			}), schema)
+			assert.NoError(t, err)
+			assert.Equal(t, decayRankType, rescorers[0].scorerType())
+			assert.Equal(t, []string{"publish_ts"}, scorerOutputFields(rescorers[1]))
+			result := newResult([]float32{0.8, 0.8, 0.8}, publishTs(95, 115, 75))
+			assert.NoError(t, rescorers[0].reScore(result))
+			assert.InDeltaSlice(t, expected, result.GetResults().GetScores(), 1e-6, fn)
+		}
+
+		// the origin is now by default
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}}, decayRankParams(map[string]any{
+			DecayFieldKey: "publish_ts",
+			DecayScaleKey: 3600,
+		}), schema)
+		assert.NoError(t, err)
+		result := newResult([]float32{1, 1}, publishTs(time.Now().Unix(), time.Now().Add(-time.Hour).Unix()))
+		assert.NoError(t, rescorers[0].reScore(result))
+		assert.InDeltaSlice(t, []float32{1, 0.5}, result.GetResults().GetScores(), 1e-3)
+
+		// the field data missing fails rather than skipped
+		err = rescorers[0].reScore(newResult([]float32{1}))
+		assert.ErrorIs(t, err, merr.ErrFieldNotFound)
+		err = rescorers[0].reScore(newResult([]float32{1, 1}, publishTs(1)))
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Nil(t, scorerOutputFields(&rrfScorer{}))
+	})
+
+	t.Run("multiple queries", func(t *testing.T) {
+		newResult := func(topks []int64, ids []int64, scores []float32) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+				Scores: scores,
+				Topks:  topks,
+			}}
+		}
+		weightedRankParams := func(params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "weighted"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+
+		// the ranks are reset by each of the 3 queries
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, nil, nil)
+		assert.NoError(t, err)
+		results := []*milvuspb.SearchResults{
+			newResult([]int64{3, 1, 2}, []int64{1, 2, 3, 4, 5, 6}, []float32{0.9, 0.8, 0.7, 0.6, 0.5, 0.4}),
+			newResult([]int64{1, 0, 2}, []int64{3, 7, 5}, []float32{0.9, 0.8, 0.7}),
+		}
+		for i, result := range results {
+			assert.NoError(t, rescorers[i].reScore(result))
+		}
+		assert.InDeltaSlice(t, []float32{1.0 / 61, 1.0 / 62, 1.0 / 63, 1.0 / 61, 1.0 / 61, 1.0 / 62}, results[0].GetResults().GetScores(), 1e-6)
+		assert.InDeltaSlice(t, []float32{1.0 / 61, 1.0 / 61, 1.0 / 62}, results[1].GetResults().GetScores(), 1e-6)
+		ranked, err := rankSearchResultData(context.Background(), 3, &rankParams{limit: 10, roundDecimal: -1},
+			schemapb.DataType_Int64, results, scoreFusion(rescorers), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3, 1, 3}, ranked.GetResults().GetTopks())
+		// 3: 1/63 + 1/61 of the first query, 7: 1/61 + 0 of the third query
+		assert.Equal(t, []int64{3, 1, 2, 4, 5, 7, 6}, ranked.GetResults().GetIds().GetIntId().GetData())
+		assert.InDeltaSlice(t, []float32{1.0/63 + 1.0/61, 1.0 / 61, 1.0 / 62, 1.0 / 61, 1.0/61 + 1.0/62, 1.0 / 61, 1.0 / 62},
+			ranked.GetResults().GetScores(), 1e-6)
+
+		// the scores are normalized by each query
+		rescorers, err = NewReScorer([]*milvuspb.SearchRequest{{}}, weightedRankParams(map[string]any{
+			WeightsParamsKey: []float64{0.5},
+			NormScoreKey:     "min_max",
+		}), nil)
+		assert.NoError(t, err)
+		result := newResult([]int64{3, 1, 2}, []int64{1, 2, 3, 4, 5, 6}, []float32{0.9, 0.8, 0.7, 0.6, 0.5, 0.4})
+		assert.NoError(t, rescorers[0].reScore(result))
+		assert.InDeltaSlice(t, []float32{0.5, 0.25, 0, 0.5, 0.5, 0}, result.GetResults().GetScores(), 1e-6)
+
+		// the topks mismatch with the scores
+		for _, topks := range [][]int64{{3, 1, 1}, {3, 1, 3}, {3, -1, 4}} {
+			result := newResult(topks, []int64{1, 2, 3, 4, 5, 6}, []float32{0.9, 0.8, 0.7, 0.6, 0.5, 0.4})
+			assert.ErrorIs(t, rescorers[0].reScore(result), merr.ErrServiceInternal, topks)
+		}
+	})
+
+	t.Run("group by", func(t *testing.T) {
+		newResult := func(topks []int64, groups ...string) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Scores: make([]float32, len(groups)),
+				Topks:  topks,
+				GroupByFieldValue: &schemapb.FieldData{
+					Type: schemapb.DataType_VarChar,
+					Field: &schemapb.FieldData_Scalars{Scalars: &schemapb.ScalarField{
+						Data: &schemapb.ScalarField_StringData{StringData: &schemapb.StringArray{Data: groups}},
+					}},
+				},
+			}}
+		}
+		bordaRankParams := []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: "borda"}}
+
+		// the groups interleave, the ranks are of each group
+		rrf, err := NewReScorer([]*milvuspb.SearchRequest{{}}, nil, nil)
+		assert.NoError(t, err)
+		result := newResult([]int64{5}, "a", "b", "a", "a", "b")
+		assert.NoError(t, rrf[0].reScore(result))
+		assert.InDeltaSlice(t, []float32{1.0 / 61, 1.0 / 61, 1.0 / 62, 1.0 / 63, 1.0 / 62}, result.GetResults().GetScores(), 1e-6)
+		borda, err := NewReScorer([]*milvuspb.SearchRequest{{}}, bordaRankParams, nil)
+		assert.NoError(t, err)
+		result = newResult([]int64{5}, "a", "b", "a", "a", "b")
+		assert.NoError(t, borda[0].reScore(result))
+		assert.Equal(t, []float32{3, 2, 2, 1, 1}, result.GetResults().GetScores())
+
+		// the groups of each query
+		result = newResult([]int64{3, 2}, "a", "b", "a", "a", "a")
+		assert.NoError(t, rrf[0].reScore(result))
+		assert.InDeltaSlice(t, []float32{1.0 / 61, 1.0 / 61, 1.0 / 62, 1.0 / 61, 1.0 / 62}, result.GetResults().GetScores(), 1e-6)
+
+		// the group by values mismatch with the results
+		result = newResult([]int64{3}, "a", "b")
+		result.Results.Scores = make([]float32, 3)
+		err = rrf[0].reScore(result)
+		assert.ErrorIs(t, err, merr.ErrServiceInternal)
+		assert.ErrorContains(t, err, "group by values")
+		result = newResult([]int64{1}, "a")
+		result.Results.GroupByFieldValue.Type = schemapb.DataType_Float
+		assert.ErrorIs(t, borda[0].reScore(result), merr.ErrParameterInvalid)
+	})
+
+	t.Run("per request", func(t *testing.T) {
+		newRankParams := func(rankType string, params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: rankType},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		reqs := []*milvuspb.SearchRequest{{}, {}}
+
+		// the first request is normalized, but not the second one
+		rescorers, err := NewReScorer(reqs, newRankParams("weighted", map[string]any{
+			WeightsParamsKey:        []float64{0.5, 0.5},
+			PerRequestRankParamsKey: []any{map[string]any{NormScoreKey: "min_max"}, nil},
+		}), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, minMaxScoreNormalizer, rescorers[0].(*weightedScorer).normalizer)
+		assert.Equal(t, noScoreNormalizer, rescorers[1].(*weightedScorer).normalizer)
+		results := []*milvuspb.SearchResults{
+			{Results: &schemapb.SearchResultData{Scores: []float32{0.9, 0.8, 0.7}, Topks: []int64{3}}},
+			{Results: &schemapb.SearchResultData{Scores: []float32{0.9, 0.8, 0.7}, Topks: []int64{3}}},
+		}
+		for i, result := range results {
+			assert.NoError(t, rescorers[i].reScore(result))
+		}
+		assert.InDeltaSlice(t, []float32{0.5, 0.25, 0}, results[0].GetResults().GetScores(), 1e-6)
+		assert.InDeltaSlice(t, []float32{0.45, 0.4, 0.35}, results[1].GetResults().GetScores(), 1e-6)
+
+		// the overrides win key by key
+		rescorers, err = NewReScorer(reqs, newRankParams("rrf", map[string]any{
+			RRFParamsKey:            60,
+			PerRequestRankParamsKey: []any{map[string]any{}, map[string]any{RRFParamsKey: 10}},
+		}), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(60), rescorers[0].(*rrfScorer).k)
+		assert.Equal(t, float32(10), rescorers[1].(*rrfScorer).k)
+
+		schema := &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{
+			{Name: "publish_ts", DataType: schemapb.DataType_Int64},
+			{Name: "price", DataType: schemapb.DataType_Float},
+		}}
+		rescorers, err = NewReScorer(reqs, newRankParams("decay", map[string]any{
+			DecayFieldKey:           "publish_ts",
+			DecayScaleKey:           10,
+			PerRequestRankParamsKey: []any{map[string]any{}, map[string]any{DecayFieldKey: "price", DecayFuncKey: "linear"}},
+		}), schema)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"publish_ts"}, scorerOutputFields(rescorers[0]))
+		assert.Equal(t, []string{"price"}, scorerOutputFields(rescorers[1]))
+		assert.Equal(t, linearDecayFunc, rescorers[1].(*decayScorer).fn)
+
+		for _, params := range []map[string]any{
+			{RRFParamsKey: 60, PerRequestRankParamsKey: []any{map[string]any{}}},
+			{RRFParamsKey: 60, PerRequestRankParamsKey: map[string]any{}},
+			{RRFParamsKey: 60, PerRequestRankParamsKey: []any{1, 2}},
+			{RRFParamsKey: 60, PerRequestRankParamsKey: []any{map[string]any{PerRequestRankParamsKey: []any{}}, nil}},
+		} {
+			_, err = NewReScorer(reqs, newRankParams("rrf", params), nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid, params)
+		}
+		_, err = NewReScorer(reqs, newRankParams("rrf", map[string]any{
+			RRFParamsKey:            60,
+			PerRequestRankParamsKey: []any{nil, map[string]any{RRFParamsKey: 0}},
+		}), nil)
+		assert.ErrorContains(t, err, "invalid rank params of request 1")
+		_, err = NewReScorer(reqs, newRankParams("expr", map[string]any{
+			ExprParamsKey:           "score_0",
+			PerRequestRankParamsKey: []any{nil, nil},
+		}), nil)
+		assert.ErrorContains(t, err, "per_request is not supported by rank expr")
+
+		// the unknown keys are rejected only in the strict mode
+		for _, params := range []map[string]any{
+			{RRFParamsKey: 60, "norm_scores": "min_max"},
+			{RRFParamsKey: 60, PerRequestRankParamsKey: []any{nil, map[string]any{NormScoreKey: "min_max"}}},
+		} {
+			_, err = NewReScorer(reqs, newRankParams("rrf", params), nil)
+			assert.NoError(t, err)
+			params[StrictRankParamsKey] = true
+			_, err = NewReScorer(reqs, newRankParams("rrf", params), nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+			assert.ErrorContains(t, err, "unknown key")
+		}
+	})
+
+	t.Run("boost", func(t *testing.T) {
+		schema := &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{
+			{Name: "category", DataType: schemapb.DataType_VarChar},
+			{Name: "price", DataType: schemapb.DataType_Int32},
+			{Name: "vector", DataType: schemapb.DataType_FloatVector},
+		}}
+		boostRankParams := func(rules ...map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(map[string]any{BoostRulesKey: rules})
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "boost"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+
+		for _, rule := range []map[string]any{
+			{"field": "brand", "value": "a", "multiplier": 1.2},
+			{"field": "vector", "value": "a", "multiplier": 1.2},
+			{"field": "category", "value": "premium"},
+			{"field": "category", "value": "premium", "multiplier": -1},
+			{"field": "category", "value": 1, "multiplier": 1.2},
+			{"field": "category", "range": []float64{1, 2}, "multiplier": 1.2},
+			{"field": "price", "range": []float64{2, 1}, "multiplier": 1.2},
+			{"field": "price", "range": []float64{1}, "multiplier": 1.2},
+			{"field": "price", "value": 1, "range": []float64{1, 2}, "multiplier": 1.2},
+			{"field": "price", "multiplier": 1.2},
+			{"field": "price", "range": []any{"1", "a"}, "multiplier": 1.2},
+		} {
+			_, err := NewReScorer([]*milvuspb.SearchRequest{{}}, boostRankParams(rule), schema)
+			assert.Error(t, err, rule)
+		}
+		_, err := NewReScorer([]*milvuspb.SearchRequest{{}}, boostRankParams(), schema)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		rules := make([]map[string]any, maxBoostRules+1)
+		for i := range rules {
+			rules[i] = map[string]any{"field": "price", "value": i, "multiplier": 1.2}
+		}
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}}, boostRankParams(rules...), schema)
+		assert.ErrorContains(t, err, "the number of rules")
+
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}}, boostRankParams(
+			map[string]any{"field": "category", "value": "premium", "multiplier": 1.2},
+			map[string]any{"field": "price", "range": []float64{10, 100}, "multiplier": 0.5},
+			map[string]any{"field": "category", "value": "used", "multiplier": 0},
+		), schema)
+		assert.NoError(t, err)
+		assert.Equal(t, boostRankType, rescorers[0].scorerType())
+		assert.Equal(t, []string{"category", "price"}, scorerOutputFields(rescorers[0]))
+
+		category := &schemapb.FieldData{
+			FieldName: "category",
+			Type:      schemapb.DataType_VarChar,
+			Field: &schemapb.FieldData_Scalars{Scalars: &schemapb.ScalarField{
+				Data: &schemapb.ScalarField_StringData{StringData: &schemapb.StringArray{Data: []string{"premium", "premium", "basic", "used"}}},
+			}},
+		}
+		price := &schemapb.FieldData{
+			FieldName: "price",
+			Type:      schemapb.DataType_Int32,
+			Field: &schemapb.FieldData_Scalars{Scalars: &schemapb.ScalarField{
+				Data: &schemapb.ScalarField_IntData{IntData: &schemapb.IntArray{Data: []int32{5, 100, 10, 50}}},
+			}},
+		}
+		result := &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+			Scores:     []float32{1, 1, 1, 1},
+			Topks:      []int64{4},
+			FieldsData: []*schemapb.FieldData{category, price},
+		}}
+		assert.NoError(t, rescorers[0].reScore(result))
+		// the second one matches both the category and the price
+		assert.InDeltaSlice(t, []float32{1.2, 0.6, 0.5, 0}, result.GetResults().GetScores(), 1e-6)
+
+		result = &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+			Scores:     []float32{1, 1, 1, 1},
+			Topks:      []int64{4},
+			FieldsData: []*schemapb.FieldData{category},
+		}}
+		assert.ErrorIs(t, rescorers[0].reScore(result), merr.ErrFieldNotFound)
+	})
+
+	t.Run("sigmoid", func(t *testing.T) {
+		newRankParams := func(rankType string, params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: rankType},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		reScore := func(rescorer reScorer, scores ...float32) []float32 {
+			result := &milvuspb.SearchResults{Results: &schemapb.SearchResultData{Scores: scores, Topks: []int64{int64(len(scores))}}}
+			assert.NoError(t, rescorer.reScore(result))
+			return result.GetResults().GetScores()
+		}
+
+		for _, params := range []map[string]any{
+			{SigmoidScaleKey: "one"},
+			{SigmoidBiasKey: nil},
+		} {
+			_, err := NewReScorer([]*milvuspb.SearchRequest{{}}, newRankParams("sigmoid", params), nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid, params)
+		}
+		_, err := parseSigmoidTransform(map[string]any{SigmoidScaleKey: math.Inf(1)})
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		_, err = parseSigmoidTransform(map[string]any{SigmoidBiasKey: math.NaN()})
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+
+		// no params needed
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}}, []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: "sigmoid"}}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, sigmoidRankType, rescorers[0].scorerType())
+		assert.InDeltaSlice(t, []float32{0.5, float32(1 / (1 + math.Exp(-2)))}, reScore(rescorers[0], 0, 2), 1e-6)
+
+		rescorers, err = NewReScorer([]*milvuspb.SearchRequest{{}}, newRankParams("sigmoid", map[string]any{
+			SigmoidScaleKey: 2,
+			SigmoidBiasKey:  -1,
+		}), nil)
+		assert.NoError(t, err)
+		assert.InDeltaSlice(t, []float32{0.5, float32(1 / (1 + math.Exp(1)))}, reScore(rescorers[0], 0.5, 0), 1e-6)
+		// stable for the extreme ones
+		scores := reScore(rescorers[0], math.MaxFloat32, -math.MaxFloat32, 1000, -1000)
+		assert.Equal(t, []float32{1, 0, 1, 0}, scores)
+		for _, score := range scores {
+			assert.False(t, math.IsNaN(float64(score)))
+		}
+
+		// the pre-step of weighted
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}}, newRankParams("weighted", map[string]any{
+			WeightsParamsKey: []float64{0.5},
+			TransformKey:     "tanh",
+		}), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		rescorers, err = NewReScorer([]*milvuspb.SearchRequest{{}}, newRankParams("weighted", map[string]any{
+			WeightsParamsKey: []float64{0.5},
+			TransformKey:     "sigmoid",
+			SigmoidBiasKey:   1,
+		}), nil)
+		assert.NoError(t, err)
+		assert.InDeltaSlice(t, []float32{0.5 / (1 + float32(math.Exp(-1))), 0.25}, reScore(rescorers[0], 0, -1), 1e-6)
+	})
+
+	t.Run("multiply", func(t *testing.T) {
+		newRankParams := func(params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "multiply"},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		newResult := func(ids []int64, scores []float32) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+				Scores: scores,
+				Topks:  []int64{int64(len(ids))},
+			}}
+		}
+		reqs := []*milvuspb.SearchRequest{{}, {}}
+		rank := func(rescorers []reScorer) *milvuspb.SearchResults {
+			results := []*milvuspb.SearchResults{
+				newResult([]int64{1, 2, 3}, []float32{0.9, 0.5, -0.2}),
+				newResult([]int64{1, 4}, []float32{0.5, 0.8}),
+			}
+			for i, result := range results {
+				assert.NoError(t, rescorers[i].reScore(result))
+			}
+			// the negative score is clamped
+			assert.Equal(t, []float32{0.9, 0.5, 0}, results[0].GetResults().GetScores())
+			ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: 10, roundDecimal: -1},
+				schemapb.DataType_Int64, results, scoreFusion(rescorers), nil)
+			assert.NoError(t, err)
+			return ranked
+		}
+
+		for _, params := range []map[string]any{
+			{MissingValueKey: -1},
+			{MissingValueKey: "one"},
+			{MissingValueKey: math.MaxFloat64},
+			{SimilarityKey: "negate"},
+			{NormScoreKey: "l2"},
+			{PerRequestRankParamsKey: []map[string]any{{}, {}}},
+		} {
+			_, err := NewReScorer(reqs, newRankParams(params), nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid, params)
+		}
+
+		// the missing requests are neutral by default
+		rescorers, err := NewReScorer(reqs, []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: "multiply"}}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, multiplyRankType, rescorers[0].scorerType())
+		ranked := rank(rescorers)
+		// 1: 0.9*0.5, 2: 0.5*1, 3: 0*1, 4: 1*0.8
+		assert.Equal(t, []int64{4, 2, 1, 3}, ranked.GetResults().GetIds().GetIntId().GetData())
+		assert.InDeltaSlice(t, []float32{0.8, 0.5, 0.45, 0}, ranked.GetResults().GetScores(), 1e-6)
+
+		// only the entities recalled by all the requests score
+		rescorers, err = NewReScorer(reqs, newRankParams(map[string]any{MissingValueKey: 0}), nil)
+		assert.NoError(t, err)
+		ranked = rank(rescorers)
+		assert.Equal(t, []int64{1, 2, 3, 4}, ranked.GetResults().GetIds().GetIntId().GetData())
+		assert.InDeltaSlice(t, []float32{0.45, 0, 0, 0}, ranked.GetResults().GetScores(), 1e-6)
+
+		// the distances are converted, and normalized if asked
+		rescorers, err = NewReScorer([]*milvuspb.SearchRequest{
+			{SearchParams: []*commonpb.KeyValuePair{{Key: MetricTypeKey, Value: "L2"}}},
+		}, newRankParams(map[string]any{NormScoreKey: "min_max", SimilarityKey: "reciprocal"}), nil)
+		assert.NoError(t, err)
+		result := newResult([]int64{1, 2, 3}, []float32{0, 1, 3})
+		assert.NoError(t, rescorers[0].reScore(result))
+		assert.InDeltaSlice(t, []float32{1, 1.0 / 3, 0}, result.GetResults().GetScores(), 1e-6)
+	})
+
+	t.Run("string numbers", func(t *testing.T) {
+		newRankParams := func(rankType string, params string) []*commonpb.KeyValuePair {
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: rankType},
+				{Key: RankParamsKey, Value: params},
+			}
+		}
+		reqs := []*milvuspb.SearchRequest{{}, {}}
+
+		rescorers, err := NewReScorer(reqs, newRankParams("rrf", `{"k": "60"}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(60), rescorers[1].(*rrfScorer).k)
+		rescorers, err = NewReScorer(reqs, newRankParams("rrf", `{"k": [" 30 ", 10]}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(30), rescorers[0].(*rrfScorer).k)
+		assert.Equal(t, float32(10), rescorers[1].(*rrfScorer).k)
+		rescorers, err = NewReScorer(reqs, newRankParams("weighted", `{"weights": ["0.3", 0.7], "clip_max": "0.5"}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(0.3), rescorers[0].(*weightedScorer).weight)
+		assert.Equal(t, float32(0.7), rescorers[1].(*weightedScorer).weight)
+		assert.Equal(t, float32(0.5), *rescorers[0].(*weightedScorer).bounds.clipMax)
+		rescorers, err = NewReScorer(reqs, newRankParams("sigmoid", `{"a": "2", "b": -1}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(2), rescorers[0].(*sigmoidScorer).sigmoid.a)
+		rescorers, err = NewReScorer(reqs, newRankParams("multiply", `{"missing_value": "0"}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(0), rescorers[0].(*multiplyScorer).missing)
+		schema := &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{{Name: "price", DataType: schemapb.DataType_Int32}}}
+		rescorers, err = NewReScorer(reqs[:1], newRankParams("boost",
+			`{"rules": [{"field": "price", "range": ["1", 2], "multiplier": "1.5"}]}`), schema)
+		assert.NoError(t, err)
+		rule := rescorers[0].(*boostScorer).rules[0]
+		assert.Equal(t, []rankFloat{1, 2}, rule.Range)
+		assert.Equal(t, rankFloat(1.5), *rule.Multiplier)
+
+		// validated the same as the numbers
+		for _, c := range []struct {
+			rankType string
+			params   string
+			err      string
+		}{
+			{"rrf", `{"k": "0"}`, "rank param k should be in range (0, 16384), got 0"},
+			{"rrf", `{"k": ["60", "1e400"]}`, `rank param k[1] should be a number or a string of finite float, got "1e400"`},
+			{"weighted", `{"weights": ["0.3", "1.5"]}`, "rank param weights[1] should be in range [0, 1]"},
+			{"weighted", `{"weights": [0.3, "NaN"]}`, `rank param weights[1] should be a number or a string of finite float, got "NaN"`},
+			{"weighted", `{"weights": ["0.3", ""]}`, `rank param weights[1] should be a number or a string of finite float, got ""`},
+			{"multiply", `{"missing_value": "-1"}`, "rank param missing_value should be a finite float not less than 0, got -1"},
+			{"max", `{"clip_min": "1", "clip_max": "0"}`, "rank param clip_min should not be greater than clip_max"},
+		} {
+			_, err := NewReScorer(reqs, newRankParams(c.rankType, c.params), nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid, c.params)
+			assert.ErrorContains(t, err, c.err, c.params)
+		}
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		cases := []struct {
+			rankType string
+			// the params are not set if empty
+			params string
+			err    string
+		}{
+			{"unknown", `{}`, "unsupported rank type unknown, should be one of [boost borda decay expr max min multiply rrf sigmoid weighted]"},
+			{"invalid", `{}`, "unsupported rank type invalid"},
+			{"rrf", "", "params not found in rank_params"},
+			{"rrf", `[60]`, "params of rank_params should be a JSON object"},
+			{"rrf", `{}`, "k not found in rank_params"},
+			{"rrf", `{"k": true}`, "the type of rank param k should be float, got true"},
+			{"rrf", `{"k": "a"}`, `rank param k should be a number or a string of finite float, got "a"`},
+			{"rrf", `{"k": 0}`, "rank param k should be in range (0, 16384), got 0"},
+			{"rrf", `{"k": [60]}`, "the length of rank param k mismatch with ann search requests"},
+			{"rrf", `{"k": [60, 20000]}`, "rank param k[1] should be in range (0, 16384), got 20000"},
+			{"weighted", `{}`, "weights not found in rank_params"},
+			{"weighted", `{"weights": "a"}`, "rank param weights should be an array, got a"},
+			// the length is checked before the range
+			{"weighted", `{"weights": [2]}`, "the length of weights param mismatch with ann search requests"},
+			{"weighted", `{"weights": ["a", 0.5]}`, `rank param weights[0] should be a number or a string of finite float, got "a"`},
+			{"weighted", `{"weights": [0.5, 2]}`, "rank param weights[1] should be in range [0, 1], or set allow_unbounded_weights to true, got 2"},
+			{"weighted", `{"weights": [0.5, 0.5], "norm_score": "l2"}`, "unsupported rank param norm_score l2, should be min_max or z_score"},
+			{"weighted", `{"weights": [0.5, 0.5], "similarity": "log"}`, "unsupported rank param similarity log, should be reciprocal or negate"},
+			{"weighted", `{"weights": [0.5, 0.5], "transform": "tanh"}`, "unsupported rank param transform tanh, should be sigmoid"},
+			{"borda", `{"weights": [1]}`, "the length of weights param mismatch with ann search requests"},
+			{"sigmoid", `{"a": "NaN"}`, `rank param a should be a number or a string of finite float, got "NaN"`},
+			{"expr", `{}`, "expr of string not found in rank_params"},
+			{"expr", `{"expr": "score_0 +"}`, "invalid rank expr score_0 +"},
+			{"decay", `{}`, "field of string not found in rank_params"},
+			{"boost", `{}`, "rules of array not found in rank_params"},
+			{"multiply", `{"missing_value": -1}`, "rank param missing_value should be a finite float not less than 0, got -1"},
+			{"max", `{"per_request": [{}, {}]}`, "per_request is not supported by rank max"},
+			{"rrf", `{"k": 60, "per_request": [{}]}`, "the length of per_request mismatch with ann search requests"},
+		}
+		for _, c := range cases {
+			rankParams := []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: c.rankType}}
+			if c.params != "" {
+				rankParams = append(rankParams, &commonpb.KeyValuePair{Key: RankParamsKey, Value: c.params})
+			}
+			_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, rankParams, nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid, c.rankType, c.params)
+			assert.Equal(t, merr.Code(merr.ErrParameterInvalid), merr.Code(err), c.rankType, c.params)
+			assert.ErrorContains(t, err, c.err, c.rankType, c.params)
+		}
+	})
+
+	t.Run("score bounds", func(t *testing.T) {
+		newRankParams := func(rankType string, params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: rankType},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		newResult := func(topks []int64, ids []int64, scores []float32) *milvuspb.SearchResults {
+			// the values of the field are the indexes of the results
+			values := make([]int64, len(ids))
+			for i := range values {
+				values[i] = int64(i)
+			}
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+				Scores: scores,
+				Topks:  topks,
+				FieldsData: []*schemapb.FieldData{{
+					Type:      schemapb.DataType_Int64,
+					FieldName: "ts",
+					Field: &schemapb.FieldData_Scalars{Scalars: &schemapb.ScalarField{
+						Data: &schemapb.ScalarField_LongData{LongData: &schemapb.LongArray{Data: values}},
+					}},
+				}},
+			}}
+		}
+		reqs := []*milvuspb.SearchRequest{{}, {}}
+
+		for _, params := range []map[string]any{
+			{ClipMinKey: 1, ClipMaxKey: 0},
+			{ClipMaxKey: "one"},
+			{DropBelowKey: nil},
+		} {
+			params[WeightsParamsKey] = []float64{1, 1}
+			_, err := NewReScorer(reqs, newRankParams("weighted", params), nil)
+			assert.ErrorIs(t, err, merr.ErrParameterInvalid, params)
+		}
+
+		// the hits dropped leave room for the others
+		rank := func(params map[string]any) *milvuspb.SearchResults {
+			params[WeightsParamsKey] = []float64{1, 1}
+			rescorers, err := NewReScorer(reqs, newRankParams("weighted", params), nil)
+			assert.NoError(t, err)
+			results := []*milvuspb.SearchResults{
+				newResult([]int64{3}, []int64{1, 2, 3}, []float32{0.9, 0.8, 0.3}),
+				newResult([]int64{2}, []int64{3, 4}, []float32{0.7, 0.5}),
+			}
+			for i, result := range results {
+				assert.NoError(t, rescorers[i].reScore(result))
+				_, err := rescorers[i].boundScores(result)
+				assert.NoError(t, err)
+			}
+			ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: 2, roundDecimal: -1},
+				schemapb.DataType_Int64, results, scoreFusion(rescorers), nil)
+			assert.NoError(t, err)
+			return ranked
+		}
+		// 3: 0.3 + 0.7, 1: 0.9
+		ranked := rank(map[string]any{})
+		assert.Equal(t, []int64{3, 1}, ranked.GetResults().GetIds().GetIntId().GetData())
+		// 1: 0.9, 2: 0.8, 3: 0.7 of the second request only
+		ranked = rank(map[string]any{DropBelowKey: 0.6})
+		assert.Equal(t, []int64{1, 2}, ranked.GetResults().GetIds().GetIntId().GetData())
+		assert.InDeltaSlice(t, []float32{0.9, 0.8}, ranked.GetResults().GetScores(), 1e-6)
+
+		// the ids, fields and topks are dropped along with the scores
+		rescorers, err := NewReScorer(reqs[:1], newRankParams("max", map[string]any{DropBelowKey: 0.6}), nil)
+		assert.NoError(t, err)
+		result := newResult([]int64{3, 2}, []int64{1, 2, 3, 4, 5}, []float32{0.9, 0.8, 0.3, 0.7, 0.5})
+		kept, err := rescorers[0].boundScores(result)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{0, 1, 3}, kept)
+		assert.Equal(t, []int64{2, 1}, result.GetResults().GetTopks())
+		assert.Equal(t, []int64{1, 2, 4}, result.GetResults().GetIds().GetIntId().GetData())
+		assert.Equal(t, []float32{0.9, 0.8, 0.7}, result.GetResults().GetScores())
+		assert.Equal(t, []int64{0, 1, 3}, result.GetResults().GetFieldsData()[0].GetScalars().GetLongData().GetData())
+		// all of a query dropped
+		kept, err = rescorers[0].boundScores(newResult([]int64{1, 1}, []int64{1, 2}, []float32{0.5, 0.7}))
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1}, kept)
+		// none dropped
+		kept, err = rescorers[0].boundScores(newResult([]int64{1}, []int64{1}, []float32{0.7}))
+		assert.NoError(t, err)
+		assert.Nil(t, kept)
+
+		// the scores are clipped after dropped
+		rescorers, err = NewReScorer(reqs[:1], newRankParams("max", map[string]any{
+			ClipMinKey:   0,
+			ClipMaxKey:   0.8,
+			DropBelowKey: -0.5,
+		}), nil)
+		assert.NoError(t, err)
+		result = newResult([]int64{4}, []int64{1, 2, 3, 4}, []float32{0.9, 0.5, -0.1, -0.7})
+		_, err = rescorers[0].boundScores(result)
+		assert.NoError(t, err)
+		assert.Equal(t, []float32{0.8, 0.5, 0}, result.GetResults().GetScores())
+		assert.Equal(t, []int64{3}, result.GetResults().GetTopks())
+	})
+
+	t.Run("scorer registry", func(t *testing.T) {
+		assert.Panics(t, func() { RegisterScorerFactory("rrf", newRRFScorers) })
+		assert.Panics(t, func() { RegisterScorerFactory("", newRRFScorers) })
+		assert.Panics(t, func() { RegisterScorerFactory("toy", nil) })
+
+		// the toy scores the results by the ranks of them reversed
+		RegisterScorerFactory("toy", func(params map[string]interface{}, reqs []*milvuspb.SearchRequest, schema *schemapb.CollectionSchema) ([]reScorer, error) {
+			weight, err := rankParamFloat(params[WeightsParamsKey], WeightsParamsKey)
+			if err != nil {
+				return nil, err
+			}
+			res := make([]reScorer, len(reqs))
+			for i := range reqs {
+				res[i] = &toyScorer{baseScorer: baseScorer{scorerName: "toy"}, weight: float32(weight)}
+			}
+			return res, nil
+		})
+		assert.Panics(t, func() { RegisterScorerFactory("toy", newRRFScorers) })
+
+		newRankParams := func(params string) []*commonpb.KeyValuePair {
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: "toy"},
+				{Key: RankParamsKey, Value: params},
+			}
+		}
+		_, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, newRankParams(`{"weights": "a"}`), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		_, err = NewReScorer([]*milvuspb.SearchRequest{{}, {}}, []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: "unknown"}}, nil)
+		assert.ErrorContains(t, err, "toy")
+
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, newRankParams(`{"weights": 2, "drop_below": 1}`), nil)
+		assert.NoError(t, err)
+		assert.Nil(t, scoreFusion(rescorers))
+		newResult := func(ids []int64) *milvuspb.SearchResults {
+			return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+				Ids:    &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+				Scores: make([]float32, len(ids)),
+				Topks:  []int64{int64(len(ids))},
+			}}
+		}
+		results := []*milvuspb.SearchResults{newResult([]int64{1, 2, 3}), newResult([]int64{3, 4})}
+		for i, result := range results {
+			assert.NoError(t, rescorers[i].reScore(result))
+			_, err := rescorers[i].boundScores(result)
+			assert.NoError(t, err)
+		}
+		ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: 10, roundDecimal: -1},
+			schemapb.DataType_Int64, results, scoreFusion(rescorers), nil)
+		assert.NoError(t, err)
+		// 1: 2*3, 3: 2*1 + 2*2, 2: 2*2, 4: 2*1, none of which is dropped below 1
+		assert.Equal(t, []int64{1, 3, 2, 4}, ranked.GetResults().GetIds().GetIntId().GetData())
+		assert.Equal(t, []float32{6, 6, 4, 2}, ranked.GetResults().GetScores())
+	})
+
+	t.Run("deterministic order", func(t *testing.T) {
+		rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, []*commonpb.KeyValuePair{
+			{Key: RankTypeKey, Value: "weighted"},
+			{Key: RankParamsKey, Value: `{"weights": [1, 1]}`},
+		}, nil)
+		assert.NoError(t, err)
+		// the results of the requests, most of the scores fused of which tie
+		newResults := func(pkType schemapb.DataType) []*milvuspb.SearchResults {
+			results := make([]*milvuspb.SearchResults, 2)
+			for r := range results {
+				n := 20 + r*5
+				ids := &schemapb.IDs{}
+				scores := make([]float32, n)
+				for i := 0; i < n; i++ {
+					if pkType == schemapb.DataType_Int64 {
+						typeutil.AppendPKs(ids, int64(i))
+					} else {
+						typeutil.AppendPKs(ids, fmt.Sprintf("pk%d", i))
+					}
+					scores[i] = float32(i % 3)
+				}
+				// the same hits in another order
+				perm := rand.Perm(n)
+				shuffled := &schemapb.IDs{}
+				shuffledScores := make([]float32, n)
+				for i, j := range perm {
+					typeutil.AppendPKs(shuffled, typeutil.GetPK(ids, int64(j)))
+					shuffledScores[i] = scores[j]
+				}
+				results[r] = &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+					Ids:    shuffled,
+					Scores: shuffledScores,
+					Topks:  []int64{int64(n)},
+				}}
+			}
+			return results
+		}
+		for _, pkType := range []schemapb.DataType{schemapb.DataType_Int64, schemapb.DataType_VarChar} {
+			var expected []byte
+			for i := 0; i < 10; i++ {
+				results := newResults(pkType)
+				for r, result := range results {
+					assert.NoError(t, rescorers[r].reScore(result))
+				}
+				ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: 15, offset: 3, roundDecimal: -1},
+					pkType, results, scoreFusion(rescorers), nil)
+				assert.NoError(t, err)
+				b, err := json.Marshal(ranked.GetResults())
+				assert.NoError(t, err)
+				if expected == nil {
+					expected = b
+				}
+				assert.Equal(t, string(expected), string(b), pkType)
+			}
+		}
+
+		// by the scores descending, then by the pks ascending, and NaN last
+		assert.True(t, fusedBefore(2, 1, int64(2), int64(1)))
+		assert.True(t, fusedBefore(1, 1, int64(1), int64(2)))
+		assert.False(t, fusedBefore(1, 1, int64(2), int64(1)))
+		assert.True(t, fusedBefore(1, 1, "a", "b"))
+		assert.True(t, fusedBefore(1, float32(math.NaN()), int64(2), int64(1)))
+		assert.False(t, fusedBefore(float32(math.NaN()), -1, int64(1), int64(2)))
+		assert.True(t, fusedBefore(float32(math.NaN()), float32(math.NaN()), int64(1), int64(2)))
+		pks := []interface{}{int64(3), int64(1), int64(4), int64(2)}
+		sortFusedPKs(pks, map[interface{}]float32{int64(1): 1, int64(2): float32(math.NaN()), int64(3): 1, int64(4): 2})
+		assert.Equal(t, []interface{}{int64(4), int64(1), int64(3), int64(2)}, pks)
+	})
+
+	t.Run("parallel rescoring", func(t *testing.T) {
+		schema := &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{{Name: "ts", DataType: schemapb.DataType_Int64}}}
+		reqs := []*milvuspb.SearchRequest{
+			{Nq: 8, SearchParams: []*commonpb.KeyValuePair{{Key: MetricTypeKey, Value: "L2"}}},
+		}
+		defer func(threshold int) {
+			parallelReScoreThreshold = threshold
+		}(parallelReScoreThreshold)
+
+		for _, strategies := range []map[string]string{
+			{"rrf": `{"k": 60}`},
+			{"rrf": `{"k": [[1, 2, 3, 4, 5, 6, 7, 8]]}`},
+			{"weighted": `{"weights": [0.7], "norm_score": "min_max"}`},
+			{"weighted": `{"weights": [0.7], "norm_score": "z_score", "transform": "sigmoid", "a": 0.3}`},
+			{"borda": `{}`},
+			{"sigmoid": `{"a": 2, "b": -1}`},
+			{"multiply": `{"norm_score": "z_score"}`},
+			{"decay": `{"field": "ts", "origin": 500, "scale": 100}`},
+			{"boost": `{"rules": [{"field": "ts", "range": [100, 600], "multiplier": 1.5}, {"field": "ts", "value": 7, "multiplier": 3}]}`},
+		} {
+			for rankType, params := range strategies {
+				rescorers, err := NewReScorer(reqs, []*commonpb.KeyValuePair{
+					{Key: RankTypeKey, Value: rankType},
+					{Key: RankParamsKey, Value: params},
+				}, schema)
+				assert.NoError(t, err)
+				serial, parallel := newBenchmarkResult(8, 300), newBenchmarkResult(8, 300)
+				parallelReScoreThreshold = math.MaxInt
+				assert.NoError(t, rescorers[0].reScore(serial))
+				parallelReScoreThreshold = 0
+				assert.NoError(t, rescorers[0].reScore(parallel))
+				for i, score := range serial.GetResults().GetScores() {
+					assert.Equal(t, math.Float32bits(score), math.Float32bits(parallel.GetResults().GetScores()[i]), rankType, params, i)
+				}
+			}
+		}
+
+		// the error of a chunk fails them all
+		err := runChunks(4, 100, func(chunk int) error {
+			if chunk == 2 {
+				return merr.WrapErrServiceInternal("chunk")
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, merr.ErrServiceInternal)
+	})
+
+	t.Run("default rank strategy", func(t *testing.T) {
+		params := paramtable.Get()
+		cfg := &params.ProxyCfg
+		defer params.Reset(cfg.HybridSearchDefaultRankType.Key)
+		defer params.Reset(cfg.HybridSearchDefaultRRFK.Key)
+		defer params.Reset(cfg.HybridSearchDefaultWeights.Key)
+		reqs := []*milvuspb.SearchRequest{{}, {}}
+
+		params.Save(cfg.HybridSearchDefaultRRFK.Key, "10")
+		rescorers, err := NewReScorer(reqs, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(10), rescorers[0].(*rrfScorer).k)
+
+		// weighted by 1 equally if no weights
+		params.Save(cfg.HybridSearchDefaultRankType.Key, "weighted")
+		rescorers, err = NewReScorer(reqs, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(1), rescorers[0].(*weightedScorer).weight)
+		assert.Equal(t, float32(1), rescorers[1].(*weightedScorer).weight)
+		params.Save(cfg.HybridSearchDefaultWeights.Key, "0.7, 0.3")
+		rescorers, err = NewReScorer(reqs, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(0.7), rescorers[0].(*weightedScorer).weight)
+		assert.Equal(t, float32(0.3), rescorers[1].(*weightedScorer).weight)
+
+		// the rank params specified win
+		rescorers, err = NewReScorer(reqs, []*commonpb.KeyValuePair{{Key: RankTypeKey, Value: "borda"}}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, bordaRankType, rescorers[0].scorerType())
+
+		// the invalid ones fall back to rrf
+		assertFallback := func() {
+			rescorers, err := NewReScorer(reqs, nil, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, rrfRankType, rescorers[0].scorerType())
+			assert.Equal(t, float32(defaultRRFParamsValue), rescorers[0].(*rrfScorer).k)
+		}
+		params.Save(cfg.HybridSearchDefaultWeights.Key, "0.7")
+		assertFallback()
+		params.Save(cfg.HybridSearchDefaultWeights.Key, "0.7,a")
+		assertFallback()
+		params.Save(cfg.HybridSearchDefaultRankType.Key, "rrf")
+		params.Save(cfg.HybridSearchDefaultRRFK.Key, "0")
+		assertFallback()
+		params.Save(cfg.HybridSearchDefaultRankType.Key, "unknown")
+		assertFallback()
+	})
+
+	t.Run("strict rank params", func(t *testing.T) {
+		newRankParams := func(rankType string, params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: rankType},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		reqs := []*milvuspb.SearchRequest{{}, {}}
+		params := map[string]any{"wieghts": []float64{0.5, 0.5}, WeightsParamsKey: []float64{0.5, 0.5}, "norm": "min_max"}
+
+		// lenient by default
+		_, err := NewReScorer(reqs, newRankParams("weighted", params), nil)
+		assert.NoError(t, err)
+
+		params[StrictRankParamsKey] = true
+		_, err = NewReScorer(reqs, newRankParams("weighted", params), nil)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.ErrorContains(t, err, "unknown keys [norm wieghts] in params of rank weighted, the accepted keys are "+
+			"[a allow_unbounded_weights b clip_max clip_min drop_below explain norm_score per_request similarity strict transform weights]")
+
+		// by the config, which the params override
+		delete(params, StrictRankParamsKey)
+		paramtable.Get().Save(paramtable.Get().ProxyCfg.HybridSearchStrictRankParams.Key, "true")
+		defer paramtable.Get().Reset(paramtable.Get().ProxyCfg.HybridSearchStrictRankParams.Key)
+		_, err = NewReScorer(reqs, newRankParams("weighted", params), nil)
+		assert.ErrorContains(t, err, "unknown keys [norm wieghts] in params of rank weighted")
+		_, err = NewReScorer(reqs, newRankParams("rrf", map[string]any{RRFParamsKey: 60, "K": 10}), nil)
+		assert.ErrorContains(t, err, "unknown keys [K] in params of rank rrf, the accepted keys are [clip_max clip_min drop_below explain k per_request strict]")
+		params[StrictRankParamsKey] = false
+		_, err = NewReScorer(reqs, newRankParams("weighted", params), nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("explain", func(t *testing.T) {
+		newRankParams := func(rankType string, params map[string]any) []*commonpb.KeyValuePair {
+			b, err := json.Marshal(params)
+			assert.NoError(t, err)
+			return []*commonpb.KeyValuePair{
+				{Key: RankTypeKey, Value: rankType},
+				{Key: RankParamsKey, Value: string(b)},
+			}
+		}
+		newResults := func() []*milvuspb.SearchResults {
+			newResult := func(ids []int64, scores []float32) *milvuspb.SearchResults {
+				return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+					Ids:    &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+					Scores: scores,
+					Topks:  []int64{int64(len(ids))},
+				}}
+			}
+			return []*milvuspb.SearchResults{
+				newResult([]int64{1, 2, 3}, []float32{0.9, 0.5, 0.1}),
+				newResult([]int64{3, 4}, []float32{0.6, 0.3}),
+			}
+		}
+		raw := []map[int64]float32{{1: 0.9, 2: 0.5, 3: 0.1}, {3: 0.6, 4: 0.3}}
+		// rescore and rank the results the way hybridSearchTask does
+		rank := func(params []*commonpb.KeyValuePair, limit int64) (*milvuspb.SearchResults, *scoreExplainer) {
+			rescorers, err := NewReScorer([]*milvuspb.SearchRequest{{}, {}}, params, nil)
+			assert.NoError(t, err)
+			var explainer *scoreExplainer
+			if explainRank(params) {
+				explainer = &scoreExplainer{}
+			}
+			results := newResults()
+			for i, result := range results {
+				if explainer != nil {
+					explainer.addRawScores(result.GetResults().GetScores())
+				}
+				assert.NoError(t, rescorers[i].reScore(result))
+			}
+			ranked, err := rankSearchResultData(context.Background(), 1, &rankParams{limit: limit, roundDecimal: -1},
+				schemapb.DataType_Int64, results, scoreFusion(rescorers), explainer)
+			assert.NoError(t, err)
+			if explainer == nil {
+				return ranked, nil
+			}
+			explained := &scoreExplainer{}
+			assert.NoError(t, json.Unmarshal([]byte(ranked.GetStatus().GetDetail()), explained))
+			return ranked, explained
+		}
+
+		// the scores of the hits are the sums of the rescored ones
+		for _, params := range [][]*commonpb.KeyValuePair{
+			newRankParams("rrf", map[string]any{RRFParamsKey: 60, ExplainRankParamsKey: true}),
+			newRankParams("weighted", map[string]any{WeightsParamsKey: []float64{0.3, 0.7}, ExplainRankParamsKey: true}),
+		} {
+			ranked, explained := rank(params, 3)
+			assert.Equal(t, "sum", explained.Fusion)
+			// bounded by the topk
+			assert.Equal(t, 3, len(explained.Hits))
+			for i, hit := range explained.Hits {
+				id := ranked.GetResults().GetIds().GetIntId().GetData()[i]
+				assert.Equal(t, float64(id), hit.ID)
+				assert.InDelta(t, ranked.GetResults().GetScores()[i], hit.Score, 1e-6)
+				var sum float32
+				for r := range hit.Rescored {
+					if hit.Rescored[r] == nil {
+						assert.Nil(t, hit.Raw[r])
+						continue
+					}
+					assert.Equal(t, raw[r][id], *hit.Raw[r])
+					sum += *hit.Rescored[r]
+				}
+				assert.InDelta(t, hit.Score, sum, 1e-6)
+			}
+		}
+
+		// the scores of the hits are reproduced by the expression
+		code := "max(score_0, 2*score_1)"
+		ranked, explained := rank(newRankParams("expr", map[string]any{ExprParamsKey: code, ExplainRankParamsKey: true}), 10)
+		assert.Equal(t, code, explained.Fusion)
+		assert.Equal(t, 4, len(explained.Hits))
+		fusion, err := newScoreExpr(code, 2)
+		assert.NoError(t, err)
+		for i, hit := range explained.Hits {
+			scores, recalled := make([]float32, 2), make([]bool, 2)
+			for r := range hit.Rescored {
+				if hit.Rescored[r] != nil {
+					scores[r], recalled[r] = *hit.Rescored[r], true
+				}
+			}
+			score, err := fusion.fuse(scores, recalled)
+			assert.NoError(t, err)
+			assert.InDelta(t, ranked.GetResults().GetScores()[i], score, 1e-6)
+		}
+
+		// nothing explained if not asked
+		ranked, explained = rank(newRankParams("rrf", map[string]any{RRFParamsKey: 60}), 3)
+		assert.Nil(t, explained)
+		assert.Empty(t, ranked.GetStatus().GetDetail())
+		assert.False(t, explainRank(newRankParams("rrf", map[string]any{ExplainRankParamsKey: false})))
+	})
+}
+
+// toyScorer scores the results of each query by the ranks of them reversed, weighted
+type toyScorer struct {
+	baseScorer
+	weight float32
+}
+
+func (ts *toyScorer) reScore(input *milvuspb.SearchResults) error {
+	queries, err := queryScores(input.GetResults())
+	if err != nil {
+		return err
+	}
+	for _, query := range queries {
+		for i := range query {
+			query[i] = ts.weight * float32(len(query)-i)
+		}
+	}
+	return nil
+}
+
+func (ts *toyScorer) scorerType() rankType {
+	return invalidRankType
+}
+
+// newBenchmarkResult returns the search results of nq queries of topk each, the scores of which are of L2 and ascending,
+// with the values of field ts
+func newBenchmarkResult(nq int, topk int) *milvuspb.SearchResults {
+	r := rand.New(rand.NewSource(int64(nq * topk)))
+	ids := make([]int64, 0, nq*topk)
+	scores := make([]float32, 0, nq*topk)
+	values := make([]int64, 0, nq*topk)
+	topks := make([]int64, nq)
+	for i := 0; i < nq; i++ {
+		score := float32(0)
+		for j := 0; j < topk; j++ {
+			score += r.Float32()
+			ids = append(ids, int64(i*topk+j))
+			scores = append(scores, score)
+			values = append(values, r.Int63n(1000))
+		}
+		topks[i] = int64(topk)
+	}
+	return &milvuspb.SearchResults{Results: &schemapb.SearchResultData{
+		NumQueries: int64(nq),
+		TopK:       int64(topk),
+		Ids:        &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}}},
+		Scores:     scores,
+		Topks:      topks,
+		FieldsData: []*schemapb.FieldData{{
+			Type:      schemapb.DataType_Int64,
+			FieldName: "ts",
+			Field: &schemapb.FieldData_Scalars{Scalars: &schemapb.ScalarField{
+				Data: &schemapb.ScalarField_LongData{LongData: &schemapb.LongArray{Data: values}},
+			}},
+		}},
+	}}
+}
+
+func BenchmarkReScore(b *testing.B) {
+	paramtable.Init()
+	reqs := make([]*milvuspb.SearchRequest, 4)
+	for i := range reqs {
+		reqs[i] = &milvuspb.SearchRequest{Nq: 50}
+	}
+	rescorers, err := NewReScorer(reqs, []*commonpb.KeyValuePair{
+		{Key: RankTypeKey, Value: "weighted"},
+		{Key: RankParamsKey, Value: `{"weights": [0.4, 0.3, 0.2, 0.1], "norm_score": "z_score"}`},
+	}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	results := make([]*milvuspb.SearchResults, len(reqs))
+	for i := range results {
+		results[i] = newBenchmarkResult(50, 1000)
+	}
+	for _, bc := range []struct {
+		name      string
+		threshold int
+	}{
+		{"serial", math.MaxInt},
+		{"parallel", 16384},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			defer func(threshold int) {
+				parallelReScoreThreshold = threshold
+			}(parallelReScoreThreshold)
+			parallelReScoreThreshold = bc.threshold
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for r, result := range results {
+					if err := rescorers[r].reScore(result); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
 }
@@ -95,6 +95,33 @@ const (
 	RankParamsKey    = "params"
 	RRFParamsKey     = "k"
 	WeightsParamsKey = "weights"
+	ExprParamsKey    = "expr"
+	NormScoreKey     = "norm_score"
+	SimilarityKey    = "similarity"
+	DecayFieldKey    = "field"
+	DecayFuncKey     = "function"
+	DecayOriginKey   = "origin"
+	DecayScaleKey    = "scale"
+	DecayOffsetKey   = "offset"
+	DecayFactorKey   = "decay"
+	BoostRulesKey    = "rules"
+	TransformKey     = "transform"
+	SigmoidScaleKey  = "a"
+	SigmoidBiasKey   = "b"
+	MissingValueKey  = "missing_value"
+
+	// relax the range [0, 1] of the weights of the rank params
+	UnboundedWeightsKey = "allow_unbounded_weights"
+	// the overrides of the rank params of each request
+	PerRequestRankParamsKey = "per_request"
+	// reject the unknown keys of the rank params
+	StrictRankParamsKey = "strict"
+	// explain the scores of the hits of hybrid search in the detail of the status of the results
+	ExplainRankParamsKey = "explain"
+	// bound the scores of the results of each request of hybrid search, see scoreBounds
+	ClipMinKey   = "clip_min"
+	ClipMaxKey   = "clip_max"
+	DropBelowKey = "drop_below"
 )
 
 type task interface {
@@ -268,6 +295,19 @@ func (t *createCollectionTask) PreExecute(ctx context.Context) error {
 		return fmt.Errorf("maximum vector field's number should be limited to %d", Params.ProxyCfg.MaxVectorFieldNum.GetAsInt())
 	}
 
+	// validate shard hash, the primary keys couldn't be routed otherwise,
+	// the new collections declare the default one to tell them from the ones routed by the legacy hash
+	if shardHash := common.GetShardHashName(t.GetProperties()...); shardHash != "" {
+		if _, err := typeutil.GetShardHash(shardHash); err != nil {
+			return merr.WrapErrParameterInvalidMsg("%s", err.Error())
+		}
+	} else {
+		t.Properties = append(t.Properties, &commonpb.KeyValuePair{
+			Key:   common.CollectionShardHashKey,
+			Value: typeutil.DefaultShardHash,
+		})
+	}
+
 	// validate collection name
 	if err := validateCollectionName(t.schema.Name); err != nil {
 		return err
@@ -848,6 +888,12 @@ func (t *alterCollectionTask) PreExecute(ctx context.Context) error {
 	t.Base.MsgType = commonpb.MsgType_AlterCollection
 	t.Base.SourceID = paramtable.GetNodeID()
 
+	// the data inserted has been routed by the shard hash
+	for _, kv := range t.GetProperties() {
+		if kv.GetKey() == common.CollectionShardHashKey {
+			return merr.WrapErrParameterInvalidMsg("%s can't be altered", common.CollectionShardHashKey)
+		}
+	}
 	return nil
 }
 
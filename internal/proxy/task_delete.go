@@ -2,12 +2,24 @@ package proxy
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/golang/protobuf/proto"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
@@ -20,19 +32,204 @@ import (
 	"github.com/milvus-io/milvus/internal/proto/internalpb"
 	"github.com/milvus-io/milvus/internal/proto/planpb"
 	"github.com/milvus-io/milvus/internal/proto/querypb"
+	"github.com/milvus-io/milvus/internal/proxy/accesslog"
 	"github.com/milvus-io/milvus/internal/types"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
 	"github.com/milvus-io/milvus/pkg/util/commonpbutil"
+	"github.com/milvus-io/milvus/pkg/util/funcutil"
+	"github.com/milvus-io/milvus/pkg/util/hardware"
 	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/parameterutil"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/retry"
 	"github.com/milvus-io/milvus/pkg/util/timerecord"
+	"github.com/milvus-io/milvus/pkg/util/tsoutil"
 	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 type BaseDeleteTask = msgstream.DeleteMsg
 
+// Delete request options, carried by the properties of the request base.
+const (
+	// DeleteSkipPKValidationKey skips checking the primary keys of a simple delete against the schema,
+	// for clients relying on the legacy behavior.
+	DeleteSkipPKValidationKey = "delete.skip_pk_validation"
+	// DeleteIDsKey carries the primary keys to delete, a base64 encoded serialized schemapb.IDs,
+	// the expr of the request must be DeleteIDsExpr then.
+	DeleteIDsKey = "delete.ids"
+	// DeleteIdempotencyKey identifies a delete across client retries,
+	// a replayed delete returns the result of the completed one, see deleteIdempotencyCache.
+	DeleteIdempotencyKey = "delete.idempotency_key"
+	// DeleteScopeKey limits the data retrieved by complex delete, one of DeleteScopeAll,
+	// DeleteScopeHistorical and DeleteScopeStreaming, DeleteScopeAll by default.
+	DeleteScopeKey = "delete.scope"
+	// DeleteGuaranteeTsKey carries the guarantee timestamp of the retrieve behind complex delete,
+	// the query node waits until the timestamp before scanning, overriding the consistency level.
+	DeleteGuaranteeTsKey = "delete.guarantee_timestamp"
+	// DeleteVerifyKey makes a simple delete retrieve the primary keys first,
+	// only the existing ones are deleted and counted, at the cost of a query.
+	DeleteVerifyKey = "delete.verify"
+	// DeletePartitionKeyOverrideKey permits a partition name in partition key mode,
+	// only if proxy.deletePartitionKeyOverride is enabled too.
+	DeletePartitionKeyOverrideKey = "delete.partition_key_override"
+	// DeleteExprTemplateValuesKey carries a json object of the values bound to the placeholders
+	// like {name} in expr, string values are always taken as literals.
+	DeleteExprTemplateValuesKey = "delete.expr_template_values"
+	// DeleteContinuationTokenKey carries the continuation token returned by a complex delete failed midway,
+	// to resume the same delete from where it stopped, see deleteContinuation.
+	DeleteContinuationTokenKey = "delete.continuation_token"
+	// DeletePartitionBreakdownKey makes a complex delete in partition key mode count the rows deleted per partition,
+	// returned as a json object of partition name to count in the status detail of the result.
+	// Deletes by primary keys are only counted with DeleteVerifyKey, as they don't retrieve the rows.
+	DeletePartitionBreakdownKey = "delete.partition_breakdown"
+)
+
+// Values of DeleteScopeKey.
+const (
+	DeleteScopeAll        = "all"
+	DeleteScopeHistorical = "historical"
+	DeleteScopeStreaming  = "streaming"
+)
+
+// DeleteIDsExpr is the placeholder expr of delete by primary keys carried in DeleteIDsKey.
+const DeleteIDsExpr = "$delete_ids"
+
+// getDeleteOption returns the value of a delete request option.
+func getDeleteOption(req *milvuspb.DeleteRequest, key string) (string, bool) {
+	value, ok := req.GetBase().GetProperties()[key]
+	return value, ok
+}
+
+// getDeleteBoolOption returns the bool value of a delete request option, false if absent or malformed.
+func getDeleteBoolOption(req *milvuspb.DeleteRequest, key string) bool {
+	value, ok := getDeleteOption(req, key)
+	if !ok {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err == nil && enabled
+}
+
+// getDeleteScope returns the data scope of the retrieve behind complex delete.
+func getDeleteScope(req *milvuspb.DeleteRequest) (querypb.DataScope, error) {
+	value, ok := getDeleteOption(req, DeleteScopeKey)
+	if !ok {
+		return querypb.DataScope_All, nil
+	}
+	switch value {
+	case DeleteScopeAll:
+		return querypb.DataScope_All, nil
+	case DeleteScopeHistorical:
+		return querypb.DataScope_Historical, nil
+	case DeleteScopeStreaming:
+		return querypb.DataScope_Streaming, nil
+	default:
+		return querypb.DataScope_UnKnown, merr.WrapErrParameterInvalid(
+			fmt.Sprintf("%s, %s or %s", DeleteScopeAll, DeleteScopeHistorical, DeleteScopeStreaming), value, "invalid delete scope")
+	}
+}
+
+// getDeleteGuaranteeTs returns the guarantee timestamp carried by the request, 0 if absent.
+func getDeleteGuaranteeTs(req *milvuspb.DeleteRequest) (Timestamp, error) {
+	value, ok := getDeleteOption(req, DeleteGuaranteeTsKey)
+	if !ok {
+		return 0, nil
+	}
+	ts, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, merr.WrapErrParameterInvalid("timestamp", value, "invalid guarantee timestamp of delete")
+	}
+	return ts, nil
+}
+
+// getDeleteExpr returns the expr of the request, with the template values bound if any.
+func getDeleteExpr(req *milvuspb.DeleteRequest) (string, error) {
+	value, ok := getDeleteOption(req, DeleteExprTemplateValuesKey)
+	if !ok {
+		return req.GetExpr(), nil
+	}
+	values := make(map[string]interface{})
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return "", merr.WrapErrParameterInvalidMsg("failed to decode template values of delete expr: %s", err.Error())
+	}
+	expr, err := planparserv2.BindExprTemplate(req.GetExpr(), values)
+	if err != nil {
+		return "", merr.WrapErrParameterInvalidMsg("failed to bind template values of delete expr: %s", err.Error())
+	}
+	return expr, nil
+}
+
+// setDeleteIDs makes req delete the primary keys in ids without parsing an expr.
+func setDeleteIDs(req *milvuspb.DeleteRequest, ids *schemapb.IDs) error {
+	bytes, err := proto.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	if req.Base == nil {
+		req.Base = commonpbutil.NewMsgBase(commonpbutil.WithMsgType(commonpb.MsgType_Delete))
+	}
+	if req.Base.Properties == nil {
+		req.Base.Properties = make(map[string]string)
+	}
+	req.Base.Properties[DeleteIDsKey] = base64.StdEncoding.EncodeToString(bytes)
+	req.Expr = DeleteIDsExpr
+	return nil
+}
+
+// getDeleteIDs returns the primary keys carried by the request if it's a delete by primary keys.
+func getDeleteIDs(req *milvuspb.DeleteRequest) (*schemapb.IDs, bool, error) {
+	value, ok := getDeleteOption(req, DeleteIDsKey)
+	if !ok {
+		return nil, false, nil
+	}
+	if req.GetExpr() != DeleteIDsExpr {
+		return nil, false, merr.WrapErrParameterInvalid(DeleteIDsExpr, req.GetExpr(), "expr must be the placeholder when deleting by primary keys")
+	}
+	bytes, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false, merr.WrapErrParameterInvalidMsg("failed to decode primary keys to delete: %s", err.Error())
+	}
+	ids := &schemapb.IDs{}
+	if err := proto.Unmarshal(bytes, ids); err != nil {
+		return nil, false, merr.WrapErrParameterInvalidMsg("failed to unmarshal primary keys to delete: %s", err.Error())
+	}
+	return ids, true, nil
+}
+
+// validateDeleteIDs checks the primary keys to delete against the pk field, and returns the number of them.
+func validateDeleteIDs(schema *schemapb.CollectionSchema, ids *schemapb.IDs, validatePK bool) (int64, error) {
+	pkField, err := typeutil.GetPrimaryFieldSchema(schema)
+	if err != nil {
+		return 0, err
+	}
+	switch pkField.GetDataType() {
+	case schemapb.DataType_Int64:
+		if ids.GetIntId() == nil {
+			return 0, merr.WrapErrParameterInvalid(pkField.GetDataType().String(), "non-int64 primary keys", "primary keys to delete mismatch the pk field")
+		}
+		return int64(len(ids.GetIntId().GetData())), nil
+	case schemapb.DataType_VarChar:
+		if ids.GetStrId() == nil {
+			return 0, merr.WrapErrParameterInvalid(pkField.GetDataType().String(), "non-varchar primary keys", "primary keys to delete mismatch the pk field")
+		}
+		if validatePK {
+			for idx, value := range ids.GetStrId().GetData() {
+				if err := validateVarCharPrimaryKey(pkField, idx, value); err != nil {
+					return 0, err
+				}
+			}
+		}
+		return int64(len(ids.GetStrId().GetData())), nil
+	default:
+		return 0, merr.WrapErrParameterInvalidMsg("unsupported primary key type %s", pkField.GetDataType().String())
+	}
+}
+
 type deleteTask struct {
 	Condition
 	ctx context.Context
@@ -53,13 +250,28 @@ type deleteTask struct {
 	collectionID     UniqueID
 	partitionID      UniqueID
 	partitionKeyMode bool
+	// routes the primary keys to the channels, the default one if nil
+	shardHash typeutil.ShardHash
 
 	// set by scheduler
 	ts    Timestamp
 	msgID UniqueID
 
 	// result
-	count int64
+	count       int64
+	produceSpan time.Duration
+	// rows of the task per partition, set if the partition breakdown is requested
+	partitionCnt map[string]int64
+
+	// bytes acquired from deleteBufferGate, released once the task finished
+	bufferedSize int64
+	// ids of the msgs produced to each pchannel, and the position of the last one produced to each vchannel
+	msgIDs       map[string][]msgstream.MessageID
+	endPositions map[string]deletePosition
+	// set if enqueued by deleteRunner, to trace the time waiting in the queue
+	enqueueTime time.Time
+	// the knobs of the delete producing the task, the current ones if nil
+	knobs *deleteKnobs
 }
 
 func (dt *deleteTask) TraceCtx() context.Context {
@@ -95,9 +307,17 @@ func (dt *deleteTask) SetTs(ts Timestamp) {
 }
 
 func (dt *deleteTask) OnEnqueue() error {
+	dt.markTaskState(TaskStateScheduled)
 	return nil
 }
 
+// markTaskState moves the task to state, the tasks built without a condition are skipped.
+func (dt *deleteTask) markTaskState(state TaskState) {
+	if dt.Condition != nil {
+		dt.markState(state)
+	}
+}
+
 func (dt *deleteTask) setChannels() error {
 	collID, err := globalMetaCache.GetCollectionID(dt.ctx, dt.req.GetDbName(), dt.req.GetCollectionName())
 	if err != nil {
@@ -115,11 +335,33 @@ func (dt *deleteTask) getChannels() []pChan {
 	return dt.pChannels
 }
 
+func (dt *deleteTask) rowsHint() int64 {
+	if dt.primaryKeys == nil {
+		return 0
+	}
+	return int64(typeutil.GetSizeOfIDs(dt.primaryKeys))
+}
+
+func (dt *deleteTask) collectionKey() string {
+	return dmlCollectionKey(dt.req.GetDbName(), dt.req.GetCollectionName())
+}
+
 func (dt *deleteTask) PreExecute(ctx context.Context) error {
+	dt.markTaskState(TaskStateExecuting)
 	return nil
 }
 
 func (dt *deleteTask) Execute(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			dt.markTaskState(TaskStateFailed)
+		}
+	}()
+	if !dt.enqueueTime.IsZero() {
+		_, waitSp := otel.Tracer(typeutil.ProxyRole).Start(dt.TraceCtx(), "Proxy-Delete-QueueWait",
+			trace.WithTimestamp(dt.enqueueTime), trace.WithAttributes(attribute.Int64("collectionID", dt.collectionID)))
+		waitSp.End()
+	}
 	ctx, sp := otel.Tracer(typeutil.ProxyRole).Start(ctx, "Proxy-Delete-Execute")
 	defer sp.End()
 	// log := log.Ctx(ctx)
@@ -128,35 +370,20 @@ func (dt *deleteTask) Execute(ctx context.Context) (err error) {
 		return merr.WrapErrParameterInvalid("valid expr", "empty expr", "invalid expression")
 	}
 
-	dt.tr = timerecord.NewTimeRecorder(fmt.Sprintf("proxy execute delete %d", dt.ID()))
 	stream, err := dt.chMgr.getOrCreateDmlStream(dt.collectionID)
 	if err != nil {
 		return err
 	}
 
-	hashValues := typeutil.HashPK2Channels(dt.primaryKeys, dt.vChannels)
-	// repack delete msg by dmChannel
-	result := make(map[uint32]msgstream.TsMsg)
-	numRows := int64(0)
-	for index, key := range hashValues {
-		vchannel := dt.vChannels[key]
-		_, ok := result[key]
-		if !ok {
-			deleteMsg, err := dt.newDeleteMsg(ctx)
-			if err != nil {
-				return err
-			}
-			deleteMsg.ShardName = vchannel
-			result[key] = deleteMsg
-		}
-		curMsg := result[key].(*msgstream.DeleteMsg)
-		curMsg.HashValues = append(curMsg.HashValues, hashValues[index])
-		curMsg.Timestamps = append(curMsg.Timestamps, dt.ts)
-
-		typeutil.AppendIDs(curMsg.PrimaryKeys, dt.primaryKeys, index)
-		curMsg.NumRows++
-		numRows++
+	dt.tr = newDeletePhaseRecorder(fmt.Sprintf("proxy execute delete %d", dt.ID()), dt.req.GetCollectionName())
+	// rows are routed by the same hash of primary keys as insert, so only the channels they were inserted into
+	// receive msgs. Partitions span all channels, a delete scoped by partition can't narrow the channels further.
+	hashValues := hashDeletePK2Channels(dt.shardHash, dt.primaryKeys, dt.vChannels)
+	result, err := dt.repack(ctx, hashValues)
+	if err != nil {
+		return err
 	}
+	numRows := int64(len(hashValues))
 
 	// send delete request to log broker
 	msgPack := &msgstream.MsgPack{
@@ -164,32 +391,182 @@ func (dt *deleteTask) Execute(ctx context.Context) (err error) {
 		EndTs:   dt.EndTs(),
 	}
 
+	vChannels := make([]string, 0, len(result))
 	for _, msg := range result {
 		if msg != nil {
 			msgPack.Msgs = append(msgPack.Msgs, msg)
+			vChannels = append(vChannels, msg.GetShardName())
 		}
 	}
 
+	prepareSpan := dt.tr.RecordSpanAs(metrics.DeleteRepackLabel)
 	log.Debug("send delete request to virtual channels",
 		zap.String("collectionName", dt.req.GetCollectionName()),
 		zap.Int64("collectionID", dt.collectionID),
-		zap.Strings("virtual_channels", dt.vChannels),
+		zap.Strings("virtual_channels", vChannels),
 		zap.Int64("taskID", dt.ID()),
-		zap.Duration("prepare duration", dt.tr.RecordSpan()))
-
-	err = stream.Produce(msgPack)
+		zap.Duration("prepare duration", prepareSpan))
+
+	produceCtx, produceSp := otel.Tracer(typeutil.ProxyRole).Start(ctx, "Proxy-Delete-Produce", trace.WithAttributes(
+		attribute.Int64("collectionID", dt.collectionID),
+		attribute.Int64("partitionID", dt.partitionID),
+		attribute.Int64("numRows", numRows),
+		attribute.StringSlice("vchannels", vChannels),
+	))
+	dt.msgIDs, err = dt.produceWithRetry(produceCtx, stream, msgPack)
+	if err != nil {
+		produceSp.RecordError(err)
+		produceSp.SetStatus(codes.Error, err.Error())
+	}
+	produceSp.End()
+	dt.produceSpan = dt.tr.RecordSpanAs(metrics.DeleteProduceLabel)
 	if err != nil {
 		return err
 	}
+	dt.endPositions = getDeleteEndPositions(msgPack, dt.msgIDs, dt.ts)
 	dt.count += numRows
 	return nil
 }
 
+// deletePosition is the position of the last delete msg produced to a vchannel.
+type deletePosition struct {
+	msgID msgstream.MessageID
+	ts    Timestamp
+}
+
+// getDeleteEndPositions returns the position of the last msg produced to each vchannel of msgPack,
+// the vchannels sharing a pchannel get the same position, which is still the upper bound of them.
+func getDeleteEndPositions(msgPack *msgstream.MsgPack, msgIDs map[string][]msgstream.MessageID, ts Timestamp) map[string]deletePosition {
+	positions := make(map[string]deletePosition)
+	for _, msg := range msgPack.Msgs {
+		vChannel := msg.(*msgstream.DeleteMsg).GetShardName()
+		ids := msgIDs[funcutil.ToPhysicalChannel(vChannel)]
+		if len(ids) == 0 {
+			continue
+		}
+		positions[vChannel] = deletePosition{msgID: ids[len(ids)-1], ts: ts}
+	}
+	return positions
+}
+
+// repack splits the primary keys into delete msgs by dmChannel, hashValues are the channel indexes of them.
+// The primary keys of each channel are copied to a bucket sized in advance, in one pass over them.
+func (dt *deleteTask) repack(ctx context.Context, hashValues []uint32) (map[uint32]*msgstream.DeleteMsg, error) {
+	buckets := typeutil.PartitionIDsByHash(dt.primaryKeys, hashValues, len(dt.vChannels))
+
+	result := make(map[uint32]*msgstream.DeleteMsg)
+	for key, primaryKeys := range buckets {
+		size := typeutil.GetSizeOfIDs(primaryKeys)
+		if size == 0 {
+			continue
+		}
+		keys := make([]uint32, size)
+		for i := range keys {
+			keys[i] = uint32(key)
+		}
+		deleteMsg, err := dt.newDeleteMsg(ctx, primaryKeys, keys)
+		if err != nil {
+			return nil, err
+		}
+		deleteMsg.ShardName = dt.vChannels[key]
+		result[uint32(key)] = deleteMsg
+	}
+	return result, nil
+}
+
+// newDeletePhaseRecorder creates a TimeRecorder which observes the spans of the phases of delete,
+// the phases are named by the delete phase labels.
+func newDeletePhaseRecorder(header string, collection string) *timerecord.TimeRecorder {
+	return timerecord.NewTimeRecorderWithMetrics(header, metrics.ProxyDeletePhaseLatency,
+		strconv.FormatInt(paramtable.GetNodeID(), 10), collection)
+}
+
+// hashDeletePK2Channels hashes primary keys to channels by shardHash, in parallel if there are too many of them.
+func hashDeletePK2Channels(shardHash typeutil.ShardHash, primaryKeys *schemapb.IDs, vChannels []vChan) []uint32 {
+	threshold := paramtable.Get().ProxyCfg.DeleteParallelHashThreshold.GetAsInt()
+	if threshold <= 0 || typeutil.GetSizeOfIDs(primaryKeys) <= threshold {
+		return typeutil.HashPK2ChannelsWith(shardHash, primaryKeys, vChannels)
+	}
+	return typeutil.ParallelHashPK2ChannelsWith(shardHash, primaryKeys, vChannels, hardware.GetCPUNum())
+}
+
+// produceWithRetry produces msgPack, retrying with backoff on transient mq errors,
+// other errors are returned immediately.
+func (dt *deleteTask) produceWithRetry(ctx context.Context, stream msgstream.MsgStream, msgPack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+	knobs := dt.knobs
+	if knobs == nil {
+		knobs = loadDeleteKnobs()
+	}
+
+	var msgIDs map[string][]msgstream.MessageID
+	var produceErr error
+	attempt := 0
+	err := retry.Do(ctx, func() error {
+		if attempt > 0 {
+			trace.SpanFromContext(ctx).AddEvent("retry to produce delete msgs", trace.WithAttributes(
+				attribute.Int("attempt", attempt),
+				attribute.String("error", produceErr.Error())))
+			log.Ctx(ctx).Debug("retry to produce delete msgs", zap.Int("attempt", attempt), zap.Error(produceErr))
+			metrics.ProxyDeleteProduceRetryCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10)).Inc()
+		}
+		attempt++
+		msgIDs, produceErr = stream.ProduceMark(msgPack)
+		if produceErr != nil && !isTransientProduceError(produceErr) {
+			return retry.Unrecoverable(produceErr)
+		}
+		return produceErr
+	}, retry.Attempts(uint(knobs.produceMaxRetries+1)), retry.Sleep(knobs.produceRetryInterval))
+	if err != nil && produceErr != nil {
+		// return the error of mq rather than the one wrapped by retry
+		return nil, produceErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msgIDs, nil
+}
+
+// transientProduceErrorKeywords are the error messages of mq clients indicating
+// the broker is temporarily unavailable, the msgs may be produced by retrying later.
+var transientProduceErrorKeywords = []string{
+	"timeout",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"not connected",
+	"connecterror",
+	"service not ready",
+	"broker not ready",
+}
+
+// isTransientProduceError returns whether producing msgs failed for a transient mq error.
+func isTransientProduceError(err error) bool {
+	if errors.Is(err, merr.ErrDenyProduceMsg) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.IsAny(err, syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.EPIPE, io.EOF, io.ErrUnexpectedEOF) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, keyword := range transientProduceErrorKeywords {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
 func (dt *deleteTask) PostExecute(ctx context.Context) error {
+	dt.markTaskState(TaskStateDone)
 	return nil
 }
 
-func (dt *deleteTask) newDeleteMsg(ctx context.Context) (*msgstream.DeleteMsg, error) {
+// newDeleteMsg returns a delete msg of primaryKeys, hashValues are the channel indexes of them.
+func (dt *deleteTask) newDeleteMsg(ctx context.Context, primaryKeys *schemapb.IDs, hashValues []uint32) (*msgstream.DeleteMsg, error) {
 	msgid, err := dt.idAllocator.AllocOne()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to allocate MsgID of delete")
@@ -207,16 +584,36 @@ func (dt *deleteTask) newDeleteMsg(ctx context.Context) (*msgstream.DeleteMsg, e
 		PartitionID:    dt.partitionID,
 		CollectionName: dt.req.GetCollectionName(),
 		PartitionName:  dt.req.GetPartitionName(),
-		PrimaryKeys:    &schemapb.IDs{},
+		NumRows:        int64(len(hashValues)),
+		PrimaryKeys:    primaryKeys,
+		Timestamps:     make([]uint64, len(hashValues)),
+	}
+	for i := range sliceRequest.Timestamps {
+		sliceRequest.Timestamps[i] = dt.ts
+	}
+	// carry the trace context in msg itself, so it survives the serialization of msg
+	properties := make(map[string]string)
+	msgstream.InjectCtx(ctx, properties)
+	if len(properties) > 0 {
+		sliceRequest.Base.Properties = properties
 	}
 	return &msgstream.DeleteMsg{
 		BaseMsg: msgstream.BaseMsg{
-			Ctx: ctx,
+			Ctx:        ctx,
+			HashValues: hashValues,
 		},
 		DeleteRequest: sliceRequest,
 	}, nil
 }
 
+// paths of a delete, written with the access log of it
+const (
+	// the primary keys are carried by the request or parsed from the expr
+	deletePathSimple = "simple"
+	// the primary keys are queried by the expr
+	deletePathComplex = "complex"
+)
+
 type deleteRunner struct {
 	req    *milvuspb.DeleteRequest
 	result *milvuspb.MutationResult
@@ -230,20 +627,65 @@ type deleteRunner struct {
 	tsoAllocatorIns tsoAllocator
 
 	// delete info
+	expr string
+	path string
+	// schema cached at Init, the delete is aborted if it changes midway
 	schema           *schemaInfo
 	collectionID     UniqueID
 	partitionID      UniqueID
 	partitionKeyMode bool
+	shardHash        typeutil.ShardHash
 
 	// for query
+	scope querypb.DataScope
 	msgID int64
 	ts    uint64
 	lb    LBPolicy
 	count atomic.Int64
-	err   error
+	// guarantee timestamp carried by the request, 0 if absent
+	userGuaranteeTs uint64
+	guaranteeTs     uint64
+	// rows matched by query, count only includes the produced ones
+	matchedCount atomic.Int64
+	// rows admitted to produce, bounded by maxRows if it's positive
+	admittedCount atomic.Int64
+	maxRows       int64
+	err           error
+	// progress of each channel, to resume the delete if it fails midway
+	progress *deleteProgress
+	// rows deleted per partition, only counted if the partition breakdown is requested.
+	// partitionNames are in the hash order of partition keys, or the partition specified,
+	// partitionKeyField is nil if the partition is specified, as all rows are in it
+	partitionBreakdown bool
+	partitionKeyField  *schemapb.FieldSchema
+	partitionNames     []string
+	partitionCntMu     sync.Mutex
+	partitionCnt       map[string]int64
+
+	// latency of each phase, query is pipelined with produce and wait in complex delete
+	tr          *timerecord.TimeRecorder
+	planSpan    time.Duration
+	querySpan   time.Duration
+	produceSpan atomic.Duration
+	waitSpan    atomic.Duration
+
+	// the max position produced to each vchannel, to correlate the delete with the dml channels
+	endPositionsMu sync.Mutex
+	endPositions   map[string]deletePosition
 
 	// task queue
 	queue *dmTaskQueue
+
+	// the tuning knobs read at the start of Run, kept till the end of it
+	knobs *deleteKnobs
+}
+
+// getKnobs returns the knobs read at the start of Run, or the current ones if it's not run.
+func (dr *deleteRunner) getKnobs() *deleteKnobs {
+	if dr.knobs == nil {
+		return loadDeleteKnobs()
+	}
+	return dr.knobs
 }
 
 func (dr *deleteRunner) Init(ctx context.Context) error {
@@ -263,16 +705,29 @@ func (dr *deleteRunner) Init(ctx context.Context) error {
 	if err != nil {
 		return ErrWithLog(log, "Failed to get collection schema", err)
 	}
+	dr.shardHash, err = dr.schema.GetShardHash()
+	if err != nil {
+		return ErrWithLog(log, "Failed to get shard hash of collection", err)
+	}
 
 	dr.partitionKeyMode = dr.schema.IsPartitionKeyCollection()
 	// get partitionIDs of delete
 	dr.partitionID = common.InvalidPartitionID
 	if len(dr.req.PartitionName) > 0 {
+		partName := dr.req.GetPartitionName()
 		if dr.partitionKeyMode {
-			return errors.New("not support manually specifying the partition names if partition key mode is used")
+			if !getDeleteBoolOption(dr.req, DeletePartitionKeyOverrideKey) ||
+				!paramtable.Get().ProxyCfg.DeletePartitionKeyOverride.GetAsBool() {
+				return errors.New("not support manually specifying the partition names if partition key mode is used")
+			}
+			user, _ := GetCurUserFromContext(ctx)
+			log.Warn("delete with partition name in partition key mode",
+				zap.String("user", user),
+				zap.String("collection", collName),
+				zap.String("partition", partName),
+				zap.String("expr", dr.req.GetExpr()))
 		}
 
-		partName := dr.req.GetPartitionName()
 		if err := validatePartitionTag(partName, true); err != nil {
 			return ErrWithLog(log, "Invalid partition name", err)
 		}
@@ -299,31 +754,146 @@ func (dr *deleteRunner) Init(ctx context.Context) error {
 	return nil
 }
 
-func (dr *deleteRunner) Run(ctx context.Context) error {
-	plan, err := planparserv2.CreateRetrievePlan(dr.schema.CollectionSchema, dr.req.Expr)
-	if err != nil {
-		return fmt.Errorf("failed to create expr plan, expr = %s", dr.req.GetExpr())
+func (dr *deleteRunner) Run(ctx context.Context) (err error) {
+	dr.knobs = loadDeleteKnobs()
+	dr.tr = timerecord.NewTimeRecorder("delete")
+	defer func() {
+		dr.logSlowDelete(ctx, err)
+		if positions := dr.getEndPositions(); len(positions) > 0 {
+			log.Ctx(ctx).Debug("delete produced to dml channels",
+				zap.Int64("collectionID", dr.collectionID),
+				zap.Int64("deleteCnt", dr.result.GetDeleteCnt()),
+				zap.Any("endPositions", positions),
+				zap.Error(err))
+		}
+	}()
+
+	idempotencyKey, _ := getDeleteOption(dr.req, DeleteIdempotencyKey)
+	if len(idempotencyKey) > 0 {
+		result, ok, err := getDeleteIdempotencyCache().Get(idempotencyKey, dr.req)
+		if err != nil {
+			return err
+		}
+		if ok {
+			log.Ctx(ctx).Info("delete replayed with idempotency key, return the previous result",
+				zap.String("idempotencyKey", idempotencyKey),
+				zap.Int64("deleteCnt", result.GetDeleteCnt()))
+			dr.result = result
+			return nil
+		}
 	}
 
-	isSimple, pk, numRow := getPrimaryKeysFromPlan(dr.schema.CollectionSchema, plan)
-	if isSimple {
-		// if could get delete.primaryKeys from delete expr
-		err := dr.simpleDelete(ctx, pk, numRow)
+	validatePK := !getDeleteBoolOption(dr.req, DeleteSkipPKValidationKey)
+	dr.scope, err = getDeleteScope(dr.req)
+	if err != nil {
+		return err
+	}
+	dr.userGuaranteeTs, err = getDeleteGuaranteeTs(dr.req)
+	if err != nil {
+		return err
+	}
+	ids, withIDs, err := getDeleteIDs(dr.req)
+	if err != nil {
+		return err
+	}
+	if withIDs {
+		// primary keys are carried by the request, no expr to parse
+		numRow, err := validateDeleteIDs(dr.schema.CollectionSchema, ids, validatePK)
 		if err != nil {
 			return err
 		}
+		if err := dr.runIntercepted(ctx, nil, func() error {
+			return dr.simpleDelete(ctx, ids, numRow)
+		}); err != nil {
+			return err
+		}
 	} else {
-		// if get complex delete expr
-		// need query from querynode before delete
-		err = dr.complexDelete(ctx, plan)
+		dr.expr, err = getDeleteExpr(dr.req)
 		if err != nil {
-			log.Warn("complex delete failed,but delete some data", zap.Int64("count", dr.result.DeleteCnt), zap.String("expr", dr.req.GetExpr()))
 			return err
 		}
+		if err := dr.runExpr(ctx, validatePK); err != nil {
+			return err
+		}
+	}
+
+	if len(idempotencyKey) > 0 {
+		getDeleteIdempotencyCache().Put(idempotencyKey, dr.req, dr.result)
 	}
 	return nil
 }
 
+func (dr *deleteRunner) runExpr(ctx context.Context, validatePK bool) error {
+	plan, isSimple, pk, numRow, err := dr.createPlan(ctx, validatePK)
+	if err != nil {
+		return err
+	}
+	if isSimple {
+		// if could get delete.primaryKeys from delete expr
+		return dr.runIntercepted(ctx, plan, func() error {
+			return dr.simpleDelete(ctx, pk, numRow)
+		})
+	}
+
+	// if get complex delete expr
+	// need query from querynode before delete
+	err = dr.runIntercepted(ctx, plan, func() error {
+		return dr.complexDelete(ctx, plan)
+	})
+	if err != nil && dr.result.GetDeleteCnt() > 0 && !isAbortedDelete(err) {
+		log.Warn("complex delete failed,but delete some data", zap.Int64("count", dr.result.DeleteCnt), zap.String("expr", dr.req.GetExpr()))
+		if token, ok := dr.progress.token(); ok {
+			return merr.WrapErrDeleteResumable(dr.result.GetDeleteCnt(), token, err)
+		}
+		return merr.WrapErrDeletePartial(dr.result.GetDeleteCnt(), err)
+	}
+	return err
+}
+
+// createPlan creates the plan of expr, and the primary keys if they could be got from expr.
+func (dr *deleteRunner) createPlan(ctx context.Context, validatePK bool) (plan *planpb.PlanNode, isSimple bool, pk *schemapb.IDs, numRow int64, err error) {
+	tr := newDeletePhaseRecorder("delete plan", dr.req.GetCollectionName())
+	_, sp := otel.Tracer(typeutil.ProxyRole).Start(ctx, "Proxy-Delete-Plan", trace.WithAttributes(
+		attribute.Int64("collectionID", dr.collectionID),
+	))
+	defer func() {
+		if err != nil {
+			sp.RecordError(err)
+			sp.SetStatus(codes.Error, err.Error())
+		} else {
+			sp.SetAttributes(attribute.Bool("simple", isSimple), attribute.Int64("rows", numRow))
+		}
+		sp.End()
+	}()
+
+	plan, err = getDeletePlanCache().GetOrCreate(dr.collectionID, dr.schema, dr.expr)
+	if err != nil {
+		return nil, false, nil, 0, fmt.Errorf("failed to create expr plan, expr = %s: %w", dr.expr, err)
+	}
+
+	if err := validateDeletePlanFields(dr.schema.CollectionSchema, plan); err != nil {
+		return nil, false, nil, 0, err
+	}
+
+	isSimple, pk, numRow, err = getPrimaryKeysFromPlan(dr.schema.CollectionSchema, plan, validatePK)
+	if err != nil {
+		return nil, false, nil, 0, err
+	}
+	dr.planSpan = tr.ElapseSpanAs(metrics.DeletePlanLabel)
+	return plan, isSimple, pk, numRow, nil
+}
+
+// runIntercepted runs the delete between the registered DeleteInterceptors.
+func (dr *deleteRunner) runIntercepted(ctx context.Context, plan *planpb.PlanNode, execute func() error) error {
+	if err := globalDeleteInterceptors.beforeDelete(ctx, dr.req, plan); err != nil {
+		log.Ctx(ctx).Warn("delete is rejected by interceptor", zap.Error(err))
+		return err
+	}
+	err := execute()
+	globalDeleteInterceptors.afterDelete(ctx, dr.req, dr.result)
+	return err
+}
+
 func (dr *deleteRunner) produce(ctx context.Context, primaryKeys *schemapb.IDs) (*deleteTask, error) {
 	task := &deleteTask{
 		ctx:              ctx,
@@ -335,11 +905,19 @@ func (dr *deleteRunner) produce(ctx context.Context, primaryKeys *schemapb.IDs)
 		collectionID:     dr.collectionID,
 		partitionID:      dr.partitionID,
 		partitionKeyMode: dr.partitionKeyMode,
+		shardHash:        dr.shardHash,
 		vChannels:        dr.vChannels,
 		primaryKeys:      primaryKeys,
+		enqueueTime:      time.Now(),
+		knobs:            dr.getKnobs(),
 	}
 
 	if err := dr.queue.Enqueue(task); err != nil {
+		if errors.Is(err, merr.ErrServiceOverloaded) {
+			// pushed back to the client, which is expected to retry later
+			log.Ctx(ctx).RatedWarn(10, "delete task rejected as the dml queue is overloaded", zap.Error(err))
+			return nil, err
+		}
 		log.Error("Failed to enqueue delete task: " + err.Error())
 		return nil, err
 	}
@@ -350,11 +928,27 @@ func (dr *deleteRunner) produce(ctx context.Context, primaryKeys *schemapb.IDs)
 // getStreamingQueryAndDelteFunc return query function used by LBPolicy
 // make sure it concurrent safe
 func (dr *deleteRunner) getStreamingQueryAndDelteFunc(plan *planpb.PlanNode) executeFunc {
-	return func(ctx context.Context, nodeID int64, qn types.QueryNodeClient, channel string) error {
+	return func(ctx context.Context, nodeID int64, qn types.QueryNodeClient, channel string) (err error) {
+		ctx, sp := otel.Tracer(typeutil.ProxyRole).Start(ctx, "Proxy-Delete-QueryStream", trace.WithAttributes(
+			attribute.Int64("msgID", dr.msgID),
+			attribute.Int64("collectionID", dr.collectionID),
+			attribute.Int64("nodeID", nodeID),
+			attribute.String("channel", channel),
+		))
+		rows := int64(0)
+		defer func() {
+			sp.SetAttributes(attribute.Int64("rows", rows))
+			if err != nil {
+				sp.RecordError(err)
+				sp.SetStatus(codes.Error, err.Error())
+			}
+			sp.End()
+		}()
+
 		var partitionIDs []int64
 
-		// optimize query when partitionKey on
-		if dr.partitionKeyMode {
+		// optimize query when partitionKey on, unless the partition is specified explicitly
+		if dr.partitionKeyMode && dr.partitionID == common.InvalidPartitionID {
 			expr, err := ParseExprFromPlan(plan)
 			if err != nil {
 				return err
@@ -368,7 +962,7 @@ func (dr *deleteRunner) getStreamingQueryAndDelteFunc(plan *planpb.PlanNode) exe
 			if err != nil {
 				return err
 			}
-		} else if dr.partitionID != common.InvalidFieldID {
+		} else if dr.partitionID != common.InvalidPartitionID {
 			partitionIDs = []int64{dr.partitionID}
 		}
 
@@ -378,12 +972,32 @@ func (dr *deleteRunner) getStreamingQueryAndDelteFunc(plan *planpb.PlanNode) exe
 			zap.String("channel", channel),
 			zap.Int64("nodeID", nodeID))
 
+		run, skip, lastPK := dr.progress.start(channel)
+		if skip {
+			log.Info("channel is done by the resumed delete, skip it")
+			return nil
+		}
+
 		// set plan
+		channelPlan := plan
+		if lastPK != nil {
+			pkField, err := typeutil.GetPrimaryFieldSchema(dr.schema.CollectionSchema)
+			if err != nil {
+				return err
+			}
+			// rows up to the last primary key are deleted by the resumed delete
+			channelPlan = withPKGreaterThan(plan, pkField, lastPK)
+			log.Info("resume delete of channel", zap.Any("lastPK", lastPK))
+		}
 		_, outputFieldIDs := translatePkOutputFields(dr.schema.CollectionSchema)
 		outputFieldIDs = append(outputFieldIDs, common.TimeStampField)
-		plan.OutputFieldIds = outputFieldIDs
+		if dr.partitionKeyField != nil {
+			// partition key is only retrieved for the breakdown, to resolve the partition of rows
+			outputFieldIDs = append(outputFieldIDs, dr.partitionKeyField.GetFieldID())
+		}
+		channelPlan.OutputFieldIds = outputFieldIDs
 
-		serializedPlan, err := proto.Marshal(plan)
+		serializedPlan, err := proto.Marshal(channelPlan)
 		if err != nil {
 			return err
 		}
@@ -403,50 +1017,90 @@ func (dr *deleteRunner) getStreamingQueryAndDelteFunc(plan *planpb.PlanNode) exe
 				PartitionIDs:       partitionIDs,
 				SerializedExprPlan: serializedPlan,
 				OutputFieldsId:     outputFieldIDs,
-				GuaranteeTimestamp: parseGuaranteeTsFromConsistency(dr.ts, dr.ts, dr.req.GetConsistencyLevel()),
+				GuaranteeTimestamp: dr.guaranteeTs,
 			},
 			DmlChannels: []string{channel},
-			Scope:       querypb.DataScope_All,
+			Scope:       dr.scope,
+		}
+		// link the spans of query node to the channel
+		properties := make(map[string]string)
+		msgstream.InjectCtx(ctx, properties)
+		if len(properties) > 0 {
+			queryReq.Req.Base.Properties = properties
 		}
 
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
 		log.Debug("start query for delete", zap.Int64("msgID", dr.msgID))
-		client, err := qn.QueryStream(ctx, queryReq)
+		// the same request is re-issued on retriable error statuses, to retrieve with the same plan and mvcc ts
+		queryStream := func() (querypb.QueryNode_QueryStreamClient, error) {
+			return qn.QueryStream(ctx, queryReq)
+		}
+		client, err := queryStream()
 		if err != nil {
 			log.Warn("query stream for delete create failed", zap.Error(err))
 			return err
 		}
 
-		taskCh := make(chan *deleteTask, 256)
-		go dr.receiveQueryResult(ctx, client, taskCh)
+		// memory of buffered tasks is bounded by deleteBufferGate,
+		// the capacity only bounds the number of them, like the dml queue does
+		taskCh := make(chan *deleteTask, dr.getKnobs().taskBufferSize)
+		go dr.receiveQueryResult(ctx, client, queryStream, taskCh, run)
+		gate := getDeleteBufferGate()
+		defer func() {
+			// release tasks left by early return, the receiver quits as ctx is canceled
+			go func() {
+				for task := range taskCh {
+					_ = task.WaitToFinish()
+					gate.Release(task.bufferedSize)
+				}
+			}()
+		}()
 		// wait all task finish
 		for task := range taskCh {
-			err := task.WaitToFinish()
+			err := dr.waitTask(ctx, task)
+			gate.Release(task.bufferedSize)
 			if err != nil {
 				return err
 			}
 			dr.count.Add(task.count)
+			rows += task.count
+			dr.addPartitionCnt(task.partitionCnt)
+			run.delete(task.primaryKeys)
 		}
 
 		// query or produce task failed
 		if dr.err != nil {
+			// retrying on other replicas can't help, let the other channels be canceled
+			if isAbortedDelete(dr.err) {
+				return retry.Unrecoverable(dr.err)
+			}
 			return dr.err
 		}
+		run.done()
 		return nil
 	}
 }
 
-func (dr *deleteRunner) receiveQueryResult(ctx context.Context, client querypb.QueryNode_QueryStreamClient, taskCh chan *deleteTask) {
+func (dr *deleteRunner) receiveQueryResult(ctx context.Context, client querypb.QueryNode_QueryStreamClient,
+	queryStream func() (querypb.QueryNode_QueryStreamClient, error), taskCh chan *deleteTask, run *deleteChannelRun,
+) {
 	defer func() {
 		close(taskCh)
 	}()
+	gate := getDeleteBufferGate()
+	knobs := dr.getKnobs()
+	maxRetries := knobs.queryStreamMaxRetries
+	retries := 0
+	// a re-issued query stream returns the rows produced already again, in the same order
+	cursor := &queryStreamCursor{}
 
 	for {
 		result, err := client.Recv()
 		if err != nil {
 			if err == io.EOF {
 				log.Debug("query stream for delete finished", zap.Int64("msgID", dr.msgID))
+				run.finishReceive()
 				return
 			}
 			dr.err = err
@@ -455,25 +1109,356 @@ func (dr *deleteRunner) receiveQueryResult(ctx context.Context, client querypb.Q
 
 		err = merr.Error(result.GetStatus())
 		if err != nil {
+			// segments handoff or target updates are resolved soon, re-issue the query stream instead of failing the channel
+			if merr.IsRetryableErr(err) && retries < maxRetries {
+				retries++
+				log.Warn("query stream for delete get retriable error status, re-issue it",
+					zap.Int64("msgID", dr.msgID), zap.Int("retries", retries), zap.Error(err))
+				trace.SpanFromContext(ctx).AddEvent("re-issue query stream", trace.WithAttributes(
+					attribute.Int("retries", retries),
+					attribute.String("error", err.Error())))
+				client, err = queryStream()
+				if err != nil {
+					dr.err = err
+					log.Warn("re-issue query stream for delete failed", zap.Int64("msgID", dr.msgID), zap.Error(err))
+					return
+				}
+				cursor.reissue()
+				continue
+			}
 			dr.err = err
 			log.Warn("query stream for delete get error status", zap.Int64("msgID", dr.msgID), zap.Error(err))
 			return
 		}
 
-		task, err := dr.produce(ctx, result.GetIds())
+		partitions, err := dr.resolvePartitions(result)
 		if err != nil {
 			dr.err = err
-			log.Warn("produce delete task failed", zap.Error(err))
+			log.Warn("resolve partitions of query result for delete failed", zap.Int64("msgID", dr.msgID), zap.Error(err))
+			return
+		}
+		resultIDs, partitions, err := cursor.advance(result.GetIds(), partitions)
+		if err != nil {
+			dr.err = err
+			log.Warn("resume re-issued query stream for delete failed", zap.Int64("msgID", dr.msgID), zap.Error(err))
+			return
+		}
+		dr.matchedCount.Add(int64(typeutil.GetSizeOfIDs(resultIDs)))
+		run.receive(resultIDs)
+		// throttle the query result once too many primary keys are buffered,
+		// the bytes of the result are handed over to its tasks, and released once they finished
+		pending := estimateDeleteIDsSize(resultIDs)
+		if err := gate.Acquire(ctx, pending); err != nil {
+			dr.err = err
 			return
 		}
+		offset := 0
+		// split oversized result, so a single task won't stall the dml queue
+		for _, ids := range splitDeleteIDs(resultIDs, knobs.chunkSize) {
+			rows := typeutil.GetSizeOfIDs(ids)
+			if !dr.admitRows(int64(rows)) {
+				log.Warn("rows of delete exceeded the limit, stop consuming query result",
+					zap.Int64("msgID", dr.msgID), zap.Int64("limit", dr.maxRows))
+				gate.Release(pending)
+				dr.err = merr.ErrDeleteRowsExceeded
+				return
+			}
+			// the plan and partition routing are stale if the schema changed
+			if err := dr.checkSchema(ctx); err != nil {
+				gate.Release(pending)
+				dr.err = err
+				return
+			}
+			task, err := dr.produce(ctx, ids)
+			if err != nil {
+				gate.Release(pending)
+				dr.err = err
+				log.Warn("produce delete task failed", zap.Error(err))
+				return
+			}
+			task.bufferedSize = estimateDeleteIDsSize(ids)
+			pending -= task.bufferedSize
+			if partitions != nil {
+				task.partitionCnt = dr.countPartitions(partitions[offset : offset+rows])
+			}
+			offset += rows
+
+			select {
+			case taskCh <- task:
+			case <-ctx.Done():
+				// consumer has quit
+				gate.Release(pending + task.bufferedSize)
+				dr.err = ctx.Err()
+				return
+			}
+		}
+	}
+}
 
-		taskCh <- task
+// resolvePartitions returns the index in partitionNames of the partition of each row in result,
+// nil if the partition breakdown is not requested.
+func (dr *deleteRunner) resolvePartitions(result *internalpb.RetrieveResults) ([]uint32, error) {
+	if !dr.partitionBreakdown {
+		return nil, nil
 	}
+	rows := typeutil.GetSizeOfIDs(result.GetIds())
+	if dr.partitionKeyField == nil {
+		return make([]uint32, rows), nil
+	}
+
+	for _, fieldData := range result.GetFieldsData() {
+		if fieldData.GetFieldId() != dr.partitionKeyField.GetFieldID() {
+			continue
+		}
+		partitions, err := typeutil.HashKey2Partitions(fieldData, dr.partitionNames)
+		if err != nil {
+			return nil, err
+		}
+		if len(partitions) != rows {
+			return nil, merr.WrapErrServiceInternal(fmt.Sprintf("query result for delete has %d rows but %d partition keys", rows, len(partitions)))
+		}
+		return partitions, nil
+	}
+	return nil, merr.WrapErrServiceInternal("partition key is not returned by query for delete")
+}
+
+// countPartitions returns the number of rows per partition name.
+func (dr *deleteRunner) countPartitions(partitions []uint32) map[string]int64 {
+	cnt := make(map[string]int64)
+	for _, idx := range partitions {
+		cnt[dr.partitionNames[idx]]++
+	}
+	return cnt
+}
+
+func (dr *deleteRunner) addPartitionCnt(cnt map[string]int64) {
+	if len(cnt) == 0 {
+		return
+	}
+	dr.partitionCntMu.Lock()
+	defer dr.partitionCntMu.Unlock()
+	for name, n := range cnt {
+		dr.partitionCnt[name] += n
+	}
+}
+
+// initPartitionBreakdown prepares counting the rows deleted per partition if it's requested.
+func (dr *deleteRunner) initPartitionBreakdown(ctx context.Context) error {
+	if !getDeleteBoolOption(dr.req, DeletePartitionBreakdownKey) {
+		return nil
+	}
+	if !dr.partitionKeyMode {
+		return merr.WrapErrParameterInvalidMsg("partition breakdown of delete is only supported in partition key mode")
+	}
+
+	if dr.partitionID != common.InvalidPartitionID {
+		dr.partitionNames = []string{dr.req.GetPartitionName()}
+	} else {
+		var err error
+		dr.partitionKeyField, err = typeutil.GetPartitionKeyFieldSchema(dr.schema.CollectionSchema)
+		if err != nil {
+			return err
+		}
+		dr.partitionNames, err = globalMetaCache.GetPartitionsIndex(ctx, dr.req.GetDbName(), dr.req.GetCollectionName())
+		if err != nil {
+			return err
+		}
+	}
+	dr.partitionBreakdown = true
+	dr.partitionCnt = make(map[string]int64)
+	return nil
+}
+
+// checkSchema returns ErrCollectionIdentityChanged if the name is repointed or the collection dropped since Init,
+// or ErrDeleteSchemaChanged if the collection is altered. The version cached is checked first,
+// the schema is compared only if it's reloaded.
+func (dr *deleteRunner) checkSchema(ctx context.Context) error {
+	dbName, collName := dr.req.GetDbName(), dr.req.GetCollectionName()
+	collectionID, version, ok := globalMetaCache.GetCollectionSchemaVersion(dbName, collName)
+	if ok && collectionID == dr.collectionID && version == dr.schema.Version() {
+		return nil
+	}
+
+	if err := dr.checkCollectionIdentity(ctx); err != nil {
+		return err
+	}
+	schema, err := globalMetaCache.GetCollectionSchema(ctx, dbName, collName)
+	if err != nil {
+		return err
+	}
+	if !sameSchemaFields(dr.schema.CollectionSchema, schema.CollectionSchema) {
+		log.Ctx(ctx).Warn("schema changed during delete, abort it",
+			zap.String("collection", collName),
+			zap.Int64("collectionID", dr.collectionID))
+		return merr.WrapErrDeleteSchemaChanged(collName, dr.count.Load())
+	}
+	return nil
+}
+
+// checkCollectionIdentity resolves the collection name again, and returns ErrCollectionIdentityChanged
+// if it's resolved to another collection than the one resolved at Init, or the collection is dropped.
+func (dr *deleteRunner) checkCollectionIdentity(ctx context.Context) error {
+	dbName, collName := dr.req.GetDbName(), dr.req.GetCollectionName()
+	collectionID, err := globalMetaCache.GetCollectionID(ctx, dbName, collName)
+	if errors.Is(err, merr.ErrCollectionNotFound) {
+		collectionID, err = 0, nil
+	}
+	if err != nil {
+		return err
+	}
+	if collectionID != dr.collectionID {
+		log.Ctx(ctx).Warn("collection identity changed during delete, abort it",
+			zap.String("collection", collName),
+			zap.Int64("collectionID", dr.collectionID),
+			zap.Int64("currentCollectionID", collectionID))
+		return merr.WrapErrCollectionIdentityChanged(collName, dr.collectionID, collectionID)
+	}
+	return nil
+}
+
+// classifyError returns ErrCollectionIdentityChanged rather than err the delete failed with, if the collection
+// identity changed in the meantime, as err is caused by the change, e.g. the shard leaders or segments mismatch,
+// which the clients retry in vain.
+func (dr *deleteRunner) classifyError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, merr.ErrCollectionIdentityChanged) {
+		return err
+	}
+	if identityErr := dr.checkCollectionIdentity(ctx); errors.Is(identityErr, merr.ErrCollectionIdentityChanged) {
+		return errors.Wrapf(identityErr, "delete failed with %s", err.Error())
+	}
+	return err
+}
+
+// isAbortedDelete returns whether the delete is aborted by err, which retrying on the other replicas or
+// resuming from the progress can't help.
+func isAbortedDelete(err error) bool {
+	return errors.Is(err, merr.ErrDeleteRowsExceeded) || errors.Is(err, merr.ErrDeleteSchemaChanged) ||
+		errors.Is(err, merr.ErrCollectionIdentityChanged)
+}
+
+// sameSchemaFields returns whether the fields of the schemas are the same.
+func sameSchemaFields(a, b *schemapb.CollectionSchema) bool {
+	if len(a.GetFields()) != len(b.GetFields()) {
+		return false
+	}
+	for i, field := range a.GetFields() {
+		if !proto.Equal(field, b.GetFields()[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// admitRows returns false if deleting n more rows would exceed the row limit of the request.
+func (dr *deleteRunner) admitRows(n int64) bool {
+	if dr.maxRows <= 0 {
+		return true
+	}
+	return dr.admittedCount.Add(n) <= dr.maxRows
+}
+
+// queryStreamCursor is the position in the query stream of a channel. A re-issued query stream returns the rows
+// in the same order, so the ones received already are skipped by their number, no primary key is kept but the last one.
+type queryStreamCursor struct {
+	// number of rows received, and the primary key of the last one
+	received  int
+	lastIntPK int64
+	lastStrPK string
+	// number of rows of the re-issued query stream left to skip
+	skip int
+}
+
+// reissue skips the rows received already from the re-issued query stream.
+func (c *queryStreamCursor) reissue() {
+	c.skip = c.received
+}
+
+// advance returns the rows of ids not received before, with their partitions if partitions is not nil,
+// ids is returned as is if none of them is skipped. It fails if the last row skipped is not the last one
+// received, which means the re-issued query stream returns the rows in another order.
+func (c *queryStreamCursor) advance(ids *schemapb.IDs, partitions []uint32) (*schemapb.IDs, []uint32, error) {
+	rows := typeutil.GetSizeOfIDs(ids)
+	if c.skip > 0 && rows > 0 {
+		skipped := rows
+		if skipped > c.skip {
+			skipped = c.skip
+		}
+		c.skip -= skipped
+		if c.skip == 0 && !c.isLast(ids, skipped-1) {
+			return nil, nil, merr.WrapErrServiceInternal("re-issued query stream for delete returns the rows in another order")
+		}
+		ids = typeutil.SliceIDs(ids, skipped, rows)
+		if partitions != nil {
+			partitions = partitions[skipped:]
+		}
+		rows -= skipped
+	}
+	if rows > 0 {
+		c.received += rows
+		switch ids.GetIdField().(type) {
+		case *schemapb.IDs_IntId:
+			c.lastIntPK = ids.GetIntId().GetData()[rows-1]
+		case *schemapb.IDs_StrId:
+			c.lastStrPK = ids.GetStrId().GetData()[rows-1]
+		}
+	}
+	return ids, partitions, nil
+}
+
+// isLast returns whether the i-th primary key of ids is the last one received.
+func (c *queryStreamCursor) isLast(ids *schemapb.IDs, i int) bool {
+	switch ids.GetIdField().(type) {
+	case *schemapb.IDs_IntId:
+		return ids.GetIntId().GetData()[i] == c.lastIntPK
+	case *schemapb.IDs_StrId:
+		return ids.GetStrId().GetData()[i] == c.lastStrPK
+	default:
+		return false
+	}
+}
+
+// splitDeleteIDs splits ids into chunks of at most chunkSize ids, sharing the underlying arrays.
+func splitDeleteIDs(ids *schemapb.IDs, chunkSize int) []*schemapb.IDs {
+	size := typeutil.GetSizeOfIDs(ids)
+	if chunkSize <= 0 || size <= chunkSize {
+		return []*schemapb.IDs{ids}
+	}
+
+	chunks := make([]*schemapb.IDs, 0, (size+chunkSize-1)/chunkSize)
+	for start := 0; start < size; start += chunkSize {
+		end := start + chunkSize
+		if end > size {
+			end = size
+		}
+		switch ids.GetIdField().(type) {
+		case *schemapb.IDs_IntId:
+			chunks = append(chunks, &schemapb.IDs{
+				IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids.GetIntId().GetData()[start:end]}},
+			})
+		case *schemapb.IDs_StrId:
+			chunks = append(chunks, &schemapb.IDs{
+				IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: ids.GetStrId().GetData()[start:end]}},
+			})
+		}
+	}
+	return chunks
 }
 
 func (dr *deleteRunner) complexDelete(ctx context.Context, plan *planpb.PlanNode) error {
-	rc := timerecord.NewTimeRecorder("QueryStreamDelete")
+	rc := newDeletePhaseRecorder("QueryStreamDelete", dr.req.GetCollectionName())
+	ctx, sp := otel.Tracer(typeutil.ProxyRole).Start(ctx, "Proxy-Delete-Query", trace.WithAttributes(
+		attribute.Int64("collectionID", dr.collectionID),
+	))
+	defer sp.End()
 	var err error
+	dr.path = deletePathComplex
+
+	// wait for the buffered deletes to drain before querying, the ts is allocated after that
+	timeout := getDeleteParamDuration(&paramtable.Get().ProxyCfg.DeleteAdmissionTimeout, time.Millisecond)
+	if err := getDeleteBufferGate().Admit(ctx, timeout); err != nil {
+		log.Ctx(ctx).Warn("complex delete is not admitted", zap.Error(err))
+		return err
+	}
 
 	dr.msgID, err = dr.idAllocator.AllocOne()
 	if err != nil {
@@ -485,79 +1470,341 @@ func (dr *deleteRunner) complexDelete(ctx context.Context, plan *planpb.PlanNode
 		return err
 	}
 
+	dr.guaranteeTs, err = dr.parseGuaranteeTs()
+	if err != nil {
+		return err
+	}
+	dr.maxRows = paramtable.Get().ProxyCfg.MaxDeleteRowsPerRequest.GetAsInt64()
+	token, _ := getDeleteOption(dr.req, DeleteContinuationTokenKey)
+	dr.progress, err = newDeleteProgress(token, dr.collectionID, dr.partitionID, dr.schema.CollectionSchema, dr.expr, dr.vChannels)
+	if err != nil {
+		return err
+	}
+	if err := dr.initPartitionBreakdown(ctx); err != nil {
+		return err
+	}
+
 	err = dr.lb.Execute(ctx, CollectionWorkLoad{
 		db:             dr.req.GetDbName(),
 		collectionName: dr.req.GetCollectionName(),
 		collectionID:   dr.collectionID,
 		nq:             1,
 		exec:           dr.getStreamingQueryAndDelteFunc(plan),
+		// the streaming queries of delete stick to the delegators warmed up by the previous ones,
+		// unless another replica is less loaded, e.g. the delegators are saturated by searches
+		affinity:  true,
+		loadAware: true,
 	})
+	dr.querySpan = rc.ElapseSpanAs(metrics.DeleteQueryLabel)
 	dr.result.DeleteCnt = dr.count.Load()
+	matchedCnt := dr.matchedCount.Load()
+	if matchedCnt != dr.result.GetDeleteCnt() {
+		log.Warn("rows matched by query are not all deleted",
+			zap.Int64("matchedCnt", matchedCnt),
+			zap.Int64("deleteCnt", dr.result.GetDeleteCnt()),
+			zap.Int64("undeletedCnt", matchedCnt-dr.result.GetDeleteCnt()))
+	}
+	sp.SetAttributes(
+		attribute.Int64("msgID", dr.msgID),
+		attribute.Int64("rows", dr.result.GetDeleteCnt()),
+		attribute.Int64("matchedRows", matchedCnt))
 	if err != nil {
+		sp.RecordError(err)
+		sp.SetStatus(codes.Error, err.Error())
+		if dr.result.GetDeleteCnt() > 0 {
+			sp.AddEvent("delete partially failed", trace.WithAttributes(attribute.Int64("deletedRows", dr.result.GetDeleteCnt())))
+		}
 		log.Warn("fail to execute complex delete",
 			zap.Int64("deleteCnt", dr.result.GetDeleteCnt()),
 			zap.Duration("interval", rc.ElapseSpan()),
 			zap.Error(err))
+		if errors.Is(err, merr.ErrDeleteRowsExceeded) {
+			return merr.WrapErrDeleteRowsExceeded(dr.maxRows, dr.result.GetDeleteCnt())
+		}
+		if errors.Is(err, merr.ErrDeleteSchemaChanged) {
+			return merr.WrapErrDeleteSchemaChanged(dr.req.GetCollectionName(), dr.result.GetDeleteCnt())
+		}
+		err = dr.classifyError(ctx, err)
+		if errors.Is(err, merr.ErrCollectionIdentityChanged) {
+			return errors.Wrapf(err, "complex delete aborted with %d rows deleted", dr.result.GetDeleteCnt())
+		}
+		if matchedCnt != dr.result.GetDeleteCnt() {
+			return errors.Wrapf(err, "complex delete matched %d rows but only deleted %d rows", matchedCnt, dr.result.GetDeleteCnt())
+		}
 		return err
 	}
 
+	if dr.partitionBreakdown {
+		detail, err := json.Marshal(dr.partitionCnt)
+		if err != nil {
+			return err
+		}
+		dr.result.Status.Detail = string(detail)
+	}
+
 	log.Info("complex delete finished", zap.Int64("deleteCnt", dr.result.GetDeleteCnt()), zap.Duration("interval", rc.ElapseSpan()))
 	return nil
 }
 
+// parseGuaranteeTs returns the guarantee timestamp of the retrieve, the one carried by the request
+// takes precedence over the one derived from the consistency level.
+func (dr *deleteRunner) parseGuaranteeTs() (Timestamp, error) {
+	if dr.userGuaranteeTs == 0 {
+		return parseGuaranteeTsFromConsistency(dr.ts, dr.ts, dr.req.GetConsistencyLevel()), nil
+	}
+
+	tolerance := paramtable.Get().ProxyCfg.DeleteGuaranteeTsTolerance.GetAsDuration(time.Millisecond)
+	if dr.userGuaranteeTs > tsoutil.AddPhysicalDurationOnTs(dr.ts, tolerance) {
+		return 0, merr.WrapErrParameterInvalidMsg("guarantee timestamp %s of delete is too far in the future, current timestamp %s",
+			tsoutil.ParseAndFormatHybridTs(dr.userGuaranteeTs), tsoutil.ParseAndFormatHybridTs(dr.ts))
+	}
+	return parseGuaranteeTsFromConsistency(dr.userGuaranteeTs, dr.ts, commonpb.ConsistencyLevel_Customized), nil
+}
+
 func (dr *deleteRunner) simpleDelete(ctx context.Context, pk *schemapb.IDs, numRow int64) error {
 	log.Debug("get primary keys from expr",
 		zap.Int64("len of primary keys", numRow),
 		zap.Int64("collectionID", dr.collectionID),
 		zap.Int64("partitionID", dr.partitionID))
 
+	dr.path = deletePathSimple
+	if getDeleteBoolOption(dr.req, DeleteVerifyKey) {
+		pkField, err := typeutil.GetPrimaryFieldSchema(dr.schema.CollectionSchema)
+		if err != nil {
+			return err
+		}
+		// retrieve the primary keys like complex delete, so only the existing ones are deleted
+		return dr.complexDelete(ctx, planparserv2.CreateRequeryPlan(pkField, pk))
+	}
+
+	// the name may be repointed or the collection dropped since Init
+	if err := dr.checkCollectionIdentity(ctx); err != nil {
+		return err
+	}
 	task, err := dr.produce(ctx, pk)
 	if err != nil {
 		log.Warn("produce delete task failed")
-		return err
+		return dr.classifyError(ctx, err)
 	}
 
-	err = task.WaitToFinish()
+	err = dr.waitTask(ctx, task)
 	if err == nil {
 		dr.result.DeleteCnt = task.count
 	}
+	return dr.classifyError(ctx, err)
+}
+
+// accessDeleteInfo returns the detail of the delete written with the access log of it,
+// the partitions are the ones rows are deleted from if the partition breakdown is requested.
+func (dr *deleteRunner) accessDeleteInfo() *accesslog.DeleteInfo {
+	info := &accesslog.DeleteInfo{
+		Path:      dr.path,
+		DeleteCnt: dr.result.GetDeleteCnt(),
+	}
+	dr.partitionCntMu.Lock()
+	defer dr.partitionCntMu.Unlock()
+	for name, cnt := range dr.partitionCnt {
+		if cnt > 0 {
+			info.PartitionNames = append(info.PartitionNames, name)
+		}
+	}
+	sort.Strings(info.PartitionNames)
+	return info
+}
+
+// waitTask waits the delete task to finish no longer than the deadline of ctx, and records the time spent on it.
+func (dr *deleteRunner) waitTask(ctx context.Context, task *deleteTask) error {
+	tr := timerecord.NewTimeRecorder("delete wait")
+	var err error
+	if deadline, ok := ctx.Deadline(); ok {
+		err = task.WaitToFinishWithTimeout(time.Until(deadline))
+	} else {
+		err = task.WaitToFinish()
+	}
+	dr.waitSpan.Add(tr.ElapseSpan())
+	dr.produceSpan.Add(task.produceSpan)
+	if err == nil {
+		dr.mergeEndPositions(task.endPositions)
+	}
 	return err
 }
 
-func getPrimaryKeysFromPlan(schema *schemapb.CollectionSchema, plan *planpb.PlanNode) (bool, *schemapb.IDs, int64) {
+// mergeEndPositions keeps the max position produced to each vchannel.
+func (dr *deleteRunner) mergeEndPositions(positions map[string]deletePosition) {
+	dr.endPositionsMu.Lock()
+	defer dr.endPositionsMu.Unlock()
+	if dr.endPositions == nil {
+		dr.endPositions = make(map[string]deletePosition)
+	}
+	for vChannel, position := range positions {
+		if current, ok := dr.endPositions[vChannel]; ok {
+			if before, err := position.msgID.LessOrEqualThan(current.msgID.Serialize()); err != nil || before {
+				continue
+			}
+		}
+		dr.endPositions[vChannel] = position
+	}
+}
+
+// getEndPositions returns the max positions produced to the vchannels, in the order of vchannel names.
+func (dr *deleteRunner) getEndPositions() []*msgpb.MsgPosition {
+	dr.endPositionsMu.Lock()
+	defer dr.endPositionsMu.Unlock()
+	positions := make([]*msgpb.MsgPosition, 0, len(dr.endPositions))
+	for vChannel, position := range dr.endPositions {
+		positions = append(positions, &msgpb.MsgPosition{
+			ChannelName: vChannel,
+			MsgID:       position.msgID.Serialize(),
+			Timestamp:   position.ts,
+		})
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].GetChannelName() < positions[j].GetChannelName()
+	})
+	return positions
+}
+
+// maxSlowDeleteExprLen is the max length of expr printed in slow delete log.
+const maxSlowDeleteExprLen = 256
+
+// logSlowDelete logs the delete with latency breakdown if it exceeds proxy.slowDeleteThreshold.
+func (dr *deleteRunner) logSlowDelete(ctx context.Context, err error) {
+	span := dr.tr.ElapseSpan()
+	threshold := paramtable.Get().ProxyCfg.SlowDeleteThreshold.GetAsDuration(time.Millisecond)
+	if threshold <= 0 || span < threshold {
+		return
+	}
+
+	expr := dr.req.GetExpr()
+	if len(expr) > maxSlowDeleteExprLen {
+		expr = expr[:maxSlowDeleteExprLen] + "..."
+	}
+	log.Ctx(ctx).Warn("slow delete",
+		zap.String("collection", dr.req.GetCollectionName()),
+		zap.Int64("collectionID", dr.collectionID),
+		zap.String("expr", expr),
+		zap.Int64("deleteCnt", dr.result.GetDeleteCnt()),
+		zap.Duration("duration", span),
+		zap.Duration("plan", dr.planSpan),
+		zap.Duration("query", dr.querySpan),
+		zap.Duration("produce", dr.produceSpan.Load()),
+		zap.Duration("wait", dr.waitSpan.Load()),
+		zap.Error(err))
+	metrics.ProxySlowDeleteCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), dr.req.GetCollectionName()).Inc()
+}
+
+// validateDeletePlanFields rejects delete plans referencing vector fields, only scalar predicates are supported.
+func validateDeletePlanFields(schema *schemapb.CollectionSchema, plan *planpb.PlanNode) error {
+	expr, err := ParseExprFromPlan(plan)
+	if err != nil {
+		return err
+	}
+	for _, info := range ParseColumnInfosFromExpr(expr) {
+		if !typeutil.IsVectorType(info.GetDataType()) {
+			continue
+		}
+		fieldName := fmt.Sprint(info.GetFieldId())
+		for _, field := range schema.GetFields() {
+			if field.GetFieldID() == info.GetFieldId() {
+				fieldName = field.GetName()
+			}
+		}
+		return merr.WrapErrParameterInvalidMsg("delete expr references vector field %s, delete only supports scalar predicates", fieldName)
+	}
+	return nil
+}
+
+// getPrimaryKeysFromPlan returns the primary keys if the plan is a simple delete.
+// When validatePK is set, a simple delete with primary keys violating the schema is rejected
+// with a parameter invalid error, other errors fall back to complex delete.
+func getPrimaryKeysFromPlan(schema *schemapb.CollectionSchema, plan *planpb.PlanNode, validatePK bool) (bool, *schemapb.IDs, int64, error) {
 	// simple delete request need expr with "pk in [a, b]"
 	termExpr, ok := plan.Node.(*planpb.PlanNode_Query).Query.Predicates.Expr.(*planpb.Expr_TermExpr)
 	if ok {
 		if !termExpr.TermExpr.GetColumnInfo().GetIsPrimaryKey() {
-			return false, nil, 0
+			return false, nil, 0, nil
 		}
 
-		ids, rowNum, err := getPrimaryKeysFromTermExpr(schema, termExpr)
+		ids, rowNum, err := getPrimaryKeysFromTermExpr(schema, termExpr, validatePK)
+		if errors.Is(err, merr.ErrParameterInvalid) {
+			return false, nil, 0, err
+		}
 		if err != nil {
-			return false, nil, 0
+			return false, nil, 0, nil
 		}
-		return true, ids, rowNum
+		return true, ids, rowNum, nil
 	}
 
 	// simple delete if expr with "pk == a"
 	unaryRangeExpr, ok := plan.Node.(*planpb.PlanNode_Query).Query.Predicates.Expr.(*planpb.Expr_UnaryRangeExpr)
 	if ok {
 		if unaryRangeExpr.UnaryRangeExpr.GetOp() != planpb.OpType_Equal || !unaryRangeExpr.UnaryRangeExpr.GetColumnInfo().GetIsPrimaryKey() {
-			return false, nil, 0
+			return false, nil, 0, nil
 		}
 
-		ids, err := getPrimaryKeysFromUnaryRangeExpr(schema, unaryRangeExpr)
+		ids, err := getPrimaryKeysFromUnaryRangeExpr(schema, unaryRangeExpr, validatePK)
+		if errors.Is(err, merr.ErrParameterInvalid) {
+			return false, nil, 0, err
+		}
 		if err != nil {
-			return false, nil, 0
+			return false, nil, 0, nil
+		}
+		return true, ids, 1, nil
+	}
+
+	return false, nil, 0, nil
+}
+
+// validatePrimaryKeyValue checks the idx-th primary key value of a simple delete against the pk field schema.
+func validatePrimaryKeyValue(pkField *schemapb.FieldSchema, idx int, value *planpb.GenericValue) error {
+	switch pkField.GetDataType() {
+	case schemapb.DataType_Int64:
+		if _, ok := value.GetVal().(*planpb.GenericValue_Int64Val); !ok {
+			return merr.WrapErrParameterInvalidMsg("primary key at index %d is not a valid int64 value: %v", idx, value)
 		}
-		return true, ids, 1
+	case schemapb.DataType_VarChar:
+		strVal, ok := value.GetVal().(*planpb.GenericValue_StringVal)
+		if !ok {
+			return merr.WrapErrParameterInvalidMsg("primary key at index %d is not a valid varchar value: %v", idx, value)
+		}
+		return validateVarCharPrimaryKey(pkField, idx, strVal.StringVal)
+	}
+	return nil
+}
+
+func validateVarCharPrimaryKey(pkField *schemapb.FieldSchema, idx int, value string) error {
+	if len(value) == 0 {
+		return merr.WrapErrParameterInvalidMsg("primary key at index %d is empty", idx)
+	}
+	// legacy schemas may not specify max_length, only emptiness is checked then
+	maxLength, err := parameterutil.GetMaxLength(pkField)
+	if err == nil && int64(len(value)) > maxLength {
+		return merr.WrapErrParameterInvalidMsg("primary key at index %d exceeds max length %d, length: %d",
+			idx, maxLength, len(value))
 	}
+	return nil
+}
 
-	return false, nil, 0
+func validatePrimaryKeyValues(schema *schemapb.CollectionSchema, values []*planpb.GenericValue) error {
+	pkField, err := typeutil.GetPrimaryFieldSchema(schema)
+	if err != nil {
+		return err
+	}
+	for idx, value := range values {
+		if err := validatePrimaryKeyValue(pkField, idx, value); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func getPrimaryKeysFromUnaryRangeExpr(schema *schemapb.CollectionSchema, unaryRangeExpr *planpb.Expr_UnaryRangeExpr) (res *schemapb.IDs, err error) {
+func getPrimaryKeysFromUnaryRangeExpr(schema *schemapb.CollectionSchema, unaryRangeExpr *planpb.Expr_UnaryRangeExpr, validatePK bool) (res *schemapb.IDs, err error) {
 	res = &schemapb.IDs{}
+	if validatePK {
+		if err := validatePrimaryKeyValues(schema, []*planpb.GenericValue{unaryRangeExpr.UnaryRangeExpr.GetValue()}); err != nil {
+			return res, err
+		}
+	}
 	switch unaryRangeExpr.UnaryRangeExpr.GetColumnInfo().GetDataType() {
 	case schemapb.DataType_Int64:
 		res.IdField = &schemapb.IDs_IntId{
@@ -578,9 +1825,14 @@ func getPrimaryKeysFromUnaryRangeExpr(schema *schemapb.CollectionSchema, unaryRa
 	return res, nil
 }
 
-func getPrimaryKeysFromTermExpr(schema *schemapb.CollectionSchema, termExpr *planpb.Expr_TermExpr) (res *schemapb.IDs, rowNum int64, err error) {
+func getPrimaryKeysFromTermExpr(schema *schemapb.CollectionSchema, termExpr *planpb.Expr_TermExpr, validatePK bool) (res *schemapb.IDs, rowNum int64, err error) {
 	res = &schemapb.IDs{}
 	rowNum = int64(len(termExpr.TermExpr.Values))
+	if validatePK {
+		if err := validatePrimaryKeyValues(schema, termExpr.TermExpr.GetValues()); err != nil {
+			return res, 0, err
+		}
+	}
 	switch termExpr.TermExpr.ColumnInfo.GetDataType() {
 	case schemapb.DataType_Int64:
 		ids := make([]int64, 0)
@@ -0,0 +1,562 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/internal/mocks"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/proto/planpb"
+	"github.com/milvus-io/milvus/internal/proto/querypb"
+	"github.com/milvus-io/milvus/internal/util/streamrpc"
+	typeutil2 "github.com/milvus-io/milvus/internal/util/typeutil"
+	"github.com/milvus-io/milvus/pkg/common"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+// The property checked here is the contract of the delete path:
+// after a delete finished, a query with the same predicate at a guarantee ts
+// no smaller than the delete ts returns none of the deleted rows, and DeleteCnt
+// equals the number of rows that were visible before the delete.
+
+const (
+	deletePropertyIterations = 100
+	deletePropertyMaxRows    = 40
+	// the cases are generated from deletePropertySeed, or the seed set by deletePropertySeedEnv
+	// to reproduce a failure or explore other cases
+	deletePropertySeed    = 19530
+	deletePropertySeedEnv = "MILVUS_DELETE_PROPERTY_SEED"
+)
+
+type deletePropertyRow struct {
+	intPK       int64
+	strPK       string
+	age         int64
+	channel     string
+	partitionID int64
+}
+
+type deletePropertyCase struct {
+	seed           int64
+	varcharPK      bool
+	partitionKey   bool
+	channels       []string
+	partitionNames []string
+	partitionIDs   []int64
+	// partitionName is set when the delete targets a single partition
+	partitionName string
+	partitionID   int64
+	rows          []deletePropertyRow
+
+	expr   string
+	match  func(row deletePropertyRow) bool
+	simple bool
+}
+
+func (c *deletePropertyCase) schema() *schemapb.CollectionSchema {
+	pkField := &schemapb.FieldSchema{
+		FieldID:      common.StartOfUserFieldID,
+		Name:         "pk",
+		IsPrimaryKey: true,
+		DataType:     schemapb.DataType_Int64,
+	}
+	if c.varcharPK {
+		pkField.DataType = schemapb.DataType_VarChar
+		pkField.TypeParams = []*commonpb.KeyValuePair{{Key: common.MaxLengthKey, Value: "64"}}
+	}
+	return &schemapb.CollectionSchema{
+		Name: "test_delete_property",
+		Fields: []*schemapb.FieldSchema{
+			pkField,
+			{
+				FieldID:        common.StartOfUserFieldID + 1,
+				Name:           "age",
+				DataType:       schemapb.DataType_Int64,
+				IsPartitionKey: c.partitionKey,
+			},
+		},
+	}
+}
+
+func (c *deletePropertyCase) pkKey(row deletePropertyRow) string {
+	if c.varcharPK {
+		return row.strPK
+	}
+	return fmt.Sprint(row.intPK)
+}
+
+func (c *deletePropertyCase) pkLiteral(row deletePropertyRow) string {
+	if c.varcharPK {
+		return fmt.Sprintf("%q", row.strPK)
+	}
+	return fmt.Sprint(row.intPK)
+}
+
+func (c *deletePropertyCase) idsOf(rows []deletePropertyRow) *schemapb.IDs {
+	if c.varcharPK {
+		data := make([]string, 0, len(rows))
+		for _, row := range rows {
+			data = append(data, row.strPK)
+		}
+		return &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: data}}}
+	}
+	data := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		data = append(data, row.intPK)
+	}
+	return &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: data}}}
+}
+
+// visible reports whether the row is deleted by the request.
+func (c *deletePropertyCase) visible(row deletePropertyRow) bool {
+	if c.partitionName != "" && row.partitionID != c.partitionID {
+		return false
+	}
+	return c.match(row)
+}
+
+func (c *deletePropertyCase) String() string {
+	rows := make([]string, 0, len(c.rows))
+	for _, row := range c.rows {
+		rows = append(rows, fmt.Sprintf("{pk: %s, age: %d, channel: %s, partition: %d}",
+			c.pkLiteral(row), row.age, row.channel, row.partitionID))
+	}
+	return fmt.Sprintf("seed: %d, varcharPK: %v, partitionKey: %v, channels: %v, partition: %q, expr: %q, rows: [%s]",
+		c.seed, c.varcharPK, c.partitionKey, c.channels, c.partitionName, c.expr, strings.Join(rows, ", "))
+}
+
+// assignRows routes rows to channels and partitions the same way the insert path does.
+func (c *deletePropertyCase) assignRows(r *rand.Rand) error {
+	channelIdx := typeutil.HashPK2Channels(c.idsOf(c.rows), c.channels)
+	partitionKeyField := c.schema().GetFields()[1]
+	for i := range c.rows {
+		c.rows[i].channel = c.channels[channelIdx[i]]
+		if !c.partitionKey {
+			c.rows[i].partitionID = c.partitionIDs[r.Intn(len(c.partitionIDs))]
+			continue
+		}
+		names, err := typeutil2.HashKey2Partitions(partitionKeyField, []*planpb.GenericValue{
+			{Val: &planpb.GenericValue_Int64Val{Int64Val: c.rows[i].age}},
+		}, c.partitionNames)
+		if err != nil {
+			return err
+		}
+		for j, name := range c.partitionNames {
+			if name == names[0] {
+				c.rows[i].partitionID = c.partitionIDs[j]
+			}
+		}
+	}
+	return nil
+}
+
+func genDeletePropertyCase(seed int64) (*deletePropertyCase, error) {
+	r := rand.New(rand.NewSource(seed))
+	c := &deletePropertyCase{
+		seed:         seed,
+		varcharPK:    r.Intn(2) == 0,
+		partitionKey: r.Intn(2) == 0,
+	}
+
+	for i := 0; i < 1+r.Intn(3); i++ {
+		c.channels = append(c.channels, fmt.Sprintf("by-dev-rootcoord-dml_%d_v%d", i, i))
+	}
+	numPartitions := 1 + r.Intn(4)
+	for i := 0; i < numPartitions; i++ {
+		c.partitionNames = append(c.partitionNames, fmt.Sprintf("_default_%d", i))
+		c.partitionIDs = append(c.partitionIDs, int64(100+i))
+	}
+	if !c.partitionKey && numPartitions > 1 && r.Intn(2) == 0 {
+		target := r.Intn(numPartitions)
+		c.partitionName = c.partitionNames[target]
+		c.partitionID = c.partitionIDs[target]
+	}
+
+	pks := r.Perm(1000)
+	for i := 0; i < 1+r.Intn(deletePropertyMaxRows); i++ {
+		c.rows = append(c.rows, deletePropertyRow{
+			intPK: int64(pks[i]),
+			strPK: fmt.Sprintf("pk_%03d", pks[i]),
+			age:   int64(r.Intn(20)),
+		})
+	}
+	if err := c.assignRows(r); err != nil {
+		return nil, err
+	}
+
+	c.expr, c.match, c.simple = c.genPredicate(r)
+	return c, nil
+}
+
+// genPredicate returns a delete expression together with its reference evaluation.
+// The simple forms only reference pks visible in the target partition,
+// since DeleteCnt of a simple delete is the number of pks in the expression.
+func (c *deletePropertyCase) genPredicate(r *rand.Rand) (string, func(deletePropertyRow) bool, bool) {
+	candidates := make([]deletePropertyRow, 0, len(c.rows))
+	for _, row := range c.rows {
+		if c.partitionName == "" || row.partitionID == c.partitionID {
+			candidates = append(candidates, row)
+		}
+	}
+
+	if len(candidates) > 0 {
+		switch r.Intn(6) {
+		case 0:
+			expr, match := c.genPkIn(r, candidates)
+			return expr, match, true
+		case 1:
+			row := candidates[r.Intn(len(candidates))]
+			key := c.pkKey(row)
+			return fmt.Sprintf("pk == %s", c.pkLiteral(row)), func(other deletePropertyRow) bool {
+				return c.pkKey(other) == key
+			}, true
+		}
+	}
+
+	switch r.Intn(3) {
+	case 0:
+		// without candidates the atom is never a pk term, which would be a simple delete
+		expr, match := c.genAtom(r, nil)
+		return expr, match, false
+	case 1:
+		leftExpr, left := c.genAtom(r, candidates)
+		rightExpr, right := c.genAtom(r, candidates)
+		return fmt.Sprintf("(%s) and (%s)", leftExpr, rightExpr), func(row deletePropertyRow) bool {
+			return left(row) && right(row)
+		}, false
+	default:
+		// or-combinations only use range atoms, so the partition key pruning
+		// never narrows the query to the partitions of a single branch
+		leftExpr, left := c.genRange(r)
+		rightExpr, right := c.genRange(r)
+		return fmt.Sprintf("(%s) or (%s)", leftExpr, rightExpr), func(row deletePropertyRow) bool {
+			return left(row) || right(row)
+		}, false
+	}
+}
+
+func (c *deletePropertyCase) genPkIn(r *rand.Rand, candidates []deletePropertyRow) (string, func(deletePropertyRow) bool) {
+	selected := make(map[string]struct{})
+	literals := make([]string, 0)
+	for _, idx := range r.Perm(len(candidates))[:1+r.Intn(len(candidates))] {
+		selected[c.pkKey(candidates[idx])] = struct{}{}
+		literals = append(literals, c.pkLiteral(candidates[idx]))
+	}
+	return fmt.Sprintf("pk in [%s]", strings.Join(literals, ", ")), func(row deletePropertyRow) bool {
+		_, ok := selected[c.pkKey(row)]
+		return ok
+	}
+}
+
+func (c *deletePropertyCase) genRange(r *rand.Rand) (string, func(deletePropertyRow) bool) {
+	if r.Intn(2) == 0 {
+		bound := int64(r.Intn(20))
+		return fmt.Sprintf("age < %d", bound), func(row deletePropertyRow) bool {
+			return row.age < bound
+		}
+	}
+	bound := r.Intn(1000)
+	if c.varcharPK {
+		strBound := fmt.Sprintf("pk_%03d", bound)
+		return fmt.Sprintf("pk > %q", strBound), func(row deletePropertyRow) bool {
+			return row.strPK > strBound
+		}
+	}
+	return fmt.Sprintf("pk > %d", bound), func(row deletePropertyRow) bool {
+		return row.intPK > int64(bound)
+	}
+}
+
+func (c *deletePropertyCase) genAtom(r *rand.Rand, candidates []deletePropertyRow) (string, func(deletePropertyRow) bool) {
+	switch r.Intn(3) {
+	case 0:
+		ages := make(map[int64]struct{})
+		literals := make([]string, 0)
+		for i := 0; i < 1+r.Intn(3); i++ {
+			age := int64(r.Intn(20))
+			if _, ok := ages[age]; !ok {
+				ages[age] = struct{}{}
+				literals = append(literals, fmt.Sprint(age))
+			}
+		}
+		return fmt.Sprintf("age in [%s]", strings.Join(literals, ", ")), func(row deletePropertyRow) bool {
+			_, ok := ages[row.age]
+			return ok
+		}
+	case 1:
+		if len(candidates) > 0 {
+			return c.genPkIn(r, candidates)
+		}
+	}
+	return c.genRange(r)
+}
+
+// deletePropertyCluster is the mock cluster state, rows are visible until a delete msg reaches their channel.
+type deletePropertyCluster struct {
+	mu          sync.Mutex
+	c           *deletePropertyCase
+	live        map[string]deletePropertyRow
+	maxQueryTs  uint64
+	minDeleteTs uint64
+	misrouted   []string
+}
+
+func newDeletePropertyCluster(c *deletePropertyCase) *deletePropertyCluster {
+	cluster := &deletePropertyCluster{
+		c:    c,
+		live: make(map[string]deletePropertyRow),
+	}
+	for _, row := range c.rows {
+		cluster.live[c.pkKey(row)] = row
+	}
+	return cluster
+}
+
+func (cluster *deletePropertyCluster) produce(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	for _, msg := range pack.Msgs {
+		deleteMsg, ok := msg.(*msgstream.DeleteMsg)
+		if !ok {
+			return nil, errors.Newf("unexpected msg type %s", msg.Type().String())
+		}
+		for i := 0; i < int(deleteMsg.GetNumRows()); i++ {
+			if cluster.minDeleteTs == 0 || deleteMsg.GetTimestamps()[i] < cluster.minDeleteTs {
+				cluster.minDeleteTs = deleteMsg.GetTimestamps()[i]
+			}
+			key := fmt.Sprint(typeutil.GetPK(deleteMsg.GetPrimaryKeys(), int64(i)))
+			row, ok := cluster.live[key]
+			if !ok {
+				continue
+			}
+			if row.channel != deleteMsg.GetShardName() {
+				cluster.misrouted = append(cluster.misrouted, fmt.Sprintf("pk %s to %s", key, deleteMsg.GetShardName()))
+				continue
+			}
+			if deleteMsg.GetPartitionID() != common.InvalidPartitionID && deleteMsg.GetPartitionID() != row.partitionID {
+				continue
+			}
+			delete(cluster.live, key)
+		}
+	}
+	return nil, nil
+}
+
+// query serves a retrieve request with the reference predicate, in random sized batches.
+func (cluster *deletePropertyCluster) query(ctx context.Context, in *querypb.QueryRequest, batchSize int) querypb.QueryNode_QueryStreamClient {
+	cluster.mu.Lock()
+	if in.GetReq().GetMvccTimestamp() > cluster.maxQueryTs {
+		cluster.maxQueryTs = in.GetReq().GetMvccTimestamp()
+	}
+	partitions := typeutil.NewSet(in.GetReq().GetPartitionIDs()...)
+	channels := typeutil.NewSet(in.GetDmlChannels()...)
+	matched := make([]deletePropertyRow, 0)
+	for _, row := range cluster.live {
+		if !channels.Contain(row.channel) {
+			continue
+		}
+		if partitions.Len() > 0 && !partitions.Contain(row.partitionID) {
+			continue
+		}
+		if cluster.c.match(row) {
+			matched = append(matched, row)
+		}
+	}
+	cluster.mu.Unlock()
+
+	client := streamrpc.NewLocalQueryClient(ctx)
+	server := client.CreateServer()
+	go func() {
+		for len(matched) > 0 {
+			n := batchSize
+			if n > len(matched) {
+				n = len(matched)
+			}
+			server.Send(&internalpb.RetrieveResults{
+				Status: merr.Success(),
+				Ids:    cluster.c.idsOf(matched[:n]),
+			})
+			matched = matched[n:]
+		}
+		server.FinishSend(nil)
+	}()
+	return client
+}
+
+func runDeletePropertyCase(t *testing.T, c *deletePropertyCase) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dbName := "test_db"
+	collectionName := "test_delete_property"
+	collectionID := int64(1)
+	tsoAllocator := &mockTsoAllocator{}
+
+	queue, err := newTaskScheduler(ctx, tsoAllocator, nil)
+	if err != nil {
+		return err
+	}
+	queue.Start()
+	defer queue.Close()
+
+	partitionsMap := make(map[string]int64)
+	for i, name := range c.partitionNames {
+		partitionsMap[name] = c.partitionIDs[i]
+	}
+	schema := newSchemaInfo(c.schema())
+
+	mockCache := NewMockCache(t)
+	mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil).Maybe()
+	mockCache.EXPECT().GetCollectionSchema(mock.Anything, dbName, collectionName).Return(schema, nil).Maybe()
+	mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true).Maybe()
+	mockCache.EXPECT().GetPartitionID(mock.Anything, dbName, collectionName, c.partitionName).Return(c.partitionID, nil).Maybe()
+	mockCache.EXPECT().GetPartitions(mock.Anything, dbName, collectionName).Return(partitionsMap, nil).Maybe()
+	mockCache.EXPECT().GetPartitionsIndex(mock.Anything, dbName, collectionName).Return(c.partitionNames, nil).Maybe()
+	oldCache := globalMetaCache
+	globalMetaCache = mockCache
+	defer func() { globalMetaCache = oldCache }()
+
+	cluster := newDeletePropertyCluster(c)
+	batchSize := 1 + rand.New(rand.NewSource(c.seed)).Intn(4)
+
+	stream := msgstream.NewMockMsgStream(t)
+	stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(cluster.produce).Maybe()
+	chMgr := NewMockChannelsMgr(t)
+	chMgr.EXPECT().getVChannels(collectionID).Return(c.channels, nil).Maybe()
+	chMgr.EXPECT().getChannels(collectionID).Return(c.channels, nil).Maybe()
+	chMgr.EXPECT().getOrCreateDmlStream(collectionID).Return(stream, nil).Maybe()
+
+	qn := mocks.NewMockQueryNodeClient(t)
+	qn.EXPECT().QueryStream(mock.Anything, mock.Anything).RunAndReturn(
+		func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) (querypb.QueryNode_QueryStreamClient, error) {
+			return cluster.query(ctx, in, batchSize), nil
+		}).Maybe()
+	lb := NewMockLBPolicy(t)
+	lb.EXPECT().Execute(mock.Anything, mock.Anything).RunAndReturn(
+		func(ctx context.Context, workload CollectionWorkLoad) error {
+			for _, channel := range c.channels {
+				if err := workload.exec(ctx, 1, qn, channel); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Maybe()
+
+	visible := 0
+	for _, row := range c.rows {
+		if c.visible(row) {
+			visible++
+		}
+	}
+
+	dr := deleteRunner{
+		req: &milvuspb.DeleteRequest{
+			DbName:         dbName,
+			CollectionName: collectionName,
+			PartitionName:  c.partitionName,
+			Expr:           c.expr,
+		},
+		idAllocator:     &mockIDAllocatorInterface{},
+		tsoAllocatorIns: tsoAllocator,
+		chMgr:           chMgr,
+		queue:           queue.dmQueue,
+		lb:              lb,
+	}
+	if err := dr.Init(ctx); err != nil {
+		return errors.Wrap(err, "init delete runner failed")
+	}
+	if err := dr.Run(ctx); err != nil {
+		return errors.Wrap(err, "run delete runner failed")
+	}
+
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if len(cluster.misrouted) > 0 {
+		return errors.Newf("delete of pks routed to wrong channel: %v", cluster.misrouted)
+	}
+	if !c.simple && cluster.minDeleteTs != 0 && cluster.minDeleteTs <= cluster.maxQueryTs {
+		return errors.Newf("delete ts %d is not after the query ts %d", cluster.minDeleteTs, cluster.maxQueryTs)
+	}
+	var stillVisible, wronglyDeleted []string
+	for _, row := range c.rows {
+		_, ok := cluster.live[c.pkKey(row)]
+		if c.visible(row) && ok {
+			stillVisible = append(stillVisible, c.pkLiteral(row))
+		}
+		if !c.visible(row) && !ok {
+			wronglyDeleted = append(wronglyDeleted, c.pkLiteral(row))
+		}
+	}
+	sort.Strings(stillVisible)
+	sort.Strings(wronglyDeleted)
+	if len(stillVisible) > 0 {
+		return errors.Newf("rows still visible after delete: %v", stillVisible)
+	}
+	if len(wronglyDeleted) > 0 {
+		return errors.Newf("rows not matched by the expr are deleted: %v", wronglyDeleted)
+	}
+	if dr.result.GetDeleteCnt() != int64(visible) {
+		return errors.Newf("delete count %d not equal to visible rows %d", dr.result.GetDeleteCnt(), visible)
+	}
+	return nil
+}
+
+// shrinkDeletePropertyCase drops rows from a failing case as long as it keeps failing.
+// Rows referenced by a simple expr are pinned, removing them changes what the expr means.
+func shrinkDeletePropertyCase(t *testing.T, c *deletePropertyCase, failure error) (*deletePropertyCase, error) {
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for i := range c.rows {
+			if c.simple && c.visible(c.rows[i]) {
+				continue
+			}
+			candidate := *c
+			candidate.rows = append(append([]deletePropertyRow{}, c.rows[:i]...), c.rows[i+1:]...)
+			if err := runDeletePropertyCase(t, &candidate); err != nil {
+				c, failure, shrunk = &candidate, err, true
+				break
+			}
+		}
+	}
+	return c, failure
+}
+
+func TestDeleteRunner_Property(t *testing.T) {
+	baseSeed := int64(deletePropertySeed)
+	if value, ok := os.LookupEnv(deletePropertySeedEnv); ok {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid %s %q: %v", deletePropertySeedEnv, value, err)
+		}
+		baseSeed = seed
+	}
+	t.Logf("base seed: %d, set %s to reproduce", baseSeed, deletePropertySeedEnv)
+	for i := 0; i < deletePropertyIterations; i++ {
+		seed := baseSeed + int64(i)
+		c, err := genDeletePropertyCase(seed)
+		if err != nil {
+			t.Fatalf("generate case with seed %d failed: %v", seed, err)
+		}
+		if err := runDeletePropertyCase(t, c); err != nil {
+			minimal, failure := shrinkDeletePropertyCase(t, c, err)
+			t.Fatalf("delete property violated: %v\nminimal case: %s", failure, minimal)
+		}
+	}
+}
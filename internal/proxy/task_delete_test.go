@@ -2,15 +2,38 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
 	"github.com/milvus-io/milvus/internal/allocator"
 	"github.com/milvus-io/milvus/internal/mocks"
@@ -19,11 +42,21 @@ import (
 	"github.com/milvus-io/milvus/internal/proto/planpb"
 	"github.com/milvus-io/milvus/internal/proto/querypb"
 	"github.com/milvus-io/milvus/internal/proto/rootcoordpb"
+	"github.com/milvus-io/milvus/internal/proxy/accesslog"
 	"github.com/milvus-io/milvus/internal/util/streamrpc"
 	"github.com/milvus-io/milvus/pkg/common"
+	"github.com/milvus-io/milvus/pkg/config"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/mq/msgstream/mqwrapper/nmq"
+	"github.com/milvus-io/milvus/pkg/util/commonpbutil"
+	"github.com/milvus-io/milvus/pkg/util/funcutil"
 	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/retry"
+	"github.com/milvus-io/milvus/pkg/util/timerecord"
+	"github.com/milvus-io/milvus/pkg/util/tsoutil"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 func Test_getPrimaryKeysFromPlan(t *testing.T) {
@@ -50,7 +83,8 @@ func Test_getPrimaryKeysFromPlan(t *testing.T) {
 		expr := "pk < 4"
 		plan, err := planparserv2.CreateRetrievePlan(schema, expr)
 		assert.NoError(t, err)
-		isSimple, _, _ := getPrimaryKeysFromPlan(schema, plan)
+		isSimple, _, _, err := getPrimaryKeysFromPlan(schema, plan, true)
+		assert.NoError(t, err)
 		assert.False(t, isSimple)
 	})
 
@@ -58,7 +92,8 @@ func Test_getPrimaryKeysFromPlan(t *testing.T) {
 		expr := "non_pk == 1"
 		plan, err := planparserv2.CreateRetrievePlan(schema, expr)
 		assert.NoError(t, err)
-		isSimple, _, _ := getPrimaryKeysFromPlan(schema, plan)
+		isSimple, _, _, err := getPrimaryKeysFromPlan(schema, plan, true)
+		assert.NoError(t, err)
 		assert.False(t, isSimple)
 	})
 
@@ -66,7 +101,8 @@ func Test_getPrimaryKeysFromPlan(t *testing.T) {
 		expr := "pk in [1, 2, 3]"
 		plan, err := planparserv2.CreateRetrievePlan(schema, expr)
 		assert.NoError(t, err)
-		isSimple, _, rowNum := getPrimaryKeysFromPlan(schema, plan)
+		isSimple, _, rowNum, err := getPrimaryKeysFromPlan(schema, plan, true)
+		assert.NoError(t, err)
 		assert.True(t, isSimple)
 		assert.Equal(t, int64(3), rowNum)
 	})
@@ -78,7 +114,8 @@ func Test_getPrimaryKeysFromPlan(t *testing.T) {
 		termExpr := plan.Node.(*planpb.PlanNode_Query).Query.Predicates.Expr.(*planpb.Expr_TermExpr)
 		termExpr.TermExpr.ColumnInfo.DataType = -1
 
-		isSimple, _, _ := getPrimaryKeysFromPlan(schema, plan)
+		isSimple, _, _, err := getPrimaryKeysFromPlan(schema, plan, true)
+		assert.NoError(t, err)
 		assert.False(t, isSimple)
 	})
 
@@ -86,7 +123,8 @@ func Test_getPrimaryKeysFromPlan(t *testing.T) {
 		expr := "pk == 1"
 		plan, err := planparserv2.CreateRetrievePlan(schema, expr)
 		assert.NoError(t, err)
-		isSimple, _, rowNum := getPrimaryKeysFromPlan(schema, plan)
+		isSimple, _, rowNum, err := getPrimaryKeysFromPlan(schema, plan, true)
+		assert.NoError(t, err)
 		assert.True(t, isSimple)
 		assert.Equal(t, int64(1), rowNum)
 	})
@@ -98,11 +136,354 @@ func Test_getPrimaryKeysFromPlan(t *testing.T) {
 		unaryRangeExpr := plan.Node.(*planpb.PlanNode_Query).Query.Predicates.Expr.(*planpb.Expr_UnaryRangeExpr)
 		unaryRangeExpr.UnaryRangeExpr.ColumnInfo.DataType = -1
 
-		isSimple, _, _ := getPrimaryKeysFromPlan(schema, plan)
+		isSimple, _, _, err := getPrimaryKeysFromPlan(schema, plan, true)
+		assert.NoError(t, err)
 		assert.False(t, isSimple)
 	})
 }
 
+func Test_getPrimaryKeysFromPlanValidation(t *testing.T) {
+	schema := &schemapb.CollectionSchema{
+		Name: "test_delete",
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_VarChar,
+				TypeParams: []*commonpb.KeyValuePair{
+					{Key: common.MaxLengthKey, Value: "4"},
+				},
+			},
+		},
+	}
+
+	t.Run("valid varchar pks", func(t *testing.T) {
+		plan, err := planparserv2.CreateRetrievePlan(schema, `pk in ["a", "abcd"]`)
+		assert.NoError(t, err)
+		isSimple, _, rowNum, err := getPrimaryKeysFromPlan(schema, plan, true)
+		assert.NoError(t, err)
+		assert.True(t, isSimple)
+		assert.Equal(t, int64(2), rowNum)
+	})
+
+	t.Run("varchar pk exceeds max length", func(t *testing.T) {
+		plan, err := planparserv2.CreateRetrievePlan(schema, `pk in ["a", "abcde"]`)
+		assert.NoError(t, err)
+		_, _, _, err = getPrimaryKeysFromPlan(schema, plan, true)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "index 1")
+	})
+
+	t.Run("empty varchar pk", func(t *testing.T) {
+		plan, err := planparserv2.CreateRetrievePlan(schema, `pk == ""`)
+		assert.NoError(t, err)
+		_, _, _, err = getPrimaryKeysFromPlan(schema, plan, true)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "index 0")
+	})
+
+	t.Run("skip validation", func(t *testing.T) {
+		plan, err := planparserv2.CreateRetrievePlan(schema, `pk in ["a", "abcde"]`)
+		assert.NoError(t, err)
+		isSimple, _, rowNum, err := getPrimaryKeysFromPlan(schema, plan, false)
+		assert.NoError(t, err)
+		assert.True(t, isSimple)
+		assert.Equal(t, int64(2), rowNum)
+	})
+
+	t.Run("int64 pk type mismatch", func(t *testing.T) {
+		intSchema := &schemapb.CollectionSchema{
+			Name: "test_delete",
+			Fields: []*schemapb.FieldSchema{
+				{
+					FieldID:      common.StartOfUserFieldID,
+					Name:         "pk",
+					IsPrimaryKey: true,
+					DataType:     schemapb.DataType_Int64,
+				},
+			},
+		}
+		plan, err := planparserv2.CreateRetrievePlan(intSchema, "pk in [1, 2]")
+		assert.NoError(t, err)
+		termExpr := plan.Node.(*planpb.PlanNode_Query).Query.Predicates.Expr.(*planpb.Expr_TermExpr)
+		termExpr.TermExpr.Values[1] = &planpb.GenericValue{Val: &planpb.GenericValue_FloatVal{FloatVal: 1e30}}
+		_, _, _, err = getPrimaryKeysFromPlan(intSchema, plan, true)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "index 1")
+	})
+}
+
+func Test_getDeleteIDs(t *testing.T) {
+	schema := &schemapb.CollectionSchema{
+		Name: "test_delete",
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_VarChar,
+				TypeParams: []*commonpb.KeyValuePair{
+					{Key: common.MaxLengthKey, Value: "4"},
+				},
+			},
+		},
+	}
+
+	t.Run("without ids", func(t *testing.T) {
+		_, ok, err := getDeleteIDs(&milvuspb.DeleteRequest{Expr: "pk in [1]"})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		req := &milvuspb.DeleteRequest{}
+		assert.NoError(t, setDeleteIDs(req, &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b"}}},
+		}))
+		assert.Equal(t, DeleteIDsExpr, req.GetExpr())
+
+		ids, ok, err := getDeleteIDs(req)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		numRow, err := validateDeleteIDs(schema, ids, true)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), numRow)
+	})
+
+	t.Run("expr is not the placeholder", func(t *testing.T) {
+		req := &milvuspb.DeleteRequest{}
+		assert.NoError(t, setDeleteIDs(req, &schemapb.IDs{}))
+		req.Expr = "pk in [1]"
+		_, _, err := getDeleteIDs(req)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+	})
+
+	t.Run("malformed ids", func(t *testing.T) {
+		req := &milvuspb.DeleteRequest{
+			Base: &commonpb.MsgBase{Properties: map[string]string{DeleteIDsKey: "???"}},
+			Expr: DeleteIDsExpr,
+		}
+		_, _, err := getDeleteIDs(req)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+	})
+
+	t.Run("validate varchar ids", func(t *testing.T) {
+		ids := &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "abcde"}}},
+		}
+		_, err := validateDeleteIDs(schema, ids, true)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "index 1")
+
+		numRow, err := validateDeleteIDs(schema, ids, false)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), numRow)
+
+		_, err = validateDeleteIDs(schema, &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1}}},
+		}, true)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+	})
+}
+
+func BenchmarkDeletePrimaryKeys(b *testing.B) {
+	schema := &schemapb.CollectionSchema{
+		Name: "test_delete",
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_Int64,
+			},
+		},
+	}
+	numRows := 100000
+	pks := make([]int64, 0, numRows)
+	literals := make([]string, 0, numRows)
+	for i := 0; i < numRows; i++ {
+		pks = append(pks, int64(i))
+		literals = append(literals, fmt.Sprint(i))
+	}
+
+	b.Run("expr", func(b *testing.B) {
+		// delete plan cache is not used here, since ids differ between delete requests in practice
+		expr := fmt.Sprintf("pk in [%s]", strings.Join(literals, ","))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			plan, err := planparserv2.CreateRetrievePlan(schema, expr)
+			if err != nil {
+				b.Fatal(err)
+			}
+			isSimple, _, _, err := getPrimaryKeysFromPlan(schema, plan, true)
+			if err != nil || !isSimple {
+				b.Fatal("not simple delete", err)
+			}
+		}
+	})
+
+	b.Run("ids", func(b *testing.B) {
+		req := &milvuspb.DeleteRequest{}
+		err := setDeleteIDs(req, &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: pks}},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ids, _, err := getDeleteIDs(req)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := validateDeleteIDs(schema, ids, true); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func Test_getDeleteOption(t *testing.T) {
+	req := &milvuspb.DeleteRequest{}
+	assert.False(t, getDeleteBoolOption(req, DeleteSkipPKValidationKey))
+
+	req.Base = &commonpb.MsgBase{Properties: map[string]string{DeleteSkipPKValidationKey: "invalid"}}
+	assert.False(t, getDeleteBoolOption(req, DeleteSkipPKValidationKey))
+
+	req.Base.Properties[DeleteSkipPKValidationKey] = "true"
+	assert.True(t, getDeleteBoolOption(req, DeleteSkipPKValidationKey))
+	value, ok := getDeleteOption(req, DeleteSkipPKValidationKey)
+	assert.True(t, ok)
+	assert.Equal(t, "true", value)
+}
+
+func Test_getDeleteScope(t *testing.T) {
+	newReq := func(scope string) *milvuspb.DeleteRequest {
+		return &milvuspb.DeleteRequest{
+			Base: &commonpb.MsgBase{Properties: map[string]string{DeleteScopeKey: scope}},
+		}
+	}
+
+	scope, err := getDeleteScope(&milvuspb.DeleteRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, querypb.DataScope_All, scope)
+
+	scope, err = getDeleteScope(newReq(DeleteScopeAll))
+	assert.NoError(t, err)
+	assert.Equal(t, querypb.DataScope_All, scope)
+
+	scope, err = getDeleteScope(newReq(DeleteScopeHistorical))
+	assert.NoError(t, err)
+	assert.Equal(t, querypb.DataScope_Historical, scope)
+
+	scope, err = getDeleteScope(newReq(DeleteScopeStreaming))
+	assert.NoError(t, err)
+	assert.Equal(t, querypb.DataScope_Streaming, scope)
+
+	_, err = getDeleteScope(newReq("growing"))
+	assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+}
+
+func Test_getDeleteGuaranteeTs(t *testing.T) {
+	ts, err := getDeleteGuaranteeTs(&milvuspb.DeleteRequest{})
+	assert.NoError(t, err)
+	assert.Zero(t, ts)
+
+	req := &milvuspb.DeleteRequest{
+		Base: &commonpb.MsgBase{Properties: map[string]string{DeleteGuaranteeTsKey: "449012345678901248"}},
+	}
+	ts, err = getDeleteGuaranteeTs(req)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 449012345678901248, ts)
+
+	req.Base.Properties[DeleteGuaranteeTsKey] = "-1"
+	_, err = getDeleteGuaranteeTs(req)
+	assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+}
+
+func Test_getDeleteExpr(t *testing.T) {
+	expr, err := getDeleteExpr(&milvuspb.DeleteRequest{Expr: "pk in [1, 2]"})
+	assert.NoError(t, err)
+	assert.Equal(t, "pk in [1, 2]", expr)
+
+	req := &milvuspb.DeleteRequest{
+		Base: &commonpb.MsgBase{Properties: map[string]string{DeleteExprTemplateValuesKey: `{"pks": ["a", "b\" or pk != \"c"]}`}},
+		Expr: "pk in {pks}",
+	}
+	expr, err = getDeleteExpr(req)
+	assert.NoError(t, err)
+	assert.Equal(t, `pk in ["a", "b\" or pk != \"c"]`, expr)
+
+	req.Base.Properties[DeleteExprTemplateValuesKey] = `{"pks": [1], "extra": 1}`
+	_, err = getDeleteExpr(req)
+	assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+
+	req.Base.Properties[DeleteExprTemplateValuesKey] = `{}`
+	_, err = getDeleteExpr(req)
+	assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+
+	req.Base.Properties[DeleteExprTemplateValuesKey] = `[1, 2]`
+	_, err = getDeleteExpr(req)
+	assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+}
+
+func TestDeleteRunner_parseGuaranteeTs(t *testing.T) {
+	paramtable.Init()
+	now := tsoutil.ComposeTSByTime(time.Now(), 0)
+
+	t.Run("derived from consistency level", func(t *testing.T) {
+		dr := &deleteRunner{
+			req: &milvuspb.DeleteRequest{ConsistencyLevel: commonpb.ConsistencyLevel_Strong},
+			ts:  now,
+		}
+		ts, err := dr.parseGuaranteeTs()
+		assert.NoError(t, err)
+		assert.Equal(t, now, ts)
+	})
+
+	t.Run("explicit ts overrides consistency level", func(t *testing.T) {
+		userTs := tsoutil.AddPhysicalDurationOnTs(now, -time.Second)
+		dr := &deleteRunner{
+			req:             &milvuspb.DeleteRequest{ConsistencyLevel: commonpb.ConsistencyLevel_Strong},
+			ts:              now,
+			userGuaranteeTs: userTs,
+		}
+		ts, err := dr.parseGuaranteeTs()
+		assert.NoError(t, err)
+		assert.Equal(t, userTs, ts)
+
+		dr.req.ConsistencyLevel = commonpb.ConsistencyLevel_Eventually
+		ts, err = dr.parseGuaranteeTs()
+		assert.NoError(t, err)
+		assert.Equal(t, userTs, ts)
+	})
+
+	t.Run("explicit ts within tolerance", func(t *testing.T) {
+		userTs := tsoutil.AddPhysicalDurationOnTs(now, time.Second)
+		dr := &deleteRunner{
+			req:             &milvuspb.DeleteRequest{},
+			ts:              now,
+			userGuaranteeTs: userTs,
+		}
+		ts, err := dr.parseGuaranteeTs()
+		assert.NoError(t, err)
+		assert.Equal(t, userTs, ts)
+	})
+
+	t.Run("explicit ts too far in the future", func(t *testing.T) {
+		dr := &deleteRunner{
+			req:             &milvuspb.DeleteRequest{},
+			ts:              now,
+			userGuaranteeTs: tsoutil.AddPhysicalDurationOnTs(now, time.Hour),
+		}
+		_, err := dr.parseGuaranteeTs()
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+	})
+}
+
 func TestDeleteTask_GetChannels(t *testing.T) {
 	collectionID := UniqueID(0)
 	collectionName := "col-0"
@@ -221,95 +602,420 @@ func TestDeleteTask_Execute(t *testing.T) {
 		}
 		stream := msgstream.NewMockMsgStream(t)
 		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
-		stream.EXPECT().Produce(mock.Anything).Return(errors.New("mock error"))
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, errors.New("mock error"))
 		assert.Error(t, dt.Execute(context.Background()))
 	})
-}
 
-func TestDeleteRunner_Init(t *testing.T) {
-	collectionName := "test_delete"
-	collectionID := int64(111)
-	partitionName := "default"
-	partitionID := int64(222)
-	// channels := []string{"test_channel"}
-	dbName := "test_1"
+	t.Run("only channels of primary keys receive msgs", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-	collSchema := &schemapb.CollectionSchema{
-		Name:        collectionName,
-		Description: "",
-		AutoID:      false,
-		Fields: []*schemapb.FieldSchema{
-			{
-				FieldID:      common.StartOfUserFieldID,
-				Name:         "pk",
-				IsPrimaryKey: true,
-				DataType:     schemapb.DataType_Int64,
-			},
-			{
-				FieldID:      common.StartOfUserFieldID + 1,
-				Name:         "non_pk",
-				IsPrimaryKey: false,
-				DataType:     schemapb.DataType_Int64,
-			},
-		},
-	}
-	schema := newSchemaInfo(collSchema)
+		mockMgr := NewMockChannelsMgr(t)
+		rc := mocks.NewMockRootCoordClient(t)
+		rc.EXPECT().AllocID(mock.Anything, mock.Anything).Return(
+			&rootcoordpb.AllocIDResponse{
+				Status: merr.Success(),
+				ID:     0,
+				Count:  1,
+			}, nil)
+		allocator, err := allocator.NewIDAllocator(ctx, rc, paramtable.GetNodeID())
+		allocator.Start()
+		assert.NoError(t, err)
 
-	t.Run("empty collection name", func(t *testing.T) {
-		dr := deleteRunner{}
-		assert.Error(t, dr.Init(context.Background()))
-	})
+		// primary keys inserted into the same channel, as rows retrieved from a channel in complex delete
+		vChannels := []string{"test_channel_0", "test_channel_1", "test_channel_2", "test_channel_3"}
+		var pks []int64
+		for i := int64(0); len(pks) < 10; i++ {
+			if typeutil.HashPK2Channels(int64IDs(i), vChannels)[0] == 2 {
+				pks = append(pks, i)
+			}
+		}
 
-	t.Run("fail to get collection id", func(t *testing.T) {
-		dr := deleteRunner{
+		dt := deleteTask{
+			chMgr:        mockMgr,
+			collectionID: collectionID,
+			partitionID:  partitionID,
+			vChannels:    vChannels,
+			idAllocator:  allocator,
 			req: &milvuspb.DeleteRequest{
 				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "non_pk > 10",
 			},
+			primaryKeys: int64IDs(pks...),
 		}
-		cache := NewMockCache(t)
-		cache.On("GetCollectionID",
-			mock.Anything, // context.Context
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-		).Return(int64(0), errors.New("mock GetCollectionID err"))
-		globalMetaCache = cache
-		assert.Error(t, dr.Init(context.Background()))
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			// no msgs for the unrelated channels
+			assert.Len(t, pack.Msgs, 1)
+			msg := pack.Msgs[0].(*msgstream.DeleteMsg)
+			assert.Equal(t, "test_channel_2", msg.GetShardName())
+			assert.ElementsMatch(t, pks, msg.GetPrimaryKeys().GetIntId().GetData())
+			return nil, nil
+		})
+		assert.NoError(t, dt.Execute(context.Background()))
+		assert.Equal(t, int64(len(pks)), dt.count)
 	})
 
-	t.Run("fail get collection schema", func(t *testing.T) {
-		dr := deleteRunner{req: &milvuspb.DeleteRequest{
-			CollectionName: collectionName,
-			DbName:         dbName,
-		}}
-		cache := NewMockCache(t)
-		cache.On("GetCollectionID",
-			mock.Anything, // context.Context
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-		).Return(collectionID, nil)
-		cache.On("GetCollectionSchema",
-			mock.Anything, // context.Context
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-		).Return(nil, errors.New("mock GetCollectionSchema err"))
+	t.Run("end positions of msgs produced", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
-		globalMetaCache = cache
-		assert.Error(t, dr.Init(context.Background()))
-	})
+		mockMgr := NewMockChannelsMgr(t)
+		rc := mocks.NewMockRootCoordClient(t)
+		rc.EXPECT().AllocID(mock.Anything, mock.Anything).Return(
+			&rootcoordpb.AllocIDResponse{
+				Status: merr.Success(),
+				ID:     0,
+				Count:  1,
+			}, nil)
+		idAllocator, err := allocator.NewIDAllocator(ctx, rc, paramtable.GetNodeID())
+		assert.NoError(t, err)
+		idAllocator.Start()
 
-	t.Run("partition key mode but delete with partition name", func(t *testing.T) {
-		dr := deleteRunner{req: &milvuspb.DeleteRequest{
-			CollectionName: collectionName,
-			DbName:         dbName,
-			PartitionName:  partitionName,
-		}}
-		cache := NewMockCache(t)
-		cache.On("GetCollectionID",
-			mock.Anything, // context.Context
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-		).Return(collectionID, nil)
-		cache.On("GetCollectionSchema",
+		vChannels := []string{"dml_0_100v0", "dml_1_100v1"}
+		dt := deleteTask{
+			chMgr:        mockMgr,
+			collectionID: collectionID,
+			partitionID:  partitionID,
+			vChannels:    vChannels,
+			idAllocator:  idAllocator,
+			ts:           100,
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "non_pk > 10",
+			},
+			primaryKeys: int64IDs(0, 1, 2, 3, 4, 5, 6, 7, 8, 9),
+		}
+		msgIDs := map[string][]msgstream.MessageID{
+			"dml_0": {nmq.NewNmqID(5), nmq.NewNmqID(6)},
+			"dml_1": {nmq.NewNmqID(9)},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			assert.Len(t, pack.Msgs, 2)
+			return msgIDs, nil
+		})
+		assert.NoError(t, dt.Execute(context.Background()))
+		assert.Equal(t, msgIDs, dt.msgIDs)
+		assert.Equal(t, map[string]deletePosition{
+			"dml_0_100v0": {msgID: nmq.NewNmqID(6), ts: 100},
+			"dml_1_100v1": {msgID: nmq.NewNmqID(9), ts: 100},
+		}, dt.endPositions)
+	})
+}
+
+func TestDeleteRunner_mergeEndPositions(t *testing.T) {
+	dr := &deleteRunner{}
+	assert.Empty(t, dr.getEndPositions())
+
+	dr.mergeEndPositions(map[string]deletePosition{"v0": {msgID: nmq.NewNmqID(5), ts: 1}})
+	dr.mergeEndPositions(map[string]deletePosition{
+		"v0": {msgID: nmq.NewNmqID(3), ts: 2},
+		"v1": {msgID: nmq.NewNmqID(7), ts: 2},
+	})
+	dr.mergeEndPositions(map[string]deletePosition{"v0": {msgID: nmq.NewNmqID(8), ts: 3}})
+	assert.Equal(t, []*msgpb.MsgPosition{
+		{ChannelName: "v0", MsgID: nmq.NewNmqID(8).Serialize(), Timestamp: 3},
+		{ChannelName: "v1", MsgID: nmq.NewNmqID(7).Serialize(), Timestamp: 2},
+	}, dr.getEndPositions())
+}
+
+func TestDeleteRunner_waitTask(t *testing.T) {
+	t.Run("bounded by deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		dr := &deleteRunner{}
+		task := &deleteTask{Condition: NewTaskCondition(context.Background())}
+		err := dr.waitTask(ctx, task)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.GreaterOrEqual(t, dr.waitSpan.Load(), 40*time.Millisecond)
+	})
+
+	t.Run("finished before deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		dr := &deleteRunner{}
+		task := &deleteTask{
+			Condition:   NewTaskCondition(context.Background()),
+			produceSpan: time.Millisecond,
+			endPositions: map[string]deletePosition{
+				"v0": {msgID: nmq.NewNmqID(1), ts: 1},
+			},
+		}
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			task.Notify(nil)
+		}()
+		assert.NoError(t, dr.waitTask(ctx, task))
+		assert.Equal(t, time.Millisecond, dr.produceSpan.Load())
+		assert.Len(t, dr.getEndPositions(), 1)
+	})
+}
+
+func TestDeleteTask_ProduceWithRetry(t *testing.T) {
+	paramtable.Init()
+	paramtable.Get().Save(Params.ProxyCfg.DeleteProduceRetryInterval.Key, "1")
+	defer paramtable.Get().Reset(Params.ProxyCfg.DeleteProduceRetryInterval.Key)
+
+	counter := metrics.ProxyDeleteProduceRetryCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10))
+	transientErr := errors.New("write tcp: connection reset by peer")
+	newTask := func() *deleteTask {
+		return &deleteTask{tr: timerecord.NewTimeRecorder("test delete")}
+	}
+
+	t.Run("succeed after transient failures", func(t *testing.T) {
+		stream := msgstream.NewMockMsgStream(t)
+		msgIDs := map[string][]msgstream.MessageID{"pchan": {nmq.NewNmqID(1)}}
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, transientErr).Times(2)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(msgIDs, nil).Once()
+
+		before := testutil.ToFloat64(counter)
+		ids, err := newTask().produceWithRetry(context.Background(), stream, &msgstream.MsgPack{})
+		assert.NoError(t, err)
+		assert.Equal(t, msgIDs, ids)
+		assert.Equal(t, before+2, testutil.ToFloat64(counter))
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		paramtable.Get().Save(Params.ProxyCfg.DeleteProduceMaxRetries.Key, "2")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteProduceMaxRetries.Key)
+
+		stream := msgstream.NewMockMsgStream(t)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, transientErr).Times(3)
+
+		_, err := newTask().produceWithRetry(context.Background(), stream, &msgstream.MsgPack{})
+		assert.Equal(t, transientErr, err)
+	})
+
+	t.Run("non-transient error fails immediately", func(t *testing.T) {
+		stream := msgstream.NewMockMsgStream(t)
+		mqErr := errors.New("message size exceeds MaxMessageSize: MessageTooBig")
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, mqErr).Once()
+
+		_, err := newTask().produceWithRetry(context.Background(), stream, &msgstream.MsgPack{})
+		assert.Equal(t, mqErr, err)
+	})
+
+	t.Run("retry disabled", func(t *testing.T) {
+		paramtable.Get().Save(Params.ProxyCfg.DeleteProduceMaxRetries.Key, "0")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteProduceMaxRetries.Key)
+
+		stream := msgstream.NewMockMsgStream(t)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, transientErr).Once()
+
+		_, err := newTask().produceWithRetry(context.Background(), stream, &msgstream.MsgPack{})
+		assert.Error(t, err)
+	})
+}
+
+func Test_hashDeletePK2Channels(t *testing.T) {
+	paramtable.Init()
+	channels := []string{"ch0", "ch1", "ch2"}
+	ids := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b", "c", "d", "e", "f", "g"}}},
+	}
+	expected := typeutil.HashPK2Channels(ids, channels)
+	assert.Equal(t, expected, hashDeletePK2Channels(nil, ids, channels))
+
+	paramtable.Get().Save(Params.ProxyCfg.DeleteParallelHashThreshold.Key, "2")
+	defer paramtable.Get().Reset(Params.ProxyCfg.DeleteParallelHashThreshold.Key)
+	assert.Equal(t, expected, hashDeletePK2Channels(nil, ids, channels))
+}
+
+func Test_isTransientProduceError(t *testing.T) {
+	assert.True(t, isTransientProduceError(errors.New("message send timeout: TimeoutError")))
+	assert.True(t, isTransientProduceError(errors.New("connection closed: NotConnectedError")))
+	assert.True(t, isTransientProduceError(errors.Wrap(syscall.ECONNRESET, "produce")))
+	assert.True(t, isTransientProduceError(io.ErrUnexpectedEOF))
+	assert.True(t, isTransientProduceError(&net.DNSError{IsTimeout: true}))
+
+	assert.False(t, isTransientProduceError(errors.New("message size exceeds MaxMessageSize: MessageTooBig")))
+	assert.False(t, isTransientProduceError(errors.New("not authorized: AuthorizationError")))
+	assert.False(t, isTransientProduceError(merr.ErrDenyProduceMsg))
+}
+
+// deletePhaseLatencySample returns the count and sum of the observed latency of a delete phase.
+func deletePhaseLatencySample(t *testing.T, collection string, phase string) (uint64, float64) {
+	m := &dto.Metric{}
+	observer := metrics.ProxyDeletePhaseLatency.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), collection, phase)
+	require.NoError(t, observer.(prometheus.Metric).Write(m))
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+// the rows must be deleted from the channels they were inserted into, whichever the shard hash of the collection is.
+func Test_shardHashRoutingAgrees(t *testing.T) {
+	paramtable.Init()
+	channels := []string{"ch0", "ch1", "ch2", "ch3"}
+	int64IDs := &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{}}}
+	stringIDs := &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{}}}
+	for i := 0; i < 1000; i++ {
+		int64IDs.GetIntId().Data = append(int64IDs.GetIntId().Data, int64(i)*7919-500)
+		stringIDs.GetStrId().Data = append(stringIDs.GetStrId().Data, fmt.Sprintf("pk_%0120d", i))
+	}
+
+	// the empty name is the legacy routing of the collections declaring no shard hash
+	for _, name := range append(typeutil.RegisteredShardHashes(), "") {
+		for _, ids := range []*schemapb.IDs{int64IDs, stringIDs} {
+			schema := newSchemaInfo(&schemapb.CollectionSchema{Name: "test_shard_hash"})
+			schema.shardHashName = name
+			shardHash, err := schema.GetShardHash()
+			require.NoError(t, err)
+
+			// insert path
+			inserted := make(map[interface{}]string, typeutil.GetSizeOfIDs(ids))
+			for channel, offsets := range assignChannelsByPK(shardHash, ids, channels, &msgstream.InsertMsg{}) {
+				for _, offset := range offsets {
+					inserted[typeutil.GetPK(ids, int64(offset))] = channel
+				}
+			}
+
+			// delete path, in sequence and in parallel
+			for _, threshold := range []string{Params.ProxyCfg.DeleteParallelHashThreshold.GetValue(), "10"} {
+				paramtable.Get().Save(Params.ProxyCfg.DeleteParallelHashThreshold.Key, threshold)
+				dt := newRepackDeleteTask(ids)
+				dt.shardHash = shardHash
+				msgs, err := dt.repack(context.Background(), hashDeletePK2Channels(dt.shardHash, dt.primaryKeys, dt.vChannels))
+				require.NoError(t, err)
+				deleted := 0
+				for _, msg := range msgs {
+					for i := 0; i < typeutil.GetSizeOfIDs(msg.GetPrimaryKeys()); i++ {
+						pk := typeutil.GetPK(msg.GetPrimaryKeys(), int64(i))
+						assert.Equal(t, inserted[pk], msg.GetShardName(), "shard hash %q, pk %v", name, pk)
+						deleted++
+					}
+				}
+				assert.Equal(t, len(inserted), deleted)
+				paramtable.Get().Reset(Params.ProxyCfg.DeleteParallelHashThreshold.Key)
+			}
+		}
+	}
+
+	// the routing differs by the shard hash
+	crc32Hash, _ := typeutil.GetShardHash(typeutil.CRC32ShardHash)
+	murmur3Hash, _ := typeutil.GetShardHash(typeutil.Murmur3ShardHash)
+	legacyHash, _ := typeutil.GetShardHash("")
+	for _, ids := range []*schemapb.IDs{int64IDs, stringIDs} {
+		assert.NotEqual(t, hashDeletePK2Channels(crc32Hash, ids, channels), hashDeletePK2Channels(murmur3Hash, ids, channels))
+		assert.NotEqual(t, hashDeletePK2Channels(crc32Hash, ids, channels), hashDeletePK2Channels(legacyHash, ids, channels))
+		assert.NotEqual(t, hashDeletePK2Channels(murmur3Hash, ids, channels), hashDeletePK2Channels(legacyHash, ids, channels))
+	}
+}
+
+func TestDeleteRunner_Init(t *testing.T) {
+	collectionName := "test_delete"
+	collectionID := int64(111)
+	partitionName := "default"
+	partitionID := int64(222)
+	// channels := []string{"test_channel"}
+	dbName := "test_1"
+
+	collSchema := &schemapb.CollectionSchema{
+		Name:        collectionName,
+		Description: "",
+		AutoID:      false,
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_Int64,
+			},
+			{
+				FieldID:      common.StartOfUserFieldID + 1,
+				Name:         "non_pk",
+				IsPrimaryKey: false,
+				DataType:     schemapb.DataType_Int64,
+			},
+		},
+	}
+	schema := newSchemaInfo(collSchema)
+
+	t.Run("empty collection name", func(t *testing.T) {
+		dr := deleteRunner{}
+		assert.Error(t, dr.Init(context.Background()))
+	})
+
+	t.Run("fail to get collection id", func(t *testing.T) {
+		dr := deleteRunner{
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+			},
+		}
+		cache := NewMockCache(t)
+		cache.On("GetCollectionID",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(int64(0), errors.New("mock GetCollectionID err"))
+		globalMetaCache = cache
+		assert.Error(t, dr.Init(context.Background()))
+	})
+
+	t.Run("fail get collection schema", func(t *testing.T) {
+		dr := deleteRunner{req: &milvuspb.DeleteRequest{
+			CollectionName: collectionName,
+			DbName:         dbName,
+		}}
+		cache := NewMockCache(t)
+		cache.On("GetCollectionID",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(collectionID, nil)
+		cache.On("GetCollectionSchema",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(nil, errors.New("mock GetCollectionSchema err"))
+
+		globalMetaCache = cache
+		assert.Error(t, dr.Init(context.Background()))
+	})
+
+	t.Run("unknown shard hash", func(t *testing.T) {
+		dr := deleteRunner{req: &milvuspb.DeleteRequest{
+			CollectionName: collectionName,
+			DbName:         dbName,
+		}}
+		unknownHashSchema := newSchemaInfo(collSchema)
+		unknownHashSchema.shardHashName = "xxhash"
+		cache := NewMockCache(t)
+		cache.On("GetCollectionID",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(collectionID, nil)
+		cache.On("GetCollectionSchema",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(unknownHashSchema, nil)
+
+		globalMetaCache = cache
+		err := dr.Init(context.Background())
+		assert.ErrorIs(t, err, merr.ErrCollectionIllegalSchema)
+		assert.ErrorContains(t, err, "unknown shard hash xxhash")
+	})
+
+	t.Run("partition key mode but delete with partition name", func(t *testing.T) {
+		dr := deleteRunner{req: &milvuspb.DeleteRequest{
+			CollectionName: collectionName,
+			DbName:         dbName,
+			PartitionName:  partitionName,
+		}}
+		cache := NewMockCache(t)
+		cache.On("GetCollectionID",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(collectionID, nil)
+		cache.On("GetCollectionSchema",
 			mock.Anything, // context.Context
 			mock.AnythingOfType("string"),
 			mock.AnythingOfType("string"),
@@ -332,6 +1038,56 @@ func TestDeleteRunner_Init(t *testing.T) {
 		assert.Error(t, dr.Init(context.Background()))
 	})
 
+	t.Run("partition key mode with partition override", func(t *testing.T) {
+		partitionKeySchema := newSchemaInfo(&schemapb.CollectionSchema{
+			Name: collectionName,
+			Fields: []*schemapb.FieldSchema{
+				{
+					FieldID:        common.StartOfUserFieldID,
+					Name:           "pk",
+					IsPrimaryKey:   true,
+					DataType:       schemapb.DataType_Int64,
+					IsPartitionKey: true,
+				},
+			},
+		})
+		newRunner := func(override string) (*deleteRunner, *MockChannelsMgr) {
+			chMgr := NewMockChannelsMgr(t)
+			return &deleteRunner{
+				req: &milvuspb.DeleteRequest{
+					Base:           &commonpb.MsgBase{Properties: map[string]string{DeletePartitionKeyOverrideKey: override}},
+					CollectionName: collectionName,
+					DbName:         dbName,
+					PartitionName:  partitionName,
+					Expr:           "pk in [1, 2, 3]",
+				},
+				chMgr: chMgr,
+			}, chMgr
+		}
+		cache := NewMockCache(t)
+		cache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil)
+		cache.EXPECT().GetCollectionSchema(mock.Anything, dbName, collectionName).Return(partitionKeySchema, nil)
+		cache.EXPECT().GetPartitionID(mock.Anything, dbName, collectionName, partitionName).Return(partitionID, nil).Maybe()
+		globalMetaCache = cache
+
+		// denied if the proxy does not allow overriding
+		dr, _ := newRunner("true")
+		assert.Error(t, dr.Init(context.Background()))
+
+		paramtable.Get().Save(Params.ProxyCfg.DeletePartitionKeyOverride.Key, "true")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeletePartitionKeyOverride.Key)
+
+		// denied if the request does not ask for overriding
+		dr, _ = newRunner("false")
+		assert.Error(t, dr.Init(context.Background()))
+
+		dr, chMgr := newRunner("true")
+		chMgr.EXPECT().getVChannels(collectionID).Return([]string{"test_channel"}, nil)
+		assert.NoError(t, dr.Init(context.Background()))
+		assert.True(t, dr.partitionKeyMode)
+		assert.Equal(t, partitionID, dr.partitionID)
+	})
+
 	t.Run("invalid partition name", func(t *testing.T) {
 		dr := deleteRunner{
 			req: &milvuspb.DeleteRequest{
@@ -395,105 +1151,1397 @@ func TestDeleteRunner_Init(t *testing.T) {
 				CollectionName: collectionName,
 				DbName:         dbName,
 				PartitionName:  partitionName,
-				Expr:           "non_pk in [1, 2, 3]",
+				Expr:           "non_pk in [1, 2, 3]",
+			},
+			chMgr: chMgr,
+		}
+		cache := NewMockCache(t)
+		cache.On("GetCollectionID",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(collectionID, nil)
+		cache.On("GetCollectionSchema",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(schema, nil)
+		cache.On("GetPartitionID",
+			mock.Anything, // context.Context
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"),
+		).Return(partitionID, nil)
+		chMgr.On("getVChannels", mock.Anything).Return(nil, fmt.Errorf("mock error"))
+
+		globalMetaCache = cache
+		assert.Error(t, dr.Init(context.Background()))
+	})
+}
+
+func TestDeleteRunner_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	collectionName := "test_delete"
+	collectionID := int64(111)
+	partitionName := "default"
+	partitionID := int64(222)
+	channels := []string{"test_channel"}
+	dbName := "test_1"
+	tsoAllocator := &mockTsoAllocator{}
+	idAllocator := &mockIDAllocatorInterface{}
+
+	queue, err := newTaskScheduler(ctx, tsoAllocator, nil)
+	assert.NoError(t, err)
+	queue.Start()
+	defer queue.Close()
+
+	collSchema := &schemapb.CollectionSchema{
+		Name:        collectionName,
+		Description: "",
+		AutoID:      false,
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_Int64,
+			},
+			{
+				FieldID:      common.StartOfUserFieldID + 1,
+				Name:         "non_pk",
+				IsPrimaryKey: false,
+				DataType:     schemapb.DataType_Int64,
+			},
+		},
+	}
+	schema := newSchemaInfo(collSchema)
+
+	metaCache := NewMockCache(t)
+	metaCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil).Maybe()
+	metaCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true).Maybe()
+	globalMetaCache = metaCache
+	defer func() {
+		globalMetaCache = nil
+	}()
+
+	t.Run("create plan failed", func(t *testing.T) {
+		mockMgr := NewMockChannelsMgr(t)
+		dr := deleteRunner{
+			chMgr: mockMgr,
+			req: &milvuspb.DeleteRequest{
+				Expr: "????",
+			},
+			schema: schema,
+		}
+		assert.Error(t, dr.Run(context.Background()))
+	})
+
+	t.Run("invalid delete scope", func(t *testing.T) {
+		dr := deleteRunner{
+			req: &milvuspb.DeleteRequest{
+				Base: &commonpb.MsgBase{Properties: map[string]string{DeleteScopeKey: "growing"}},
+				Expr: "non_pk > 1",
+			},
+			schema: schema,
+		}
+		assert.ErrorIs(t, dr.Run(context.Background()), merr.ErrParameterInvalid)
+	})
+
+	t.Run("simple delete task failed", func(t *testing.T) {
+		mockMgr := NewMockChannelsMgr(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			queue:           queue.dmQueue,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk in [1,2,3]",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, fmt.Errorf("mock error"))
+
+		assert.Error(t, dr.Run(context.Background()))
+		assert.Equal(t, int64(0), dr.result.DeleteCnt)
+	})
+
+	t.Run("simple delete after alias repointed", func(t *testing.T) {
+		mockMgr := NewMockChannelsMgr(t)
+		lb := NewMockLBPolicy(t)
+
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID+1, nil)
+		globalMetaCache = mockCache
+		defer func() { globalMetaCache = metaCache }()
+
+		dr := deleteRunner{
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			queue:           queue.dmQueue,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk in [1,2,3]",
+			},
+		}
+
+		// nothing is produced to the channels of either collection
+		assert.ErrorIs(t, dr.Run(context.Background()), merr.ErrCollectionIdentityChanged)
+		assert.Equal(t, int64(0), dr.result.DeleteCnt)
+		assert.Equal(t, deletePathSimple, dr.path)
+	})
+
+	t.Run("delete by ids success", func(t *testing.T) {
+		mockMgr := NewMockChannelsMgr(t)
+		lb := NewMockLBPolicy(t)
+
+		req := &milvuspb.DeleteRequest{
+			CollectionName: collectionName,
+			PartitionName:  partitionName,
+			DbName:         dbName,
+		}
+		assert.NoError(t, setDeleteIDs(req, &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2, 3}}},
+		}))
+		dr := deleteRunner{
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			queue:           queue.dmQueue,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: req,
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			assert.Len(t, pack.Msgs, 1)
+			deleteMsg := pack.Msgs[0].(*msgstream.DeleteMsg)
+			assert.Equal(t, partitionID, deleteMsg.GetPartitionID())
+			assert.Equal(t, []int64{1, 2, 3}, deleteMsg.GetPrimaryKeys().GetIntId().GetData())
+			return nil, nil
+		})
+
+		assert.NoError(t, dr.Run(context.Background()))
+		assert.Equal(t, int64(3), dr.result.DeleteCnt)
+	})
+
+	t.Run("delete by expr template", func(t *testing.T) {
+		mockMgr := NewMockChannelsMgr(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			queue:           queue.dmQueue,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				Base:           &commonpb.MsgBase{Properties: map[string]string{DeleteExprTemplateValuesKey: `{"pks": [4, 5, 6]}`}},
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk in {pks}",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			assert.Len(t, pack.Msgs, 1)
+			assert.Equal(t, []int64{4, 5, 6}, pack.Msgs[0].(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData())
+			return nil, nil
+		})
+
+		assert.NoError(t, dr.Run(context.Background()))
+		assert.Equal(t, int64(3), dr.result.DeleteCnt)
+
+		// type mismatch of the bound value fails the plan creation
+		dr.req.Base.Properties[DeleteExprTemplateValuesKey] = `{"pks": ["4"]}`
+		assert.Error(t, dr.Run(context.Background()))
+	})
+
+	t.Run("delete vetoed by interceptor", func(t *testing.T) {
+		vetoErr := errors.New("delete is not allowed")
+		interceptor := &testDeleteInterceptor{err: vetoErr}
+		withDeleteInterceptors(t, interceptor)
+
+		// no task is produced, channels mgr must not be touched
+		mockMgr := NewMockChannelsMgr(t)
+		dr := deleteRunner{
+			chMgr:        mockMgr,
+			schema:       schema,
+			collectionID: collectionID,
+			vChannels:    channels,
+			queue:        queue.dmQueue,
+			result:       &milvuspb.MutationResult{Status: merr.Success()},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				DbName:         dbName,
+				Expr:           "pk in [1,2,3]",
+			},
+		}
+		assert.ErrorIs(t, dr.Run(context.Background()), vetoErr)
+		assert.Equal(t, 1, interceptor.beforeCnt)
+		assert.NotNil(t, interceptor.plan)
+		assert.Equal(t, 0, interceptor.afterCnt)
+		assert.EqualValues(t, 0, dr.result.GetDeleteCnt())
+	})
+
+	t.Run("delete passes through interceptor", func(t *testing.T) {
+		interceptor := &testDeleteInterceptor{}
+		withDeleteInterceptors(t, interceptor)
+
+		mockMgr := NewMockChannelsMgr(t)
+		req := &milvuspb.DeleteRequest{
+			CollectionName: collectionName,
+			DbName:         dbName,
+		}
+		assert.NoError(t, setDeleteIDs(req, &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2, 3}}},
+		}))
+		dr := deleteRunner{
+			chMgr:        mockMgr,
+			schema:       schema,
+			collectionID: collectionID,
+			partitionID:  partitionID,
+			vChannels:    channels,
+			idAllocator:  idAllocator,
+			queue:        queue.dmQueue,
+			result:       &milvuspb.MutationResult{Status: merr.Success()},
+			req:          req,
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, nil)
+
+		assert.NoError(t, dr.Run(context.Background()))
+		assert.Equal(t, 1, interceptor.beforeCnt)
+		assert.Nil(t, interceptor.plan)
+		assert.Equal(t, 1, interceptor.afterCnt)
+		assert.EqualValues(t, 3, interceptor.result.GetDeleteCnt())
+	})
+
+	t.Run("delete by ids with mismatched pk type", func(t *testing.T) {
+		req := &milvuspb.DeleteRequest{
+			CollectionName: collectionName,
+			DbName:         dbName,
+		}
+		assert.NoError(t, setDeleteIDs(req, &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"1"}}},
+		}))
+		dr := deleteRunner{
+			schema: schema,
+			req:    req,
+		}
+		assert.ErrorIs(t, dr.Run(context.Background()), merr.ErrParameterInvalid)
+	})
+
+	t.Run("complex delete query rpc failed", func(t *testing.T) {
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			idAllocator:     idAllocator,
+			tsoAllocatorIns: tsoAllocator,
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 3",
+			},
+		}
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Return(nil, errors.New("mock error"))
+		assert.Error(t, dr.Run(context.Background()))
+		assert.Equal(t, int64(0), dr.result.DeleteCnt)
+	})
+
+	t.Run("complex delete query failed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 3",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, nil)
+
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids: &schemapb.IDs{
+						IdField: &schemapb.IDs_IntId{
+							IntId: &schemapb.LongArray{
+								Data: []int64{0, 1, 2},
+							},
+						},
+					},
+				})
+
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Status(errors.New("mock error")),
+				})
+				return client
+			}, nil)
+
+		assert.Error(t, dr.Run(ctx))
+	})
+
+	t.Run("complex delete query stream retried", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 4",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		var produced []int64
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			for _, msg := range pack.Msgs {
+				produced = append(produced, msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData()...)
+			}
+			return nil, nil
+		})
+
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		var requests []*querypb.QueryRequest
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				requests = append(requests, in)
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+
+				if len(requests) == 1 {
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Success(),
+						Ids:    int64IDs(0, 1),
+					})
+					// segment handoff
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Status(merr.WrapErrServiceUnavailable("mock handoff")),
+					})
+					return client
+				}
+				// the same rows in the same order
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids:    int64IDs(0),
+				})
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids:    int64IDs(1, 2, 3),
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+
+		assert.NoError(t, dr.Run(ctx))
+		// the rows produced before the retry are not deleted again
+		assert.Equal(t, int64(4), dr.result.DeleteCnt)
+		assert.ElementsMatch(t, []int64{0, 1, 2, 3}, produced)
+		// the stream is re-issued with the same plan and mvcc ts
+		assert.Len(t, requests, 2)
+		assert.Same(t, requests[0], requests[1])
+	})
+
+	t.Run("complex delete query stream retried in another order", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 4",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil).Maybe()
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil).Maybe()
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, nil).Maybe()
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		issued := 0
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				issued++
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+				if issued == 1 {
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Success(),
+						Ids:    int64IDs(0, 1),
+					})
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Status(merr.WrapErrServiceUnavailable("mock handoff")),
+					})
+					return client
+				}
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids:    int64IDs(1, 0, 2, 3),
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+
+		// the rows received can't be skipped by their number
+		assert.ErrorIs(t, dr.Run(ctx), merr.ErrServiceInternal)
+		assert.Equal(t, 2, issued)
+	})
+
+	t.Run("complex delete with retries enabled but not retried", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteQueryStreamMaxRetries.Key, "3")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteQueryStreamMaxRetries.Key)
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 4",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		var produced []int64
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			for _, msg := range pack.Msgs {
+				produced = append(produced, msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData()...)
+			}
+			return nil, nil
+		})
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids:    int64IDs(0, 1),
+				})
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids:    int64IDs(2, 3),
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil).Once()
+
+		assert.NoError(t, dr.Run(ctx))
+		assert.Equal(t, int64(4), dr.result.DeleteCnt)
+		assert.ElementsMatch(t, []int64{0, 1, 2, 3}, produced)
+	})
+
+	t.Run("complex delete traced", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteChunkSize.Key, "2")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteChunkSize.Key)
+
+		recorder := tracetest.NewSpanRecorder()
+		tracerProvider := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(tracerProvider)
+		propagator := otel.GetTextMapPropagator()
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		defer otel.SetTextMapPropagator(propagator)
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 4",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, nil)
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, channels[0])
+		})
+
+		var queryReq *querypb.QueryRequest
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+				if queryReq == nil {
+					queryReq = in
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Status(merr.WrapErrServiceUnavailable("mock handoff")),
+					})
+					return client
+				}
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids:    int64IDs(0, 1, 2, 3),
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+
+		ctx, root := otel.Tracer("test").Start(ctx, "Delete")
+		assert.NoError(t, dr.Run(ctx))
+		root.End()
+		assert.Equal(t, int64(4), dr.result.DeleteCnt)
+
+		spans := make(map[string][]sdktrace.ReadOnlySpan)
+		byID := make(map[trace.SpanID]sdktrace.ReadOnlySpan)
+		for _, span := range recorder.Started() {
+			spans[span.Name()] = append(spans[span.Name()], span)
+			byID[span.SpanContext().SpanID()] = span
+		}
+		isAncestor := func(ancestor sdktrace.ReadOnlySpan, span sdktrace.ReadOnlySpan) bool {
+			for parent := span.Parent(); parent.IsValid(); {
+				if parent.SpanID() == ancestor.SpanContext().SpanID() {
+					return true
+				}
+				parentSpan, ok := byID[parent.SpanID()]
+				if !ok {
+					return false
+				}
+				parent = parentSpan.Parent()
+			}
+			return false
+		}
+		attrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+			m := make(map[attribute.Key]attribute.Value)
+			for _, kv := range span.Attributes() {
+				m[kv.Key] = kv.Value
+			}
+			return m
+		}
+
+		require.Len(t, spans["Proxy-Delete-Plan"], 1)
+		planSpan := spans["Proxy-Delete-Plan"][0]
+		assert.Equal(t, root.SpanContext().SpanID(), planSpan.Parent().SpanID())
+		assert.Equal(t, collectionID, attrs(planSpan)["collectionID"].AsInt64())
+		assert.False(t, attrs(planSpan)["simple"].AsBool())
+
+		require.Len(t, spans["Proxy-Delete-Query"], 1)
+		querySpan := spans["Proxy-Delete-Query"][0]
+		assert.Equal(t, root.SpanContext().SpanID(), querySpan.Parent().SpanID())
+		assert.Equal(t, collectionID, attrs(querySpan)["collectionID"].AsInt64())
+		assert.Equal(t, int64(4), attrs(querySpan)["rows"].AsInt64())
+
+		require.Len(t, spans["Proxy-Delete-QueryStream"], 1)
+		channelSpan := spans["Proxy-Delete-QueryStream"][0]
+		assert.Equal(t, querySpan.SpanContext().SpanID(), channelSpan.Parent().SpanID())
+		assert.Equal(t, int64(1), attrs(channelSpan)["nodeID"].AsInt64())
+		assert.Equal(t, channels[0], attrs(channelSpan)["channel"].AsString())
+		assert.Equal(t, dr.msgID, attrs(channelSpan)["msgID"].AsInt64())
+		assert.Equal(t, int64(4), attrs(channelSpan)["rows"].AsInt64())
+		assert.True(t, lo.ContainsBy(channelSpan.Events(), func(event sdktrace.Event) bool {
+			return event.Name == "re-issue query stream"
+		}))
+
+		// the trace context is carried by the retrieve request to query node
+		assert.Equal(t, fmt.Sprintf("00-%s-%s-01", channelSpan.SpanContext().TraceID(), channelSpan.SpanContext().SpanID()),
+			queryReq.GetReq().GetBase().GetProperties()["traceparent"])
+		recovered := commonpbutil.ExtractTraceCtx(context.Background(), queryReq.GetReq().GetBase())
+		assert.Equal(t, channelSpan.SpanContext().SpanID(), trace.SpanContextFromContext(recovered).SpanID())
+
+		// the tasks are produced in chunks under the channel
+		require.Len(t, spans["Proxy-Delete-Execute"], 2)
+		for _, span := range spans["Proxy-Delete-Execute"] {
+			assert.True(t, isAncestor(channelSpan, span))
+		}
+		require.Len(t, spans["Proxy-Delete-QueueWait"], 2)
+		for _, span := range spans["Proxy-Delete-QueueWait"] {
+			assert.Equal(t, channelSpan.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+		require.Len(t, spans["Proxy-Delete-Produce"], 2)
+		produced := int64(0)
+		for _, span := range spans["Proxy-Delete-Produce"] {
+			assert.Equal(t, "Proxy-Delete-Execute", byID[span.Parent().SpanID()].Name())
+			produced += attrs(span)["numRows"].AsInt64()
+		}
+		assert.Equal(t, int64(4), produced)
+	})
+
+	t.Run("complex delete knobs changed midway", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		source := config.NewMemorySource("delete-knobs-"+funcutil.GenRandomStr(), config.HighPriority)
+		require.NoError(t, paramtable.GetBaseTable().Manager().AddSource(source))
+		source.Set(Params.ProxyCfg.DeleteChunkSize.Key, "2")
+		defer source.Delete(Params.ProxyCfg.DeleteChunkSize.Key)
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+		newRunner := func() *deleteRunner {
+			return &deleteRunner{
+				queue:           queue.dmQueue,
+				chMgr:           mockMgr,
+				schema:          schema,
+				collectionID:    collectionID,
+				partitionID:     partitionID,
+				vChannels:       channels,
+				tsoAllocatorIns: tsoAllocator,
+				idAllocator:     idAllocator,
+				lb:              lb,
+				result: &milvuspb.MutationResult{
+					Status: merr.Success(),
+					IDs: &schemapb.IDs{
+						IdField: nil,
+					},
+				},
+				req: &milvuspb.DeleteRequest{
+					CollectionName: collectionName,
+					PartitionName:  partitionName,
+					DbName:         dbName,
+					Expr:           "pk < 4",
+				},
+			}
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		produced := atomic.NewInt32(0)
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			produced.Inc()
+			return nil, nil
+		})
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, channels[0])
+		})
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				// changed while the delete is ongoing
+				source.Set(Params.ProxyCfg.DeleteChunkSize.Key, "1")
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids:    int64IDs(0, 1, 2, 3),
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+
+		// the ongoing delete keeps the chunk size it read at the start
+		dr := newRunner()
+		assert.NoError(t, dr.Run(ctx))
+		assert.Equal(t, int64(4), dr.result.DeleteCnt)
+		assert.Equal(t, 2, dr.knobs.chunkSize)
+		assert.EqualValues(t, 2, produced.Load())
+
+		// and the next one reads the changed one
+		produced.Store(0)
+		dr = newRunner()
+		assert.NoError(t, dr.Run(ctx))
+		assert.Equal(t, int64(4), dr.result.DeleteCnt)
+		assert.Equal(t, 1, dr.knobs.chunkSize)
+		assert.EqualValues(t, 4, produced.Load())
+	})
+
+	t.Run("complex delete query stream retries exhausted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		paramtable.Get().Save(paramtable.Get().ProxyCfg.DeleteQueryStreamMaxRetries.Key, "1")
+		defer paramtable.Get().Reset(paramtable.Get().ProxyCfg.DeleteQueryStreamMaxRetries.Key)
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			tsoAllocatorIns: tsoAllocator,
+			idAllocator:     idAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 4",
+			},
+		}
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil).Maybe()
+
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Status(merr.WrapErrServiceUnavailable("mock handoff")),
+				})
+				return client
+			}, nil).Times(2)
+
+		assert.ErrorIs(t, dr.Run(ctx), merr.ErrServiceUnavailable)
+	})
+
+	t.Run("complex delete produce failed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			chMgr:           mockMgr,
+			queue:           queue.dmQueue,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			idAllocator:     idAllocator,
+			tsoAllocatorIns: tsoAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 3",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids: &schemapb.IDs{
+						IdField: &schemapb.IDs_IntId{
+							IntId: &schemapb.LongArray{
+								Data: []int64{0, 1, 2},
+							},
+						},
+					},
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, errors.New("mock error"))
+
+		err := dr.Run(ctx)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "matched 3 rows but only deleted 0 rows")
+		assert.Equal(t, int64(0), dr.result.DeleteCnt)
+		assert.Equal(t, int64(3), dr.matchedCount.Load())
+	})
+
+	t.Run("complex delete success", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			idAllocator:     idAllocator,
+			tsoAllocatorIns: tsoAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 3",
+			},
+		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids: &schemapb.IDs{
+						IdField: &schemapb.IDs_IntId{
+							IntId: &schemapb.LongArray{
+								Data: []int64{0, 1, 2},
+							},
+						},
+					},
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, nil)
+
+		phases := []string{metrics.DeletePlanLabel, metrics.DeleteQueryLabel, metrics.DeleteRepackLabel, metrics.DeleteProduceLabel}
+		counts := make(map[string]uint64)
+		sums := make(map[string]float64)
+		for _, phase := range phases {
+			counts[phase], sums[phase] = deletePhaseLatencySample(t, collectionName, phase)
+		}
+
+		assert.NoError(t, dr.Run(ctx))
+		assert.Equal(t, int64(3), dr.result.DeleteCnt)
+		assert.Equal(t, int64(3), dr.matchedCount.Load())
+		for _, phase := range phases {
+			count, sum := deletePhaseLatencySample(t, collectionName, phase)
+			assert.Equal(t, counts[phase]+1, count, phase)
+			assert.Greater(t, sum, sums[phase], phase)
+		}
+	})
+
+	t.Run("delete with verify", func(t *testing.T) {
+		run := func(t *testing.T, expr string, found []int64) (*deleteRunner, []int64, *planpb.PlanNode) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mockMgr := NewMockChannelsMgr(t)
+			qn := mocks.NewMockQueryNodeClient(t)
+			lb := NewMockLBPolicy(t)
+
+			dr := &deleteRunner{
+				queue:           queue.dmQueue,
+				chMgr:           mockMgr,
+				schema:          schema,
+				collectionID:    collectionID,
+				partitionID:     partitionID,
+				vChannels:       channels,
+				idAllocator:     idAllocator,
+				tsoAllocatorIns: tsoAllocator,
+				lb:              lb,
+				result: &milvuspb.MutationResult{
+					Status: merr.Success(),
+					IDs: &schemapb.IDs{
+						IdField: nil,
+					},
+				},
+				req: &milvuspb.DeleteRequest{
+					Base:           &commonpb.MsgBase{Properties: map[string]string{DeleteVerifyKey: "true"}},
+					CollectionName: collectionName,
+					PartitionName:  partitionName,
+					DbName:         dbName,
+					Expr:           expr,
+				},
+			}
+			stream := msgstream.NewMockMsgStream(t)
+			mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+			mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+			lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+				return workload.exec(ctx, 1, qn, "")
+			})
+
+			plan := &planpb.PlanNode{}
+			qn.EXPECT().QueryStream(mock.Anything, mock.Anything).RunAndReturn(
+				func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) (querypb.QueryNode_QueryStreamClient, error) {
+					assert.NoError(t, proto.Unmarshal(in.GetReq().GetSerializedExprPlan(), plan))
+					client := streamrpc.NewLocalQueryClient(ctx)
+					server := client.CreateServer()
+
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Success(),
+						Ids: &schemapb.IDs{
+							IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: found}},
+						},
+					})
+					server.FinishSend(nil)
+					return client, nil
+				})
+			produced := make([]int64, 0)
+			stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+				for _, msg := range pack.Msgs {
+					produced = append(produced, msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData()...)
+				}
+				return nil, nil
+			})
+
+			assert.NoError(t, dr.Run(ctx))
+			return dr, produced, plan
+		}
+
+		t.Run("simple delete counts existing rows only", func(t *testing.T) {
+			dr, produced, plan := run(t, "pk in [1, 2, 3]", []int64{2})
+			assert.Equal(t, int64(1), dr.result.DeleteCnt)
+			assert.Equal(t, []int64{2}, produced)
+			// the retrieve is restricted to the primary keys to delete
+			termExpr := plan.GetQuery().GetPredicates().GetTermExpr()
+			assert.True(t, termExpr.GetColumnInfo().GetIsPrimaryKey())
+			assert.Len(t, termExpr.GetValues(), 3)
+		})
+
+		t.Run("complex delete is untouched", func(t *testing.T) {
+			dr, produced, plan := run(t, "pk < 3", []int64{0, 1, 2})
+			assert.Equal(t, int64(3), dr.result.DeleteCnt)
+			assert.ElementsMatch(t, []int64{0, 1, 2}, produced)
+			assert.NotNil(t, plan.GetQuery().GetPredicates().GetUnaryRangeExpr())
+		})
+	})
+
+	t.Run("complex delete with oversized query result", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteChunkSize.Key, "2")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteChunkSize.Key)
+
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		dr := deleteRunner{
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			idAllocator:     idAllocator,
+			tsoAllocatorIns: tsoAllocator,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 5",
 			},
-			chMgr: chMgr,
 		}
-		cache := NewMockCache(t)
-		cache.On("GetCollectionID",
-			mock.Anything, // context.Context
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-		).Return(collectionID, nil)
-		cache.On("GetCollectionSchema",
-			mock.Anything, // context.Context
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-		).Return(schema, nil)
-		cache.On("GetPartitionID",
-			mock.Anything, // context.Context
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-			mock.AnythingOfType("string"),
-		).Return(partitionID, nil)
-		chMgr.On("getVChannels", mock.Anything).Return(nil, fmt.Errorf("mock error"))
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
 
-		globalMetaCache = cache
-		assert.Error(t, dr.Init(context.Background()))
-	})
-}
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
 
-func TestDeleteRunner_Run(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids: &schemapb.IDs{
+						IdField: &schemapb.IDs_IntId{
+							IntId: &schemapb.LongArray{
+								Data: []int64{0, 1, 2, 3, 4},
+							},
+						},
+					},
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+		// tasks of chunks are executed concurrently
+		var mu sync.Mutex
+		produced := make([]int64, 0)
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, msg := range pack.Msgs {
+				pks := msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData()
+				assert.LessOrEqual(t, len(pks), 2)
+				produced = append(produced, pks...)
+			}
+			return nil, nil
+		}).Times(3)
 
-	collectionName := "test_delete"
-	collectionID := int64(111)
-	partitionName := "default"
-	partitionID := int64(222)
-	channels := []string{"test_channel"}
-	dbName := "test_1"
-	tsoAllocator := &mockTsoAllocator{}
-	idAllocator := &mockIDAllocatorInterface{}
+		assert.NoError(t, dr.Run(ctx))
+		assert.Equal(t, int64(5), dr.result.DeleteCnt)
+		assert.ElementsMatch(t, []int64{0, 1, 2, 3, 4}, produced)
+	})
 
-	queue, err := newTaskScheduler(ctx, tsoAllocator, nil)
-	assert.NoError(t, err)
-	queue.Start()
-	defer queue.Close()
+	t.Run("complex deletes admitted as the buffer drains", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteBufferMemoryLimit.Key, "16")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteBufferMemoryLimit.Key)
+
+		// the produce of the first delete is blocked until drained
+		drain := make(chan struct{})
+		secondProduced := atomic.NewBool(false)
+		newRunner := func(expr string, ids []int64, produce func(*msgstream.MsgPack) (map[string][]msgstream.MessageID, error)) *deleteRunner {
+			mockMgr := NewMockChannelsMgr(t)
+			qn := mocks.NewMockQueryNodeClient(t)
+			lb := NewMockLBPolicy(t)
+			stream := msgstream.NewMockMsgStream(t)
+			mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+			mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+			lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+				return workload.exec(ctx, 1, qn, "")
+			})
+			qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+				func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+					client := streamrpc.NewLocalQueryClient(ctx)
+					server := client.CreateServer()
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Success(),
+						Ids: &schemapb.IDs{
+							IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: ids}},
+						},
+					})
+					server.FinishSend(nil)
+					return client
+				}, nil)
+			stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(produce)
+
+			return &deleteRunner{
+				queue:           queue.dmQueue,
+				chMgr:           mockMgr,
+				schema:          schema,
+				collectionID:    collectionID,
+				partitionID:     partitionID,
+				vChannels:       channels,
+				idAllocator:     idAllocator,
+				tsoAllocatorIns: tsoAllocator,
+				lb:              lb,
+				result: &milvuspb.MutationResult{
+					Status: merr.Success(),
+					IDs:    &schemapb.IDs{},
+				},
+				req: &milvuspb.DeleteRequest{
+					CollectionName: collectionName,
+					PartitionName:  partitionName,
+					DbName:         dbName,
+					Expr:           expr,
+				},
+			}
+		}
+		first := newRunner("pk < 3", []int64{0, 1, 2}, func(*msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			<-drain
+			return nil, nil
+		})
+		second := newRunner("pk >= 3", []int64{3, 4}, func(*msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			secondProduced.Store(true)
+			return nil, nil
+		})
 
-	collSchema := &schemapb.CollectionSchema{
-		Name:        collectionName,
-		Description: "",
-		AutoID:      false,
-		Fields: []*schemapb.FieldSchema{
-			{
-				FieldID:      common.StartOfUserFieldID,
-				Name:         "pk",
-				IsPrimaryKey: true,
-				DataType:     schemapb.DataType_Int64,
-			},
-			{
-				FieldID:      common.StartOfUserFieldID + 1,
-				Name:         "non_pk",
-				IsPrimaryKey: false,
-				DataType:     schemapb.DataType_Int64,
-			},
-		},
-	}
-	schema := newSchemaInfo(collSchema)
+		firstDone := make(chan error, 1)
+		go func() {
+			firstDone <- first.Run(ctx)
+		}()
+		// 24 bytes of the first delete exceeds the budget
+		assert.Eventually(t, func() bool {
+			return getDeleteBufferGate().Buffered() >= 24
+		}, 5*time.Second, 10*time.Millisecond)
+
+		secondDone := make(chan error, 1)
+		go func() {
+			secondDone <- second.Run(ctx)
+		}()
+		select {
+		case <-secondDone:
+			t.Fatal("the second delete should wait for the first to drain")
+		case <-time.After(100 * time.Millisecond):
+		}
+		assert.False(t, secondProduced.Load())
+
+		close(drain)
+		assert.NoError(t, <-firstDone)
+		assert.NoError(t, <-secondDone)
+		assert.True(t, secondProduced.Load())
+		assert.EqualValues(t, 3, first.result.GetDeleteCnt())
+		assert.EqualValues(t, 2, second.result.GetDeleteCnt())
+		assert.Eventually(t, func() bool {
+			return getDeleteBufferGate().Buffered() == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
 
-	metaCache := NewMockCache(t)
-	metaCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil).Maybe()
-	globalMetaCache = metaCache
-	defer func() {
-		globalMetaCache = nil
-	}()
+	t.Run("complex delete rejected as the buffer is full", func(t *testing.T) {
+		paramtable.Get().Save(Params.ProxyCfg.DeleteBufferMemoryLimit.Key, "16")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteBufferMemoryLimit.Key)
+		paramtable.Get().Save(Params.ProxyCfg.DeleteAdmissionTimeout.Key, "10")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteAdmissionTimeout.Key)
+
+		gate := getDeleteBufferGate()
+		assert.NoError(t, gate.Acquire(context.Background(), 16))
+		defer gate.Release(16)
 
-	t.Run("create plan failed", func(t *testing.T) {
-		mockMgr := NewMockChannelsMgr(t)
 		dr := deleteRunner{
-			chMgr: mockMgr,
+			queue:        queue.dmQueue,
+			schema:       schema,
+			collectionID: collectionID,
+			partitionID:  partitionID,
+			vChannels:    channels,
+			idAllocator:  idAllocator,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs:    &schemapb.IDs{},
+			},
 			req: &milvuspb.DeleteRequest{
-				Expr: "????",
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 3",
 			},
-			schema: schema,
 		}
-		assert.Error(t, dr.Run(context.Background()))
+		err := dr.Run(context.Background())
+		assert.ErrorIs(t, err, merr.ErrServiceMemoryLimitExceeded)
+		assert.EqualValues(t, 0, dr.result.GetDeleteCnt())
 	})
 
-	t.Run("simple delete task failed", func(t *testing.T) {
+	t.Run("complex delete partially failed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteChunkSize.Key, "2")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteChunkSize.Key)
+
 		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
 		lb := NewMockLBPolicy(t)
 
 		dr := deleteRunner{
+			queue:           queue.dmQueue,
 			chMgr:           mockMgr,
 			schema:          schema,
 			collectionID:    collectionID,
 			partitionID:     partitionID,
 			vChannels:       channels,
-			tsoAllocatorIns: tsoAllocator,
 			idAllocator:     idAllocator,
-			queue:           queue.dmQueue,
+			tsoAllocatorIns: tsoAllocator,
 			lb:              lb,
 			result: &milvuspb.MutationResult{
 				Status: merr.Success(),
@@ -505,32 +2553,72 @@ func TestDeleteRunner_Run(t *testing.T) {
 				CollectionName: collectionName,
 				PartitionName:  partitionName,
 				DbName:         dbName,
-				Expr:           "pk in [1,2,3]",
+				Expr:           "pk < 5",
 			},
 		}
 		stream := msgstream.NewMockMsgStream(t)
 		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
 		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
-		stream.EXPECT().Produce(mock.Anything).Return(fmt.Errorf("mock error"))
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
 
-		assert.Error(t, dr.Run(context.Background()))
-		assert.Equal(t, int64(0), dr.result.DeleteCnt)
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+
+				server.Send(&internalpb.RetrieveResults{
+					Status: merr.Success(),
+					Ids: &schemapb.IDs{
+						IdField: &schemapb.IDs_IntId{
+							IntId: &schemapb.LongArray{
+								Data: []int64{0, 1, 2, 3, 4},
+							},
+						},
+					},
+				})
+				server.FinishSend(nil)
+				return client
+			}, nil)
+		// the first chunk is deleted, the second one fails
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			for _, msg := range pack.Msgs {
+				if lo.Contains(msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData(), 2) {
+					return nil, errors.New("mock error")
+				}
+			}
+			return nil, nil
+		})
+
+		err := dr.Run(ctx)
+		assert.ErrorIs(t, err, merr.ErrDeletePartial)
+		assert.False(t, merr.IsRetryableErr(err))
+		assert.Contains(t, err.Error(), "deleted=2")
+		assert.Equal(t, int64(2), dr.result.DeleteCnt)
 	})
 
-	t.Run("complex delete query rpc failed", func(t *testing.T) {
+	t.Run("complex delete exceeds max rows", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteChunkSize.Key, "2")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteChunkSize.Key)
+		paramtable.Get().Save(Params.ProxyCfg.MaxDeleteRowsPerRequest.Key, "4")
+		defer paramtable.Get().Reset(Params.ProxyCfg.MaxDeleteRowsPerRequest.Key)
+
 		mockMgr := NewMockChannelsMgr(t)
 		qn := mocks.NewMockQueryNodeClient(t)
 		lb := NewMockLBPolicy(t)
 
 		dr := deleteRunner{
-			idAllocator:     idAllocator,
-			tsoAllocatorIns: tsoAllocator,
 			queue:           queue.dmQueue,
 			chMgr:           mockMgr,
 			schema:          schema,
 			collectionID:    collectionID,
 			partitionID:     partitionID,
 			vChannels:       channels,
+			idAllocator:     idAllocator,
+			tsoAllocatorIns: tsoAllocator,
 			lb:              lb,
 			result: &milvuspb.MutationResult{
 				Status: merr.Success(),
@@ -542,26 +2630,97 @@ func TestDeleteRunner_Run(t *testing.T) {
 				CollectionName: collectionName,
 				PartitionName:  partitionName,
 				DbName:         dbName,
-				Expr:           "pk < 3",
+				Expr:           "pk < 10",
 			},
 		}
+		stream := msgstream.NewMockMsgStream(t)
+		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
+		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		// the channels are executed one by one, the first error stops the others
+		var channelErrs []error
 		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
-			return workload.exec(ctx, 1, qn, "")
+			for _, channel := range []string{"test_channel_0", "test_channel_1"} {
+				if err := workload.exec(ctx, 1, qn, channel); err != nil {
+					channelErrs = append(channelErrs, err)
+					return err
+				}
+			}
+			return nil
 		})
 
-		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Return(nil, errors.New("mock error"))
-		assert.Error(t, dr.Run(context.Background()))
-		assert.Equal(t, int64(0), dr.result.DeleteCnt)
+		pks := map[string][][]int64{
+			"test_channel_0": {{0, 1, 2}},
+			"test_channel_1": {{3, 4}, {5, 6}},
+		}
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+
+				for _, data := range pks[in.GetDmlChannels()[0]] {
+					server.Send(&internalpb.RetrieveResults{
+						Status: merr.Success(),
+						Ids: &schemapb.IDs{
+							IdField: &schemapb.IDs_IntId{
+								IntId: &schemapb.LongArray{
+									Data: data,
+								},
+							},
+						},
+					})
+				}
+				server.FinishSend(nil)
+				return client
+			}, nil)
+		var produced []int64
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			for _, msg := range pack.Msgs {
+				produced = append(produced, msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData()...)
+			}
+			return nil, nil
+		})
+
+		err := dr.Run(ctx)
+		assert.ErrorIs(t, err, merr.ErrDeleteRowsExceeded)
+		assert.NotErrorIs(t, err, merr.ErrDeletePartial)
+		assert.Contains(t, err.Error(), "limit=4")
+		assert.Contains(t, err.Error(), "deleted=3")
+		// the rows of the first channel are deleted, the second channel is stopped at the limit
+		assert.Equal(t, int64(3), dr.result.DeleteCnt)
+		assert.ElementsMatch(t, []int64{0, 1, 2}, produced)
+		// the channel is not retried on other replicas
+		assert.False(t, retry.IsRecoverable(channelErrs[0]))
 	})
 
-	t.Run("complex delete query failed", func(t *testing.T) {
+	t.Run("complex delete aborted by schema change", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteChunkSize.Key, "2")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteChunkSize.Key)
 
 		mockMgr := NewMockChannelsMgr(t)
 		qn := mocks.NewMockQueryNodeClient(t)
 		lb := NewMockLBPolicy(t)
 
+		// a field is added after the first chunk is produced
+		alteredSchema := newSchemaInfo(&schemapb.CollectionSchema{
+			Name: collectionName,
+			Fields: append(lo.Map(collSchema.GetFields(), func(field *schemapb.FieldSchema, _ int) *schemapb.FieldSchema {
+				return proto.Clone(field).(*schemapb.FieldSchema)
+			}), &schemapb.FieldSchema{
+				FieldID:  common.StartOfUserFieldID + 2,
+				Name:     "added",
+				DataType: schemapb.DataType_Int64,
+			}),
+		})
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true).Once()
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, alteredSchema.Version(), true)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil)
+		mockCache.EXPECT().GetCollectionSchema(mock.Anything, dbName, collectionName).Return(alteredSchema, nil)
+		globalMetaCache = mockCache
+		defer func() { globalMetaCache = metaCache }()
+
 		dr := deleteRunner{
 			queue:           queue.dmQueue,
 			chMgr:           mockMgr,
@@ -569,8 +2728,8 @@ func TestDeleteRunner_Run(t *testing.T) {
 			collectionID:    collectionID,
 			partitionID:     partitionID,
 			vChannels:       channels,
-			tsoAllocatorIns: tsoAllocator,
 			idAllocator:     idAllocator,
+			tsoAllocatorIns: tsoAllocator,
 			lb:              lb,
 			result: &milvuspb.MutationResult{
 				Status: merr.Success(),
@@ -582,16 +2741,16 @@ func TestDeleteRunner_Run(t *testing.T) {
 				CollectionName: collectionName,
 				PartitionName:  partitionName,
 				DbName:         dbName,
-				Expr:           "pk < 3",
+				Expr:           "pk < 5",
 			},
 		}
 		stream := msgstream.NewMockMsgStream(t)
 		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
 		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
-		stream.EXPECT().Produce(mock.Anything).Return(nil)
-
+		var channelErr error
 		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
-			return workload.exec(ctx, 1, qn, "")
+			channelErr = workload.exec(ctx, 1, qn, "")
+			return channelErr
 		})
 
 		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
@@ -604,32 +2763,54 @@ func TestDeleteRunner_Run(t *testing.T) {
 					Ids: &schemapb.IDs{
 						IdField: &schemapb.IDs_IntId{
 							IntId: &schemapb.LongArray{
-								Data: []int64{0, 1, 2},
+								Data: []int64{0, 1, 2, 3},
 							},
 						},
 					},
 				})
-
-				server.Send(&internalpb.RetrieveResults{
-					Status: merr.Status(errors.New("mock error")),
-				})
+				server.FinishSend(nil)
 				return client
 			}, nil)
+		var produced []int64
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			for _, msg := range pack.Msgs {
+				produced = append(produced, msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData()...)
+			}
+			return nil, nil
+		})
 
-		assert.Error(t, dr.Run(ctx))
+		err := dr.Run(ctx)
+		assert.ErrorIs(t, err, merr.ErrDeleteSchemaChanged)
+		assert.NotErrorIs(t, err, merr.ErrDeletePartial)
+		assert.Contains(t, err.Error(), "deleted=2")
+		// only the chunk produced before the schema change is deleted
+		assert.Equal(t, int64(2), dr.result.DeleteCnt)
+		assert.ElementsMatch(t, []int64{0, 1}, produced)
+		// the channel is not retried on other replicas
+		assert.False(t, retry.IsRecoverable(channelErr))
 	})
 
-	t.Run("complex delete produce failed", func(t *testing.T) {
+	t.Run("complex delete aborted by alias repointed", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
+		paramtable.Get().Save(Params.ProxyCfg.DeleteChunkSize.Key, "2")
+		defer paramtable.Get().Reset(Params.ProxyCfg.DeleteChunkSize.Key)
 
 		mockMgr := NewMockChannelsMgr(t)
 		qn := mocks.NewMockQueryNodeClient(t)
 		lb := NewMockLBPolicy(t)
 
+		// the name is repointed to another collection after the first chunk is produced
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true).Once()
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID+1, schema.Version(), true)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID+1, nil)
+		globalMetaCache = mockCache
+		defer func() { globalMetaCache = metaCache }()
+
 		dr := deleteRunner{
-			chMgr:           mockMgr,
 			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
 			schema:          schema,
 			collectionID:    collectionID,
 			partitionID:     partitionID,
@@ -647,14 +2828,16 @@ func TestDeleteRunner_Run(t *testing.T) {
 				CollectionName: collectionName,
 				PartitionName:  partitionName,
 				DbName:         dbName,
-				Expr:           "pk < 3",
+				Expr:           "pk < 5",
 			},
 		}
 		stream := msgstream.NewMockMsgStream(t)
 		mockMgr.EXPECT().getOrCreateDmlStream(mock.Anything).Return(stream, nil)
 		mockMgr.EXPECT().getChannels(collectionID).Return(channels, nil)
+		var channelErr error
 		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
-			return workload.exec(ctx, 1, qn, "")
+			channelErr = workload.exec(ctx, 1, qn, "")
+			return channelErr
 		})
 
 		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
@@ -667,7 +2850,7 @@ func TestDeleteRunner_Run(t *testing.T) {
 					Ids: &schemapb.IDs{
 						IdField: &schemapb.IDs_IntId{
 							IntId: &schemapb.LongArray{
-								Data: []int64{0, 1, 2},
+								Data: []int64{0, 1, 2, 3},
 							},
 						},
 					},
@@ -675,13 +2858,82 @@ func TestDeleteRunner_Run(t *testing.T) {
 				server.FinishSend(nil)
 				return client
 			}, nil)
-		stream.EXPECT().Produce(mock.Anything).Return(errors.New("mock error"))
+		var produced []int64
+		stream.EXPECT().ProduceMark(mock.Anything).RunAndReturn(func(pack *msgstream.MsgPack) (map[string][]msgstream.MessageID, error) {
+			for _, msg := range pack.Msgs {
+				produced = append(produced, msg.(*msgstream.DeleteMsg).GetPrimaryKeys().GetIntId().GetData()...)
+			}
+			return nil, nil
+		})
 
-		assert.Error(t, dr.Run(ctx))
+		err := dr.Run(ctx)
+		assert.ErrorIs(t, err, merr.ErrCollectionIdentityChanged)
+		assert.NotErrorIs(t, err, merr.ErrDeletePartial)
+		assert.NotErrorIs(t, err, merr.ErrDeleteSchemaChanged)
+		assert.Contains(t, err.Error(), "aborted with 2 rows deleted")
+		// only the chunk produced before the name is repointed is deleted
+		assert.Equal(t, int64(2), dr.result.DeleteCnt)
+		assert.Equal(t, deletePathComplex, dr.path)
+		assert.ElementsMatch(t, []int64{0, 1}, produced)
+		// the channel is not retried on other replicas
+		assert.False(t, retry.IsRecoverable(channelErr))
+	})
+
+	t.Run("complex delete failed after collection dropped", func(t *testing.T) {
+		mockMgr := NewMockChannelsMgr(t)
+		qn := mocks.NewMockQueryNodeClient(t)
+		lb := NewMockLBPolicy(t)
+
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true).Maybe()
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(0, merr.WrapErrCollectionNotFound(collectionName))
+		globalMetaCache = mockCache
+		defer func() { globalMetaCache = metaCache }()
+
+		dr := deleteRunner{
+			idAllocator:     idAllocator,
+			tsoAllocatorIns: tsoAllocator,
+			queue:           queue.dmQueue,
+			chMgr:           mockMgr,
+			schema:          schema,
+			collectionID:    collectionID,
+			partitionID:     partitionID,
+			vChannels:       channels,
+			lb:              lb,
+			result: &milvuspb.MutationResult{
+				Status: merr.Success(),
+				IDs: &schemapb.IDs{
+					IdField: nil,
+				},
+			},
+			req: &milvuspb.DeleteRequest{
+				CollectionName: collectionName,
+				PartitionName:  partitionName,
+				DbName:         dbName,
+				Expr:           "pk < 3",
+			},
+		}
+		lb.EXPECT().Execute(mock.Anything, mock.Anything).Call.Return(func(ctx context.Context, workload CollectionWorkLoad) error {
+			return workload.exec(ctx, 1, qn, "")
+		})
+
+		// the segments are released with the collection
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Return(nil, merr.WrapErrSegmentNotFound(1))
+		err := dr.Run(context.Background())
+		assert.ErrorIs(t, err, merr.ErrCollectionIdentityChanged)
+		assert.Contains(t, err.Error(), merr.ErrSegmentNotFound.Error())
+		assert.False(t, merr.IsRetryableErr(err))
 		assert.Equal(t, int64(0), dr.result.DeleteCnt)
 	})
 
-	t.Run("complex delete success", func(t *testing.T) {
+	schema.Fields[1].IsPartitionKey = true
+	partitionMaps := make(map[string]int64)
+	partitionMaps["test_0"] = 1
+	partitionMaps["test_1"] = 2
+	partitionMaps["test_2"] = 3
+	indexedPartitions := []string{"test_0", "test_1", "test_2"}
+
+	t.Run("complex delete with partitionKey mode success", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -689,16 +2941,29 @@ func TestDeleteRunner_Run(t *testing.T) {
 		qn := mocks.NewMockQueryNodeClient(t)
 		lb := NewMockLBPolicy(t)
 
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil).Maybe()
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true).Maybe()
+		mockCache.EXPECT().GetPartitions(mock.Anything, mock.Anything, mock.Anything).Return(
+			partitionMaps, nil)
+		mockCache.EXPECT().GetCollectionSchema(mock.Anything, mock.Anything, mock.Anything).Return(
+			schema, nil)
+		mockCache.EXPECT().GetPartitionsIndex(mock.Anything, mock.Anything, mock.Anything).
+			Return(indexedPartitions, nil)
+		globalMetaCache = mockCache
+		defer func() { globalMetaCache = metaCache }()
+
 		dr := deleteRunner{
-			queue:           queue.dmQueue,
-			chMgr:           mockMgr,
-			schema:          schema,
-			collectionID:    collectionID,
-			partitionID:     partitionID,
-			vChannels:       channels,
-			idAllocator:     idAllocator,
-			tsoAllocatorIns: tsoAllocator,
-			lb:              lb,
+			queue:            queue.dmQueue,
+			chMgr:            mockMgr,
+			schema:           schema,
+			collectionID:     collectionID,
+			partitionID:      common.InvalidPartitionID,
+			vChannels:        channels,
+			idAllocator:      idAllocator,
+			tsoAllocatorIns:  tsoAllocator,
+			lb:               lb,
+			partitionKeyMode: true,
 			result: &milvuspb.MutationResult{
 				Status: merr.Success(),
 				IDs: &schemapb.IDs{
@@ -707,9 +2972,9 @@ func TestDeleteRunner_Run(t *testing.T) {
 			},
 			req: &milvuspb.DeleteRequest{
 				CollectionName: collectionName,
-				PartitionName:  partitionName,
+				PartitionName:  "",
 				DbName:         dbName,
-				Expr:           "pk < 3",
+				Expr:           "non_pk in [2, 3]",
 			},
 		}
 		stream := msgstream.NewMockMsgStream(t)
@@ -723,7 +2988,7 @@ func TestDeleteRunner_Run(t *testing.T) {
 			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
 				client := streamrpc.NewLocalQueryClient(ctx)
 				server := client.CreateServer()
-
+				assert.Greater(t, len(in.Req.PartitionIDs), 0)
 				server.Send(&internalpb.RetrieveResults{
 					Status: merr.Success(),
 					Ids: &schemapb.IDs{
@@ -737,20 +3002,13 @@ func TestDeleteRunner_Run(t *testing.T) {
 				server.FinishSend(nil)
 				return client
 			}, nil)
-		stream.EXPECT().Produce(mock.Anything).Return(nil)
 
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, nil)
 		assert.NoError(t, dr.Run(ctx))
 		assert.Equal(t, int64(3), dr.result.DeleteCnt)
 	})
 
-	schema.Fields[1].IsPartitionKey = true
-	partitionMaps := make(map[string]int64)
-	partitionMaps["test_0"] = 1
-	partitionMaps["test_1"] = 2
-	partitionMaps["test_2"] = 3
-	indexedPartitions := []string{"test_0", "test_1", "test_2"}
-
-	t.Run("complex delete with partitionKey mode success", func(t *testing.T) {
+	t.Run("complex delete with partition breakdown", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -760,6 +3018,7 @@ func TestDeleteRunner_Run(t *testing.T) {
 
 		mockCache := NewMockCache(t)
 		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil).Maybe()
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true).Maybe()
 		mockCache.EXPECT().GetPartitions(mock.Anything, mock.Anything, mock.Anything).Return(
 			partitionMaps, nil)
 		mockCache.EXPECT().GetCollectionSchema(mock.Anything, mock.Anything, mock.Anything).Return(
@@ -774,7 +3033,7 @@ func TestDeleteRunner_Run(t *testing.T) {
 			chMgr:            mockMgr,
 			schema:           schema,
 			collectionID:     collectionID,
-			partitionID:      int64(-1),
+			partitionID:      common.InvalidPartitionID,
 			vChannels:        channels,
 			idAllocator:      idAllocator,
 			tsoAllocatorIns:  tsoAllocator,
@@ -787,6 +3046,9 @@ func TestDeleteRunner_Run(t *testing.T) {
 				},
 			},
 			req: &milvuspb.DeleteRequest{
+				Base: &commonpb.MsgBase{
+					Properties: map[string]string{DeletePartitionBreakdownKey: "true"},
+				},
 				CollectionName: collectionName,
 				PartitionName:  "",
 				DbName:         dbName,
@@ -800,11 +3062,21 @@ func TestDeleteRunner_Run(t *testing.T) {
 			return workload.exec(ctx, 1, qn, "")
 		})
 
+		partitionKeys := &schemapb.FieldData{
+			FieldId: common.StartOfUserFieldID + 1,
+			Type:    schemapb.DataType_Int64,
+			Field: &schemapb.FieldData_Scalars{
+				Scalars: &schemapb.ScalarField{
+					Data: &schemapb.ScalarField_LongData{LongData: &schemapb.LongArray{Data: []int64{2, 3, 2}}},
+				},
+			},
+		}
 		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).Call.Return(
 			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) querypb.QueryNode_QueryStreamClient {
 				client := streamrpc.NewLocalQueryClient(ctx)
 				server := client.CreateServer()
-				assert.Greater(t, len(in.Req.PartitionIDs), 0)
+				// partition key is retrieved to resolve the partition of rows
+				assert.Contains(t, in.GetReq().GetOutputFieldsId(), int64(common.StartOfUserFieldID+1))
 				server.Send(&internalpb.RetrieveResults{
 					Status: merr.Success(),
 					Ids: &schemapb.IDs{
@@ -814,14 +3086,235 @@ func TestDeleteRunner_Run(t *testing.T) {
 							},
 						},
 					},
+					FieldsData: []*schemapb.FieldData{partitionKeys},
 				})
 				server.FinishSend(nil)
 				return client
 			}, nil)
 
-		stream.EXPECT().Produce(mock.Anything).Return(nil)
+		stream.EXPECT().ProduceMark(mock.Anything).Return(nil, nil)
 		assert.NoError(t, dr.Run(ctx))
 		assert.Equal(t, int64(3), dr.result.DeleteCnt)
+
+		hashValues, err := typeutil.HashKey2Partitions(partitionKeys, indexedPartitions)
+		require.NoError(t, err)
+		expected := make(map[string]int64)
+		for _, idx := range hashValues {
+			expected[indexedPartitions[idx]]++
+		}
+		breakdown := make(map[string]int64)
+		require.NoError(t, json.Unmarshal([]byte(dr.result.GetStatus().GetDetail()), &breakdown))
+		assert.Equal(t, expected, breakdown)
+	})
+}
+
+func TestDeleteRunner_checkSchema(t *testing.T) {
+	ctx := context.Background()
+	dbName, collectionName, collectionID := "test_db", "test_delete", int64(111)
+	fields := func() []*schemapb.FieldSchema {
+		return []*schemapb.FieldSchema{
+			{FieldID: common.StartOfUserFieldID, Name: "pk", IsPrimaryKey: true, DataType: schemapb.DataType_Int64},
+			{FieldID: common.StartOfUserFieldID + 1, Name: "non_pk", DataType: schemapb.DataType_Int64},
+		}
+	}
+	schema := newSchemaInfo(&schemapb.CollectionSchema{Name: collectionName, Fields: fields()})
+	dr := &deleteRunner{
+		req:          &milvuspb.DeleteRequest{DbName: dbName, CollectionName: collectionName},
+		schema:       schema,
+		collectionID: collectionID,
+	}
+	defer func() { globalMetaCache = nil }()
+
+	t.Run("version unchanged", func(t *testing.T) {
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, schema.Version(), true)
+		globalMetaCache = mockCache
+		assert.NoError(t, dr.checkSchema(ctx))
+	})
+
+	t.Run("reloaded without change", func(t *testing.T) {
+		reloaded := newSchemaInfo(&schemapb.CollectionSchema{Name: collectionName, Fields: fields()})
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID, reloaded.Version(), true)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil)
+		mockCache.EXPECT().GetCollectionSchema(mock.Anything, dbName, collectionName).Return(reloaded, nil)
+		globalMetaCache = mockCache
+		assert.NoError(t, dr.checkSchema(ctx))
+	})
+
+	t.Run("field added", func(t *testing.T) {
+		altered := newSchemaInfo(&schemapb.CollectionSchema{Name: collectionName, Fields: append(fields(),
+			&schemapb.FieldSchema{FieldID: common.StartOfUserFieldID + 2, Name: "added", DataType: schemapb.DataType_Int64})})
+		mockCache := NewMockCache(t)
+		// evicted from the cache
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(0, 0, false)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID, nil)
+		mockCache.EXPECT().GetCollectionSchema(mock.Anything, dbName, collectionName).Return(altered, nil)
+		globalMetaCache = mockCache
+		assert.ErrorIs(t, dr.checkSchema(ctx), merr.ErrDeleteSchemaChanged)
+	})
+
+	t.Run("alias repointed", func(t *testing.T) {
+		repointed := newSchemaInfo(&schemapb.CollectionSchema{Name: collectionName, Fields: fields()})
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(collectionID+1, repointed.Version(), true)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(collectionID+1, nil)
+		globalMetaCache = mockCache
+		err := dr.checkSchema(ctx)
+		assert.ErrorIs(t, err, merr.ErrCollectionIdentityChanged)
+		assert.NotErrorIs(t, err, merr.ErrDeleteSchemaChanged)
+		assert.False(t, merr.IsRetryableErr(err))
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(0, 0, false)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(0, merr.WrapErrCollectionNotFound(collectionName))
+		globalMetaCache = mockCache
+		err := dr.checkSchema(ctx)
+		assert.ErrorIs(t, err, merr.ErrCollectionIdentityChanged)
+		assert.Contains(t, err.Error(), "currentCollectionID=0")
+	})
+
+	t.Run("reload failed", func(t *testing.T) {
+		mockCache := NewMockCache(t)
+		mockCache.EXPECT().GetCollectionSchemaVersion(dbName, collectionName).Return(0, 0, false)
+		mockCache.EXPECT().GetCollectionID(mock.Anything, dbName, collectionName).Return(0, errors.New("mock error"))
+		globalMetaCache = mockCache
+		err := dr.checkSchema(ctx)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, merr.ErrDeleteSchemaChanged)
+	})
+}
+
+func TestDeleteRunner_initPartitionBreakdown(t *testing.T) {
+	collSchema := &schemapb.CollectionSchema{
+		Name: "test_delete",
+		Fields: []*schemapb.FieldSchema{
+			{FieldID: common.StartOfUserFieldID, Name: "pk", IsPrimaryKey: true, DataType: schemapb.DataType_Int64},
+			{FieldID: common.StartOfUserFieldID + 1, Name: "non_pk", IsPartitionKey: true, DataType: schemapb.DataType_Int64},
+		},
+	}
+	req := &milvuspb.DeleteRequest{
+		Base: &commonpb.MsgBase{
+			Properties: map[string]string{DeletePartitionBreakdownKey: "true"},
+		},
+		CollectionName: "test_delete",
+		PartitionName:  "test_1",
+		Expr:           "non_pk in [2, 3]",
+	}
+
+	t.Run("not requested", func(t *testing.T) {
+		dr := &deleteRunner{req: &milvuspb.DeleteRequest{}, schema: newSchemaInfo(collSchema), partitionKeyMode: true}
+		assert.NoError(t, dr.initPartitionBreakdown(context.Background()))
+		assert.False(t, dr.partitionBreakdown)
+	})
+
+	t.Run("not partition key mode", func(t *testing.T) {
+		dr := &deleteRunner{req: req, schema: newSchemaInfo(collSchema), partitionID: common.InvalidPartitionID}
+		assert.ErrorIs(t, dr.initPartitionBreakdown(context.Background()), merr.ErrParameterInvalid)
+	})
+
+	t.Run("partition specified", func(t *testing.T) {
+		dr := &deleteRunner{req: req, schema: newSchemaInfo(collSchema), partitionKeyMode: true, partitionID: 2}
+		assert.NoError(t, dr.initPartitionBreakdown(context.Background()))
+		assert.True(t, dr.partitionBreakdown)
+		// all rows are in the partition, no need to retrieve the partition key
+		assert.Nil(t, dr.partitionKeyField)
+
+		partitions, err := dr.resolvePartitions(&internalpb.RetrieveResults{Ids: int64IDs(1, 2)})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"test_1": 2}, dr.countPartitions(partitions))
+	})
+
+	t.Run("partition key not returned", func(t *testing.T) {
+		dr := &deleteRunner{
+			req:                req,
+			partitionBreakdown: true,
+			partitionKeyField:  collSchema.Fields[1],
+			partitionNames:     []string{"test_0", "test_1"},
+		}
+		_, err := dr.resolvePartitions(&internalpb.RetrieveResults{Ids: int64IDs(1, 2)})
+		assert.ErrorIs(t, err, merr.ErrServiceInternal)
+	})
+}
+
+func Test_queryStreamCursor(t *testing.T) {
+	t.Run("not reissued", func(t *testing.T) {
+		cursor := &queryStreamCursor{}
+		ids := int64IDs(1, 2, 3)
+		partitions := []uint32{0, 1, 2}
+		var advanced *schemapb.IDs
+		var advancedPartitions []uint32
+		var err error
+		// no primary key is kept for the retries, the rows are returned as is
+		allocs := testing.AllocsPerRun(100, func() {
+			advanced, advancedPartitions, err = cursor.advance(ids, partitions)
+		})
+		assert.Zero(t, allocs)
+		assert.NoError(t, err)
+		assert.Same(t, ids, advanced)
+		assert.Equal(t, partitions, advancedPartitions)
+		assert.Equal(t, 303, cursor.received)
+		assert.Equal(t, int64(3), cursor.lastIntPK)
+	})
+
+	t.Run("reissued", func(t *testing.T) {
+		cursor := &queryStreamCursor{}
+		_, _, err := cursor.advance(int64IDs(4, 2), nil)
+		assert.NoError(t, err)
+		_, _, err = cursor.advance(int64IDs(), nil)
+		assert.NoError(t, err)
+		_, _, err = cursor.advance(int64IDs(7), nil)
+		assert.NoError(t, err)
+
+		// the rows received are skipped across the results
+		cursor.reissue()
+		ids, partitions, err := cursor.advance(int64IDs(4), []uint32{0})
+		assert.NoError(t, err)
+		assert.Empty(t, ids.GetIntId().GetData())
+		assert.Empty(t, partitions)
+		ids, partitions, err = cursor.advance(int64IDs(2, 7, 1, 5), []uint32{1, 0, 0, 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 5}, ids.GetIntId().GetData())
+		assert.Equal(t, []uint32{0, 1}, partitions)
+		ids, partitions, err = cursor.advance(int64IDs(6), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{6}, ids.GetIntId().GetData())
+		assert.Nil(t, partitions)
+		assert.Equal(t, 6, cursor.received)
+
+		// reissued again
+		cursor.reissue()
+		ids, _, err = cursor.advance(int64IDs(4, 2, 7, 1, 5, 6, 8), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{8}, ids.GetIntId().GetData())
+	})
+
+	t.Run("reissued in another order", func(t *testing.T) {
+		cursor := &queryStreamCursor{}
+		_, _, err := cursor.advance(int64IDs(1, 2), nil)
+		assert.NoError(t, err)
+		cursor.reissue()
+		_, _, err = cursor.advance(int64IDs(2, 1, 3), nil)
+		assert.ErrorIs(t, err, merr.ErrServiceInternal)
+	})
+
+	t.Run("varchar", func(t *testing.T) {
+		strIDs := func(pks ...string) *schemapb.IDs {
+			return &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: pks}}}
+		}
+		cursor := &queryStreamCursor{}
+		_, _, err := cursor.advance(strIDs("a", "b"), nil)
+		assert.NoError(t, err)
+		cursor.reissue()
+		ids, _, err := cursor.advance(strIDs("a", "b", "c"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c"}, ids.GetStrId().GetData())
+
+		cursor.reissue()
+		_, _, err = cursor.advance(strIDs("a", "c", "b"), nil)
+		assert.ErrorIs(t, err, merr.ErrServiceInternal)
 	})
 }
 
@@ -880,7 +3373,7 @@ func TestDeleteRunner_StreamingQueryAndDelteFunc(t *testing.T) {
 			tsoAllocatorIns:  tsoAllocator,
 			idAllocator:      idAllocator,
 			collectionID:     collectionID,
-			partitionID:      int64(-1),
+			partitionID:      common.InvalidPartitionID,
 			vChannels:        channels,
 			partitionKeyMode: true,
 			result: &milvuspb.MutationResult{
@@ -911,7 +3404,7 @@ func TestDeleteRunner_StreamingQueryAndDelteFunc(t *testing.T) {
 			tsoAllocatorIns:  tsoAllocator,
 			idAllocator:      idAllocator,
 			collectionID:     collectionID,
-			partitionID:      int64(-1),
+			partitionID:      common.InvalidPartitionID,
 			vChannels:        channels,
 			partitionKeyMode: true,
 			result: &milvuspb.MutationResult{
@@ -950,7 +3443,7 @@ func TestDeleteRunner_StreamingQueryAndDelteFunc(t *testing.T) {
 			tsoAllocatorIns:  tsoAllocator,
 			idAllocator:      idAllocator,
 			collectionID:     collectionID,
-			partitionID:      int64(-1),
+			partitionID:      common.InvalidPartitionID,
 			vChannels:        channels,
 			partitionKeyMode: true,
 			result: &milvuspb.MutationResult{
@@ -984,3 +3477,354 @@ func TestDeleteRunner_StreamingQueryAndDelteFunc(t *testing.T) {
 		assert.Error(t, queryFunc(ctx, 1, qn, ""))
 	})
 }
+
+func TestDeleteRunner_StreamingQueryPartitionIDs(t *testing.T) {
+	collSchema := &schemapb.CollectionSchema{
+		Name: "test_delete",
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_Int64,
+			},
+			{
+				FieldID:  common.StartOfUserFieldID + 1,
+				Name:     "non_pk",
+				DataType: schemapb.DataType_Int64,
+			},
+		},
+	}
+	schema := newSchemaInfo(collSchema)
+
+	run := func(t *testing.T, partitionID int64, scope querypb.DataScope) *querypb.QueryRequest {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		dr := deleteRunner{
+			schema:       schema,
+			collectionID: 111,
+			partitionID:  partitionID,
+			scope:        scope,
+			req: &milvuspb.DeleteRequest{
+				CollectionName: "test_delete",
+				Expr:           "non_pk > 1",
+			},
+		}
+		var queryReq *querypb.QueryRequest
+		qn := mocks.NewMockQueryNodeClient(t)
+		qn.EXPECT().QueryStream(mock.Anything, mock.Anything).RunAndReturn(
+			func(ctx context.Context, in *querypb.QueryRequest, opts ...grpc.CallOption) (querypb.QueryNode_QueryStreamClient, error) {
+				queryReq = in
+				client := streamrpc.NewLocalQueryClient(ctx)
+				server := client.CreateServer()
+				server.FinishSend(nil)
+				return client, nil
+			})
+
+		plan, err := planparserv2.CreateRetrievePlan(collSchema, dr.req.GetExpr())
+		assert.NoError(t, err)
+		assert.NoError(t, dr.getStreamingQueryAndDelteFunc(plan)(ctx, 1, qn, "test_channel"))
+		return queryReq
+	}
+
+	t.Run("named partition", func(t *testing.T) {
+		assert.Equal(t, []int64{222}, run(t, 222, querypb.DataScope_All).GetReq().GetPartitionIDs())
+	})
+
+	t.Run("no partition", func(t *testing.T) {
+		assert.Empty(t, run(t, common.InvalidPartitionID, querypb.DataScope_All).GetReq().GetPartitionIDs())
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		assert.Equal(t, querypb.DataScope_All, run(t, common.InvalidPartitionID, querypb.DataScope_All).GetScope())
+		assert.Equal(t, querypb.DataScope_Historical, run(t, common.InvalidPartitionID, querypb.DataScope_Historical).GetScope())
+		assert.Equal(t, querypb.DataScope_Streaming, run(t, common.InvalidPartitionID, querypb.DataScope_Streaming).GetScope())
+	})
+}
+
+func Test_splitDeleteIDs(t *testing.T) {
+	intIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2, 3, 4, 5}}},
+	}
+	chunks := splitDeleteIDs(intIDs, 2)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, []int64{1, 2}, chunks[0].GetIntId().GetData())
+	assert.Equal(t, []int64{3, 4}, chunks[1].GetIntId().GetData())
+	assert.Equal(t, []int64{5}, chunks[2].GetIntId().GetData())
+
+	// no split needed
+	assert.Equal(t, []*schemapb.IDs{intIDs}, splitDeleteIDs(intIDs, 5))
+	assert.Equal(t, []*schemapb.IDs{intIDs}, splitDeleteIDs(intIDs, 0))
+
+	strIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b", "c"}}},
+	}
+	chunks = splitDeleteIDs(strIDs, 2)
+	assert.Len(t, chunks, 2)
+	assert.Equal(t, []string{"a", "b"}, chunks[0].GetStrId().GetData())
+	assert.Equal(t, []string{"c"}, chunks[1].GetStrId().GetData())
+}
+
+// legacyRepack is the per-row repack deleteTask.repack replaced, kept to verify the msgs are identical.
+func legacyRepack(dt *deleteTask, hashValues []uint32) map[uint32]*msgstream.DeleteMsg {
+	result := make(map[uint32]*msgstream.DeleteMsg)
+	for index, key := range hashValues {
+		curMsg, ok := result[key]
+		if !ok {
+			curMsg = &msgstream.DeleteMsg{
+				DeleteRequest: msgpb.DeleteRequest{PrimaryKeys: &schemapb.IDs{}},
+			}
+			curMsg.ShardName = dt.vChannels[key]
+			result[key] = curMsg
+		}
+		curMsg.HashValues = append(curMsg.HashValues, hashValues[index])
+		curMsg.Timestamps = append(curMsg.Timestamps, dt.ts)
+		typeutil.AppendIDs(curMsg.PrimaryKeys, dt.primaryKeys, index)
+		curMsg.NumRows++
+	}
+	return result
+}
+
+func newRepackDeleteTask(primaryKeys *schemapb.IDs) *deleteTask {
+	return &deleteTask{
+		req:          &milvuspb.DeleteRequest{CollectionName: "test_delete"},
+		idAllocator:  &mockIDAllocatorInterface{},
+		collectionID: 111,
+		partitionID:  common.InvalidPartitionID,
+		vChannels:    []vChan{"ch0", "ch1", "ch2", "ch3"},
+		primaryKeys:  primaryKeys,
+		ts:           100,
+	}
+}
+
+func TestDeleteTask_repack(t *testing.T) {
+	intIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: make([]int64, 0, 1000)}},
+	}
+	strIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, 1000)}},
+	}
+	for i := 0; i < 1000; i++ {
+		intIDs.GetIntId().Data = append(intIDs.GetIntId().Data, int64(i))
+		strIDs.GetStrId().Data = append(strIDs.GetStrId().Data, fmt.Sprintf("pk_%d", i))
+	}
+
+	for _, ids := range []*schemapb.IDs{intIDs, strIDs} {
+		dt := newRepackDeleteTask(ids)
+		hashValues := typeutil.HashPK2Channels(ids, dt.vChannels)
+		// duplicated primary keys of the same channel
+		hashValues = append(hashValues, 1, 1, 1)
+		typeutil.AppendIDsRange(ids, ids, []int{0, 1, 2})
+
+		expected := legacyRepack(dt, hashValues)
+		actual, err := dt.repack(context.Background(), hashValues)
+		assert.NoError(t, err)
+		assert.Equal(t, len(expected), len(actual))
+		for key, msg := range expected {
+			assert.Equal(t, msg.GetShardName(), actual[key].GetShardName())
+			assert.Equal(t, msg.HashValues, actual[key].HashValues)
+			assert.Equal(t, msg.GetTimestamps(), actual[key].GetTimestamps())
+			assert.Equal(t, msg.GetNumRows(), actual[key].GetNumRows())
+			assert.Equal(t, msg.GetPrimaryKeys().GetIntId().GetData(), actual[key].GetPrimaryKeys().GetIntId().GetData())
+			assert.Equal(t, msg.GetPrimaryKeys().GetStrId().GetData(), actual[key].GetPrimaryKeys().GetStrId().GetData())
+		}
+	}
+
+	// the primary keys of task are not touched
+	dt := newRepackDeleteTask(strIDs)
+	expected := append([]string{}, strIDs.GetStrId().GetData()...)
+	_, err := dt.repack(context.Background(), typeutil.HashPK2Channels(strIDs, dt.vChannels))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, strIDs.GetStrId().GetData())
+}
+
+func BenchmarkDeleteTask_repack(b *testing.B) {
+	paramtable.Init()
+	ids := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, 1000000)}},
+	}
+	for i := 0; i < 1000000; i++ {
+		// 64 bytes keys
+		ids.GetStrId().Data = append(ids.GetStrId().Data, fmt.Sprintf("primary_key_%052d", i))
+	}
+	dt := newRepackDeleteTask(ids)
+
+	b.Run("legacy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			legacyRepack(dt, typeutil.HashPK2Channels(ids, dt.vChannels))
+		}
+	})
+	b.Run("repack", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := dt.repack(context.Background(), typeutil.HashPK2Channels(ids, dt.vChannels)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestDeleteTask_NewDeleteMsgTraceContext(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagator)
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	dt := deleteTask{
+		req: &milvuspb.DeleteRequest{
+			CollectionName: "test_delete",
+		},
+		idAllocator:  &mockIDAllocatorInterface{},
+		collectionID: 111,
+		partitionID:  common.InvalidPartitionID,
+	}
+	msg, err := dt.newDeleteMsg(ctx, &schemapb.IDs{}, nil)
+	assert.NoError(t, err)
+
+	bytes, err := msg.Marshal(msg)
+	assert.NoError(t, err)
+	unmarshaled, err := (&msgstream.DeleteMsg{}).Unmarshal(bytes)
+	assert.NoError(t, err)
+	deleteMsg := unmarshaled.(*msgstream.DeleteMsg)
+
+	recovered := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier(deleteMsg.GetBase().GetProperties()))
+	assert.Equal(t, spanCtx.TraceID(), trace.SpanContextFromContext(recovered).TraceID())
+	assert.Equal(t, spanCtx.SpanID(), trace.SpanContextFromContext(recovered).SpanID())
+}
+
+func Test_validateDeletePlanFields(t *testing.T) {
+	schema := &schemapb.CollectionSchema{
+		Name: "test_delete",
+		Fields: []*schemapb.FieldSchema{
+			{
+				FieldID:      common.StartOfUserFieldID,
+				Name:         "pk",
+				IsPrimaryKey: true,
+				DataType:     schemapb.DataType_Int64,
+			},
+			{
+				FieldID:  common.StartOfUserFieldID + 1,
+				Name:     "vector",
+				DataType: schemapb.DataType_FloatVector,
+			},
+		},
+	}
+	pkColumn := &planpb.ColumnInfo{
+		FieldId:      common.StartOfUserFieldID,
+		DataType:     schemapb.DataType_Int64,
+		IsPrimaryKey: true,
+	}
+	vectorColumn := &planpb.ColumnInfo{
+		FieldId:  common.StartOfUserFieldID + 1,
+		DataType: schemapb.DataType_FloatVector,
+	}
+	newPlan := func(expr *planpb.Expr) *planpb.PlanNode {
+		return &planpb.PlanNode{
+			Node: &planpb.PlanNode_Query{Query: &planpb.QueryPlanNode{Predicates: expr}},
+		}
+	}
+
+	t.Run("scalar predicate", func(t *testing.T) {
+		plan, err := planparserv2.CreateRetrievePlan(schema, "pk > 1 and pk < 10")
+		assert.NoError(t, err)
+		assert.NoError(t, validateDeletePlanFields(schema, plan))
+	})
+
+	t.Run("vector predicate", func(t *testing.T) {
+		plan := newPlan(&planpb.Expr{
+			Expr: &planpb.Expr_CompareExpr{CompareExpr: &planpb.CompareExpr{
+				LeftColumnInfo:  vectorColumn,
+				RightColumnInfo: vectorColumn,
+				Op:              planpb.OpType_Equal,
+			}},
+		})
+		err := validateDeletePlanFields(schema, plan)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "vector field vector")
+	})
+
+	t.Run("mixed scalar and vector predicate", func(t *testing.T) {
+		plan := newPlan(&planpb.Expr{
+			Expr: &planpb.Expr_BinaryExpr{BinaryExpr: &planpb.BinaryExpr{
+				Op: planpb.BinaryExpr_LogicalAnd,
+				Left: &planpb.Expr{
+					Expr: &planpb.Expr_UnaryRangeExpr{UnaryRangeExpr: &planpb.UnaryRangeExpr{
+						ColumnInfo: pkColumn,
+						Op:         planpb.OpType_GreaterThan,
+						Value:      &planpb.GenericValue{Val: &planpb.GenericValue_Int64Val{Int64Val: 1}},
+					}},
+				},
+				Right: &planpb.Expr{
+					Expr: &planpb.Expr_UnaryExpr{UnaryExpr: &planpb.UnaryExpr{
+						Op: planpb.UnaryExpr_Not,
+						Child: &planpb.Expr{
+							Expr: &planpb.Expr_ExistsExpr{ExistsExpr: &planpb.ExistsExpr{Info: vectorColumn}},
+						},
+					}},
+				},
+			}},
+		})
+		err := validateDeletePlanFields(schema, plan)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.Contains(t, err.Error(), "delete only supports scalar predicates")
+	})
+}
+
+func TestDeleteRunner_logSlowDelete(t *testing.T) {
+	paramtable.Init()
+	collectionName := "test_slow_delete"
+	dr := &deleteRunner{
+		req: &milvuspb.DeleteRequest{
+			CollectionName: collectionName,
+			Expr:           strings.Repeat("pk > 1 and ", 100) + "pk < 10",
+		},
+		result: &milvuspb.MutationResult{DeleteCnt: 3},
+		tr:     timerecord.NewTimeRecorder("delete"),
+	}
+	counter := metrics.ProxySlowDeleteCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), collectionName)
+	time.Sleep(10 * time.Millisecond)
+
+	t.Run("below threshold", func(t *testing.T) {
+		before := testutil.ToFloat64(counter)
+		dr.logSlowDelete(context.Background(), nil)
+		assert.Equal(t, before, testutil.ToFloat64(counter))
+	})
+
+	t.Run("exceed threshold", func(t *testing.T) {
+		paramtable.Get().Save(Params.ProxyCfg.SlowDeleteThreshold.Key, "1")
+		defer paramtable.Get().Reset(Params.ProxyCfg.SlowDeleteThreshold.Key)
+
+		before := testutil.ToFloat64(counter)
+		dr.logSlowDelete(context.Background(), nil)
+		assert.Equal(t, before+1, testutil.ToFloat64(counter))
+	})
+}
+
+func TestDeleteRunner_accessDeleteInfo(t *testing.T) {
+	t.Run("not executed", func(t *testing.T) {
+		dr := &deleteRunner{req: &milvuspb.DeleteRequest{PartitionName: "p0"}}
+		assert.Equal(t, &accesslog.DeleteInfo{}, dr.accessDeleteInfo())
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		dr := &deleteRunner{
+			req:          &milvuspb.DeleteRequest{},
+			result:       &milvuspb.MutationResult{DeleteCnt: 3},
+			path:         deletePathComplex,
+			partitionCnt: map[string]int64{"p2": 1, "p1": 0, "p0": 2},
+		}
+		assert.Equal(t, &accesslog.DeleteInfo{
+			PartitionNames: []string{"p0", "p2"},
+			Path:           deletePathComplex,
+			DeleteCnt:      3,
+		}, dr.accessDeleteInfo())
+	})
+}
@@ -2,9 +2,9 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
-	"sort"
 	"strconv"
 
 	"github.com/cockroachdb/errors"
@@ -49,8 +49,11 @@ type hybridSearchTask struct {
 
 	collectionID UniqueID
 
-	multipleRecallResults *typeutil.ConcurrentSet[*milvuspb.SearchResults]
+	// the results of the requests in order, see scoreFusion
+	multipleRecallResults []*milvuspb.SearchResults
 	reScorers             []reScorer
+	// nil unless explained, see ExplainRankParamsKey
+	explainer *scoreExplainer
 }
 
 func (t *hybridSearchTask) PreExecute(ctx context.Context) error {
@@ -128,33 +131,40 @@ func (t *hybridSearchTask) Execute(ctx context.Context) error {
 	tr := timerecord.NewTimeRecorder(fmt.Sprintf("proxy execute hybrid search %d", t.ID()))
 	defer tr.CtxElapse(ctx, "done")
 
+	var err error
+	t.reScorers, err = NewReScorer(t.request.GetRequests(), t.request.GetRankParams(), t.schema.CollectionSchema)
+	if err != nil {
+		log.Info("generate reScorer failed", zap.Any("rank params", t.request.GetRankParams()), zap.Error(err))
+		return err
+	}
+	if explainRank(t.request.GetRankParams()) {
+		t.explainer = &scoreExplainer{}
+	}
+
 	futures := make([]*conc.Future[*milvuspb.SearchResults], len(t.request.Requests))
 	for index := range t.request.Requests {
 		searchReq := t.request.Requests[index]
+		// only the fields the scorer reads
+		outputFields := scorerOutputFields(t.reScorers[index])
 		future := conc.Go(func() (*milvuspb.SearchResults, error) {
 			searchReq.TravelTimestamp = t.request.GetTravelTimestamp()
 			searchReq.GuaranteeTimestamp = t.request.GetGuaranteeTimestamp()
 			searchReq.NotReturnAllMeta = t.request.GetNotReturnAllMeta()
 			searchReq.ConsistencyLevel = t.request.GetConsistencyLevel()
 			searchReq.UseDefaultConsistency = t.request.GetUseDefaultConsistency()
-			searchReq.OutputFields = nil
+			searchReq.OutputFields = outputFields
 
 			return t.node.Search(ctx, searchReq)
 		})
 		futures[index] = future
 	}
 
-	err := conc.AwaitAll(futures...)
+	err = conc.AwaitAll(futures...)
 	if err != nil {
 		return err
 	}
 
-	t.reScorers, err = NewReScorer(t.request.GetRequests(), t.request.GetRankParams())
-	if err != nil {
-		log.Info("generate reScorer failed", zap.Any("rank params", t.request.GetRankParams()), zap.Error(err))
-		return err
-	}
-	t.multipleRecallResults = typeutil.NewConcurrentSet[*milvuspb.SearchResults]()
+	t.multipleRecallResults = make([]*milvuspb.SearchResults, 0, len(futures))
 	for i, future := range futures {
 		err = future.Err()
 		if err != nil {
@@ -168,8 +178,23 @@ func (t *hybridSearchTask) Execute(ctx context.Context) error {
 			return merr.Error(result.GetStatus())
 		}
 
-		t.reScorers[i].reScore(result)
-		t.multipleRecallResults.Insert(result)
+		if t.explainer != nil {
+			t.explainer.addRawScores(result.GetResults().GetScores())
+		}
+		err = t.reScorers[i].reScore(result)
+		if err != nil {
+			log.Info("rescore search result failed", zap.Int("request", i), zap.Error(err))
+			return err
+		}
+		kept, err := t.reScorers[i].boundScores(result)
+		if err != nil {
+			log.Info("bound scores of search result failed", zap.Int("request", i), zap.Error(err))
+			return err
+		}
+		if t.explainer != nil && kept != nil {
+			t.explainer.keepRawScores(i, kept)
+		}
+		t.multipleRecallResults = append(t.multipleRecallResults, result)
 	}
 
 	log.Debug("hybrid search execute done.")
@@ -258,7 +283,9 @@ func (t *hybridSearchTask) PostExecute(ctx context.Context) error {
 	t.result, err = rankSearchResultData(ctx, 1,
 		rankParams,
 		primaryFieldSchema.GetDataType(),
-		t.multipleRecallResults.Collect())
+		t.multipleRecallResults,
+		scoreFusion(t.reScorers),
+		t.explainer)
 	if err != nil {
 		log.Warn("rank search result failed", zap.Error(err))
 		return err
@@ -306,6 +333,8 @@ func rankSearchResultData(ctx context.Context,
 	params *rankParams,
 	pkType schemapb.DataType,
 	searchResults []*milvuspb.SearchResults,
+	fusion scoreFuser,
+	explainer *scoreExplainer,
 ) (*milvuspb.SearchResults, error) {
 	tr := timerecord.NewTimeRecorder("rankSearchResultData")
 	defer func() {
@@ -357,18 +386,64 @@ func rankSearchResultData(ctx context.Context,
 		accumulatedScores[i] = make(map[interface{}]float32)
 	}
 
-	for _, result := range searchResults {
+	// the scores of each request by the ids, to fuse them by the fuser rather than summing them, or to explain them
+	type fusedScores struct {
+		scores   []float32
+		recalled []bool
+		// the raw scores before rescored, only if explained
+		raw []float32
+	}
+	var requestScores []map[interface{}]*fusedScores
+	if fusion != nil || explainer != nil {
+		requestScores = make([]map[interface{}]*fusedScores, nq)
+		for i := int64(0); i < nq; i++ {
+			requestScores[i] = make(map[interface{}]*fusedScores)
+		}
+	}
+
+	for r, result := range searchResults {
 		scores := result.GetResults().GetScores()
 		start := int64(0)
 		for i := int64(0); i < nq; i++ {
 			realTopk := result.GetResults().Topks[i]
 			for j := start; j < start+realTopk; j++ {
 				id := typeutil.GetPK(result.GetResults().GetIds(), j)
-				accumulatedScores[i][id] += scores[j]
+				if fusion == nil {
+					accumulatedScores[i][id] += scores[j]
+					if explainer == nil {
+						continue
+					}
+				}
+				// the score of the request not recalling the id is 0
+				fused, ok := requestScores[i][id]
+				if !ok {
+					fused = &fusedScores{
+						scores:   make([]float32, len(searchResults)),
+						recalled: make([]bool, len(searchResults)),
+					}
+					if explainer != nil {
+						fused.raw = make([]float32, len(searchResults))
+					}
+					requestScores[i][id] = fused
+				}
+				fused.scores[r] = scores[j]
+				fused.recalled[r] = true
+				if explainer != nil {
+					fused.raw[r] = explainer.rawScore(r, j)
+				}
 			}
 			start += realTopk
 		}
 	}
+	for i := 0; fusion != nil && i < len(requestScores); i++ {
+		for id, fused := range requestScores[i] {
+			score, err := fusion.fuse(fused.scores, fused.recalled)
+			if err != nil {
+				return nil, err
+			}
+			accumulatedScores[i][id] = score
+		}
+	}
 
 	for i := int64(0); i < nq; i++ {
 		idSet := accumulatedScores[i]
@@ -382,10 +457,7 @@ func rankSearchResultData(ctx context.Context,
 			continue
 		}
 
-		// sort id by score
-		sort.Slice(keys, func(i, j int) bool {
-			return idSet[keys[i]] >= idSet[keys[j]]
-		})
+		sortFusedPKs(keys, idSet)
 
 		if int64(len(keys)) > topk {
 			keys = keys[:topk]
@@ -402,9 +474,21 @@ func rankSearchResultData(ctx context.Context,
 				score = float32(math.Floor(float64(score)*multiplier+0.5) / multiplier)
 			}
 			ret.Results.Scores = append(ret.Results.Scores, score)
+			if explainer != nil {
+				fused := requestScores[i][keys[index]]
+				explainer.explain(keys[index], idSet[keys[index]], fused.raw, fused.scores, fused.recalled)
+			}
 		}
 	}
 
+	if explainer != nil {
+		explainer.Fusion = fusionName(fusion)
+		detail, err := json.Marshal(explainer)
+		if err != nil {
+			return nil, err
+		}
+		ret.Status.Detail = string(detail)
+	}
 	return ret, nil
 }
 
@@ -320,7 +320,7 @@ func TestHybridSearchTask_PostExecute(t *testing.T) {
 				CollectionName: collectionName,
 				RankParams:     rankParams,
 			},
-			multipleRecallResults: typeutil.NewConcurrentSet[*milvuspb.SearchResults](),
+			multipleRecallResults: []*milvuspb.SearchResults{},
 		}
 
 		err = qt.PostExecute(context.TODO())
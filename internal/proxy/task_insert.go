@@ -28,7 +28,7 @@ type insertTask struct {
 	ctx       context.Context
 
 	result        *milvuspb.MutationResult
-	idAllocator   *allocator.IDAllocator
+	idAllocator   allocator.Interface
 	segIDAssigner *segIDAssigner
 	chMgr         channelsMgr
 	chTicker      channelsTimeTicker
@@ -36,6 +36,8 @@ type insertTask struct {
 	pChannels     []pChan
 	schema        *schemapb.CollectionSchema
 	partitionKeys *schemapb.FieldData
+	// routes the primary keys to the channels, the default one if nil
+	shardHash typeutil.ShardHash
 }
 
 // TraceCtx returns insertTask context
@@ -89,6 +91,14 @@ func (it *insertTask) getChannels() []pChan {
 	return it.pChannels
 }
 
+func (it *insertTask) rowsHint() int64 {
+	return int64(it.insertMsg.NumRows)
+}
+
+func (it *insertTask) collectionKey() string {
+	return dmlCollectionKey(it.insertMsg.GetDbName(), it.insertMsg.GetCollectionName())
+}
+
 func (it *insertTask) OnEnqueue() error {
 	return nil
 }
@@ -117,6 +127,11 @@ func (it *insertTask) PreExecute(ctx context.Context) error {
 		return err
 	}
 	it.schema = schema.CollectionSchema
+	it.shardHash, err = schema.GetShardHash()
+	if err != nil {
+		log.Warn("get shard hash of collection failed", zap.String("collectionName", collectionName), zap.Error(err))
+		return err
+	}
 
 	rowNums := uint32(it.insertMsg.NRows())
 	// set insertTask.rowIDs
@@ -248,9 +263,9 @@ func (it *insertTask) Execute(ctx context.Context) error {
 	// assign segmentID for insert data and repack data by segmentID
 	var msgPack *msgstream.MsgPack
 	if it.partitionKeys == nil {
-		msgPack, err = repackInsertData(it.TraceCtx(), channelNames, it.insertMsg, it.result, it.idAllocator, it.segIDAssigner)
+		msgPack, err = repackInsertData(it.TraceCtx(), channelNames, it.shardHash, it.insertMsg, it.result, it.idAllocator, it.segIDAssigner)
 	} else {
-		msgPack, err = repackInsertDataWithPartitionKey(it.TraceCtx(), channelNames, it.partitionKeys, it.insertMsg, it.result, it.idAllocator, it.segIDAssigner)
+		msgPack, err = repackInsertDataWithPartitionKey(it.TraceCtx(), channelNames, it.shardHash, it.partitionKeys, it.insertMsg, it.result, it.idAllocator, it.segIDAssigner)
 	}
 	if err != nil {
 		log.Warn("assign segmentID and repack insert data failed", zap.Error(err))
@@ -0,0 +1,170 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// maxRetainedTasks bounds the finished tasks retained, in case of a long retention under heavy traffic.
+const maxRetainedTasks = 4096
+
+// taskStateInfo is a transition of the state of a task, listed by the debug endpoint.
+type taskStateInfo struct {
+	State string    `json:"state"`
+	Time  time.Time `json:"time"`
+}
+
+// taskInfo is the state of a task, listed by the debug endpoint.
+type taskInfo struct {
+	ID    UniqueID `json:"id"`
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	State string   `json:"state"`
+	// since the task was created, and since it moved to the current state
+	Age      string          `json:"age"`
+	StateAge string          `json:"state_age"`
+	States   []taskStateInfo `json:"states"`
+
+	age time.Duration
+}
+
+type finishedTask struct {
+	t          task
+	finishedAt time.Time
+}
+
+// taskRegistry tracks the tasks enqueued to the task scheduler, so the wedged ones could be told
+// whether they're waiting in the queue, executing or finished but not waited by the callers.
+// The finished tasks are retained for proxy.taskRegistry.retention, the recent failures could be listed too.
+type taskRegistry struct {
+	mu       sync.Mutex
+	inflight map[task]struct{}
+	// the finished tasks in the order of finishing
+	finished *list.List
+}
+
+func newTaskRegistry() *taskRegistry {
+	return &taskRegistry{
+		inflight: make(map[task]struct{}),
+		finished: list.New(),
+	}
+}
+
+// register tracks the task enqueued.
+func (r *taskRegistry) register(t task) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inflight[t] = struct{}{}
+}
+
+// unregister stops tracking the task failed to enqueue.
+func (r *taskRegistry) unregister(t task) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.inflight, t)
+}
+
+// finish moves the task to the finished ones.
+func (r *taskRegistry) finish(t task) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.inflight[t]; !ok {
+		return
+	}
+	delete(r.inflight, t)
+	now := time.Now()
+	r.finished.PushBack(&finishedTask{t: t, finishedAt: now})
+	r.purgeLocked(now)
+}
+
+// purgeLocked drops the finished tasks retained longer than proxy.taskRegistry.retention.
+func (r *taskRegistry) purgeLocked(now time.Time) {
+	retention := paramtable.Get().ProxyCfg.TaskRegistryRetention.GetAsDuration(time.Second)
+	for e := r.finished.Front(); e != nil; e = r.finished.Front() {
+		if r.finished.Len() <= maxRetainedTasks && now.Sub(e.Value.(*finishedTask).finishedAt) < retention {
+			return
+		}
+		r.finished.Remove(e)
+	}
+}
+
+// list returns the tasks in flight and the ones finished within the retention, the oldest first.
+func (r *taskRegistry) list() []taskInfo {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	now := time.Now()
+	r.purgeLocked(now)
+	tasks := make([]task, 0, len(r.inflight)+r.finished.Len())
+	for t := range r.inflight {
+		tasks = append(tasks, t)
+	}
+	for e := r.finished.Front(); e != nil; e = e.Next() {
+		tasks = append(tasks, e.Value.(*finishedTask).t)
+	}
+	r.mu.Unlock()
+
+	infos := make([]taskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		infos = append(infos, newTaskInfo(t, now))
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].age > infos[j].age
+	})
+	return infos
+}
+
+func newTaskInfo(t task, now time.Time) taskInfo {
+	info := taskInfo{
+		ID:   t.ID(),
+		Name: t.Name(),
+		Type: t.Type().String(),
+	}
+	c, ok := t.(Condition)
+	if !ok {
+		return info
+	}
+	transitions := c.StateTransitions()
+	first, last := transitions[0], transitions[len(transitions)-1]
+	info.State = last.State.String()
+	info.age = now.Sub(first.Time)
+	info.Age = info.age.String()
+	info.StateAge = now.Sub(last.Time).String()
+	for _, transition := range transitions {
+		info.States = append(info.States, taskStateInfo{State: transition.State.String(), Time: transition.Time})
+	}
+	return info
+}
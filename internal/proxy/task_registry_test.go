@@ -0,0 +1,182 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
+)
+
+// blockedTask executes until it's released, and fails with err then.
+type blockedTask struct {
+	*mockDqlTask
+	release chan struct{}
+	err     error
+}
+
+func (t *blockedTask) Execute(ctx context.Context) error {
+	<-t.release
+	return t.err
+}
+
+func newBlockedTask(err error) *blockedTask {
+	return &blockedTask{
+		mockDqlTask: newDefaultMockDqlTask(),
+		release:     make(chan struct{}),
+		err:         err,
+	}
+}
+
+func TestTaskRegistry(t *testing.T) {
+	paramtable.Init()
+	params := paramtable.Get()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sched, err := newTaskScheduler(ctx, &mockTsoAllocator{}, nil)
+	require.NoError(t, err)
+	require.NoError(t, sched.Start())
+	defer sched.Close()
+
+	find := func(name string) (taskInfo, bool) {
+		return lo.Find(sched.registry.list(), func(info taskInfo) bool { return info.Name == name })
+	}
+	states := func(info taskInfo) []string {
+		return lo.Map(info.States, func(state taskStateInfo, _ int) string { return state.State })
+	}
+
+	succeeded := newBlockedTask(nil)
+	failed := newBlockedTask(errors.New("mock error"))
+	require.NoError(t, sched.dqQueue.Enqueue(succeeded))
+	require.NoError(t, sched.dqQueue.Enqueue(failed))
+
+	t.Run("in flight", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			return succeeded.GetState() == TaskStateExecuting && failed.GetState() == TaskStateExecuting
+		}, 5*time.Second, 10*time.Millisecond)
+		info, ok := find(succeeded.Name())
+		require.True(t, ok)
+		assert.Equal(t, succeeded.ID(), info.ID)
+		assert.Equal(t, succeeded.Type().String(), info.Type)
+		assert.Equal(t, "Executing", info.State)
+		assert.NotEmpty(t, info.Age)
+		assert.NotEmpty(t, info.StateAge)
+		assert.Equal(t, []string{"Pending", "Scheduled", "Executing"}, states(info))
+
+		// the oldest first
+		infos := sched.registry.list()
+		for i := 1; i < len(infos); i++ {
+			assert.GreaterOrEqual(t, infos[i-1].age, infos[i].age)
+		}
+	})
+
+	t.Run("finished", func(t *testing.T) {
+		close(succeeded.release)
+		close(failed.release)
+		assert.NoError(t, succeeded.WaitToFinish())
+		assert.Error(t, failed.WaitToFinish())
+
+		assert.Eventually(t, func() bool {
+			info, ok := find(succeeded.Name())
+			return ok && info.State == "Done"
+		}, 5*time.Second, 10*time.Millisecond)
+		info, ok := find(succeeded.Name())
+		require.True(t, ok)
+		assert.Equal(t, []string{"Pending", "Scheduled", "Executing", "Done"}, states(info))
+		assert.Eventually(t, func() bool {
+			info, ok := find(failed.Name())
+			return ok && info.State == "Failed"
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		params.Save(params.ProxyCfg.TaskRegistryRetention.Key, "0.1")
+		defer params.Reset(params.ProxyCfg.TaskRegistryRetention.Key)
+		time.Sleep(200 * time.Millisecond)
+		_, ok := find(succeeded.Name())
+		assert.False(t, ok)
+		_, ok = find(failed.Name())
+		assert.False(t, ok)
+
+		// the ones in flight are kept however long
+		blocked := newBlockedTask(nil)
+		require.NoError(t, sched.dqQueue.Enqueue(blocked))
+		time.Sleep(200 * time.Millisecond)
+		_, ok = find(blocked.Name())
+		assert.True(t, ok)
+		close(blocked.release)
+		assert.NoError(t, blocked.WaitToFinish())
+	})
+
+	t.Run("failed to enqueue", func(t *testing.T) {
+		queue := sched.dqQueue
+		maxTaskNum := queue.getMaxTaskNum()
+		queue.setMaxTaskNum(0)
+		defer queue.setMaxTaskNum(maxTaskNum)
+
+		task := newDefaultMockDqlTask()
+		assert.Error(t, queue.Enqueue(task))
+		_, ok := find(task.Name())
+		assert.False(t, ok)
+	})
+
+	t.Run("delete task", func(t *testing.T) {
+		dt := &deleteTask{Condition: NewTaskCondition(ctx)}
+		assert.NoError(t, dt.OnEnqueue())
+		assert.Equal(t, TaskStateScheduled, dt.GetState())
+		assert.NoError(t, dt.PreExecute(ctx))
+		assert.Equal(t, TaskStateExecuting, dt.GetState())
+		assert.NoError(t, dt.PostExecute(ctx))
+		assert.Equal(t, TaskStateDone, dt.GetState())
+
+		// skipped without a condition
+		assert.NoError(t, (&deleteTask{}).OnEnqueue())
+	})
+
+	t.Run("debug endpoint", func(t *testing.T) {
+		blocked := newBlockedTask(nil)
+		require.NoError(t, sched.dqQueue.Enqueue(blocked))
+		defer func() {
+			close(blocked.release)
+			blocked.WaitToFinish()
+		}()
+
+		req, err := http.NewRequest(http.MethodGet, mgrRouteTasks, nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		(&Proxy{sched: sched}).ListTasks(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var infos []taskInfo
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &infos))
+		assert.True(t, lo.ContainsBy(infos, func(info taskInfo) bool { return info.Name == blocked.Name() }))
+
+		recorder = httptest.NewRecorder()
+		(&Proxy{}).ListTasks(recorder, req)
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+}
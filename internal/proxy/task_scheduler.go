@@ -19,6 +19,8 @@ package proxy
 import (
 	"container/list"
 	"context"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,7 +28,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
+	"github.com/milvus-io/milvus/pkg/util"
 	"github.com/milvus-io/milvus/pkg/util/conc"
 	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
@@ -65,6 +69,8 @@ type baseTaskQueue struct {
 	utBufChan chan int // to block scheduler
 
 	tsoAllocatorIns tsoAllocator
+	// tracks the tasks enqueued, shared by the queues of the scheduler, nil if not scheduled by one
+	registry *taskRegistry
 }
 
 func (queue *baseTaskQueue) utChan() <-chan int {
@@ -179,7 +185,16 @@ func (queue *baseTaskQueue) Enqueue(t task) error {
 	// we always use same msg id and ts for now.
 	t.SetID(UniqueID(ts))
 
-	return queue.addUnissuedTask(t)
+	if c, ok := t.(Condition); ok {
+		c.markEnqueued()
+	}
+	// registered before added, as the task may be finished right after
+	queue.registry.register(t)
+	err = queue.addUnissuedTask(t)
+	if err != nil {
+		queue.registry.unregister(t)
+	}
+	return err
 }
 
 func (queue *baseTaskQueue) setMaxTaskNum(num int64) {
@@ -218,11 +233,96 @@ type pChanStatInfo struct {
 	tsSet map[Timestamp]struct{}
 }
 
+// sizedDmlTask is implemented by the dml tasks hinting their sizes,
+// the small ones could be issued ahead of the large ones of other collections, see dmTaskQueue.
+type sizedDmlTask interface {
+	dmlTask
+	// rowsHint returns the number of rows mutated by the task
+	rowsHint() int64
+	// collectionKey identifies the collection of the task, the tasks of the same collection are issued in timestamp order
+	collectionKey() string
+}
+
+func dmlCollectionKey(dbName, collectionName string) string {
+	if dbName == "" {
+		dbName = util.DefaultDBName
+	}
+	return dbName + "." + collectionName
+}
+
+// splitDmlCollectionKey splits the key of dmlCollectionKey into the database and collection names.
+func splitDmlCollectionKey(key string) (string, string) {
+	dbName, collectionName, _ := strings.Cut(key, ".")
+	return dbName, collectionName
+}
+
+// dmlFairWeights looks up the weights of the collections in the fair scheduling,
+// proxy.dmlQueue.fairScheduling.weights is parsed again only if it changes.
+type dmlFairWeights struct {
+	raw     string
+	weights map[string]int
+}
+
+// get returns the weight of the collection key, or else the one of its database, 1 if neither is listed.
+func (w *dmlFairWeights) get(key string) int {
+	raw := Params.ProxyCfg.DmlQueueFairWeights.GetValue()
+	if w.weights == nil || raw != w.raw {
+		w.raw, w.weights = raw, parseDmlFairWeights(raw)
+	}
+	if weight, ok := w.weights[key]; ok {
+		return weight
+	}
+	dbName, _ := splitDmlCollectionKey(key)
+	if weight, ok := w.weights[dbName]; ok {
+		return weight
+	}
+	return 1
+}
+
+// parseDmlFairWeights parses the weights like "db1:4,db2.collection1:8", the invalid ones are ignored.
+func parseDmlFairWeights(raw string) map[string]int {
+	weights := make(map[string]int)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(item, ":")
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if name = strings.TrimSpace(name); name == "" || err != nil || weight <= 0 {
+			log.Warn("ignore invalid weight of dml fair scheduling", zap.String("weight", item))
+			continue
+		}
+		weights[name] = weight
+	}
+	return weights
+}
+
+// dmTaskQueue issues the dml tasks in two lanes, the small tasks could be issued ahead of the large ones
+// queued before them, unless the large ones are of the same collection,
+// to keep the timestamp order of the tasks of each collection.
+// The tasks not hinting their sizes are large, and no task is issued ahead of them.
+// If proxy.dmlQueue.fairScheduling.enabled, the collections take turns to issue their tasks instead,
+// weighted by proxy.dmlQueue.fairScheduling.weights, see selectFairTask.
 type dmTaskQueue struct {
 	*baseTaskQueue
 
 	statsLock            sync.RWMutex
 	pChanStatisticsInfos map[pChan]*pChanStatInfo
+
+	// the time the unissued tasks were enqueued, protected by utLock
+	enqueueTimes map[task]time.Time
+	// the number of small tasks issued ahead of the large ones in a row, protected by utLock
+	smallIssued int
+
+	// the number of tasks each collection could still issue in the current round of the fair scheduling,
+	// the ones not in it have issued none, protected by utLock
+	fairQuotas  map[string]int
+	fairWeights dmlFairWeights
+
+	// the rows hinted by the tasks queued or executing, and the sum of them, protected by statsLock
+	pendingTasks map[task]int64
+	pendingRows  int64
 }
 
 func (queue *dmTaskQueue) Enqueue(t task) error {
@@ -241,10 +341,25 @@ func (queue *dmTaskQueue) Enqueue(t task) error {
 	// 2. enqueue dml task
 	queue.statsLock.Lock()
 	defer queue.statsLock.Unlock()
+	rows := int64(0)
+	if sized, ok := t.(sizedDmlTask); ok {
+		rows = sized.rowsHint()
+	}
+	if err := queue.checkPendingLimits(rows); err != nil {
+		return err
+	}
+	queue.utLock.Lock()
+	queue.enqueueTimes[t] = time.Now()
+	queue.utLock.Unlock()
 	err = queue.baseTaskQueue.Enqueue(t)
 	if err != nil {
+		queue.utLock.Lock()
+		delete(queue.enqueueTimes, t)
+		queue.utLock.Unlock()
 		return err
 	}
+	queue.pendingTasks[t] = rows
+	queue.pendingRows += rows
 	// 3. commit will use pChannels got previously when preAdding and will definitely succeed
 	pChannels := dmt.getChannels()
 	queue.commitPChanStats(dmt, pChannels)
@@ -254,6 +369,149 @@ func (queue *dmTaskQueue) Enqueue(t task) error {
 	return nil
 }
 
+// checkPendingLimits rejects the task of rows if the pending tasks exceed proxy.dmlQueue.maxPendingTasks
+// or proxy.dmlQueue.maxPendingRows, so that the overload is pushed back to the clients rather than
+// piling up the tasks waiting longer and longer. statsLock must be held.
+func (queue *dmTaskQueue) checkPendingLimits(rows int64) error {
+	pending := int64(len(queue.pendingTasks))
+	if maxTasks := Params.ProxyCfg.DmlQueueMaxPendingTasks.GetAsInt64(); maxTasks > 0 && pending >= maxTasks {
+		metrics.ProxyDmlQueueRejectedCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), metrics.PendingTasksLimitLabel).Inc()
+		return merr.WrapErrServiceOverloaded(pending, maxTasks, "too many pending mutations")
+	}
+	// a task of rows beyond the limit is still accepted once the others drain
+	if maxRows := Params.ProxyCfg.DmlQueueMaxPendingRows.GetAsInt64(); maxRows > 0 && pending > 0 && queue.pendingRows+rows > maxRows {
+		metrics.ProxyDmlQueueRejectedCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), metrics.PendingRowsLimitLabel).Inc()
+		return merr.WrapErrServiceOverloaded(queue.pendingRows+rows, maxRows, "too many rows of pending mutations")
+	}
+	return nil
+}
+
+// isLargeTask returns whether the task is in the lane of large tasks.
+func isLargeTask(t task, largeTaskRows int64) bool {
+	sized, ok := t.(sizedDmlTask)
+	return !ok || sized.rowsHint() >= largeTaskRows
+}
+
+// selectUnissuedTask returns the task to issue, and whether it's a small task issued ahead of the large ones.
+func (queue *dmTaskQueue) selectUnissuedTask() (*list.Element, bool) {
+	if Params.ProxyCfg.DmlQueueFairScheduling.GetAsBool() {
+		return queue.selectFairTask(), false
+	}
+	front := queue.unissuedTasks.Front()
+	largeTaskRows := Params.ProxyCfg.DmlQueueLargeTaskRows.GetAsInt64()
+	if front == nil || largeTaskRows <= 0 || !isLargeTask(front.Value.(task), largeTaskRows) {
+		return front, false
+	}
+	// issue the large task once enough small tasks are issued ahead of it, so that it's not starved either
+	if queue.smallIssued >= Params.ProxyCfg.DmlQueueSmallTaskWeight.GetAsInt() {
+		return front, false
+	}
+
+	// the collections of the large tasks queued ahead, the small tasks of them have to wait
+	blocked := make(map[string]struct{})
+	for e := front; e != nil; e = e.Next() {
+		t, ok := e.Value.(sizedDmlTask)
+		if !ok {
+			break
+		}
+		if isLargeTask(t, largeTaskRows) {
+			blocked[t.collectionKey()] = struct{}{}
+			continue
+		}
+		if _, ok := blocked[t.collectionKey()]; !ok {
+			return e, true
+		}
+	}
+	return front, false
+}
+
+// selectFairTask returns the first task of the collections having quota in the current round,
+// or the first task to start a new round if none has. Each collection could issue as many tasks
+// as its weight each round, so the collections with tasks queued issue them in turn.
+// Same as the lanes, no task is issued ahead of the ones not hinting their sizes.
+func (queue *dmTaskQueue) selectFairTask() *list.Element {
+	front := queue.unissuedTasks.Front()
+	for e := front; e != nil; e = e.Next() {
+		t, ok := e.Value.(sizedDmlTask)
+		if !ok {
+			break
+		}
+		if quota, ok := queue.fairQuotas[t.collectionKey()]; !ok || quota > 0 {
+			return e
+		}
+	}
+	return front
+}
+
+// consumeFairQuota consumes the quota of the collection issuing a task, starting a new round if it has none.
+func (queue *dmTaskQueue) consumeFairQuota(key string) {
+	quota, ok := queue.fairQuotas[key]
+	if ok && quota <= 0 {
+		queue.fairQuotas = make(map[string]int)
+		ok = false
+	}
+	if !ok {
+		quota = queue.fairWeights.get(key)
+	}
+	queue.fairQuotas[key] = quota - 1
+	if queue.unissuedTasks.Len() == 0 {
+		queue.fairQuotas = make(map[string]int)
+	}
+}
+
+func (queue *dmTaskQueue) FrontUnissuedTask() task {
+	queue.utLock.RLock()
+	defer queue.utLock.RUnlock()
+
+	e, _ := queue.selectUnissuedTask()
+	if e == nil {
+		return nil
+	}
+	return e.Value.(task)
+}
+
+func (queue *dmTaskQueue) PopUnissuedTask() task {
+	queue.utLock.Lock()
+	defer queue.utLock.Unlock()
+
+	e, aheadOfLarge := queue.selectUnissuedTask()
+	if e == nil {
+		return nil
+	}
+	queue.unissuedTasks.Remove(e)
+	t := e.Value.(task)
+
+	lane := metrics.LargeTaskLaneLabel
+	if aheadOfLarge {
+		queue.smallIssued++
+		lane = metrics.SmallTaskLaneLabel
+	} else {
+		queue.smallIssued = 0
+		if largeTaskRows := Params.ProxyCfg.DmlQueueLargeTaskRows.GetAsInt64(); largeTaskRows > 0 && !isLargeTask(t, largeTaskRows) {
+			lane = metrics.SmallTaskLaneLabel
+		}
+	}
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	if sized, ok := t.(sizedDmlTask); ok {
+		if Params.ProxyCfg.DmlQueueFairScheduling.GetAsBool() {
+			queue.consumeFairQuota(sized.collectionKey())
+		}
+		_, collectionName := splitDmlCollectionKey(sized.collectionKey())
+		metrics.ProxyDmlQueueScheduledCount.WithLabelValues(nodeID, collectionName).Inc()
+	}
+	if enqueueTime, ok := queue.enqueueTimes[t]; ok {
+		delete(queue.enqueueTimes, t)
+		metrics.ProxyDmlQueueWaitLatency.WithLabelValues(nodeID, lane).
+			Observe(float64(time.Since(enqueueTime).Milliseconds()))
+	}
+	if aheadOfLarge {
+		log.Debug("Proxy dmTaskQueue issues small task ahead of large ones",
+			zap.Int64("taskID", t.ID()),
+			zap.Int("smallIssued", queue.smallIssued))
+	}
+	return t
+}
+
 func (queue *dmTaskQueue) PopActiveTask(taskID UniqueID) task {
 	queue.atLock.Lock()
 	defer queue.atLock.Unlock()
@@ -265,6 +523,8 @@ func (queue *dmTaskQueue) PopActiveTask(taskID UniqueID) task {
 		delete(queue.activeTasks, taskID)
 		log.Debug("Proxy dmTaskQueue popPChanStats", zap.Int64("taskID", t.ID()))
 		queue.popPChanStats(t)
+		queue.pendingRows -= queue.pendingTasks[t]
+		delete(queue.pendingTasks, t)
 	} else {
 		log.Warn("Proxy task not in active task list!", zap.Int64("taskID", taskID))
 	}
@@ -360,6 +620,9 @@ func newDmTaskQueue(tsoAllocatorIns tsoAllocator) *dmTaskQueue {
 	return &dmTaskQueue{
 		baseTaskQueue:        newBaseTaskQueue(tsoAllocatorIns),
 		pChanStatisticsInfos: make(map[pChan]*pChanStatInfo),
+		enqueueTimes:         make(map[task]time.Time),
+		pendingTasks:         make(map[task]int64),
+		fairQuotas:           make(map[string]int),
 	}
 }
 
@@ -383,6 +646,9 @@ type taskScheduler struct {
 	cancel context.CancelFunc
 
 	msFactory msgstream.Factory
+
+	// tracks the tasks enqueued to the queues, see taskRegistry
+	registry *taskRegistry
 }
 
 type schedOpt func(*taskScheduler)
@@ -404,6 +670,12 @@ func newTaskScheduler(ctx context.Context,
 
 	s.dcQueue = newDdTaskQueue(tsoAllocatorIns)
 
+	s.registry = newTaskRegistry()
+	s.ddQueue.registry = s.registry
+	s.dmQueue.registry = s.registry
+	s.dqQueue.registry = s.registry
+	s.dcQueue.registry = s.registry
+
 	for _, opt := range opts {
 		opt(s)
 	}
@@ -432,6 +704,9 @@ func (sched *taskScheduler) processTask(t task, q taskQueue) {
 	defer span.End()
 
 	span.AddEvent("scheduler process AddActiveTask")
+	if c, ok := t.(Condition); ok {
+		c.markExecuting()
+	}
 	q.AddActiveTask(t)
 
 	defer func() {
@@ -444,6 +719,7 @@ func (sched *taskScheduler) processTask(t task, q taskQueue) {
 
 	defer func() {
 		t.Notify(err)
+		sched.registry.finish(t)
 	}()
 	if err != nil {
 		span.RecordError(err)
@@ -20,17 +20,22 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/v2/msgpb"
+	"github.com/milvus-io/milvus/pkg/metrics"
 	"github.com/milvus-io/milvus/pkg/mq/msgstream"
 	"github.com/milvus-io/milvus/pkg/util/funcutil"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/paramtable"
 )
 
 func TestBaseTaskQueue(t *testing.T) {
@@ -213,8 +218,10 @@ func TestDmTaskQueue_Basic(t *testing.T) {
 
 	// task enqueue, only one task in queue
 
+	assert.True(t, st.EnqueueTime().IsZero())
 	err = queue.Enqueue(st)
 	assert.NoError(t, err)
+	assert.False(t, st.EnqueueTime().IsZero())
 
 	assert.False(t, queue.utEmpty())
 	assert.False(t, queue.utFull())
@@ -578,7 +585,8 @@ func TestTaskScheduler_concurrentPushAndPop(t *testing.T) {
 		chMgr := NewMockChannelsMgr(t)
 		chMgr.EXPECT().getChannels(mock.Anything).Return(channels, nil)
 		it := &insertTask{
-			ctx: context.Background(),
+			Condition: NewTaskCondition(context.Background()),
+			ctx:       context.Background(),
 			insertMsg: &msgstream.InsertMsg{
 				InsertRequest: msgpb.InsertRequest{
 					Base:           &commonpb.MsgBase{},
@@ -602,3 +610,324 @@ func TestTaskScheduler_concurrentPushAndPop(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+type mockSizedDmlTask struct {
+	*mockDmlTask
+	rows       int64
+	collection string
+}
+
+func (m *mockSizedDmlTask) rowsHint() int64 {
+	return m.rows
+}
+
+func (m *mockSizedDmlTask) collectionKey() string {
+	return m.collection
+}
+
+func newMockSizedDmlTask(collection string, rows int64) *mockSizedDmlTask {
+	return &mockSizedDmlTask{
+		mockDmlTask: newDefaultMockDmlTask(),
+		rows:        rows,
+		collection:  collection,
+	}
+}
+
+// popAllUnissuedTasks pops the unissued tasks in the order they are issued
+func popAllUnissuedTasks(t *testing.T, queue *dmTaskQueue) []task {
+	tasks := make([]task, 0)
+	for !queue.utEmpty() {
+		front := queue.FrontUnissuedTask()
+		popped := queue.PopUnissuedTask()
+		assert.Same(t, front, popped)
+		tasks = append(tasks, popped)
+	}
+	return tasks
+}
+
+// assertTimestampOrder asserts the tasks of each collection are issued in timestamp order
+func assertTimestampOrder(t *testing.T, tasks []task) {
+	lastTs := make(map[string]Timestamp)
+	for _, issued := range tasks {
+		sized, ok := issued.(sizedDmlTask)
+		if !ok {
+			continue
+		}
+		assert.Greater(t, issued.BeginTs(), lastTs[sized.collectionKey()])
+		lastTs[sized.collectionKey()] = issued.BeginTs()
+	}
+}
+
+func TestDmTaskQueue_Lanes(t *testing.T) {
+	Params.Save(Params.ProxyCfg.DmlQueueLargeTaskRows.Key, "100")
+	defer Params.Reset(Params.ProxyCfg.DmlQueueLargeTaskRows.Key)
+	Params.Save(Params.ProxyCfg.DmlQueueSmallTaskWeight.Key, "2")
+	defer Params.Reset(Params.ProxyCfg.DmlQueueSmallTaskWeight.Key)
+
+	enqueue := func(queue *dmTaskQueue, tasks ...task) {
+		for _, task := range tasks {
+			assert.NoError(t, queue.Enqueue(task))
+		}
+	}
+
+	t.Run("small tasks ahead of large ones", func(t *testing.T) {
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		large1 := newMockSizedDmlTask("a", 1000)
+		large2 := newMockSizedDmlTask("a", 1000)
+		small1 := newMockSizedDmlTask("b", 1)
+		small2 := newMockSizedDmlTask("a", 1)
+		small3 := newMockSizedDmlTask("b", 1)
+		small4 := newMockSizedDmlTask("c", 1)
+		large3 := newMockSizedDmlTask("b", 1000)
+		small5 := newMockSizedDmlTask("b", 1)
+		enqueue(queue, large1, large2, small1, small2, small3, small4, large3, small5)
+
+		tasks := popAllUnissuedTasks(t, queue)
+		// small2 and small5 wait for the large tasks of their collections,
+		// and the large tasks are issued once 2 small tasks are issued ahead of them
+		assert.Equal(t, []task{small1, small3, large1, small4, large2, small2, large3, small5}, tasks)
+		assertTimestampOrder(t, tasks)
+		assert.Empty(t, queue.enqueueTimes)
+	})
+
+	t.Run("lanes disabled", func(t *testing.T) {
+		Params.Save(Params.ProxyCfg.DmlQueueLargeTaskRows.Key, "0")
+		defer Params.Save(Params.ProxyCfg.DmlQueueLargeTaskRows.Key, "100")
+
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		large := newMockSizedDmlTask("a", 1000)
+		small := newMockSizedDmlTask("b", 1)
+		enqueue(queue, large, small)
+		assert.Equal(t, []task{large, small}, popAllUnissuedTasks(t, queue))
+	})
+
+	t.Run("no task ahead of unsized ones", func(t *testing.T) {
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		unsized := newDefaultMockDmlTask()
+		small := newMockSizedDmlTask("b", 1)
+		enqueue(queue, unsized, small)
+		assert.Equal(t, []task{unsized, small}, popAllUnissuedTasks(t, queue))
+	})
+
+	t.Run("random tasks", func(t *testing.T) {
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		fifoPositions := make(map[task]int)
+		for i := 0; i < 200; i++ {
+			task := newMockSizedDmlTask(fmt.Sprintf("col-%d", rand.Intn(4)), rand.Int63n(200))
+			enqueue(queue, task)
+			fifoPositions[task] = i
+		}
+
+		tasks := popAllUnissuedTasks(t, queue)
+		assert.Len(t, tasks, 200)
+		assertTimestampOrder(t, tasks)
+
+		// the small tasks are issued no later than they would be in timestamp order
+		smallDelay := 0
+		for i, issued := range tasks {
+			if issued.(sizedDmlTask).rowsHint() < 100 {
+				smallDelay += i - fifoPositions[issued]
+			}
+		}
+		assert.LessOrEqual(t, smallDelay, 0)
+	})
+}
+
+func TestDmTaskQueue_PendingLimits(t *testing.T) {
+	rejected := func(limit string) float64 {
+		return testutil.ToFloat64(metrics.ProxyDmlQueueRejectedCount.WithLabelValues(strconv.FormatInt(paramtable.GetNodeID(), 10), limit))
+	}
+	// issue is what the scheduler does before executing a task, the tasks issued are stalled until finish
+	issue := func(queue *dmTaskQueue) task {
+		issued := queue.PopUnissuedTask()
+		queue.AddActiveTask(issued)
+		return issued
+	}
+	finish := func(queue *dmTaskQueue, tasks ...task) {
+		for _, task := range tasks {
+			queue.PopActiveTask(task.ID())
+		}
+	}
+
+	t.Run("pending tasks", func(t *testing.T) {
+		Params.Save(Params.ProxyCfg.DmlQueueMaxPendingTasks.Key, "3")
+		defer Params.Reset(Params.ProxyCfg.DmlQueueMaxPendingTasks.Key)
+		before := rejected(metrics.PendingTasksLimitLabel)
+
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, queue.Enqueue(newMockSizedDmlTask("a", 1)))
+		}
+		executing := []task{issue(queue), issue(queue)}
+
+		// both the queued and the executing ones are pending
+		err := queue.Enqueue(newMockSizedDmlTask("a", 1))
+		assert.ErrorIs(t, err, merr.ErrServiceOverloaded)
+		assert.Contains(t, err.Error(), "too many pending mutations")
+		assert.Equal(t, commonpb.ErrorCode_RateLimit, merr.Status(err).GetErrorCode())
+		assert.Equal(t, before+1, rejected(metrics.PendingTasksLimitLabel))
+		// rejected before being queued
+		assert.Len(t, queue.pendingTasks, 3)
+		assert.Len(t, queue.enqueueTimes, 1)
+		assert.Equal(t, 1, queue.unissuedTasks.Len())
+
+		// recovers as the tasks drain
+		finish(queue, executing[0])
+		assert.NoError(t, queue.Enqueue(newMockSizedDmlTask("a", 1)))
+		assert.ErrorIs(t, queue.Enqueue(newMockSizedDmlTask("a", 1)), merr.ErrServiceOverloaded)
+		finish(queue, executing[1], issue(queue), issue(queue))
+		assert.Empty(t, queue.pendingTasks)
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, queue.Enqueue(newDefaultMockDmlTask()))
+		}
+		assert.Equal(t, before+2, rejected(metrics.PendingTasksLimitLabel))
+	})
+
+	t.Run("pending rows", func(t *testing.T) {
+		Params.Save(Params.ProxyCfg.DmlQueueMaxPendingRows.Key, "100")
+		defer Params.Reset(Params.ProxyCfg.DmlQueueMaxPendingRows.Key)
+		before := rejected(metrics.PendingRowsLimitLabel)
+
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		// accepted even if it's beyond the limit, as no other task is pending
+		large := newMockSizedDmlTask("a", 1000)
+		assert.NoError(t, queue.Enqueue(large))
+		assert.ErrorIs(t, queue.Enqueue(newMockSizedDmlTask("a", 1)), merr.ErrServiceOverloaded)
+		assert.ErrorIs(t, queue.Enqueue(newDefaultMockDmlTask()), merr.ErrServiceOverloaded)
+		assert.Equal(t, before+2, rejected(metrics.PendingRowsLimitLabel))
+
+		finish(queue, issue(queue))
+		assert.Zero(t, queue.pendingRows)
+		assert.NoError(t, queue.Enqueue(newMockSizedDmlTask("a", 60)))
+		assert.ErrorIs(t, queue.Enqueue(newMockSizedDmlTask("a", 50)), merr.ErrServiceOverloaded)
+		assert.NoError(t, queue.Enqueue(newMockSizedDmlTask("a", 40)))
+		// the unsized tasks hint no rows
+		assert.NoError(t, queue.Enqueue(newDefaultMockDmlTask()))
+		assert.EqualValues(t, 100, queue.pendingRows)
+		assert.Len(t, queue.pendingTasks, 3)
+		assert.Equal(t, before+3, rejected(metrics.PendingRowsLimitLabel))
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, queue.Enqueue(newMockSizedDmlTask("a", 1000)))
+		}
+		assert.Len(t, queue.pendingTasks, 100)
+		assert.EqualValues(t, 100000, queue.pendingRows)
+	})
+}
+
+func TestDmTaskQueue_FairScheduling(t *testing.T) {
+	Params.Save(Params.ProxyCfg.DmlQueueFairScheduling.Key, "true")
+	defer Params.Reset(Params.ProxyCfg.DmlQueueFairScheduling.Key)
+
+	nodeID := strconv.FormatInt(paramtable.GetNodeID(), 10)
+	enqueue := func(queue *dmTaskQueue, collection string, n int) {
+		for i := 0; i < n; i++ {
+			assert.NoError(t, queue.Enqueue(newMockSizedDmlTask(collection, 1)))
+		}
+	}
+	collections := func(tasks []task) []string {
+		keys := make([]string, 0, len(tasks))
+		for _, issued := range tasks {
+			_, collectionName := splitDmlCollectionKey(issued.(sizedDmlTask).collectionKey())
+			keys = append(keys, collectionName)
+		}
+		return keys
+	}
+
+	t.Run("interleaved backlogs", func(t *testing.T) {
+		a, b := "a_"+funcutil.GenRandomStr(), "b_"+funcutil.GenRandomStr()
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		// the backlog of a is queued before the one of b
+		enqueue(queue, "db."+a, 20)
+		enqueue(queue, "db."+b, 5)
+
+		tasks := popAllUnissuedTasks(t, queue)
+		assert.Len(t, tasks, 25)
+		assertTimestampOrder(t, tasks)
+		// b is not starved by the backlog of a
+		expected := []string{a, b, a, b, a, b, a, b, a, b}
+		for i := 0; i < 15; i++ {
+			expected = append(expected, a)
+		}
+		assert.Equal(t, expected, collections(tasks))
+		assert.Empty(t, queue.fairQuotas)
+
+		assert.Equal(t, float64(20), testutil.ToFloat64(metrics.ProxyDmlQueueScheduledCount.WithLabelValues(nodeID, a)))
+		assert.Equal(t, float64(5), testutil.ToFloat64(metrics.ProxyDmlQueueScheduledCount.WithLabelValues(nodeID, b)))
+		metrics.CleanupCollectionMetrics(paramtable.GetNodeID(), a)
+		assert.Zero(t, testutil.ToFloat64(metrics.ProxyDmlQueueScheduledCount.WithLabelValues(nodeID, a)))
+	})
+
+	t.Run("weights", func(t *testing.T) {
+		Params.Save(Params.ProxyCfg.DmlQueueFairWeights.Key, "db2:3,db2.c:2")
+		defer Params.Reset(Params.ProxyCfg.DmlQueueFairWeights.Key)
+
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		enqueue(queue, "db1.a", 4)
+		enqueue(queue, "db2.b", 8)
+		tasks := popAllUnissuedTasks(t, queue)
+		assertTimestampOrder(t, tasks)
+		assert.Equal(t, []string{"a", "b", "b", "b", "a", "b", "b", "b", "a", "b", "b", "a"}, collections(tasks))
+
+		// the weight of the collection overrides the one of its database
+		enqueue(queue, "db1.a", 4)
+		enqueue(queue, "db2.c", 4)
+		assert.Equal(t, []string{"a", "c", "c", "a", "c", "c", "a", "a"}, collections(popAllUnissuedTasks(t, queue)))
+	})
+
+	t.Run("newcomer", func(t *testing.T) {
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		enqueue(queue, "db.a", 10)
+		tasks := []task{queue.PopUnissuedTask(), queue.PopUnissuedTask(), queue.PopUnissuedTask()}
+		// the collection coming later has no credit of the rounds before
+		enqueue(queue, "db.b", 3)
+		tasks = append(tasks, popAllUnissuedTasks(t, queue)...)
+		assertTimestampOrder(t, tasks)
+		assert.Equal(t, []string{"a", "a", "a", "a", "b", "a", "b", "a", "b", "a", "a", "a", "a"}, collections(tasks))
+	})
+
+	t.Run("no task ahead of unsized ones", func(t *testing.T) {
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		a1 := newMockSizedDmlTask("db.a", 1)
+		a2 := newMockSizedDmlTask("db.a", 1)
+		unsized := newDefaultMockDmlTask()
+		b := newMockSizedDmlTask("db.b", 1)
+		for _, task := range []task{a1, a2, unsized, b} {
+			assert.NoError(t, queue.Enqueue(task))
+		}
+		assert.Equal(t, []task{a1, a2, unsized, b}, popAllUnissuedTasks(t, queue))
+	})
+
+	t.Run("random tasks", func(t *testing.T) {
+		queue := newDmTaskQueue(newMockTsoAllocator())
+		pending := make(map[string]int)
+		for i := 0; i < 200; i++ {
+			collection := fmt.Sprintf("db.col-%d", rand.Intn(4))
+			enqueue(queue, collection, 1)
+			pending[collection]++
+		}
+
+		tasks := popAllUnissuedTasks(t, queue)
+		assert.Len(t, tasks, 200)
+		assertTimestampOrder(t, tasks)
+		// each round issues a task of every collection with tasks queued
+		issued := make(map[string]int)
+		for _, task := range tasks {
+			collection := task.(sizedDmlTask).collectionKey()
+			issued[collection]++
+			for other := range pending {
+				if issued[other] < pending[other] {
+					assert.LessOrEqual(t, issued[collection]-issued[other], 1)
+				}
+			}
+		}
+	})
+}
+
+func TestParseDmlFairWeights(t *testing.T) {
+	assert.Equal(t, map[string]int{"db1": 4, "db2.c": 8}, parseDmlFairWeights(" db1:4, db2.c : 8,bad,x:0,:3,y:-1,z:1.5"))
+	assert.Empty(t, parseDmlFairWeights(""))
+}
@@ -907,6 +907,53 @@ func TestCreateCollectionTask(t *testing.T) {
 		}
 	})
 
+	t.Run("shard hash", func(t *testing.T) {
+		task := &createCollectionTask{
+			Condition: NewTaskCondition(ctx),
+			CreateCollectionRequest: &milvuspb.CreateCollectionRequest{
+				Base:           &commonpb.MsgBase{},
+				DbName:         dbName,
+				CollectionName: collectionName,
+				Schema:         marshaledSchema,
+				ShardsNum:      shardsNum,
+				Properties: []*commonpb.KeyValuePair{
+					{Key: common.CollectionShardHashKey, Value: typeutil.Murmur3ShardHash},
+				},
+			},
+			ctx:       ctx,
+			rootCoord: rc,
+		}
+		assert.NoError(t, task.PreExecute(ctx))
+
+		task.Properties[0].Value = "xxhash"
+		err := task.PreExecute(ctx)
+		assert.ErrorIs(t, err, merr.ErrParameterInvalid)
+		assert.ErrorContains(t, err, "unknown shard hash xxhash")
+
+		// the default one is declared if not specified
+		task.Properties = nil
+		assert.NoError(t, task.PreExecute(ctx))
+		assert.Equal(t, typeutil.DefaultShardHash, common.GetShardHashName(task.GetProperties()...))
+		assert.NoError(t, task.PreExecute(ctx))
+		assert.Len(t, task.GetProperties(), 1)
+
+		alterTask := &alterCollectionTask{
+			Condition: NewTaskCondition(ctx),
+			AlterCollectionRequest: &milvuspb.AlterCollectionRequest{
+				Base:           &commonpb.MsgBase{},
+				CollectionName: collectionName,
+				Properties: []*commonpb.KeyValuePair{
+					{Key: common.CollectionTTLConfigKey, Value: "10"},
+				},
+			},
+			ctx:       ctx,
+			rootCoord: rc,
+		}
+		assert.NoError(t, alterTask.PreExecute(ctx))
+		alterTask.Properties = append(alterTask.Properties, &commonpb.KeyValuePair{Key: common.CollectionShardHashKey, Value: typeutil.CRC32ShardHash})
+		assert.ErrorIs(t, alterTask.PreExecute(ctx), merr.ErrParameterInvalid)
+	})
+
 	t.Run("specify dynamic field", func(t *testing.T) {
 		dynamicField := &schemapb.FieldSchema{
 			Name:      "json",
@@ -52,7 +52,7 @@ type upsertTask struct {
 	timestamps       []uint64
 	rowIDs           []int64
 	result           *milvuspb.MutationResult
-	idAllocator      *allocator.IDAllocator
+	idAllocator      allocator.Interface
 	segIDAssigner    *segIDAssigner
 	collectionID     UniqueID
 	chMgr            channelsMgr
@@ -62,6 +62,8 @@ type upsertTask struct {
 	schema           *schemaInfo
 	partitionKeyMode bool
 	partitionKeys    *schemapb.FieldData
+	// routes the primary keys to the channels, the default one if nil
+	shardHash typeutil.ShardHash
 }
 
 // TraceCtx returns upsertTask context
@@ -132,6 +134,14 @@ func (it *upsertTask) getChannels() []pChan {
 	return it.pChannels
 }
 
+func (it *upsertTask) rowsHint() int64 {
+	return int64(it.req.GetNumRows())
+}
+
+func (it *upsertTask) collectionKey() string {
+	return dmlCollectionKey(it.req.GetDbName(), it.req.GetCollectionName())
+}
+
 func (it *upsertTask) OnEnqueue() error {
 	return nil
 }
@@ -291,6 +301,13 @@ func (it *upsertTask) PreExecute(ctx context.Context) error {
 		return err
 	}
 	it.schema = schema
+	it.shardHash, err = schema.GetShardHash()
+	if err != nil {
+		log.Warn("Failed to get shard hash of collection",
+			zap.String("collectionName", collectionName),
+			zap.Error(err))
+		return err
+	}
 
 	it.partitionKeyMode, err = isPartitionKeyMode(ctx, it.req.GetDbName(), collectionName)
 	if err != nil {
@@ -405,9 +422,9 @@ func (it *upsertTask) insertExecute(ctx context.Context, msgPack *msgstream.MsgP
 	// assign segmentID for insert data and repack data by segmentID
 	var insertMsgPack *msgstream.MsgPack
 	if it.partitionKeys == nil {
-		insertMsgPack, err = repackInsertData(it.TraceCtx(), channelNames, it.upsertMsg.InsertMsg, it.result, it.idAllocator, it.segIDAssigner)
+		insertMsgPack, err = repackInsertData(it.TraceCtx(), channelNames, it.shardHash, it.upsertMsg.InsertMsg, it.result, it.idAllocator, it.segIDAssigner)
 	} else {
-		insertMsgPack, err = repackInsertDataWithPartitionKey(it.TraceCtx(), channelNames, it.partitionKeys, it.upsertMsg.InsertMsg, it.result, it.idAllocator, it.segIDAssigner)
+		insertMsgPack, err = repackInsertDataWithPartitionKey(it.TraceCtx(), channelNames, it.shardHash, it.partitionKeys, it.upsertMsg.InsertMsg, it.result, it.idAllocator, it.segIDAssigner)
 	}
 	if err != nil {
 		log.Warn("assign segmentID and repack insert data failed when insertExecute",
@@ -440,7 +457,7 @@ func (it *upsertTask) deleteExecute(ctx context.Context, msgPack *msgstream.MsgP
 		return err
 	}
 	it.upsertMsg.DeleteMsg.PrimaryKeys = it.result.IDs
-	it.upsertMsg.DeleteMsg.HashValues = typeutil.HashPK2Channels(it.upsertMsg.DeleteMsg.PrimaryKeys, channelNames)
+	it.upsertMsg.DeleteMsg.HashValues = typeutil.HashPK2ChannelsWith(it.shardHash, it.upsertMsg.DeleteMsg.PrimaryKeys, channelNames)
 
 	// repack delete msg by dmChannel
 	result := make(map[uint32]msgstream.TsMsg)
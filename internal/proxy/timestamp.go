@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
@@ -34,6 +35,22 @@ import (
 type timestampAllocator struct {
 	tso    timestampAllocatorInterface
 	peerID UniqueID
+
+	// coalesces the concurrent AllocOne calls into batched allocations
+	mu       sync.Mutex
+	batch    *tsoBatch
+	inflight int
+}
+
+// tsoBatch is the AllocOne calls waiting for a batched allocation.
+type tsoBatch struct {
+	count uint32
+	done  chan struct{}
+
+	// set once done
+	start     Timestamp
+	allocated uint32
+	err       error
 }
 
 // newTimestampAllocator creates a new timestampAllocator
@@ -80,11 +97,84 @@ func (ta *timestampAllocator) alloc(ctx context.Context, count uint32) ([]Timest
 	return ret, nil
 }
 
-// AllocOne allocates a timestamp.
+// AllocOne allocates a timestamp. The concurrent calls while an allocation is in flight are batched into one allocation,
+// which waits proxy.tsoBatch.window at most, the calls are allocated at once if the proxy is idle.
 func (ta *timestampAllocator) AllocOne(ctx context.Context) (Timestamp, error) {
-	ret, err := ta.alloc(ctx, 1)
+	window := Params.ProxyCfg.TsoBatchWindow.GetAsDuration(time.Microsecond)
+	maxSize := Params.ProxyCfg.TsoBatchMaxSize.GetAsInt()
+
+	ta.mu.Lock()
+	if window <= 0 || maxSize <= 1 || (ta.batch == nil && ta.inflight == 0) {
+		ta.inflight++
+		ta.mu.Unlock()
+		defer func() {
+			ta.mu.Lock()
+			ta.inflight--
+			ta.mu.Unlock()
+		}()
+
+		ret, err := ta.alloc(ctx, 1)
+		if err != nil {
+			return 0, err
+		}
+		return ret[0], nil
+	}
+
+	b := ta.batch
+	if b == nil {
+		b = &tsoBatch{done: make(chan struct{})}
+		ta.batch = b
+		time.AfterFunc(window, func() { ta.flush(b) })
+	}
+	index := b.count
+	b.count++
+	if b.count >= uint32(maxSize) {
+		ta.batch = nil
+		ta.inflight++
+		go ta.allocBatch(b)
+	}
+	ta.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	if index >= b.allocated {
+		return 0, fmt.Errorf("syncTimestamp Failed: %d timestamps allocated for %d calls", b.allocated, b.count)
+	}
+	return b.start + uint64(index), nil
+}
+
+// flush allocates the timestamps of the batch, unless it's flushed already.
+func (ta *timestampAllocator) flush(b *tsoBatch) {
+	ta.mu.Lock()
+	if ta.batch != b {
+		ta.mu.Unlock()
+		return
+	}
+	ta.batch = nil
+	ta.inflight++
+	ta.mu.Unlock()
+	ta.allocBatch(b)
+}
+
+// allocBatch allocates the timestamps of the batch detached.
+func (ta *timestampAllocator) allocBatch(b *tsoBatch) {
+	// the batch is shared by the calls, it's not canceled by any of them
+	ret, err := ta.alloc(context.Background(), b.count)
+
+	ta.mu.Lock()
+	ta.inflight--
+	ta.mu.Unlock()
 	if err != nil {
-		return 0, err
+		b.err = err
+	} else if len(ret) > 0 {
+		b.start = ret[0]
+		b.allocated = uint32(len(ret))
 	}
-	return ret[0], nil
+	close(b.done)
 }
@@ -19,10 +19,15 @@ package proxy
 import (
 	"context"
 	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 
+	"github.com/milvus-io/milvus/internal/proto/rootcoordpb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
 	"github.com/milvus-io/milvus/pkg/util/uniquegenerator"
 )
 
@@ -62,3 +67,156 @@ func TestTimestampAllocator_AllocOne(t *testing.T) {
 	_, err = tsAllocator.AllocOne(ctx)
 	assert.NoError(t, err)
 }
+
+// mockCountingTimestampAllocator allocates the timestamps in ascending order with the latency, and counts the rpcs.
+type mockCountingTimestampAllocator struct {
+	mu      sync.Mutex
+	next    Timestamp
+	rpcs    int
+	latency time.Duration
+}
+
+func (m *mockCountingTimestampAllocator) AllocTimestamp(ctx context.Context, req *rootcoordpb.AllocTimestampRequest, opts ...grpc.CallOption) (*rootcoordpb.AllocTimestampResponse, error) {
+	time.Sleep(m.latency)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rpcs++
+	start := m.next
+	m.next += Timestamp(req.GetCount())
+	return &rootcoordpb.AllocTimestampResponse{
+		Status:    merr.Success(),
+		Timestamp: start,
+		Count:     req.GetCount(),
+	}, nil
+}
+
+func (m *mockCountingTimestampAllocator) getRPCs() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rpcs
+}
+
+func TestTimestampAllocator_Batch(t *testing.T) {
+	ctx := context.Background()
+
+	// allocates the timestamps concurrently, each goroutine asserts its timestamps are increasing
+	run := func(tsAllocator *timestampAllocator, concurrency int, calls int, unbatched bool) []Timestamp {
+		mu := sync.Mutex{}
+		allocated := make([]Timestamp, 0, concurrency*calls)
+		wg := sync.WaitGroup{}
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				last := Timestamp(0)
+				for j := 0; j < calls; j++ {
+					var ts Timestamp
+					if unbatched && (i+j)%2 == 0 {
+						ret, err := tsAllocator.alloc(ctx, 1)
+						assert.NoError(t, err)
+						ts = ret[0]
+					} else {
+						var err error
+						ts, err = tsAllocator.AllocOne(ctx)
+						assert.NoError(t, err)
+					}
+					assert.Greater(t, ts, last)
+					last = ts
+
+					mu.Lock()
+					allocated = append(allocated, ts)
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		return allocated
+	}
+	assertUnique := func(allocated []Timestamp) {
+		unique := make(map[Timestamp]struct{}, len(allocated))
+		for _, ts := range allocated {
+			unique[ts] = struct{}{}
+		}
+		assert.Equal(t, len(allocated), len(unique))
+	}
+
+	t.Run("batched", func(t *testing.T) {
+		tso := &mockCountingTimestampAllocator{next: 1, latency: time.Millisecond}
+		tsAllocator, err := newTimestampAllocator(tso, 1)
+		assert.NoError(t, err)
+
+		concurrency, calls := 64, 20
+		allocated := run(tsAllocator, concurrency, calls, false)
+		assert.Len(t, allocated, concurrency*calls)
+		assertUnique(allocated)
+		// the concurrent calls are coalesced into far fewer rpcs
+		assert.Less(t, tso.getRPCs(), concurrency*calls/4)
+		t.Logf("%d calls allocated by %d rpcs", concurrency*calls, tso.getRPCs())
+
+		// the timestamps allocated afterwards are greater than all the ones before
+		ts, err := tsAllocator.AllocOne(ctx)
+		assert.NoError(t, err)
+		for _, allocatedTs := range allocated {
+			assert.Greater(t, ts, allocatedTs)
+		}
+	})
+
+	t.Run("mixed batched and unbatched", func(t *testing.T) {
+		tso := &mockCountingTimestampAllocator{next: 1, latency: time.Millisecond}
+		tsAllocator, err := newTimestampAllocator(tso, 1)
+		assert.NoError(t, err)
+
+		allocated := run(tsAllocator, 32, 20, true)
+		assertUnique(allocated)
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		tso := &mockCountingTimestampAllocator{next: 1}
+		tsAllocator, err := newTimestampAllocator(tso, 1)
+		assert.NoError(t, err)
+
+		// no allocation in flight, allocated at once without waiting for the window
+		Params.Save(Params.ProxyCfg.TsoBatchWindow.Key, "10000000")
+		defer Params.Reset(Params.ProxyCfg.TsoBatchWindow.Key)
+		start := time.Now()
+		for i := 0; i < 10; i++ {
+			_, err := tsAllocator.AllocOne(ctx)
+			assert.NoError(t, err)
+		}
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, 10, tso.getRPCs())
+	})
+
+	t.Run("batching disabled", func(t *testing.T) {
+		Params.Save(Params.ProxyCfg.TsoBatchWindow.Key, "0")
+		defer Params.Reset(Params.ProxyCfg.TsoBatchWindow.Key)
+		tso := &mockCountingTimestampAllocator{next: 1, latency: time.Millisecond}
+		tsAllocator, err := newTimestampAllocator(tso, 1)
+		assert.NoError(t, err)
+
+		allocated := run(tsAllocator, 8, 10, false)
+		assertUnique(allocated)
+		assert.Equal(t, 80, tso.getRPCs())
+	})
+
+	t.Run("canceled while batched", func(t *testing.T) {
+		tso := &mockCountingTimestampAllocator{next: 1, latency: 100 * time.Millisecond}
+		tsAllocator, err := newTimestampAllocator(tso, 1)
+		assert.NoError(t, err)
+		Params.Save(Params.ProxyCfg.TsoBatchWindow.Key, "10000000")
+		defer Params.Reset(Params.ProxyCfg.TsoBatchWindow.Key)
+
+		// an allocation in flight
+		go tsAllocator.AllocOne(ctx)
+		assert.Eventually(t, func() bool {
+			tsAllocator.mu.Lock()
+			defer tsAllocator.mu.Unlock()
+			return tsAllocator.inflight > 0
+		}, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = tsAllocator.AllocOne(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
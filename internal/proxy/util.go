@@ -1425,8 +1425,10 @@ func getDefaultPartitionNames(ctx context.Context, dbName string, collectionName
 	return partitionNames, nil
 }
 
-func assignChannelsByPK(pks *schemapb.IDs, channelNames []string, insertMsg *msgstream.InsertMsg) map[string][]int {
-	insertMsg.HashValues = typeutil.HashPK2Channels(pks, channelNames)
+// assignChannelsByPK hashes the primary keys to the channels by shardHash, the default one if nil,
+// and returns the row offsets of each channel.
+func assignChannelsByPK(shardHash typeutil.ShardHash, pks *schemapb.IDs, channelNames []string, insertMsg *msgstream.InsertMsg) map[string][]int {
+	insertMsg.HashValues = typeutil.HashPK2ChannelsWith(shardHash, pks, channelNames)
 
 	// groupedHashKeys represents the dmChannel index
 	channel2RowOffsets := make(map[string][]int) //   channelName to count
@@ -987,7 +987,8 @@ func (node *QueryNode) Query(ctx context.Context, req *querypb.QueryRequest) (*i
 }
 
 func (node *QueryNode) QueryStream(req *querypb.QueryRequest, srv querypb.QueryNode_QueryStreamServer) error {
-	ctx := srv.Context()
+	// link to the span of the caller carried by the request, e.g. the streaming query of delete
+	ctx := commonpbutil.ExtractTraceCtx(srv.Context(), req.GetReq().GetBase())
 	log := log.Ctx(ctx).With(
 		zap.Int64("collectionID", req.GetReq().GetCollectionID()),
 		zap.Strings("shards", req.GetDmlChannels()),
@@ -1048,7 +1049,7 @@ func (node *QueryNode) QueryStream(req *querypb.QueryRequest, srv querypb.QueryN
 }
 
 func (node *QueryNode) QueryStreamSegments(req *querypb.QueryRequest, srv querypb.QueryNode_QueryStreamSegmentsServer) error {
-	ctx := srv.Context()
+	ctx := commonpbutil.ExtractTraceCtx(srv.Context(), req.GetReq().GetBase())
 	msgID := req.Req.Base.GetMsgID()
 	traceID := trace.SpanFromContext(ctx).SpanContext().TraceID()
 	channel := req.GetDmlChannels()[0]
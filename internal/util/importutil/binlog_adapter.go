@@ -30,7 +30,6 @@ import (
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/log"
 	"github.com/milvus-io/milvus/pkg/util/merr"
-	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 // SegmentFilesHolder A struct to hold insert log paths and delta log paths of a segment
@@ -543,7 +542,7 @@ func (p *BinlogAdapter) getShardingListByPrimaryInt64(primaryKeys []int64,
 			shardList = append(shardList, -1) // this entity has been deleted, set shardID = -1 and skip this entity
 			actualDeleted++
 		} else {
-			hash, _ := typeutil.Hash32Int64(key)
+			hash := p.collectionInfo.ShardHash.HashInt64(key)
 			shardID := hash % uint32(p.collectionInfo.ShardNum)
 			partitions := memoryData[shardID]                      // initBlockData() can ensure the existence, no need to check bound here
 			fields := partitions[p.collectionInfo.PartitionIDs[0]] // NewBinlogAdapter() can ensure only one partition
@@ -598,7 +597,7 @@ func (p *BinlogAdapter) getShardingListByPrimaryVarchar(primaryKeys []string,
 			shardList = append(shardList, -1) // this entity has been deleted, set shardID = -1 and skip this entity
 			actualDeleted++
 		} else {
-			hash := typeutil.HashString2Uint32(key)
+			hash := p.collectionInfo.ShardHash.HashString(key)
 			shardID := hash % uint32(p.collectionInfo.ShardNum)
 			partitions := memoryData[shardID]                      // initBlockData() can ensure the existence, no need to check bound here
 			fields := partitions[p.collectionInfo.PartitionIDs[0]] // NewBinlogAdapter() can ensure only one partition
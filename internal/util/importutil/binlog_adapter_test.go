@@ -29,6 +29,7 @@ import (
 	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 const (
@@ -570,6 +571,19 @@ func Test_BinlogAdapterShardListInt64(t *testing.T) {
 	for i := 0; i < len(shardList); i++ {
 		assert.Equal(t, correctShardList[i], shardList[i])
 	}
+
+	// routed by the shard hash of collection, the same as the deletions
+	adapter.tsEndPoint = math.MaxUint64
+	for _, name := range typeutil.RegisteredShardHashes() {
+		assert.NoError(t, collectionInfo.SetShardHash(name))
+		shardList, err = adapter.getShardingListByPrimaryInt64(idList, tsList, shardsData, map[int64]uint64{})
+		assert.NoError(t, err)
+		ids := &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: idList}}}
+		deleteShards := typeutil.HashPK2ChannelsWith(collectionInfo.ShardHash, ids, []string{"ch0", "ch1"})
+		for i := 0; i < len(shardList); i++ {
+			assert.EqualValues(t, deleteShards[i], shardList[i], "shard hash %s", name)
+		}
+	}
 }
 
 func Test_BinlogAdapterShardListVarchar(t *testing.T) {
@@ -611,6 +625,19 @@ func Test_BinlogAdapterShardListVarchar(t *testing.T) {
 	for i := 0; i < len(shardList); i++ {
 		assert.Equal(t, correctShardList[i], shardList[i])
 	}
+
+	// routed by the shard hash of collection, the same as the deletions
+	adapter.tsEndPoint = math.MaxUint64
+	for _, name := range typeutil.RegisteredShardHashes() {
+		assert.NoError(t, collectionInfo.SetShardHash(name))
+		shardList, err = adapter.getShardingListByPrimaryVarchar(idList, tsList, shardsData, map[string]uint64{})
+		assert.NoError(t, err)
+		ids := &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: idList}}}
+		deleteShards := typeutil.HashPK2ChannelsWith(collectionInfo.ShardHash, ids, []string{"ch0", "ch1"})
+		for i := 0; i < len(shardList); i++ {
+			assert.EqualValues(t, deleteShards[i], shardList[i], "shard hash %s", name)
+		}
+	}
 }
 
 func Test_BinlogAdapterReadInt64PK(t *testing.T) {
@@ -22,11 +22,15 @@ import (
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
 	"github.com/milvus-io/milvus/pkg/common"
 	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 type CollectionInfo struct {
 	Schema   *schemapb.CollectionSchema
 	ShardNum int32
+	// ShardHash routes the primary keys to the shards, it must be the one of the collection,
+	// or the imported entities can't be deleted since the deletions are routed by it
+	ShardHash typeutil.ShardHash
 
 	PartitionIDs []int64 // target partitions of bulkinsert
 
@@ -49,8 +53,11 @@ func NewCollectionInfo(collectionSchema *schemapb.CollectionSchema,
 		return nil, merr.WrapErrImportFailed("partition list is empty")
 	}
 
+	// the legacy routing of the collections declaring no shard hash, call SetShardHash() for the others
+	shardHash, _ := typeutil.GetShardHash("")
 	info := &CollectionInfo{
 		ShardNum:     shardNum,
+		ShardHash:    shardHash,
 		PartitionIDs: partitionIDs,
 	}
 
@@ -62,6 +69,16 @@ func NewCollectionInfo(collectionSchema *schemapb.CollectionSchema,
 	return info, nil
 }
 
+// SetShardHash sets the shard hash declared by the collection, empty name for the legacy routing.
+func (c *CollectionInfo) SetShardHash(name string) error {
+	shardHash, err := typeutil.GetShardHash(name)
+	if err != nil {
+		return merr.WrapErrImportFailed(err.Error())
+	}
+	c.ShardHash = shardHash
+	return nil
+}
+
 func (c *CollectionInfo) resetSchema(collectionSchema *schemapb.CollectionSchema) error {
 	if collectionSchema == nil {
 		return merr.WrapErrImportFailed("collection schema is null")
@@ -21,6 +21,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+	"github.com/milvus-io/milvus/pkg/util/merr"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 func Test_CollectionInfoNew(t *testing.T) {
@@ -117,3 +119,25 @@ func Test_CollectionInfoNew(t *testing.T) {
 		assert.Nil(t, info)
 	})
 }
+
+func Test_CollectionInfoSetShardHash(t *testing.T) {
+	info, err := NewCollectionInfo(sampleSchema(), 2, []int64{1})
+	assert.NoError(t, err)
+
+	// the legacy routing by default
+	legacy, _ := typeutil.GetShardHash("")
+	assert.Equal(t, legacy, info.ShardHash)
+
+	err = info.SetShardHash(typeutil.Murmur3ShardHash)
+	assert.NoError(t, err)
+	expected, _ := typeutil.GetShardHash(typeutil.Murmur3ShardHash)
+	assert.Equal(t, expected, info.ShardHash)
+
+	err = info.SetShardHash("xxhash")
+	assert.ErrorIs(t, err, merr.ErrImportFailed)
+	assert.Equal(t, expected, info.ShardHash)
+
+	err = info.SetShardHash("")
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, info.ShardHash)
+}
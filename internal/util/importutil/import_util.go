@@ -807,11 +807,14 @@ func getTypeName(dt schemapb.DataType) string {
 	}
 }
 
-func pkToShard(pk interface{}, shardNum uint32) (uint32, error) {
+// partitionKeyHash hashes the partition keys to the partitions like the proxy does, whatever the shard hash is
+var partitionKeyHash, _ = typeutil.GetShardHash("")
+
+func pkToShard(shardHash typeutil.ShardHash, pk interface{}, shardNum uint32) (uint32, error) {
 	var shard uint32
 	strPK, ok := pk.(string)
 	if ok {
-		hash := typeutil.HashString2Uint32(strPK)
+		hash := shardHash.HashString(strPK)
 		shard = hash % shardNum
 	} else {
 		intPK, ok := pk.(int64)
@@ -819,7 +822,7 @@ func pkToShard(pk interface{}, shardNum uint32) (uint32, error) {
 			log.Warn("parser: primary key field must be int64 or varchar")
 			return 0, merr.WrapErrImportFailed("primary key field must be int64 or varchar")
 		}
-		hash, _ := typeutil.Hash32Int64(intPK)
+		hash := shardHash.HashInt64(intPK)
 		shard = hash % shardNum
 	}
 
@@ -988,7 +991,7 @@ func hashToPartition(collectionInfo *CollectionInfo, fieldsData BlockData, rowNu
 	partitionKeyID := collectionInfo.PartitionKey.GetFieldID()
 	fieldData := fieldsData[partitionKeyID]
 	value := fieldData.GetRow(rowNumber)
-	index, err := pkToShard(value, uint32(len(collectionInfo.PartitionIDs)))
+	index, err := pkToShard(partitionKeyHash, value, uint32(len(collectionInfo.PartitionIDs)))
 	if err != nil {
 		return 0, err
 	}
@@ -1071,7 +1074,7 @@ func splitFieldsData(collectionInfo *CollectionInfo, fieldsData BlockData, shard
 	for i := 0; i < rowCount; i++ {
 		// hash to a shard number and partition
 		pk := primaryData.GetRow(i)
-		shard, err := pkToShard(pk, uint32(collectionInfo.ShardNum))
+		shard, err := pkToShard(collectionInfo.ShardHash, pk, uint32(collectionInfo.ShardNum))
 		if err != nil {
 			return nil, err
 		}
@@ -1234,30 +1234,39 @@ func Test_GetTypeName(t *testing.T) {
 
 func Test_PkToShard(t *testing.T) {
 	a := int32(99)
-	shard, err := pkToShard(a, 2)
+	shardHash, _ := typeutil.GetShardHash("")
+	shard, err := pkToShard(shardHash, a, 2)
 	assert.Error(t, err)
 	assert.Zero(t, shard)
 
 	s := "abcdef"
 	shardNum := uint32(3)
-	shard, err = pkToShard(s, shardNum)
+	shard, err = pkToShard(shardHash, s, shardNum)
 	assert.NoError(t, err)
 	hash := typeutil.HashString2Uint32(s)
 	assert.Equal(t, hash%shardNum, shard)
 
 	pk := int64(100)
 	shardNum = uint32(4)
-	shard, err = pkToShard(pk, shardNum)
+	shard, err = pkToShard(shardHash, pk, shardNum)
 	assert.NoError(t, err)
 	hash, _ = typeutil.Hash32Int64(pk)
 	assert.Equal(t, hash%shardNum, shard)
 
 	pk = int64(99999)
 	shardNum = uint32(5)
-	shard, err = pkToShard(pk, shardNum)
+	shard, err = pkToShard(shardHash, pk, shardNum)
 	assert.NoError(t, err)
 	hash, _ = typeutil.Hash32Int64(pk)
 	assert.Equal(t, hash%shardNum, shard)
+
+	shardHash, _ = typeutil.GetShardHash(typeutil.Murmur3ShardHash)
+	shard, err = pkToShard(shardHash, pk, shardNum)
+	assert.NoError(t, err)
+	assert.Equal(t, shardHash.HashInt64(pk)%shardNum, shard)
+	shard, err = pkToShard(shardHash, s, shardNum)
+	assert.NoError(t, err)
+	assert.Equal(t, shardHash.HashString(s)%shardNum, shard)
 }
 
 func Test_UpdateKVInfo(t *testing.T) {
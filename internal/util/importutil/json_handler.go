@@ -195,7 +195,7 @@ func (v *JSONRowConsumer) Handle(rows []map[storage.FieldID]interface{}) error {
 			}
 
 			// hash to shard based on pk, hash to partition if partition key exist
-			hash := typeutil.HashString2Uint32(pk)
+			hash := v.collectionInfo.ShardHash.HashString(pk)
 			shard = hash % uint32(v.collectionInfo.ShardNum)
 			partitionID, err = v.hashToPartition(row, rowNumber)
 			if err != nil {
@@ -228,15 +228,10 @@ func (v *JSONRowConsumer) Handle(rows []map[storage.FieldID]interface{}) error {
 				}
 			}
 
-			hash, err := typeutil.Hash32Int64(pk)
-			if err != nil {
-				log.Warn("JSON row consumer: failed to hash primary key at the row",
-					zap.Int64("key", pk), zap.Int64("rowNumber", rowNumber), zap.Error(err))
-				return merr.WrapErrImportFailed(fmt.Sprintf("failed to hash primary key %d at the row %d, error: %v", pk, rowNumber, err))
-			}
-
 			// hash to shard based on pk, hash to partition if partition key exist
+			hash := v.collectionInfo.ShardHash.HashInt64(pk)
 			shard = hash % uint32(v.collectionInfo.ShardNum)
+			var err error
 			partitionID, err = v.hashToPartition(row, rowNumber)
 			if err != nil {
 				return err
@@ -123,6 +123,10 @@ const (
 	CollectionSearchRateMaxKey   = "collection.searchRate.max.vps"
 	CollectionSearchRateMinKey   = "collection.searchRate.min.vps"
 	CollectionDiskQuotaKey       = "collection.diskProtection.diskQuota.mb"
+
+	// CollectionShardHashKey names the hash routing the primary keys to the shards, see typeutil.GetShardHash,
+	// it's set at creation only, as the data inserted has been routed by it.
+	CollectionShardHashKey = "collection.shard.hash"
 )
 
 // common properties
@@ -148,6 +152,16 @@ func IsMmapEnabled(kvs ...*commonpb.KeyValuePair) bool {
 	return false
 }
 
+// GetShardHashName returns the shard hash declared by the collection properties, empty if none.
+func GetShardHashName(kvs ...*commonpb.KeyValuePair) string {
+	for _, kv := range kvs {
+		if kv.GetKey() == CollectionShardHashKey {
+			return kv.GetValue()
+		}
+	}
+	return ""
+}
+
 func IsFieldMmapEnabled(schema *schemapb.CollectionSchema, fieldID int64) bool {
 	for _, field := range schema.GetFields() {
 		if field.GetFieldID() == fieldID {
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 )
 
 func TestIsSystemField(t *testing.T) {
@@ -38,3 +40,12 @@ func TestIsSystemField(t *testing.T) {
 		})
 	}
 }
+
+func TestGetShardHashName(t *testing.T) {
+	assert.Equal(t, "", GetShardHashName())
+	assert.Equal(t, "", GetShardHashName(&commonpb.KeyValuePair{Key: CollectionTTLConfigKey, Value: "10"}))
+	assert.Equal(t, "murmur3", GetShardHashName(
+		&commonpb.KeyValuePair{Key: CollectionTTLConfigKey, Value: "10"},
+		&commonpb.KeyValuePair{Key: CollectionShardHashKey, Value: "murmur3"},
+	))
+}
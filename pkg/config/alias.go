@@ -0,0 +1,170 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/util/typeutil"
+)
+
+// AliasKeyPrefix is the section of the aliases of renamed configs, each key under it is the new key,
+// and the value is its deprecated keys separated by comma in precedence order, see Manager.LoadAliases,
+// e.g. "config.aliases.common.chanNamePrefix.cluster: msgChannel.chanNamePrefix.cluster"
+const AliasKeyPrefix = "config.aliases."
+
+type alias struct {
+	// the new key as set, and its deprecated keys formatted
+	key            string
+	deprecatedKeys []string
+}
+
+// aliasTable maps the new keys of configs to their deprecated ones, all the keys are formatted.
+type aliasTable struct {
+	mu      sync.Mutex
+	aliases map[string]*alias
+	// the new key of each deprecated key
+	renamed map[string]string
+	// the deprecated keys warned, to warn each once
+	warned *typeutil.ConcurrentSet[string]
+}
+
+func newAliasTable() *aliasTable {
+	return &aliasTable{
+		aliases: make(map[string]*alias),
+		renamed: make(map[string]string),
+		warned:  typeutil.NewConcurrentSet[string](),
+	}
+}
+
+// alias returns the new key as set and its deprecated keys, or false if the key has no deprecated keys.
+func (t *aliasTable) alias(key string) (string, []string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a, ok := t.aliases[key]
+	if !ok {
+		return "", nil, false
+	}
+	return a.key, a.deprecatedKeys, true
+}
+
+// SetAlias sets the deprecated keys of the new key in precedence order, or removes them if empty.
+// Getting the new key falls back to the first deprecated one set if it's not set, a warning is logged once per deprecated key,
+// and the events of the deprecated key falling back to are re-emitted under the new key for the watchers of it.
+func (m *Manager) SetAlias(key string, deprecatedKeys ...string) {
+	realKey := formatKey(key)
+	m.aliases.mu.Lock()
+	defer m.aliases.mu.Unlock()
+	if a, ok := m.aliases.aliases[realKey]; ok {
+		for _, deprecatedKey := range a.deprecatedKeys {
+			delete(m.aliases.renamed, deprecatedKey)
+		}
+		delete(m.aliases.aliases, realKey)
+	}
+	if len(deprecatedKeys) > 0 {
+		a := &alias{
+			key:            key,
+			deprecatedKeys: lo.Map(deprecatedKeys, func(key string, _ int) string { return formatKey(key) }),
+		}
+		for _, deprecatedKey := range a.deprecatedKeys {
+			m.aliases.renamed[deprecatedKey] = realKey
+		}
+		m.aliases.aliases[realKey] = a
+	}
+	// the value may fall back to the deprecated keys now
+	m.misses.clear()
+}
+
+// LoadAliases sets the aliases of the configs under AliasKeyPrefix, see SetAlias, which is called again to reload them.
+func (m *Manager) LoadAliases() {
+	formattedPrefix := formatKey(AliasKeyPrefix)
+	for key, value := range m.GetConfigsByKeyPrefix(AliasKeyPrefix) {
+		// the raw key and the formatted one are the same alias
+		newKey := strings.TrimPrefix(formatKey(key), formattedPrefix)
+		deprecatedKeys := lo.Filter(lo.Map(strings.Split(value, ","), func(key string, _ int) string {
+			return strings.TrimSpace(key)
+		}), func(key string, _ int) bool { return key != "" })
+		m.SetAlias(newKey, deprecatedKeys...)
+	}
+}
+
+// getDeprecatedConfig returns the value of the first deprecated key of the new key set, and warns once that it's deprecated.
+func (m *Manager) getDeprecatedConfig(newKey string, deprecatedKeys []string) (string, bool) {
+	for _, deprecatedKey := range deprecatedKeys {
+		value, err := m.getFormattedConfig(deprecatedKey)
+		if err != nil {
+			continue
+		}
+		if m.aliases.warned.Insert(deprecatedKey) {
+			log.Warn("config key is deprecated, use the new key instead", zap.String("deprecatedKey", deprecatedKey),
+				zap.String("newKey", newKey))
+		}
+		return value, true
+	}
+	return "", false
+}
+
+// emitRenamedEvent re-emits the event of the deprecated key under its new key, if the new key is not set,
+// and none of the deprecated keys before it is set either, see SetAlias.
+// The source of the event may be locked while firing it, so the values of the source are not read here.
+func (m *Manager) emitRenamedEvent(event *Event) {
+	realKey := formatKey(event.Key)
+	// the raw key and the formatted one are changed together, except the deleted ones, see mergeDeleteEvents
+	if event.EventType != DeleteType && event.Key != realKey {
+		return
+	}
+	m.aliases.mu.Lock()
+	newKey, ok := m.aliases.renamed[realKey]
+	a := m.aliases.aliases[newKey]
+	m.aliases.mu.Unlock()
+	if !ok || m.hasConfig(newKey) {
+		return
+	}
+	for _, deprecatedKey := range a.deprecatedKeys {
+		if deprecatedKey == realKey {
+			break
+		}
+		if m.hasConfig(deprecatedKey) {
+			return
+		}
+	}
+
+	renamedEvent := newEvent(event.EventSource, event.EventType, a.key, event.Value)
+	if event.EventType == DeleteType {
+		// falls back to another source
+		if sourceName, ok := m.keySourceMap.Get(realKey); ok && sourceName != event.EventSource {
+			if value, err := m.getConfigValueBySource(realKey, sourceName); err == nil {
+				renamedEvent = newEvent(sourceName, UpdateType, a.key, value)
+			}
+		}
+	}
+	renamedEvent.HasUpdated = true
+	m.Dispatcher.Dispatch(renamedEvent)
+}
+
+// hasConfig returns whether the formatted key is set in the overlays or the sources, without reading the value.
+func (m *Manager) hasConfig(realKey string) bool {
+	if v, ok := m.overlays.Get(realKey); ok {
+		return v != TombValue
+	}
+	return m.keySourceMap.Contain(realKey)
+}
@@ -0,0 +1,96 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// CompressedPrefix marks the configuration values compressed by gzip and encoded by base64, see CompressValue
+	CompressedPrefix = "gzip+b64:"
+	// DefaultCompressThreshold is the size in bytes of the values written by EtcdSource.SetConfig above which they're compressed
+	DefaultCompressThreshold = 64 * 1024
+)
+
+// CompressValue compresses the value by gzip, returns it encoded by base64 with CompressedPrefix,
+// which is decompressed transparently by the etcd source.
+func CompressValue(value string) (string, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(value)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return CompressedPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecompressValue returns the value compressed by CompressValue decompressed, or the value itself if it's not compressed.
+func DecompressValue(value string) (string, error) {
+	payload, ok := strings.CutPrefix(value, CompressedPrefix)
+	if !ok {
+		return value, nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64 of compressed config")
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", errors.Wrap(err, "invalid gzip of compressed config")
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid gzip of compressed config")
+	}
+	return string(decompressed), nil
+}
+
+func compressThresholdOrDefault(threshold int) int {
+	if threshold == 0 {
+		return DefaultCompressThreshold
+	}
+	// no compression
+	if threshold < 0 {
+		return 0
+	}
+	return threshold
+}
+
+// compressIfLarge compresses the value larger than threshold if it's smaller compressed, no compression if threshold is 0.
+func compressIfLarge(value string, threshold int) (string, error) {
+	if threshold <= 0 || len(value) <= threshold || strings.HasPrefix(value, CompressedPrefix) {
+		return value, nil
+	}
+	compressed, err := CompressValue(value)
+	if err != nil {
+		return "", err
+	}
+	if len(compressed) >= len(value) {
+		return value, nil
+	}
+	return compressed, nil
+}
@@ -0,0 +1,87 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressValue(t *testing.T) {
+	for _, value := range []string{"", "a", strings.Repeat(`{"role": "admin", "privileges": ["read", "write"]}`, 100)} {
+		compressed, err := CompressValue(value)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(compressed, CompressedPrefix))
+		decompressed, err := DecompressValue(compressed)
+		assert.NoError(t, err)
+		assert.Equal(t, value, decompressed)
+	}
+
+	// not compressed
+	value, err := DecompressValue("plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", value)
+
+	t.Run("corrupted", func(t *testing.T) {
+		_, err := DecompressValue(CompressedPrefix + "not base64!")
+		assert.Error(t, err)
+		_, err = DecompressValue(CompressedPrefix + base64.StdEncoding.EncodeToString([]byte("not gzip")))
+		assert.Error(t, err)
+		compressed, err := CompressValue("truncated")
+		require.NoError(t, err)
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(compressed, CompressedPrefix))
+		require.NoError(t, err)
+		_, err = DecompressValue(CompressedPrefix + base64.StdEncoding.EncodeToString(raw[:len(raw)-4]))
+		assert.Error(t, err)
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		assert.Equal(t, DefaultCompressThreshold, compressThresholdOrDefault(0))
+		assert.Equal(t, 0, compressThresholdOrDefault(-1))
+		assert.Equal(t, 10, compressThresholdOrDefault(10))
+
+		large := strings.Repeat("x", 100)
+		for _, c := range []struct {
+			value      string
+			threshold  int
+			compressed bool
+		}{
+			{large, 99, true},
+			{large, 100, false},
+			{large, 0, false},
+			// larger compressed
+			{"0123456789abcdef", 10, false},
+		} {
+			value, err := compressIfLarge(c.value, c.threshold)
+			assert.NoError(t, err)
+			assert.Equal(t, c.compressed, strings.HasPrefix(value, CompressedPrefix))
+			decompressed, err := DecompressValue(value)
+			assert.NoError(t, err)
+			assert.Equal(t, c.value, decompressed)
+		}
+		// compressed already
+		compressed, err := CompressValue(large)
+		require.NoError(t, err)
+		value, err := compressIfLarge(compressed, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, compressed, value)
+	})
+}
@@ -26,6 +26,16 @@ var (
 	ErrNotInitial   = errors.New("config is not initialized")
 	ErrIgnoreChange = errors.New("ignore change")
 	ErrKeyNotFound  = errors.New("key not found")
+	ErrReadOnly     = errors.New("config source is read only")
+	ErrSourceClosed = errors.New("config source closed")
+	// ErrDuplicateSource marks the sources added with the name of another one, see Manager.AddSource
+	ErrDuplicateSource = errors.New("duplicate config source")
+	// ErrEtcdAuth marks the failures of etcd authentication and permission, see EtcdSource.Health
+	ErrEtcdAuth = errors.New("etcd authentication failed")
+	// ErrInvalidValue marks the config values failed to parse, see Manager.GetInt64
+	ErrInvalidValue = errors.New("invalid config value")
+	// ErrRefreshPanicked marks the refreshing panicked, see MaxRefreshPanics
+	ErrRefreshPanicked = errors.New("refreshing configurations panicked")
 )
 
 func Init(opts ...Option) (*Manager, error) {
@@ -38,6 +48,9 @@ func Init(opts ...Option) (*Manager, error) {
 		s := NewFileSource(o.FileInfo)
 		sourceManager.AddSource(s)
 	}
+	if o.DirInfo != nil {
+		sourceManager.AddSource(NewDirSource(o.DirInfo))
+	}
 	if o.EnvKeyFormatter != nil {
 		sourceManager.AddSource(NewEnvSource(o.EnvKeyFormatter))
 	}
@@ -48,9 +61,32 @@ func Init(opts ...Option) (*Manager, error) {
 		}
 		sourceManager.AddSource(s)
 	}
+	if o.HTTPInfo != nil {
+		s, err := NewHTTPSource(o.HTTPInfo)
+		if err != nil {
+			return nil, err
+		}
+		sourceManager.AddSource(s)
+	}
 	return sourceManager, nil
 }
 
 func formatKey(key string) string {
 	return strings.NewReplacer("/", "", "_", "", ".", "").Replace(strings.ToLower(key))
 }
+
+// hasKeyPrefix returns whether the key starts with the prefix, which is formatted already.
+func hasKeyPrefix(key, formattedPrefix string) bool {
+	return strings.HasPrefix(formatKey(key), formattedPrefix)
+}
+
+func filterByKeyPrefix(configs map[string]string, prefix string) map[string]string {
+	prefix = formatKey(prefix)
+	matched := make(map[string]string)
+	for key, value := range configs {
+		if hasKeyPrefix(key, prefix) {
+			matched[key] = value
+		}
+	}
+	return matched
+}
@@ -0,0 +1,114 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// DefaultEncryptedPrefix marks the encrypted configuration values, e.g. "enc:<ciphertext>"
+	DefaultEncryptedPrefix = "enc:"
+	// DecryptionKeyEnv is the env var of the base64 encoded AES key, see NewAESGCMDecryptorFromEnv
+	DecryptionKeyEnv = "MILVUS_CONFIG_DECRYPTION_KEY"
+)
+
+var ErrNoDecryptor = errors.New("no decryptor for the encrypted config")
+
+// Decryptor decrypts the encrypted configuration values, the ciphertext is the value without the encrypted prefix.
+type Decryptor interface {
+	Decrypt(ciphertext string) (string, error)
+}
+
+// AESGCMDecryptor decrypts the values encrypted by AES-GCM, whose ciphertext is the base64 encoded nonce followed by the sealed value.
+type AESGCMDecryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMDecryptor returns the decryptor of the AES key, which is of 16, 24 or 32 bytes.
+func NewAESGCMDecryptor(key []byte) (*AESGCMDecryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCMDecryptor{aead: aead}, nil
+}
+
+// NewAESGCMDecryptorFromFile returns the decryptor of the base64 encoded AES key in the file.
+func NewAESGCMDecryptorFromFile(keyFile string) (*AESGCMDecryptor, error) {
+	encodedKey, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read decryption key file %s", keyFile)
+	}
+	return newAESGCMDecryptorFromBase64(string(encodedKey))
+}
+
+// NewAESGCMDecryptorFromEnv returns the decryptor of the base64 encoded AES key in the env var.
+func NewAESGCMDecryptorFromEnv(name string) (*AESGCMDecryptor, error) {
+	encodedKey, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("decryption key env %s not set", name)
+	}
+	return newAESGCMDecryptorFromBase64(encodedKey)
+}
+
+func newAESGCMDecryptorFromBase64(encodedKey string) (*AESGCMDecryptor, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64 decryption key")
+	}
+	return NewAESGCMDecryptor(key)
+}
+
+// Encrypt encrypts the value with a random nonce, which is decrypted by Decrypt.
+func (d *AESGCMDecryptor) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, d.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := d.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt implements Decryptor
+func (d *AESGCMDecryptor) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base64 ciphertext")
+	}
+	nonceSize := d.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := d.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,82 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAESGCMDecryptor(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	_, err := NewAESGCMDecryptor(key[:10])
+	assert.Error(t, err)
+
+	decryptor, err := NewAESGCMDecryptor(key)
+	require.NoError(t, err)
+	ciphertext, err := decryptor.Encrypt("secret")
+	require.NoError(t, err)
+	another, err := decryptor.Encrypt("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, another)
+	for _, c := range []string{ciphertext, another} {
+		plaintext, err := decryptor.Decrypt(c)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+	}
+
+	t.Run("invalid ciphertext", func(t *testing.T) {
+		_, err := decryptor.Decrypt("not base64!")
+		assert.Error(t, err)
+		_, err = decryptor.Decrypt(base64.StdEncoding.EncodeToString([]byte("short")))
+		assert.Error(t, err)
+		other, err := NewAESGCMDecryptor(key[:16])
+		require.NoError(t, err)
+		_, err = other.Decrypt(ciphertext)
+		assert.Error(t, err)
+	})
+
+	t.Run("key from file and env", func(t *testing.T) {
+		encodedKey := base64.StdEncoding.EncodeToString(key)
+		keyFile := path.Join(t.TempDir(), "key")
+		require.NoError(t, os.WriteFile(keyFile, []byte(encodedKey+"\n"), 0o600))
+		fromFile, err := NewAESGCMDecryptorFromFile(keyFile)
+		require.NoError(t, err)
+		plaintext, err := fromFile.Decrypt(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+		_, err = NewAESGCMDecryptorFromFile(path.Join(t.TempDir(), "not_exist"))
+		assert.Error(t, err)
+
+		_, err = NewAESGCMDecryptorFromEnv(DecryptionKeyEnv)
+		assert.Error(t, err)
+		t.Setenv(DecryptionKeyEnv, "invalid base64!")
+		_, err = NewAESGCMDecryptorFromEnv(DecryptionKeyEnv)
+		assert.Error(t, err)
+		t.Setenv(DecryptionKeyEnv, encodedKey)
+		fromEnv, err := NewAESGCMDecryptorFromEnv(DecryptionKeyEnv)
+		require.NoError(t, err)
+		plaintext, err = fromEnv.Decrypt(ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+	})
+}
@@ -0,0 +1,85 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"math"
+	"time"
+)
+
+// OverlayPriority is the priority of the configs set at runtime, which override the ones of all the sources
+const OverlayPriority = math.MinInt
+
+// ConfigDescription describes the effective config of a key, and where it's from.
+type ConfigDescription struct {
+	Value       string
+	Source      string
+	Priority    int
+	LastUpdated time.Time
+}
+
+// Describe returns the effective value of the key as GetConfig, with the source and the priority of it,
+// and when it's updated in the source, which is zero if the source doesn't track it. The source is empty if not found.
+func (m *Manager) Describe(key string) (value string, source string, priority int, lastUpdated time.Time) {
+	realKey := formatKey(key)
+	entry := SnapshotEntry{Key: realKey}
+	if v, ok := m.overlays.Get(realKey); ok {
+		if v == TombValue {
+			return "", "", 0, time.Time{}
+		}
+		entry.Value, entry.Source = v, OverlaySourceName
+	} else {
+		sourceName, ok := m.keySourceMap.Get(realKey)
+		if !ok {
+			return "", "", 0, time.Time{}
+		}
+		v, err := m.getConfigValueBySource(realKey, sourceName)
+		if err != nil {
+			return "", "", 0, time.Time{}
+		}
+		entry.Value, entry.Source = v, sourceName
+	}
+	description := m.describe(entry)
+	return description.Value, description.Source, description.Priority, description.LastUpdated
+}
+
+// DescribeAll describes the effective configs of all the keys, keyed by the formatted keys, see Describe.
+func (m *Manager) DescribeAll() map[string]ConfigDescription {
+	configs := m.effectiveConfigs()
+	descriptions := make(map[string]ConfigDescription, len(configs))
+	for key, entry := range configs {
+		descriptions[key] = m.describe(entry)
+	}
+	return descriptions
+}
+
+func (m *Manager) describe(entry SnapshotEntry) ConfigDescription {
+	description := ConfigDescription{Value: entry.Value, Source: entry.Source}
+	if entry.Source == OverlaySourceName {
+		description.Priority = OverlayPriority
+		return description
+	}
+	source, ok := m.sources.Get(entry.Source)
+	if !ok {
+		return description
+	}
+	description.Priority = m.getPriority(source)
+	if timer, ok := source.(UpdateTimer); ok {
+		description.LastUpdated, _ = timer.GetUpdateTime(entry.Key)
+	}
+	return description
+}
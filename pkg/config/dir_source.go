@@ -0,0 +1,329 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/pkg/log"
+)
+
+// DirDataLink is the symlink swapped atomically to update the ConfigMap mounted as a directory in Kubernetes,
+// through which each file of the directory resolves.
+const DirDataLink = "..data"
+
+// DirSource loads the configurations from the files of a directory, each regular file is a key by its name,
+// and its content trimmed of the surrounding whitespaces is the value, as the ConfigMap mounted in Kubernetes.
+// The hidden files are ignored, and so are the subdirectories unless DirInfo.Separator is set.
+type DirSource struct {
+	sync.RWMutex
+	name      string
+	dir       string
+	separator string
+	priority  int
+	configs   map[string]string
+
+	configRefresher *refresher
+
+	// watch the directory to reload it once changed, see watchDir
+	watchEnabled bool
+	watcher      *fsnotify.Watcher
+	watchWg      sync.WaitGroup
+
+	// error of the last load, nil if it succeeded, and when it succeeded last time
+	lastErr     error
+	lastSuccess time.Time
+}
+
+func NewDirSource(dirInfo *DirInfo) *DirSource {
+	ds := &DirSource{
+		name:         sourceName("DirSource", dirInfo.Name),
+		dir:          dirInfo.Dir,
+		separator:    dirInfo.Separator,
+		priority:     dirPriorityOrDefault(dirInfo.Priority),
+		configs:      make(map[string]string),
+		watchEnabled: dirInfo.Watch,
+	}
+	ds.configRefresher = newRefresher(dirInfo.RefreshInterval, ds.loadFromDir)
+	ds.configRefresher.snapshot = func(fn func(string, map[string]string)) {
+		ds.RLock()
+		defer ds.RUnlock()
+		fn(ds.GetSourceName(), ds.configs)
+	}
+	return ds
+}
+
+func dirPriorityOrDefault(priority int) int {
+	if priority == 0 {
+		return LowPriority - 1
+	}
+	return priority
+}
+
+// GetConfigurationByKey implements ConfigSource
+func (ds *DirSource) GetConfigurationByKey(key string) (string, error) {
+	ds.RLock()
+	v, ok := ds.configs[key]
+	ds.RUnlock()
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return v, nil
+}
+
+// GetConfigurationsByKeyPrefix implements ConfigSource
+func (ds *DirSource) GetConfigurationsByKeyPrefix(prefix string) (map[string]string, error) {
+	ds.RLock()
+	defer ds.RUnlock()
+	return filterByKeyPrefix(ds.configs, prefix), nil
+}
+
+// GetConfigurations implements ConfigSource
+func (ds *DirSource) GetConfigurations() (map[string]string, error) {
+	err := ds.loadFromDir()
+	if err != nil {
+		return nil, err
+	}
+
+	ds.configRefresher.start(ds.GetSourceName())
+	if ds.watchEnabled {
+		ds.startWatch()
+	}
+
+	ds.RLock()
+	configMap := make(map[string]string, len(ds.configs))
+	for k, v := range ds.configs {
+		configMap[k] = v
+	}
+	ds.RUnlock()
+	ds.configRefresher.markLoaded()
+	return configMap, nil
+}
+
+// GetPriority implements ConfigSource
+func (ds *DirSource) GetPriority() int {
+	return ds.priority
+}
+
+// GetSourceName implements ConfigSource
+func (ds *DirSource) GetSourceName() string {
+	return ds.name
+}
+
+// GetUpdateTime implements UpdateTimer
+func (ds *DirSource) GetUpdateTime(key string) (time.Time, bool) {
+	return ds.configRefresher.updateTime(key)
+}
+
+// Health implements Source, the last good configurations are kept while failing to load the directory
+func (ds *DirSource) Health() SourceHealth {
+	ds.RLock()
+	defer ds.RUnlock()
+	health := SourceHealth{
+		LastSuccessTime: ds.lastSuccess,
+		LastError:       ds.lastErr,
+		KeyCount:        len(ds.configs),
+		Connected:       ds.lastErr == nil && !ds.lastSuccess.IsZero(),
+	}
+	if err := ds.configRefresher.failure(); err != nil {
+		health.LastError, health.Connected = err, false
+	}
+	return health
+}
+
+func (ds *DirSource) Close() {
+	ds.configRefresher.stop()
+	ds.stopWatch()
+}
+
+// SetEventHandler implements Source
+//
+// Deprecated: use AddEventHandler instead, which keeps the other handlers.
+func (ds *DirSource) SetEventHandler(eh EventHandler) {
+	ds.configRefresher.setEventHandler(eh)
+}
+
+// AddEventHandler implements Source
+func (ds *DirSource) AddEventHandler(eh EventHandler) int64 {
+	return ds.configRefresher.addEventHandler(eh)
+}
+
+// RemoveEventHandler implements Source
+func (ds *DirSource) RemoveEventHandler(id int64) {
+	ds.configRefresher.removeEventHandler(id)
+}
+
+func (ds *DirSource) UpdateOptions(opts Options) {
+	if opts.DirInfo == nil {
+		return
+	}
+
+	ds.Lock()
+	ds.dir = opts.DirInfo.Dir
+	ds.separator = opts.DirInfo.Separator
+	ds.watchEnabled = opts.DirInfo.Watch
+	ds.Unlock()
+
+	// watch the new directory
+	ds.stopWatch()
+	if opts.DirInfo.Watch {
+		ds.startWatch()
+	}
+}
+
+// startWatch starts watching the directory, unless it's started already.
+func (ds *DirSource) startWatch() {
+	ds.Lock()
+	defer ds.Unlock()
+	if ds.watcher != nil {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warn("failed to watch config directory, it's only reloaded periodically", zap.Error(err))
+		return
+	}
+	if err := watcher.Add(ds.dir); err != nil {
+		log.Warn("failed to watch config directory", zap.String("dir", ds.dir), zap.Error(err))
+	}
+	ds.watcher = watcher
+	ds.watchWg.Add(1)
+	go ds.watchDir(watcher)
+}
+
+func (ds *DirSource) stopWatch() {
+	ds.Lock()
+	watcher := ds.watcher
+	ds.watcher = nil
+	ds.Unlock()
+	if watcher != nil {
+		watcher.Close()
+		ds.watchWg.Wait()
+	}
+}
+
+// watchDir reloads the directory once any of the files is changed, or DirDataLink is swapped, until the watcher is closed.
+// The other hidden files are changed while updating the ConfigMap, which are ignored, so it's reloaded once per update.
+// The subdirectories are not watched, the changes of them are reloaded periodically, unless they're swapped by DirDataLink too.
+func (ds *DirSource) watchDir(watcher *fsnotify.Watcher) {
+	defer ds.watchWg.Done()
+	log.Info("start watching config directory", zap.String("source", ds.GetSourceName()))
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				log.Info("stop watching config directory", zap.String("source", ds.GetSourceName()))
+				return
+			}
+			name := filepath.Base(event.Name)
+			if name != DirDataLink && strings.HasPrefix(name, ".") {
+				continue
+			}
+			log.Info("config directory changed, reload it", zap.String("event", event.String()))
+			if err := ds.loadFromDir(); err != nil {
+				log.Error("failed to reload config directory, keep the last good configurations", zap.Error(err))
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn("error watching config directory", zap.Error(err))
+		}
+	}
+}
+
+func (ds *DirSource) loadFromDir() (err error) {
+	start := time.Now()
+	defer func() {
+		observeRefresh(ds.GetSourceName(), start, err)
+		ds.Lock()
+		defer ds.Unlock()
+		ds.lastErr = err
+		if err == nil {
+			ds.lastSuccess = time.Now()
+		}
+	}()
+
+	ds.RLock()
+	dir, separator := ds.dir, ds.separator
+	ds.RUnlock()
+
+	newConfig := make(map[string]string)
+	if err := readConfigDir(dir, "", separator, newConfig); err != nil {
+		return errors.Wrap(err, "Read config directory failed: "+dir)
+	}
+
+	ds.Lock()
+	defer ds.Unlock()
+	err = ds.configRefresher.fireEvents(ds.GetSourceName(), ds.configs, newConfig)
+	if err != nil {
+		// the changes held while frozen are applied once unfrozen
+		return ignoreFrozen(err)
+	}
+	ds.configs = newConfig
+	return nil
+}
+
+// readConfigDir reads the files of the directory into configs, the keys are prefixed with keyPrefix,
+// and the subdirectories are read recursively with their names joined by the separator, unless it's empty.
+// The symlinks are followed, as the files of the ConfigMap resolve through DirDataLink.
+func readConfigDir(dir, keyPrefix, separator string, configs map[string]string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		file := filepath.Join(dir, name)
+		info, err := os.Stat(file)
+		if err != nil {
+			// removed while reading, or a dangling symlink
+			log.Warn("failed to stat config file, skip it", zap.String("file", file), zap.Error(err))
+			continue
+		}
+		key := keyPrefix + name
+		switch {
+		case info.IsDir():
+			if separator == "" {
+				continue
+			}
+			if err := readConfigDir(file, key+separator, separator, configs); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			value := strings.TrimSpace(string(content))
+			configs[key] = value
+			configs[formatKey(key)] = value
+		}
+	}
+	return nil
+}
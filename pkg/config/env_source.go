@@ -16,73 +16,183 @@
 package config
 
 import (
-	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
 
 	"github.com/milvus-io/milvus/pkg/util/typeutil"
 )
 
 type EnvSource struct {
+	mu           sync.RWMutex
+	name         string
 	configs      *typeutil.ConcurrentMap[string, string]
 	KeyFormatter func(string) string
+	keyPrefix    string
+	priority     int
+	// when the env vars are loaded last time
+	loadTime time.Time
+
+	configRefresher *refresher
 }
 
-func NewEnvSource(KeyFormatter func(string) string) EnvSource {
-	es := EnvSource{
-		configs:      typeutil.NewConcurrentMap[string, string](),
-		KeyFormatter: KeyFormatter,
-	}
+func NewEnvSource(KeyFormatter func(string) string) *EnvSource {
+	return NewEnvSourceWithInfo(&EnvInfo{KeyFormatter: KeyFormatter})
+}
 
-	for _, value := range os.Environ() {
-		rs := []rune(value)
-		in := strings.Index(value, "=")
-		key := string(rs[0:in])
-		value := string(rs[in+1:])
-		envKey := KeyFormatter(key)
-		es.configs.Insert(key, value)
-		es.configs.Insert(envKey, value)
+func NewEnvSourceWithInfo(envInfo *EnvInfo) *EnvSource {
+	es := &EnvSource{
+		name:         sourceName("EnvironmentSource", lo.Ternary(envInfo.Name == "", envInfo.KeyPrefix, envInfo.Name)),
+		KeyFormatter: envInfo.KeyFormatter,
+		keyPrefix:    envInfo.KeyPrefix,
+		priority:     envInfo.Priority,
+	}
+	if es.priority == 0 {
+		es.priority = NormalPriority
 	}
+	// env vars are only reloaded on Refresh
+	es.configRefresher = newRefresher(0, es.Refresh)
+	es.configs = es.loadFromEnv()
+	es.loadTime = time.Now()
+	es.configs.Range(func(key, _ string) bool {
+		es.configRefresher.touch(key)
+		return true
+	})
+	es.configRefresher.snapshot = func(fn func(string, map[string]string)) {
+		es.mu.RLock()
+		defer es.mu.RUnlock()
+		fn(es.GetSourceName(), toMap(es.configs))
+	}
+	// loaded once created
+	es.configRefresher.markLoaded()
 	return es
 }
 
+func (es *EnvSource) loadFromEnv() *typeutil.ConcurrentMap[string, string] {
+	configs := typeutil.NewConcurrentMap[string, string]()
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if es.keyPrefix != "" {
+			var ok bool
+			key, ok = strings.CutPrefix(key, es.keyPrefix)
+			if !ok || key == "" {
+				continue
+			}
+		}
+		configs.Insert(key, value)
+		configs.Insert(es.KeyFormatter(key), value)
+	}
+	return configs
+}
+
+// Refresh reloads the env vars, and fires the events of the changed ones
+func (es *EnvSource) Refresh() error {
+	defer observeRefresh(es.GetSourceName(), time.Now(), nil)
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	configs := es.loadFromEnv()
+	err := es.configRefresher.fireEvents(es.GetSourceName(), toMap(es.configs), toMap(configs))
+	if err != nil {
+		// the changes held while frozen are applied once unfrozen
+		return ignoreFrozen(err)
+	}
+	es.configs = configs
+	es.loadTime = time.Now()
+	return nil
+}
+
+func toMap(configs *typeutil.ConcurrentMap[string, string]) map[string]string {
+	configMap := make(map[string]string)
+	configs.Range(func(k, v string) bool {
+		configMap[k] = v
+		return true
+	})
+	return configMap
+}
+
 // GetConfigurationByKey implements ConfigSource
-func (es EnvSource) GetConfigurationByKey(key string) (string, error) {
+func (es *EnvSource) GetConfigurationByKey(key string) (string, error) {
+	es.mu.RLock()
 	value, ok := es.configs.Get(key)
+	es.mu.RUnlock()
 
 	if !ok {
-		return "", fmt.Errorf("key not found: %s", key)
+		return "", ErrKeyNotFound
 	}
 
 	return value, nil
 }
 
-// GetConfigurations implements ConfigSource
-func (es EnvSource) GetConfigurations() (map[string]string, error) {
+// GetConfigurationsByKeyPrefix implements ConfigSource
+func (es *EnvSource) GetConfigurationsByKeyPrefix(prefix string) (map[string]string, error) {
+	prefix = formatKey(prefix)
 	configMap := make(map[string]string)
+	es.mu.RLock()
+	defer es.mu.RUnlock()
 	es.configs.Range(func(k, v string) bool {
-		configMap[k] = v
+		if hasKeyPrefix(k, prefix) {
+			configMap[k] = v
+		}
 		return true
 	})
-
 	return configMap, nil
 }
 
+// GetConfigurations implements ConfigSource
+func (es *EnvSource) GetConfigurations() (map[string]string, error) {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+	return toMap(es.configs), nil
+}
+
 // GetPriority implements ConfigSource
-func (es EnvSource) GetPriority() int {
-	return NormalPriority
+func (es *EnvSource) GetPriority() int {
+	return es.priority
+}
+
+// GetSourceName implements ConfigSource, which is "EnvironmentSource[<name>]" by EnvInfo.Name, or by EnvInfo.KeyPrefix if not set
+func (es *EnvSource) GetSourceName() string {
+	return es.name
+}
+
+// GetUpdateTime implements UpdateTimer
+func (es *EnvSource) GetUpdateTime(key string) (time.Time, bool) {
+	return es.configRefresher.updateTime(key)
+}
+
+// Health implements Source, the env vars are always loaded
+func (es *EnvSource) Health() SourceHealth {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+	return SourceHealth{
+		LastSuccessTime: es.loadTime,
+		KeyCount:        es.configs.Len(),
+		Connected:       true,
+	}
+}
+
+// SetEventHandler implements Source
+//
+// Deprecated: use AddEventHandler instead, which keeps the other handlers.
+func (es *EnvSource) SetEventHandler(eh EventHandler) {
+	es.configRefresher.setEventHandler(eh)
 }
 
-// GetSourceName implements ConfigSource
-func (es EnvSource) GetSourceName() string {
-	return "EnvironmentSource"
+// AddEventHandler implements Source
+func (es *EnvSource) AddEventHandler(eh EventHandler) int64 {
+	return es.configRefresher.addEventHandler(eh)
 }
 
-func (es EnvSource) SetEventHandler(eh EventHandler) {
+// RemoveEventHandler implements Source
+func (es *EnvSource) RemoveEventHandler(id int64) {
+	es.configRefresher.removeEventHandler(id)
 }
 
-func (es EnvSource) UpdateOptions(opts Options) {
+func (es *EnvSource) UpdateOptions(opts Options) {
 }
 
-func (es EnvSource) Close() {
+func (es *EnvSource) Close() {
 }
@@ -18,146 +18,1700 @@ package config
 
 import (
 	"context"
-	"fmt"
 	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/cockroachdb/errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 
 	"github.com/milvus-io/milvus/pkg/log"
 	"github.com/milvus-io/milvus/pkg/util/etcd"
 )
 
 const (
-	ReadConfigTimeout = 3 * time.Second
+	// ReadConfigTimeout is the timeout of reading the configurations, unless EtcdInfo.RequestTimeout is set
+	ReadConfigTimeout  = 3 * time.Second
+	WriteConfigTimeout = 3 * time.Second
+
+	DefaultEtcdDialTimeout      = 5 * time.Second
+	DefaultEtcdKeepAliveTime    = 10 * time.Second
+	DefaultEtcdKeepAliveTimeout = 3 * time.Second
+	DefaultEtcdPageSize         = 1000
+	DefaultRefreshJitter        = 0.1
+
+	// the refreshing backs off exponentially after consecutive failures, at most MaxRefreshBackoff,
+	// and the failures are logged as warnings and errors after RefreshFailuresToWarn and RefreshFailuresToError
+	MaxRefreshBackoff      = 5 * time.Minute
+	RefreshFailuresToWarn  = 3
+	RefreshFailuresToError = 10
+
+	// DefaultEtcdFailoverThreshold is the consecutive failures of refreshing to fail over to EtcdInfo.SecondaryEndpoints,
+	// and the primary endpoints are probed every DefaultEtcdProbeInterval to fail back
+	DefaultEtcdFailoverThreshold = RefreshFailuresToError
+	DefaultEtcdProbeInterval     = 10 * time.Second
+	// DegradedSecondary is the SourceHealth.Degraded of the etcd source failed over to the secondary endpoints
+	DegradedSecondary = "degraded (secondary)"
 )
 
+// DefaultBlobSuffixes are the suffixes of the keys whose values are YAML or JSON documents, see EtcdInfo.BlobSuffixes
+var DefaultBlobSuffixes = []string{".yaml", ".yml", ".json"}
+
 type EtcdSource struct {
 	sync.RWMutex
+	// the name of the source, see EtcdInfo.Name
+	name string
+	// the client is rebuilt to rotate the credentials, which waits for the operations in progress with the old one
+	clientMu      sync.RWMutex
 	etcdCli       *clientv3.Client
+	clientInfo    EtcdInfo
 	ctx           context.Context
+	cancel        context.CancelFunc
 	currentConfig map[string]string
-	keyPrefix     string
+	// increased each time the client is rebuilt with other endpoints or credentials, the configurations read by the old client are not applied
+	clientGen int64
+	// the client is failed over to the secondary endpoints after failoverThreshold consecutive failures of refreshing,
+	// and fails back once the primary ones pass the probe every probeInterval, see failover and probePrimary
+	primaryEndpoints  []string
+	standbyEndpoints  []string
+	failoverThreshold int
+	probeInterval     time.Duration
+	failedOver        bool
+	// timeout of reading the configurations, and the max keys read by each request
+	requestTimeout time.Duration
+	pageSize       int64
+	// the prefixes of configurations in precedence order, and the configurations under each of them
+	prefixes      []string
+	prefixConfigs []map[string]string
+	// the winning prefixes of the keys collided across prefixes, to log each collision once
+	collisions map[string]string
+	// the etcd keys of each formatted key, and the winning key of each one collided with it in the same prefix
+	originalKeys  map[string][]string
+	keyCollisions map[string]string
+	// decrypt the values with the encrypted prefix, the results are cached by the ciphertexts to log each failure once
+	decryptor       Decryptor
+	encryptedPrefix string
+	decrypted       map[string]decryption
+	// the keys of the values encrypted, in the current configurations and in the previous ones, which are redacted in the change records
+	secretKeys     map[string]bool
+	prevSecretKeys map[string]bool
+	// the keys with the suffixes are blobs of YAML or JSON documents, which are flattened and cached by the etcd keys
+	blobSuffixes []string
+	blobs        map[string]blobConfigs
+	// the compressed values are decompressed and cached by the etcd keys, and the values written larger than
+	// compressThreshold are compressed, see CompressedPrefix
+	compressThreshold int
+	decompressed      map[string]decompression
+	readOnly          bool
+	priority          int
+	// etcd revision of currentConfig, and the revisions watched of each prefix since then
+	revision        int64
+	watchedRevision []int64
+	// the count of keys of each prefix at revision if currentConfig is in sync with it, nil if not, see unchanged
+	syncedCounts []int64
 
 	configRefresher *refresher
+	// dispatch the changes to the handlers registered by keys and patterns, see Dispatcher
+	dispatcher *EventDispatcher
+	// the id of dispatcher in the handlers of configRefresher, which is kept by SetEventHandler
+	dispatcherID int64
+
+	// watch the changes rather than polling them, see watchConfigurations
+	watchEnabled bool
+	watchMu      sync.Mutex
+	watchCancel  context.CancelFunc
+	watchWg      sync.WaitGroup
+
+	// watch the TLS files to rebuild the client once they're rotated, see ReloadTLS
+	certMu      sync.Mutex
+	certWatcher *fsnotify.Watcher
+	certWatchWg sync.WaitGroup
+
+	// no refreshing starts once closed, and Close waits for the ones in progress
+	closeMu   sync.RWMutex
+	closed    bool
+	refreshWg sync.WaitGroup
+	// held by the refreshing in progress, so the periodic refreshing and the forced one never interleave
+	refreshing chan struct{}
+
+	// health of refreshing, see Health
+	healthMu    sync.RWMutex
+	failures    int
+	lastErr     error
+	lastSuccess time.Time
+	nextRetry   time.Time
+	// failure of reloading the TLS files, while the client with the old ones is kept
+	tlsErr error
 }
 
 func NewEtcdSource(etcdInfo *EtcdInfo) (*EtcdSource, error) {
-	log.Debug("init etcd source", zap.Any("etcdInfo", etcdInfo))
-	etcdCli, err := etcd.GetEtcdClient(
+	log.Debug("init etcd source", zap.Any("endpoints", etcdInfo.Endpoints), zap.String("keyPrefix", etcdInfo.KeyPrefix),
+		zap.Bool("enableAuth", etcdInfo.EnableAuth), zap.String("username", etcdInfo.Username))
+	etcdCli, err := newEtcdClient(etcdInfo)
+	if err != nil {
+		return nil, markAuthError(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	es := &EtcdSource{
+		name:              sourceName("EtcdSource", lo.Ternary(etcdInfo.Name == "", etcdInfo.KeyPrefix, etcdInfo.Name)),
+		etcdCli:           etcdCli,
+		clientInfo:        *etcdInfo,
+		primaryEndpoints:  etcdInfo.Endpoints,
+		standbyEndpoints:  etcdInfo.SecondaryEndpoints,
+		failoverThreshold: lo.Ternary(etcdInfo.FailoverThreshold <= 0, DefaultEtcdFailoverThreshold, etcdInfo.FailoverThreshold),
+		probeInterval:     durationOrDefault(etcdInfo.ProbeInterval, DefaultEtcdProbeInterval),
+		requestTimeout:    durationOrDefault(etcdInfo.RequestTimeout, ReadConfigTimeout),
+		pageSize:          pageSizeOrDefault(etcdInfo.PageSize),
+		ctx:               ctx,
+		cancel:            cancel,
+		currentConfig:     make(map[string]string),
+		prefixes:          configPrefixes(etcdInfo),
+		collisions:        make(map[string]string),
+		decryptor:         etcdInfo.Decryptor,
+		encryptedPrefix:   lo.Ternary(etcdInfo.EncryptedPrefix == "", DefaultEncryptedPrefix, etcdInfo.EncryptedPrefix),
+		decrypted:         make(map[string]decryption),
+		blobSuffixes:      blobSuffixesOrDefault(etcdInfo.BlobSuffixes),
+		blobs:             make(map[string]blobConfigs),
+		compressThreshold: compressThresholdOrDefault(etcdInfo.CompressThreshold),
+		decompressed:      make(map[string]decompression),
+		readOnly:          etcdInfo.ReadOnly,
+		priority:          lo.Ternary(etcdInfo.Priority == 0, HighPriority, etcdInfo.Priority),
+		watchEnabled:      etcdInfo.Watch,
+		dispatcher:        NewEventDispatcher(),
+		refreshing:        make(chan struct{}, 1),
+	}
+	es.configRefresher = newRefresher(etcdInfo.RefreshInterval, es.refreshWithBackoff)
+	es.configRefresher.setJitter(jitterOrDefault(etcdInfo.RefreshJitter))
+	es.configRefresher.isSecret = es.isSecret
+	es.configRefresher.snapshot = func(fn func(string, map[string]string)) {
+		es.RLock()
+		defer es.RUnlock()
+		fn(es.GetSourceName(), es.currentConfig)
+	}
+	es.dispatcherID = es.configRefresher.addEventHandler(es.dispatcher)
+	es.startCertWatch(tlsFiles(etcdInfo))
+	return es, nil
+}
+
+func newEtcdClient(etcdInfo *EtcdInfo) (*clientv3.Client, error) {
+	return etcd.CreateEtcdClient(
 		etcdInfo.UseEmbed,
+		etcdInfo.EnableAuth,
+		etcdInfo.Username,
+		etcdInfo.Password,
 		etcdInfo.UseSSL,
 		etcdInfo.Endpoints,
 		etcdInfo.CertFile,
 		etcdInfo.KeyFile,
 		etcdInfo.CaCertFile,
-		etcdInfo.MinVersion)
-	if err != nil {
-		return nil, err
+		etcdInfo.MinVersion,
+		etcd.WithDialTimeout(durationOrDefault(etcdInfo.DialTimeout, DefaultEtcdDialTimeout)),
+		etcd.WithKeepAlive(durationOrDefault(etcdInfo.KeepAliveTime, DefaultEtcdKeepAliveTime),
+			durationOrDefault(etcdInfo.KeepAliveTimeout, DefaultEtcdKeepAliveTimeout)))
+}
+
+func pageSizeOrDefault(pageSize int64) int64 {
+	if pageSize == 0 {
+		return DefaultEtcdPageSize
 	}
-	es := &EtcdSource{
-		etcdCli:       etcdCli,
-		ctx:           context.Background(),
-		currentConfig: make(map[string]string),
-		keyPrefix:     etcdInfo.KeyPrefix,
+	// no limit
+	if pageSize < 0 {
+		return 0
 	}
-	es.configRefresher = newRefresher(etcdInfo.RefreshInterval, es.refreshConfigurations)
-	return es, nil
+	return pageSize
+}
+
+func blobSuffixesOrDefault(suffixes []string) []string {
+	if len(suffixes) == 0 {
+		return DefaultBlobSuffixes
+	}
+	return suffixes
+}
+
+func jitterOrDefault(jitter float64) float64 {
+	if jitter == 0 {
+		return DefaultRefreshJitter
+	}
+	// no jitter
+	if jitter < 0 {
+		return 0
+	}
+	return jitter
+}
+
+func durationOrDefault(duration, defaultValue time.Duration) time.Duration {
+	if duration <= 0 {
+		return defaultValue
+	}
+	return duration
+}
+
+// markAuthError marks the failures of authentication and permission as ErrEtcdAuth, to tell them from the network errors.
+func markAuthError(err error) error {
+	for _, authErr := range []error{
+		rpctypes.ErrAuthFailed,
+		rpctypes.ErrInvalidAuthToken,
+		rpctypes.ErrAuthOldRevision,
+		rpctypes.ErrPermissionDenied,
+		rpctypes.ErrUserNotFound,
+		rpctypes.ErrUserEmpty,
+	} {
+		if errors.Is(err, authErr) {
+			return errors.Mark(err, ErrEtcdAuth)
+		}
+	}
+	return err
+}
+
+func configPrefixes(etcdInfo *EtcdInfo) []string {
+	configPaths := etcdInfo.ConfigPaths
+	if len(configPaths) == 0 {
+		configPaths = []string{"config"}
+	}
+	return lo.Map(configPaths, func(configPath string, _ int) string {
+		return path.Join(etcdInfo.KeyPrefix, configPath)
+	})
 }
 
 // GetConfigurationByKey implements ConfigSource
 func (es *EtcdSource) GetConfigurationByKey(key string) (string, error) {
+	if es.isClosed() {
+		return "", ErrSourceClosed
+	}
 	es.RLock()
 	v, ok := es.currentConfig[key]
 	es.RUnlock()
 	if !ok {
-		return "", fmt.Errorf("key not found: %s", key)
+		return "", ErrKeyNotFound
 	}
 	return v, nil
 }
 
+// GetConfigurationsByKeyPrefix implements ConfigSource
+func (es *EtcdSource) GetConfigurationsByKeyPrefix(prefix string) (map[string]string, error) {
+	if es.isClosed() {
+		return nil, ErrSourceClosed
+	}
+	es.RLock()
+	defer es.RUnlock()
+	return filterByKeyPrefix(es.currentConfig, prefix), nil
+}
+
 // GetConfigurations implements ConfigSource
 func (es *EtcdSource) GetConfigurations() (map[string]string, error) {
 	configMap := make(map[string]string)
 	err := es.refreshConfigurations()
-	if err != nil {
+	if errors.Is(err, ErrSourceClosed) {
 		return nil, err
 	}
-	es.configRefresher.start(es.GetSourceName())
+	if err != nil {
+		lastSuccess := es.LastSuccess()
+		if lastSuccess.IsZero() {
+			return nil, err
+		}
+		es.logger().Warn("failed to refresh configurations, serve the stale ones", zap.Time("lastSuccess", lastSuccess), zap.Error(err))
+	}
+	if es.watchEnabled {
+		es.startWatch()
+	} else {
+		es.configRefresher.start(es.GetSourceName())
+	}
 	es.RLock()
 	for key, value := range es.currentConfig {
 		configMap[key] = value
 	}
 	es.RUnlock()
+	es.configRefresher.markLoaded()
 
 	return configMap, nil
 }
 
 // GetPriority implements ConfigSource
 func (es *EtcdSource) GetPriority() int {
-	return HighPriority
+	return es.priority
 }
 
-// GetSourceName implements ConfigSource
+// GetSourceName implements ConfigSource, which is "EtcdSource[<name>]" by EtcdInfo.Name, or by EtcdInfo.KeyPrefix if not set
 func (es *EtcdSource) GetSourceName() string {
-	return "EtcdSource"
+	return es.name
+}
+
+// logger returns the logger with the source name, which is built each time to follow the global logger replaced.
+func (es *EtcdSource) logger() *log.MLogger {
+	return log.With(zap.String("source", es.name))
+}
+
+// GetUpdateTime implements UpdateTimer
+func (es *EtcdSource) GetUpdateTime(key string) (time.Time, bool) {
+	return es.configRefresher.updateTime(key)
 }
 
+// Close stops refreshing, and waits for the refreshing in progress, no events are fired once it returns.
 func (es *EtcdSource) Close() {
+	es.closeMu.Lock()
+	es.closed = true
+	es.closeMu.Unlock()
+	es.cancel()
 	// cannot close client here, since client is shared with components
 	es.configRefresher.stop()
+	es.stopWatch()
+	es.stopCertWatch()
+	es.refreshWg.Wait()
+}
+
+func (es *EtcdSource) isClosed() bool {
+	es.closeMu.RLock()
+	defer es.closeMu.RUnlock()
+	return es.closed
 }
 
+// SetEventHandler sets the handler of all the changes, which replaces the ones added before.
+//
+// Deprecated: use AddEventHandler instead, which keeps the other handlers.
 func (es *EtcdSource) SetEventHandler(eh EventHandler) {
-	es.configRefresher.eh = eh
+	es.configRefresher.setEventHandler(eh, es.dispatcherID)
+}
+
+// AddEventHandler implements Source, the handler is called with all the changes after the ones registered in Dispatcher.
+func (es *EtcdSource) AddEventHandler(eh EventHandler) int64 {
+	return es.configRefresher.addEventHandler(eh)
+}
+
+// RemoveEventHandler implements Source
+func (es *EtcdSource) RemoveEventHandler(id int64) {
+	es.configRefresher.removeEventHandler(id)
+}
+
+// Dispatcher returns the dispatcher of the changes, to register the handlers of specific keys and patterns.
+func (es *EtcdSource) Dispatcher() *EventDispatcher {
+	return es.dispatcher
 }
 
 func (es *EtcdSource) UpdateOptions(opts Options) {
 	if opts.EtcdInfo == nil {
 		return
 	}
+	prefixes := configPrefixes(opts.EtcdInfo)
+	requestTimeout := durationOrDefault(opts.EtcdInfo.RequestTimeout, ReadConfigTimeout)
+	pageSize := pageSizeOrDefault(opts.EtcdInfo.PageSize)
+	es.RLock()
+	prefixesChanged := !slices.Equal(es.prefixes, prefixes)
+	es.RUnlock()
+	// update the interval without restarting the refresher, whose refreshing in progress takes the lock
+	es.configRefresher.setJitter(jitterOrDefault(opts.EtcdInfo.RefreshJitter))
+	es.configRefresher.setInterval(opts.EtcdInfo.RefreshInterval)
+
+	// re-sync and watch the new prefixes with the new client, the watch takes the lock to apply changes
+	watching := es.watchEnabled && es.stopWatch()
+	rebuilt := es.updateClient(opts.EtcdInfo)
+	// the new prefixes are read before switching to them, so the configurations of the old ones are never served as the new ones
+	start := time.Now()
+	var switchPrefixes func() error
+	if prefixesChanged {
+		switchPrefixes = es.loadPrefixes(prefixes, requestTimeout, pageSize)
+	}
+
 	es.Lock()
-	defer es.Unlock()
-	es.keyPrefix = opts.EtcdInfo.KeyPrefix
-	if es.configRefresher.refreshInterval != opts.EtcdInfo.RefreshInterval {
-		es.configRefresher.stop()
-		eh := es.configRefresher.eh
-		es.configRefresher = newRefresher(opts.EtcdInfo.RefreshInterval, es.refreshConfigurations)
-		es.configRefresher.eh = eh
-		es.configRefresher.start(es.GetSourceName())
+	es.prefixes = prefixes
+	es.requestTimeout = requestTimeout
+	es.pageSize = pageSize
+	es.blobSuffixes = blobSuffixesOrDefault(opts.EtcdInfo.BlobSuffixes)
+	es.compressThreshold = compressThresholdOrDefault(opts.EtcdInfo.CompressThreshold)
+	// the configurations are merged by the new options next time
+	es.syncedCounts = nil
+	var err error
+	if switchPrefixes != nil {
+		err = switchPrefixes()
+	}
+	es.Unlock()
+
+	if prefixesChanged {
+		if !errors.Is(err, ErrSourceClosed) {
+			observeRefresh(es.GetSourceName(), start, err)
+			es.recordRefresh(err)
+		}
+		if err != nil {
+			es.logger().Warn("failed to load configurations of new prefixes, serve the old ones until refreshed", zap.Strings("prefixes", prefixes), zap.Error(err))
+		}
+	} else if watching || rebuilt {
+		if err := es.refreshConfigurations(); err != nil {
+			es.logger().Warn("failed to refresh configurations with new options", zap.Strings("prefixes", prefixes), zap.Error(err))
+		}
+	}
+	if watching {
+		es.startWatch()
+	}
+}
+
+// loadPrefixes reads the configurations of the new prefixes, returns the function applying them with the lock,
+// which fires the events of the whole transition from the old prefixes at once.
+func (es *EtcdSource) loadPrefixes(prefixes []string, requestTimeout time.Duration, pageSize int64) func() error {
+	if !es.track() {
+		return func() error { return ErrSourceClosed }
+	}
+	prefixConfigs, revision, err := es.fetchPrefixes(prefixes, requestTimeout, pageSize, clientv3.WithSerializable())
+	return func() error {
+		defer es.refreshWg.Done()
+		if es.isClosed() {
+			return ErrSourceClosed
+		}
+		if err != nil {
+			return err
+		}
+		return es.applyConfigurations(prefixConfigs, revision)
+	}
+}
+
+// updateClient rebuilds the client if the credentials or the connection options changed, returns whether it's rebuilt.
+// The old client is closed after the operations in progress with it are done, so the refreshing is paused meanwhile.
+func (es *EtcdSource) updateClient(etcdInfo *EtcdInfo) bool {
+	es.clientMu.RLock()
+	clientInfo := es.clientInfo
+	es.clientMu.RUnlock()
+	tlsChanged := clientInfo.UseSSL != etcdInfo.UseSSL || clientInfo.CertFile != etcdInfo.CertFile ||
+		clientInfo.KeyFile != etcdInfo.KeyFile || clientInfo.CaCertFile != etcdInfo.CaCertFile || clientInfo.MinVersion != etcdInfo.MinVersion
+	if clientInfo.UseEmbed || (clientInfo.EnableAuth == etcdInfo.EnableAuth &&
+		clientInfo.Username == etcdInfo.Username && clientInfo.Password == etcdInfo.Password &&
+		clientInfo.DialTimeout == etcdInfo.DialTimeout && clientInfo.KeepAliveTime == etcdInfo.KeepAliveTime &&
+		clientInfo.KeepAliveTimeout == etcdInfo.KeepAliveTimeout && !tlsChanged) {
+		return false
+	}
+	clientInfo.EnableAuth, clientInfo.Username, clientInfo.Password = etcdInfo.EnableAuth, etcdInfo.Username, etcdInfo.Password
+	clientInfo.DialTimeout, clientInfo.KeepAliveTime, clientInfo.KeepAliveTimeout = etcdInfo.DialTimeout, etcdInfo.KeepAliveTime, etcdInfo.KeepAliveTimeout
+	clientInfo.UseSSL, clientInfo.CertFile, clientInfo.KeyFile = etcdInfo.UseSSL, etcdInfo.CertFile, etcdInfo.KeyFile
+	clientInfo.CaCertFile, clientInfo.MinVersion = etcdInfo.CaCertFile, etcdInfo.MinVersion
+	etcdCli, err := newEtcdClient(&clientInfo)
+	if err != nil {
+		err = markAuthError(err)
+		es.logger().Warn("failed to rebuild etcd client, keep the old one", zap.String("username", etcdInfo.Username), zap.Error(err))
+		observeRefresh(es.GetSourceName(), time.Now(), err)
+		es.recordRefresh(err)
+		return false
+	}
+
+	es.clientMu.Lock()
+	oldCli := es.etcdCli
+	es.etcdCli = etcdCli
+	es.clientInfo = clientInfo
+	es.clientGen++
+	es.clientMu.Unlock()
+	if err := oldCli.Close(); err != nil {
+		es.logger().Warn("failed to close the old etcd client", zap.Error(err))
+	}
+	es.setTLSError(nil)
+	if tlsChanged {
+		es.stopCertWatch()
+		es.startCertWatch(tlsFiles(&clientInfo))
+	}
+	es.logger().Info("etcd client rebuilt with new options", zap.String("username", etcdInfo.Username),
+		zap.Duration("dialTimeout", etcdInfo.DialTimeout), zap.Duration("keepAliveTime", etcdInfo.KeepAliveTime),
+		zap.Duration("keepAliveTimeout", etcdInfo.KeepAliveTimeout))
+	return true
+}
+
+// withClient calls fn with the client, which is not closed by rotating the credentials until fn returns.
+func (es *EtcdSource) withClient(fn func(etcdCli *clientv3.Client) error) error {
+	es.clientMu.RLock()
+	defer es.clientMu.RUnlock()
+	return fn(es.etcdCli)
+}
+
+func (es *EtcdSource) clientGeneration() int64 {
+	es.clientMu.RLock()
+	defer es.clientMu.RUnlock()
+	return es.clientGen
+}
+
+// shouldFailover returns whether to fail over to the secondary endpoints, after failoverThreshold consecutive failures.
+func (es *EtcdSource) shouldFailover() bool {
+	es.clientMu.RLock()
+	failedOver, useEmbed := es.failedOver, es.clientInfo.UseEmbed
+	es.clientMu.RUnlock()
+	es.healthMu.RLock()
+	failures := es.failures
+	es.healthMu.RUnlock()
+	return len(es.standbyEndpoints) > 0 && !useEmbed && !failedOver && failures >= es.failoverThreshold
+}
+
+// failover switches the client to the secondary endpoints, and probes the primary ones to fail back, returns false if failed to.
+func (es *EtcdSource) failover() bool {
+	if err := es.switchEndpoints(es.standbyEndpoints, true); err != nil {
+		es.logger().Warn("failed to fail over to the secondary etcd endpoints", zap.Strings("endpoints", es.standbyEndpoints), zap.Error(err))
+		return false
+	}
+	es.logger().Warn("failed over to the secondary etcd endpoints", zap.Strings("primary", es.primaryEndpoints),
+		zap.Strings("secondary", es.standbyEndpoints))
+	if es.track() {
+		go es.probePrimary()
+	}
+	return true
+}
+
+// probePrimary probes the primary endpoints every probeInterval while failed over, and fails back once they pass,
+// then reloads the configurations from them, until closed.
+func (es *EtcdSource) probePrimary() {
+	defer es.refreshWg.Done()
+	for {
+		select {
+		case <-es.ctx.Done():
+			return
+		case <-time.After(es.probeInterval):
+		}
+		if err := es.probe(es.primaryEndpoints); err != nil {
+			es.logger().Debug("primary etcd endpoints are still unavailable", zap.Strings("endpoints", es.primaryEndpoints), zap.Error(err))
+			continue
+		}
+		if err := es.switchEndpoints(es.primaryEndpoints, false); err != nil {
+			es.logger().Warn("failed to fail back to the primary etcd endpoints", zap.Strings("endpoints", es.primaryEndpoints), zap.Error(err))
+			continue
+		}
+		es.logger().Info("failed back to the primary etcd endpoints", zap.Strings("endpoints", es.primaryEndpoints))
+		// the failure is logged already
+		es.refreshConfigurations()
+		return
+	}
+}
+
+// probe reads the configurations of the first prefix from the endpoints without the values, which requires the quorum of them.
+func (es *EtcdSource) probe(endpoints []string) error {
+	es.clientMu.RLock()
+	clientInfo := es.clientInfo
+	es.clientMu.RUnlock()
+	clientInfo.Endpoints = endpoints
+	etcdCli, err := newEtcdClient(&clientInfo)
+	if err != nil {
+		return err
+	}
+	defer etcdCli.Close()
+	es.RLock()
+	prefix, timeout := es.prefixes[0], es.requestTimeout
+	es.RUnlock()
+	ctx, cancel := context.WithTimeout(es.ctx, timeout)
+	defer cancel()
+	_, err = etcdCli.Get(ctx, prefix+"/", clientv3.WithPrefix(), clientv3.WithCountOnly())
+	return err
+}
+
+// switchEndpoints rebuilds the client with the endpoints, which waits for the operations in progress with the old one.
+// The revisions of the clusters are unrelated, so the configurations are reloaded in full next time, see unchanged.
+func (es *EtcdSource) switchEndpoints(endpoints []string, failedOver bool) error {
+	es.clientMu.Lock()
+	clientInfo := es.clientInfo
+	clientInfo.Endpoints = endpoints
+	etcdCli, err := newEtcdClient(&clientInfo)
+	if err != nil {
+		es.clientMu.Unlock()
+		return markAuthError(err)
+	}
+	oldCli := es.etcdCli
+	es.etcdCli = etcdCli
+	es.clientInfo = clientInfo
+	es.clientGen++
+	es.failedOver = failedOver
+	es.clientMu.Unlock()
+	if err := oldCli.Close(); err != nil {
+		es.logger().Warn("failed to close the old etcd client", zap.Error(err))
+	}
+
+	es.Lock()
+	es.syncedCounts = nil
+	es.Unlock()
+	return nil
+}
+
+// ReloadTLS rebuilds the client with the TLS files reloaded, which waits for the operations in progress with the old one.
+// The files are reloaded once they're changed, and could be reloaded explicitly by it. The client with the old ones is kept
+// if failed to load them, and the failure is reported by Health until they're reloaded.
+func (es *EtcdSource) ReloadTLS() error {
+	if es.isClosed() {
+		return ErrSourceClosed
+	}
+	es.clientMu.Lock()
+	clientInfo := es.clientInfo
+	if clientInfo.UseEmbed || !clientInfo.UseSSL {
+		es.clientMu.Unlock()
+		return nil
+	}
+	etcdCli, err := newEtcdClient(&clientInfo)
+	if err != nil {
+		es.clientMu.Unlock()
+		err = errors.Wrap(err, "failed to reload etcd TLS files, the old ones are used")
+		es.logger().Warn("failed to reload etcd TLS files, keep the old client", zap.String("certFile", clientInfo.CertFile),
+			zap.String("keyFile", clientInfo.KeyFile), zap.String("caCertFile", clientInfo.CaCertFile), zap.Error(err))
+		es.setTLSError(err)
+		return err
+	}
+	oldCli := es.etcdCli
+	es.etcdCli = etcdCli
+	es.clientMu.Unlock()
+	if err := oldCli.Close(); err != nil {
+		es.logger().Warn("failed to close the old etcd client", zap.Error(err))
+	}
+	es.setTLSError(nil)
+	es.logger().Info("etcd client rebuilt with reloaded TLS files", zap.String("certFile", clientInfo.CertFile))
+	return nil
+}
+
+func (es *EtcdSource) setTLSError(err error) {
+	es.healthMu.Lock()
+	defer es.healthMu.Unlock()
+	es.tlsErr = err
+}
+
+// tlsFiles returns the TLS files of the client to watch, empty if it's not secured by them.
+func tlsFiles(etcdInfo *EtcdInfo) []string {
+	if etcdInfo.UseEmbed || !etcdInfo.UseSSL {
+		return nil
+	}
+	return lo.Compact([]string{etcdInfo.CertFile, etcdInfo.KeyFile, etcdInfo.CaCertFile})
+}
+
+// startCertWatch watches the directories of the TLS files rather than the files, so the files rotated by rename,
+// or by swapping the symlink they resolve through as the Secret mounted in Kubernetes, are still watched.
+func (es *EtcdSource) startCertWatch(files []string) {
+	if len(files) == 0 {
+		return
+	}
+	es.certMu.Lock()
+	defer es.certMu.Unlock()
+	if es.certWatcher != nil {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		es.logger().Warn("failed to watch etcd TLS files, they are only reloaded by ReloadTLS", zap.Error(err))
+		return
+	}
+	for _, dir := range lo.Uniq(lo.Map(files, func(file string, _ int) string { return filepath.Dir(file) })) {
+		if err := watcher.Add(dir); err != nil {
+			es.logger().Warn("failed to watch directory of etcd TLS files", zap.String("dir", dir), zap.Error(err))
+		}
+	}
+	es.certWatcher = watcher
+	es.certWatchWg.Add(1)
+	go es.watchCerts(watcher, files)
+}
+
+func (es *EtcdSource) stopCertWatch() {
+	es.certMu.Lock()
+	watcher := es.certWatcher
+	es.certWatcher = nil
+	es.certMu.Unlock()
+	if watcher != nil {
+		watcher.Close()
+		es.certWatchWg.Wait()
+	}
+}
+
+// watchCerts reloads the TLS files once any of them is changed, until the watcher is closed.
+// The certificate and the key are rotated one by one, so the ones mismatched are kept failing until both are rotated.
+func (es *EtcdSource) watchCerts(watcher *fsnotify.Watcher, files []string) {
+	defer es.certWatchWg.Done()
+	realPaths := make(map[string]string, len(files))
+	for _, file := range files {
+		realPaths[file] = realPath(file)
+	}
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !filesChanged(event, files, realPaths) {
+				continue
+			}
+			es.logger().Info("etcd TLS files changed, reload them", zap.String("event", event.String()))
+			// the failure is logged and reported by Health already
+			es.ReloadTLS()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			es.logger().Warn("error watching etcd TLS files", zap.Error(err))
+		}
+	}
+}
+
+// Health implements Source, the configurations are stale but still served while failing,
+// LastError tells whether they are never loaded, or since when they are stale.
+func (es *EtcdSource) Health() SourceHealth {
+	es.RLock()
+	keyCount := len(es.currentConfig)
+	es.RUnlock()
+	es.healthMu.RLock()
+	defer es.healthMu.RUnlock()
+	health := SourceHealth{
+		LastSuccessTime: es.lastSuccess,
+		KeyCount:        keyCount,
+		Connected:       es.lastErr == nil && !es.lastSuccess.IsZero(),
+	}
+	switch {
+	case es.lastErr == nil:
+	case es.lastSuccess.IsZero():
+		health.LastError = errors.Wrapf(es.lastErr, "configurations never loaded after %d failures", es.failures)
+	default:
+		health.LastError = errors.Wrapf(es.lastErr, "configurations stale since %s after %d failures",
+			es.lastSuccess.Format(time.RFC3339), es.failures)
+	}
+	// the client with the old TLS files still works
+	if es.tlsErr != nil && health.LastError == nil {
+		health.LastError = es.tlsErr
+	}
+	if err := es.configRefresher.failure(); err != nil {
+		health.LastError, health.Connected = err, false
+	}
+	es.clientMu.RLock()
+	if es.failedOver {
+		health.Degraded = DegradedSecondary
+	}
+	es.clientMu.RUnlock()
+	return health
+}
+
+// LastSuccess returns when the configurations are synced last time, zero if never.
+func (es *EtcdSource) LastSuccess() time.Time {
+	es.healthMu.RLock()
+	defer es.healthMu.RUnlock()
+	return es.lastSuccess
+}
+
+// refreshWithBackoff refreshes the configurations every refresh interval, unless backing off from failures.
+func (es *EtcdSource) refreshWithBackoff() error {
+	es.healthMu.RLock()
+	nextRetry := es.nextRetry
+	es.healthMu.RUnlock()
+	if time.Now().Before(nextRetry) {
+		return nil
+	}
+	// the failure is logged already
+	es.refreshConfigurations()
+	return nil
+}
+
+// retryDelay returns how long to wait before refreshing next time.
+func (es *EtcdSource) retryDelay() time.Duration {
+	es.healthMu.RLock()
+	defer es.healthMu.RUnlock()
+	return es.backoff(es.failures)
+}
+
+func (es *EtcdSource) backoff(failures int) time.Duration {
+	interval := es.refreshInterval()
+	if failures <= 1 || interval >= MaxRefreshBackoff {
+		return interval
+	}
+	// no overflow as the interval is less than MaxRefreshBackoff
+	shift := failures - 1
+	if shift > 16 {
+		shift = 16
+	}
+	if backoff := interval << shift; backoff < MaxRefreshBackoff {
+		return backoff
+	}
+	return MaxRefreshBackoff
+}
+
+func (es *EtcdSource) recordRefresh(err error) {
+	es.healthMu.Lock()
+	defer es.healthMu.Unlock()
+	if err == nil {
+		if es.failures > 0 {
+			es.logger().Info("refresh configurations recovered", zap.Int("failures", es.failures))
+		}
+		es.failures = 0
+		es.lastErr = nil
+		es.lastSuccess = time.Now()
+		es.nextRetry = time.Time{}
+		return
+	}
+
+	es.failures++
+	es.lastErr = err
+	backoff := es.backoff(es.failures)
+	es.nextRetry = time.Now().Add(backoff)
+	fields := []zap.Field{
+		zap.Int("failures", es.failures),
+		zap.Bool("authFailed", errors.Is(err, ErrEtcdAuth)),
+		zap.Time("lastSuccess", es.lastSuccess),
+		zap.Duration("backoff", backoff),
+		zap.Error(err),
+	}
+	switch {
+	case es.failures >= RefreshFailuresToError:
+		es.logger().Error("failed to refresh configurations, the stale ones are served", fields...)
+	case es.failures >= RefreshFailuresToWarn:
+		es.logger().Warn("failed to refresh configurations, the stale ones are served", fields...)
+	default:
+		es.logger().Info("failed to refresh configurations, retry later", fields...)
 	}
 }
 
 func (es *EtcdSource) refreshConfigurations() error {
-	log := log.Ctx(context.TODO()).WithRateGroup("config.etcdSource", 1, 60)
+	return es.refresh(clientv3.WithSerializable())
+}
+
+// ForceRefresh implements ForceRefresher, it refreshes the configurations at once with the linearizable reads, rather than
+// waiting for the refresh interval or backing off from failures, and returns the failure of it. It waits for the refreshing
+// in progress until ctx is done, so it must not be called by the event handlers, which are called while refreshing.
+func (es *EtcdSource) ForceRefresh(ctx context.Context) error {
+	return es.refreshContext(ctx)
+}
+
+func (es *EtcdSource) refresh(readOpts ...clientv3.OpOption) error {
+	return es.refreshContext(context.Background(), readOpts...)
+}
+
+// refreshContext refreshes the configurations after the refreshing in progress, or returns the error of ctx if it's done first.
+func (es *EtcdSource) refreshContext(ctx context.Context, readOpts ...clientv3.OpOption) error {
+	if !es.track() {
+		return ErrSourceClosed
+	}
+	defer es.refreshWg.Done()
+	select {
+	case es.refreshing <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-es.ctx.Done():
+		return ErrSourceClosed
+	}
+	defer func() { <-es.refreshing }()
+	return es.refreshLocked(readOpts...)
+}
+
+func (es *EtcdSource) refreshLocked(readOpts ...clientv3.OpOption) error {
+	start := time.Now()
+	err := es.loadConfigurations(readOpts...)
+	if err != nil && es.isClosed() {
+		// canceled by Close rather than failed
+		return ErrSourceClosed
+	}
+	observeRefresh(es.GetSourceName(), start, err)
+	es.recordRefresh(err)
+	// reload from the secondary endpoints at once
+	if err != nil && es.shouldFailover() && es.failover() {
+		return es.refreshLocked(readOpts...)
+	}
+	return err
+}
+
+// track tracks the operation reading etcd, which Close waits for until refreshWg.Done, or returns false if closed.
+func (es *EtcdSource) track() bool {
+	es.closeMu.RLock()
+	defer es.closeMu.RUnlock()
+	if es.closed {
+		return false
+	}
+	es.refreshWg.Add(1)
+	return true
+}
+
+func (es *EtcdSource) loadConfigurations(readOpts ...clientv3.OpOption) error {
+	if es.unchanged(readOpts...) {
+		return nil
+	}
+	clientGen := es.clientGeneration()
+	prefixes, prefixConfigs, revision, err := es.fetchConfigurations(readOpts...)
+	if err != nil {
+		return err
+	}
+	es.Lock()
+	defer es.Unlock()
+	if es.isClosed() {
+		return ErrSourceClosed
+	}
+	if !slices.Equal(es.prefixes, prefixes) {
+		return errors.New("prefixes of configurations changed while refreshing")
+	}
+	if es.clientGeneration() != clientGen {
+		return errors.New("etcd client rebuilt while refreshing")
+	}
+	return es.applyConfigurations(prefixConfigs, revision)
+}
+
+// applyConfigurations merges the configurations of the prefixes read at the revision, and fires the events of the changes,
+// it must be called with the lock.
+func (es *EtcdSource) applyConfigurations(prefixConfigs []map[string]string, revision int64) error {
+	newConfig := es.mergeConfigurations(prefixConfigs)
+	err := es.configRefresher.fireEvents(es.GetSourceName(), es.currentConfig, newConfig)
+	if err != nil {
+		// the changes held while frozen are reloaded in full once unfrozen
+		return ignoreFrozen(err)
+	}
+	es.currentConfig = newConfig
+	es.prefixConfigs = prefixConfigs
+	es.revision = revision
+	es.watchedRevision = make([]int64, len(prefixConfigs))
+	for i := range es.watchedRevision {
+		es.watchedRevision[i] = revision
+	}
+	// the changes rejected are validated again next time
+	es.syncedCounts = nil
+	if !es.configRefresher.rejected {
+		es.syncedCounts = lo.Map(prefixConfigs, func(configs map[string]string, _ int) int64 { return int64(len(configs)) })
+	}
+	return nil
+}
+
+// unchanged returns whether none of the configurations in etcd is changed since currentConfig is loaded in sync with them,
+// so refreshing them is skipped without rebuilding and diffing them. Each prefix is checked by reading the keys modified after
+// the revision loaded without the values, and the count of keys for the deleted ones. The check doesn't read at the revision,
+// so it's not affected by compaction, and any failure of it falls back to reading all the configurations.
+func (es *EtcdSource) unchanged(readOpts ...clientv3.OpOption) bool {
+	es.RLock()
+	prefixes, revision, counts, timeout := es.prefixes, es.revision, es.syncedCounts, es.requestTimeout
+	es.RUnlock()
+	if len(counts) != len(prefixes) {
+		return false
+	}
+	err := es.withClient(func(etcdCli *clientv3.Client) error {
+		for i, prefix := range prefixes {
+			opts := append([]clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithMinModRev(revision + 1)}, readOpts...)
+			ctx, cancel := context.WithTimeout(es.ctx, timeout)
+			resp, err := etcdCli.Get(ctx, prefix+"/", opts...)
+			cancel()
+			if err != nil {
+				return err
+			}
+			// the count is of all the keys, not only the modified ones
+			if len(resp.Kvs) > 0 || resp.Count != counts[i] {
+				return errors.New("configurations changed")
+			}
+		}
+		return nil
+	})
+	return err == nil
+}
+
+// fetchConfigurations reads the configurations of the prefixes, returns them with the prefixes and the revision read at.
+func (es *EtcdSource) fetchConfigurations(readOpts ...clientv3.OpOption) ([]string, []map[string]string, int64, error) {
 	es.RLock()
-	prefix := path.Join(es.keyPrefix, "config")
+	prefixes, requestTimeout, pageSize := es.prefixes, es.requestTimeout, es.pageSize
 	es.RUnlock()
+	prefixConfigs, revision, err := es.fetchPrefixes(prefixes, requestTimeout, pageSize, readOpts...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return prefixes, prefixConfigs, revision, nil
+}
+
+// fetchPrefixes reads the configurations of the prefixes, returns them with the revision read at.
+func (es *EtcdSource) fetchPrefixes(prefixes []string, requestTimeout time.Duration, pageSize int64,
+	readOpts ...clientv3.OpOption,
+) ([]map[string]string, int64, error) {
+	log := es.logger().WithRateGroup("config.etcdSource", 1, 60)
+	// read all the prefixes at the revision of the first page, so they are consistent
+	var revision int64
+	prefixConfigs := make([]map[string]string, len(prefixes))
+	err := es.withClient(func(etcdCli *clientv3.Client) error {
+		log.RatedDebug(10, "etcd refreshConfigurations", zap.Strings("prefixes", prefixes), zap.Any("endpoints", etcdCli.Endpoints()))
+		for i, prefix := range prefixes {
+			var err error
+			prefixConfigs[i], revision, err = es.loadPrefix(etcdCli, prefix, revision, pageSize, requestTimeout, readOpts...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, markAuthError(err)
+	}
+	return prefixConfigs, revision, nil
+}
+
+// PendingChanges returns the changes which refreshing would apply now, sorted by the keys, as a dry run of refreshConfigurations,
+// neither the configurations are updated nor the events are fired. The validation of handlers is not run either,
+// so the changes they would reject are returned too.
+func (es *EtcdSource) PendingChanges() ([]Event, error) {
+	if !es.track() {
+		return nil, ErrSourceClosed
+	}
+	defer es.refreshWg.Done()
+
+	prefixes, prefixConfigs, _, err := es.fetchConfigurations(clientv3.WithSerializable())
+	if err != nil {
+		if es.isClosed() {
+			return nil, ErrSourceClosed
+		}
+		return nil, err
+	}
+	es.RLock()
+	defer es.RUnlock()
+	if !slices.Equal(es.prefixes, prefixes) {
+		return nil, errors.New("prefixes of configurations changed while refreshing")
+	}
+	newConfig, _ := es.merge(prefixConfigs)
+	events, err := PopulateEvents(es.GetSourceName(), es.currentConfig, newConfig)
+	if err != nil {
+		return nil, err
+	}
+	changes := lo.Map(mergeDeleteEvents(events), func(e *Event, _ int) Event { return *e })
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes, nil
+}
+
+// loadPrefix reads the configurations under the prefix by pages of at most pageSize keys, each within the timeout,
+// at the revision or the one of the first page if it's 0, returns them with the revision read at.
+func (es *EtcdSource) loadPrefix(etcdCli *clientv3.Client, prefix string, revision, pageSize int64, timeout time.Duration,
+	readOpts ...clientv3.OpOption,
+) (map[string]string, int64, error) {
+	logger := es.logger()
+	configs := make(map[string]string)
+	key, end := prefix+"/", clientv3.GetPrefixRangeEnd(prefix+"/")
+	for {
+		opts := append([]clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(pageSize)}, readOpts...)
+		if revision > 0 {
+			opts = append(opts, clientv3.WithRev(revision))
+		}
+		ctx, cancel := context.WithTimeout(es.ctx, timeout)
+		resp, err := etcdCli.Get(ctx, key, opts...)
+		cancel()
+		if err != nil {
+			return nil, 0, err
+		}
+		if revision == 0 {
+			revision = resp.Header.GetRevision()
+		}
+		for _, kv := range resp.Kvs {
+			configs[strings.TrimPrefix(string(kv.Key), prefix+"/")] = string(kv.Value)
+			logger.Debug("got config from etcd", zap.String("key", string(kv.Key)), zap.String("value", string(kv.Value)))
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return configs, revision, nil
+		}
+		// continue from the key next to the last one
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// mergeConfigurations merges the decrypted configurations of prefixes, the later prefixes override the earlier ones.
+// The keys of a prefix formatted to the same key collide, the explicit keys win over the ones flattened from blobs,
+// then the lexicographically smallest one wins the formatted key, while the others are still got by their original keys.
+// It must be called with the lock.
+func (es *EtcdSource) mergeConfigurations(prefixConfigs []map[string]string) map[string]string {
+	merged, state := es.merge(prefixConfigs)
+	es.decrypted = state.decrypted
+	es.prevSecretKeys, es.secretKeys = es.secretKeys, state.secretKeys
+	es.blobs = state.blobs
+	es.decompressed = state.decompressed
+	es.originalKeys = state.originalKeys
+	es.keyCollisions = state.keyCollisions
+	maps.Copy(es.collisions, state.collisions)
+	return merged
+}
+
+// mergeState is the state of the configurations merged, which mergeConfigurations keeps in the source.
+type mergeState struct {
+	decrypted     map[string]decryption
+	blobs         map[string]blobConfigs
+	decompressed  map[string]decompression
+	originalKeys  map[string][]string
+	keyCollisions map[string]string
+	secretKeys    map[string]bool
+	// the winning prefixes of the keys newly collided across prefixes
+	collisions map[string]string
+}
+
+// merge merges the configurations as mergeConfigurations without updating the source, it must be called with the read lock at least.
+func (es *EtcdSource) merge(prefixConfigs []map[string]string) (map[string]string, mergeState) {
+	merged := make(map[string]string)
+	// the prefix index of each formatted key
+	winners := make(map[string]int)
+	decrypted := make(map[string]decryption)
+	blobs := make(map[string]blobConfigs)
+	decompressed := make(map[string]decompression)
+	originalKeys := make(map[string][]string)
+	keyCollisions := make(map[string]string)
+	secretKeys := make(map[string]bool)
+	collisions := make(map[string]string)
+	for i, configs := range prefixConfigs {
+		// the origin of the winning key of each formatted key in the prefix
+		claimed := make(map[string]string)
+		configs = es.decompressConfigs(i, configs, decompressed)
+		for _, entry := range es.prefixEntries(es.prefixes[i], configs, decrypted, blobs) {
+			key := entry.key
+			formattedKey := formatKey(key)
+			originalKeys[formattedKey] = append(originalKeys[formattedKey], entry.origin)
+			winner, collided := claimed[formattedKey]
+			if collided {
+				if es.keyCollisions[entry.origin] != winner {
+					es.logger().Warn("config keys collided after formatted, the explicit and smallest one wins", zap.String("formattedKey", formattedKey),
+						zap.String("winningKey", winner), zap.String("overriddenKey", entry.origin))
+				}
+				keyCollisions[entry.origin] = winner
+				if key == formattedKey {
+					// shadowed by the winner entirely
+					continue
+				}
+			} else {
+				claimed[formattedKey] = entry.origin
+			}
+			value, ok := es.decrypt(key, entry.value, decrypted)
+			if !ok {
+				// neither the ciphertext nor the overridden value is exposed
+				delete(merged, key)
+				if !collided {
+					delete(merged, formattedKey)
+				}
+				continue
+			}
+			merged[key] = value
+			if entry.secret {
+				secretKeys[key] = true
+				secretKeys[formattedKey] = true
+			}
+			if collided {
+				continue
+			}
+			if j, ok := winners[formattedKey]; ok && j != i && es.collisions[formattedKey] != es.prefixes[i] {
+				es.logger().Info("config key set in multiple prefixes, the later one wins", zap.String("key", key),
+					zap.String("overriddenPrefix", es.prefixes[j]), zap.String("winningPrefix", es.prefixes[i]))
+				collisions[formattedKey] = es.prefixes[i]
+			}
+			winners[formattedKey] = i
+			merged[formattedKey] = value
+		}
+	}
+	return merged, mergeState{
+		decrypted:     decrypted,
+		blobs:         blobs,
+		decompressed:  decompressed,
+		originalKeys:  originalKeys,
+		keyCollisions: keyCollisions,
+		secretKeys:    secretKeys,
+		collisions:    collisions,
+	}
+}
+
+type configEntry struct {
+	key   string
+	value string
+	// the etcd key, followed by "#" and the key in it if it's a blob
+	origin string
+	// the value is encrypted, or flattened from an encrypted blob
+	secret bool
+}
+
+type blobConfigs struct {
+	// the last value parsed successfully, and the configs flattened from it
+	value   string
+	configs map[string]string
+	// the last value failed to parse, to log each failure once
+	failedValue string
+}
+
+// prefixEntries returns the configurations of the prefix, with the ones flattened from the blobs, see parseBlob.
+// The explicit keys come first in sorted order, then the flattened ones not set explicitly, the later blobs override the earlier ones.
+// A blob failed to parse keeps the configs flattened from its last value parsed, which are cached in blobs.
+func (es *EtcdSource) prefixEntries(prefix string, configs map[string]string, decrypted map[string]decryption,
+	blobs map[string]blobConfigs,
+) []configEntry {
+	var keys, blobKeys []string
+	for key := range configs {
+		if es.isBlob(key) {
+			blobKeys = append(blobKeys, key)
+		} else {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	slices.Sort(blobKeys)
+	entries := make([]configEntry, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, configEntry{key: key, value: configs[key], origin: prefix + "/" + key, secret: es.isEncrypted(configs[key])})
+	}
+
+	flattened := make(map[string]configEntry)
+	for _, blobKey := range blobKeys {
+		etcdKey := prefix + "/" + blobKey
+		value, ok := es.decrypt(blobKey, configs[blobKey], decrypted)
+		if !ok {
+			continue
+		}
+		blob, ok := es.blobs[etcdKey]
+		if !ok || (blob.value != value && blob.failedValue != value) {
+			blobConfigs, err := parseBlob(blobKey, value)
+			if err != nil {
+				es.logger().Error("failed to parse config blob, keep the configs of its last value", zap.String("key", etcdKey), zap.Error(err))
+				blob.failedValue = value
+			} else {
+				blob.value, blob.configs, blob.failedValue = value, blobConfigs, ""
+			}
+		}
+		blobs[etcdKey] = blob
+		for key, value := range blob.configs {
+			if _, ok := configs[key]; !ok {
+				flattened[key] = configEntry{key: key, value: value, origin: etcdKey + "#" + key, secret: es.isEncrypted(configs[blobKey])}
+			}
+		}
+	}
+	flattenedKeys := lo.Keys(flattened)
+	slices.Sort(flattenedKeys)
+	for _, key := range flattenedKeys {
+		entries = append(entries, flattened[key])
+	}
+	return entries
+}
+
+func (es *EtcdSource) isBlob(key string) bool {
+	return lo.ContainsBy(es.blobSuffixes, func(suffix string) bool {
+		return strings.HasSuffix(key, suffix)
+	})
+}
+
+// parseBlob flattens the nested YAML or JSON document by the extension of the key with dot separated keys,
+// each element of the lists is keyed by its index, e.g. "a.b.0", and the lists of scalars are also joined with comma as the file source.
+func parseBlob(key, value string) (map[string]string, error) {
+	reader := viper.New()
+	reader.SetConfigType(strings.TrimPrefix(path.Ext(key), "."))
+	if err := reader.ReadConfig(strings.NewReader(value)); err != nil {
+		return nil, err
+	}
+	configs := make(map[string]string)
+	for _, key := range reader.AllKeys() {
+		if err := flattenBlobValue(configs, key, reader.Get(key)); err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
+
+func flattenBlobValue(configs map[string]string, key string, value any) error {
+	switch value := value.(type) {
+	case map[string]any:
+		for k, v := range value {
+			if err := flattenBlobValue(configs, key+"."+k, v); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		// the nested maps of YAML lists
+		for k, v := range value {
+			if err := flattenBlobValue(configs, key+"."+cast.ToString(k), v); err != nil {
+				return err
+			}
+		}
+	case []any:
+		values := make([]string, 0, len(value))
+		for i, v := range value {
+			elemKey := key + "." + strconv.Itoa(i)
+			if err := flattenBlobValue(configs, elemKey, v); err != nil {
+				return err
+			}
+			if str, ok := configs[elemKey]; ok {
+				values = append(values, str)
+			}
+		}
+		// the lists of scalars only
+		if len(values) == len(value) {
+			configs[key] = strings.Join(values, ",")
+		}
+	default:
+		str, err := cast.ToStringE(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value of %s", key)
+		}
+		configs[key] = str
+	}
+	return nil
+}
+
+// GetOriginalKeys returns the etcd keys formatted to the key, there are multiple ones if they collide.
+// They're in the order of prefixes, then the explicit keys in sorted order before the ones flattened from blobs.
+func (es *EtcdSource) GetOriginalKeys(formattedKey string) []string {
+	es.RLock()
+	defer es.RUnlock()
+	return slices.Clone(es.originalKeys[formatKey(formattedKey)])
+}
 
-	ctx, cancel := context.WithTimeout(es.ctx, ReadConfigTimeout)
+func (es *EtcdSource) isEncrypted(value string) bool {
+	return strings.HasPrefix(value, es.encryptedPrefix)
+}
+
+// isSecret returns whether the value of the key is encrypted, now or before the configurations merged last time.
+// It must be called with the lock.
+func (es *EtcdSource) isSecret(key string) bool {
+	return es.secretKeys[key] || es.prevSecretKeys[key]
+}
+
+type decryption struct {
+	value string
+	err   error
+}
+
+// decrypt returns the value decrypted if it's encrypted, or false if failed to decrypt it.
+// The results are cached by the ciphertexts, the ones of current configurations are kept in the cache.
+func (es *EtcdSource) decrypt(key, value string, cache map[string]decryption) (string, bool) {
+	ciphertext, ok := strings.CutPrefix(value, es.encryptedPrefix)
+	if !ok {
+		return value, true
+	}
+	result, ok := es.decrypted[ciphertext]
+	if !ok {
+		if es.decryptor == nil {
+			result.err = ErrNoDecryptor
+		} else {
+			result.value, result.err = es.decryptor.Decrypt(ciphertext)
+		}
+		if result.err != nil {
+			es.logger().Error("failed to decrypt config, which is left out", zap.String("key", key), zap.Error(result.err))
+		}
+	}
+	cache[ciphertext] = result
+	return result.value, result.err == nil
+}
+
+type decompression struct {
+	// the last payload decompressed successfully, and its value decompressed
+	payload string
+	value   string
+	ok      bool
+	// the last payload failed to decompress, to log each failure once
+	failedPayload string
+}
+
+// decompressConfigs returns the configurations of the prefix at index with the compressed values decompressed, see CompressedPrefix.
+// A value failed to decompress keeps its last value, which is the last one decompressed or the previous one not compressed,
+// or is left out if neither. The results are cached by the etcd keys, it must be called with the read lock at least.
+func (es *EtcdSource) decompressConfigs(index int, configs map[string]string, cache map[string]decompression) map[string]string {
+	var decompressed map[string]string
+	for key, value := range configs {
+		payload, ok := strings.CutPrefix(value, CompressedPrefix)
+		if !ok {
+			continue
+		}
+		if decompressed == nil {
+			decompressed = make(map[string]string, len(configs))
+			for k, v := range configs {
+				decompressed[k] = v
+			}
+		}
+		etcdKey := es.prefixes[index] + "/" + key
+		result := es.decompressed[etcdKey]
+		if result.payload != payload && result.failedPayload != payload {
+			value, err := DecompressValue(value)
+			if err != nil {
+				es.logger().Error("failed to decompress config, keep its last value", zap.String("key", etcdKey), zap.Error(err))
+				result.failedPayload = payload
+				if prev, ok := es.previousConfig(index, key); !result.ok && ok && !strings.HasPrefix(prev, CompressedPrefix) {
+					result.value, result.ok = prev, true
+				}
+			} else {
+				result = decompression{payload: payload, value: value, ok: true}
+			}
+		}
+		cache[etcdKey] = result
+		if !result.ok {
+			delete(decompressed, key)
+			continue
+		}
+		decompressed[key] = result.value
+	}
+	if decompressed == nil {
+		return configs
+	}
+	return decompressed
+}
+
+// previousConfig returns the value of the key in the current configurations of the prefix at index.
+func (es *EtcdSource) previousConfig(index int, key string) (string, bool) {
+	if index >= len(es.prefixConfigs) {
+		return "", false
+	}
+	value, ok := es.prefixConfigs[index][key]
+	return value, ok
+}
+
+// SetConfig writes the config to the prefix with the highest precedence, which is compressed if it's larger than
+// EtcdInfo.CompressThreshold, and refreshes the configurations at once to fire the events.
+func (es *EtcdSource) SetConfig(key, value string) error {
+	etcdKey, err := es.writableKey(key)
+	if err != nil {
+		return err
+	}
+	es.RLock()
+	threshold := es.compressThreshold
+	es.RUnlock()
+	value, err = compressIfLarge(value, threshold)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(es.ctx, WriteConfigTimeout)
 	defer cancel()
-	log.RatedDebug(10, "etcd refreshConfigurations", zap.String("prefix", prefix), zap.Any("endpoints", es.etcdCli.Endpoints()))
-	response, err := es.etcdCli.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithSerializable())
+	err = es.withClient(func(etcdCli *clientv3.Client) error {
+		_, err := etcdCli.Put(ctx, etcdKey, value)
+		return err
+	})
+	if err != nil {
+		return markAuthError(err)
+	}
+	es.updateOptimistically(key, &value)
+	return nil
+}
+
+// DeleteConfig deletes the config from the prefix with the highest precedence,
+// and refreshes the configurations at once to fire the events.
+func (es *EtcdSource) DeleteConfig(key string) error {
+	etcdKey, err := es.writableKey(key)
 	if err != nil {
 		return err
 	}
-	newConfig := make(map[string]string, len(response.Kvs))
-	for _, kv := range response.Kvs {
-		key := string(kv.Key)
-		key = strings.TrimPrefix(key, prefix+"/")
-		newConfig[key] = string(kv.Value)
-		newConfig[formatKey(key)] = string(kv.Value)
-		log.Debug("got config from etcd", zap.String("key", string(kv.Key)), zap.String("value", string(kv.Value)))
+	ctx, cancel := context.WithTimeout(es.ctx, WriteConfigTimeout)
+	defer cancel()
+	err = es.withClient(func(etcdCli *clientv3.Client) error {
+		_, err := etcdCli.Delete(ctx, etcdKey)
+		return err
+	})
+	if err != nil {
+		return markAuthError(err)
 	}
+	es.updateOptimistically(key, nil)
+	return nil
+}
+
+// CompareAndSwap writes the config as SetConfig only if its value is expected, or it doesn't exist if expected is empty,
+// returns whether it's swapped.
+func (es *EtcdSource) CompareAndSwap(key, expected, value string) (bool, error) {
+	etcdKey, err := es.writableKey(key)
+	if err != nil {
+		return false, err
+	}
+	cmp := clientv3.Compare(clientv3.Value(etcdKey), "=", expected)
+	if expected == "" {
+		cmp = clientv3.Compare(clientv3.CreateRevision(etcdKey), "=", 0)
+	}
+	ctx, cancel := context.WithTimeout(es.ctx, WriteConfigTimeout)
+	defer cancel()
+	var resp *clientv3.TxnResponse
+	err = es.withClient(func(etcdCli *clientv3.Client) error {
+		resp, err = etcdCli.Txn(ctx).If(cmp).Then(clientv3.OpPut(etcdKey, value)).Commit()
+		return err
+	})
+	if err != nil {
+		return false, markAuthError(err)
+	}
+	if !resp.Succeeded {
+		return false, nil
+	}
+	es.updateOptimistically(key, &value)
+	return true, nil
+}
+
+// writableKey returns the etcd key of the config in the prefix with the highest precedence.
+func (es *EtcdSource) writableKey(key string) (string, error) {
+	if es.readOnly {
+		return "", ErrReadOnly
+	}
+	if key == "" {
+		return "", errors.New("empty config key")
+	}
+	es.RLock()
+	defer es.RUnlock()
+	return path.Join(es.prefixes[len(es.prefixes)-1], key), nil
+}
+
+// updateOptimistically applies the written config, deleted if value is nil, and fires the events at once.
+// Then re-syncs the configurations, which is linearizable to read the write.
+func (es *EtcdSource) updateOptimistically(key string, value *string) {
+	es.Lock()
+	// skip it if not loaded yet
+	if len(es.prefixConfigs) == len(es.prefixes) {
+		index := len(es.prefixes) - 1
+		configs := make(map[string]string, len(es.prefixConfigs[index]))
+		for k, v := range es.prefixConfigs[index] {
+			configs[k] = v
+		}
+		if value != nil {
+			configs[key] = *value
+		} else {
+			delete(configs, key)
+		}
+		prefixConfigs := slices.Clone(es.prefixConfigs)
+		prefixConfigs[index] = configs
+		newConfig := es.mergeConfigurations(prefixConfigs)
+		if err := es.configRefresher.fireEvents(es.GetSourceName(), es.currentConfig, newConfig); err == nil {
+			es.currentConfig = newConfig
+			es.prefixConfigs = prefixConfigs
+		}
+	}
+	es.Unlock()
+
+	if err := es.refresh(); err != nil {
+		es.logger().Warn("failed to refresh configurations after written, retry later", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// startWatch starts watching the configurations, unless it's started already or refreshing is disabled.
+func (es *EtcdSource) startWatch() {
+	es.watchMu.Lock()
+	defer es.watchMu.Unlock()
+	if es.watchCancel != nil || es.refreshInterval() <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(es.ctx)
+	es.watchCancel = cancel
+	es.watchWg.Add(1)
+	go es.watchConfigurations(ctx)
+}
+
+// stopWatch stops watching the configurations, returns false if it's not started.
+func (es *EtcdSource) stopWatch() bool {
+	es.watchMu.Lock()
+	cancel := es.watchCancel
+	es.watchCancel = nil
+	es.watchMu.Unlock()
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	es.watchWg.Wait()
+	return true
+}
+
+func (es *EtcdSource) refreshInterval() time.Duration {
+	return es.configRefresher.interval()
+}
+
+// watchConfigurations applies the changes of configurations as soon as they are watched.
+// Once the watch is broken, e.g. the revision is compacted or the watch is canceled,
+// it falls back to polling the configurations every refresh interval, until re-synced and watched again.
+func (es *EtcdSource) watchConfigurations(ctx context.Context) {
+	defer es.watchWg.Done()
+	es.logger().Info("start watching configurations")
+	for {
+		es.RLock()
+		prefixes := es.prefixes
+		revision := es.revision
+		es.RUnlock()
+
+		es.watchFrom(ctx, prefixes, revision)
+		for {
+			select {
+			case <-ctx.Done():
+				es.logger().Info("stop watching configurations")
+				return
+			case <-time.After(es.retryDelay()):
+			}
+			// the failure is logged already
+			if es.refreshConfigurations() == nil {
+				break
+			}
+		}
+	}
+}
+
+// watchResponse is the response watched of the prefix at index.
+type watchResponse struct {
+	index  int
+	resp   clientv3.WatchResponse
+	closed bool
+}
+
+// watchFrom applies the changes of configurations under prefixes after revision, until any watch is broken.
+func (es *EtcdSource) watchFrom(ctx context.Context, prefixes []string, revision int64) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	respCh := make(chan watchResponse)
+	for i, prefix := range prefixes {
+		// the watch is stopped before rotating the credentials, see UpdateOptions
+		es.clientMu.RLock()
+		watchCh := es.etcdCli.Watch(clientv3.WithRequireLeader(ctx), prefix+"/", clientv3.WithPrefix(), clientv3.WithRev(revision+1))
+		es.clientMu.RUnlock()
+		go func(i int) {
+			for resp := range watchCh {
+				select {
+				case respCh <- watchResponse{index: i, resp: resp}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case respCh <- watchResponse{index: i, closed: true}:
+			case <-ctx.Done():
+			}
+		}(i)
+	}
+
+	for {
+		var watched watchResponse
+		select {
+		case <-ctx.Done():
+			return
+		case watched = <-respCh:
+		}
+		prefix := prefixes[watched.index]
+		if watched.closed {
+			es.logger().Warn("watch channel of configurations closed, fall back to polling until re-synced", zap.String("prefix", prefix))
+			return
+		}
+		if err := watched.resp.Err(); err != nil {
+			es.logger().Warn("watch configurations failed, fall back to polling until re-synced",
+				zap.String("prefix", prefix), zap.Int64("compactRevision", watched.resp.CompactRevision), zap.Error(err))
+			return
+		}
+		if err := es.applyEvents(watched.index, prefix, watched.resp.Events, watched.resp.Header.GetRevision()); err != nil {
+			es.logger().Warn("apply watched configurations failed, fall back to polling until re-synced", zap.String("prefix", prefix), zap.Error(err))
+			return
+		}
+		es.recordRefresh(nil)
+	}
+}
+
+// applyEvents applies the watched changes to the configurations, and fires the events of them.
+func (es *EtcdSource) applyEvents(index int, prefix string, events []*clientv3.Event, revision int64) error {
 	es.Lock()
 	defer es.Unlock()
-	err = es.configRefresher.fireEvents(es.GetSourceName(), es.currentConfig, newConfig)
+	if es.isClosed() {
+		return ErrSourceClosed
+	}
+	if index >= len(es.prefixes) || es.prefixes[index] != prefix {
+		// the prefixes are changed, and re-synced soon
+		return nil
+	}
+	if revision <= es.watchedRevision[index] {
+		// re-synced already
+		return nil
+	}
+	configs := make(map[string]string, len(es.prefixConfigs[index]))
+	for key, value := range es.prefixConfigs[index] {
+		configs[key] = value
+	}
+	logger := es.logger()
+	for _, event := range events {
+		key := strings.TrimPrefix(string(event.Kv.Key), prefix+"/")
+		switch event.Type {
+		case clientv3.EventTypePut:
+			configs[key] = string(event.Kv.Value)
+			logger.Debug("watched config from etcd", zap.String("key", string(event.Kv.Key)), zap.String("value", string(event.Kv.Value)))
+		case clientv3.EventTypeDelete:
+			delete(configs, key)
+			logger.Debug("watched config deleted from etcd", zap.String("key", string(event.Kv.Key)))
+		}
+	}
+	prefixConfigs := slices.Clone(es.prefixConfigs)
+	prefixConfigs[index] = configs
+	newConfig := es.mergeConfigurations(prefixConfigs)
+	err := es.configRefresher.fireEvents(es.GetSourceName(), es.currentConfig, newConfig)
 	if err != nil {
-		return err
+		// the changes held while frozen are reloaded in full once unfrozen
+		return ignoreFrozen(err)
 	}
 	es.currentConfig = newConfig
+	es.prefixConfigs = prefixConfigs
+	es.watchedRevision[index] = revision
 	return nil
 }
@@ -0,0 +1,2242 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3client"
+	"go.uber.org/atomic"
+
+	"github.com/milvus-io/milvus/pkg/metrics"
+)
+
+// cancelableWatcher cancels the watches on demand, as if they are canceled by etcd.
+type cancelableWatcher struct {
+	clientv3.Watcher
+	mu      sync.Mutex
+	cancels []context.CancelFunc
+}
+
+func (w *cancelableWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	ctx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	w.cancels = append(w.cancels, cancel)
+	w.mu.Unlock()
+	return w.Watcher.Watch(ctx, key, opts...)
+}
+
+func (w *cancelableWatcher) watching() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.cancels) > 0
+}
+
+// slowKV delays the txns, as if etcd is congested.
+type slowKV struct {
+	clientv3.KV
+	delay time.Duration
+}
+
+func (kv *slowKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	select {
+	case <-time.After(kv.delay):
+		return kv.KV.Get(ctx, key, opts...)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (kv *slowKV) Txn(ctx context.Context) clientv3.Txn {
+	return &slowTxn{Txn: kv.KV.Txn(ctx), ctx: ctx, delay: kv.delay}
+}
+
+type slowTxn struct {
+	clientv3.Txn
+	ctx   context.Context
+	delay time.Duration
+}
+
+func (txn *slowTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	txn.Txn = txn.Txn.If(cs...)
+	return txn
+}
+
+func (txn *slowTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	txn.Txn = txn.Txn.Then(ops...)
+	return txn
+}
+
+func (txn *slowTxn) Commit() (*clientv3.TxnResponse, error) {
+	select {
+	case <-time.After(txn.delay):
+		return txn.Txn.Commit()
+	case <-txn.ctx.Done():
+		return nil, txn.ctx.Err()
+	}
+}
+
+// lateKV responds after the delay even if the request is canceled, as if the response is already on the way.
+type lateKV struct {
+	clientv3.KV
+	delay time.Duration
+}
+
+func (kv *lateKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	time.Sleep(kv.delay)
+	return kv.KV.Get(context.Background(), key, opts...)
+}
+
+// pagingKV counts the pages read, and calls afterFirstPage once the first page is read.
+type pagingKV struct {
+	clientv3.KV
+	pages          atomic.Int64
+	afterFirstPage func()
+}
+
+func (kv *pagingKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := kv.KV.Get(ctx, key, opts...)
+	if err == nil && kv.pages.Inc() == 1 && kv.afterFirstPage != nil {
+		kv.afterFirstPage()
+	}
+	return resp, err
+}
+
+func (w *cancelableWatcher) cancelAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, cancel := range w.cancels {
+		cancel()
+	}
+	w.cancels = nil
+}
+
+func TestEtcdSourceWatch(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints: []string{cfg.ACUrls[0].Host},
+		KeyPrefix: "test_watch",
+		// changes are only propagated by watch in time
+		RefreshInterval: time.Hour,
+		Watch:           true,
+	})
+	require.NoError(t, err)
+	watcher := &cancelableWatcher{Watcher: es.etcdCli.Watcher}
+	es.etcdCli.Watcher = watcher
+
+	var mu sync.Mutex
+	var events []*Event
+	es.SetEventHandler(NewHandler("test", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+
+	_, err = client.Put(ctx, "test_watch/config/a/b", "1")
+	require.NoError(t, err)
+	configs, err := es.GetConfigurations()
+	require.NoError(t, err)
+	assert.Equal(t, "1", configs["a/b"])
+	defer es.Close()
+
+	assertConfig := func(key, expected string) {
+		assert.Eventually(t, func() bool {
+			value, err := es.GetConfigurationByKey(key)
+			if expected == "" {
+				return err != nil
+			}
+			return err == nil && value == expected
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("propagated by watch", func(t *testing.T) {
+		_, err = client.Put(ctx, "test_watch/config/a/b", "2")
+		require.NoError(t, err)
+		assertConfig("a/b", "2")
+		assertConfig(formatKey("a/b"), "2")
+
+		_, err = client.Put(ctx, "test_watch/config/c/d", "3")
+		require.NoError(t, err)
+		assertConfig("c/d", "3")
+
+		_, err = client.Delete(ctx, "test_watch/config/c/d")
+		require.NoError(t, err)
+		assertConfig("c/d", "")
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, events, newEvent(es.GetSourceName(), UpdateType, formatKey("a/b"), "2"))
+		assert.Contains(t, events, newEvent(es.GetSourceName(), CreateType, formatKey("c/d"), "3"))
+		// a single delete event per logical key
+		assert.Contains(t, events, newEvent(es.GetSourceName(), DeleteType, "c/d", "3"))
+		assert.NotContains(t, events, newEvent(es.GetSourceName(), DeleteType, formatKey("c/d"), "3"))
+	})
+
+	t.Run("recover from canceled watch", func(t *testing.T) {
+		es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{
+			KeyPrefix:       "test_watch",
+			RefreshInterval: 50 * time.Millisecond,
+		}})
+
+		assert.Eventually(t, watcher.watching, time.Second, 10*time.Millisecond)
+		watcher.cancelAll()
+		// changes during the watch is broken are re-synced by polling
+		_, err = client.Put(ctx, "test_watch/config/a/b", "4")
+		require.NoError(t, err)
+		assertConfig("a/b", "4")
+
+		// and watched again
+		assert.Eventually(t, watcher.watching, time.Second, 10*time.Millisecond)
+		es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{
+			KeyPrefix:       "test_watch",
+			RefreshInterval: time.Hour,
+		}})
+		_, err = client.Put(ctx, "test_watch/config/a/b", "5")
+		require.NoError(t, err)
+		assertConfig("a/b", "5")
+	})
+
+	t.Run("recover from compacted revision", func(t *testing.T) {
+		assert.True(t, es.stopWatch())
+		es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{
+			KeyPrefix:       "test_watch",
+			RefreshInterval: 50 * time.Millisecond,
+		}})
+
+		// the revision watched from is compacted
+		for i := 0; i < 3; i++ {
+			_, err = client.Put(ctx, "test_watch/config/e/f", "6")
+			require.NoError(t, err)
+		}
+		resp, err := client.Put(ctx, "test_watch/config/a/b", "6")
+		require.NoError(t, err)
+		_, err = client.Compact(ctx, resp.Header.GetRevision())
+		require.NoError(t, err)
+
+		es.startWatch()
+		assertConfig("a/b", "6")
+		assertConfig("e/f", "6")
+	})
+}
+
+func TestGetConfigurationsByKeyPrefix(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	_, err = client.Put(ctx, "test_prefix/config/quotaAndLimits/dml/enabled", "true")
+	require.NoError(t, err)
+	_, err = client.Put(ctx, "test_prefix/config/quotaAndLimits.limitWriting.forceDeny", "true")
+	require.NoError(t, err)
+	_, err = client.Put(ctx, "test_prefix/config/proxy/maxNameLength", "100")
+	require.NoError(t, err)
+
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("quotaAndLimits:\n  dml:\n    enabled: false\n  ddl:\n    enabled: true\nproxy:\n  port: 19530\n"), 0o600))
+
+	mgr, err := Init(
+		WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}),
+		WithEtcdSource(&EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       "test_prefix",
+			RefreshInterval: time.Hour,
+		}))
+	require.NoError(t, err)
+	defer mgr.Close()
+
+	es, ok := mgr.sources.Get("EtcdSource[test_prefix]")
+	require.True(t, ok)
+
+	t.Run("etcd source", func(t *testing.T) {
+		// both the raw keys and the formatted ones are matched, in any format of the prefix
+		for _, prefix := range []string{"quotaAndLimits.", "quotaAndLimits/", "quotaandlimits"} {
+			configs, err := es.GetConfigurationsByKeyPrefix(prefix)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{
+				"quotaAndLimits/dml/enabled":            "true",
+				"quotaandlimitsdmlenabled":              "true",
+				"quotaAndLimits.limitWriting.forceDeny": "true",
+				"quotaandlimitslimitwritingforcedeny":   "true",
+			}, configs, prefix)
+		}
+
+		configs, err := es.GetConfigurationsByKeyPrefix("quotaAndLimits.dml")
+		assert.NoError(t, err)
+		assert.Len(t, configs, 2)
+
+		configs, err = es.GetConfigurationsByKeyPrefix("queryNode.")
+		assert.NoError(t, err)
+		assert.Empty(t, configs)
+	})
+
+	t.Run("manager", func(t *testing.T) {
+		configs := mgr.GetConfigsByKeyPrefix("quotaAndLimits.")
+		// the values in etcd override the ones in file
+		assert.Equal(t, map[string]string{
+			"quotaAndLimits/dml/enabled":            "true",
+			"quotaandlimits.dml.enabled":            "true",
+			"quotaandlimitsdmlenabled":              "true",
+			"quotaandlimits.ddl.enabled":            "true",
+			"quotaandlimitsddlenabled":              "true",
+			"quotaAndLimits.limitWriting.forceDeny": "true",
+			"quotaandlimitslimitwritingforcedeny":   "true",
+		}, configs)
+
+		mgr.SetConfig("quotaAndLimits.ddl.enabled", "false")
+		mgr.DeleteConfig("quotaAndLimits.limitWriting.forceDeny")
+		mgr.SetConfig("quotaAndLimits.enabled", "true")
+		defer mgr.ResetConfig("quotaAndLimits.ddl.enabled")
+		defer mgr.ResetConfig("quotaAndLimits.limitWriting.forceDeny")
+		defer mgr.ResetConfig("quotaAndLimits.enabled")
+		configs = mgr.GetConfigsByKeyPrefix("quotaAndLimits.")
+		assert.Equal(t, map[string]string{
+			"quotaAndLimits/dml/enabled": "true",
+			"quotaandlimits.dml.enabled": "true",
+			"quotaandlimitsdmlenabled":   "true",
+			"quotaandlimits.ddl.enabled": "false",
+			"quotaandlimitsddlenabled":   "false",
+			"quotaandlimitsenabled":      "true",
+		}, configs)
+	})
+}
+
+func TestEtcdSourceDeleteEvents(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	for key, value := range map[string]string{"a/b": "1", "c.d": "2", "e_f": "3"} {
+		_, err = client.Put(ctx, "test_delete/config/"+key, value)
+		require.NoError(t, err)
+	}
+
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: file\n"), 0o600))
+	mgr, err := Init(
+		WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}),
+		WithEtcdSource(&EtcdInfo{
+			Endpoints: []string{cfg.ACUrls[0].Host},
+			KeyPrefix: "test_delete",
+			// refreshed manually
+			RefreshInterval: -1,
+		}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	source, ok := mgr.sources.Get("EtcdSource[test_delete]")
+	require.True(t, ok)
+	es := source.(*EtcdSource)
+
+	var mu sync.Mutex
+	var deleted []*Event
+	mgr.Dispatcher.RegisterForKeyPrefix("", NewHandler("test", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event.EventType == DeleteType {
+			deleted = append(deleted, event)
+		}
+	}))
+
+	value, err := mgr.GetConfig("a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	_, err = client.Delete(ctx, "test_delete/config/a/b")
+	require.NoError(t, err)
+	_, err = client.Delete(ctx, "test_delete/config/e_f")
+	require.NoError(t, err)
+	require.NoError(t, es.refreshConfigurations())
+
+	// the deleted key falls back to the file, rather than shadowed by the stale formatted key
+	value, err = mgr.GetConfig("a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "file", value)
+	_, err = mgr.GetConfig("e_f")
+	assert.Error(t, err)
+	_, err = mgr.GetConfig("ef")
+	assert.Error(t, err)
+	value, err = mgr.GetConfig("c.d")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", value)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"a/b", "e_f"}, lo.Map(deleted, func(e *Event, _ int) string { return e.Key }))
+	for _, e := range deleted {
+		switch e.Key {
+		case "a/b":
+			assert.Equal(t, "1", e.Value)
+		case "e_f":
+			assert.Equal(t, "3", e.Value)
+		}
+	}
+}
+
+func TestEtcdSourcePendingChanges(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	for key, value := range map[string]string{"a/b": "1", "c.d": "2"} {
+		_, err = client.Put(ctx, "test_pending/config/"+key, value)
+		require.NoError(t, err)
+	}
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_pending",
+		RefreshInterval: -1,
+	})
+	require.NoError(t, err)
+	defer es.Close()
+	fired := atomic.NewInt32(0)
+	es.SetEventHandler(NewHandler("test", func(*Event) {
+		fired.Inc()
+	}))
+	require.NoError(t, es.refreshConfigurations())
+	fired.Store(0)
+
+	changes, err := es.PendingChanges()
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = client.Put(ctx, "test_pending/config/a/b", "10")
+	require.NoError(t, err)
+	_, err = client.Delete(ctx, "test_pending/config/c.d")
+	require.NoError(t, err)
+	_, err = client.Put(ctx, "test_pending/config/e_f", "3")
+	require.NoError(t, err)
+
+	expected := []Event{
+		{EventSource: es.GetSourceName(), EventType: UpdateType, Key: "a/b", Value: "10"},
+		{EventSource: es.GetSourceName(), EventType: UpdateType, Key: "ab", Value: "10"},
+		{EventSource: es.GetSourceName(), EventType: DeleteType, Key: "c.d", Value: "2"},
+		{EventSource: es.GetSourceName(), EventType: CreateType, Key: "e_f", Value: "3"},
+		{EventSource: es.GetSourceName(), EventType: CreateType, Key: "ef", Value: "3"},
+	}
+	changes, err = es.PendingChanges()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, changes)
+	// neither applied nor fired
+	value, err := es.GetConfigurationByKey("a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+	assert.Zero(t, fired.Load())
+
+	// concurrently with refreshing
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := es.PendingChanges()
+			assert.NoError(t, err)
+		}()
+	}
+	assert.NoError(t, es.refreshConfigurations())
+	wg.Wait()
+	assert.EqualValues(t, len(expected), fired.Load())
+	changes, err = es.PendingChanges()
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	es.Close()
+	_, err = es.PendingChanges()
+	assert.ErrorIs(t, err, ErrSourceClosed)
+}
+
+func TestSourceInitialLoad(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+	client := v3client.New(e.Server)
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+
+	test := func(t *testing.T, s Source, set func(key, value string), refresh func() error) {
+		set("a.b", "1")
+		set("c.d", "2")
+		var mu sync.Mutex
+		received := make(map[string][]string)
+		// the source is locked while firing the changes, but not while replaying the initial load
+		replaying := atomic.NewBool(true)
+		record := func(ident string) EventHandler {
+			return NewHandler(ident, func(e *Event) {
+				if replaying.Load() {
+					// committed before delivered
+					value, err := s.GetConfigurationByKey(e.Key)
+					assert.NoError(t, err)
+					assert.Equal(t, e.Value, value)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				received[ident] = append(received[ident], e.EventType+" "+e.Key+"="+e.Value)
+			})
+		}
+		pop := func(ident string) []string {
+			mu.Lock()
+			defer mu.Unlock()
+			defer delete(received, ident)
+			return received[ident]
+		}
+
+		s.AddEventHandler(WithInitialLoad(record("before")))
+		_, err := s.GetConfigurations()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"CREATE a.b=1", "CREATE ab=1", "CREATE c.d=2", "CREATE cd=2"}, pop("before"))
+
+		s.AddEventHandler(WithInitialLoad(record("after")))
+		assert.Equal(t, []string{"CREATE a.b=1", "CREATE ab=1", "CREATE c.d=2", "CREATE cd=2"}, pop("after"))
+
+		// no replays since then
+		replaying.Store(false)
+		set("a.b", "10")
+		require.NoError(t, refresh())
+		_, err = s.GetConfigurations()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"UPDATE a.b=10", "UPDATE ab=10"}, pop("before"))
+		assert.ElementsMatch(t, []string{"UPDATE a.b=10", "UPDATE ab=10"}, pop("after"))
+	}
+
+	t.Run("etcd", func(t *testing.T) {
+		es, err := NewEtcdSource(&EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       "test_initial",
+			RefreshInterval: -1,
+		})
+		require.NoError(t, err)
+		defer es.Close()
+		test(t, es, func(key, value string) {
+			_, err := client.Put(context.Background(), "test_initial/config/"+key, value)
+			require.NoError(t, err)
+		}, es.refreshConfigurations)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		fs := NewFileSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1})
+		defer fs.Close()
+		configs := make(map[string]string)
+		test(t, fs, func(key, value string) {
+			configs[key] = value
+			var content string
+			for _, key := range lo.Keys(configs) {
+				content += key + ": " + configs[key] + "\n"
+			}
+			require.NoError(t, os.WriteFile(yamlFile, []byte(content), 0o600))
+		}, fs.loadFromFile)
+	})
+}
+
+func TestEtcdSourceHealth(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	_, err = client.Put(context.Background(), "test_health/config/a/b", "1")
+	require.NoError(t, err)
+
+	newSource := func() *EtcdSource {
+		es, err := NewEtcdSource(&EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       "test_health",
+			RefreshInterval: time.Minute,
+			// refreshed manually
+			RefreshJitter: -1,
+		})
+		require.NoError(t, err)
+		return es
+	}
+
+	t.Run("never loaded", func(t *testing.T) {
+		es := newSource()
+		health := es.Health()
+		assert.False(t, health.Connected)
+		assert.NoError(t, health.LastError)
+		assert.True(t, health.Stale(time.Hour))
+		es.etcdCli.Close()
+		_, err := es.GetConfigurations()
+		assert.Error(t, err)
+		health = es.Health()
+		assert.False(t, health.Connected)
+		assert.ErrorContains(t, health.LastError, "never loaded")
+		assert.True(t, health.LastSuccessTime.IsZero())
+		assert.True(t, health.Stale(time.Hour))
+		assert.True(t, es.LastSuccess().IsZero())
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		es := newSource()
+		defer es.Close()
+		_, err := es.GetConfigurations()
+		require.NoError(t, err)
+		health := es.Health()
+		assert.True(t, health.Connected)
+		assert.NoError(t, health.LastError)
+		assert.Equal(t, 2, health.KeyCount)
+		assert.False(t, health.Stale(0))
+		lastSuccess := es.LastSuccess()
+		assert.False(t, lastSuccess.IsZero())
+
+		failures := metrics.ConfigRefreshFailCounter.WithLabelValues(es.GetSourceName())
+		failed := testutil.ToFloat64(failures)
+		es.etcdCli.Close()
+		// the stale configurations are still served
+		configs, err := es.GetConfigurations()
+		assert.NoError(t, err)
+		assert.Equal(t, "1", configs["a/b"])
+		health = es.Health()
+		assert.False(t, health.Connected)
+		assert.ErrorContains(t, health.LastError, "stale since")
+		assert.Equal(t, lastSuccess, health.LastSuccessTime)
+		assert.Equal(t, 2, health.KeyCount)
+		assert.False(t, health.Stale(time.Hour))
+		assert.True(t, health.Stale(0))
+		assert.Equal(t, lastSuccess, es.LastSuccess())
+		assert.Equal(t, 1, es.failures)
+		assert.Equal(t, failed+1, testutil.ToFloat64(failures))
+
+		// back off before retrying
+		assert.NoError(t, es.refreshWithBackoff())
+		assert.Equal(t, 1, es.failures)
+		es.nextRetry = time.Now()
+		assert.NoError(t, es.refreshWithBackoff())
+		assert.Equal(t, 2, es.failures)
+		assert.Equal(t, failed+2, testutil.ToFloat64(failures))
+		assert.Equal(t, 2*time.Minute, es.retryDelay())
+	})
+}
+
+func TestEtcdSourceBackoff(t *testing.T) {
+	es := &EtcdSource{configRefresher: newRefresher(time.Second, nil)}
+	cases := []struct {
+		failures int
+		backoff  time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{9, 256 * time.Second},
+		{10, MaxRefreshBackoff},
+		{1000, MaxRefreshBackoff},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.backoff, es.backoff(c.failures), c.failures)
+	}
+
+	// never less than the refresh interval
+	es.configRefresher = newRefresher(time.Hour, nil)
+	assert.Equal(t, time.Hour, es.backoff(5))
+}
+
+func TestEtcdSourceRefreshJitter(t *testing.T) {
+	assert.Equal(t, DefaultRefreshJitter, jitterOrDefault(0))
+	assert.Equal(t, float64(0), jitterOrDefault(-1))
+	assert.Equal(t, 0.5, jitterOrDefault(0.5))
+
+	interval := time.Second
+	r := newRefresher(interval, nil)
+	r.setJitter(0.2)
+	delays := make(map[time.Duration]struct{})
+	for i := 0; i < 1000; i++ {
+		delay, ok := r.nextDelay(false)
+		require.True(t, ok)
+		assert.GreaterOrEqual(t, delay, 800*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1200*time.Millisecond)
+		delays[delay] = struct{}{}
+		first, ok := r.nextDelay(true)
+		require.True(t, ok)
+		assert.Greater(t, first, time.Duration(0))
+		assert.LessOrEqual(t, first, interval)
+	}
+	assert.Greater(t, len(delays), 1)
+
+	r.setJitter(0)
+	delay, _ := r.nextDelay(true)
+	assert.Equal(t, interval, delay)
+	r.setInterval(0)
+	_, ok := r.nextDelay(false)
+	assert.False(t, ok)
+
+	t.Run("staleness", func(t *testing.T) {
+		interval, jitter := 50*time.Millisecond, 0.5
+		var mu sync.Mutex
+		var refreshed []time.Time
+		r := newRefresher(interval, func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			refreshed = append(refreshed, time.Now())
+			return nil
+		})
+		r.setJitter(jitter)
+		start := time.Now()
+		r.start("test")
+		time.Sleep(20 * interval)
+		r.stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, refreshed)
+		// tolerate the scheduling delays
+		bound := time.Duration(float64(interval)*(1+jitter)) + 50*time.Millisecond
+		last := start
+		for _, at := range refreshed {
+			assert.LessOrEqual(t, at.Sub(last), bound)
+			last = at
+		}
+	})
+}
+
+func TestEtcdSourceMultiplePrefixes(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	for key, value := range map[string]string{
+		"config/a/b":       "1",
+		"config/c/d":       "2",
+		"config-proxy/a.b": "10",
+		"config-proxy/e/f": "3",
+	} {
+		_, err = client.Put(ctx, "test_multi/"+key, value)
+		require.NoError(t, err)
+	}
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_multi",
+		ConfigPaths:     []string{"config", "config-proxy"},
+		RefreshInterval: time.Hour,
+		Watch:           true,
+	})
+	require.NoError(t, err)
+	configs, err := es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+
+	// the later prefixes override the earlier ones
+	assert.Equal(t, map[string]string{
+		"a/b": "1",
+		"a.b": "10",
+		"ab":  "10",
+		"c/d": "2",
+		"cd":  "2",
+		"e/f": "3",
+		"ef":  "3",
+	}, configs)
+
+	assertConfig := func(key, expected string) {
+		assert.Eventually(t, func() bool {
+			value, err := es.GetConfigurationByKey(key)
+			if expected == "" {
+				return err != nil
+			}
+			return err == nil && value == expected
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("watch", func(t *testing.T) {
+		_, err = client.Put(ctx, "test_multi/config/a/b", "5")
+		require.NoError(t, err)
+		_, err = client.Put(ctx, "test_multi/config/g/h", "6")
+		require.NoError(t, err)
+		assertConfig("gh", "6")
+		assertConfig("ab", "10")
+
+		// falls back to the earlier prefix once deleted from the later one
+		_, err = client.Delete(ctx, "test_multi/config-proxy/a.b")
+		require.NoError(t, err)
+		assertConfig("ab", "5")
+		assertConfig("a.b", "")
+	})
+
+	t.Run("update prefixes", func(t *testing.T) {
+		es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{
+			KeyPrefix:       "test_multi",
+			ConfigPaths:     []string{"config-proxy"},
+			RefreshInterval: time.Hour,
+		}})
+		assertConfig("ef", "3")
+		assertConfig("ab", "")
+		assertConfig("cd", "")
+
+		_, err = client.Put(ctx, "test_multi/config-proxy/c/d", "7")
+		require.NoError(t, err)
+		assertConfig("cd", "7")
+	})
+
+	t.Run("polling", func(t *testing.T) {
+		assert.True(t, es.stopWatch())
+		es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{
+			KeyPrefix:       "test_multi",
+			RefreshInterval: time.Hour,
+		}})
+		// the default path only, rather than the ones sharing its prefix
+		assertConfig("ab", "5")
+		assertConfig("ef", "")
+		assertConfig("cd", "2")
+	})
+}
+
+func TestEtcdSourceWrite(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+
+	newSource := func(readOnly bool) *EtcdSource {
+		es, err := NewEtcdSource(&EtcdInfo{
+			Endpoints: []string{cfg.ACUrls[0].Host},
+			KeyPrefix: "test_write",
+			ReadOnly:  readOnly,
+			// changes are only refreshed by writes
+			RefreshInterval: time.Hour,
+		})
+		require.NoError(t, err)
+		_, err = es.GetConfigurations()
+		require.NoError(t, err)
+		return es
+	}
+	es := newSource(false)
+	defer es.Close()
+
+	var mu sync.Mutex
+	var events []*Event
+	es.SetEventHandler(NewHandler("test", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	assertEtcdValue := func(key, expected string) {
+		resp, err := client.Get(ctx, "test_write/config/"+key)
+		require.NoError(t, err)
+		if expected == "" {
+			assert.Empty(t, resp.Kvs)
+			return
+		}
+		require.Len(t, resp.Kvs, 1)
+		assert.Equal(t, expected, string(resp.Kvs[0].Value))
+	}
+
+	t.Run("set", func(t *testing.T) {
+		require.NoError(t, es.SetConfig("log.level", "debug"))
+		assertEtcdValue("log.level", "debug")
+		value, err := es.GetConfigurationByKey(formatKey("log.level"))
+		assert.NoError(t, err)
+		assert.Equal(t, "debug", value)
+
+		require.NoError(t, es.SetConfig("log.level", "info"))
+		assertEtcdValue("log.level", "info")
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, events, newEvent(es.GetSourceName(), CreateType, "log.level", "debug"))
+		assert.Contains(t, events, newEvent(es.GetSourceName(), UpdateType, "log.level", "info"))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, es.DeleteConfig("log.level"))
+		assertEtcdValue("log.level", "")
+		_, err := es.GetConfigurationByKey(formatKey("log.level"))
+		assert.Error(t, err)
+		// deleting a key not existing is fine
+		assert.NoError(t, es.DeleteConfig("log.level"))
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, events, newEvent(es.GetSourceName(), DeleteType, "log.level", "info"))
+	})
+
+	t.Run("compare and swap", func(t *testing.T) {
+		swapped, err := es.CompareAndSwap("quotaAndLimits.enabled", "", "true")
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+		// it exists already
+		swapped, err = es.CompareAndSwap("quotaAndLimits.enabled", "", "false")
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+		swapped, err = es.CompareAndSwap("quotaAndLimits.enabled", "false", "false")
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+		assertEtcdValue("quotaAndLimits.enabled", "true")
+
+		swapped, err = es.CompareAndSwap("quotaAndLimits.enabled", "true", "false")
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+		assertEtcdValue("quotaAndLimits.enabled", "false")
+		value, err := es.GetConfigurationByKey("quotaAndLimits.enabled")
+		assert.NoError(t, err)
+		assert.Equal(t, "false", value)
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		es := newSource(true)
+		defer es.Close()
+		assert.ErrorIs(t, es.SetConfig("log.level", "debug"), ErrReadOnly)
+		assert.ErrorIs(t, es.DeleteConfig("quotaAndLimits.enabled"), ErrReadOnly)
+		_, err := es.CompareAndSwap("quotaAndLimits.enabled", "false", "true")
+		assert.ErrorIs(t, err, ErrReadOnly)
+		assertEtcdValue("log.level", "")
+		assertEtcdValue("quotaAndLimits.enabled", "false")
+	})
+}
+
+func TestEtcdSourceDispatcher(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_dispatcher",
+		RefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+
+	var mu sync.Mutex
+	called := make(map[string][]string)
+	record := func(ident string) EventHandler {
+		return NewHandler(ident, func(event *Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			called[ident] = append(called[ident], event.Key)
+		})
+	}
+	es.SetEventHandler(record("source"))
+	addedID := es.AddEventHandler(record("added"))
+	require.NoError(t, es.Dispatcher().RegisterForPattern("queryNode.*", record("queryNode")))
+	require.NoError(t, es.Dispatcher().RegisterForPattern("*.gracefulTime", record("gracefulTime")))
+
+	require.NoError(t, es.SetConfig("queryNode.enableDisk", "true"))
+	require.NoError(t, es.SetConfig("proxy.gracefulTime", "1000"))
+	es.RemoveEventHandler(addedID)
+	// the handler set later replaces the whole source one
+	es.SetEventHandler(record("source2"))
+	require.NoError(t, es.SetConfig("queryNode.gracefulTime", "1000"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	for ident, keys := range map[string][]string{
+		"source":       {"queryNode.enableDisk", "proxy.gracefulTime"},
+		"added":        {"queryNode.enableDisk", "proxy.gracefulTime"},
+		"source2":      {"queryNode.gracefulTime"},
+		"queryNode":    {"queryNode.enableDisk", "queryNode.gracefulTime"},
+		"gracefulTime": {"proxy.gracefulTime", "queryNode.gracefulTime"},
+	} {
+		for _, key := range keys {
+			assert.Contains(t, called[ident], key, ident)
+		}
+		assert.Len(t, called[ident], len(keys)*2, ident)
+	}
+}
+
+func TestEtcdSourceUpdateOptionsRace(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	info := func(interval time.Duration) *EtcdInfo {
+		return &EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       "test_update_options",
+			RefreshInterval: interval,
+		}
+	}
+	es, err := NewEtcdSource(info(time.Millisecond))
+	require.NoError(t, err)
+	eventNum := atomic.NewInt64(0)
+	es.SetEventHandler(NewHandler("test", func(event *Event) {
+		eventNum.Inc()
+	}))
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+
+	deadline := time.Now().Add(time.Second)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; time.Now().Before(deadline); j++ {
+				es.UpdateOptions(Options{EtcdInfo: info(time.Duration(1+(i+j)%5) * time.Millisecond)})
+			}
+		}(i)
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for time.Now().Before(deadline) {
+			assert.NoError(t, es.refreshConfigurations())
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for j := 0; time.Now().Before(deadline); j++ {
+			_, err := client.Put(ctx, "test_update_options/config/a/b", strconv.Itoa(j))
+			assert.NoError(t, err)
+		}
+	}()
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		resp, err := client.Get(ctx, "test_update_options/config/a/b")
+		if err != nil {
+			return false
+		}
+		value, err := es.GetConfigurationByKey("a/b")
+		return err == nil && value == string(resp.Kvs[0].Value)
+	}, time.Second, 10*time.Millisecond)
+	assert.Positive(t, eventNum.Load())
+
+	// no more events once closed
+	es.Close()
+	closed := eventNum.Load()
+	_, err = client.Put(ctx, "test_update_options/config/a/b", "closed")
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, closed, eventNum.Load())
+}
+
+func TestEtcdSourceAuth(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	ctx := context.Background()
+	client := v3client.New(e.Server)
+	_, err = client.Put(ctx, "test_auth/config/a/b", "1")
+	require.NoError(t, err)
+	_, err = client.UserAdd(ctx, "root", "root")
+	require.NoError(t, err)
+	_, err = client.UserGrantRole(ctx, "root", "root")
+	require.NoError(t, err)
+	_, err = client.RoleAdd(ctx, "config")
+	require.NoError(t, err)
+	_, err = client.RoleGrantPermission(ctx, "config", "test_auth/config/", clientv3.GetPrefixRangeEnd("test_auth/config/"),
+		clientv3.PermissionType(clientv3.PermReadWrite))
+	require.NoError(t, err)
+	_, err = client.UserAdd(ctx, "milvus", "password")
+	require.NoError(t, err)
+	_, err = client.UserGrantRole(ctx, "milvus", "config")
+	require.NoError(t, err)
+	_, err = client.AuthEnable(ctx)
+	require.NoError(t, err)
+
+	info := func(enableAuth bool, password string) *EtcdInfo {
+		return &EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       "test_auth",
+			EnableAuth:      enableAuth,
+			Username:        "milvus",
+			Password:        password,
+			RefreshInterval: time.Hour,
+		}
+	}
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		_, err := NewEtcdSource(info(true, "invalid"))
+		assert.True(t, errors.Is(err, ErrEtcdAuth))
+		_, err = NewEtcdSource(info(true, ""))
+		assert.Error(t, err)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		es, err := NewEtcdSource(info(false, ""))
+		require.NoError(t, err)
+		defer es.Close()
+		authFailures := metrics.ConfigRefreshAuthFailCounter.WithLabelValues(es.GetSourceName())
+		failed := testutil.ToFloat64(authFailures)
+		_, err = es.GetConfigurations()
+		assert.True(t, errors.Is(err, ErrEtcdAuth))
+		assert.True(t, errors.Is(es.Health().LastError, ErrEtcdAuth))
+		assert.Equal(t, failed+1, testutil.ToFloat64(authFailures))
+	})
+
+	t.Run("rotate credentials", func(t *testing.T) {
+		es, err := NewEtcdSource(info(true, "password"))
+		require.NoError(t, err)
+		defer es.Close()
+		_, err = es.GetConfigurations()
+		require.NoError(t, err)
+		require.NoError(t, es.SetConfig("c.d", "2"))
+		value, err := es.GetConfigurationByKey("a/b")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", value)
+
+		rootCli, err := clientv3.New(clientv3.Config{
+			Endpoints: []string{cfg.ACUrls[0].Host},
+			Username:  "root",
+			Password:  "root",
+		})
+		require.NoError(t, err)
+		defer rootCli.Close()
+		_, err = rootCli.UserChangePassword(ctx, "milvus", "rotated")
+		require.NoError(t, err)
+		_, err = rootCli.Put(ctx, "test_auth/config/a/b", "2")
+		require.NoError(t, err)
+		assert.True(t, errors.Is(es.refreshConfigurations(), ErrEtcdAuth))
+
+		// keep the old client if the new credentials are invalid
+		es.UpdateOptions(Options{EtcdInfo: info(true, "invalid")})
+		assert.True(t, errors.Is(es.Health().LastError, ErrEtcdAuth))
+
+		es.UpdateOptions(Options{EtcdInfo: info(true, "rotated")})
+		assert.True(t, es.Health().Connected)
+		value, err = es.GetConfigurationByKey("a/b")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", value)
+		assert.NoError(t, es.SetConfig("c.d", "3"))
+	})
+}
+
+func TestEtcdSourceRequestTimeout(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	_, err = client.Put(context.Background(), "test_timeout/config/a/b", "1")
+	require.NoError(t, err)
+	info := &EtcdInfo{
+		Endpoints:        []string{cfg.ACUrls[0].Host},
+		KeyPrefix:        "test_timeout",
+		DialTimeout:      time.Second,
+		KeepAliveTime:    time.Second,
+		KeepAliveTimeout: time.Second,
+		RequestTimeout:   10 * time.Millisecond,
+		RefreshInterval:  time.Hour,
+	}
+	es, err := NewEtcdSource(info)
+	require.NoError(t, err)
+	defer es.Close()
+	es.etcdCli.KV = &slowKV{KV: es.etcdCli.KV, delay: 200 * time.Millisecond}
+
+	_, err = es.GetConfigurations()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, es.Health().Connected)
+
+	// the new request timeout takes effect without rebuilding the client
+	info.RequestTimeout = time.Second
+	es.UpdateOptions(Options{EtcdInfo: info})
+	assert.NoError(t, es.refreshConfigurations())
+	assert.True(t, es.Health().Connected)
+	value, err := es.GetConfigurationByKey("a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	// the client is rebuilt with the new connection options
+	etcdCli := es.etcdCli
+	info.DialTimeout = 2 * time.Second
+	es.UpdateOptions(Options{EtcdInfo: info})
+	assert.NotSame(t, etcdCli, es.etcdCli)
+	_, err = client.Put(context.Background(), "test_timeout/config/a/b", "2")
+	require.NoError(t, err)
+	assert.NoError(t, es.refreshConfigurations())
+	value, err = es.GetConfigurationByKey("a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", value)
+}
+
+func TestEtcdSourceEncryptedValues(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	decryptor, err := NewAESGCMDecryptor([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+	encrypt := func(plaintext string) string {
+		ciphertext, err := decryptor.Encrypt(plaintext)
+		require.NoError(t, err)
+		return DefaultEncryptedPrefix + ciphertext
+	}
+	put := func(key, value string) {
+		_, err := client.Put(ctx, "test_encrypted/config/"+key, value)
+		require.NoError(t, err)
+	}
+	put("minio/accessKeyID", "minioadmin")
+	put("minio/secretAccessKey", encrypt("secret"))
+	put("minio/invalid", DefaultEncryptedPrefix+"invalid")
+
+	newSource := func(decryptor Decryptor) *EtcdSource {
+		es, err := NewEtcdSource(&EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       "test_encrypted",
+			Decryptor:       decryptor,
+			RefreshInterval: time.Hour,
+		})
+		require.NoError(t, err)
+		return es
+	}
+
+	t.Run("no decryptor", func(t *testing.T) {
+		es := newSource(nil)
+		defer es.Close()
+		configs, err := es.GetConfigurations()
+		require.NoError(t, err)
+		assert.Equal(t, "minioadmin", configs["minio/accessKeyID"])
+		assert.NotContains(t, configs, "minio/secretAccessKey")
+		assert.NotContains(t, configs, formatKey("minio/secretAccessKey"))
+	})
+
+	es := newSource(decryptor)
+	defer es.Close()
+	var mu sync.Mutex
+	var events []*Event
+	es.SetEventHandler(NewHandler("test", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	configs, err := es.GetConfigurations()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", configs["minio/secretAccessKey"])
+	assert.Equal(t, "secret", configs[formatKey("minio/secretAccessKey")])
+	// neither the ciphertext is exposed
+	_, err = es.GetConfigurationByKey("minio/invalid")
+	assert.Error(t, err)
+
+	rawEvents := func() []*Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return lo.Filter(events, func(e *Event, _ int) bool { return e.Key == "minio/secretAccessKey" })
+	}
+	mu.Lock()
+	events = nil
+	mu.Unlock()
+	// re-encrypted with the same secret
+	put("minio/secretAccessKey", encrypt("secret"))
+	require.NoError(t, es.refreshConfigurations())
+	assert.Empty(t, rawEvents())
+
+	// rotated to a new secret
+	put("minio/secretAccessKey", encrypt("rotated"))
+	require.NoError(t, es.refreshConfigurations())
+	value, err := es.GetConfigurationByKey("minio/secretAccessKey")
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated", value)
+	assert.Equal(t, []*Event{newEvent(es.GetSourceName(), UpdateType, "minio/secretAccessKey", "rotated")}, rawEvents())
+
+	// written encrypted
+	require.NoError(t, es.SetConfig("minio/secretAccessKey", encrypt("written")))
+	value, err = es.GetConfigurationByKey("minio/secretAccessKey")
+	assert.NoError(t, err)
+	assert.Equal(t, "written", value)
+}
+
+func TestEtcdSourceHistory(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	decryptor, err := NewAESGCMDecryptor([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+	encrypt := func(plaintext string) string {
+		ciphertext, err := decryptor.Encrypt(plaintext)
+		require.NoError(t, err)
+		return DefaultEncryptedPrefix + ciphertext
+	}
+	put := func(key, value string) {
+		_, err := client.Put(ctx, "test_history/config/"+key, value)
+		require.NoError(t, err)
+	}
+	put("minio/accessKeyID", "minioadmin")
+	put("minio/secretAccessKey", encrypt("secret"))
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_history",
+		Decryptor:       decryptor,
+		RefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	mgr, _ := Init()
+	defer mgr.Close()
+	require.NoError(t, mgr.AddSource(es))
+
+	put("minio/accessKeyID", "admin")
+	put("minio/secretAccessKey", encrypt("rotated"))
+	put("creds.yaml", encrypt("minio:\n  password: pwd\n"))
+	require.NoError(t, es.refreshConfigurations())
+	// no longer encrypted
+	put("minio/secretAccessKey", "plain")
+	_, err = client.Delete(ctx, "test_history/config/creds.yaml")
+	require.NoError(t, err)
+	require.NoError(t, es.refreshConfigurations())
+
+	records := lo.Map(mgr.History(time.Time{}, "minio"), func(r ChangeRecord, _ int) ChangeRecord {
+		r.Time = time.Time{}
+		return r
+	})
+	name := es.GetSourceName()
+	assert.Equal(t, []ChangeRecord{
+		{Source: name, EventType: CreateType, Key: "minio.password", NewValue: RedactedValue},
+		{Source: name, EventType: UpdateType, Key: "minio/accessKeyID", OldValue: "minioadmin", NewValue: "admin"},
+		{Source: name, EventType: UpdateType, Key: "minio/secretAccessKey", OldValue: RedactedValue, NewValue: RedactedValue},
+		{Source: name, EventType: DeleteType, Key: "minio.password", OldValue: RedactedValue},
+		{Source: name, EventType: UpdateType, Key: "minio/secretAccessKey", OldValue: RedactedValue, NewValue: RedactedValue},
+	}, records)
+}
+
+func TestEtcdSourcePagination(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	const keyNum = 10000
+	ops := make([]clientv3.Op, 0, 100)
+	for i := 0; i < keyNum; i++ {
+		ops = append(ops, clientv3.OpPut(fmt.Sprintf("test_pagination/config/tenant/%05d", i), strconv.Itoa(i)))
+		if len(ops) == cap(ops) {
+			_, err := client.Txn(ctx).Then(ops...).Commit()
+			require.NoError(t, err)
+			ops = ops[:0]
+		}
+	}
+	_, err = client.Put(ctx, "test_pagination/config-proxy/a/b", "1")
+	require.NoError(t, err)
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_pagination",
+		ConfigPaths:     []string{"config", "config-proxy"},
+		PageSize:        500,
+		RefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer es.Close()
+	kv := &pagingKV{KV: es.etcdCli.KV}
+	es.etcdCli.KV = kv
+	// the keys written during the scan are not read, as the later pages are pinned at the revision of the first one
+	kv.afterFirstPage = func() {
+		_, err := client.Put(ctx, "test_pagination/config/tenant/99999", "written")
+		assert.NoError(t, err)
+		_, err = client.Put(ctx, "test_pagination/config-proxy/c/d", "written")
+		assert.NoError(t, err)
+	}
+
+	configs, err := es.GetConfigurations()
+	require.NoError(t, err)
+	// 20 pages of the first prefix, and the one of the second
+	assert.EqualValues(t, keyNum/500+1, kv.pages.Load())
+	// the keys and their formatted duplicates
+	assert.Len(t, configs, (keyNum+1)*2)
+	assert.Equal(t, "0", configs["tenant/00000"])
+	assert.Equal(t, "9999", configs["tenant/09999"])
+	assert.Equal(t, "1", configs["a/b"])
+	assert.NotContains(t, configs, "tenant/99999")
+	assert.NotContains(t, configs, "c/d")
+
+	// the keys written are read next time
+	require.NoError(t, es.refreshConfigurations())
+	value, err := es.GetConfigurationByKey("tenant/99999")
+	assert.NoError(t, err)
+	assert.Equal(t, "written", value)
+
+	t.Run("no limit", func(t *testing.T) {
+		es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       "test_pagination",
+			ConfigPaths:     []string{"config", "config-proxy"},
+			PageSize:        -1,
+			RefreshInterval: time.Hour,
+		}})
+		pages := kv.pages.Load()
+		require.NoError(t, es.refreshConfigurations())
+		assert.Equal(t, pages+2, kv.pages.Load())
+	})
+}
+
+func TestEtcdSourceKeyCollisions(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	for key, value := range map[string]string{
+		"a/b": "1",
+		"a.b": "2",
+		"a_b": "3",
+		"ab":  "4",
+	} {
+		_, err = client.Put(ctx, "test_collision/config/"+key, value)
+		require.NoError(t, err)
+	}
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_collision",
+		RefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+	assert.Equal(t, HighPriority, es.GetPriority())
+
+	// the smallest key wins the formatted key, which is stable across refreshes
+	for i := 0; i < 10; i++ {
+		require.NoError(t, es.refreshConfigurations())
+		configs, err := es.GetConfigurations()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"a.b": "2",
+			"a/b": "1",
+			"a_b": "3",
+			"ab":  "2",
+		}, configs)
+	}
+	assert.Equal(t, []string{
+		"test_collision/config/a.b",
+		"test_collision/config/a/b",
+		"test_collision/config/a_b",
+		"test_collision/config/ab",
+	}, es.GetOriginalKeys("ab"))
+	assert.Equal(t, es.GetOriginalKeys("ab"), es.GetOriginalKeys("a.b"))
+	assert.Empty(t, es.GetOriginalKeys("cd"))
+
+	_, err = client.Delete(ctx, "test_collision/config/a.b")
+	require.NoError(t, err)
+	require.NoError(t, es.refreshConfigurations())
+	value, err := es.GetConfigurationByKey("ab")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+	assert.Len(t, es.GetOriginalKeys("ab"), 3)
+}
+
+func TestEtcdSourceClose(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	_, err = client.Put(ctx, "test_close/config/a/b", "1")
+	require.NoError(t, err)
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_close",
+		RefreshInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	var events atomic.Int64
+	es.SetEventHandler(NewHandler("close", func(event *Event) {
+		events.Add(1)
+	}))
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+
+	_, err = client.Put(ctx, "test_close/config/a/b", "2")
+	require.NoError(t, err)
+	es.withClient(func(etcdCli *clientv3.Client) error {
+		etcdCli.KV = &lateKV{KV: etcdCli.KV, delay: 100 * time.Millisecond}
+		return nil
+	})
+
+	// race the refreshing in progress against Close
+	fired := events.Load()
+	refreshed := make(chan error, 1)
+	go func() {
+		refreshed <- es.refreshConfigurations()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	es.Close()
+	closedEvents := events.Load()
+	assert.True(t, errors.Is(<-refreshed, ErrSourceClosed))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, fired, closedEvents)
+	assert.Equal(t, closedEvents, events.Load())
+
+	_, err = es.GetConfigurationByKey("a/b")
+	assert.True(t, errors.Is(err, ErrSourceClosed))
+	_, err = es.GetConfigurationsByKeyPrefix("a")
+	assert.True(t, errors.Is(err, ErrSourceClosed))
+	_, err = es.GetConfigurations()
+	assert.True(t, errors.Is(err, ErrSourceClosed))
+	assert.True(t, errors.Is(es.refreshConfigurations(), ErrSourceClosed))
+	// closed again
+	es.Close()
+}
+
+func TestEtcdSourceBlobs(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	overrideYAML := `
+proxy:
+  maxNameLength: 10
+  list: [a, b]
+  nested:
+    - name: x
+      size: 1
+common:
+  retentionDuration: 5
+`
+	for key, value := range map[string]string{
+		"override.yaml":       overrideYAML,
+		"other.json":          `{"dataCoord": {"segment": {"maxSize": 512}}, "common": {"retentionDuration": 6}}`,
+		"proxy/maxNameLength": "20",
+	} {
+		_, err = client.Put(ctx, "test_blob/config/"+key, value)
+		require.NoError(t, err)
+	}
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_blob",
+		RefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+
+	assertConfigs := func(expected map[string]string) {
+		for key, value := range expected {
+			actual, err := es.GetConfigurationByKey(key)
+			if value == "" {
+				assert.Error(t, err, key)
+				continue
+			}
+			assert.NoError(t, err, key)
+			assert.Equal(t, value, actual, key)
+		}
+	}
+	assertConfigs(map[string]string{
+		// the explicit key wins
+		"proxymaxnamelength":  "20",
+		"proxy/maxNameLength": "20",
+		// the later blob wins
+		"commonretentionduration": "5",
+		"datacoordsegmentmaxsize": "512",
+		"proxy.list":              "a,b",
+		"proxylist":               "a,b",
+		"proxy.list.0":            "a",
+		"proxylist1":              "b",
+		"proxy.nested.0.name":     "x",
+		"proxynested0size":        "1",
+		"proxy.nested":            "",
+		"override.yaml":           "",
+	})
+	assert.Equal(t, []string{
+		"test_blob/config/proxy/maxNameLength",
+		"test_blob/config/override.yaml#proxy.maxnamelength",
+	}, es.GetOriginalKeys("proxymaxnamelength"))
+
+	t.Run("parse error", func(t *testing.T) {
+		_, err = client.Put(ctx, "test_blob/config/override.yaml", "proxy: [")
+		require.NoError(t, err)
+		_, err = client.Put(ctx, "test_blob/config/other.json", `{"dataCoord": {"segment": {"maxSize": 1024}}}`)
+		require.NoError(t, err)
+		require.NoError(t, es.refreshConfigurations())
+		// the configs of the last valid document are kept
+		assertConfigs(map[string]string{
+			"commonretentionduration": "5",
+			"proxylist":               "a,b",
+			"datacoordsegmentmaxsize": "1024",
+		})
+
+		_, err = client.Put(ctx, "test_blob/config/override.yaml", "proxy:\n  list: [c]\n")
+		require.NoError(t, err)
+		require.NoError(t, es.refreshConfigurations())
+		assertConfigs(map[string]string{
+			"commonretentionduration": "",
+			"proxylist":               "c",
+			"proxylist1":              "",
+		})
+	})
+
+	t.Run("explicit key removed", func(t *testing.T) {
+		_, err = client.Put(ctx, "test_blob/config/override.yaml", "proxy:\n  maxNameLength: 30\n")
+		require.NoError(t, err)
+		_, err = client.Delete(ctx, "test_blob/config/proxy/maxNameLength")
+		require.NoError(t, err)
+		require.NoError(t, es.refreshConfigurations())
+		assertConfigs(map[string]string{
+			"proxymaxnamelength": "30",
+			"proxylist":          "",
+		})
+
+		_, err = client.Delete(ctx, "test_blob/config/override.yaml")
+		require.NoError(t, err)
+		require.NoError(t, es.refreshConfigurations())
+		assertConfigs(map[string]string{
+			"proxymaxnamelength": "",
+		})
+	})
+}
+
+func TestEtcdSourceCompression(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	compress := func(value string) string {
+		compressed, err := CompressValue(value)
+		require.NoError(t, err)
+		return compressed
+	}
+	for key, value := range map[string]string{
+		"a/b":       compress("compressed"),
+		"c/d":       "1",
+		"blob.yaml": compress("proxy:\n  maxNameLength: 10\n"),
+	} {
+		_, err = client.Put(ctx, "test_compress/config/"+key, value)
+		require.NoError(t, err)
+	}
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:         []string{cfg.ACUrls[0].Host},
+		KeyPrefix:         "test_compress",
+		RefreshInterval:   time.Hour,
+		CompressThreshold: 100,
+	})
+	require.NoError(t, err)
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+
+	assertConfigs := func(expected map[string]string) {
+		for key, value := range expected {
+			actual, err := es.GetConfigurationByKey(key)
+			if value == "" {
+				assert.Error(t, err, key)
+				continue
+			}
+			assert.NoError(t, err, key)
+			assert.Equal(t, value, actual, key)
+		}
+	}
+	assertConfigs(map[string]string{
+		"a/b":                "compressed",
+		"ab":                 "compressed",
+		"c/d":                "1",
+		"proxymaxnamelength": "10",
+	})
+
+	t.Run("write", func(t *testing.T) {
+		assertEtcdValue := func(key string, compressed bool) {
+			resp, err := client.Get(ctx, "test_compress/config/"+key)
+			require.NoError(t, err)
+			require.Len(t, resp.Kvs, 1)
+			assert.Equal(t, compressed, strings.HasPrefix(string(resp.Kvs[0].Value), CompressedPrefix))
+		}
+		small := strings.Repeat("x", 100)
+		require.NoError(t, es.SetConfig("small", small))
+		assertEtcdValue("small", false)
+		large := strings.Repeat("x", 101)
+		require.NoError(t, es.SetConfig("large", large))
+		assertEtcdValue("large", true)
+		assertConfigs(map[string]string{"small": small, "large": large})
+
+		// no compression
+		es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{KeyPrefix: "test_compress", RefreshInterval: time.Hour, CompressThreshold: -1}})
+		require.NoError(t, es.SetConfig("large", large+"y"))
+		assertEtcdValue("large", false)
+		assertConfigs(map[string]string{"large": large + "y"})
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		for key, value := range map[string]string{
+			"a/b": CompressedPrefix + "not base64!",
+			"c/d": CompressedPrefix + "H4sI",
+			"e/f": CompressedPrefix + "H4sI",
+		} {
+			_, err = client.Put(ctx, "test_compress/config/"+key, value)
+			require.NoError(t, err)
+		}
+		require.NoError(t, es.refreshConfigurations())
+		// the last values are kept, the new key is left out
+		assertConfigs(map[string]string{
+			"a/b": "compressed",
+			"ab":  "compressed",
+			"c/d": "1",
+			"cd":  "1",
+			"e/f": "",
+			"ef":  "",
+		})
+		// kept across refreshing
+		require.NoError(t, es.refreshConfigurations())
+		assertConfigs(map[string]string{"a/b": "compressed", "c/d": "1"})
+
+		_, err = client.Put(ctx, "test_compress/config/a/b", compress("fixed"))
+		require.NoError(t, err)
+		require.NoError(t, es.refreshConfigurations())
+		assertConfigs(map[string]string{"a/b": "fixed", "c/d": "1"})
+	})
+}
+
+func TestEtcdSourceUnchanged(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	_, err = client.Put(ctx, "test_unchanged/config/a/b", "1")
+	require.NoError(t, err)
+	_, err = client.Put(ctx, "test_unchanged/config/c/d", "2")
+	require.NoError(t, err)
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{cfg.ACUrls[0].Host},
+		KeyPrefix:       "test_unchanged",
+		RefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+
+	fired := func() uint64 {
+		return histogramCount(t, metrics.ConfigRefreshEvents.WithLabelValues(es.GetSourceName()))
+	}
+	assertRefresh := func(fullPath bool) {
+		count := fired()
+		require.NoError(t, es.refreshConfigurations())
+		assert.Equal(t, lo.Ternary(fullPath, count+1, count), fired())
+		assert.True(t, es.Health().Connected)
+	}
+	for i := 0; i < 3; i++ {
+		assertRefresh(false)
+	}
+	// writes to other keys don't change the configurations
+	_, err = client.Put(ctx, "test_unchanged/other/e/f", "3")
+	require.NoError(t, err)
+	assertRefresh(false)
+
+	_, err = client.Put(ctx, "test_unchanged/config/a/b", "10")
+	require.NoError(t, err)
+	assertRefresh(true)
+	assertRefresh(false)
+	value, err := es.GetConfigurationByKey("ab")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	// deleted without any key modified
+	_, err = client.Delete(ctx, "test_unchanged/config/c/d")
+	require.NoError(t, err)
+	assertRefresh(true)
+	assertRefresh(false)
+	_, err = es.GetConfigurationByKey("cd")
+	assert.Error(t, err)
+
+	es.UpdateOptions(Options{EtcdInfo: &EtcdInfo{KeyPrefix: "test_unchanged", RefreshInterval: time.Hour}})
+	assertRefresh(true)
+	assertRefresh(false)
+
+	t.Run("rejected", func(t *testing.T) {
+		// the panics of validators reject the changes
+		id := es.AddEventHandler(panicValidator{NewHandler("reject", func(*Event) {})})
+		defer es.RemoveEventHandler(id)
+		_, err = client.Put(ctx, "test_unchanged/config/a/b", "20")
+		require.NoError(t, err)
+		// validated again each time until accepted
+		assertRefresh(true)
+		assertRefresh(true)
+		value, err := es.GetConfigurationByKey("ab")
+		assert.NoError(t, err)
+		assert.Equal(t, "10", value)
+	})
+}
+
+func TestEtcdSourceSwitchPrefix(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	for key, value := range map[string]string{
+		"test_switch_a/config/a/b": "1",
+		"test_switch_a/config/c/d": "2",
+		"test_switch_b/config/a/b": "10",
+		"test_switch_b/config/e/f": "3",
+	} {
+		_, err = client.Put(ctx, key, value)
+		require.NoError(t, err)
+	}
+
+	info := func(keyPrefix string) *EtcdInfo {
+		return &EtcdInfo{
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       keyPrefix,
+			RefreshInterval: time.Hour,
+		}
+	}
+	es, err := NewEtcdSource(info("test_switch_a"))
+	require.NoError(t, err)
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+
+	var mu sync.Mutex
+	var events []*Event
+	es.AddEventHandler(NewHandler("switch", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	// the transition is complete once switched, without waiting for the refreshing
+	assertSwitched := func(expectedConfigs map[string]string, expectedEvents ...*Event) {
+		configs, err := es.GetConfigurationsByKeyPrefix("")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedConfigs, configs)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.ElementsMatch(t, expectedEvents, events)
+		events = nil
+	}
+
+	es.UpdateOptions(Options{EtcdInfo: info("test_switch_b")})
+	assertSwitched(map[string]string{"a/b": "10", "ab": "10", "e/f": "3", "ef": "3"},
+		newEvent(es.GetSourceName(), UpdateType, "a/b", "10"),
+		newEvent(es.GetSourceName(), UpdateType, "ab", "10"),
+		newEvent(es.GetSourceName(), DeleteType, "c/d", "2"),
+		newEvent(es.GetSourceName(), CreateType, "e/f", "3"),
+		newEvent(es.GetSourceName(), CreateType, "ef", "3"))
+	assert.True(t, es.Health().Connected)
+
+	es.UpdateOptions(Options{EtcdInfo: info("test_switch_a")})
+	assertSwitched(map[string]string{"a/b": "1", "ab": "1", "c/d": "2", "cd": "2"},
+		newEvent(es.GetSourceName(), UpdateType, "a/b", "1"),
+		newEvent(es.GetSourceName(), UpdateType, "ab", "1"),
+		newEvent(es.GetSourceName(), CreateType, "c/d", "2"),
+		newEvent(es.GetSourceName(), CreateType, "cd", "2"),
+		newEvent(es.GetSourceName(), DeleteType, "e/f", "3"))
+
+	// the same prefix changes nothing
+	es.UpdateOptions(Options{EtcdInfo: info("test_switch_a")})
+	assertSwitched(map[string]string{"a/b": "1", "ab": "1", "c/d": "2", "cd": "2"})
+}
+
+func TestEtcdSourceNames(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	_, err = client.Put(ctx, "test_names_a/config/a/b", "1")
+	require.NoError(t, err)
+	_, err = client.Put(ctx, "test_names_b/config/a/b", "2")
+	require.NoError(t, err)
+
+	newSource := func(name, keyPrefix string, priority int) *EtcdSource {
+		es, err := NewEtcdSource(&EtcdInfo{
+			Name:            name,
+			Endpoints:       []string{cfg.ACUrls[0].Host},
+			KeyPrefix:       keyPrefix,
+			RefreshInterval: time.Hour,
+			Priority:        priority,
+		})
+		require.NoError(t, err)
+		return es
+	}
+	mgr := NewManager()
+	defer mgr.Close()
+	a := newSource("", "test_names_a", HighPriority+1)
+	assert.Equal(t, "EtcdSource[test_names_a]", a.GetSourceName())
+	require.NoError(t, mgr.AddSource(a))
+	b := newSource("cluster-b", "test_names_b", HighPriority)
+	assert.Equal(t, "EtcdSource[cluster-b]", b.GetSourceName())
+	require.NoError(t, mgr.AddSource(b))
+
+	// the same prefix is rejected unless named apart
+	dup := newSource("", "test_names_a", HighPriority)
+	defer dup.Close()
+	assert.ErrorIs(t, mgr.AddSource(dup), ErrDuplicateSource)
+
+	value, err := mgr.GetConfig("a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", value)
+	_, source, _, _ := mgr.Describe("a.b")
+	assert.Equal(t, "EtcdSource[cluster-b]", source)
+
+	// the events tell the sources apart
+	var mu sync.Mutex
+	var events []*Event
+	mgr.Dispatcher.Register("a.b", NewHandler("names", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	_, err = client.Delete(ctx, "test_names_b/config/a/b")
+	require.NoError(t, err)
+	require.NoError(t, b.refreshConfigurations())
+	value, err = mgr.GetConfig("a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, events)
+	assert.Equal(t, "EtcdSource[cluster-b]", events[0].EventSource)
+}
+
+func TestEtcdSourceFailover(t *testing.T) {
+	secondaryCfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	secondaryCfg.Dir = t.TempDir()
+	secondary, err := embed.StartEtcd(secondaryCfg)
+	require.NoError(t, err)
+	defer secondary.Close()
+
+	// the primary cluster on the other ports, restarted with the same data later
+	primaryCfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	primaryCfg.Dir = t.TempDir()
+	primaryCfg.Name = "primary"
+	peerURL, _ := url.Parse("http://localhost:2580")
+	clientURL, _ := url.Parse("http://localhost:2579")
+	primaryCfg.LPUrls, primaryCfg.APUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	primaryCfg.LCUrls, primaryCfg.ACUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	primaryCfg.InitialCluster = "primary=http://localhost:2580"
+	primary, err := embed.StartEtcd(primaryCfg)
+	require.NoError(t, err)
+	defer func() { primary.Close() }()
+
+	ctx := context.Background()
+	for client, configs := range map[*clientv3.Client]map[string]string{
+		v3client.New(primary.Server):   {"a/b": "1", "c/d": "2"},
+		v3client.New(secondary.Server): {"a/b": "10", "e/f": "3"},
+	} {
+		for key, value := range configs {
+			_, err = client.Put(ctx, "test_failover/config/"+key, value)
+			require.NoError(t, err)
+		}
+	}
+
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:          []string{clientURL.Host},
+		SecondaryEndpoints: []string{secondaryCfg.ACUrls[0].Host},
+		FailoverThreshold:  2,
+		ProbeInterval:      100 * time.Millisecond,
+		KeyPrefix:          "test_failover",
+		RequestTimeout:     500 * time.Millisecond,
+		RefreshInterval:    time.Hour,
+	})
+	require.NoError(t, err)
+	_, err = es.GetConfigurations()
+	require.NoError(t, err)
+	defer es.Close()
+	assertConfigs := func(expected map[string]string) {
+		for key, value := range expected {
+			actual, err := es.GetConfigurationByKey(key)
+			assert.NoError(t, err, key)
+			assert.Equal(t, value, actual, key)
+		}
+	}
+	assertConfigs(map[string]string{"a/b": "1", "c/d": "2"})
+	assert.Empty(t, es.Health().Degraded)
+
+	primary.Close()
+	<-primary.Server.StopNotify()
+	assert.Error(t, es.refreshConfigurations())
+	assert.Empty(t, es.Health().Degraded)
+	// failed over at the threshold, and reloaded from the secondary endpoints at once
+	require.NoError(t, es.refreshConfigurations())
+	health := es.Health()
+	assert.Equal(t, DegradedSecondary, health.Degraded)
+	assert.True(t, health.Connected)
+	assertConfigs(map[string]string{"a/b": "10", "e/f": "3"})
+	_, err = es.GetConfigurationByKey("c/d")
+	assert.Error(t, err)
+
+	// fails back once the primary cluster is back
+	primary, err = embed.StartEtcd(primaryCfg)
+	require.NoError(t, err)
+	<-primary.Server.ReadyNotify()
+	assert.Eventually(t, func() bool {
+		return es.Health().Degraded == ""
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		value, err := es.GetConfigurationByKey("c/d")
+		return err == nil && value == "2"
+	}, 10*time.Second, 50*time.Millisecond)
+	assertConfigs(map[string]string{"a/b": "1"})
+	_, err = es.GetConfigurationByKey("e/f")
+	assert.Error(t, err)
+}
+
+// writeClientCert writes the client certificate signed by the CA of configs/cert with a new key.
+func writeClientCert(t *testing.T, certFile, keyFile string) {
+	caCertPEM, err := os.ReadFile("../../configs/cert/ca.pem")
+	require.NoError(t, err)
+	caKeyPEM, err := os.ReadFile("../../configs/cert/ca.key")
+	require.NoError(t, err)
+	block, _ := pem.Decode(caCertPEM)
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	block, _ = pem.Decode(caKeyPEM)
+	caKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0o600))
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+}
+
+func TestEtcdSourceReloadTLS(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	cfg.Name = "tls"
+	peerURL, _ := url.Parse("http://localhost:2680")
+	clientURL, _ := url.Parse("https://localhost:2679")
+	cfg.LPUrls, cfg.APUrls = []url.URL{*peerURL}, []url.URL{*peerURL}
+	cfg.LCUrls, cfg.ACUrls = []url.URL{*clientURL}, []url.URL{*clientURL}
+	cfg.InitialCluster = "tls=http://localhost:2680"
+	cfg.ClientTLSInfo.CertFile = "../../configs/cert/server.pem"
+	cfg.ClientTLSInfo.KeyFile = "../../configs/cert/server.key"
+	cfg.ClientTLSInfo.TrustedCAFile = "../../configs/cert/ca.pem"
+	cfg.ClientTLSInfo.ClientCertAuth = true
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	_, err = client.Put(ctx, "test_tls/config/a/b", "1")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := path.Join(dir, "client.pem"), path.Join(dir, "client.key")
+	writeClientCert(t, certFile, keyFile)
+	es, err := NewEtcdSource(&EtcdInfo{
+		Endpoints:       []string{clientURL.Host},
+		KeyPrefix:       "test_tls",
+		UseSSL:          true,
+		CertFile:        certFile,
+		KeyFile:         keyFile,
+		CaCertFile:      "../../configs/cert/ca.pem",
+		MinVersion:      "1.2",
+		RefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer es.Close()
+	require.NoError(t, es.refreshConfigurations())
+	currentClient := func() *clientv3.Client {
+		es.clientMu.RLock()
+		defer es.clientMu.RUnlock()
+		return es.etcdCli
+	}
+
+	t.Run("rotated", func(t *testing.T) {
+		oldCli := currentClient()
+		writeClientCert(t, certFile, keyFile)
+		assert.Eventually(t, func() bool {
+			return currentClient() != oldCli
+		}, 5*time.Second, 20*time.Millisecond)
+		_, err = client.Put(ctx, "test_tls/config/a/b", "2")
+		require.NoError(t, err)
+		require.NoError(t, es.refreshConfigurations())
+		value, err := es.GetConfigurationByKey("a/b")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", value)
+		assert.NoError(t, es.Health().LastError)
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		oldCli := currentClient()
+		require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+		assert.Eventually(t, func() bool {
+			return es.Health().LastError != nil
+		}, 5*time.Second, 20*time.Millisecond)
+		assert.Error(t, es.ReloadTLS())
+		assert.Same(t, oldCli, currentClient())
+
+		// the old client keeps working
+		_, err = client.Put(ctx, "test_tls/config/a/b", "3")
+		require.NoError(t, err)
+		require.NoError(t, es.refreshConfigurations())
+		value, err := es.GetConfigurationByKey("a/b")
+		assert.NoError(t, err)
+		assert.Equal(t, "3", value)
+		health := es.Health()
+		assert.True(t, health.Connected)
+		assert.ErrorContains(t, health.LastError, "TLS")
+
+		// fixed by the explicit reload
+		es.stopCertWatch()
+		writeClientCert(t, certFile, keyFile)
+		assert.NoError(t, es.ReloadTLS())
+		assert.NotSame(t, oldCli, currentClient())
+		assert.NoError(t, es.Health().LastError)
+		assert.NoError(t, es.refreshConfigurations())
+	})
+}
+
+func TestEtcdSourceForceRefresh(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	_, err = client.Put(ctx, "test_force_refresh/config/a/b", "1")
+	require.NoError(t, err)
+	mgr, err := Init(WithEtcdSource(&EtcdInfo{
+		Endpoints: []string{cfg.ACUrls[0].Host},
+		KeyPrefix: "test_force_refresh",
+		// never refreshed by the ticker in the test
+		RefreshInterval: time.Hour,
+	}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	source, ok := mgr.sources.Get("EtcdSource[test_force_refresh]")
+	require.True(t, ok)
+	es := source.(*EtcdSource)
+
+	_, err = client.Put(ctx, "test_force_refresh/config/a/b", "2")
+	require.NoError(t, err)
+	value, err := es.GetConfigurationByKey("a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+	require.NoError(t, es.ForceRefresh(ctx))
+	value, err = es.GetConfigurationByKey("a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", value)
+
+	_, err = client.Put(ctx, "test_force_refresh/config/a/b", "3")
+	require.NoError(t, err)
+	require.NoError(t, mgr.ForceRefresh(ctx))
+	value, err = mgr.GetConfig("a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", value)
+
+	// waits for the refreshing in progress
+	es.refreshing <- struct{}{}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, es.ForceRefresh(timeoutCtx), context.DeadlineExceeded)
+	assert.ErrorIs(t, mgr.ForceRefresh(timeoutCtx), context.DeadlineExceeded)
+	<-es.refreshing
+	assert.NoError(t, es.ForceRefresh(ctx))
+
+	es.Close()
+	assert.ErrorIs(t, es.ForceRefresh(ctx), ErrSourceClosed)
+}
@@ -20,6 +20,8 @@ const (
 	UpdateType = "UPDATE"
 	DeleteType = "DELETE"
 	CreateType = "CREATE"
+	// RejectType is the change rejected by the validators, see Manager.RegisterRejectHandler
+	RejectType = "REJECT"
 )
 
 type Event struct {
@@ -40,6 +42,28 @@ func newEvent(eventSource, eventType string, key string, value string) *Event {
 	}
 }
 
+// mergeDeleteEvents drops the delete events of the formatted duplicate keys whose raw keys are deleted too,
+// so a single delete event is fired per logical key, the handler deletes the formatted keys in lockstep.
+func mergeDeleteEvents(events []*Event) []*Event {
+	deletedKeys := make(map[string]struct{})
+	for _, e := range events {
+		if formattedKey := formatKey(e.Key); e.EventType == DeleteType && formattedKey != e.Key {
+			deletedKeys[formattedKey] = struct{}{}
+		}
+	}
+	if len(deletedKeys) == 0 {
+		return events
+	}
+	merged := make([]*Event, 0, len(events))
+	for _, e := range events {
+		if _, ok := deletedKeys[e.Key]; ok && e.EventType == DeleteType {
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
 func PopulateEvents(source string, currentConfig, updatedConfig map[string]string) ([]*Event, error) {
 	events := make([]*Event, 0)
 
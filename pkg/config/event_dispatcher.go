@@ -16,47 +16,217 @@
 package config
 
 import (
+	"path"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"go.uber.org/atomic"
+)
+
+const (
+	// WildcardPattern matches all the keys, see RegisterForPattern
+	WildcardPattern = "*"
+	// DefaultEventQuietPeriod is how long the events are coalesced for the batch handlers, see BatchEventHandler
+	DefaultEventQuietPeriod = 200 * time.Millisecond
 )
 
+// registration is a handler registered, which is marked removed once unregistered,
+// so that the dispatching in progress skips it.
+type registration struct {
+	handler EventHandler
+	removed atomic.Bool
+}
+
+type patternRegistration struct {
+	pattern string
+	*registration
+}
+
+// eventBatch is the events coalesced for a batch handler, which are delivered once the timer fires.
+type eventBatch struct {
+	*registration
+	events []*Event
+	timer  *time.Timer
+}
+
 type EventDispatcher struct {
 	mut       sync.RWMutex
-	registry  map[string][]EventHandler
+	registry  map[string][]*registration
 	keyPrefix []string
+	patterns  []patternRegistration
+
+	// the pending events of each batch handler, the batches are delivered one by one by the timers,
+	// so the handlers are never called by the sources firing events while holding their locks
+	batchMu     sync.Mutex
+	deliverMu   sync.Mutex
+	quietPeriod time.Duration
+	batches     map[string]*eventBatch
 }
 
 func NewEventDispatcher() *EventDispatcher {
 	return &EventDispatcher{
-		registry:  make(map[string][]EventHandler),
-		keyPrefix: make([]string, 0),
+		registry:    make(map[string][]*registration),
+		keyPrefix:   make([]string, 0),
+		quietPeriod: DefaultEventQuietPeriod,
+		batches:     make(map[string]*eventBatch),
 	}
 }
 
+// SetQuietPeriod sets how long the events are coalesced for the batch handlers without more events.
+func (ed *EventDispatcher) SetQuietPeriod(quietPeriod time.Duration) {
+	ed.batchMu.Lock()
+	defer ed.batchMu.Unlock()
+	ed.quietPeriod = quietPeriod
+}
+
 func (ed *EventDispatcher) Get(key string) []EventHandler {
 	ed.mut.RLock()
 	defer ed.mut.RUnlock()
-	return ed.registry[formatKey(key)]
+	return lo.Map(ed.registry[formatKey(key)], func(r *registration, _ int) EventHandler { return r.handler })
 }
 
+// Dispatch calls the handlers of the event key in the order they registered, the ones registered by key or key prefix first,
+// then the ones by pattern. The handlers are called without holding the lock, so they could register and unregister handlers,
+// and the ones unregistered during dispatching are not called since then. The panic of a handler doesn't suppress the others.
+// The events are coalesced for the BatchEventHandler, which are delivered asynchronously.
 func (ed *EventDispatcher) Dispatch(event *Event) {
+	for _, r := range ed.match(formatKey(event.Key)) {
+		if r.removed.Load() {
+			continue
+		}
+		if _, ok := r.handler.(BatchEventHandler); ok {
+			ed.enqueue(r, event)
+			continue
+		}
+		callHandler(r.handler, event)
+	}
+}
+
+func (ed *EventDispatcher) enqueue(r *registration, event *Event) {
+	ed.batchMu.Lock()
+	defer ed.batchMu.Unlock()
+	id := r.handler.GetIdentifier()
+	batch, ok := ed.batches[id]
+	if !ok {
+		batch = &eventBatch{registration: r}
+		batch.timer = time.AfterFunc(ed.quietPeriod, func() {
+			ed.deliver(id, batch)
+		})
+		ed.batches[id] = batch
+	} else {
+		batch.timer.Reset(ed.quietPeriod)
+	}
+	batch.events = append(batch.events, event)
+}
+
+// deliver delivers the batch sorted by the keys, unless it's delivered already, the events of a key keep the order fired.
+func (ed *EventDispatcher) deliver(id string, batch *eventBatch) {
+	ed.deliverMu.Lock()
+	defer ed.deliverMu.Unlock()
+	ed.batchMu.Lock()
+	if ed.batches[id] != batch {
+		ed.batchMu.Unlock()
+		return
+	}
+	delete(ed.batches, id)
+	ed.batchMu.Unlock()
+
+	if batch.removed.Load() {
+		return
+	}
+	sort.SliceStable(batch.events, func(i, j int) bool {
+		return batch.events[i].Key < batch.events[j].Key
+	})
+	recoverHandler(batch.handler, func() { batch.handler.(BatchEventHandler).OnBatch(batch.events) })
+}
+
+// flushEvents implements eventFlusher, which delivers the pending batches without waiting for the quiet period,
+// and flushes the handlers which dispatch the events further.
+func (ed *EventDispatcher) flushEvents() {
+	ed.batchMu.Lock()
+	for _, batch := range ed.batches {
+		batch.timer.Reset(0)
+	}
+	ed.batchMu.Unlock()
+
+	for _, r := range ed.all() {
+		if flusher, ok := r.handler.(eventFlusher); ok && !r.removed.Load() {
+			recoverHandler(r.handler, flusher.flushEvents)
+		}
+	}
+}
+
+// recordChanges implements changeRecorder, which passes the records to the handlers recording them.
+func (ed *EventDispatcher) recordChanges(records []ChangeRecord) {
+	for _, r := range ed.all() {
+		if recorder, ok := r.handler.(changeRecorder); ok && !r.removed.Load() {
+			recoverHandler(r.handler, func() { recorder.recordChanges(records) })
+		}
+	}
+}
+
+// all returns all the registrations, each handler once.
+func (ed *EventDispatcher) all() []*registration {
+	ed.mut.RLock()
+	defer ed.mut.RUnlock()
+	var hs []*registration
+	for _, rs := range ed.registry {
+		hs = append(hs, rs...)
+	}
+	for _, p := range ed.patterns {
+		hs = append(hs, p.registration)
+	}
+	return lo.UniqBy(hs, func(r *registration) string { return r.handler.GetIdentifier() })
+}
+
+func (ed *EventDispatcher) match(realKey string) []*registration {
 	ed.mut.RLock()
 	defer ed.mut.RUnlock()
-	var hs []EventHandler
-	realKey := formatKey(event.Key)
 	hs, ok := ed.registry[realKey]
+	hs = append([]*registration(nil), hs...)
 	if !ok {
 		for _, v := range ed.keyPrefix {
 			if strings.HasPrefix(realKey, v) {
-				if _, exist := ed.registry[v]; exist {
-					hs = append(hs, ed.registry[v]...)
-				}
+				hs = append(hs, ed.registry[v]...)
 			}
 		}
 	}
-	for _, h := range hs {
-		h.OnEvent(event)
+	// the handler registered by overlapping patterns is called once
+	matched := make(map[string]struct{})
+	for _, p := range ed.patterns {
+		if _, ok := matched[p.handler.GetIdentifier()]; ok {
+			continue
+		}
+		if ok, _ := path.Match(p.pattern, realKey); ok {
+			matched[p.handler.GetIdentifier()] = struct{}{}
+			hs = append(hs, p.registration)
+		}
+	}
+	return hs
+}
+
+// OnEvent implements EventHandler, which dispatches the event.
+func (ed *EventDispatcher) OnEvent(event *Event) {
+	ed.Dispatch(event)
+}
+
+func (ed *EventDispatcher) GetIdentifier() string {
+	return "EventDispatcher"
+}
+
+// Validate validates the event by the handlers of the event key which are validators, see Manager.Validate
+func (ed *EventDispatcher) Validate(event *Event) error {
+	for _, r := range ed.match(formatKey(event.Key)) {
+		if validator, ok := r.handler.(eventValidator); ok && !r.removed.Load() {
+			if err := validator.Validate(event); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
 // register a handler to watch specific config changed
@@ -64,12 +234,7 @@ func (ed *EventDispatcher) Register(key string, handler EventHandler) {
 	ed.mut.Lock()
 	defer ed.mut.Unlock()
 	key = formatKey(key)
-	v, ok := ed.registry[key]
-	if ok {
-		ed.registry[key] = append(v, handler)
-	} else {
-		ed.registry[key] = []EventHandler{handler}
-	}
+	ed.registry[key] = append(ed.registry[key], &registration{handler: handler})
 }
 
 // register a handler to watch specific config changed
@@ -77,15 +242,23 @@ func (ed *EventDispatcher) RegisterForKeyPrefix(keyPrefix string, handler EventH
 	ed.mut.Lock()
 	defer ed.mut.Unlock()
 	keyPrefix = formatKey(keyPrefix)
-	v, ok := ed.registry[keyPrefix]
-	if ok {
-		ed.registry[keyPrefix] = append(v, handler)
-	} else {
-		ed.registry[keyPrefix] = []EventHandler{handler}
-	}
+	ed.registry[keyPrefix] = append(ed.registry[keyPrefix], &registration{handler: handler})
 	ed.keyPrefix = append(ed.keyPrefix, keyPrefix)
 }
 
+// RegisterForPattern registers a handler to watch the configs matching the glob pattern, e.g. "querynode.*" and "*.gracefulTime",
+// which is matched against the formatted keys as path.Match, so "*" matches any keys.
+func (ed *EventDispatcher) RegisterForPattern(pattern string, handler EventHandler) error {
+	pattern = formatKey(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	ed.mut.Lock()
+	defer ed.mut.Unlock()
+	ed.patterns = append(ed.patterns, patternRegistration{pattern: pattern, registration: &registration{handler: handler}})
+	return nil
+}
+
 func (ed *EventDispatcher) Unregister(key string, handler EventHandler) {
 	ed.mut.Lock()
 	defer ed.mut.Unlock()
@@ -94,12 +267,25 @@ func (ed *EventDispatcher) Unregister(key string, handler EventHandler) {
 	if !ok {
 		return
 	}
-	newGroup := make([]EventHandler, 0)
-	for _, eh := range v {
-		if eh.GetIdentifier() == handler.GetIdentifier() {
-			continue
-		}
-		newGroup = append(newGroup, eh)
+	ed.registry[key] = lo.Filter(v, func(r *registration, _ int) bool {
+		return !removeIfIdentical(r, handler)
+	})
+}
+
+// UnregisterForPattern unregisters the handler registered for the pattern.
+func (ed *EventDispatcher) UnregisterForPattern(pattern string, handler EventHandler) {
+	ed.mut.Lock()
+	defer ed.mut.Unlock()
+	pattern = formatKey(pattern)
+	ed.patterns = lo.Filter(ed.patterns, func(p patternRegistration, _ int) bool {
+		return p.pattern != pattern || !removeIfIdentical(p.registration, handler)
+	})
+}
+
+func removeIfIdentical(r *registration, handler EventHandler) bool {
+	if r.handler.GetIdentifier() != handler.GetIdentifier() {
+		return false
 	}
-	ed.registry[key] = newGroup
+	r.removed.Store(true)
+	return true
 }
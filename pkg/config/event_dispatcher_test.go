@@ -17,7 +17,9 @@
 package config
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/suite"
@@ -136,6 +138,157 @@ func (s *EventDispatcherSuite) TestDispatch() {
 	})
 }
 
+func (s *EventDispatcherSuite) TestRegisterForPattern() {
+	dispatcher := s.dispatcher
+	var called []string
+	record := func(ident string) EventHandler {
+		return NewHandler(ident, func(e *Event) { called = append(called, ident+":"+e.Key) })
+	}
+
+	s.Error(dispatcher.RegisterForPattern("querynode.[", record("invalid")))
+	s.NoError(dispatcher.RegisterForPattern("querynode.*", record("querynode")))
+	s.NoError(dispatcher.RegisterForPattern("*.gracefulTime", record("gracefulTime")))
+	// overlapping patterns of a handler
+	s.NoError(dispatcher.RegisterForPattern("queryNode.*", record("overlapped")))
+	s.NoError(dispatcher.RegisterForPattern("*.gracefulTime", record("overlapped")))
+
+	dispatcher.Dispatch(newEvent("test", "test", "queryNode.gracefulTime", "1"))
+	s.Equal([]string{"querynode:queryNode.gracefulTime", "gracefulTime:queryNode.gracefulTime", "overlapped:queryNode.gracefulTime"}, called)
+
+	called = nil
+	dispatcher.Dispatch(newEvent("test", "test", "proxy.gracefulTime", "1"))
+	dispatcher.Dispatch(newEvent("test", "test", "queryNode.enableDisk", "true"))
+	dispatcher.Dispatch(newEvent("test", "test", "dataNode.enableDisk", "true"))
+	s.Equal([]string{
+		"gracefulTime:proxy.gracefulTime", "overlapped:proxy.gracefulTime",
+		"querynode:queryNode.enableDisk", "overlapped:queryNode.enableDisk",
+	}, called)
+
+	called = nil
+	dispatcher.UnregisterForPattern("querynode.*", record("overlapped"))
+	dispatcher.Dispatch(newEvent("test", "test", "queryNode.enableDisk", "true"))
+	dispatcher.Dispatch(newEvent("test", "test", "proxy.gracefulTime", "1"))
+	s.Equal([]string{
+		"querynode:queryNode.enableDisk",
+		"gracefulTime:proxy.gracefulTime", "overlapped:proxy.gracefulTime",
+	}, called)
+}
+
+func (s *EventDispatcherSuite) TestDispatchOrder() {
+	dispatcher := s.dispatcher
+	var called []string
+	record := func(ident string) EventHandler {
+		return NewHandler(ident, func(e *Event) { called = append(called, ident+":"+e.Value) })
+	}
+
+	s.NoError(dispatcher.RegisterForPattern(WildcardPattern, record("wildcard")))
+	dispatcher.Register("a.b", record("key"))
+	s.NoError(dispatcher.RegisterForPattern("a.*", record("pattern")))
+	dispatcher.Register("a.b", record("key2"))
+
+	for _, value := range []string{"1", "2", "3"} {
+		dispatcher.Dispatch(newEvent("test", "test", "a.b", value))
+	}
+	s.Equal([]string{
+		"key:1", "key2:1", "wildcard:1", "pattern:1",
+		"key:2", "key2:2", "wildcard:2", "pattern:2",
+		"key:3", "key2:3", "wildcard:3", "pattern:3",
+	}, called)
+}
+
+func (s *EventDispatcherSuite) TestUnregisterDuringDispatch() {
+	dispatcher := s.dispatcher
+	var called []string
+	second := NewHandler("second", func(e *Event) { called = append(called, "second") })
+	first := NewHandler("first", func(e *Event) {
+		called = append(called, "first")
+		// unregistering in the handler neither blocks nor calls the one unregistered
+		dispatcher.Unregister("a", second)
+		dispatcher.UnregisterForPattern("a*", second)
+		s.NoError(dispatcher.RegisterForPattern("a*", NewHandler("third", func(e *Event) { called = append(called, "third") })))
+	})
+	dispatcher.Register("a", first)
+	dispatcher.Register("a", second)
+	s.NoError(dispatcher.RegisterForPattern("a*", second))
+
+	dispatcher.Dispatch(newEvent("test", "test", "a", "1"))
+	s.Equal([]string{"first"}, called)
+
+	// the handler registered during dispatching is called since the next event
+	called = nil
+	dispatcher.Unregister("a", first)
+	dispatcher.Dispatch(newEvent("test", "test", "a", "2"))
+	s.Equal([]string{"third"}, called)
+}
+
+func (s *EventDispatcherSuite) TestBatchDispatch() {
+	dispatcher := s.dispatcher
+	var mu sync.Mutex
+	var batches [][]*Event
+	var events []*Event
+	batchHandler := NewBatchHandler("batch", func(batch []*Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, batch)
+	})
+	dispatcher.RegisterForPattern(WildcardPattern, batchHandler)
+	dispatcher.RegisterForPattern(WildcardPattern, NewHandler("single", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	delivered := func(n int) func() bool {
+		return func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(batches) == n
+		}
+	}
+	fired := []*Event{
+		newEvent("test", CreateType, "c", "1"),
+		newEvent("test", CreateType, "a", "1"),
+		newEvent("test", CreateType, "b", "1"),
+		newEvent("test", UpdateType, "a", "2"),
+	}
+
+	s.Run("quiet period", func() {
+		dispatcher.SetQuietPeriod(50 * time.Millisecond)
+		for _, event := range fired {
+			dispatcher.Dispatch(event)
+		}
+		// the single event handler is not affected
+		mu.Lock()
+		s.Equal(fired, events)
+		s.Empty(batches)
+		mu.Unlock()
+
+		s.Eventually(delivered(1), time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		// sorted by keys, and the events of a key are in the order fired
+		s.Equal([]*Event{fired[1], fired[3], fired[2], fired[0]}, batches[0])
+	})
+
+	s.Run("flush", func() {
+		dispatcher.SetQuietPeriod(time.Hour)
+		dispatcher.Dispatch(fired[0])
+		dispatcher.Dispatch(fired[1])
+		dispatcher.flushEvents()
+		s.Eventually(delivered(2), time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		s.Equal([]*Event{fired[1], fired[0]}, batches[1])
+	})
+
+	s.Run("unregistered", func() {
+		dispatcher.Dispatch(fired[0])
+		dispatcher.UnregisterForPattern(WildcardPattern, batchHandler)
+		dispatcher.flushEvents()
+		time.Sleep(50 * time.Millisecond)
+		s.True(delivered(2)())
+	})
+}
+
 func TestEventDispatcher(t *testing.T) {
 	suite.Run(t, new(EventDispatcherSuite))
 }
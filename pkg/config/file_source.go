@@ -17,11 +17,13 @@
 package config
 
 import (
-	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -31,18 +33,37 @@ import (
 
 type FileSource struct {
 	sync.RWMutex
+	name    string
 	files   []string
 	configs map[string]string
 
 	configRefresher *refresher
+
+	// watch the files to reload them once changed, see watchFiles
+	watchEnabled bool
+	watcher      *fsnotify.Watcher
+	watchWg      sync.WaitGroup
+	// the paths files resolve to, to detect the symlinks swapped
+	realPaths map[string]string
+
+	// error of the last load, nil if it succeeded, and when it succeeded last time
+	lastErr     error
+	lastSuccess time.Time
 }
 
 func NewFileSource(fileInfo *FileInfo) *FileSource {
 	fs := &FileSource{
-		files:   fileInfo.Files,
-		configs: make(map[string]string),
+		name:         sourceName("FileSource", fileInfo.Name),
+		files:        fileInfo.Files,
+		configs:      make(map[string]string),
+		watchEnabled: fileInfo.Watch,
 	}
 	fs.configRefresher = newRefresher(fileInfo.RefreshInterval, fs.loadFromFile)
+	fs.configRefresher.snapshot = func(fn func(string, map[string]string)) {
+		fs.RLock()
+		defer fs.RUnlock()
+		fn(fs.GetSourceName(), fs.configs)
+	}
 	return fs
 }
 
@@ -52,11 +73,18 @@ func (fs *FileSource) GetConfigurationByKey(key string) (string, error) {
 	v, ok := fs.configs[key]
 	fs.RUnlock()
 	if !ok {
-		return "", fmt.Errorf("key not found: %s", key)
+		return "", ErrKeyNotFound
 	}
 	return v, nil
 }
 
+// GetConfigurationsByKeyPrefix implements ConfigSource
+func (fs *FileSource) GetConfigurationsByKeyPrefix(prefix string) (map[string]string, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	return filterByKeyPrefix(fs.configs, prefix), nil
+}
+
 // GetConfigurations implements ConfigSource
 func (fs *FileSource) GetConfigurations() (map[string]string, error) {
 	configMap := make(map[string]string)
@@ -67,12 +95,16 @@ func (fs *FileSource) GetConfigurations() (map[string]string, error) {
 	}
 
 	fs.configRefresher.start(fs.GetSourceName())
+	if fs.watchEnabled {
+		fs.startWatch()
+	}
 
 	fs.RLock()
 	for k, v := range fs.configs {
 		configMap[k] = v
 	}
 	fs.RUnlock()
+	fs.configRefresher.markLoaded()
 	return configMap, nil
 }
 
@@ -83,17 +115,50 @@ func (fs *FileSource) GetPriority() int {
 
 // GetSourceName implements ConfigSource
 func (fs *FileSource) GetSourceName() string {
-	return "FileSource"
+	return fs.name
+}
+
+// GetUpdateTime implements UpdateTimer
+func (fs *FileSource) GetUpdateTime(key string) (time.Time, bool) {
+	return fs.configRefresher.updateTime(key)
+}
+
+// Health implements Source, the last good configurations are kept while failing to load the files
+func (fs *FileSource) Health() SourceHealth {
+	fs.RLock()
+	defer fs.RUnlock()
+	health := SourceHealth{
+		LastSuccessTime: fs.lastSuccess,
+		LastError:       fs.lastErr,
+		KeyCount:        len(fs.configs),
+		Connected:       fs.lastErr == nil && !fs.lastSuccess.IsZero(),
+	}
+	if err := fs.configRefresher.failure(); err != nil {
+		health.LastError, health.Connected = err, false
+	}
+	return health
 }
 
 func (fs *FileSource) Close() {
 	fs.configRefresher.stop()
+	fs.stopWatch()
 }
 
+// SetEventHandler implements Source
+//
+// Deprecated: use AddEventHandler instead, which keeps the other handlers.
 func (fs *FileSource) SetEventHandler(eh EventHandler) {
-	fs.RWMutex.Lock()
-	defer fs.RWMutex.Unlock()
-	fs.configRefresher.eh = eh
+	fs.configRefresher.setEventHandler(eh)
+}
+
+// AddEventHandler implements Source
+func (fs *FileSource) AddEventHandler(eh EventHandler) int64 {
+	return fs.configRefresher.addEventHandler(eh)
+}
+
+// RemoveEventHandler implements Source
+func (fs *FileSource) RemoveEventHandler(id int64) {
+	fs.configRefresher.removeEventHandler(id)
 }
 
 func (fs *FileSource) UpdateOptions(opts Options) {
@@ -102,11 +167,133 @@ func (fs *FileSource) UpdateOptions(opts Options) {
 	}
 
 	fs.Lock()
-	defer fs.Unlock()
 	fs.files = opts.FileInfo.Files
+	fs.watchEnabled = opts.FileInfo.Watch
+	fs.Unlock()
+
+	// watch the directories of the new files
+	fs.stopWatch()
+	if opts.FileInfo.Watch {
+		fs.startWatch()
+	}
+}
+
+// startWatch starts watching the files, unless it's started already.
+// The directories of files are watched rather than the files, so the files replaced by rename,
+// or by swapping the symlink they resolve through as the ConfigMap mounted in Kubernetes, are still watched.
+func (fs *FileSource) startWatch() {
+	fs.Lock()
+	defer fs.Unlock()
+	if fs.watcher != nil {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warn("failed to watch config files, they are only reloaded periodically", zap.Error(err))
+		return
+	}
+
+	dirs := make(map[string]struct{})
+	fs.realPaths = make(map[string]string, len(fs.files))
+	for _, file := range fs.files {
+		fs.realPaths[file] = realPath(file)
+		dir := filepath.Dir(file)
+		if _, ok := dirs[dir]; ok {
+			continue
+		}
+		dirs[dir] = struct{}{}
+		if err := watcher.Add(dir); err != nil {
+			log.Warn("failed to watch directory of config file", zap.String("file", file), zap.Error(err))
+		}
+	}
+	fs.watcher = watcher
+	fs.watchWg.Add(1)
+	go fs.watchFiles(watcher)
+}
+
+func (fs *FileSource) stopWatch() {
+	fs.Lock()
+	watcher := fs.watcher
+	fs.watcher = nil
+	fs.Unlock()
+	if watcher != nil {
+		watcher.Close()
+		fs.watchWg.Wait()
+	}
 }
 
-func (fs *FileSource) loadFromFile() error {
+// watchFiles reloads the files once any of them is changed, until the watcher is closed.
+// A file failed to parse keeps the last good configurations, until it's fixed.
+func (fs *FileSource) watchFiles(watcher *fsnotify.Watcher) {
+	defer fs.watchWg.Done()
+	log.Info("start watching config files", zap.String("source", fs.GetSourceName()))
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				log.Info("stop watching config files", zap.String("source", fs.GetSourceName()))
+				return
+			}
+			if !fs.fileChanged(event) {
+				continue
+			}
+			log.Info("config file changed, reload it", zap.String("event", event.String()))
+			if err := fs.loadFromFile(); err != nil {
+				log.Error("failed to reload config files, keep the last good configurations", zap.Error(err))
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn("error watching config files", zap.Error(err))
+		}
+	}
+}
+
+// fileChanged returns whether event changes any of the files, either the file is written or created,
+// or the symlink it resolves through is swapped. A file removed is not reloaded until it's created again,
+// so the configurations are not wiped while a file is being replaced.
+func (fs *FileSource) fileChanged(event fsnotify.Event) bool {
+	fs.Lock()
+	defer fs.Unlock()
+	return filesChanged(event, fs.files, fs.realPaths)
+}
+
+// filesChanged returns whether event changes any of the files, and updates realPaths of the files with the symlinks swapped.
+func filesChanged(event fsnotify.Event, files []string, realPaths map[string]string) bool {
+	changed := false
+	for _, file := range files {
+		if filepath.Clean(event.Name) == filepath.Clean(file) && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+			changed = true
+		}
+		if path := realPath(file); path != "" && path != realPaths[file] {
+			realPaths[file] = path
+			changed = true
+		}
+	}
+	return changed
+}
+
+// realPath returns the path file resolves to, empty if it doesn't exist.
+func realPath(file string) string {
+	path, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func (fs *FileSource) loadFromFile() (err error) {
+	start := time.Now()
+	defer func() {
+		observeRefresh(fs.GetSourceName(), start, err)
+		fs.Lock()
+		defer fs.Unlock()
+		fs.lastErr = err
+		if err == nil {
+			fs.lastSuccess = time.Now()
+		}
+	}()
 	yamlReader := viper.New()
 	newConfig := make(map[string]string)
 	var configFiles []string
@@ -156,9 +343,10 @@ func (fs *FileSource) loadFromFile() error {
 
 	fs.Lock()
 	defer fs.Unlock()
-	err := fs.configRefresher.fireEvents(fs.GetSourceName(), fs.configs, newConfig)
+	err = fs.configRefresher.fireEvents(fs.GetSourceName(), fs.configs, newConfig)
 	if err != nil {
-		return err
+		// the changes held while frozen are applied once unfrozen
+		return ignoreFrozen(err)
 	}
 	fs.configs = newConfig
 
@@ -0,0 +1,175 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/pkg/log"
+)
+
+// ErrConfigFrozen is returned by firing the events while the configs are frozen, the changes of which are held
+// rather than applied by the source until unfrozen, see Manager.Freeze
+var ErrConfigFrozen = errors.New("configs are frozen")
+
+// FreezeStatus is the status of freezing the configs, see Manager.Freeze
+type FreezeStatus struct {
+	Frozen bool `json:"frozen"`
+	// when the configs are frozen, and for how long, zero if not frozen
+	FrozenSince time.Time     `json:"frozenSince"`
+	Duration    time.Duration `json:"duration"`
+	// number of the configs changed in the sources since frozen, which are applied once unfrozen
+	PendingChanges int `json:"pendingChanges"`
+}
+
+// eventHolder holds the events fired by the sources while the configs are frozen, see refresher.fireEvents
+type eventHolder interface {
+	// holdEvents returns whether the changes of the source are held, and keeps reload to apply them once unfrozen
+	holdEvents(name string, changes int, reload func() error) bool
+}
+
+// heldChanges are the changes of a source held while frozen, and how to reload the source to apply them
+type heldChanges struct {
+	changes int
+	reload  func() error
+}
+
+// configFreezer holds the changes of the sources since frozen, the sources keep fetching the configs,
+// but don't apply them or fire the events until unfrozen, so no change lands during the maintenance.
+type configFreezer struct {
+	mu          sync.Mutex
+	frozenSince time.Time
+	// the changes held of each source, the later ones of a source replace the earlier ones,
+	// as they are against the same configs applied before frozen
+	held map[string]heldChanges
+}
+
+func newConfigFreezer() *configFreezer {
+	return &configFreezer{held: make(map[string]heldChanges)}
+}
+
+func (f *configFreezer) freeze() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.frozenSince.IsZero() {
+		return false
+	}
+	f.frozenSince = time.Now()
+	return true
+}
+
+// unfreeze returns the changes held by the source names, and how long it's frozen, false if not frozen.
+func (f *configFreezer) unfreeze() (map[string]heldChanges, time.Duration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.frozenSince.IsZero() {
+		return nil, 0, false
+	}
+	held, duration := f.held, time.Since(f.frozenSince)
+	f.held = make(map[string]heldChanges)
+	f.frozenSince = time.Time{}
+	return held, duration, true
+}
+
+func (f *configFreezer) hold(name string, changes int, reload func() error) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.frozenSince.IsZero() {
+		return false
+	}
+	f.held[name] = heldChanges{changes: changes, reload: reload}
+	return true
+}
+
+func (f *configFreezer) pendingChanges(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.held[name].changes
+}
+
+func (f *configFreezer) status() FreezeStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.frozenSince.IsZero() {
+		return FreezeStatus{}
+	}
+	status := FreezeStatus{Frozen: true, FrozenSince: f.frozenSince, Duration: time.Since(f.frozenSince)}
+	for _, held := range f.held {
+		status.PendingChanges += held.changes
+	}
+	return status
+}
+
+// Freeze freezes the configs during maintenance, e.g. rolling upgrades, so no change of them lands until Unfreeze.
+// The sources keep fetching the configs meanwhile, but the changes are held rather than applied, and no event is fired,
+// neither are the events of the handlers added to the sources directly. The overlays set at runtime are not frozen.
+// It returns false if frozen already.
+func (m *Manager) Freeze() bool {
+	if !m.freezer.freeze() {
+		return false
+	}
+	log.Info("configs are frozen, the changes are held until unfrozen")
+	return true
+}
+
+// Unfreeze applies the changes held since Freeze, each source reloads the configs and fires the events of all the changes
+// since frozen at once, the ones changed several times are fired once with the latest values. It returns the failures of
+// reloading, the changes of which are applied by the next refreshing of the sources.
+func (m *Manager) Unfreeze() error {
+	held, duration, ok := m.freezer.unfreeze()
+	if !ok {
+		return nil
+	}
+	log.Info("configs are unfrozen, apply the changes held", zap.Duration("duration", duration), zap.Int("sources", len(held)))
+	names := make([]string, 0, len(held))
+	for name := range held {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var result error
+	for _, name := range names {
+		if err := held[name].reload(); err != nil {
+			log.Warn("failed to apply the config changes held while frozen, retry later", zap.String("source", name), zap.Error(err))
+			result = errors.CombineErrors(result, errors.Wrapf(err, "failed to apply configs of %s", name))
+		}
+	}
+	return result
+}
+
+// FreezeStatus returns whether the configs are frozen, since when, and the number of changes held.
+func (m *Manager) FreezeStatus() FreezeStatus {
+	return m.freezer.status()
+}
+
+// holdEvents implements eventHolder
+func (m *Manager) holdEvents(name string, changes int, reload func() error) bool {
+	return m.freezer.hold(name, changes, reload)
+}
+
+// ignoreFrozen returns nil if the changes are held by freezing, which keeps the configs applied before,
+// so the sources fetching them don't take it as a failure.
+func ignoreFrozen(err error) error {
+	if errors.Is(err, ErrConfigFrozen) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerFreeze(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("x.y: 1\n"), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	ms := NewMemorySource("override", NormalPriority)
+	ms.SetConfigs(map[string]string{"a.b": "1", "e.f": "1"})
+	require.NoError(t, mgr.AddSource(ms))
+	fs, ok := mgr.sources.Get("FileSource")
+	require.True(t, ok)
+
+	var mu sync.Mutex
+	events := make(map[string][]string)
+	record := func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events[event.Key] = append(events[event.Key], event.Value)
+	}
+	mgr.Dispatcher.RegisterForKeyPrefix("", NewHandler("freeze", record))
+	// the handlers of the source are frozen too
+	ms.AddEventHandler(NewHandler("source", record))
+	recorded := func() map[string][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		result := events
+		events = make(map[string][]string)
+		return result
+	}
+
+	assert.True(t, mgr.Freeze())
+	assert.False(t, mgr.Freeze())
+	ms.Set("a.b", "2")
+	ms.Set("a.b", "3")
+	ms.Set("c.d", "1")
+	ms.Delete("e.f")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("x.y: 2\n"), 0o600))
+	// fetched without failure, but held
+	require.NoError(t, fs.(*FileSource).loadFromFile())
+	assert.NoError(t, fs.Health().LastError)
+
+	assert.Empty(t, recorded())
+	for key, expected := range map[string]string{"a.b": "1", "e.f": "1", "x.y": "1"} {
+		value, err := mgr.GetConfig(key)
+		assert.NoError(t, err, key)
+		assert.Equal(t, expected, value, key)
+	}
+	_, err = mgr.GetConfig("c.d")
+	assert.Error(t, err)
+	status := mgr.FreezeStatus()
+	assert.True(t, status.Frozen)
+	assert.False(t, status.FrozenSince.IsZero())
+	assert.Equal(t, 4, status.PendingChanges)
+	health := mgr.Health()
+	assert.Equal(t, 3, health["override"].PendingChanges)
+	assert.Equal(t, 1, health["FileSource"].PendingChanges)
+
+	// the complete diff is fired once, with the latest values
+	require.NoError(t, mgr.Unfreeze())
+	changes := recorded()
+	for key, expected := range map[string][]string{
+		"a.b": {"3", "3"},
+		"c.d": {"1", "1"},
+		// deleted with the old value
+		"e.f": {"1", "1"},
+		"x.y": {"2"},
+	} {
+		assert.Equal(t, expected, changes[key], key)
+	}
+	for key, expected := range map[string]string{"a.b": "3", "c.d": "1", "x.y": "2"} {
+		value, err := mgr.GetConfig(key)
+		assert.NoError(t, err, key)
+		assert.Equal(t, expected, value, key)
+	}
+	_, err = mgr.GetConfig("e.f")
+	assert.Error(t, err)
+	assert.Equal(t, FreezeStatus{}, mgr.FreezeStatus())
+	assert.Zero(t, mgr.Health()["override"].PendingChanges)
+	assert.NoError(t, mgr.Unfreeze())
+
+	// applied at once if not frozen
+	ms.Set("a.b", "4")
+	assert.Equal(t, []string{"4", "4"}, recorded()["a.b"])
+}
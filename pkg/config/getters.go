@@ -0,0 +1,125 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// ParseBool parses the bool value case-insensitively, "1", "t", "true" are true, "0", "f", "false" are false.
+func ParseBool(value string) (bool, error) {
+	b, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(value)))
+	if err != nil {
+		return false, errors.Wrapf(ErrInvalidValue, "%q is not a bool", value)
+	}
+	return b, nil
+}
+
+// ParseInt64 parses the decimal integer value.
+func ParseInt64(value string) (int64, error) {
+	i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidValue, "%q is not an int64", value)
+	}
+	return i, nil
+}
+
+// ParseFloat64 parses the float value.
+func ParseFloat64(value string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidValue, "%q is not a float64", value)
+	}
+	return f, nil
+}
+
+// ParseDuration parses the duration value, a number is in unit, e.g. "1.5" is 1500ms if unit is time.Second,
+// otherwise it's a Go duration string with the unit, e.g. "1h30m".
+func ParseDuration(value string, unit time.Duration) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(i) * unit, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(f * float64(unit)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidValue, "%q is not a duration", value)
+	}
+	return d, nil
+}
+
+// ParseStringSlice splits the comma separated value, the elements are trimmed, and the empty ones are left out.
+func ParseStringSlice(value string) []string {
+	return lo.FilterMap(strings.Split(value, ","), func(s string, _ int) (string, bool) {
+		s = strings.TrimSpace(s)
+		return s, s != ""
+	})
+}
+
+// getTyped returns the value of the key parsed, or def if the key is not set.
+// The error of the value set invalid is returned with def, which includes the key and the value.
+func getTyped[T any](m *Manager, key string, def T, parse func(string) (T, error)) (T, error) {
+	value, err := m.GetConfig(key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return def, nil
+	}
+	if err != nil {
+		return def, err
+	}
+	result, err := parse(value)
+	if err != nil {
+		return def, errors.Wrapf(err, "config %s", key)
+	}
+	return result, nil
+}
+
+// GetInt64 returns the value of the key as int64, def if it's not set, or def with ErrInvalidValue if it's not an int64.
+func (m *Manager) GetInt64(key string, def int64) (int64, error) {
+	return getTyped(m, key, def, ParseInt64)
+}
+
+// GetBool returns the value of the key as bool, see ParseBool, def if it's not set, or def with ErrInvalidValue if it's not a bool.
+func (m *Manager) GetBool(key string, def bool) (bool, error) {
+	return getTyped(m, key, def, ParseBool)
+}
+
+// GetFloat64 returns the value of the key as float64, def if it's not set, or def with ErrInvalidValue if it's not a float64.
+func (m *Manager) GetFloat64(key string, def float64) (float64, error) {
+	return getTyped(m, key, def, ParseFloat64)
+}
+
+// GetDuration returns the value of the key as duration, the numbers are in unit, see ParseDuration,
+// def if it's not set, or def with ErrInvalidValue if it's not a duration.
+func (m *Manager) GetDuration(key string, def time.Duration, unit time.Duration) (time.Duration, error) {
+	return getTyped(m, key, def, func(value string) (time.Duration, error) {
+		return ParseDuration(value, unit)
+	})
+}
+
+// GetStringSlice returns the comma separated value of the key, see ParseStringSlice, or def if it's not set.
+func (m *Manager) GetStringSlice(key string, def []string) ([]string, error) {
+	return getTyped(m, key, def, func(value string) ([]string, error) {
+		return ParseStringSlice(value), nil
+	})
+}
@@ -0,0 +1,207 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValues(t *testing.T) {
+	t.Run("bool", func(t *testing.T) {
+		for _, c := range []struct {
+			value    string
+			expected bool
+			valid    bool
+		}{
+			{"true", true, true},
+			{"True", true, true},
+			{"TRUE", true, true},
+			{" tRuE ", true, true},
+			{"1", true, true},
+			{"t", true, true},
+			{"false", false, true},
+			{"False", false, true},
+			{"0", false, true},
+			{"f", false, true},
+			{"yes", false, false},
+			{"", false, false},
+			{"2", false, false},
+		} {
+			b, err := ParseBool(c.value)
+			assert.Equal(t, c.valid, err == nil, c.value)
+			assert.Equal(t, c.expected, b, c.value)
+		}
+	})
+
+	t.Run("int64", func(t *testing.T) {
+		for _, c := range []struct {
+			value    string
+			expected int64
+			valid    bool
+		}{
+			{"0", 0, true},
+			{"-1", -1, true},
+			{" 42 ", 42, true},
+			{"9223372036854775807", 9223372036854775807, true},
+			{"9223372036854775808", 0, false},
+			{"1.5", 0, false},
+			{"1e3", 0, false},
+			{"0x10", 0, false},
+			{"", 0, false},
+		} {
+			i, err := ParseInt64(c.value)
+			assert.Equal(t, c.valid, err == nil, c.value)
+			assert.Equal(t, c.expected, i, c.value)
+		}
+	})
+
+	t.Run("float64", func(t *testing.T) {
+		for _, c := range []struct {
+			value    string
+			expected float64
+			valid    bool
+		}{
+			{"0", 0, true},
+			{"1.5", 1.5, true},
+			{" -0.25 ", -0.25, true},
+			{"1e3", 1000, true},
+			{"abc", 0, false},
+			{"", 0, false},
+		} {
+			f, err := ParseFloat64(c.value)
+			assert.Equal(t, c.valid, err == nil, c.value)
+			assert.Equal(t, c.expected, f, c.value)
+		}
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		for _, c := range []struct {
+			value    string
+			unit     time.Duration
+			expected time.Duration
+			valid    bool
+		}{
+			{"10", time.Second, 10 * time.Second, true},
+			{"10", time.Millisecond, 10 * time.Millisecond, true},
+			{"1.5", time.Second, 1500 * time.Millisecond, true},
+			{"1h", time.Second, time.Hour, true},
+			{" 1h30m ", time.Millisecond, 90 * time.Minute, true},
+			{"500ms", time.Second, 500 * time.Millisecond, true},
+			{"-1", time.Second, -time.Second, true},
+			{"1d", time.Second, 0, false},
+			{"h", time.Second, 0, false},
+			{"", time.Second, 0, false},
+		} {
+			d, err := ParseDuration(c.value, c.unit)
+			assert.Equal(t, c.valid, err == nil, c.value)
+			assert.Equal(t, c.expected, d, c.value)
+		}
+	})
+
+	t.Run("string slice", func(t *testing.T) {
+		for _, c := range []struct {
+			value    string
+			expected []string
+		}{
+			{"a", []string{"a"}},
+			{"a,b", []string{"a", "b"}},
+			{" a , b ,", []string{"a", "b"}},
+			{"a,,b", []string{"a", "b"}},
+			{"", []string{}},
+			{" , ", []string{}},
+		} {
+			assert.Equal(t, c.expected, ParseStringSlice(c.value), c.value)
+		}
+	})
+}
+
+func TestManagerTypedGetters(t *testing.T) {
+	mgr, err := Init()
+	require.NoError(t, err)
+	defer mgr.Close()
+	for key, value := range map[string]string{
+		"a.int":      "42",
+		"a.bool":     "True",
+		"a.float":    "0.5",
+		"a.duration": "1h",
+		"a.slice":    "x, y",
+		"a.invalid":  "abc",
+	} {
+		mgr.SetConfig(key, value)
+	}
+
+	i, err := mgr.GetInt64("a.int", 1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 42, i)
+	b, err := mgr.GetBool("a.bool", false)
+	assert.NoError(t, err)
+	assert.True(t, b)
+	f, err := mgr.GetFloat64("a.float", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, f)
+	d, err := mgr.GetDuration("a.duration", time.Second, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, d)
+	s, err := mgr.GetStringSlice("a.slice", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, s)
+
+	t.Run("missing", func(t *testing.T) {
+		i, err := mgr.GetInt64("a.missing", 1)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, i)
+		b, err := mgr.GetBool("a.missing", true)
+		assert.NoError(t, err)
+		assert.True(t, b)
+		f, err := mgr.GetFloat64("a.missing", 1.5)
+		assert.NoError(t, err)
+		assert.Equal(t, 1.5, f)
+		d, err := mgr.GetDuration("a.missing", time.Minute, time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, d)
+		s, err := mgr.GetStringSlice("a.missing", []string{"z"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"z"}, s)
+
+		// deleted at runtime
+		mgr.DeleteConfig("a.int")
+		i, err = mgr.GetInt64("a.int", 1)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, i)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		i, err := mgr.GetInt64("a.invalid", 1)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.ErrorContains(t, err, "a.invalid")
+		assert.ErrorContains(t, err, `"abc"`)
+		assert.EqualValues(t, 1, i)
+		b, err := mgr.GetBool("a.invalid", true)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.True(t, b)
+		f, err := mgr.GetFloat64("a.invalid", 1.5)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.Equal(t, 1.5, f)
+		d, err := mgr.GetDuration("a.invalid", time.Minute, time.Second)
+		assert.ErrorIs(t, err, ErrInvalidValue)
+		assert.Equal(t, time.Minute, d)
+	})
+}
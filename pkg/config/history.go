@@ -0,0 +1,129 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistorySize is the max change records kept, see Manager.SetHistorySize
+	DefaultHistorySize = 1000
+	// RedactedValue replaces the values of the secrets in the change records
+	RedactedValue = "******"
+)
+
+// ChangeRecord records a change of config in a source, the values of the secrets are redacted,
+// i.e. the ones encrypted in etcd, see EtcdInfo.Decryptor
+type ChangeRecord struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	EventType string    `json:"eventType"`
+	Key       string    `json:"key"`
+	OldValue  string    `json:"oldValue"`
+	NewValue  string    `json:"newValue"`
+}
+
+// changeRecorder records the changes of the events fired by the sources, after the rejected ones are dropped, see Manager.History
+type changeRecorder interface {
+	recordChanges(records []ChangeRecord)
+}
+
+// changeHistory is a ring of the latest change records, the oldest ones are overwritten once full.
+type changeHistory struct {
+	mu      sync.RWMutex
+	records []ChangeRecord
+	// the index of the oldest record, and the number of records
+	start int
+	count int
+}
+
+func newChangeHistory(size int) *changeHistory {
+	if size < 0 {
+		size = 0
+	}
+	return &changeHistory{records: make([]ChangeRecord, size)}
+}
+
+func (h *changeHistory) add(records ...ChangeRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	size := len(h.records)
+	if size == 0 {
+		return
+	}
+	for _, record := range records {
+		if h.count < size {
+			h.records[(h.start+h.count)%size] = record
+			h.count++
+			continue
+		}
+		h.records[h.start] = record
+		h.start = (h.start + 1) % size
+	}
+}
+
+// list returns the records since the time whose keys start with the prefix in order, all of them if the time is zero.
+func (h *changeHistory) list(since time.Time, keyPrefix string) []ChangeRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	keyPrefix = formatKey(keyPrefix)
+	records := make([]ChangeRecord, 0)
+	for i := 0; i < h.count; i++ {
+		record := h.records[(h.start+i)%len(h.records)]
+		if record.Time.Before(since) || !hasKeyPrefix(record.Key, keyPrefix) {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// resize keeps the latest records fitting in the size.
+func (h *changeHistory) resize(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	records := make([]ChangeRecord, size)
+	count := h.count
+	if count > size {
+		count = size
+	}
+	for i := 0; i < count; i++ {
+		records[i] = h.records[(h.start+h.count-count+i)%len(h.records)]
+	}
+	h.records, h.start, h.count = records, 0, count
+}
+
+// History returns the changes of the sources since the time whose keys start with the prefix, in the order recorded,
+// all of them if the time is zero. At most the latest DefaultHistorySize changes are kept, see SetHistorySize.
+func (m *Manager) History(since time.Time, keyPrefix string) []ChangeRecord {
+	return m.history.list(since, keyPrefix)
+}
+
+// SetHistorySize updates the max change records kept, the oldest ones are dropped if more than it, no records if not positive.
+func (m *Manager) SetHistorySize(size int) {
+	m.history.resize(size)
+}
+
+// recordChanges implements changeRecorder
+func (m *Manager) recordChanges(records []ChangeRecord) {
+	m.history.add(records...)
+}
@@ -0,0 +1,366 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/pkg/log"
+)
+
+// HTTPChecksumHeader carries the hex encoded sha256 checksum of the document, which is verified if present
+const HTTPChecksumHeader = "X-Config-Checksum"
+
+type HTTPSource struct {
+	sync.RWMutex
+	name          string
+	client        *http.Client
+	url           string
+	currentConfig map[string]string
+	// ETag of currentConfig, to skip the unchanged document
+	etag string
+	// error of the last fetch, nil if it succeeded, and when it succeeded last time
+	lastErr     error
+	lastSuccess time.Time
+
+	configRefresher *refresher
+}
+
+func NewHTTPSource(httpInfo *HTTPInfo) (*HTTPSource, error) {
+	log.Debug("init http source", zap.String("url", httpInfo.URL))
+	client := &http.Client{Timeout: ReadConfigTimeout}
+	if httpInfo.UseSSL {
+		tlsConfig, err := newHTTPTLSConfig(httpInfo)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	hs := &HTTPSource{
+		name:          sourceName("HTTPSource", httpInfo.Name),
+		client:        client,
+		url:           httpInfo.URL,
+		currentConfig: make(map[string]string),
+	}
+	hs.configRefresher = newRefresher(httpInfo.RefreshInterval, hs.refreshConfigurations)
+	hs.configRefresher.snapshot = func(fn func(string, map[string]string)) {
+		hs.RLock()
+		defer hs.RUnlock()
+		fn(hs.GetSourceName(), hs.currentConfig)
+	}
+	return hs, nil
+}
+
+func newHTTPTLSConfig(httpInfo *HTTPInfo) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	switch httpInfo.MinVersion {
+	case "":
+	case "1.0":
+		tlsConfig.MinVersion = tls.VersionTLS10
+	case "1.1":
+		tlsConfig.MinVersion = tls.VersionTLS11
+	case "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, errors.Errorf("unknown TLS version,%s", httpInfo.MinVersion)
+	}
+
+	if httpInfo.CaCertFile != "" {
+		caCert, err := os.ReadFile(httpInfo.CaCertFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load http source CACert file error, filename = %s", httpInfo.CaCertFile)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(caCert)
+	}
+	if httpInfo.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(httpInfo.CertFile, httpInfo.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load http source cert key pair error")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// GetConfigurationByKey implements ConfigSource
+func (hs *HTTPSource) GetConfigurationByKey(key string) (string, error) {
+	hs.RLock()
+	v, ok := hs.currentConfig[key]
+	hs.RUnlock()
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return v, nil
+}
+
+// GetConfigurationsByKeyPrefix implements ConfigSource
+func (hs *HTTPSource) GetConfigurationsByKeyPrefix(prefix string) (map[string]string, error) {
+	hs.RLock()
+	defer hs.RUnlock()
+	return filterByKeyPrefix(hs.currentConfig, prefix), nil
+}
+
+// GetConfigurations implements ConfigSource
+func (hs *HTTPSource) GetConfigurations() (map[string]string, error) {
+	configMap := make(map[string]string)
+	err := hs.refreshConfigurations()
+	if err != nil {
+		return nil, err
+	}
+	hs.configRefresher.start(hs.GetSourceName())
+	hs.RLock()
+	for key, value := range hs.currentConfig {
+		configMap[key] = value
+	}
+	hs.RUnlock()
+	hs.configRefresher.markLoaded()
+
+	return configMap, nil
+}
+
+// GetPriority implements ConfigSource
+func (hs *HTTPSource) GetPriority() int {
+	return HighPriority
+}
+
+// GetSourceName implements ConfigSource
+func (hs *HTTPSource) GetSourceName() string {
+	return hs.name
+}
+
+// GetUpdateTime implements UpdateTimer
+func (hs *HTTPSource) GetUpdateTime(key string) (time.Time, bool) {
+	return hs.configRefresher.updateTime(key)
+}
+
+// Health implements Source, LastError is the error of the last fetch, the last good configurations are kept meanwhile
+func (hs *HTTPSource) Health() SourceHealth {
+	hs.RLock()
+	defer hs.RUnlock()
+	health := SourceHealth{
+		LastSuccessTime: hs.lastSuccess,
+		LastError:       hs.lastErr,
+		KeyCount:        len(hs.currentConfig),
+		Connected:       hs.lastErr == nil && !hs.lastSuccess.IsZero(),
+	}
+	if err := hs.configRefresher.failure(); err != nil {
+		health.LastError, health.Connected = err, false
+	}
+	return health
+}
+
+func (hs *HTTPSource) Close() {
+	hs.configRefresher.stop()
+	hs.client.CloseIdleConnections()
+}
+
+// SetEventHandler implements Source
+//
+// Deprecated: use AddEventHandler instead, which keeps the other handlers.
+func (hs *HTTPSource) SetEventHandler(eh EventHandler) {
+	hs.configRefresher.setEventHandler(eh)
+}
+
+// AddEventHandler implements Source
+func (hs *HTTPSource) AddEventHandler(eh EventHandler) int64 {
+	return hs.configRefresher.addEventHandler(eh)
+}
+
+// RemoveEventHandler implements Source
+func (hs *HTTPSource) RemoveEventHandler(id int64) {
+	hs.configRefresher.removeEventHandler(id)
+}
+
+func (hs *HTTPSource) UpdateOptions(opts Options) {
+	if opts.HTTPInfo == nil {
+		return
+	}
+	hs.Lock()
+	if hs.url != opts.HTTPInfo.URL {
+		hs.url = opts.HTTPInfo.URL
+		hs.etag = ""
+	}
+	hs.Unlock()
+	hs.configRefresher.setInterval(opts.HTTPInfo.RefreshInterval)
+}
+
+func (hs *HTTPSource) refreshConfigurations() error {
+	hs.RLock()
+	url, etag := hs.url, hs.etag
+	hs.RUnlock()
+
+	start := time.Now()
+	newConfig, newEtag, err := hs.fetch(url, etag)
+	observeRefresh(hs.GetSourceName(), start, err)
+	hs.Lock()
+	defer hs.Unlock()
+	hs.lastErr = err
+	if err != nil {
+		return err
+	}
+	hs.lastSuccess = time.Now()
+	// not modified
+	if newConfig == nil {
+		return nil
+	}
+	err = hs.configRefresher.fireEvents(hs.GetSourceName(), hs.currentConfig, newConfig)
+	if err != nil {
+		// the changes held while frozen are applied once unfrozen
+		return ignoreFrozen(err)
+	}
+	hs.currentConfig = newConfig
+	hs.etag = newEtag
+	return nil
+}
+
+// fetch gets the document from url, returns nil configurations if it's not modified since etag.
+func (hs *HTTPSource) fetch(url string, etag string) (map[string]string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ReadConfigTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, etag, nil
+	case http.StatusOK:
+	default:
+		return nil, "", errors.Errorf("failed to get configurations from %s, status: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if checksum := resp.Header.Get(HTTPChecksumHeader); checksum != "" {
+		sum := sha256.Sum256(body)
+		if !strings.EqualFold(checksum, hex.EncodeToString(sum[:])) {
+			return nil, "", errors.Errorf("checksum of configurations from %s mismatched, expected: %s, actual: %x", url, checksum, sum)
+		}
+	}
+	configs, err := parseHTTPConfigs(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to parse configurations from %s", url)
+	}
+	return configs, resp.Header.Get("ETag"), nil
+}
+
+// parseHTTPConfigs parses the JSON document, or the flattened properties document with a key=value per line.
+func parseHTTPConfigs(contentType string, body []byte) (map[string]string, error) {
+	configs := make(map[string]string)
+	if strings.Contains(contentType, "json") || bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var document map[string]any
+		if err := decoder.Decode(&document); err != nil {
+			return nil, err
+		}
+		if err := flattenJSON(configs, "", document); err != nil {
+			return nil, err
+		}
+		return configs, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, errors.Errorf("invalid properties line: %s", line)
+		}
+		setHTTPConfig(configs, key, strings.TrimSpace(value))
+	}
+	return configs, scanner.Err()
+}
+
+// flattenJSON flattens the nested objects with dot separated keys, and joins the arrays with comma as the file source.
+func flattenJSON(configs map[string]string, prefix string, document map[string]any) error {
+	for key, val := range document {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch val := val.(type) {
+		case map[string]any:
+			if err := flattenJSON(configs, key, val); err != nil {
+				return err
+			}
+		case []any:
+			values := make([]string, 0, len(val))
+			for _, v := range val {
+				str, err := jsonValueToString(v)
+				if err != nil {
+					return errors.Wrapf(err, "invalid value of %s", key)
+				}
+				values = append(values, str)
+			}
+			setHTTPConfig(configs, key, strings.Join(values, ","))
+		default:
+			str, err := jsonValueToString(val)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value of %s", key)
+			}
+			setHTTPConfig(configs, key, str)
+		}
+	}
+	return nil
+}
+
+func jsonValueToString(val any) (string, error) {
+	if number, ok := val.(json.Number); ok {
+		return number.String(), nil
+	}
+	return cast.ToStringE(val)
+}
+
+func setHTTPConfig(configs map[string]string, key, value string) {
+	configs[key] = value
+	configs[formatKey(key)] = value
+}
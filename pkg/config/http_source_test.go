@@ -0,0 +1,249 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/pkg/metrics"
+)
+
+// configServer serves the document with its ETag and checksum.
+type configServer struct {
+	mu          sync.Mutex
+	contentType string
+	document    string
+	checksum    string
+	status      int
+	served      int
+}
+
+func (s *configServer) set(contentType, document string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contentType = contentType
+	s.document = document
+	sum := sha256.Sum256([]byte(document))
+	s.checksum = hex.EncodeToString(sum[:])
+	s.status = http.StatusOK
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != http.StatusOK {
+		w.WriteHeader(s.status)
+		return
+	}
+	etag := `"` + s.checksum + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.served++
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", s.contentType)
+	w.Header().Set(HTTPChecksumHeader, s.checksum)
+	w.Write([]byte(s.document))
+}
+
+func (s *configServer) servedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.served
+}
+
+func TestHTTPSource(t *testing.T) {
+	cs := &configServer{}
+	cs.set("application/json", `{"a": {"b": 1, "c": "x", "d": [1, 2]}, "e.f": true}`)
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	hs, err := NewHTTPSource(&HTTPInfo{URL: server.URL, RefreshInterval: time.Hour})
+	require.NoError(t, err)
+	var mu sync.Mutex
+	var events []*Event
+	hs.SetEventHandler(NewHandler("test", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+
+	configs, err := hs.GetConfigurations()
+	require.NoError(t, err)
+	defer hs.Close()
+	assert.Equal(t, "1", configs["a.b"])
+	assert.Equal(t, "x", configs["a.c"])
+	assert.Equal(t, "1,2", configs["a.d"])
+	assert.Equal(t, "true", configs[formatKey("e.f")])
+	assert.True(t, hs.Health().Connected)
+
+	t.Run("skip unchanged", func(t *testing.T) {
+		assert.NoError(t, hs.refreshConfigurations())
+		assert.Equal(t, 1, cs.servedCount())
+	})
+
+	t.Run("properties", func(t *testing.T) {
+		cs.set("text/plain", "# comment\na.b = 2\n\ne.f=false\n")
+		assert.NoError(t, hs.refreshConfigurations())
+		value, err := hs.GetConfigurationByKey("a.b")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", value)
+		_, err = hs.GetConfigurationByKey("a.c")
+		assert.Error(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, events, newEvent(hs.GetSourceName(), UpdateType, "a.b", "2"))
+		assert.Contains(t, events, newEvent(hs.GetSourceName(), DeleteType, "a.c", "x"))
+	})
+
+	assertKept := func(t *testing.T) {
+		assert.Error(t, hs.refreshConfigurations())
+		assert.Error(t, hs.Health().LastError)
+		value, err := hs.GetConfigurationByKey("a.b")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", value)
+	}
+
+	t.Run("checksum mismatched", func(t *testing.T) {
+		cs.set("text/plain", "a.b=3")
+		cs.mu.Lock()
+		cs.checksum = "invalid"
+		cs.mu.Unlock()
+		assertKept(t)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		cs.set("application/json", `{"a": `)
+		assertKept(t)
+		cs.set("text/plain", "a.b")
+		assertKept(t)
+	})
+
+	t.Run("server unavailable", func(t *testing.T) {
+		cs.mu.Lock()
+		cs.status = http.StatusServiceUnavailable
+		cs.mu.Unlock()
+		assertKept(t)
+
+		// healthy again once recovered
+		cs.set("text/plain", "a.b=4")
+		assert.NoError(t, hs.refreshConfigurations())
+		assert.True(t, hs.Health().Connected)
+		value, err := hs.GetConfigurationByKey("a.b")
+		assert.NoError(t, err)
+		assert.Equal(t, "4", value)
+	})
+
+	t.Run("refresh periodically", func(t *testing.T) {
+		hs.UpdateOptions(Options{HTTPInfo: &HTTPInfo{URL: server.URL, RefreshInterval: 10 * time.Millisecond}})
+		cs.set("text/plain", "a.b=5")
+		assert.Eventually(t, func() bool {
+			value, err := hs.GetConfigurationByKey("a.b")
+			return err == nil && value == "5"
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestHTTPSource_TLS(t *testing.T) {
+	cs := &configServer{}
+	cs.set("text/plain", "a.b=1")
+	server := httptest.NewTLSServer(cs)
+	defer server.Close()
+
+	_, err := NewHTTPSource(&HTTPInfo{URL: server.URL, UseSSL: true, MinVersion: "1.4"})
+	assert.Error(t, err)
+	_, err = NewHTTPSource(&HTTPInfo{URL: server.URL, UseSSL: true, CaCertFile: "not_exist.pem"})
+	assert.Error(t, err)
+
+	// the certificate of the server is unknown
+	hs, err := NewHTTPSource(&HTTPInfo{URL: server.URL, UseSSL: true, MinVersion: "1.2"})
+	require.NoError(t, err)
+	_, err = hs.GetConfigurations()
+	assert.Error(t, err)
+
+	caCertFile := path.Join(t.TempDir(), "ca.pem")
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caCertFile, caCert, 0o600))
+	hs, err = NewHTTPSource(&HTTPInfo{URL: server.URL, UseSSL: true, CaCertFile: caCertFile, MinVersion: "1.2"})
+	require.NoError(t, err)
+	configs, err := hs.GetConfigurations()
+	require.NoError(t, err)
+	defer hs.Close()
+	assert.Equal(t, "1", configs["a.b"])
+}
+
+func histogramCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var m dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestHTTPSourceMetrics(t *testing.T) {
+	cs := &configServer{}
+	cs.set("text/plain", "a.b=1\nc.d=2")
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	hs, err := NewHTTPSource(&HTTPInfo{URL: server.URL, RefreshInterval: time.Hour})
+	require.NoError(t, err)
+	name := hs.GetSourceName()
+	latency := metrics.ConfigRefreshLatency.WithLabelValues(name)
+	failures := metrics.ConfigRefreshFailCounter.WithLabelValues(name)
+	events := metrics.ConfigRefreshEvents.WithLabelValues(name)
+	keyNum := metrics.ConfigKeyNum.WithLabelValues(name)
+
+	refreshed, failed, fired := histogramCount(t, latency), testutil.ToFloat64(failures), histogramCount(t, events)
+	_, err = hs.GetConfigurations()
+	require.NoError(t, err)
+	defer hs.Close()
+	assert.Equal(t, refreshed+1, histogramCount(t, latency))
+	assert.Equal(t, fired+1, histogramCount(t, events))
+	assert.Equal(t, float64(4), testutil.ToFloat64(keyNum))
+
+	cs.set("text/plain", "a.b=1")
+	assert.NoError(t, hs.refreshConfigurations())
+	assert.Equal(t, refreshed+2, histogramCount(t, latency))
+	assert.Equal(t, float64(2), testutil.ToFloat64(keyNum))
+
+	cs.mu.Lock()
+	cs.status = http.StatusInternalServerError
+	cs.mu.Unlock()
+	assert.Error(t, hs.refreshConfigurations())
+	assert.Error(t, hs.refreshConfigurations())
+	assert.Equal(t, refreshed+4, histogramCount(t, latency))
+	assert.Equal(t, failed+2, testutil.ToFloat64(failures))
+	// no events fired by failures
+	assert.Equal(t, fired+2, histogramCount(t, events))
+	assert.Equal(t, float64(2), testutil.ToFloat64(keyNum))
+}
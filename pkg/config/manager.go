@@ -16,8 +16,10 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/cockroachdb/errors"
 	"go.uber.org/zap"
@@ -84,6 +86,21 @@ type Manager struct {
 	keySourceMap  *typeutil.ConcurrentMap[string, string] // store the key to config source, example: key is A.B.C and source is file which means the A.B.C's value is from file
 	overlays      *typeutil.ConcurrentMap[string, string] // store the highest priority configs which modified at runtime
 	forbiddenKeys *typeutil.ConcurrentSet[string]
+
+	validators     *validatorRegistry
+	rejectMu       sync.RWMutex
+	rejectHandlers []EventHandler
+
+	snapshots *snapshotRing
+	misses    *missCache
+	history   *changeHistory
+	aliases   *aliasTable
+
+	// the priorities of sources overridden at runtime, see SetSourcePriority
+	priorityMu sync.Mutex
+	priorities *typeutil.ConcurrentMap[string, int]
+	// hold the changes of sources while frozen, see Freeze
+	freezer *configFreezer
 }
 
 func NewManager() *Manager {
@@ -93,21 +110,52 @@ func NewManager() *Manager {
 		keySourceMap:  typeutil.NewConcurrentMap[string, string](),
 		overlays:      typeutil.NewConcurrentMap[string, string](),
 		forbiddenKeys: typeutil.NewConcurrentSet[string](),
+		validators:    newValidatorRegistry(),
+		snapshots:     newSnapshotRing(),
+		misses:        newMissCache(DefaultMissCacheTTL, DefaultMissCacheCapacity),
+		history:       newChangeHistory(DefaultHistorySize),
+		aliases:       newAliasTable(),
+		priorities:    typeutil.NewConcurrentMap[string, int](),
+		freezer:       newConfigFreezer(),
 	}
 }
 
+// GetConfig returns the value of the key, or ErrKeyNotFound if not found.
 func (m *Manager) GetConfig(key string) (string, error) {
+	missed, generation := m.misses.missed(key)
+	if missed {
+		return "", ErrKeyNotFound
+	}
 	realKey := formatKey(key)
+	if v, ok := m.overlays.Get(realKey); ok && v == TombValue {
+		return "", ErrKeyNotFound
+	}
+	value, err := m.getFormattedConfig(realKey)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return value, err
+	}
+	// fall back to the deprecated keys, see SetAlias
+	if newKey, deprecatedKeys, ok := m.aliases.alias(realKey); ok {
+		if value, ok := m.getDeprecatedConfig(newKey, deprecatedKeys); ok {
+			return value, nil
+		}
+	}
+	m.misses.add(key, generation)
+	return "", ErrKeyNotFound
+}
+
+// getFormattedConfig returns the value of the formatted key from the overlays or the sources, or ErrKeyNotFound if not found.
+func (m *Manager) getFormattedConfig(realKey string) (string, error) {
 	v, ok := m.overlays.Get(realKey)
 	if ok {
 		if v == TombValue {
-			return "", fmt.Errorf("key not found %s", key)
+			return "", ErrKeyNotFound
 		}
 		return v, nil
 	}
 	sourceName, ok := m.keySourceMap.Get(realKey)
 	if !ok {
-		return "", fmt.Errorf("key not found: %s", key)
+		return "", ErrKeyNotFound
 	}
 	return m.getConfigValueBySource(realKey, sourceName)
 }
@@ -134,6 +182,35 @@ func (m *Manager) GetConfigs() map[string]string {
 	return config
 }
 
+// GetConfigsByKeyPrefix returns the key values whose keys start with the prefix, see Source.GetConfigurationsByKeyPrefix,
+// the value of each key is the same as GetConfig, which respects the priorities of sources and the overlays.
+func (m *Manager) GetConfigsByKeyPrefix(prefix string) map[string]string {
+	config := make(map[string]string)
+	m.sources.Range(func(sourceName string, source Source) bool {
+		configs, err := source.GetConfigurationsByKeyPrefix(prefix)
+		if err != nil {
+			log.Warn("failed to get configs by key prefix", zap.String("source", sourceName), zap.Error(err))
+			return true
+		}
+		for key := range configs {
+			// the value may be from another source with higher priority, or overlaid
+			if value, err := m.GetConfig(key); err == nil {
+				config[key] = value
+			}
+		}
+		return true
+	})
+
+	formattedPrefix := formatKey(prefix)
+	m.overlays.Range(func(key, value string) bool {
+		if value != TombValue && hasKeyPrefix(key, formattedPrefix) {
+			config[key] = value
+		}
+		return true
+	})
+	return config
+}
+
 func (m *Manager) GetBy(filters ...Filter) map[string]string {
 	matchedConfig := make(map[string]string)
 
@@ -159,6 +236,36 @@ func (m *Manager) FileConfigs() map[string]string {
 	return config
 }
 
+// Health returns the health of each source by the source name, with the changes held while frozen, see Freeze.
+func (m *Manager) Health() map[string]SourceHealth {
+	health := make(map[string]SourceHealth)
+	m.sources.Range(func(sourceName string, source Source) bool {
+		sourceHealth := source.Health()
+		sourceHealth.PendingChanges = m.freezer.pendingChanges(sourceName)
+		health[sourceName] = sourceHealth
+		return true
+	})
+	return health
+}
+
+// ForceRefresh refreshes the configurations of the sources implementing ForceRefresher at once, e.g. to apply the configs
+// written to etcd without waiting for the refresh interval, and returns the failures of them, which keep the last good ones.
+func (m *Manager) ForceRefresh(ctx context.Context) error {
+	var result error
+	m.sources.Range(func(sourceName string, source Source) bool {
+		refresher, ok := source.(ForceRefresher)
+		if !ok {
+			return true
+		}
+		if err := refresher.ForceRefresh(ctx); err != nil {
+			log.Warn("failed to force refreshing configs", zap.String("source", sourceName), zap.Error(err))
+			result = errors.CombineErrors(result, errors.Wrapf(err, "failed to refresh configs of %s", sourceName))
+		}
+		return true
+	})
+	return result
+}
+
 func (m *Manager) Close() {
 	m.sources.Range(func(key string, value Source) bool {
 		value.Close()
@@ -166,12 +273,13 @@ func (m *Manager) Close() {
 	})
 }
 
+// AddSource adds the source, which is rejected if the name of it is taken by another source,
+// the sources of the same kind are named by their names set in the options, e.g. EtcdInfo.Name.
 func (m *Manager) AddSource(source Source) error {
 	sourceName := source.GetSourceName()
 	_, ok := m.sources.Get(sourceName)
 	if ok {
-		err := errors.New("duplicate source supplied")
-		return err
+		return errors.Wrapf(ErrDuplicateSource, "source %s is added already, name it by the options", sourceName)
 	}
 
 	m.sources.Insert(sourceName, source)
@@ -182,7 +290,7 @@ func (m *Manager) AddSource(source Source) error {
 		return err
 	}
 
-	source.SetEventHandler(m)
+	source.AddEventHandler(m)
 
 	return nil
 }
@@ -191,10 +299,12 @@ func (m *Manager) AddSource(source Source) error {
 // The most used scenario is UT
 func (m *Manager) SetConfig(key, value string) {
 	m.overlays.Insert(formatKey(key), value)
+	m.misses.clear()
 }
 
 func (m *Manager) SetMapConfig(key, value string) {
 	m.overlays.Insert(strings.ToLower(key), value)
+	m.misses.clear()
 }
 
 // Delete config at runtime, which has the highest priority to override all other sources
@@ -237,7 +347,7 @@ func (m *Manager) pullSourceConfigs(source string) error {
 		return err
 	}
 
-	sourcePriority := configSource.GetPriority()
+	sourcePriority := m.getPriority(configSource)
 	for key := range configs {
 		sourceName, ok := m.keySourceMap.Get(key)
 		if !ok { // if key do not exist then add source
@@ -251,12 +361,13 @@ func (m *Manager) pullSourceConfigs(source string) error {
 			continue
 		}
 
-		currentSrcPriority := currentSource.GetPriority()
+		currentSrcPriority := m.getPriority(currentSource)
 		if currentSrcPriority > sourcePriority { // lesser value has high priority
 			m.keySourceMap.Insert(key, source)
 		}
 	}
 
+	m.misses.clear()
 	return nil
 }
 
@@ -279,6 +390,7 @@ func (m *Manager) updateEvent(e *Event) error {
 		sourceName, ok := m.keySourceMap.Get(e.Key)
 		if !ok {
 			m.keySourceMap.Insert(e.Key, e.EventSource)
+			m.misses.clear()
 			e.EventType = CreateType
 		} else if sourceName == e.EventSource {
 			e.EventType = UpdateType
@@ -302,12 +414,12 @@ func (m *Manager) updateEvent(e *Event) error {
 				e.EventSource, sourceName))
 			return ErrIgnoreChange
 		} else if sourceName == e.EventSource {
-			// find less priority source or delete key
-			source := m.findNextBestSource(e.Key, sourceName)
-			if source == nil {
-				m.keySourceMap.Remove(e.Key)
-			} else {
-				m.keySourceMap.Insert(e.Key, source.GetSourceName())
+			m.deleteKeySource(e.Key, sourceName)
+			// the formatted duplicate key is deleted in lockstep, otherwise the stale one shadows the fallback
+			if formattedKey := formatKey(e.Key); formattedKey != e.Key {
+				if sourceName, ok := m.keySourceMap.Get(formattedKey); ok && sourceName == e.EventSource {
+					m.deleteKeySource(formattedKey, sourceName)
+				}
 			}
 		}
 	}
@@ -330,12 +442,64 @@ func (m *Manager) OnEvent(event *Event) {
 	}
 
 	m.Dispatcher.Dispatch(event)
+	m.emitRenamedEvent(event)
+}
+
+// RegisterValidator registers the validator of the key, the new values rejected by it are not applied.
+func (m *Manager) RegisterValidator(key string, validator Validator) {
+	m.validators.register(key, validator)
+}
+
+// RegisterValidatorForKeyPrefix registers the validator of the keys with the prefix.
+func (m *Manager) RegisterValidatorForKeyPrefix(keyPrefix string, validator Validator) {
+	m.validators.registerForKeyPrefix(keyPrefix, validator)
+}
+
+// RegisterRejectHandler registers the handler of the changes rejected by validators, which are of RejectType.
+func (m *Manager) RegisterRejectHandler(handler EventHandler) {
+	m.rejectMu.Lock()
+	defer m.rejectMu.Unlock()
+	m.rejectHandlers = append(m.rejectHandlers, handler)
+}
+
+// Validate implements eventValidator, the config of the rejected event keeps the old value in its source.
+func (m *Manager) Validate(event *Event) error {
+	err := m.validators.validate(event.Key, event.Value)
+	if err == nil {
+		return nil
+	}
+	log.Warn("reject the invalid config change, keep the old value", zap.String("source", event.EventSource),
+		zap.String("key", event.Key), zap.String("value", event.Value), zap.Error(err))
+
+	rejected := *event
+	rejected.EventType = RejectType
+	m.rejectMu.RLock()
+	defer m.rejectMu.RUnlock()
+	for _, handler := range m.rejectHandlers {
+		handler.OnEvent(&rejected)
+	}
+	return err
 }
 
 func (m *Manager) GetIdentifier() string {
 	return "Manager"
 }
 
+// flushEvents implements eventFlusher
+func (m *Manager) flushEvents() {
+	m.Dispatcher.flushEvents()
+}
+
+// deleteKeySource makes the key from the less priority source, or deletes the key if no source has it.
+func (m *Manager) deleteKeySource(key string, sourceName string) {
+	source := m.findNextBestSource(key, sourceName)
+	if source == nil {
+		m.keySourceMap.Remove(key)
+	} else {
+		m.keySourceMap.Insert(key, source.GetSourceName())
+	}
+}
+
 func (m *Manager) findNextBestSource(configKey string, sourceName string) Source {
 	var rSource Source
 	m.sources.Range(func(key string, value Source) bool {
@@ -350,7 +514,7 @@ func (m *Manager) findNextBestSource(configKey string, sourceName string) Source
 			rSource = value
 			return true
 		}
-		if value.GetPriority() < rSource.GetPriority() { // less value has high priority
+		if m.getPriority(value) < m.getPriority(rSource) { // less value has high priority
 			rSource = value
 		}
 		return true
@@ -371,9 +535,83 @@ func (m *Manager) getHighPrioritySource(srcNameA, srcNameB string) Source {
 		return sourceA
 	}
 
-	if sourceA.GetPriority() < sourceB.GetPriority() { // less value has high priority
+	if m.getPriority(sourceA) < m.getPriority(sourceB) { // less value has high priority
 		return sourceA
 	}
 
 	return sourceB
 }
+
+// getPriority returns the priority of the source, which may be overridden at runtime.
+func (m *Manager) getPriority(source Source) int {
+	if priority, ok := m.priorities.Get(source.GetSourceName()); ok {
+		return priority
+	}
+	return source.GetPriority()
+}
+
+// SetSourcePriority overrides the priority of the source at runtime, e.g. to make the file configs win over the etcd ones
+// temporarily without deleting them. The keys whose values change with the priority are re-evaluated and fire the events.
+func (m *Manager) SetSourcePriority(sourceName string, priority int) error {
+	if _, ok := m.sources.Get(sourceName); !ok {
+		return fmt.Errorf("source %s not found", sourceName)
+	}
+	m.priorityMu.Lock()
+	defer m.priorityMu.Unlock()
+	m.priorities.Insert(sourceName, priority)
+	log.Info("source priority overridden", zap.String("source", sourceName), zap.Int("priority", priority))
+	m.reevaluateKeySources()
+	return nil
+}
+
+// ResetSourcePriority reverts the priority of the source to its own one, see SetSourcePriority.
+func (m *Manager) ResetSourcePriority(sourceName string) {
+	m.priorityMu.Lock()
+	defer m.priorityMu.Unlock()
+	if _, ok := m.priorities.GetAndRemove(sourceName); !ok {
+		return
+	}
+	log.Info("source priority reset", zap.String("source", sourceName))
+	m.reevaluateKeySources()
+}
+
+// reevaluateKeySources makes each key from the source with the highest priority,
+// and fires the update events of the keys whose values change.
+func (m *Manager) reevaluateKeySources() {
+	candidates := make(map[string]Source)
+	m.sources.Range(func(sourceName string, source Source) bool {
+		configs, err := source.GetConfigurations()
+		if err != nil {
+			log.Warn("failed to get configs to re-evaluate priorities", zap.String("source", sourceName), zap.Error(err))
+			return true
+		}
+		for key := range configs {
+			if best, ok := candidates[key]; !ok || m.getPriority(source) < m.getPriority(best) {
+				candidates[key] = source
+			}
+		}
+		return true
+	})
+
+	for key, source := range candidates {
+		sourceName, ok := m.keySourceMap.Get(key)
+		if (ok && sourceName == source.GetSourceName()) || m.forbiddenKeys.Contain(formatKey(key)) {
+			continue
+		}
+		oldValue, oldErr := m.GetConfig(key)
+		m.keySourceMap.Insert(key, source.GetSourceName())
+		if !ok {
+			m.misses.clear()
+		}
+		newValue, err := m.GetConfig(key)
+		if err != nil || (oldErr == nil && oldValue == newValue) {
+			continue
+		}
+		event := newEvent(source.GetSourceName(), UpdateType, key, newValue)
+		event.HasUpdated = true
+		log.Info("config changed by priorities", zap.String("key", key), zap.String("oldValue", oldValue),
+			zap.String("newValue", newValue), zap.String("source", source.GetSourceName()))
+		m.Dispatcher.Dispatch(event)
+	}
+	m.Dispatcher.flushEvents()
+}
@@ -18,15 +18,21 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
-	"go.etcd.io/etcd/server/v3/embed"
-	"go.etcd.io/etcd/server/v3/etcdserver/api/v3client"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -45,7 +51,7 @@ func TestConfigChangeEvent(t *testing.T) {
 	os.WriteFile(path.Join(dir, "milvus.yaml"), []byte("a.b: 1\nc.d: 2"), 0o600)
 	os.WriteFile(path.Join(dir, "user.yaml"), []byte("a.b: 3"), 0o600)
 
-	fs := NewFileSource(&FileInfo{[]string{path.Join(dir, "milvus.yaml"), path.Join(dir, "user.yaml")}, 1})
+	fs := NewFileSource(&FileInfo{Files: []string{path.Join(dir, "milvus.yaml"), path.Join(dir, "user.yaml")}, RefreshInterval: 1})
 	mgr, _ := Init()
 	err := mgr.AddSource(fs)
 	assert.NoError(t, err)
@@ -64,7 +70,10 @@ func TestAllDupliateSource(t *testing.T) {
 	err := mgr.AddSource(NewEnvSource(formatKey))
 	assert.NoError(t, err)
 	err = mgr.AddSource(NewEnvSource(formatKey))
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrDuplicateSource)
+	// named apart
+	err = mgr.AddSource(NewEnvSourceWithInfo(&EnvInfo{Name: "other", KeyFormatter: formatKey}))
+	assert.NoError(t, err)
 
 	err = mgr.AddSource(ErrSource{})
 	assert.Error(t, err, "error")
@@ -125,27 +134,16 @@ func TestBasic(t *testing.T) {
 }
 
 func TestOnEvent(t *testing.T) {
-	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
-	cfg.Dir = "/tmp/milvus/test"
-	e, err := embed.StartEtcd(cfg)
-	assert.NoError(t, err)
-	defer e.Close()
-	defer os.RemoveAll(cfg.Dir)
-
-	client := v3client.New(e.Server)
-
 	dir, _ := os.MkdirTemp("", "milvus")
 	yamlFile := path.Join(dir, "milvus.yaml")
 	mgr, _ := Init(WithEnvSource(formatKey),
 		WithFilesSource(&FileInfo{
 			Files:           []string{yamlFile},
 			RefreshInterval: 10 * time.Millisecond,
-		}),
-		WithEtcdSource(&EtcdInfo{
-			Endpoints:       []string{cfg.ACUrls[0].Host},
-			KeyPrefix:       "test",
-			RefreshInterval: 10 * time.Millisecond,
 		}))
+	// in place of the etcd source
+	ms := NewMemorySource("memory", HighPriority)
+	assert.NoError(t, mgr.AddSource(ms))
 
 	os.WriteFile(yamlFile, []byte("a.b: aaa"), 0o600)
 	time.Sleep(time.Second)
@@ -153,15 +151,12 @@ func TestOnEvent(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, value, "aaa")
 
-	ctx := context.Background()
-	client.KV.Put(ctx, "test/config/a/b", "bbb")
-	time.Sleep(time.Second)
+	ms.Set("a/b", "bbb")
 	value, err = mgr.GetConfig("a.b")
 	assert.NoError(t, err)
 	assert.Equal(t, value, "bbb")
 
-	client.KV.Put(ctx, "test/config/a/b", "ccc")
-	time.Sleep(time.Second)
+	ms.Set("a/b", "ccc")
 	value, err = mgr.GetConfig("a.b")
 	assert.NoError(t, err)
 	assert.Equal(t, value, "ccc")
@@ -172,8 +167,7 @@ func TestOnEvent(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, value, "ccc")
 
-	client.KV.Delete(ctx, "test/config/a/b")
-	time.Sleep(time.Second)
+	ms.Delete("a/b")
 	value, err = mgr.GetConfig("a.b")
 	assert.NoError(t, err)
 	assert.Equal(t, value, "ddd")
@@ -210,6 +204,11 @@ func (e ErrSource) GetConfigurationByKey(string) (string, error) {
 	return "", errors.New("error")
 }
 
+// GetConfigurationsByKeyPrefix implements Source
+func (ErrSource) GetConfigurationsByKeyPrefix(string) (map[string]string, error) {
+	return nil, errors.New("error")
+}
+
 // GetConfigurations implements Source
 func (ErrSource) GetConfigurations() (map[string]string, error) {
 	return nil, errors.New("error")
@@ -225,8 +224,456 @@ func (ErrSource) GetSourceName() string {
 	return "ErrSource"
 }
 
+func (e ErrSource) AddEventHandler(eh EventHandler) int64 {
+	return 0
+}
+
+func (e ErrSource) RemoveEventHandler(id int64) {
+}
+
 func (e ErrSource) SetEventHandler(eh EventHandler) {
 }
 
 func (e ErrSource) UpdateOptions(opt Options) {
 }
+
+func (e ErrSource) Health() SourceHealth {
+	return SourceHealth{LastError: errors.New("error")}
+}
+
+func TestManagerValidation(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\nc.d: 2\ne.f: 3\n"), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	source, ok := mgr.sources.Get("FileSource")
+	require.True(t, ok)
+	fs := source.(*FileSource)
+
+	isInt := func(key, value string) error {
+		_, err := strconv.Atoi(value)
+		return err
+	}
+	mgr.RegisterValidator("c.d", isInt)
+	mgr.RegisterValidatorForKeyPrefix("e.", func(key, value string) error {
+		if value == "bad" {
+			return errors.New("bad value")
+		}
+		return nil
+	})
+	mgr.RegisterValidator("i_j", func(key, value string) error {
+		return errors.New("always rejected")
+	})
+
+	var mu sync.Mutex
+	var applied, rejected []*Event
+	mgr.Dispatcher.RegisterForKeyPrefix("", NewHandler("applied", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		applied = append(applied, event)
+	}))
+	mgr.RegisterRejectHandler(NewHandler("rejected", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		rejected = append(rejected, event)
+	}))
+
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 10\nc.d: five seconds\ne.f: bad\ng.h: 4\ni.j: 5\n"), 0o600))
+	require.NoError(t, fs.loadFromFile())
+
+	// only the invalid keys keep the old values
+	for key, expected := range map[string]string{"a.b": "10", "c.d": "2", "e.f": "3", "g.h": "4"} {
+		value, err := mgr.GetConfig(key)
+		assert.NoError(t, err, key)
+		assert.Equal(t, expected, value, key)
+	}
+	_, err = mgr.GetConfig("i.j")
+	assert.Error(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.NotEmpty(t, applied)
+	for _, e := range applied {
+		assert.Contains(t, []string{"ab", "gh"}, formatKey(e.Key))
+	}
+	// a rejected event per logical key
+	assert.ElementsMatch(t, []*Event{
+		{EventSource: fs.GetSourceName(), EventType: RejectType, Key: "c.d", Value: "five seconds"},
+		{EventSource: fs.GetSourceName(), EventType: RejectType, Key: "e.f", Value: "bad"},
+		{EventSource: fs.GetSourceName(), EventType: RejectType, Key: "i.j", Value: "5"},
+	}, rejected)
+}
+
+func TestManagerSourcePriority(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\nc.d: 2\n"), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	ms := NewMemorySource("override", NormalPriority)
+	ms.SetConfigs(map[string]string{"a.b": "10", "e.f": "3"})
+	require.NoError(t, mgr.AddSource(ms))
+
+	var mu sync.Mutex
+	var events []*Event
+	mgr.Dispatcher.RegisterForKeyPrefix("", NewHandler("priority", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	assertConfigs := func(expected map[string]string, expectedEvents ...*Event) {
+		for key, value := range expected {
+			actual, err := mgr.GetConfig(key)
+			assert.NoError(t, err, key)
+			assert.Equal(t, value, actual, key)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		assert.ElementsMatch(t, expectedEvents, events)
+		events = nil
+	}
+	assertConfigs(map[string]string{"a.b": "10", "c.d": "2", "e.f": "3"})
+
+	assert.Error(t, mgr.SetSourcePriority("NotExist", HighPriority))
+
+	// the file source wins over the other one temporarily
+	require.NoError(t, mgr.SetSourcePriority("FileSource", HighPriority))
+	assertConfigs(map[string]string{"a.b": "1", "c.d": "2", "e.f": "3"},
+		&Event{EventSource: "FileSource", EventType: UpdateType, Key: "ab", Value: "1", HasUpdated: true})
+	// the changes from the lower priority source are ignored
+	ms.SetConfigs(map[string]string{"a.b": "20"})
+	assertConfigs(map[string]string{"a.b": "1"})
+
+	mgr.ResetSourcePriority("FileSource")
+	assertConfigs(map[string]string{"a.b": "20", "c.d": "2", "e.f": "3"},
+		&Event{EventSource: "override", EventType: UpdateType, Key: "ab", Value: "20", HasUpdated: true})
+	// reset again takes no effect
+	mgr.ResetSourcePriority("FileSource")
+	assertConfigs(map[string]string{"a.b": "20"})
+}
+
+func TestManagerDescribe(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\nc.d: 2\n"), 0o600))
+	start := time.Now()
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	ms := NewMemorySource("override", NormalPriority)
+	require.NoError(t, mgr.AddSource(ms))
+	ms.SetConfigs(map[string]string{"a.b": "10"})
+	mgr.SetConfig("e.f", "3")
+
+	value, source, priority, lastUpdated := mgr.Describe("a.b")
+	assert.Equal(t, "10", value)
+	assert.Equal(t, "override", source)
+	assert.Equal(t, NormalPriority, priority)
+	assert.False(t, lastUpdated.Before(start))
+
+	value, source, priority, fileUpdated := mgr.Describe("c_d")
+	assert.Equal(t, "2", value)
+	assert.Equal(t, "FileSource", source)
+	assert.Equal(t, LowPriority, priority)
+	assert.False(t, fileUpdated.Before(start))
+
+	value, source, priority, lastUpdated = mgr.Describe("e.f")
+	assert.Equal(t, "3", value)
+	assert.Equal(t, OverlaySourceName, source)
+	assert.Equal(t, OverlayPriority, priority)
+	assert.True(t, lastUpdated.IsZero())
+
+	_, source, _, _ = mgr.Describe("not.exist")
+	assert.Empty(t, source)
+
+	// updated since changed
+	updated := time.Now()
+	ms.SetConfigs(map[string]string{"a.b": "11"})
+	_, _, _, lastUpdated = mgr.Describe("a.b")
+	assert.False(t, lastUpdated.Before(updated))
+
+	descriptions := mgr.DescribeAll()
+	assert.Len(t, descriptions, 3)
+	assert.Equal(t, ConfigDescription{Value: "11", Source: "override", Priority: NormalPriority, LastUpdated: lastUpdated}, descriptions["ab"])
+	assert.Equal(t, ConfigDescription{Value: "2", Source: "FileSource", Priority: LowPriority, LastUpdated: fileUpdated}, descriptions["cd"])
+	assert.Equal(t, ConfigDescription{Value: "3", Source: OverlaySourceName, Priority: OverlayPriority}, descriptions["ef"])
+}
+
+func TestManagerHistory(t *testing.T) {
+	mgr, err := Init()
+	require.NoError(t, err)
+	defer mgr.Close()
+	ms := NewMemorySource("override", NormalPriority)
+	require.NoError(t, mgr.AddSource(ms))
+
+	start := time.Now()
+	ms.SetConfigs(map[string]string{"a.b": "1", "c.d": "2"})
+	ms.SetConfigs(map[string]string{"a.b": "10"})
+	// not changed
+	ms.SetConfigs(map[string]string{"c.d": "2"})
+	records := mgr.History(time.Time{}, "")
+	require.Len(t, records, 3)
+	// the formatted duplicate keys are left out
+	assert.Equal(t, []string{"a.b", "c.d", "a.b"}, lo.Map(records, func(r ChangeRecord, _ int) string { return r.Key }))
+	assert.Equal(t, ChangeRecord{Time: records[0].Time, Source: "override", EventType: CreateType, Key: "a.b", NewValue: "1"}, records[0])
+	assert.Equal(t, ChangeRecord{Time: records[2].Time, Source: "override", EventType: UpdateType, Key: "a.b", OldValue: "1", NewValue: "10"}, records[2])
+	assert.False(t, records[0].Time.Before(start))
+
+	// filtered by the time and the formatted key prefix
+	assert.Equal(t, []ChangeRecord{records[0], records[2]}, mgr.History(time.Time{}, "A_"))
+	assert.Equal(t, records[2:], mgr.History(records[2].Time, "a."))
+	assert.Empty(t, mgr.History(time.Now().Add(time.Hour), ""))
+
+	// the latest ones are kept
+	mgr.SetHistorySize(2)
+	assert.Equal(t, records[1:], mgr.History(time.Time{}, ""))
+	ms.SetConfigs(map[string]string{"e.f": "3"})
+	records = mgr.History(time.Time{}, "")
+	assert.Equal(t, []string{"a.b", "e.f"}, lo.Map(records, func(r ChangeRecord, _ int) string { return r.Key }))
+	mgr.SetHistorySize(0)
+	ms.SetConfigs(map[string]string{"e.f": "4"})
+	assert.Empty(t, mgr.History(time.Time{}, ""))
+}
+
+func TestManagerHealth(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\n"), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	require.NoError(t, mgr.AddSource(NewMemorySource("override", NormalPriority)))
+
+	health := mgr.Health()
+	assert.Len(t, health, 2)
+	assert.True(t, health["override"].Connected)
+	assert.Equal(t, 0, health["override"].KeyCount)
+	fileHealth := health["FileSource"]
+	assert.True(t, fileHealth.Connected)
+	assert.NoError(t, fileHealth.LastError)
+	assert.Equal(t, 2, fileHealth.KeyCount)
+	assert.False(t, fileHealth.Stale(0))
+	lastSuccess := fileHealth.LastSuccessTime
+	assert.False(t, lastSuccess.IsZero())
+
+	// failing to load the broken file, the last good configurations are kept
+	source, _ := mgr.sources.Get("FileSource")
+	fs := source.(*FileSource)
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: [1\n"), 0o600))
+	assert.Error(t, fs.loadFromFile())
+	fileHealth = mgr.Health()["FileSource"]
+	assert.False(t, fileHealth.Connected)
+	assert.Error(t, fileHealth.LastError)
+	assert.Equal(t, lastSuccess, fileHealth.LastSuccessTime)
+	assert.Equal(t, 2, fileHealth.KeyCount)
+	assert.False(t, fileHealth.Stale(time.Hour))
+	assert.True(t, fileHealth.Stale(0))
+	value, err := mgr.GetConfig("a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	// recovered
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 2\n"), 0o600))
+	assert.NoError(t, fs.loadFromFile())
+	fileHealth = mgr.Health()["FileSource"]
+	assert.True(t, fileHealth.Connected)
+	assert.NoError(t, fileHealth.LastError)
+	assert.True(t, fileHealth.LastSuccessTime.After(lastSuccess))
+}
+
+func TestManagerAliases(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`
+config:
+  aliases:
+    common.chanNamePrefix.cluster: msgChannel.chanNamePrefix.cluster, msgChannel.cluster
+msgChannel:
+  cluster: old
+`), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	ms := NewMemorySource("override", NormalPriority)
+	require.NoError(t, mgr.AddSource(ms))
+
+	_, err = mgr.GetConfig("common.chanNamePrefix.cluster")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	mgr.LoadAliases()
+	value, err := mgr.GetConfig("common.chanNamePrefix.cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, "old", value)
+
+	var mu sync.Mutex
+	var events []*Event
+	mgr.Dispatcher.Register("common.chanNamePrefix.cluster", NewHandler("test", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	popEvents := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		defer func() { events = nil }()
+		return lo.Map(events, func(e *Event, _ int) string { return e.EventType + " " + e.Value })
+	}
+
+	// the former deprecated key wins
+	ms.SetConfigs(map[string]string{"msgChannel.chanNamePrefix.cluster": "older"})
+	value, err = mgr.GetConfig("common.chanNamePrefix.cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, "older", value)
+	assert.Equal(t, []string{CreateType + " older"}, popEvents())
+	// overridden by the former one
+	ms.SetConfigs(map[string]string{"msgChannel.cluster": "shadowed"})
+	assert.Empty(t, popEvents())
+
+	// the new key wins over the deprecated ones
+	ms.SetConfigs(map[string]string{"common.chanNamePrefix.cluster": "new"})
+	value, err = mgr.GetConfig("common.chanNamePrefix.cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", value)
+	assert.Len(t, popEvents(), 2)
+	ms.SetConfigs(map[string]string{"msgChannel.chanNamePrefix.cluster": "ignored"})
+	assert.Empty(t, popEvents())
+	value, err = mgr.GetConfig("common.chanNamePrefix.cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", value)
+
+	// set programmatically
+	mgr.SetConfig("proxy.oldKey", "1")
+	mgr.SetAlias("proxy.newKey", "proxy.oldKey")
+	value, err = mgr.GetConfig("proxy_newKey")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+	mgr.SetAlias("proxy.newKey")
+	_, err = mgr.GetConfig("proxy.newKey")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestManagerMissCache(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\n"), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	source, ok := mgr.sources.Get("FileSource")
+	require.True(t, ok)
+	fs := source.(*FileSource)
+
+	assertMissed := func(key string) {
+		_, err := mgr.GetConfig(key)
+		assert.True(t, errors.Is(err, ErrKeyNotFound), key)
+		missed, _ := mgr.misses.missed(key)
+		assert.True(t, missed, key)
+	}
+	assertMissed("c.d")
+	assertMissed("e.f")
+
+	t.Run("key added by refreshing", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\nc.d: 2\n"), 0o600))
+		require.NoError(t, fs.loadFromFile())
+		value, err := mgr.GetConfig("c.d")
+		assert.NoError(t, err)
+		assert.Equal(t, "2", value)
+		assertMissed("e.f")
+	})
+
+	t.Run("key set at runtime", func(t *testing.T) {
+		mgr.SetConfig("e.f", "3")
+		value, err := mgr.GetConfig("e.f")
+		assert.NoError(t, err)
+		assert.Equal(t, "3", value)
+		mgr.DeleteConfig("e.f")
+		_, err = mgr.GetConfig("e.f")
+		assert.True(t, errors.Is(err, ErrKeyNotFound))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		cache := newMissCache(10*time.Millisecond, 2)
+		missed, generation := cache.missed("a")
+		assert.False(t, missed)
+		cache.add("a", generation)
+		missed, _ = cache.missed("a")
+		assert.True(t, missed)
+		time.Sleep(20 * time.Millisecond)
+		missed, _ = cache.missed("a")
+		assert.False(t, missed)
+
+		// not cached if cleared since looked up
+		_, generation = cache.missed("b")
+		cache.clear()
+		cache.add("b", generation)
+		missed, _ = cache.missed("b")
+		assert.False(t, missed)
+
+		// bounded by the capacity
+		_, generation = cache.missed("c")
+		for _, key := range []string{"c", "d", "e"} {
+			cache.add(key, generation)
+		}
+		assert.Len(t, cache.keys, 1)
+	})
+}
+
+func BenchmarkManagerGetConfigMiss(b *testing.B) {
+	yamlFile := path.Join(b.TempDir(), "milvus.yaml")
+	require.NoError(b, os.WriteFile(yamlFile, []byte("a.b: 1\n"), 0o600))
+	for _, ttl := range []time.Duration{0, DefaultMissCacheTTL} {
+		b.Run("ttl-"+ttl.String(), func(b *testing.B) {
+			mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+			require.NoError(b, err)
+			defer mgr.Close()
+			mgr.misses = newMissCache(ttl, DefaultMissCacheCapacity)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mgr.GetConfig("dataCoord.segment.optional_key")
+			}
+		})
+	}
+}
+
+func TestManagerBatchEvents(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\n"), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	source, ok := mgr.sources.Get("FileSource")
+	require.True(t, ok)
+	fs := source.(*FileSource)
+
+	// delivered once the refreshing completes rather than the quiet period
+	mgr.Dispatcher.SetQuietPeriod(time.Hour)
+	var mu sync.Mutex
+	var batches [][]*Event
+	var single atomic.Int64
+	mgr.Dispatcher.RegisterForPattern("key*", NewBatchHandler("batch", func(events []*Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, events)
+	}))
+	mgr.Dispatcher.RegisterForPattern("key*", NewHandler("single", func(*Event) {
+		single.Add(1)
+	}))
+
+	var document strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&document, "key.k%03d: %d\n", i, i)
+	}
+	require.NoError(t, os.WriteFile(yamlFile, []byte(document.String()), 0o600))
+	require.NoError(t, fs.loadFromFile())
+	// an event of the raw key and the formatted one each
+	assert.Equal(t, int64(400), single.Load())
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(batches) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, batches[0], 400)
+	assert.True(t, sort.SliceIsSorted(batches[0], func(i, j int) bool {
+		return batches[0][i].Key < batches[0][j].Key
+	}))
+}
@@ -0,0 +1,225 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// MemorySource holds the configs in memory, which are set programmatically and fire the events synchronously,
+// e.g. for the tests of the components driven by dynamic configs, without etcd.
+type MemorySource struct {
+	mu       sync.RWMutex
+	name     string
+	priority int
+	configs  map[string]string
+	// the configs set while frozen, which are applied once unfrozen, nil if none, see Manager.Freeze
+	held map[string]string
+	// when the configs are set last time
+	setTime time.Time
+	// the delay and the error injected into reading the configs, see InjectFault
+	delay time.Duration
+	err   error
+
+	configRefresher *refresher
+}
+
+func NewMemorySource(name string, priority int) *MemorySource {
+	ms := &MemorySource{
+		name:     name,
+		priority: priority,
+		configs:  make(map[string]string),
+		setTime:  time.Now(),
+	}
+	ms.configRefresher = newRefresher(0, ms.applyHeld)
+	ms.configRefresher.snapshot = func(fn func(string, map[string]string)) {
+		ms.mu.RLock()
+		defer ms.mu.RUnlock()
+		fn(ms.name, ms.configs)
+	}
+	// loaded once created, with no configurations
+	ms.configRefresher.markLoaded()
+	return ms
+}
+
+// Set sets the config, and fires the events of it before returning, see SetConfigs.
+func (ms *MemorySource) Set(key, value string) {
+	ms.SetConfigs(map[string]string{key: value})
+}
+
+// SetConfigs sets the configs, and fires the events of them before returning.
+// The configs are kept by both the keys and the formatted ones, as the other sources.
+func (ms *MemorySource) SetConfigs(configs map[string]string) {
+	ms.update(func(newConfig map[string]string) {
+		for key, value := range configs {
+			newConfig[key] = value
+			newConfig[formatKey(key)] = value
+		}
+	})
+}
+
+// Delete deletes the configs, and fires the events of them before returning.
+func (ms *MemorySource) Delete(keys ...string) {
+	ms.update(func(newConfig map[string]string) {
+		for _, key := range keys {
+			delete(newConfig, key)
+			delete(newConfig, formatKey(key))
+		}
+	})
+}
+
+func (ms *MemorySource) update(fn func(newConfig map[string]string)) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	// the configs set while frozen are updated on the ones held
+	current := ms.configs
+	if ms.held != nil {
+		current = ms.held
+	}
+	newConfig := make(map[string]string, len(current))
+	for key, value := range current {
+		newConfig[key] = value
+	}
+	fn(newConfig)
+	ms.apply(newConfig)
+}
+
+// applyHeld applies the configs set while frozen, once unfrozen.
+func (ms *MemorySource) applyHeld() error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.held != nil {
+		ms.apply(ms.held)
+	}
+	return nil
+}
+
+func (ms *MemorySource) apply(newConfig map[string]string) {
+	err := ms.configRefresher.fireEvents(ms.name, ms.configs, newConfig)
+	switch {
+	case err == nil:
+		ms.configs = newConfig
+		ms.held = nil
+		ms.setTime = time.Now()
+	case errors.Is(err, ErrConfigFrozen):
+		ms.held = newConfig
+	}
+}
+
+// InjectFault makes reading the configs delayed and failed with the error if it's not nil, which is reported by Health too,
+// for the tests of the failure paths. The configs are still set and fire the events. InjectFault(0, nil) clears the fault.
+func (ms *MemorySource) InjectFault(delay time.Duration, err error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.delay, ms.err = delay, err
+}
+
+// fault waits for the delay injected, and returns the error injected.
+func (ms *MemorySource) fault() error {
+	ms.mu.RLock()
+	delay, err := ms.delay, ms.err
+	ms.mu.RUnlock()
+	time.Sleep(delay)
+	return err
+}
+
+// GetConfigurationByKey implements ConfigSource
+func (ms *MemorySource) GetConfigurationByKey(key string) (string, error) {
+	if err := ms.fault(); err != nil {
+		return "", err
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	value, ok := ms.configs[key]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// GetConfigurationsByKeyPrefix implements ConfigSource
+func (ms *MemorySource) GetConfigurationsByKeyPrefix(prefix string) (map[string]string, error) {
+	if err := ms.fault(); err != nil {
+		return nil, err
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return filterByKeyPrefix(ms.configs, prefix), nil
+}
+
+// GetConfigurations implements ConfigSource
+func (ms *MemorySource) GetConfigurations() (map[string]string, error) {
+	if err := ms.fault(); err != nil {
+		return nil, err
+	}
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return filterByKeyPrefix(ms.configs, ""), nil
+}
+
+// GetPriority implements ConfigSource
+func (ms *MemorySource) GetPriority() int {
+	return ms.priority
+}
+
+// GetSourceName implements ConfigSource
+func (ms *MemorySource) GetSourceName() string {
+	return ms.name
+}
+
+// GetUpdateTime implements UpdateTimer
+func (ms *MemorySource) GetUpdateTime(key string) (time.Time, bool) {
+	return ms.configRefresher.updateTime(key)
+}
+
+// Health implements Source, the configs in memory are always loaded, unless the fault is injected
+func (ms *MemorySource) Health() SourceHealth {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return SourceHealth{
+		LastSuccessTime: ms.setTime,
+		LastError:       ms.err,
+		KeyCount:        len(ms.configs),
+		Connected:       ms.err == nil,
+	}
+}
+
+// SetEventHandler implements Source
+//
+// Deprecated: use AddEventHandler instead, which keeps the other handlers.
+func (ms *MemorySource) SetEventHandler(eh EventHandler) {
+	ms.configRefresher.setEventHandler(eh)
+}
+
+// AddEventHandler implements Source
+func (ms *MemorySource) AddEventHandler(eh EventHandler) int64 {
+	return ms.configRefresher.addEventHandler(eh)
+}
+
+// RemoveEventHandler implements Source
+func (ms *MemorySource) RemoveEventHandler(id int64) {
+	ms.configRefresher.removeEventHandler(id)
+}
+
+func (ms *MemorySource) UpdateOptions(opts Options) {
+}
+
+func (ms *MemorySource) Close() {
+}
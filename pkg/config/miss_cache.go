@@ -0,0 +1,79 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMissCacheTTL      = time.Second
+	DefaultMissCacheCapacity = 1024
+)
+
+// missCache caches the keys recently missed by the raw keys, to save formatting and looking up them again.
+// It's cleared once any key is added, and each miss expires after the ttl anyway.
+type missCache struct {
+	mu       sync.RWMutex
+	keys     map[string]time.Time
+	ttl      time.Duration
+	capacity int
+	// increased by clear, so the misses looked up before it are not cached
+	generation uint64
+}
+
+func newMissCache(ttl time.Duration, capacity int) *missCache {
+	return &missCache{
+		keys:     make(map[string]time.Time),
+		ttl:      ttl,
+		capacity: capacity,
+	}
+}
+
+// missed returns whether the key is missed within the ttl, and the generation to cache the miss of it, see add.
+func (c *missCache) missed(key string) (bool, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	missedAt, ok := c.keys[key]
+	return ok && time.Since(missedAt) < c.ttl, c.generation
+}
+
+// add caches the miss of the key, unless the cache is cleared since the generation.
+func (c *missCache) add(key string, generation uint64) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if len(c.keys) >= c.capacity {
+		c.keys = make(map[string]time.Time)
+	}
+	c.keys[key] = time.Now()
+}
+
+func (c *missCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if len(c.keys) > 0 {
+		c.keys = make(map[string]time.Time)
+	}
+}
@@ -16,42 +16,78 @@
 package config
 
 import (
+	"math"
+	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 
 	"github.com/milvus-io/milvus/pkg/log"
+	"github.com/milvus-io/milvus/pkg/metrics"
 )
 
+// MaxRefreshPanics is the max consecutive panics of refreshing, the refreshing panicked is retried after backing off exponentially,
+// at most MaxRefreshBackoff, and stopped once more panics, which marks the source unhealthy, see refresher.failure
+const MaxRefreshPanics = 5
+
+// refresher calls fetchFunc every interval by a goroutine, which lives until stopped,
+// and the interval could be updated without restarting it, see setInterval.
+// The interval is jittered to spread the refreshing of many nodes, see setJitter.
 type refresher struct {
+	mu               sync.Mutex
 	refreshInterval  time.Duration
+	jitter           float64
+	intervalUpdated  chan struct{}
 	intervalDone     chan struct{}
 	intervalInitOnce sync.Once
-	eh               EventHandler
+
+	// the handlers of the events fired, in the order added, see addEventHandler
+	handlerMu     sync.RWMutex
+	handlers      []*registeredHandler
+	nextHandlerID int64
+	// the first load is committed, and the snapshot of the current configurations for replaying it, see markLoaded
+	loaded   bool
+	snapshot func(fn func(name string, configs map[string]string))
 
 	fetchFunc func() error
 	stopOnce  sync.Once
 	wg        sync.WaitGroup
+	// why the refreshing is stopped before stop, see MaxRefreshPanics
+	failureErr error
+
+	// when each key is updated by the events fired, see updateTime
+	timeMu      sync.RWMutex
+	updateTimes map[string]time.Time
+	// whether the values of the key are secrets, which are redacted in the change records
+	isSecret func(key string) bool
+	// whether any of the events fired last time is rejected by the validators, it's accessed with the lock of the source held
+	rejected bool
 }
 
 func newRefresher(interval time.Duration, fetchFunc func() error) *refresher {
 	return &refresher{
 		refreshInterval: interval,
+		intervalUpdated: make(chan struct{}, 1),
 		intervalDone:    make(chan struct{}),
 		fetchFunc:       fetchFunc,
+		updateTimes:     make(map[string]time.Time),
 	}
 }
 
+// start starts the goroutine once, which refreshes only if the interval is positive.
 func (r *refresher) start(name string) {
-	if r.refreshInterval > 0 {
-		r.intervalInitOnce.Do(func() {
-			r.wg.Add(1)
-			go r.refreshPeriodically(name)
-		})
-	}
+	r.intervalInitOnce.Do(func() {
+		r.wg.Add(1)
+		go r.refreshPeriodically(name)
+	})
 }
 
+// stop stops the goroutine and waits for the refreshing in progress,
+// so it must not be called with the locks which fetchFunc takes.
 func (r *refresher) stop() {
 	r.stopOnce.Do(func() {
 		close(r.intervalDone)
@@ -59,19 +95,126 @@ func (r *refresher) stop() {
 	})
 }
 
+func (r *refresher) stopped() bool {
+	select {
+	case <-r.intervalDone:
+		return true
+	default:
+		return false
+	}
+}
+
+// setInterval updates the interval, which takes effect on the goroutine started without restarting it.
+func (r *refresher) setInterval(interval time.Duration) {
+	r.mu.Lock()
+	r.refreshInterval = interval
+	r.mu.Unlock()
+	select {
+	case r.intervalUpdated <- struct{}{}:
+	default:
+	}
+}
+
+// failure returns why the refreshing is stopped before stop, nil if it's not.
+func (r *refresher) failure() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failureErr
+}
+
+func (r *refresher) interval() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.refreshInterval
+}
+
+// setJitter sets the jitter as a fraction of the interval in [0, 1], each interval is randomized within
+// [interval*(1-jitter), interval*(1+jitter)], and the first one within [0, interval] if jittered.
+// It takes effect since the next interval.
+func (r *refresher) setJitter(jitter float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jitter = math.Max(0, math.Min(1, jitter))
+}
+
+// nextDelay returns the delay of the next refreshing, or false if not refreshing periodically.
+func (r *refresher) nextDelay(first bool) (time.Duration, bool) {
+	r.mu.Lock()
+	interval, jitter := r.refreshInterval, r.jitter
+	r.mu.Unlock()
+	if interval <= 0 {
+		return 0, false
+	}
+	spread := time.Duration(float64(interval) * jitter)
+	if spread <= 0 {
+		return interval, true
+	}
+	if first {
+		// the initial loading is synchronous, so many nodes started together are spread since the first refreshing
+		return time.Duration(rand.Int63n(int64(interval))) + 1, true
+	}
+	return interval - spread + time.Duration(rand.Int63n(int64(2*spread)+1)), true
+}
+
 func (r *refresher) refreshPeriodically(name string) {
 	defer r.wg.Done()
-	ticker := time.NewTicker(r.refreshInterval)
-	defer ticker.Stop()
+	var timer *time.Timer
+	var tick <-chan time.Time
+	scheduleAfter := func(delay time.Duration, ok bool) {
+		if timer != nil {
+			timer.Stop()
+			timer, tick = nil, nil
+		}
+		if ok {
+			timer = time.NewTimer(delay)
+			tick = timer.C
+		}
+	}
+	schedule := func(first bool) {
+		scheduleAfter(r.nextDelay(first))
+	}
+	schedule(true)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
 	log.Debug("start refreshing configurations", zap.String("source", name))
+	panics := 0
 	for {
 		select {
-		case <-ticker.C:
-			err := r.fetchFunc()
+		case <-tick:
+			// no more refreshing once stopped
+			if r.stopped() {
+				continue
+			}
+			// keep the last good configs and retry next time, stopping here would block on waiting itself
+			err := r.refresh()
+			if errors.Is(err, ErrRefreshPanicked) {
+				panics++
+				if panics > MaxRefreshPanics {
+					log.Error("stop refreshing configurations after too many panics", zap.String("source", name),
+						zap.Int("panics", panics), zap.Error(err))
+					r.mu.Lock()
+					r.failureErr = errors.Wrapf(err, "refreshing stopped after %d consecutive panics", panics)
+					r.mu.Unlock()
+					return
+				}
+				delay, ok := r.nextDelay(false)
+				delay <<= panics
+				if delay > MaxRefreshBackoff {
+					delay = MaxRefreshBackoff
+				}
+				scheduleAfter(delay, ok)
+				continue
+			}
+			panics = 0
 			if err != nil {
 				log.Error("can not pull configs", zap.Error(err))
-				r.stop()
 			}
+			schedule(false)
+		case <-r.intervalUpdated:
+			schedule(false)
 		case <-r.intervalDone:
 			log.Info("stop refreshing configurations", zap.String("source", name))
 			return
@@ -79,17 +222,325 @@ func (r *refresher) refreshPeriodically(name string) {
 	}
 }
 
+// refresh calls fetchFunc, the panic of which is recovered and logged with the stack, returned as ErrRefreshPanicked.
+// The locks are released by the deferred unlocking of fetchFunc while panicking.
+func (r *refresher) refresh() (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("refreshing configurations panicked", zap.Any("panic", p), zap.Stack("stack"))
+			err = errors.Wrapf(ErrRefreshPanicked, "%v", p)
+		}
+	}()
+	return r.fetchFunc()
+}
+
 func (r *refresher) fireEvents(name string, source, target map[string]string) error {
 	events, err := PopulateEvents(name, source, target)
 	if err != nil {
 		log.Warn("generating event error", zap.Error(err))
 		return err
 	}
+	handlers := r.eventHandlers()
+	if len(events) > 0 && r.holdEvents(handlers, name, events) {
+		return ErrConfigFrozen
+	}
+	validated := r.validateEvents(handlers, source, target, events)
+	r.rejected = len(validated) < len(events)
+	events = validated
+	events = mergeDeleteEvents(events)
+	r.recordUpdates(events)
+	r.recordChanges(handlers, source, events)
+	metrics.ConfigKeyNum.WithLabelValues(name).Set(float64(len(target)))
+	metrics.ConfigRefreshEvents.WithLabelValues(name).Observe(float64(len(events)))
 	// Generate OnEvent Callback based on the events created
-	if r.eh != nil {
-		for _, e := range events {
-			r.eh.OnEvent(e)
+	handlers = r.liveHandlers(events)
+	for _, e := range events {
+		for _, h := range handlers {
+			callHandler(h, e)
+		}
+	}
+	if len(events) > 0 {
+		for _, h := range handlers {
+			if flusher, ok := h.(eventFlusher); ok {
+				recoverHandler(h, flusher.flushEvents)
+			}
 		}
 	}
 	return nil
 }
+
+// holdEvents returns whether the events are held by any of the handlers while the configs are frozen, which reloads the source
+// by fetchFunc to fire them once unfrozen, see Manager.Freeze. The changes are counted by the formatted keys.
+func (r *refresher) holdEvents(handlers []EventHandler, name string, events []*Event) bool {
+	changes := len(lo.Uniq(lo.Map(events, func(e *Event, _ int) string { return formatKey(e.Key) })))
+	return lo.ContainsBy(handlers, func(h EventHandler) bool {
+		holder, ok := h.(eventHolder)
+		return ok && holder.holdEvents(name, changes, r.refresh)
+	})
+}
+
+type registeredHandler struct {
+	id      int64
+	handler EventHandler
+	// the handler waits for the initial load, or is replaying it with the events fired since queued, see replayInitialLoad
+	waiting   bool
+	replaying bool
+	queued    []*Event
+}
+
+// addEventHandler adds the handler of the events fired, returns the id to remove it.
+// The handlers added or removed while firing events take effect since the next firing.
+// The InitialLoadHandler replays the initial load at once if loaded, so it must not be added by the handlers.
+func (r *refresher) addEventHandler(eh EventHandler) int64 {
+	r.handlerMu.Lock()
+	r.nextHandlerID++
+	h := &registeredHandler{id: r.nextHandlerID, handler: eh}
+	r.handlers = append(r.handlers, h)
+	replay := false
+	if wantsInitialLoad(eh) {
+		h.waiting = true
+		replay = r.loaded
+	}
+	r.handlerMu.Unlock()
+	if replay {
+		r.replayInitialLoad(h)
+	}
+	return h.id
+}
+
+func (r *refresher) removeEventHandler(id int64) {
+	r.handlerMu.Lock()
+	defer r.handlerMu.Unlock()
+	r.handlers = lo.Filter(r.handlers, func(h *registeredHandler, _ int) bool { return h.id != id })
+}
+
+// setEventHandler replaces all the handlers with the handler except the kept ones, for the deprecated Source.SetEventHandler.
+func (r *refresher) setEventHandler(eh EventHandler, keepIDs ...int64) {
+	r.handlerMu.Lock()
+	r.handlers = lo.Filter(r.handlers, func(h *registeredHandler, _ int) bool { return lo.Contains(keepIDs, h.id) })
+	r.handlerMu.Unlock()
+	r.addEventHandler(eh)
+}
+
+func (r *refresher) eventHandlers() []EventHandler {
+	r.handlerMu.RLock()
+	defer r.handlerMu.RUnlock()
+	return lo.Map(r.handlers, func(h *registeredHandler, _ int) EventHandler { return h.handler })
+}
+
+// liveHandlers returns the handlers to call with the events, the ones waiting for the initial load are skipped,
+// since the events are included in the replay, and the events are queued for the ones replaying.
+func (r *refresher) liveHandlers(events []*Event) []EventHandler {
+	r.handlerMu.Lock()
+	defer r.handlerMu.Unlock()
+	handlers := make([]EventHandler, 0, len(r.handlers))
+	for _, h := range r.handlers {
+		switch {
+		case h.waiting:
+		case h.replaying:
+			h.queued = append(h.queued, events...)
+		default:
+			handlers = append(handlers, h.handler)
+		}
+	}
+	return handlers
+}
+
+// markLoaded is called by the source once the configurations of the first load are committed, without holding its lock,
+// which replays the initial load for the handlers waiting for it. It's idempotent, so no replays since then.
+func (r *refresher) markLoaded() {
+	r.handlerMu.Lock()
+	r.loaded = true
+	waiting := lo.Filter(r.handlers, func(h *registeredHandler, _ int) bool { return h.waiting })
+	r.handlerMu.Unlock()
+	for _, h := range waiting {
+		r.replayInitialLoad(h)
+	}
+}
+
+// replayInitialLoad calls the handler with the CreateType events of the current configurations in the order of keys,
+// then the events fired since the snapshot taken, which are queued meanwhile, so the handler gets all the changes in order.
+func (r *refresher) replayInitialLoad(h *registeredHandler) {
+	var events []*Event
+	r.snapshot(func(name string, configs map[string]string) {
+		r.handlerMu.Lock()
+		defer r.handlerMu.Unlock()
+		if !h.waiting {
+			return
+		}
+		h.waiting, h.replaying = false, true
+		keys := lo.Keys(configs)
+		sort.Strings(keys)
+		events = lo.Map(keys, func(key string, _ int) *Event { return newEvent(name, CreateType, key, configs[key]) })
+	})
+	if events == nil {
+		return
+	}
+	for {
+		for _, e := range events {
+			callHandler(h.handler, e)
+		}
+		if flusher, ok := h.handler.(eventFlusher); ok && len(events) > 0 {
+			recoverHandler(h.handler, flusher.flushEvents)
+		}
+		r.handlerMu.Lock()
+		if len(h.queued) == 0 {
+			h.replaying = false
+			r.handlerMu.Unlock()
+			return
+		}
+		events, h.queued = h.queued, nil
+		r.handlerMu.Unlock()
+	}
+}
+
+// callHandler calls the handler with the event, the panic of which is recovered and logged,
+// so a bad handler doesn't suppress the others.
+func callHandler(eh EventHandler, event *Event) {
+	recoverHandler(eh, func() { eh.OnEvent(event) }, zap.String("key", event.Key))
+}
+
+// recoverHandler calls fn of the handler, the panic of which is recovered and logged with the fields, returns whether it panicked.
+func recoverHandler(eh EventHandler, fn func(), fields ...zap.Field) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			log.Error("config event handler panicked", append([]zap.Field{zap.String("handler", eh.GetIdentifier()),
+				zap.Any("panic", r), zap.Stack("stack")}, fields...)...)
+		}
+	}()
+	fn()
+	return false
+}
+
+// recordChanges records the changes of the events by the handlers in the order of keys, the formatted duplicate keys are left out if their raw keys changed.
+func (r *refresher) recordChanges(handlers []EventHandler, source map[string]string, events []*Event) {
+	recorders := lo.Filter(handlers, func(h EventHandler, _ int) bool {
+		_, ok := h.(changeRecorder)
+		return ok
+	})
+	if len(recorders) == 0 || len(events) == 0 {
+		return
+	}
+	rawKeys := make(map[string]bool)
+	for _, e := range events {
+		if realKey := formatKey(e.Key); realKey != e.Key {
+			rawKeys[realKey] = true
+		}
+	}
+	now := time.Now()
+	records := make([]ChangeRecord, 0, len(events))
+	for _, e := range events {
+		if rawKeys[e.Key] {
+			continue
+		}
+		record := ChangeRecord{
+			Time:      now,
+			Source:    e.EventSource,
+			EventType: e.EventType,
+			Key:       e.Key,
+			OldValue:  source[e.Key],
+			NewValue:  lo.Ternary(e.EventType == DeleteType, "", e.Value),
+		}
+		if r.isSecret != nil && r.isSecret(e.Key) {
+			record.OldValue = lo.Ternary(record.OldValue == "", "", RedactedValue)
+			record.NewValue = lo.Ternary(record.NewValue == "", "", RedactedValue)
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key < records[j].Key
+	})
+	for _, recorder := range recorders {
+		recoverHandler(recorder, func() { recorder.(changeRecorder).recordChanges(records) })
+	}
+}
+
+// recordUpdates records the update time of the keys changed by the events.
+func (r *refresher) recordUpdates(events []*Event) {
+	now := time.Now()
+	r.timeMu.Lock()
+	defer r.timeMu.Unlock()
+	for _, e := range events {
+		if e.EventType == DeleteType {
+			delete(r.updateTimes, e.Key)
+			delete(r.updateTimes, formatKey(e.Key))
+			continue
+		}
+		r.updateTimes[e.Key] = now
+	}
+}
+
+// touch records the keys loaded without firing events as updated now.
+func (r *refresher) touch(keys ...string) {
+	now := time.Now()
+	r.timeMu.Lock()
+	defer r.timeMu.Unlock()
+	for _, key := range keys {
+		r.updateTimes[key] = now
+	}
+}
+
+func (r *refresher) updateTime(key string) (time.Time, bool) {
+	r.timeMu.RLock()
+	defer r.timeMu.RUnlock()
+	updated, ok := r.updateTimes[key]
+	return updated, ok
+}
+
+// validateEvents drops the events rejected by any of the handlers, and keeps the old values of them in target.
+// Each logical key is validated once, i.e. the formatted duplicate key follows its raw key.
+func (r *refresher) validateEvents(handlers []EventHandler, source, target map[string]string, events []*Event) []*Event {
+	validators := lo.Filter(handlers, func(h EventHandler, _ int) bool {
+		_, ok := h.(eventValidator)
+		return ok
+	})
+	if len(validators) == 0 {
+		return events
+	}
+	// validate the raw keys first
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Key != formatKey(events[i].Key) && events[j].Key == formatKey(events[j].Key)
+	})
+	rejected := make(map[string]bool)
+	validated := make([]*Event, 0, len(events))
+	for _, e := range events {
+		// the deleted keys fall back to other sources
+		if e.EventType == DeleteType {
+			validated = append(validated, e)
+			continue
+		}
+		realKey := formatKey(e.Key)
+		reject, ok := rejected[realKey]
+		if !ok {
+			// the event is rejected if the validator panics
+			reject = lo.ContainsBy(validators, func(validator EventHandler) bool {
+				var err error
+				panicked := recoverHandler(validator, func() { err = validator.(eventValidator).Validate(e) }, zap.String("key", e.Key))
+				return panicked || err != nil
+			})
+			rejected[realKey] = reject
+		}
+		if !reject {
+			validated = append(validated, e)
+			continue
+		}
+		if oldValue, ok := source[e.Key]; ok {
+			target[e.Key] = oldValue
+		} else {
+			delete(target, e.Key)
+		}
+	}
+	return validated
+}
+
+// observeRefresh records the latency and the failure of refreshing the configurations of the source since start.
+func observeRefresh(name string, start time.Time, err error) {
+	metrics.ConfigRefreshLatency.WithLabelValues(name).Observe(float64(time.Since(start).Microseconds()) / 1000)
+	if err != nil {
+		metrics.ConfigRefreshFailCounter.WithLabelValues(name).Inc()
+	}
+	if errors.Is(err, ErrEtcdAuth) {
+		metrics.ConfigRefreshAuthFailCounter.WithLabelValues(name).Inc()
+	}
+}
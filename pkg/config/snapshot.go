@@ -0,0 +1,269 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/pkg/log"
+)
+
+const (
+	DefaultSnapshotMaxCount = 10
+	DefaultSnapshotMaxAge   = time.Hour
+
+	// OverlaySourceName is the origin of the configs set at runtime, see Manager.SetConfig
+	OverlaySourceName = "Overlay"
+	// RollbackSourceName is the in-memory source overriding the others, when the configs can't be rolled back by writing etcd
+	RollbackSourceName = "RollbackSource"
+)
+
+var ErrSnapshotNotFound = errors.New("config snapshot not found")
+
+// SnapshotEntry is the effective config of a key, and the source it's from.
+type SnapshotEntry struct {
+	// the key in the source, rather than the formatted one unless it's formatted in the source
+	Key    string
+	Value  string
+	Source string
+}
+
+// ConfigSnapshot is the effective configs at a time, keyed by the formatted keys.
+type ConfigSnapshot struct {
+	ID      int64
+	Time    time.Time
+	Configs map[string]SnapshotEntry
+}
+
+// snapshotRing retains the latest snapshots, at most maxCount of them and no older than maxAge.
+type snapshotRing struct {
+	mu        sync.Mutex
+	snapshots []ConfigSnapshot
+	lastID    int64
+	maxCount  int
+	maxAge    time.Duration
+}
+
+func newSnapshotRing() *snapshotRing {
+	return &snapshotRing{
+		maxCount: DefaultSnapshotMaxCount,
+		maxAge:   DefaultSnapshotMaxAge,
+	}
+}
+
+func (r *snapshotRing) add(configs map[string]SnapshotEntry) ConfigSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID++
+	snapshot := ConfigSnapshot{ID: r.lastID, Time: time.Now(), Configs: configs}
+	r.snapshots = append(r.snapshots, snapshot)
+	r.pruneLocked()
+	return snapshot
+}
+
+func (r *snapshotRing) get(id int64) (ConfigSnapshot, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	for _, snapshot := range r.snapshots {
+		if snapshot.ID == id {
+			return snapshot, true
+		}
+	}
+	return ConfigSnapshot{}, false
+}
+
+func (r *snapshotRing) list() []ConfigSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneLocked()
+	return append([]ConfigSnapshot(nil), r.snapshots...)
+}
+
+func (r *snapshotRing) setRetention(maxCount int, maxAge time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxCount = maxCount
+	r.maxAge = maxAge
+	r.pruneLocked()
+}
+
+func (r *snapshotRing) pruneLocked() {
+	expired := time.Now().Add(-r.maxAge)
+	start := sort.Search(len(r.snapshots), func(i int) bool {
+		return r.snapshots[i].Time.After(expired)
+	})
+	if len(r.snapshots)-start > r.maxCount {
+		start = len(r.snapshots) - r.maxCount
+	}
+	r.snapshots = r.snapshots[start:]
+}
+
+// SetSnapshotRetention sets how many snapshots and how long they are retained.
+func (m *Manager) SetSnapshotRetention(maxCount int, maxAge time.Duration) {
+	m.snapshots.setRetention(maxCount, maxAge)
+}
+
+// Snapshot captures the effective configs, which is retained to roll back to, see Rollback.
+func (m *Manager) Snapshot() ConfigSnapshot {
+	snapshot := m.snapshots.add(m.effectiveConfigs())
+	log.Info("config snapshot taken", zap.Int64("id", snapshot.ID), zap.Int("keys", len(snapshot.Configs)))
+	return snapshot
+}
+
+// Snapshots returns the snapshots retained, from the oldest to the latest.
+func (m *Manager) Snapshots() []ConfigSnapshot {
+	return m.snapshots.list()
+}
+
+// effectiveConfigs returns the config of each formatted key as GetConfig.
+func (m *Manager) effectiveConfigs() map[string]SnapshotEntry {
+	configs := make(map[string]SnapshotEntry)
+	m.keySourceMap.Range(func(key, sourceName string) bool {
+		realKey := formatKey(key)
+		// the value is from the source of the formatted key, see GetConfig
+		owner, ok := m.keySourceMap.Get(realKey)
+		if !ok || owner != sourceName {
+			return true
+		}
+		if entry, ok := configs[realKey]; ok && entry.Key != realKey {
+			// the raw key is recorded already
+			return true
+		}
+		value, err := m.getConfigValueBySource(realKey, owner)
+		if err != nil {
+			return true
+		}
+		configs[realKey] = SnapshotEntry{Key: key, Value: value, Source: owner}
+		return true
+	})
+	m.overlays.Range(func(key, value string) bool {
+		if value == TombValue {
+			delete(configs, key)
+		} else {
+			configs[key] = SnapshotEntry{Key: key, Value: value, Source: OverlaySourceName}
+		}
+		return true
+	})
+	return configs
+}
+
+// Rollback re-applies the configs of the snapshot, by writing the differing keys to the etcd source.
+// If the etcd source is not writable, the configs are overridden by an in-memory source with the highest priority,
+// which can't remove the keys added since the snapshot. Returns error with the keys not rolled back if any.
+func (m *Manager) Rollback(snapshotID int64) error {
+	snapshot, ok := m.snapshots.get(snapshotID)
+	if !ok {
+		return errors.Wrapf(ErrSnapshotNotFound, "id: %d", snapshotID)
+	}
+	log := log.With(zap.Int64("snapshotID", snapshotID))
+
+	es := m.writableEtcdSource()
+	current := m.effectiveConfigs()
+	overrides := make(map[string]string)
+	for realKey, entry := range snapshot.Configs {
+		cur, ok := current[realKey]
+		if ok && cur.Value == entry.Value {
+			continue
+		}
+		if es != nil {
+			err := m.rollbackToEtcd(es, realKey, entry, cur, ok)
+			if err == nil {
+				continue
+			}
+			log.Warn("failed to roll back config by etcd, override it in memory", zap.String("key", entry.Key), zap.Error(err))
+		}
+		overrides[entry.Key] = entry.Value
+	}
+	for realKey, cur := range current {
+		if _, ok := snapshot.Configs[realKey]; ok || es == nil || cur.Source != es.GetSourceName() {
+			continue
+		}
+		if err := es.DeleteConfig(cur.Key); err != nil {
+			log.Warn("failed to roll back config added since the snapshot", zap.String("key", cur.Key), zap.Error(err))
+		}
+	}
+	if len(overrides) > 0 {
+		if err := m.overrideInMemory(overrides); err != nil {
+			return err
+		}
+	}
+
+	// verify the effective configs
+	current = m.effectiveConfigs()
+	var failedKeys []string
+	for realKey, entry := range snapshot.Configs {
+		if cur, ok := current[realKey]; !ok || cur.Value != entry.Value {
+			failedKeys = append(failedKeys, entry.Key)
+		}
+	}
+	for realKey, cur := range current {
+		if _, ok := snapshot.Configs[realKey]; !ok {
+			failedKeys = append(failedKeys, cur.Key)
+		}
+	}
+	if len(failedKeys) > 0 {
+		sort.Strings(failedKeys)
+		return fmt.Errorf("failed to roll back configs of keys %v to snapshot %d", failedKeys, snapshotID)
+	}
+	log.Info("configs rolled back")
+	return nil
+}
+
+// rollbackToEtcd rolls back the config of the key to entry by writing etcd, deletes the one in etcd if entry is from other sources.
+func (m *Manager) rollbackToEtcd(es *EtcdSource, realKey string, entry, cur SnapshotEntry, exist bool) error {
+	if exist && cur.Source == es.GetSourceName() && (entry.Source != es.GetSourceName() || cur.Key != entry.Key) {
+		if err := es.DeleteConfig(cur.Key); err != nil {
+			return err
+		}
+		if value, err := m.GetConfig(realKey); err == nil && value == entry.Value {
+			return nil
+		}
+	}
+	return es.SetConfig(entry.Key, entry.Value)
+}
+
+func (m *Manager) writableEtcdSource() *EtcdSource {
+	var es *EtcdSource
+	m.sources.Range(func(_ string, source Source) bool {
+		if s, ok := source.(*EtcdSource); ok && !s.readOnly {
+			es = s
+			return false
+		}
+		return true
+	})
+	return es
+}
+
+// overrideInMemory overrides the configs by the rollback source, which fires the events.
+func (m *Manager) overrideInMemory(configs map[string]string) error {
+	source, ok := m.sources.Get(RollbackSourceName)
+	if !ok {
+		source = NewMemorySource(RollbackSourceName, HighPriority-1)
+		if err := m.AddSource(source); err != nil {
+			return err
+		}
+	}
+	source.(*MemorySource).SetConfigs(configs)
+	return nil
+}
@@ -0,0 +1,179 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.etcd.io/etcd/server/v3/etcdserver/api/v3client"
+)
+
+func TestSnapshotRetention(t *testing.T) {
+	mgr, _ := Init()
+	defer mgr.Close()
+
+	mgr.SetSnapshotRetention(2, time.Hour)
+	first := mgr.Snapshot()
+	second := mgr.Snapshot()
+	third := mgr.Snapshot()
+	assert.Less(t, first.ID, second.ID)
+	snapshots := mgr.Snapshots()
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, second.ID, snapshots[0].ID)
+	assert.Equal(t, third.ID, snapshots[1].ID)
+	assert.True(t, errors.Is(mgr.Rollback(first.ID), ErrSnapshotNotFound))
+
+	mgr.SetSnapshotRetention(2, time.Nanosecond)
+	assert.Empty(t, mgr.Snapshots())
+	assert.True(t, errors.Is(mgr.Rollback(third.ID), ErrSnapshotNotFound))
+}
+
+func TestSnapshotRollback(t *testing.T) {
+	cfg, _ := embed.ConfigFromFile("../../configs/advanced/etcd.yaml")
+	cfg.Dir = t.TempDir()
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	defer e.Close()
+
+	client := v3client.New(e.Server)
+	ctx := context.Background()
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\nc.d: 2\n"), 0o600))
+
+	newManager := func(keyPrefix string, readOnly bool) *Manager {
+		_, err := client.Put(ctx, keyPrefix+"/config/a/b", "10")
+		require.NoError(t, err)
+		_, err = client.Put(ctx, keyPrefix+"/config/x/y", "1")
+		require.NoError(t, err)
+		mgr, err := Init(
+			WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}),
+			WithEtcdSource(&EtcdInfo{
+				Endpoints: []string{cfg.ACUrls[0].Host},
+				KeyPrefix: keyPrefix,
+				ReadOnly:  readOnly,
+				// changes are refreshed manually
+				RefreshInterval: time.Hour,
+			}))
+		require.NoError(t, err)
+		return mgr
+	}
+	mutate := func(mgr *Manager, keyPrefix string) {
+		_, err := client.Put(ctx, keyPrefix+"/config/x/y", "2")
+		require.NoError(t, err)
+		_, err = client.Delete(ctx, keyPrefix+"/config/a/b")
+		require.NoError(t, err)
+		_, err = client.Put(ctx, keyPrefix+"/config/e/f", "3")
+		require.NoError(t, err)
+		source, ok := mgr.sources.Get("EtcdSource[" + keyPrefix + "]")
+		require.True(t, ok)
+		require.NoError(t, source.(*EtcdSource).refreshConfigurations())
+
+		for key, expected := range map[string]string{"a.b": "1", "x.y": "2", "e.f": "3"} {
+			value, err := mgr.GetConfig(key)
+			assert.NoError(t, err, key)
+			assert.Equal(t, expected, value, key)
+		}
+	}
+	recordEvents := func(mgr *Manager) func() []string {
+		var mu sync.Mutex
+		keys := make(map[string]struct{})
+		mgr.Dispatcher.RegisterForKeyPrefix("", NewHandler("rollback", func(event *Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			keys[formatKey(event.Key)] = struct{}{}
+		}))
+		return func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			var result []string
+			for key := range keys {
+				result = append(result, key)
+			}
+			return result
+		}
+	}
+	assertRestored := func(t *testing.T, mgr *Manager, snapshot ConfigSnapshot) {
+		for key, expected := range map[string]string{"a.b": "10", "c.d": "2", "x.y": "1"} {
+			value, err := mgr.GetConfig(key)
+			assert.NoError(t, err, key)
+			assert.Equal(t, expected, value, key)
+			assert.Equal(t, expected, snapshot.Configs[formatKey(key)].Value, key)
+		}
+	}
+
+	t.Run("write etcd", func(t *testing.T) {
+		mgr := newManager("test_rollback", false)
+		defer mgr.Close()
+		snapshot := mgr.Snapshot()
+		assert.Equal(t, SnapshotEntry{Key: "a/b", Value: "10", Source: "EtcdSource[test_rollback]"}, snapshot.Configs["ab"])
+		assert.Equal(t, SnapshotEntry{Key: "c.d", Value: "2", Source: "FileSource"}, snapshot.Configs["cd"])
+		_, ok := snapshot.Configs["ef"]
+		assert.False(t, ok)
+
+		mutate(mgr, "test_rollback")
+		eventKeys := recordEvents(mgr)
+		require.NoError(t, mgr.Rollback(snapshot.ID))
+		assertRestored(t, mgr, snapshot)
+		_, err := mgr.GetConfig("e.f")
+		assert.Error(t, err)
+		assert.ElementsMatch(t, []string{"ab", "xy", "ef"}, eventKeys())
+
+		// the configs are written back to etcd
+		resp, err := client.Get(ctx, "test_rollback/config/", clientv3.WithPrefix())
+		require.NoError(t, err)
+		configs := make(map[string]string)
+		for _, kv := range resp.Kvs {
+			configs[string(kv.Key)] = string(kv.Value)
+		}
+		assert.Equal(t, map[string]string{
+			"test_rollback/config/a/b": "10",
+			"test_rollback/config/x/y": "1",
+		}, configs)
+		_, ok = mgr.sources.Get(RollbackSourceName)
+		assert.False(t, ok)
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		mgr := newManager("test_rollback_read_only", true)
+		defer mgr.Close()
+		snapshot := mgr.Snapshot()
+
+		mutate(mgr, "test_rollback_read_only")
+		eventKeys := recordEvents(mgr)
+		// the key added can't be removed by the in-memory source
+		err := mgr.Rollback(snapshot.ID)
+		assert.ErrorContains(t, err, "e/f")
+		assertRestored(t, mgr, snapshot)
+		assert.ElementsMatch(t, []string{"ab", "xy"}, eventKeys())
+
+		resp, err := client.Get(ctx, "test_rollback_read_only/config/x/y")
+		require.NoError(t, err)
+		assert.Equal(t, "2", string(resp.Kvs[0].Value))
+		current := mgr.effectiveConfigs()
+		assert.Equal(t, RollbackSourceName, current["xy"].Source)
+	})
+}
@@ -15,7 +15,10 @@
 // limitations under the License.
 package config
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 const (
 	HighPriority   = 1
@@ -26,15 +29,74 @@ const (
 type Source interface {
 	GetConfigurations() (map[string]string, error)
 	GetConfigurationByKey(string) (string, error)
+	// GetConfigurationsByKeyPrefix returns the configurations whose keys start with the prefix,
+	// the keys are compared formatted, so both the raw keys and the formatted ones are matched
+	GetConfigurationsByKeyPrefix(prefix string) (map[string]string, error)
 	GetPriority() int
 	GetSourceName() string
+	// AddEventHandler adds the handler of the changes, all the handlers added are called with each change,
+	// returns the id to remove it by RemoveEventHandler
+	AddEventHandler(eh EventHandler) int64
+	RemoveEventHandler(id int64)
+	// Deprecated: SetEventHandler replaces all the handlers with the handler, use AddEventHandler instead
 	SetEventHandler(eh EventHandler)
 	UpdateOptions(opt Options)
+	// Health reports whether the source is synced with where the configurations come from, see SourceHealth
+	Health() SourceHealth
 	Close()
 }
 
+// SourceHealth is the health of a source, the last good configurations are served while it's failing.
+type SourceHealth struct {
+	// when the configurations are loaded successfully last time, zero if never
+	LastSuccessTime time.Time
+	// error of the last load, nil if it succeeded or never tried, or of reloading the credentials while the old ones are still used
+	LastError error
+	// number of configurations held, including the formatted duplicates of the keys
+	KeyCount int
+	// whether the last load succeeded
+	Connected bool
+	// why the configurations are loaded in a degraded way, e.g. DegradedSecondary, empty if not
+	Degraded string
+	// number of the configurations changed but held while frozen, see Manager.Freeze
+	PendingChanges int
+}
+
+// Stale returns whether the source has failed to load the configurations for longer than tolerance,
+// or never loaded them, the ones not failing are never stale.
+func (h SourceHealth) Stale(tolerance time.Duration) bool {
+	if h.Connected {
+		return false
+	}
+	return h.LastSuccessTime.IsZero() || time.Since(h.LastSuccessTime) > tolerance
+}
+
+// sourceName returns the name of the source of the kind, "<kind>[<name>]" to tell the sources of the same kind apart,
+// or the kind if the name is empty. The sources of a Manager must be named uniquely, see Manager.AddSource.
+func sourceName(kind, name string) string {
+	if name == "" {
+		return kind
+	}
+	return kind + "[" + name + "]"
+}
+
+// UpdateTimer is implemented by the sources tracking when each configuration is updated, see Manager.Describe
+type UpdateTimer interface {
+	GetUpdateTime(key string) (time.Time, bool)
+}
+
+// ForceRefresher is implemented by the sources loading the configurations from remote periodically, which could be refreshed
+// at once rather than waiting for the next time, see Manager.ForceRefresh. The other sources are always up to date or
+// refreshed only periodically, for which forcing it is a no-op.
+type ForceRefresher interface {
+	ForceRefresh(ctx context.Context) error
+}
+
 // EtcdInfo has attribute for config center source initialization
 type EtcdInfo struct {
+	// Name of the source to tell it from the other etcd sources, e.g. of other clusters, KeyPrefix if not set,
+	// see EtcdSource.GetSourceName, which is fixed once created
+	Name       string
 	UseEmbed   bool
 	UseSSL     bool
 	Endpoints  []string
@@ -43,21 +105,112 @@ type EtcdInfo struct {
 	KeyFile    string
 	CaCertFile string
 	MinVersion string
+	// Authenticate by the user name and password, which could be rotated by EtcdSource.UpdateOptions
+	EnableAuth bool
+	Username   string
+	Password   string
+	// Timeouts of the connection and of reading the configurations, DefaultEtcdDialTimeout and so on if not set,
+	// which could be updated by EtcdSource.UpdateOptions
+	DialTimeout      time.Duration
+	KeepAliveTime    time.Duration
+	KeepAliveTimeout time.Duration
+	RequestTimeout   time.Duration
+	// Max keys read by each request, DefaultEtcdPageSize if not set, or no limit if negative
+	PageSize int64
+	// Decrypt the values with EncryptedPrefix, DefaultEncryptedPrefix if not set,
+	// the ones failed to decrypt are left out rather than exposing the ciphertext
+	Decryptor       Decryptor
+	EncryptedPrefix string
+	// The values of the keys with the suffixes are YAML or JSON documents by the extensions, DefaultBlobSuffixes if empty,
+	// which are flattened into dot separated keys, and the keys set explicitly win over them
+	BlobSuffixes []string
+	// The values written by EtcdSource.SetConfig larger than the bytes are compressed with CompressedPrefix,
+	// DefaultCompressThreshold if not set, or no compression if negative. The compressed values are always decompressed
+	CompressThreshold int
+
+	// Paths of configurations under KeyPrefix in precedence order, the later ones override the earlier ones,
+	// e.g. ["config", "config-proxy"] for the cluster-wide ones and the role-specific ones, ["config"] if empty
+	ConfigPaths []string
 
 	// Pull Configuration interval, unit is second
 	RefreshInterval time.Duration
+	// Jitter of RefreshInterval as a fraction of it, so the nodes sharing the interval don't refresh at the same time,
+	// DefaultRefreshJitter if not set, or no jitter if negative. The configurations are stale for RefreshInterval*(1+jitter) at most
+	RefreshJitter float64
+	// Reject the writes of configurations, see EtcdSource.SetConfig
+	ReadOnly bool
+	// HighPriority if not set, which could be overridden at runtime by Manager.SetSourcePriority
+	Priority int
+	// Watch the changes of configurations instead of pulling them every RefreshInterval,
+	// pulling is still the fallback while the watch is broken
+	Watch bool
+
+	// Endpoints of the standby etcd cluster, which the source fails over to after FailoverThreshold consecutive failures of refreshing,
+	// DefaultEtcdFailoverThreshold if not set, and fails back from once Endpoints pass the probe every ProbeInterval,
+	// DefaultEtcdProbeInterval if not set. The configurations are reloaded in full on failing over and back
+	SecondaryEndpoints []string
+	FailoverThreshold  int
+	ProbeInterval      time.Duration
 }
 
 // FileInfo has attribute for file source
 type FileInfo struct {
+	// Name of the source to tell it from the other file sources, see sourceName
+	Name            string
 	Files           []string
 	RefreshInterval time.Duration
+	// Watch the files to reload them as soon as they change, in addition to reloading every RefreshInterval
+	Watch bool
+}
+
+// EnvInfo has attribute for env source
+type EnvInfo struct {
+	// Name of the source to tell it from the other env sources, see sourceName
+	Name         string
+	KeyFormatter func(string) string
+	// Only load the env vars with the prefix if set, the prefix is trimmed before formatting,
+	// e.g. MILVUS_PROXY_MAXNAMELENGTH for proxy.maxNameLength with prefix MILVUS_
+	KeyPrefix string
+	// NormalPriority if not set, which is between the file source and the etcd source
+	Priority int
+}
+
+// DirInfo has attribute for directory source, see DirSource
+type DirInfo struct {
+	// Name of the source to tell it from the other directory sources, see sourceName
+	Name string
+	Dir  string
+	// Flatten the files of the subdirectories into the keys joined by the separator, e.g. "." for proxy/maxNameLength
+	// as proxy.maxNameLength, or the subdirectories are ignored if empty
+	Separator       string
+	RefreshInterval time.Duration
+	// Watch the directory to reload it as soon as it changes, in addition to reloading every RefreshInterval
+	Watch bool
+	// Right above the file source if not set
+	Priority int
+}
+
+// HTTPInfo has attribute for http source
+type HTTPInfo struct {
+	// Name of the source to tell it from the other http sources, see sourceName
+	Name string
+	// the JSON or properties document of configurations
+	URL        string
+	UseSSL     bool
+	CertFile   string
+	KeyFile    string
+	CaCertFile string
+	MinVersion string
+
+	RefreshInterval time.Duration
 }
 
 // Options hold options
 type Options struct {
 	FileInfo        *FileInfo
 	EtcdInfo        *EtcdInfo
+	HTTPInfo        *HTTPInfo
+	DirInfo         *DirInfo
 	EnvKeyFormatter func(string) string
 }
 
@@ -78,6 +231,20 @@ func WithEtcdSource(ri *EtcdInfo) Option {
 	}
 }
 
+// WithHTTPSource accept the information for initiating a http source
+func WithHTTPSource(hi *HTTPInfo) Option {
+	return func(options *Options) {
+		options.HTTPInfo = hi
+	}
+}
+
+// WithDirSource accept the information for initiating a directory source
+func WithDirSource(di *DirInfo) Option {
+	return func(options *Options) {
+		options.DirInfo = di
+	}
+}
+
 // WithEnvSource enable env source
 // archaius will read ENV as key value
 func WithEnvSource(keyFormatter func(string) string) Option {
@@ -109,3 +276,60 @@ func (s *simpleHandler) OnEvent(event *Event) {
 func NewHandler(ident string, onEvent func(*Event)) EventHandler {
 	return &simpleHandler{ident, onEvent}
 }
+
+// BatchEventHandler handles the events in batches rather than one by one, the events are coalesced until
+// no more events in the quiet period or the refreshing fired them completes, see EventDispatcher.SetQuietPeriod.
+type BatchEventHandler interface {
+	EventHandler
+	OnBatch(events []*Event)
+}
+
+type batchHandler struct {
+	identity string
+	onBatch  func([]*Event)
+}
+
+func (b *batchHandler) GetIdentifier() string {
+	return b.identity
+}
+
+// OnEvent implements EventHandler
+func (b *batchHandler) OnEvent(event *Event) {
+	b.onBatch([]*Event{event})
+}
+
+// OnBatch implements BatchEventHandler
+func (b *batchHandler) OnBatch(events []*Event) {
+	b.onBatch(events)
+}
+
+func NewBatchHandler(ident string, onBatch func([]*Event)) BatchEventHandler {
+	return &batchHandler{ident, onBatch}
+}
+
+// InitialLoadHandler is implemented by the handlers driving their entire state from the events, which get the CreateType events
+// of all the configurations loaded by the source first, after they're committed rather than while loading them,
+// or at once if added after the first load, then the changes since then in order. The initial load is replayed once per handler.
+type InitialLoadHandler interface {
+	EventHandler
+	WantsInitialLoad() bool
+}
+
+type initialLoadHandler struct {
+	EventHandler
+}
+
+// WantsInitialLoad implements InitialLoadHandler
+func (initialLoadHandler) WantsInitialLoad() bool {
+	return true
+}
+
+// WithInitialLoad makes the handler get the events of the initial load, see InitialLoadHandler.
+func WithInitialLoad(eh EventHandler) InitialLoadHandler {
+	return initialLoadHandler{eh}
+}
+
+func wantsInitialLoad(eh EventHandler) bool {
+	h, ok := eh.(InitialLoadHandler)
+	return ok && h.WantsInitialLoad()
+}
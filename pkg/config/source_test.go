@@ -19,20 +19,25 @@ package config
 import (
 	"os"
 	"path"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 )
 
 func TestLoadFromFileSource(t *testing.T) {
 	t.Run("file not exist", func(t *testing.T) {
-		fs := NewFileSource(&FileInfo{[]string{"file_not_exist.yaml"}, -1})
+		fs := NewFileSource(&FileInfo{Files: []string{"file_not_exist.yaml"}, RefreshInterval: -1})
 		_ = fs.loadFromFile()
 		assert.Zero(t, len(fs.configs))
 	})
 
 	t.Run("file type not support", func(t *testing.T) {
-		fs := NewFileSource(&FileInfo{[]string{"../../go.mod"}, -1})
+		fs := NewFileSource(&FileInfo{Files: []string{"../../go.mod"}, RefreshInterval: -1})
 		err := fs.loadFromFile()
 		assert.Error(t, err)
 	})
@@ -42,7 +47,7 @@ func TestLoadFromFileSource(t *testing.T) {
 		os.WriteFile(path.Join(dir, "milvus.yaml"), []byte("a.b: 1\nc.d: 2"), 0o600)
 		os.WriteFile(path.Join(dir, "user.yaml"), []byte("a.b: 3"), 0o600)
 
-		fs := NewFileSource(&FileInfo{[]string{path.Join(dir, "milvus.yaml"), path.Join(dir, "user.yaml")}, -1})
+		fs := NewFileSource(&FileInfo{Files: []string{path.Join(dir, "milvus.yaml"), path.Join(dir, "user.yaml")}, RefreshInterval: -1})
 		fs.loadFromFile()
 		v1, _ := fs.GetConfigurationByKey("a.b")
 		assert.Equal(t, "3", v1)
@@ -50,3 +55,439 @@ func TestLoadFromFileSource(t *testing.T) {
 		assert.Equal(t, "2", v2)
 	})
 }
+
+func TestFileSourceWatch(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, "milvus.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("a.b: 1\nc.d: 2"), 0o600))
+
+	// changes are only reloaded by watch
+	fs := NewFileSource(&FileInfo{Files: []string{file}, RefreshInterval: -1, Watch: true})
+	var mu sync.Mutex
+	var events []*Event
+	fs.SetEventHandler(NewHandler("test", func(event *Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	_, err := fs.GetConfigurations()
+	assert.NoError(t, err)
+	defer fs.Close()
+
+	assertConfig := func(key, expected string) {
+		assert.Eventually(t, func() bool {
+			value, err := fs.GetConfigurationByKey(key)
+			if expected == "" {
+				return err != nil
+			}
+			return err == nil && value == expected
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("in-place edit", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(file, []byte("a.b: 3"), 0o600))
+		assertConfig("a.b", "3")
+		assertConfig("c.d", "")
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, events, newEvent(fs.GetSourceName(), UpdateType, "a.b", "3"))
+		assert.Contains(t, events, newEvent(fs.GetSourceName(), DeleteType, "c.d", "2"))
+	})
+
+	t.Run("parse failure", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(file, []byte("a.b: [4"), 0o600))
+		// the last good configurations are kept
+		time.Sleep(100 * time.Millisecond)
+		v, err := fs.GetConfigurationByKey("a.b")
+		assert.NoError(t, err)
+		assert.Equal(t, "3", v)
+
+		assert.NoError(t, os.WriteFile(file, []byte("a.b: 4"), 0o600))
+		assertConfig("a.b", "4")
+	})
+
+	t.Run("symlink swap", func(t *testing.T) {
+		// the layout of ConfigMap mounted in Kubernetes:
+		// milvus.yaml -> ..data/milvus.yaml, ..data -> ..v1
+		dir := t.TempDir()
+		for _, version := range []string{"..v1", "..v2"} {
+			assert.NoError(t, os.Mkdir(path.Join(dir, version), 0o700))
+		}
+		assert.NoError(t, os.WriteFile(path.Join(dir, "..v1", "milvus.yaml"), []byte("e.f: 5"), 0o600))
+		assert.NoError(t, os.Symlink("..v1", path.Join(dir, "..data")))
+		file := path.Join(dir, "milvus.yaml")
+		assert.NoError(t, os.Symlink(path.Join("..data", "milvus.yaml"), file))
+
+		fs.UpdateOptions(Options{FileInfo: &FileInfo{Files: []string{file}, RefreshInterval: -1, Watch: true}})
+		assert.NoError(t, fs.loadFromFile())
+		assertConfig("e.f", "5")
+
+		// the symlink is swapped atomically by rename
+		assert.NoError(t, os.WriteFile(path.Join(dir, "..v2", "milvus.yaml"), []byte("e.f: 6"), 0o600))
+		assert.NoError(t, os.Symlink("..v2", path.Join(dir, "..data_tmp")))
+		assert.NoError(t, os.Rename(path.Join(dir, "..data_tmp"), path.Join(dir, "..data")))
+		assertConfig("e.f", "6")
+	})
+}
+
+func TestDirSource(t *testing.T) {
+	t.Run("files", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(path.Join(dir, "proxy.maxNameLength"), []byte("10\n"), 0o600))
+		assert.NoError(t, os.WriteFile(path.Join(dir, ".hidden"), []byte("1"), 0o600))
+		assert.NoError(t, os.Mkdir(path.Join(dir, "common"), 0o700))
+		assert.NoError(t, os.WriteFile(path.Join(dir, "common", "retentionDuration"), []byte("3600"), 0o600))
+
+		ds := NewDirSource(&DirInfo{Dir: dir, RefreshInterval: -1})
+		configs, err := ds.GetConfigurations()
+		assert.NoError(t, err)
+		defer ds.Close()
+		assert.Equal(t, map[string]string{"proxy.maxNameLength": "10", "proxymaxnamelength": "10"}, configs)
+		assert.Equal(t, LowPriority-1, ds.GetPriority())
+		assert.True(t, ds.Health().Connected)
+
+		// the subdirectories flattened
+		ds.UpdateOptions(Options{DirInfo: &DirInfo{Dir: dir, Separator: ".", RefreshInterval: -1}})
+		assert.NoError(t, ds.loadFromDir())
+		v, err := ds.GetConfigurationByKey("commonretentionduration")
+		assert.NoError(t, err)
+		assert.Equal(t, "3600", v)
+		v, err = ds.GetConfigurationByKey("common.retentionDuration")
+		assert.NoError(t, err)
+		assert.Equal(t, "3600", v)
+
+		// the last good configurations are kept
+		ds.UpdateOptions(Options{DirInfo: &DirInfo{Dir: path.Join(dir, "not_exist"), RefreshInterval: -1}})
+		assert.Error(t, ds.loadFromDir())
+		assert.False(t, ds.Health().Connected)
+		v, err = ds.GetConfigurationByKey("proxy.maxNameLength")
+		assert.NoError(t, err)
+		assert.Equal(t, "10", v)
+	})
+
+	t.Run("configmap update", func(t *testing.T) {
+		// the layout of ConfigMap mounted in Kubernetes:
+		// a.b -> ..data/a.b, ..data -> ..v1, ..v1/a.b
+		dir := t.TempDir()
+		writeVersion := func(version string, configs map[string]string) {
+			assert.NoError(t, os.Mkdir(path.Join(dir, version), 0o700))
+			for key, value := range configs {
+				assert.NoError(t, os.WriteFile(path.Join(dir, version, key), []byte(value), 0o600))
+			}
+		}
+		writeVersion("..v1", map[string]string{"a.b": "1", "c.d": "2"})
+		assert.NoError(t, os.Symlink("..v1", path.Join(dir, DirDataLink)))
+		for _, key := range []string{"a.b", "c.d"} {
+			assert.NoError(t, os.Symlink(path.Join(DirDataLink, key), path.Join(dir, key)))
+		}
+
+		// changes are only reloaded by watch
+		ds := NewDirSource(&DirInfo{Dir: dir, RefreshInterval: -1, Watch: true})
+		var mu sync.Mutex
+		var events []*Event
+		ds.SetEventHandler(NewHandler("test", func(event *Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		}))
+		configs, err := ds.GetConfigurations()
+		assert.NoError(t, err)
+		defer ds.Close()
+		assert.Equal(t, map[string]string{"a.b": "1", "ab": "1", "c.d": "2", "cd": "2"}, configs)
+
+		// the data is swapped atomically by rename, then the links of the keys added and removed are updated
+		writeVersion("..v2", map[string]string{"a.b": "3", "e.f": "4"})
+		assert.NoError(t, os.Symlink("..v2", path.Join(dir, "..data_tmp")))
+		assert.NoError(t, os.Rename(path.Join(dir, "..data_tmp"), path.Join(dir, DirDataLink)))
+		assert.NoError(t, os.Symlink(path.Join(DirDataLink, "e.f"), path.Join(dir, "e.f")))
+		assert.NoError(t, os.Remove(path.Join(dir, "c.d")))
+		assert.NoError(t, os.RemoveAll(path.Join(dir, "..v1")))
+
+		assert.Eventually(t, func() bool {
+			configs, err := ds.GetConfigurationsByKeyPrefix("")
+			return err == nil && assert.ObjectsAreEqual(map[string]string{"a.b": "3", "ab": "3", "e.f": "4", "ef": "4"}, configs)
+		}, time.Second, 10*time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Contains(t, events, newEvent(ds.GetSourceName(), UpdateType, "a.b", "3"))
+		assert.Contains(t, events, newEvent(ds.GetSourceName(), CreateType, "e.f", "4"))
+		assert.Contains(t, events, newEvent(ds.GetSourceName(), DeleteType, "c.d", "2"))
+	})
+}
+
+func TestMemorySource(t *testing.T) {
+	ms := NewMemorySource("memory", NormalPriority)
+	assert.Equal(t, "memory", ms.GetSourceName())
+	assert.Equal(t, NormalPriority, ms.GetPriority())
+	var events []*Event
+	ms.AddEventHandler(NewHandler("test", func(event *Event) {
+		events = append(events, event)
+	}))
+
+	// the events are fired synchronously
+	ms.Set("a.b", "1")
+	assert.ElementsMatch(t, []*Event{
+		newEvent("memory", CreateType, "a.b", "1"),
+		newEvent("memory", CreateType, "ab", "1"),
+	}, events)
+	events = nil
+	ms.SetConfigs(map[string]string{"a.b": "2", "c.d": "3"})
+	assert.Len(t, events, 4)
+	events = nil
+	ms.Delete("a.b", "e.f")
+	assert.Equal(t, []*Event{newEvent("memory", DeleteType, "a.b", "2")}, events)
+	configs, err := ms.GetConfigurations()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"c.d": "3", "cd": "3"}, configs)
+
+	t.Run("fault", func(t *testing.T) {
+		injected := errors.New("injected")
+		ms.InjectFault(50*time.Millisecond, injected)
+		start := time.Now()
+		_, err := ms.GetConfigurationByKey("c.d")
+		assert.ErrorIs(t, err, injected)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		_, err = ms.GetConfigurations()
+		assert.ErrorIs(t, err, injected)
+		health := ms.Health()
+		assert.False(t, health.Connected)
+		assert.ErrorIs(t, health.LastError, injected)
+		mgr := NewManager()
+		assert.Error(t, mgr.AddSource(ms))
+
+		ms.InjectFault(0, nil)
+		v, err := ms.GetConfigurationByKey("c.d")
+		assert.NoError(t, err)
+		assert.Equal(t, "3", v)
+		assert.True(t, ms.Health().Connected)
+	})
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv("MILVUS_PROXY_MAX_NAME_LENGTH", "1")
+	t.Setenv("MILVUS_DATACOORD_SEGMENT_MAXSIZE", "2")
+	t.Setenv("MILVUS_", "3")
+	t.Setenv("MILVUS_COMMON_SECURITY_TLSMODE", "a=b")
+	t.Setenv("OTHER_KEY", "4")
+
+	es := NewEnvSourceWithInfo(&EnvInfo{KeyFormatter: formatKey, KeyPrefix: "MILVUS_"})
+	assert.Equal(t, NormalPriority, es.GetPriority())
+	assert.Equal(t, "EnvironmentSource[MILVUS_]", es.GetSourceName())
+
+	cases := []struct {
+		key   string
+		value string
+	}{
+		// the underscores may be the separators or inside the key names
+		{"proxy.maxNameLength", "1"},
+		{"proxy.max_name_length", "1"},
+		{"PROXY_MAX_NAME_LENGTH", "1"},
+		{"dataCoord.segment.maxSize", "2"},
+		{"common.security.tlsMode", "a=b"},
+		// not prefixed
+		{"other.key", ""},
+		{"", ""},
+	}
+	for _, c := range cases {
+		value, err := es.GetConfigurationByKey(formatKey(c.key))
+		if c.value == "" {
+			assert.Error(t, err, c.key)
+			continue
+		}
+		assert.NoError(t, err, c.key)
+		assert.Equal(t, c.value, value, c.key)
+	}
+
+	t.Run("without prefix", func(t *testing.T) {
+		es := NewEnvSourceWithInfo(&EnvInfo{KeyFormatter: formatKey, Priority: HighPriority})
+		assert.Equal(t, HighPriority, es.GetPriority())
+		value, err := es.GetConfigurationByKey("otherkey")
+		assert.NoError(t, err)
+		assert.Equal(t, "4", value)
+		value, err = es.GetConfigurationByKey("milvusproxymaxnamelength")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", value)
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		var events []*Event
+		es.SetEventHandler(NewHandler("test", func(event *Event) {
+			events = append(events, event)
+		}))
+
+		t.Setenv("MILVUS_PROXY_MAX_NAME_LENGTH", "5")
+		t.Setenv("MILVUS_QUERYNODE_CACHE_ENABLED", "true")
+		os.Unsetenv("MILVUS_DATACOORD_SEGMENT_MAXSIZE")
+		assert.NoError(t, es.Refresh())
+
+		value, err := es.GetConfigurationByKey("proxymaxnamelength")
+		assert.NoError(t, err)
+		assert.Equal(t, "5", value)
+		_, err = es.GetConfigurationByKey("datacoordsegmentmaxsize")
+		assert.Error(t, err)
+
+		assert.Contains(t, events, newEvent(es.GetSourceName(), UpdateType, "proxymaxnamelength", "5"))
+		assert.Contains(t, events, newEvent(es.GetSourceName(), CreateType, "querynodecacheenabled", "true"))
+		assert.Contains(t, events, newEvent(es.GetSourceName(), DeleteType, "DATACOORD_SEGMENT_MAXSIZE", "2"))
+	})
+}
+
+func TestSourceEventHandlers(t *testing.T) {
+	ms := NewMemorySource("test", NormalPriority)
+	var first, second atomic.Int32
+	firstID := ms.AddEventHandler(NewHandler("first", func(*Event) {
+		first.Inc()
+	}))
+	ms.AddEventHandler(NewHandler("panic", func(*Event) {
+		panic("bad handler")
+	}))
+	ms.AddEventHandler(NewHandler("second", func(*Event) {
+		second.Inc()
+	}))
+
+	// the panic doesn't suppress the others
+	ms.SetConfigs(map[string]string{"a.b": "1"})
+	assert.EqualValues(t, 2, first.Load())
+	assert.EqualValues(t, 2, second.Load())
+
+	ms.RemoveEventHandler(firstID)
+	ms.SetConfigs(map[string]string{"a.b": "2"})
+	assert.EqualValues(t, 2, first.Load())
+	assert.EqualValues(t, 4, second.Load())
+
+	// replaces all the handlers
+	ms.SetEventHandler(NewHandler("first", func(*Event) {
+		first.Inc()
+	}))
+	ms.SetConfigs(map[string]string{"a.b": "3"})
+	assert.EqualValues(t, 4, first.Load())
+	assert.EqualValues(t, 4, second.Load())
+}
+
+func TestSourceEventHandlersConcurrency(t *testing.T) {
+	ms := NewMemorySource("test", NormalPriority)
+	var fired atomic.Int32
+	ms.AddEventHandler(NewHandler("fired", func(*Event) {
+		fired.Inc()
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ms.SetConfigs(map[string]string{"a.b": strconv.Itoa(i*100 + j)})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// added and removed while firing events, even by the handlers
+				var id int64
+				id = ms.AddEventHandler(NewHandler("remove", func(*Event) {
+					ms.RemoveEventHandler(id)
+				}))
+				ms.RemoveEventHandler(id)
+			}
+		}()
+	}
+	wg.Wait()
+	// each change fires the events of the raw key and the formatted one
+	assert.EqualValues(t, 2000, fired.Load())
+}
+
+type panicValidator struct {
+	EventHandler
+}
+
+func (panicValidator) Validate(event *Event) error {
+	panic("bad validator")
+}
+
+func TestRefresherPanics(t *testing.T) {
+	t.Run("handlers panic", func(t *testing.T) {
+		yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+		assert.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\nc.d: 1\n"), 0o600))
+		fs := NewFileSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: 10 * time.Millisecond})
+		defer fs.Close()
+		var fired atomic.Int32
+		fs.SetEventHandler(NewHandler("panic", func(e *Event) {
+			_ = e.Value[len(e.Value)]
+		}))
+		fs.AddEventHandler(NewHandler("fired", func(*Event) {
+			fired.Inc()
+		}))
+		_, err := fs.GetConfigurations()
+		assert.NoError(t, err)
+
+		// the refreshing continues after the handler panicked
+		assert.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 2\nc.d: 1\n"), 0o600))
+		assert.Eventually(t, func() bool {
+			value, _ := fs.GetConfigurationByKey("a.b")
+			return value == "2"
+		}, 5*time.Second, 10*time.Millisecond)
+		// created a.b, ab, c.d, cd, then updated a.b, ab
+		assert.EqualValues(t, 6, fired.Load())
+
+		// the events are rejected by the validator panicked, and the refreshing continues too
+		id := fs.AddEventHandler(panicValidator{NewHandler("validator", func(*Event) {})})
+		assert.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 3\nc.d: 1\n"), 0o600))
+		time.Sleep(100 * time.Millisecond)
+		value, _ := fs.GetConfigurationByKey("a.b")
+		assert.Equal(t, "2", value)
+		fs.RemoveEventHandler(id)
+		assert.Eventually(t, func() bool {
+			value, _ := fs.GetConfigurationByKey("a.b")
+			return value == "3"
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.True(t, fs.Health().Connected)
+	})
+
+	t.Run("refreshing panics", func(t *testing.T) {
+		var fetched atomic.Int32
+		r := newRefresher(10*time.Millisecond, func() error {
+			if fetched.Inc() <= MaxRefreshPanics {
+				panic("bad refreshing")
+			}
+			return nil
+		})
+		r.start("test")
+		defer r.stop()
+		// restarted after the panics
+		assert.Eventually(t, func() bool {
+			return fetched.Load() > MaxRefreshPanics+1
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.NoError(t, r.failure())
+	})
+
+	t.Run("too many panics", func(t *testing.T) {
+		yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+		assert.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\n"), 0o600))
+		fs := NewFileSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: 10 * time.Millisecond})
+		defer fs.Close()
+		var fetched atomic.Int32
+		// the initial loading is not by the goroutine refreshing
+		fs.configRefresher.fetchFunc = func() error {
+			fetched.Inc()
+			panic("bad refreshing")
+		}
+		_, err := fs.GetConfigurations()
+		assert.NoError(t, err)
+		assert.True(t, fs.Health().Connected)
+
+		assert.Eventually(t, func() bool {
+			return fs.configRefresher.failure() != nil
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.EqualValues(t, MaxRefreshPanics+1, fetched.Load())
+		health := fs.Health()
+		assert.False(t, health.Connected)
+		assert.ErrorIs(t, health.LastError, ErrRefreshPanicked)
+		assert.False(t, health.LastSuccessTime.IsZero())
+		assert.True(t, health.Stale(0))
+		value, err := fs.GetConfigurationByKey("a.b")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", value)
+	})
+}
@@ -0,0 +1,85 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"sync"
+)
+
+// Validator validates the new value of a config before it's applied
+type Validator func(key, value string) error
+
+// eventValidator validates the events of sources before they are applied, the rejected ones are dropped,
+// and the configs of them are kept as the old values, see refresher.validateEvents
+type eventValidator interface {
+	Validate(event *Event) error
+}
+
+// eventFlusher delivers the events coalesced, once the refreshing fired them completes, see BatchEventHandler
+type eventFlusher interface {
+	flushEvents()
+}
+
+type validatorRegistry struct {
+	mu       sync.RWMutex
+	keys     map[string][]Validator
+	prefixes map[string][]Validator
+}
+
+func newValidatorRegistry() *validatorRegistry {
+	return &validatorRegistry{
+		keys:     make(map[string][]Validator),
+		prefixes: make(map[string][]Validator),
+	}
+}
+
+func (r *validatorRegistry) register(key string, validator Validator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key = formatKey(key)
+	r.keys[key] = append(r.keys[key], validator)
+}
+
+func (r *validatorRegistry) registerForKeyPrefix(keyPrefix string, validator Validator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keyPrefix = formatKey(keyPrefix)
+	r.prefixes[keyPrefix] = append(r.prefixes[keyPrefix], validator)
+}
+
+// validate validates the value by the validators of the key, and the ones of the prefixes matched.
+func (r *validatorRegistry) validate(key, value string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	realKey := formatKey(key)
+	for _, validator := range r.keys[realKey] {
+		if err := validator(key, value); err != nil {
+			return err
+		}
+	}
+	for prefix, validators := range r.prefixes {
+		if !hasKeyPrefix(realKey, prefix) {
+			continue
+		}
+		for _, validator := range validators {
+			if err := validator(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
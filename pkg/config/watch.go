@@ -0,0 +1,174 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/pkg/log"
+)
+
+// DefaultWatchBufferSize is the number of events buffered for each watcher of WatchKey, the oldest ones are dropped once it's full.
+const DefaultWatchBufferSize = 8
+
+var watchIDs atomic.Int64
+
+// keyWatcher delivers the changes of a key to the channel of WatchKey, the events of the raw key and the formatted one
+// are delivered once, and so are the ones changing nothing.
+type keyWatcher struct {
+	m   *Manager
+	id  string
+	key string
+	// closed once the watcher is canceled, which stops waiting for the ctx
+	stop chan struct{}
+
+	mu sync.Mutex
+	ch chan Event
+	// the events fired before the current value is delivered, which are delivered after it
+	pending []*Event
+	ready   bool
+	closed  bool
+	// the value delivered last time, and whether the key is set
+	value string
+	set   bool
+}
+
+// WatchKey returns the channel of the changes of the key, the current value is delivered first as a CreateType event if it's set,
+// then each change of the value taken by GetConfig, either updated or deleted, as the sources are merged by their priorities,
+// e.g. deleting the key from the source falls back to the value of the source with lower priority, which is an UpdateType event.
+// The overlays set by SetConfig are not watched, and the changes of the sources are not delivered while the key is overlaid.
+// The events are never blocked by the receiver, the oldest ones are dropped once DefaultWatchBufferSize ones are not received.
+// The channel is closed once the ctx is done or the returned function is called.
+func (m *Manager) WatchKey(ctx context.Context, key string) (<-chan Event, context.CancelFunc) {
+	w := &keyWatcher{
+		m:    m,
+		id:   fmt.Sprintf("WatchKey-%s-%d", key, watchIDs.Inc()),
+		key:  key,
+		ch:   make(chan Event, DefaultWatchBufferSize),
+		stop: make(chan struct{}),
+	}
+	// registered before reading the current value, so no change is missed
+	m.Dispatcher.Register(key, w)
+	w.start()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(w.stop)
+			m.Dispatcher.Unregister(key, w)
+			w.close()
+		})
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-w.stop:
+		}
+	}()
+	return w.ch, cancel
+}
+
+// start delivers the current value and the events pending, the value is read without holding the lock,
+// as the sources firing events hold their locks.
+func (w *keyWatcher) start() {
+	value, err := w.m.GetConfig(w.key)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err == nil {
+		source := w.m.GetIdentifier()
+		if sourceName, ok := w.m.keySourceMap.Get(formatKey(w.key)); ok {
+			if _, overlaid := w.m.overlays.Get(formatKey(w.key)); !overlaid {
+				source = sourceName
+			}
+		}
+		w.deliver(newEvent(source, CreateType, w.key, value))
+	}
+	for _, event := range w.pending {
+		w.deliver(event)
+	}
+	w.pending = nil
+	w.ready = true
+}
+
+func (w *keyWatcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	close(w.ch)
+}
+
+// OnEvent implements EventHandler
+func (w *keyWatcher) OnEvent(event *Event) {
+	realKey := formatKey(w.key)
+	if _, ok := w.m.overlays.Get(realKey); ok {
+		return
+	}
+	e := *event
+	e.Key = w.key
+	if e.EventType == DeleteType {
+		// falls back to another source, which is not the one firing the event
+		if sourceName, ok := w.m.keySourceMap.Get(realKey); ok && sourceName != event.EventSource {
+			if value, err := w.m.getConfigValueBySource(realKey, sourceName); err == nil {
+				e = *newEvent(sourceName, UpdateType, w.key, value)
+			}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.ready {
+		w.pending = append(w.pending, &e)
+		return
+	}
+	w.deliver(&e)
+}
+
+// deliver sends the event unless it changes nothing, or drops the oldest event to make room for it, it must be called with the lock.
+func (w *keyWatcher) deliver(event *Event) {
+	set := event.EventType != DeleteType
+	if w.closed || (set == w.set && (!set || event.Value == w.value)) {
+		return
+	}
+	w.set, w.value = set, event.Value
+	if !set {
+		w.value = ""
+	}
+	select {
+	case w.ch <- *event:
+		return
+	default:
+	}
+	select {
+	case dropped := <-w.ch:
+		log.Warn("drop the oldest config change not received by the watcher", zap.String("key", w.key),
+			zap.String("type", dropped.EventType))
+	default:
+	}
+	// no other sender, so there must be room now
+	w.ch <- *event
+}
+
+// GetIdentifier implements EventHandler
+func (w *keyWatcher) GetIdentifier() string {
+	return w.id
+}
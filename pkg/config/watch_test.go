@@ -0,0 +1,136 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerWatchKey(t *testing.T) {
+	yamlFile := path.Join(t.TempDir(), "milvus.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("a.b: 1\n"), 0o600))
+	mgr, err := Init(WithFilesSource(&FileInfo{Files: []string{yamlFile}, RefreshInterval: -1}))
+	require.NoError(t, err)
+	defer mgr.Close()
+	ms := NewMemorySource("override", NormalPriority)
+	ms.SetConfigs(map[string]string{"a.b": "10"})
+	require.NoError(t, mgr.AddSource(ms))
+
+	assertEvents := func(ch <-chan Event, expected ...Event) {
+		for _, e := range expected {
+			select {
+			case actual := <-ch:
+				assert.Equal(t, e.EventSource, actual.EventSource)
+				assert.Equal(t, e.EventType, actual.EventType)
+				assert.Equal(t, e.Key, actual.Key)
+				assert.Equal(t, e.Value, actual.Value)
+			case <-time.After(time.Second):
+				assert.Fail(t, "event not delivered", e)
+			}
+		}
+		select {
+		case actual, ok := <-ch:
+			assert.False(t, ok, "unexpected event %v", actual)
+		default:
+		}
+	}
+
+	t.Run("changes", func(t *testing.T) {
+		ch, cancel := mgr.WatchKey(context.Background(), "a.b")
+		defer cancel()
+		// the current value at once
+		assertEvents(ch, Event{EventSource: "override", EventType: CreateType, Key: "a.b", Value: "10"})
+
+		ms.SetConfigs(map[string]string{"a.b": "20", "c.d": "1"})
+		assertEvents(ch, Event{EventSource: "override", EventType: UpdateType, Key: "a.b", Value: "20"})
+		// nothing changed
+		ms.SetConfigs(map[string]string{"a.b": "20"})
+		assertEvents(ch)
+
+		// falls back to the source with lower priority
+		ms.Delete("a.b")
+		assertEvents(ch, Event{EventSource: "FileSource", EventType: UpdateType, Key: "a.b", Value: "1"})
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		ch, cancel := mgr.WatchKey(context.Background(), "e_f")
+		defer cancel()
+		// not set yet
+		assertEvents(ch)
+
+		ms.SetConfigs(map[string]string{"e.f": "1"})
+		assertEvents(ch, Event{EventSource: "override", EventType: CreateType, Key: "e_f", Value: "1"})
+		ms.Delete("e.f")
+		assertEvents(ch, Event{EventSource: "override", EventType: DeleteType, Key: "e_f", Value: "1"})
+	})
+
+	t.Run("overlaid", func(t *testing.T) {
+		mgr.SetConfig("g.h", "1")
+		defer mgr.ResetConfig("g.h")
+		ch, cancel := mgr.WatchKey(context.Background(), "g.h")
+		defer cancel()
+		assertEvents(ch, Event{EventSource: "Manager", EventType: CreateType, Key: "g.h", Value: "1"})
+		// shadowed by the overlay
+		ms.SetConfigs(map[string]string{"g.h": "2"})
+		assertEvents(ch)
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		ch, cancel := mgr.WatchKey(context.Background(), "i.j")
+		defer cancel()
+		for i := 0; i < DefaultWatchBufferSize*2; i++ {
+			ms.SetConfigs(map[string]string{"i.j": strconv.Itoa(i)})
+		}
+		expected := make([]Event, 0, DefaultWatchBufferSize)
+		for i := DefaultWatchBufferSize; i < DefaultWatchBufferSize*2; i++ {
+			expected = append(expected, Event{EventSource: "override", EventType: UpdateType, Key: "i.j", Value: strconv.Itoa(i)})
+		}
+		assertEvents(ch, expected...)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ch, cancel := mgr.WatchKey(context.Background(), "a.b")
+		assertEvents(ch, Event{EventSource: "FileSource", EventType: CreateType, Key: "a.b", Value: "1"})
+		cancel()
+		_, ok := <-ch
+		assert.False(t, ok)
+		assert.Empty(t, mgr.Dispatcher.Get("a.b"))
+		// no more events, and canceled again takes no effect
+		ms.SetConfigs(map[string]string{"a.b": "30"})
+		cancel()
+
+		ctx, cancelCtx := context.WithCancel(context.Background())
+		ch, cancel = mgr.WatchKey(ctx, "a.b")
+		defer cancel()
+		assertEvents(ch, Event{EventSource: "override", EventType: CreateType, Key: "a.b", Value: "30"})
+		cancelCtx()
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			assert.Fail(t, "channel not closed")
+		}
+	})
+}
@@ -0,0 +1,74 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const configSourceLabelName = "config_source"
+
+var (
+	ConfigRefreshLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: milvusNamespace,
+			Subsystem: "config",
+			Name:      "refresh_latency",
+			Help:      "latency of refreshing configurations from the source in milliseconds",
+			Buckets:   fineBuckets,
+		}, []string{configSourceLabelName})
+
+	ConfigRefreshFailCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: "config",
+			Name:      "refresh_fail_count",
+			Help:      "count of failures refreshing configurations from the source",
+		}, []string{configSourceLabelName})
+
+	ConfigRefreshAuthFailCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: "config",
+			Name:      "refresh_auth_fail_count",
+			Help:      "count of failures refreshing configurations from the source for authentication or permission",
+		}, []string{configSourceLabelName})
+
+	ConfigKeyNum = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: "config",
+			Name:      "key_num",
+			Help:      "number of configuration keys loaded from the source, including the formatted duplicates",
+		}, []string{configSourceLabelName})
+
+	ConfigRefreshEvents = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: milvusNamespace,
+			Subsystem: "config",
+			Name:      "refresh_events",
+			Help:      "number of change events fired per refresh of the source",
+			Buckets:   []float64{0, 1, 2, 4, 8, 16, 32, 64, 128, 256},
+		}, []string{configSourceLabelName})
+)
+
+// RegisterConfigMetrics registers config metrics
+func RegisterConfigMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(ConfigRefreshLatency)
+	registry.MustRegister(ConfigRefreshFailCounter)
+	registry.MustRegister(ConfigRefreshAuthFailCounter)
+	registry.MustRegister(ConfigKeyNum)
+	registry.MustRegister(ConfigRefreshEvents)
+}
@@ -43,6 +43,25 @@ const (
 	TimetickLabel  = "timetick"
 	AllLabel       = "all"
 
+	// phases of delete
+	DeletePlanLabel    = "plan"
+	DeleteQueryLabel   = "query"
+	DeleteRepackLabel  = "repack"
+	DeleteProduceLabel = "produce"
+
+	// states of allocator breaker
+	BreakerClosedLabel   = "closed"
+	BreakerOpenLabel     = "open"
+	BreakerHalfOpenLabel = "half_open"
+
+	// lanes of dml task queue
+	SmallTaskLaneLabel = "small"
+	LargeTaskLaneLabel = "large"
+
+	// limits of the pending dml tasks
+	PendingTasksLimitLabel = "pending_tasks"
+	PendingRowsLimitLabel  = "pending_rows"
+
 	UnissuedIndexTaskLabel   = "unissued"
 	InProgressIndexTaskLabel = "in-progress"
 	FinishedIndexTaskLabel   = "finished"
@@ -91,6 +110,11 @@ const (
 	indexCountLabelName      = "indexed_field_count"
 	requestScope             = "scope"
 	fullMethodLabelName      = "full_method"
+	deletePhaseLabelName     = "delete_phase"
+	taskLaneLabelName        = "task_lane"
+	allocatorLabelName       = "allocator"
+	breakerStateLabelName    = "breaker_state"
+	pendingLimitLabelName    = "pending_limit"
 	reduceLevelName          = "reduce_level"
 	lockName                 = "lock_name"
 	lockSource               = "lock_source"
@@ -103,6 +127,10 @@ var (
 	// [1 2 4 8 16 32 64 128 256 512 1024 2048 4096 8192 16384 32768 65536 1.31072e+05]
 	buckets = prometheus.ExponentialBuckets(1, 2, 18)
 
+	// fineBuckets involves durations in milliseconds, from sub-millisecond to minutes,
+	// [0.1 0.2 0.4 ... 52428.8 104857.6]
+	fineBuckets = prometheus.ExponentialBuckets(0.1, 2, 21)
+
 	// longTaskBuckets provides long task duration in milliseconds
 	longTaskBuckets = []float64{1, 100, 500, 1000, 5000, 10000, 20000, 50000, 100000, 250000, 500000, 1000000, 3600000, 5000000, 10000000} // unit milliseconds
 
@@ -297,6 +297,160 @@ var (
 		}, []string{
 			nodeIDLabelName,
 		})
+
+	// ProxyDeleteBufferedBytes record the estimated memory of delete tasks waiting to be finished.
+	ProxyDeleteBufferedBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "delete_buffered_bytes",
+			Help:      "estimated bytes of primary keys buffered by complex deletes",
+		}, []string{
+			nodeIDLabelName,
+		})
+
+	// ProxyDeleteRejectedCount record the number of complex deletes rejected as the delete buffer is full.
+	ProxyDeleteRejectedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "delete_rejected_count",
+			Help:      "count of complex deletes rejected as the delete buffer is not drained in time",
+		}, []string{
+			nodeIDLabelName,
+		})
+
+	// ProxySlowDeleteCount record the number of deletes exceeding the slow delete threshold.
+	ProxySlowDeleteCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "slow_delete_count",
+			Help:      "count of deletes exceeding the slow delete threshold",
+		}, []string{
+			nodeIDLabelName,
+			collectionName,
+		})
+
+	// ProxyDeletePhaseLatency record the latency of each phase of delete.
+	ProxyDeletePhaseLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "delete_phase_latency",
+			Help:      "latency of each phase of delete, plan creation, query, repack and produce",
+			Buckets:   fineBuckets, // unit: ms
+		}, []string{
+			nodeIDLabelName,
+			collectionName,
+			deletePhaseLabelName,
+		})
+
+	// ProxyDeleteProduceRetryCount record the number of retries producing delete msgs.
+	ProxyDeleteProduceRetryCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "delete_produce_retry_count",
+			Help:      "count of retries producing delete msgs for transient mq errors",
+		}, []string{
+			nodeIDLabelName,
+		})
+
+	// ProxyDmlQueueWaitLatency record the time that dml tasks wait in the queue before being issued, per lane.
+	ProxyDmlQueueWaitLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "dml_queue_wait_latency",
+			Help:      "latency that dml tasks wait in the queue before being issued, per lane of small or large tasks",
+			Buckets:   buckets, // unit: ms
+		}, []string{
+			nodeIDLabelName,
+			taskLaneLabelName,
+		})
+
+	// ProxyDmlStreamNum record the number of dml streams open on Proxy.
+	ProxyDmlStreamNum = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "dml_stream_num",
+			Help:      "number of dml streams open, one per collection",
+		}, []string{nodeIDLabelName})
+
+	// ProxyMutationAuditDroppedCount record the number of mutation audit records dropped as the buffer is full.
+	ProxyMutationAuditDroppedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "mutation_audit_dropped_count",
+			Help:      "count of mutation audit records dropped as the audit buffer is full",
+		}, []string{nodeIDLabelName})
+
+	// ProxyChannelTimeTickLag record the lag of the time tick of each physical channel.
+	ProxyChannelTimeTickLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "channel_tt_lag_ms",
+			Help:      "now time minus the time tick of each physical channel with pending dml",
+		}, []string{nodeIDLabelName, channelNameLabelName})
+
+	// ProxyAllocatorBreakerTransitionCount record the number of state transitions of the allocator breakers.
+	ProxyAllocatorBreakerTransitionCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "allocator_breaker_transition_count",
+			Help:      "count of state transitions of the breakers of id and tso allocations, by the state transitioned to",
+		}, []string{nodeIDLabelName, allocatorLabelName, breakerStateLabelName})
+
+	// ProxyDmlQueueRejectedCount record the number of dml tasks rejected as the pending ones exceed the limits.
+	ProxyDmlQueueRejectedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "dml_queue_rejected_count",
+			Help:      "count of dml tasks rejected at enqueue as the pending ones exceed the limit of tasks or rows",
+		}, []string{nodeIDLabelName, pendingLimitLabelName})
+
+	// ProxyDmlChannelProduceLatency record the latency of producing the dml msgs to each physical channel.
+	ProxyDmlChannelProduceLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "dml_channel_produce_latency",
+			Help:      "latency of producing the dml msgs to each physical channel",
+			Buckets:   buckets, // unit: ms
+		}, []string{nodeIDLabelName, channelNameLabelName})
+
+	// ProxyDmlChannelProduceFailureCount record the number of failed produces of the dml msgs to each physical channel.
+	ProxyDmlChannelProduceFailureCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "dml_channel_produce_failure_count",
+			Help:      "count of failed produces of the dml msgs to each physical channel",
+		}, []string{nodeIDLabelName, channelNameLabelName})
+
+	// ProxyDmlChannelProduceMaxLatency record the max produce latency of the slowest channel of each collection lately.
+	ProxyDmlChannelProduceMaxLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "dml_channel_produce_max_latency",
+			Help:      "max latency of producing the dml msgs to the slowest physical channel of the collection within the rolling window",
+		}, []string{nodeIDLabelName, collectionIDLabelName})
+
+	// ProxyDmlQueueScheduledCount record the number of dml tasks issued from the queue for each collection.
+	ProxyDmlQueueScheduledCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.ProxyRole,
+			Name:      "dml_queue_scheduled_count",
+			Help:      "count of dml tasks issued from the queue for each collection",
+		}, []string{nodeIDLabelName, collectionName})
 )
 
 // RegisterProxy registers Proxy metrics
@@ -341,6 +495,21 @@ func RegisterProxy(registry *prometheus.Registry) {
 
 	registry.MustRegister(ProxyWorkLoadScore)
 	registry.MustRegister(ProxyExecutingTotalNq)
+	registry.MustRegister(ProxyDeleteBufferedBytes)
+	registry.MustRegister(ProxyDeleteRejectedCount)
+	registry.MustRegister(ProxySlowDeleteCount)
+	registry.MustRegister(ProxyDeleteProduceRetryCount)
+	registry.MustRegister(ProxyDeletePhaseLatency)
+	registry.MustRegister(ProxyDmlQueueWaitLatency)
+	registry.MustRegister(ProxyDmlStreamNum)
+	registry.MustRegister(ProxyMutationAuditDroppedCount)
+	registry.MustRegister(ProxyChannelTimeTickLag)
+	registry.MustRegister(ProxyAllocatorBreakerTransitionCount)
+	registry.MustRegister(ProxyDmlQueueRejectedCount)
+	registry.MustRegister(ProxyDmlChannelProduceLatency)
+	registry.MustRegister(ProxyDmlChannelProduceFailureCount)
+	registry.MustRegister(ProxyDmlChannelProduceMaxLatency)
+	registry.MustRegister(ProxyDmlQueueScheduledCount)
 }
 
 func CleanupCollectionMetrics(nodeID int64, collection string) {
@@ -388,4 +557,19 @@ func CleanupCollectionMetrics(nodeID int64, collection string) {
 		nodeIDLabelName:  strconv.FormatInt(nodeID, 10),
 		msgTypeLabelName: UpsertLabel, collectionName: collection,
 	})
+	ProxySlowDeleteCount.Delete(prometheus.Labels{
+		nodeIDLabelName: strconv.FormatInt(nodeID, 10),
+		collectionName:  collection,
+	})
+	ProxyDmlQueueScheduledCount.Delete(prometheus.Labels{
+		nodeIDLabelName: strconv.FormatInt(nodeID, 10),
+		collectionName:  collection,
+	})
+	for _, phase := range []string{DeletePlanLabel, DeleteQueryLabel, DeleteRepackLabel, DeleteProduceLabel} {
+		ProxyDeletePhaseLatency.Delete(prometheus.Labels{
+			nodeIDLabelName:      strconv.FormatInt(nodeID, 10),
+			collectionName:       collection,
+			deletePhaseLabelName: phase,
+		})
+	}
 }
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -43,6 +44,7 @@ func testMsgStreamOperation(t *testing.T, factories []Factory, pg positionGenera
 		},
 		testMqMsgStreamSeekLatest,
 		testBroadcastMark,
+		testProduceMark,
 	}
 
 	for _, testFunc := range testFuncs {
@@ -707,6 +709,56 @@ func testBroadcastMark(t *testing.T, f []Factory) {
 	assert.Error(t, err)
 }
 
+func testProduceMark(t *testing.T, f []Factory) {
+	channels := getChannel(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	producer, consumer := createStream(ctx, t, []streamNewer{f[0].NewMsgStream, f[1].NewMsgStream}, channels)
+	defer producer.Close()
+	defer consumer.Close()
+
+	// msgs are routed to the channels by their hash values
+	msgPack := MsgPack{}
+	msgPack.Msgs = append(msgPack.Msgs, getTsMsg(commonpb.MsgType_Insert, 1))
+	msgPack.Msgs = append(msgPack.Msgs, getTsMsg(commonpb.MsgType_Insert, 2))
+	msgPack.Msgs = append(msgPack.Msgs, getTsMsg(commonpb.MsgType_Insert, 3))
+
+	ids, err := producer.ProduceMark(&msgPack)
+	assert.NoError(t, err)
+	assert.Len(t, ids[channels[0]], 1)
+	assert.Len(t, ids[channels[1]], 2)
+
+	// the ids are the positions of msgs consumed
+	produced := make(map[string]struct{})
+	for _, channelIDs := range ids {
+		for _, id := range channelIDs {
+			produced[string(id.Serialize())] = struct{}{}
+		}
+	}
+	received := 0
+	for received < len(msgPack.Msgs) {
+		result := consume(ctx, consumer)
+		if !assert.NotNil(t, result) {
+			return
+		}
+		for _, msg := range result.Msgs {
+			assert.Contains(t, produced, string(msg.Position().GetMsgID()))
+			received++
+		}
+	}
+
+	// edge cases
+	ids, err = producer.ProduceMark(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	msgPack1 := MsgPack{}
+	msgPack1.Msgs = append(msgPack1.Msgs, &MarshalFailTsMsg{})
+	_, err = producer.ProduceMark(&msgPack1)
+	assert.Error(t, err)
+}
+
 func applyBroadCastAndConsume(t *testing.T, msgPack *MsgPack, newer []streamNewer, channelNum int) {
 	producer, consumer := createStream(context.Background(), t, newer, getChannel(channelNum))
 	defer producer.Close()
@@ -445,6 +445,60 @@ func (_c *MockMsgStream_Produce_Call) RunAndReturn(run func(*MsgPack) error) *Mo
 	return _c
 }
 
+// ProduceMark provides a mock function with given fields: _a0
+func (_m *MockMsgStream) ProduceMark(_a0 *MsgPack) (map[string][]mqwrapper.MessageID, error) {
+	ret := _m.Called(_a0)
+
+	var r0 map[string][]mqwrapper.MessageID
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*MsgPack) (map[string][]mqwrapper.MessageID, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(*MsgPack) map[string][]mqwrapper.MessageID); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]mqwrapper.MessageID)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*MsgPack) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMsgStream_ProduceMark_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ProduceMark'
+type MockMsgStream_ProduceMark_Call struct {
+	*mock.Call
+}
+
+// ProduceMark is a helper method to define mock.On call
+//   - _a0 *MsgPack
+func (_e *MockMsgStream_Expecter) ProduceMark(_a0 interface{}) *MockMsgStream_ProduceMark_Call {
+	return &MockMsgStream_ProduceMark_Call{Call: _e.mock.On("ProduceMark", _a0)}
+}
+
+func (_c *MockMsgStream_ProduceMark_Call) Run(run func(_a0 *MsgPack)) *MockMsgStream_ProduceMark_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*MsgPack))
+	})
+	return _c
+}
+
+func (_c *MockMsgStream_ProduceMark_Call) Return(_a0 map[string][]mqwrapper.MessageID, _a1 error) *MockMsgStream_ProduceMark_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMsgStream_ProduceMark_Call) RunAndReturn(run func(*MsgPack) (map[string][]mqwrapper.MessageID, error)) *MockMsgStream_ProduceMark_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Seek provides a mock function with given fields: ctx, offset
 func (_m *MockMsgStream) Seek(ctx context.Context, offset []*msgpb.MsgPosition) error {
 	ret := _m.Called(ctx, offset)
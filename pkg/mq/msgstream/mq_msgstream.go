@@ -272,16 +272,24 @@ func (ms *mqMsgStream) isEnabledProduce() bool {
 }
 
 func (ms *mqMsgStream) Produce(msgPack *MsgPack) error {
+	_, err := ms.ProduceMark(msgPack)
+	return err
+}
+
+// ProduceMark produces msg pack like Produce, and returns the ids of msgs produced to each channel in order,
+// the msgs produced before an error are not rolled back.
+func (ms *mqMsgStream) ProduceMark(msgPack *MsgPack) (map[string][]MessageID, error) {
+	ids := make(map[string][]MessageID)
 	if !ms.isEnabledProduce() {
 		log.Warn("can't produce the msg in the backup instance", zap.Stack("stack"))
-		return merr.ErrDenyProduceMsg
+		return ids, merr.ErrDenyProduceMsg
 	}
 	if msgPack == nil || len(msgPack.Msgs) <= 0 {
 		log.Debug("Warning: Receive empty msgPack")
-		return nil
+		return ids, nil
 	}
 	if len(ms.producers) <= 0 {
-		return errors.New("nil producer in msg stream")
+		return ids, errors.New("nil producer in msg stream")
 	}
 	tsMsgs := msgPack.Msgs
 	reBucketValues := ms.ComputeProduceChannelIndexes(msgPack.Msgs)
@@ -301,7 +309,7 @@ func (ms *mqMsgStream) Produce(msgPack *MsgPack) error {
 		}
 	}
 	if err != nil {
-		return err
+		return ids, err
 	}
 	for k, v := range result {
 		channel := ms.producerChannels[k]
@@ -311,27 +319,29 @@ func (ms *mqMsgStream) Produce(msgPack *MsgPack) error {
 
 			mb, err := v.Msgs[i].Marshal(v.Msgs[i])
 			if err != nil {
-				return err
+				return ids, err
 			}
 
 			m, err := convertToByteArray(mb)
 			if err != nil {
-				return err
+				return ids, err
 			}
 
 			msg := &mqwrapper.ProducerMessage{Payload: m, Properties: map[string]string{}}
 			InjectCtx(spanCtx, msg.Properties)
 
 			ms.producerLock.RLock()
-			if _, err := ms.producers[channel].Send(spanCtx, msg); err != nil {
+			id, err := ms.producers[channel].Send(spanCtx, msg)
+			if err != nil {
 				ms.producerLock.RUnlock()
 				sp.RecordError(err)
-				return err
+				return ids, err
 			}
 			ms.producerLock.RUnlock()
+			ids[channel] = append(ids[channel], id)
 		}
 	}
-	return nil
+	return ids, nil
 }
 
 // BroadcastMark broadcast msg pack to all producers and returns corresponding msg id
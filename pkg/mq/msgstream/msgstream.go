@@ -57,6 +57,7 @@ type MsgStream interface {
 
 	AsProducer(channels []string)
 	Produce(*MsgPack) error
+	ProduceMark(*MsgPack) (map[string][]MessageID, error)
 	SetRepackFunc(repackFunc RepackFunc)
 	GetProduceChannels() []string
 	Broadcast(*MsgPack) (map[string][]MessageID, error)
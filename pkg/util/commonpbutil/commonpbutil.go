@@ -17,8 +17,13 @@
 package commonpbutil
 
 import (
+	"context"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
 )
 
@@ -99,3 +104,17 @@ func UpdateMsgBase(msgBase *commonpb.MsgBase, options ...MsgBaseOptions) *common
 	}
 	return msgBaseRt
 }
+
+// ExtractTraceCtx returns ctx linked to the trace context the caller injected into the properties of msgBase,
+// ctx is returned as is if it's of the same trace already, e.g. propagated by the interceptors of gRPC.
+func ExtractTraceCtx(ctx context.Context, msgBase *commonpb.MsgBase) context.Context {
+	if len(msgBase.GetProperties()) == 0 {
+		return ctx
+	}
+	extracted := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msgBase.GetProperties()))
+	spanCtx := trace.SpanContextFromContext(extracted)
+	if !spanCtx.IsValid() || spanCtx.TraceID() == trace.SpanContextFromContext(ctx).TraceID() {
+		return ctx
+	}
+	return extracted
+}
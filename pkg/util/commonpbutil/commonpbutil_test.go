@@ -0,0 +1,78 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package commonpbutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+)
+
+func TestExtractTraceCtx(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagator)
+
+	caller := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	properties := make(map[string]string)
+	otel.GetTextMapPropagator().Inject(trace.ContextWithSpanContext(context.Background(), caller), propagation.MapCarrier(properties))
+	assert.Equal(t, "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01", properties["traceparent"])
+	msgBase := NewMsgBase(WithMsgType(commonpb.MsgType_Retrieve))
+	msgBase.Properties = properties
+
+	t.Run("linked to the caller", func(t *testing.T) {
+		ctx := ExtractTraceCtx(context.Background(), msgBase)
+		spanCtx := trace.SpanContextFromContext(ctx)
+		assert.Equal(t, caller.TraceID(), spanCtx.TraceID())
+		assert.Equal(t, caller.SpanID(), spanCtx.SpanID())
+		assert.True(t, spanCtx.IsRemote())
+
+		// a fresh trace is replaced too
+		fresh := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{16},
+			SpanID:  trace.SpanID{8},
+		})
+		ctx = ExtractTraceCtx(trace.ContextWithSpanContext(context.Background(), fresh), msgBase)
+		assert.Equal(t, caller.SpanID(), trace.SpanContextFromContext(ctx).SpanID())
+	})
+
+	t.Run("same trace", func(t *testing.T) {
+		linked := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: caller.TraceID(),
+			SpanID:  trace.SpanID{8},
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), linked)
+		assert.Equal(t, ctx, ExtractTraceCtx(ctx, msgBase))
+	})
+
+	t.Run("not traced", func(t *testing.T) {
+		ctx := context.Background()
+		assert.Equal(t, ctx, ExtractTraceCtx(ctx, nil))
+		assert.Equal(t, ctx, ExtractTraceCtx(ctx, NewMsgBase()))
+		assert.Equal(t, ctx, ExtractTraceCtx(ctx, &commonpb.MsgBase{Properties: map[string]string{"traceparent": "invalid"}}))
+	})
+}
@@ -34,6 +34,24 @@ import (
 
 var maxTxnNum = 128
 
+// ClientOption customizes the config of etcd client, see CreateEtcdClient
+type ClientOption func(cfg *clientv3.Config)
+
+// WithDialTimeout sets the timeout of establishing the connection
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientv3.Config) {
+		cfg.DialTimeout = timeout
+	}
+}
+
+// WithKeepAlive pings the server every keepAliveTime, and closes the connection if no response within keepAliveTimeout
+func WithKeepAlive(keepAliveTime, keepAliveTimeout time.Duration) ClientOption {
+	return func(cfg *clientv3.Config) {
+		cfg.DialKeepAliveTime = keepAliveTime
+		cfg.DialKeepAliveTimeout = keepAliveTimeout
+	}
+}
+
 // GetEtcdClient returns etcd client
 func GetEtcdClient(
 	useEmbedEtcd bool,
@@ -43,19 +61,53 @@ func GetEtcdClient(
 	keyFile string,
 	caCertFile string,
 	minVersion string,
+) (*clientv3.Client, error) {
+	return CreateEtcdClient(useEmbedEtcd, false, "", "", useSSL, endpoints, certFile, keyFile, caCertFile, minVersion)
+}
+
+// CreateEtcdClient returns etcd client, which authenticates by the user name and password if enableAuth
+func CreateEtcdClient(
+	useEmbedEtcd bool,
+	enableAuth bool,
+	userName string,
+	password string,
+	useSSL bool,
+	endpoints []string,
+	certFile string,
+	keyFile string,
+	caCertFile string,
+	minVersion string,
+	opts ...ClientOption,
 ) (*clientv3.Client, error) {
 	log.Info("create etcd client",
 		zap.Bool("useEmbedEtcd", useEmbedEtcd),
+		zap.Bool("enableAuth", enableAuth),
+		zap.String("userName", userName),
 		zap.Bool("useSSL", useSSL),
 		zap.Any("endpoints", endpoints),
 		zap.String("minVersion", minVersion))
 	if useEmbedEtcd {
 		return GetEmbedEtcdClient()
 	}
+	cfg := clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	}
+	if enableAuth {
+		// the client skips authentication silently without either of them
+		if userName == "" || password == "" {
+			return nil, errors.New("empty etcd user name or password with auth enabled")
+		}
+		cfg.Username = userName
+		cfg.Password = password
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 	if useSSL {
-		return GetRemoteEtcdSSLClient(endpoints, certFile, keyFile, caCertFile, minVersion)
+		return GetRemoteEtcdSSLClientWithCfg(endpoints, certFile, keyFile, caCertFile, minVersion, cfg)
 	}
-	return GetRemoteEtcdClient(endpoints)
+	return clientv3.New(cfg)
 }
 
 // GetRemoteEtcdClient returns client of remote etcd by given endpoints
@@ -68,8 +120,15 @@ func GetRemoteEtcdClient(endpoints []string) (*clientv3.Client, error) {
 
 func GetRemoteEtcdSSLClient(endpoints []string, certFile string, keyFile string, caCertFile string, minVersion string) (*clientv3.Client, error) {
 	var cfg clientv3.Config
+	return GetRemoteEtcdSSLClientWithCfg(endpoints, certFile, keyFile, caCertFile, minVersion, cfg)
+}
+
+// GetRemoteEtcdSSLClientWithCfg returns client of remote etcd by SSL, with the other settings of cfg such as the credentials
+func GetRemoteEtcdSSLClientWithCfg(endpoints []string, certFile string, keyFile string, caCertFile string, minVersion string, cfg clientv3.Config) (*clientv3.Client, error) {
 	cfg.Endpoints = endpoints
-	cfg.DialTimeout = 5 * time.Second
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
 		return nil, errors.Wrap(err, "load etcd cert key pair error")
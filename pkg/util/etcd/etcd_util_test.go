@@ -56,6 +56,9 @@ func TestEtcd(t *testing.T) {
 		"1.2")
 	assert.Error(t, err)
 
+	_, err = CreateEtcdClient(false, true, "user", "", false, []string{"localhost:2379"}, "", "", "", "")
+	assert.Error(t, err)
+
 	_, err = GetEtcdClient(false, true, []string{},
 		"wrong/file",
 		"../../../configs/cert/client.key",
@@ -43,6 +43,7 @@ var (
 	ErrServiceQuotaExceeded        = newMilvusError("quota exceeded", 9, false)
 	ErrServiceUnimplemented        = newMilvusError("service unimplemented", 10, false)
 	ErrServiceTimeTickLongDelay    = newMilvusError("time tick long delay", 11, false)
+	ErrServiceOverloaded           = newMilvusError("server overloaded", 12, true)
 
 	// Collection related
 	ErrCollectionNotFound         = newMilvusError("collection not found", 100, false)
@@ -51,6 +52,7 @@ var (
 	ErrCollectionNotFullyLoaded   = newMilvusError("collection not fully loaded", 103, true)
 	ErrCollectionLoaded           = newMilvusError("collection already loaded", 104, false)
 	ErrCollectionIllegalSchema    = newMilvusError("illegal collection schema", 105, false)
+	ErrCollectionIdentityChanged  = newMilvusError("collection identity changed during operation", 106, false)
 
 	// Partition related
 	ErrPartitionNotFound       = newMilvusError("partition not found", 200, false)
@@ -153,6 +155,11 @@ var (
 
 	// import
 	ErrImportFailed = newMilvusError("importing data failed", 2100, false)
+
+	// Mutation related
+	ErrDeletePartial       = newMilvusError("delete partially succeeded", 2200, false)
+	ErrDeleteRowsExceeded  = newMilvusError("delete rows exceeded the limit", 2201, false)
+	ErrDeleteSchemaChanged = newMilvusError("schema changed during delete", 2202, false)
 )
 
 type milvusError struct {
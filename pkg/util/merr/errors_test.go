@@ -22,9 +22,11 @@ import (
 	"testing"
 
 	"github.com/cockroachdb/errors"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/milvus-io/milvus-proto/go-api/v2/commonpb"
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
 	"github.com/milvus-io/milvus/pkg/util/paramtable"
 )
 
@@ -75,6 +77,7 @@ func (s *ErrSuite) TestWrap() {
 	s.ErrorIs(WrapErrServiceUnavailable("test", "test init"), ErrServiceUnavailable)
 	s.ErrorIs(WrapErrServiceMemoryLimitExceeded(110, 100, "MLE"), ErrServiceMemoryLimitExceeded)
 	s.ErrorIs(WrapErrServiceRequestLimitExceeded(100, "too many requests"), ErrServiceRequestLimitExceeded)
+	s.ErrorIs(WrapErrServiceOverloaded(100, 100, "too many pending mutations"), ErrServiceOverloaded)
 	s.ErrorIs(WrapErrServiceInternal("never throw out"), ErrServiceInternal)
 	s.ErrorIs(WrapErrServiceCrossClusterRouting("ins-0", "ins-1"), ErrServiceCrossClusterRouting)
 	s.ErrorIs(WrapErrServiceDiskLimitExceeded(110, 100, "DLE"), ErrServiceDiskLimitExceeded)
@@ -85,6 +88,8 @@ func (s *ErrSuite) TestWrap() {
 	s.ErrorIs(WrapErrCollectionNotFound("test_collection", "failed to get collection"), ErrCollectionNotFound)
 	s.ErrorIs(WrapErrCollectionNotLoaded("test_collection", "failed to query"), ErrCollectionNotLoaded)
 	s.ErrorIs(WrapErrCollectionNotFullyLoaded("test_collection", "failed to query"), ErrCollectionNotFullyLoaded)
+	s.ErrorIs(WrapErrCollectionIdentityChanged("test_alias", 1, 2, "failed to delete"), ErrCollectionIdentityChanged)
+	s.False(IsRetryableErr(WrapErrCollectionIdentityChanged("test_alias", 1, 0)))
 	s.ErrorIs(WrapErrCollectionNotLoaded("test_collection", "failed to alter index %s", "hnsw"), ErrCollectionNotLoaded)
 
 	// Partition related
@@ -144,6 +149,31 @@ func (s *ErrSuite) TestWrap() {
 	// alias related
 	s.ErrorIs(WrapErrAliasNotFound("alias", "failed to get collection id"), ErrAliasNotFound)
 	s.ErrorIs(WrapErrCollectionIDOfAliasNotFound(1000, "failed to get collection id"), ErrCollectionIDOfAliasNotFound)
+
+	// mutation related
+	s.ErrorIs(WrapErrDeletePartial(3, errors.New("mock produce error")), ErrDeletePartial)
+	s.ErrorIs(WrapErrDeleteRowsExceeded(100, 80), ErrDeleteRowsExceeded)
+	s.ErrorIs(WrapErrDeleteSchemaChanged("coll", 80), ErrDeleteSchemaChanged)
+	s.ErrorIs(WrapErrDeleteResumable(3, "token", errors.New("mock produce error")), ErrDeletePartial)
+}
+
+func (s *ErrSuite) TestDeletePartial() {
+	err := WrapErrDeletePartial(3, errors.New("mock produce error"))
+	s.False(IsRetryableErr(err))
+	s.Contains(err.Error(), "deleted=3")
+	s.Contains(err.Error(), "mock produce error")
+
+	// the count survives the grpc round trip along with the failed status
+	bytes, marshalErr := proto.Marshal(&milvuspb.MutationResult{Status: Status(err), DeleteCnt: 3})
+	s.NoError(marshalErr)
+	result := &milvuspb.MutationResult{}
+	s.NoError(proto.Unmarshal(bytes, result))
+
+	restoredErr := Error(result.GetStatus())
+	s.ErrorIs(restoredErr, ErrDeletePartial)
+	s.False(result.GetStatus().GetRetriable())
+	s.Contains(result.GetStatus().GetReason(), "deleted=3")
+	s.EqualValues(3, result.GetDeleteCnt())
 }
 
 func (s *ErrSuite) TestOldCode() {
@@ -157,6 +187,9 @@ func (s *ErrSuite) TestOldCode() {
 	s.ErrorIs(OldCodeToMerr(commonpb.ErrorCode_RateLimit), ErrServiceRateLimit)
 	s.ErrorIs(OldCodeToMerr(commonpb.ErrorCode_ForceDeny), ErrServiceQuotaExceeded)
 	s.ErrorIs(OldCodeToMerr(commonpb.ErrorCode_UnexpectedError), errUnexpected)
+
+	// the old clients back off on overloaded as rate limited
+	s.Equal(commonpb.ErrorCode_RateLimit, Status(WrapErrServiceOverloaded(100, 100)).GetErrorCode())
 }
 
 func (s *ErrSuite) TestCombine() {
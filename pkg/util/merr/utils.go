@@ -159,7 +159,7 @@ func oldCode(code int32) commonpb.ErrorCode {
 	case ErrServiceTimeTickLongDelay.code():
 		return commonpb.ErrorCode_TimeTickLongDelay
 
-	case ErrServiceRateLimit.code():
+	case ErrServiceRateLimit.code(), ErrServiceOverloaded.code():
 		return commonpb.ErrorCode_RateLimit
 
 	case ErrServiceQuotaExceeded.code():
@@ -342,6 +342,17 @@ func WrapErrServiceRequestLimitExceeded(limit int32, msg ...string) error {
 	return err
 }
 
+func WrapErrServiceOverloaded(pending, limit int64, msg ...string) error {
+	err := wrapFields(ErrServiceOverloaded,
+		value("pending", pending),
+		value("limit", limit),
+	)
+	if len(msg) > 0 {
+		err = errors.Wrap(err, strings.Join(msg, "->"))
+	}
+	return err
+}
+
 func WrapErrServiceInternal(reason string, msg ...string) error {
 	err := wrapFieldsWithDesc(ErrServiceInternal, reason)
 	if len(msg) > 0 {
@@ -461,6 +472,21 @@ func WrapErrCollectionIDOfAliasNotFound(collectionID int64, msg ...string) error
 	return err
 }
 
+// WrapErrCollectionIdentityChanged returns the error of the collection name resolved to collectionID at first,
+// but to currentCollectionID later in the same operation, currentCollectionID is 0 if the collection is dropped.
+// Retrying the same request can't help, the name should be resolved again before re-issuing it.
+func WrapErrCollectionIdentityChanged(collection string, collectionID int64, currentCollectionID int64, msg ...string) error {
+	err := wrapFields(ErrCollectionIdentityChanged,
+		value("collection", collection),
+		value("collectionID", collectionID),
+		value("currentCollectionID", currentCollectionID),
+	)
+	if len(msg) > 0 {
+		err = errors.Wrap(err, strings.Join(msg, "->"))
+	}
+	return err
+}
+
 func WrapErrCollectionNotFullyLoaded(collection any, msg ...string) error {
 	err := wrapFields(ErrCollectionNotFullyLoaded, value("collection", collection))
 	if len(msg) > 0 {
@@ -944,3 +970,25 @@ func WrapErrImportFailed(msg ...string) error {
 	}
 	return err
 }
+
+// mutation related
+// WrapErrDeletePartial returns the error of a delete failed midway, deleted is the number of rows already deleted.
+func WrapErrDeletePartial(deleted int64, cause error) error {
+	return wrapFieldsWithDesc(ErrDeletePartial, cause.Error(), value("deleted", deleted))
+}
+
+// WrapErrDeleteResumable is WrapErrDeletePartial with the continuation token to resume the delete from.
+func WrapErrDeleteResumable(deleted int64, token string, cause error) error {
+	return wrapFieldsWithDesc(ErrDeletePartial, cause.Error(), value("deleted", deleted), value("continuation_token", token))
+}
+
+// WrapErrDeleteRowsExceeded returns the error of a delete stopped by the row limit, deleted is the number of rows already deleted.
+func WrapErrDeleteRowsExceeded(limit int64, deleted int64) error {
+	return wrapFields(ErrDeleteRowsExceeded, value("limit", limit), value("deleted", deleted))
+}
+
+// WrapErrDeleteSchemaChanged returns the error of a delete aborted as the schema of the collection changed midway,
+// deleted is the number of rows already deleted.
+func WrapErrDeleteSchemaChanged(collection string, deleted int64) error {
+	return wrapFields(ErrDeleteSchemaChanged, value("collection", collection), value("deleted", deleted))
+}
@@ -12,6 +12,7 @@
 package paramtable
 
 import (
+	"context"
 	"os"
 	"path"
 	"runtime"
@@ -73,6 +74,7 @@ type baseTableConfig struct {
 	refreshInterval int
 	skipRemote      bool
 	skipEnv         bool
+	envPrefix       string
 	yamlFiles       []string
 }
 
@@ -102,6 +104,14 @@ func skipEnv(skip bool) Option {
 	}
 }
 
+// EnvPrefix sets the prefix of the env vars overriding any config, e.g. MILVUS_PROXY_MAXNAMELENGTH
+// for proxy.maxNameLength with prefix MILVUS_, empty to disable them.
+func EnvPrefix(prefix string) Option {
+	return func(bt *baseTableConfig) {
+		bt.envPrefix = prefix
+	}
+}
+
 // NewBaseTableFromYamlOnly only used in migration tool.
 // Maybe we shouldn't limit the configDir internally.
 func NewBaseTableFromYamlOnly(yaml string) *BaseTable {
@@ -115,6 +125,7 @@ func NewBaseTable(opts ...Option) *BaseTable {
 		refreshInterval: 5,
 		skipRemote:      false,
 		skipEnv:         false,
+		envPrefix:       "MILVUS_",
 	}
 	for _, opt := range opts {
 		opt(defaultConfig)
@@ -142,31 +153,60 @@ func (bt *BaseTable) init() {
 			log.Warn("init baseTable with env failed", zap.Error(err))
 			return
 		}
+		if bt.config.envPrefix != "" {
+			// the prefixed env vars are set explicitly for milvus, so they override the others
+			err = bt.mgr.AddSource(config.NewEnvSourceWithInfo(&config.EnvInfo{
+				KeyFormatter: formatter,
+				KeyPrefix:    bt.config.envPrefix,
+				Priority:     config.NormalPriority - 1,
+			}))
+			if err != nil {
+				log.Warn("init baseTable with prefixed env failed", zap.String("prefix", bt.config.envPrefix), zap.Error(err))
+				return
+			}
+		}
 	}
 	bt.initConfigsFromLocal()
 	if !bt.config.skipRemote {
 		bt.initConfigsFromRemote()
 	}
+	bt.mgr.LoadAliases()
 }
 
 func (bt *BaseTable) initConfigsFromLocal() {
 	refreshInterval := bt.config.refreshInterval
-	err := bt.mgr.AddSource(config.NewFileSource(&config.FileInfo{
+	fileInfo := &config.FileInfo{
 		Files: lo.Map(bt.config.yamlFiles, func(file string, _ int) string {
 			return path.Join(bt.config.configDir, file)
 		}),
 		RefreshInterval: time.Duration(refreshInterval) * time.Second,
-	}))
+	}
+	fs := config.NewFileSource(fileInfo)
+	err := bt.mgr.AddSource(fs)
 	if err != nil {
 		log.Warn("init baseTable with file failed", zap.Strings("configFile", bt.config.yamlFiles), zap.Error(err))
 		return
 	}
+
+	// the option may be set in the files, so it's only known after they are loaded
+	fileWatch := ParamItem{
+		Key:          "common.configFileWatch",
+		Version:      "2.4.0",
+		DefaultValue: "false",
+		Doc:          "Whether to watch the config files to reload them as soon as they change, e.g. the ConfigMap mounted in Kubernetes",
+	}
+	fileWatch.Init(bt.mgr)
+	if refreshInterval > 0 && fileWatch.GetAsBool() {
+		fileInfo.Watch = true
+		fs.UpdateOptions(config.Options{FileInfo: fileInfo})
+	}
 }
 
 func (bt *BaseTable) initConfigsFromRemote() {
 	refreshInterval := bt.config.refreshInterval
 	etcdConfig := EtcdConfig{}
 	etcdConfig.Init(bt)
+	bt.mgr.SetHistorySize(etcdConfig.ConfigHistorySize.GetAsInt())
 	etcdConfig.Endpoints.PanicIfEmpty = false
 	etcdConfig.RootPath.PanicIfEmpty = false
 	if etcdConfig.Endpoints.GetValue() == "" {
@@ -176,15 +216,28 @@ func (bt *BaseTable) initConfigsFromRemote() {
 		return
 	}
 	info := &config.EtcdInfo{
-		UseEmbed:        etcdConfig.UseEmbedEtcd.GetAsBool(),
-		UseSSL:          etcdConfig.EtcdUseSSL.GetAsBool(),
-		Endpoints:       etcdConfig.Endpoints.GetAsStrings(),
-		CertFile:        etcdConfig.EtcdTLSCert.GetValue(),
-		KeyFile:         etcdConfig.EtcdTLSKey.GetValue(),
-		CaCertFile:      etcdConfig.EtcdTLSCACert.GetValue(),
-		MinVersion:      etcdConfig.EtcdTLSMinVersion.GetValue(),
-		KeyPrefix:       etcdConfig.RootPath.GetValue(),
-		RefreshInterval: time.Duration(refreshInterval) * time.Second,
+		UseEmbed:          etcdConfig.UseEmbedEtcd.GetAsBool(),
+		UseSSL:            etcdConfig.EtcdUseSSL.GetAsBool(),
+		Endpoints:         etcdConfig.Endpoints.GetAsStrings(),
+		CertFile:          etcdConfig.EtcdTLSCert.GetValue(),
+		KeyFile:           etcdConfig.EtcdTLSKey.GetValue(),
+		CaCertFile:        etcdConfig.EtcdTLSCACert.GetValue(),
+		MinVersion:        etcdConfig.EtcdTLSMinVersion.GetValue(),
+		EnableAuth:        etcdConfig.EtcdEnableAuth.GetAsBool(),
+		Username:          etcdConfig.EtcdAuthUserName.GetValue(),
+		Password:          etcdConfig.EtcdAuthPassword.GetValue(),
+		DialTimeout:       etcdConfig.DialTimeout.GetAsDuration(time.Millisecond),
+		KeepAliveTime:     etcdConfig.KeepAliveTime.GetAsDuration(time.Millisecond),
+		KeepAliveTimeout:  etcdConfig.KeepAliveTimeout.GetAsDuration(time.Millisecond),
+		RequestTimeout:    etcdConfig.RequestTimeout.GetAsDuration(time.Millisecond),
+		PageSize:          etcdConfig.ConfigPageSize.GetAsInt64(),
+		BlobSuffixes:      etcdConfig.ConfigBlobSuffix.GetAsStrings(),
+		CompressThreshold: etcdConfig.CompressThreshold.GetAsInt(),
+		Decryptor:         newConfigDecryptor(etcdConfig.DecryptionKeyFile.GetValue()),
+		KeyPrefix:         etcdConfig.RootPath.GetValue(),
+		RefreshInterval:   time.Duration(refreshInterval) * time.Second,
+		RefreshJitter:     etcdConfig.RefreshJitter.GetAsFloat(),
+		Watch:             etcdConfig.ConfigWatch.GetAsBool(),
 	}
 
 	s, err := config.NewEtcdSource(info)
@@ -193,7 +246,24 @@ func (bt *BaseTable) initConfigsFromRemote() {
 		return
 	}
 	bt.mgr.AddSource(s)
-	s.SetEventHandler(bt.mgr)
+}
+
+// newConfigDecryptor returns the decryptor of the key in the file, or in the env if the file is not set, nil if neither.
+func newConfigDecryptor(keyFile string) config.Decryptor {
+	var decryptor *config.AESGCMDecryptor
+	var err error
+	if keyFile != "" {
+		decryptor, err = config.NewAESGCMDecryptorFromFile(keyFile)
+	} else if _, ok := os.LookupEnv(config.DecryptionKeyEnv); ok {
+		decryptor, err = config.NewAESGCMDecryptorFromEnv(config.DecryptionKeyEnv)
+	} else {
+		return nil
+	}
+	if err != nil {
+		log.Warn("failed to init config decryptor, the encrypted configurations are left out", zap.Error(err))
+		return nil
+	}
+	return decryptor
 }
 
 // GetConfigDir returns the config directory
@@ -223,6 +293,57 @@ func (bt *BaseTable) FileConfigs() map[string]string {
 	return bt.mgr.FileConfigs()
 }
 
+// DescribeConfigs describes the effective configs and where they're from, see config.Manager.DescribeAll
+func (bt *BaseTable) DescribeConfigs() map[string]config.ConfigDescription {
+	return bt.mgr.DescribeAll()
+}
+
+// DescribeConfig describes the effective config of the key, see config.Manager.Describe
+func (bt *BaseTable) DescribeConfig(key string) (config.ConfigDescription, bool) {
+	value, source, priority, lastUpdated := bt.mgr.Describe(key)
+	return config.ConfigDescription{Value: value, Source: source, Priority: priority, LastUpdated: lastUpdated}, source != ""
+}
+
+// SetAlias sets the deprecated keys of the renamed key, which are got if the key is not set, see config.Manager.SetAlias
+func (bt *BaseTable) SetAlias(key string, deprecatedKeys ...string) {
+	bt.mgr.SetAlias(key, deprecatedKeys...)
+}
+
+// ConfigHistory returns the recent changes of configs since the time whose keys start with the prefix, see config.Manager.History
+func (bt *BaseTable) ConfigHistory(since time.Time, keyPrefix string) []config.ChangeRecord {
+	return bt.mgr.History(since, keyPrefix)
+}
+
+// Manager returns the config manager, whose typed getters parse the values canonically, e.g. config.Manager.GetInt64
+func (bt *BaseTable) Manager() *config.Manager {
+	return bt.mgr
+}
+
+// ConfigHealth returns the health of each config source by the source name, see config.Manager.Health
+func (bt *BaseTable) ConfigHealth() map[string]config.SourceHealth {
+	return bt.mgr.Health()
+}
+
+// ForceRefreshConfigs refreshes the configs of the remote sources at once, see config.Manager.ForceRefresh
+func (bt *BaseTable) ForceRefreshConfigs(ctx context.Context) error {
+	return bt.mgr.ForceRefresh(ctx)
+}
+
+// FreezeConfigs holds the changes of configs until UnfreezeConfigs, see config.Manager.Freeze
+func (bt *BaseTable) FreezeConfigs() bool {
+	return bt.mgr.Freeze()
+}
+
+// UnfreezeConfigs applies the changes of configs held since FreezeConfigs, see config.Manager.Unfreeze
+func (bt *BaseTable) UnfreezeConfigs() error {
+	return bt.mgr.Unfreeze()
+}
+
+// ConfigFreezeStatus returns whether the configs are frozen, see config.Manager.FreezeStatus
+func (bt *BaseTable) ConfigFreezeStatus() config.FreezeStatus {
+	return bt.mgr.FreezeStatus()
+}
+
 func (bt *BaseTable) UpdateSourceOptions(opts ...config.Option) {
 	bt.mgr.UpdateSourceOptions(opts...)
 }
@@ -12,9 +12,12 @@
 package paramtable
 
 import (
+	"encoding/base64"
 	"os"
+	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -109,6 +112,53 @@ func TestBaseTable_Get(t *testing.T) {
 	assert.Equal(t, "", v2)
 }
 
+func TestBaseTable_DescribeConfigs(t *testing.T) {
+	err := baseParams.Save("describe.key", "10")
+	assert.NoError(t, err)
+	defer baseParams.Reset("describe.key")
+
+	description, ok := baseParams.DescribeConfig("describe.key")
+	assert.True(t, ok)
+	assert.Equal(t, "10", description.Value)
+	assert.Equal(t, config.OverlaySourceName, description.Source)
+	assert.Equal(t, description, baseParams.DescribeConfigs()["describekey"])
+
+	description, ok = baseParams.DescribeConfig("common.defaultPartitionName")
+	assert.True(t, ok)
+	assert.Equal(t, "FileSource", description.Source)
+	assert.Equal(t, config.LowPriority, description.Priority)
+	assert.False(t, description.LastUpdated.IsZero())
+
+	_, ok = baseParams.DescribeConfig("describe.none")
+	assert.False(t, ok)
+}
+
+func TestBaseTable_Alias(t *testing.T) {
+	baseParams.Save("alias.old", "old")
+	defer baseParams.Reset("alias.old")
+	baseParams.SetAlias("alias.new", "alias.old")
+	defer baseParams.SetAlias("alias.new")
+	assert.Equal(t, "old", baseParams.Get("alias.new"))
+
+	baseParams.Save("alias.new", "new")
+	defer baseParams.Reset("alias.new")
+	assert.Equal(t, "new", baseParams.Get("alias.new"))
+}
+
+func TestBaseTable_ConfigHistory(t *testing.T) {
+	assert.NotNil(t, baseParams.ConfigHistory(time.Time{}, ""))
+	assert.Empty(t, baseParams.ConfigHistory(time.Now().Add(time.Hour), ""))
+}
+
+func TestBaseTable_ConfigHealth(t *testing.T) {
+	health := baseParams.ConfigHealth()
+	assert.NotEmpty(t, health)
+	for _, h := range health {
+		assert.True(t, h.Connected)
+		assert.False(t, h.Stale(0))
+	}
+}
+
 func TestBaseTable_Pulsar(t *testing.T) {
 	// test PULSAR ADDRESS
 	t.Setenv("PULSAR_ADDRESS", "pulsar://localhost:6650")
@@ -137,6 +187,34 @@ func TestBaseTable_Env(t *testing.T) {
 	baseParams.init()
 	result, _ = baseParams.Load("invalid")
 	assert.Equal(t, result, "xxx=test")
+
+	// the prefixed env vars override the others
+	t.Setenv("PROXY_MAX_NAME_LENGTH", "100")
+	t.Setenv("MILVUS_PROXY_MAXNAMELENGTH", "200")
+	baseParams.init()
+	result, _ = baseParams.Load("proxy.maxNameLength")
+	assert.Equal(t, "200", result)
+}
+
+func TestNewConfigDecryptor(t *testing.T) {
+	assert.Nil(t, newConfigDecryptor(""))
+	assert.Nil(t, newConfigDecryptor(path.Join(t.TempDir(), "not_exist")))
+
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	t.Setenv(config.DecryptionKeyEnv, "invalid")
+	assert.Nil(t, newConfigDecryptor(""))
+	t.Setenv(config.DecryptionKeyEnv, key)
+	assert.NotNil(t, newConfigDecryptor(""))
+
+	keyFile := path.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(keyFile, []byte(key+"\n"), 0o600))
+	decryptor := newConfigDecryptor(keyFile)
+	assert.NotNil(t, decryptor)
+	ciphertext, err := decryptor.(*config.AESGCMDecryptor).Encrypt("secret")
+	assert.NoError(t, err)
+	plaintext, err := decryptor.Decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", plaintext)
 }
 
 func TestNewBaseTableFromYamlOnly(t *testing.T) {
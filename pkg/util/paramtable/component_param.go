@@ -1009,6 +1009,55 @@ type proxyConfig struct {
 	RetryTimesOnReplica          ParamItem `refreshable:"true"`
 	RetryTimesOnHealthCheck      ParamItem `refreshable:"true"`
 	PartitionNameRegexp          ParamItem `refreshable:"true"`
+	DeletePlanCacheSize          ParamItem `refreshable:"false"`
+	DeleteIdempotencyCacheSize   ParamItem `refreshable:"false"`
+	DeleteIdempotencyTTL         ParamItem `refreshable:"false"`
+	DeleteChunkSize              ParamItem `refreshable:"true"`
+	DeleteBufferMemoryLimit      ParamItem `refreshable:"true"`
+	SlowDeleteThreshold          ParamItem `refreshable:"true"`
+	DeleteProduceMaxRetries      ParamItem `refreshable:"true"`
+	DeleteProduceRetryInterval   ParamItem `refreshable:"true"`
+	DeleteAuditEnabled           ParamItem `refreshable:"true"`
+	DeleteGuaranteeTsTolerance   ParamItem `refreshable:"true"`
+	DeleteParallelHashThreshold  ParamItem `refreshable:"true"`
+	DeletePartitionKeyOverride   ParamItem `refreshable:"true"`
+	MaxDeleteRowsPerRequest      ParamItem `refreshable:"true"`
+	DeleteQueryStreamMaxRetries  ParamItem `refreshable:"true"`
+	HybridSearchDefaultRankType  ParamItem `refreshable:"true"`
+	HybridSearchDefaultRRFK      ParamItem `refreshable:"true"`
+	HybridSearchDefaultWeights   ParamItem `refreshable:"true"`
+	HybridSearchStrictRankParams ParamItem `refreshable:"true"`
+	ChannelExecuteConcurrency    ParamItem `refreshable:"true"`
+	DmlQueueLargeTaskRows        ParamItem `refreshable:"true"`
+	DmlQueueSmallTaskWeight      ParamItem `refreshable:"true"`
+	IDPrefetchWindow             ParamItem `refreshable:"true"`
+	IDPrefetchMinCount           ParamItem `refreshable:"true"`
+	IDPrefetchMaxCount           ParamItem `refreshable:"true"`
+	TsoBatchWindow               ParamItem `refreshable:"true"`
+	TsoBatchMaxSize              ParamItem `refreshable:"true"`
+	DmlStreamIdleTTL             ParamItem `refreshable:"true"`
+	DmlStreamSweepInterval       ParamItem `refreshable:"false"`
+	MutationAuditEnabled         ParamItem `refreshable:"true"`
+	MutationAuditSampleRate      ParamItem `refreshable:"true"`
+	MutationAuditBufferSize      ParamItem `refreshable:"false"`
+	DeleteAdmissionTimeout       ParamItem `refreshable:"true"`
+	TimeTickStallThreshold       ParamItem `refreshable:"true"`
+	AllocatorBreakerThreshold    ParamItem `refreshable:"true"`
+	AllocatorBreakerCoolDown     ParamItem `refreshable:"true"`
+	ChannelAffinityEnabled       ParamItem `refreshable:"true"`
+	ChannelAffinityTTL           ParamItem `refreshable:"true"`
+	DmlChannelFailureWindow      ParamItem `refreshable:"true"`
+	DmlQueueMaxPendingTasks      ParamItem `refreshable:"true"`
+	DmlQueueMaxPendingRows       ParamItem `refreshable:"true"`
+	CollectionNotFoundCacheTTL   ParamItem `refreshable:"true"`
+	CollectionNotFoundCacheSize  ParamItem `refreshable:"true"`
+	DmlProduceMetricsEnabled     ParamItem `refreshable:"true"`
+	DmlProduceMaxLatencyWindow   ParamItem `refreshable:"true"`
+	DmlQueueFairScheduling       ParamItem `refreshable:"true"`
+	DmlQueueFairWeights          ParamItem `refreshable:"true"`
+	DeleteTaskBufferSize         ParamItem `refreshable:"true"`
+	LoadAwareSelectionEnabled    ParamItem `refreshable:"true"`
+	TaskRegistryRetention        ParamItem `refreshable:"true"`
 
 	AccessLog AccessLogConfig
 }
@@ -1319,6 +1368,419 @@ please adjust in embedded Milvus: false`,
 		Doc:          "switch for whether proxy shall use partition name as regexp when searching",
 	}
 	p.PartitionNameRegexp.Init(base.mgr)
+
+	p.DeletePlanCacheSize = ParamItem{
+		Key:          "proxy.deletePlanCacheSize",
+		Version:      "2.4.0",
+		DefaultValue: "1024",
+		Doc:          "max number of compiled delete expression plans cached in proxy, 0 to disable the cache",
+	}
+	p.DeletePlanCacheSize.Init(base.mgr)
+
+	p.DeleteIdempotencyCacheSize = ParamItem{
+		Key:          "proxy.deleteIdempotencyCacheSize",
+		Version:      "2.4.0",
+		DefaultValue: "1024",
+		Doc:          "max number of delete results kept per proxy for idempotent retries, 0 to disable",
+	}
+	p.DeleteIdempotencyCacheSize.Init(base.mgr)
+
+	p.DeleteIdempotencyTTL = ParamItem{
+		Key:          "proxy.deleteIdempotencyTTL",
+		Version:      "2.4.0",
+		DefaultValue: "60",
+		Doc:          "seconds, how long the result of a delete with idempotency key is kept for retries",
+	}
+	p.DeleteIdempotencyTTL.Init(base.mgr)
+
+	p.DeleteChunkSize = ParamItem{
+		Key:          "proxy.deleteChunkSize",
+		Version:      "2.4.0",
+		DefaultValue: "50000",
+		Doc: `max number of primary keys in a single delete task, larger query results of delete are split,
+0 disables the split, safe range [0, 1000000], applies to the deletes started since changed`,
+	}
+	p.DeleteChunkSize.Init(base.mgr)
+
+	p.DeleteBufferMemoryLimit = ParamItem{
+		Key:          "proxy.deleteBufferMemoryLimit",
+		Version:      "2.4.0",
+		DefaultValue: "268435456",
+		Doc:          "bytes, max memory of primary keys buffered by complex deletes in proxy, query results are throttled once exceeded",
+	}
+	p.DeleteBufferMemoryLimit.Init(base.mgr)
+
+	p.SlowDeleteThreshold = ParamItem{
+		Key:          "proxy.slowDeleteThreshold",
+		Version:      "2.4.0",
+		DefaultValue: "5000",
+		Doc:          "ms, deletes taking longer than this are logged as slow delete",
+	}
+	p.SlowDeleteThreshold.Init(base.mgr)
+
+	p.DeleteProduceMaxRetries = ParamItem{
+		Key:          "proxy.deleteProduceMaxRetries",
+		Version:      "2.4.0",
+		DefaultValue: "3",
+		Doc: `max number of retries when producing delete msgs fails with transient mq errors, 0 to disable,
+safe range [0, 10], applies to the deletes started since changed`,
+	}
+	p.DeleteProduceMaxRetries.Init(base.mgr)
+
+	p.DeleteProduceRetryInterval = ParamItem{
+		Key:          "proxy.deleteProduceRetryInterval",
+		Version:      "2.4.0",
+		DefaultValue: "100",
+		Doc: `ms, the initial backoff of retrying to produce delete msgs, doubled on each retry,
+safe range [0, 5000], applies to the deletes started since changed`,
+	}
+	p.DeleteProduceRetryInterval.Init(base.mgr)
+
+	p.DeleteAuditEnabled = ParamItem{
+		Key:          "proxy.deleteAuditEnabled",
+		Version:      "2.4.0",
+		DefaultValue: "false",
+		Doc:          "whether to write an audit log for each delete, including the user, collection and expr",
+	}
+	p.DeleteAuditEnabled.Init(base.mgr)
+
+	p.DeleteGuaranteeTsTolerance = ParamItem{
+		Key:          "proxy.deleteGuaranteeTsTolerance",
+		Version:      "2.4.0",
+		DefaultValue: "5000",
+		Doc:          "ms, how far the guarantee timestamp carried by a delete may be ahead of proxy's timestamp",
+	}
+	p.DeleteGuaranteeTsTolerance.Init(base.mgr)
+
+	p.DeleteParallelHashThreshold = ParamItem{
+		Key:          "proxy.deleteParallelHashThreshold",
+		Version:      "2.4.0",
+		DefaultValue: "100000",
+		Doc:          "primary keys of a delete task are hashed to channels in parallel if more than this, 0 to disable",
+	}
+	p.DeleteParallelHashThreshold.Init(base.mgr)
+
+	p.DeletePartitionKeyOverride = ParamItem{
+		Key:          "proxy.deletePartitionKeyOverride",
+		Version:      "2.4.0",
+		DefaultValue: "false",
+		Doc:          "whether deletes may specify a partition name in partition key mode, if requested explicitly, for recovery only",
+	}
+	p.DeletePartitionKeyOverride.Init(base.mgr)
+
+	p.MaxDeleteRowsPerRequest = ParamItem{
+		Key:          "proxy.maxDeleteRowsPerRequest",
+		Version:      "2.4.0",
+		DefaultValue: "0",
+		Doc:          "maximum number of rows a delete by expression can delete, the delete fails once exceeded, 0 means unlimited",
+	}
+	p.MaxDeleteRowsPerRequest.Init(base.mgr)
+
+	p.DeleteQueryStreamMaxRetries = ParamItem{
+		Key:          "proxy.deleteQueryStreamMaxRetries",
+		Version:      "2.4.0",
+		DefaultValue: "3",
+		Doc: `maximum times to re-issue the query stream of a channel in complex delete on retriable error statuses,
+the re-issued one returns the rows in the same order, the ones already deleted are skipped by their number, 0 disables it,
+safe range [0, 10], applies to the deletes started since changed`,
+	}
+	p.DeleteQueryStreamMaxRetries.Init(base.mgr)
+
+	p.HybridSearchDefaultRankType = ParamItem{
+		Key:          "proxy.hybridSearch.defaultRankType",
+		Version:      "2.4.0",
+		DefaultValue: "rrf",
+		Doc: `rank strategy of the hybrid searches not specifying one, e.g. rrf or weighted,
+the searches fall back to rrf of the default k if it's invalid`,
+	}
+	p.HybridSearchDefaultRankType.Init(base.mgr)
+
+	p.HybridSearchDefaultRRFK = ParamItem{
+		Key:          "proxy.hybridSearch.defaultRRFK",
+		Version:      "2.4.0",
+		DefaultValue: "60",
+		Doc:          "k of the default rrf rank strategy of the hybrid searches, in range (0, 16384)",
+	}
+	p.HybridSearchDefaultRRFK.Init(base.mgr)
+
+	p.HybridSearchDefaultWeights = ParamItem{
+		Key:          "proxy.hybridSearch.defaultWeights",
+		Version:      "2.4.0",
+		DefaultValue: "",
+		Doc: `comma separated weights of the requests of the default weighted rank strategy of the hybrid searches,
+the requests are weighted by 1 equally if empty, or the searches of the other numbers of requests fall back to rrf`,
+	}
+	p.HybridSearchDefaultWeights.Init(base.mgr)
+
+	p.HybridSearchStrictRankParams = ParamItem{
+		Key:          "proxy.hybridSearch.strictRankParams",
+		Version:      "2.4.0",
+		DefaultValue: "false",
+		Doc: `reject the unknown keys of the rank params of the hybrid searches, e.g. the misspelled ones,
+which could be overridden by the strict rank param of each search`,
+	}
+	p.HybridSearchStrictRankParams.Init(base.mgr)
+
+	p.ChannelExecuteConcurrency = ParamItem{
+		Key:          "proxy.channelExecuteConcurrency",
+		Version:      "2.4.0",
+		DefaultValue: "0",
+		Doc: `maximum number of channels of a collection executed in parallel by a search, query or delete,
+which could be overridden by each workload, 0 means all the channels are executed in parallel`,
+	}
+	p.ChannelExecuteConcurrency.Init(base.mgr)
+
+	p.DmlQueueLargeTaskRows = ParamItem{
+		Key:          "proxy.dmlQueue.largeTaskRows",
+		Version:      "2.4.0",
+		DefaultValue: "10000",
+		Doc: `minimum rows of the large insert, upsert or delete tasks, the small ones could be issued ahead of
+the large ones of other collections queued before them, 0 issues all the tasks in timestamp order`,
+	}
+	p.DmlQueueLargeTaskRows.Init(base.mgr)
+
+	p.DmlQueueSmallTaskWeight = ParamItem{
+		Key:          "proxy.dmlQueue.smallTaskWeight",
+		Version:      "2.4.0",
+		DefaultValue: "8",
+		Doc:          "maximum number of small dml tasks issued ahead of the queued large tasks in a row",
+	}
+	p.DmlQueueSmallTaskWeight.Init(base.mgr)
+
+	p.IDPrefetchWindow = ParamItem{
+		Key:          "proxy.idPrefetch.window",
+		Version:      "2.4.0",
+		DefaultValue: "1000",
+		Doc:          "time window of the id allocations covered by the ids prefetched from root coord, by the recent allocation rate, in ms",
+	}
+	p.IDPrefetchWindow.Init(base.mgr)
+
+	p.IDPrefetchMinCount = ParamItem{
+		Key:          "proxy.idPrefetch.minCount",
+		Version:      "2.4.0",
+		DefaultValue: "1000",
+		Doc:          "minimum number of ids prefetched from root coord at a time",
+	}
+	p.IDPrefetchMinCount.Init(base.mgr)
+
+	p.IDPrefetchMaxCount = ParamItem{
+		Key:          "proxy.idPrefetch.maxCount",
+		Version:      "2.4.0",
+		DefaultValue: "200000",
+		Doc:          "maximum number of ids prefetched from root coord at a time, 0 disables the prefetching",
+	}
+	p.IDPrefetchMaxCount.Init(base.mgr)
+
+	p.TsoBatchWindow = ParamItem{
+		Key:          "proxy.tsoBatch.window",
+		Version:      "2.4.0",
+		DefaultValue: "500",
+		Doc: `maximum time the concurrent timestamp allocations wait to be batched into one allocation from root coord,
+while another allocation is in flight, in microseconds, 0 disables the batching`,
+	}
+	p.TsoBatchWindow.Init(base.mgr)
+
+	p.TsoBatchMaxSize = ParamItem{
+		Key:          "proxy.tsoBatch.maxSize",
+		Version:      "2.4.0",
+		DefaultValue: "64",
+		Doc:          "maximum number of the timestamp allocations batched, the batch is allocated at once when it's full",
+	}
+	p.TsoBatchMaxSize.Init(base.mgr)
+
+	p.DmlStreamIdleTTL = ParamItem{
+		Key:          "proxy.dmlStream.idleTTL",
+		Version:      "2.4.0",
+		DefaultValue: "1800",
+		Doc:          "the dml stream of a collection not produced for longer than it is closed, and recreated on next use, in seconds, 0 disables the closing",
+	}
+	p.DmlStreamIdleTTL.Init(base.mgr)
+
+	p.DmlStreamSweepInterval = ParamItem{
+		Key:          "proxy.dmlStream.sweepInterval",
+		Version:      "2.4.0",
+		DefaultValue: "60",
+		Doc:          "interval to close the idle dml streams, in seconds",
+	}
+	p.DmlStreamSweepInterval.Init(base.mgr)
+
+	p.MutationAuditEnabled = ParamItem{
+		Key:          "proxy.mutationAudit.enabled",
+		Version:      "2.4.0",
+		DefaultValue: "false",
+		Doc:          "whether to write a structured audit record of each delete to the mutation.audit logger",
+	}
+	p.MutationAuditEnabled.Init(base.mgr)
+
+	p.MutationAuditSampleRate = ParamItem{
+		Key:          "proxy.mutationAudit.sampleRate",
+		Version:      "2.4.0",
+		DefaultValue: "1",
+		Doc:          "fraction of the deletes audited, in [0, 1]",
+	}
+	p.MutationAuditSampleRate.Init(base.mgr)
+
+	p.MutationAuditBufferSize = ParamItem{
+		Key:          "proxy.mutationAudit.bufferSize",
+		Version:      "2.4.0",
+		DefaultValue: "1024",
+		Doc:          "max number of audit records waiting to be written, the records beyond it are dropped",
+	}
+	p.MutationAuditBufferSize.Init(base.mgr)
+
+	p.DeleteAdmissionTimeout = ParamItem{
+		Key:          "proxy.deleteAdmissionTimeout",
+		Version:      "2.4.0",
+		DefaultValue: "10000",
+		Doc:          "ms, max time a complex delete waits for the delete buffer of proxy to drain below proxy.deleteBufferMemoryLimit before being rejected",
+	}
+	p.DeleteAdmissionTimeout.Init(base.mgr)
+
+	p.TimeTickStallThreshold = ParamItem{
+		Key:          "proxy.timeTickStallThreshold",
+		Version:      "2.4.0",
+		DefaultValue: "10000",
+		Doc:          "ms, a physical channel is reported stalled if its time tick lags behind now longer than this, 0 to disable",
+	}
+	p.TimeTickStallThreshold.Init(base.mgr)
+
+	p.AllocatorBreakerThreshold = ParamItem{
+		Key:          "proxy.allocatorBreaker.failureThreshold",
+		Version:      "2.4.0",
+		DefaultValue: "5",
+		Doc:          "number of consecutive failures of the id or tso allocations of delete before they fail fast for a cool-down, 0 to disable",
+	}
+	p.AllocatorBreakerThreshold.Init(base.mgr)
+
+	p.AllocatorBreakerCoolDown = ParamItem{
+		Key:          "proxy.allocatorBreaker.coolDown",
+		Version:      "2.4.0",
+		DefaultValue: "3000",
+		Doc:          "ms, the allocations fail fast for this long once the breaker opens, then a probe is let through",
+	}
+	p.AllocatorBreakerCoolDown.Init(base.mgr)
+
+	p.ChannelAffinityEnabled = ParamItem{
+		Key:          "proxy.channelAffinity.enabled",
+		Version:      "2.4.0",
+		DefaultValue: "true",
+		Doc:          "whether the workloads sticky to the delegators, like complex delete, reuse the delegator last succeeded on each channel rather than selecting one again",
+	}
+	p.ChannelAffinityEnabled.Init(base.mgr)
+
+	p.ChannelAffinityTTL = ParamItem{
+		Key:          "proxy.channelAffinity.ttl",
+		Version:      "2.4.0",
+		DefaultValue: "60",
+		Doc:          "seconds, the delegator last succeeded on a channel is reused within this long since then",
+	}
+	p.ChannelAffinityTTL.Init(base.mgr)
+
+	p.DmlChannelFailureWindow = ParamItem{
+		Key:          "proxy.dmlChannelHealth.failureWindow",
+		Version:      "2.4.0",
+		DefaultValue: "300",
+		Doc: `seconds, a physical channel is reported unhealthy by the health check if the last produce to it failed within this long,
+and no produce succeeded after that, 0 means it's reported until a produce succeeds`,
+	}
+	p.DmlChannelFailureWindow.Init(base.mgr)
+
+	p.DmlQueueMaxPendingTasks = ParamItem{
+		Key:          "proxy.dmlQueue.maxPendingTasks",
+		Version:      "2.4.0",
+		DefaultValue: "0",
+		Doc: `maximum number of the dml tasks queued or executing, the ones enqueued beyond it are rejected as the server is overloaded,
+0 means no limit`,
+	}
+	p.DmlQueueMaxPendingTasks.Init(base.mgr)
+
+	p.DmlQueueMaxPendingRows = ParamItem{
+		Key:          "proxy.dmlQueue.maxPendingRows",
+		Version:      "2.4.0",
+		DefaultValue: "0",
+		Doc: `maximum rows hinted by the dml tasks queued or executing, the ones enqueued beyond it are rejected as the server is overloaded,
+a task is always accepted if no other one is pending, 0 means no limit`,
+	}
+	p.DmlQueueMaxPendingRows.Init(base.mgr)
+
+	p.CollectionNotFoundCacheTTL = ParamItem{
+		Key:          "proxy.collectionNotFoundCache.ttl",
+		Version:      "2.4.0",
+		DefaultValue: "3000",
+		Doc: `milliseconds, the collection names not found are remembered for this long, so that the repeated lookups fail fast
+without describing from rootcoord, a collection created in the meantime is visible once its creation is notified or this expires`,
+	}
+	p.CollectionNotFoundCacheTTL.Init(base.mgr)
+
+	p.CollectionNotFoundCacheSize = ParamItem{
+		Key:          "proxy.collectionNotFoundCache.size",
+		Version:      "2.4.0",
+		DefaultValue: "10000",
+		Doc:          "maximum collection names not found to remember, 0 disables the cache",
+	}
+	p.CollectionNotFoundCacheSize.Init(base.mgr)
+
+	p.DmlProduceMetricsEnabled = ParamItem{
+		Key:          "proxy.dmlProduceMetrics.enabled",
+		Version:      "2.4.0",
+		DefaultValue: "true",
+		Doc: `whether to measure the latency and failures of producing the dml msgs to each physical channel,
+the msgs of a request are produced to the channels one by one to be measured if enabled`,
+	}
+	p.DmlProduceMetricsEnabled.Init(base.mgr)
+
+	p.DmlProduceMaxLatencyWindow = ParamItem{
+		Key:          "proxy.dmlProduceMetrics.maxLatencyWindow",
+		Version:      "2.4.0",
+		DefaultValue: "60",
+		Doc:          "seconds, the max produce latency of the slowest channel of each collection is rolled over this window",
+	}
+	p.DmlProduceMaxLatencyWindow.Init(base.mgr)
+
+	p.DmlQueueFairScheduling = ParamItem{
+		Key:          "proxy.dmlQueue.fairScheduling.enabled",
+		Version:      "2.4.0",
+		DefaultValue: "false",
+		Doc: `whether to issue the dml tasks of the collections in weighted round robin rather than in timestamp order,
+so that the backlog of a collection doesn't hold the others back, the tasks of each collection are still issued in timestamp order`,
+	}
+	p.DmlQueueFairScheduling.Init(base.mgr)
+
+	p.DmlQueueFairWeights = ParamItem{
+		Key:          "proxy.dmlQueue.fairScheduling.weights",
+		Version:      "2.4.0",
+		DefaultValue: "",
+		Doc: `weights of the shares of the issued dml tasks, like "db1:4,db2.collection1:8", a collection is weighted by itself
+or else by its database, 1 if neither is listed`,
+	}
+	p.DmlQueueFairWeights.Init(base.mgr)
+
+	p.DeleteTaskBufferSize = ParamItem{
+		Key:          "proxy.deleteTaskBufferSize",
+		Version:      "2.4.0",
+		DefaultValue: "0",
+		Doc: `max number of delete tasks of a channel produced but not finished in complex delete, 0 follows proxy.maxTaskNum,
+safe range [0, 65536], applies to the deletes started since changed`,
+	}
+	p.DeleteTaskBufferSize.Init(base.mgr)
+
+	p.LoadAwareSelectionEnabled = ParamItem{
+		Key:          "proxy.loadAwareSelection.enabled",
+		Version:      "2.4.0",
+		DefaultValue: "true",
+		Doc: `whether the workloads of complex delete prefer the least loaded delegator of each channel,
+by the workloads executing on the delegators and the queue sizes they reported within proxy.costMetricsExpireTime,
+the delegators are selected by proxy.replicaSelectionPolicy and proxy.channelAffinity if disabled`,
+	}
+	p.LoadAwareSelectionEnabled.Init(base.mgr)
+
+	p.TaskRegistryRetention = ParamItem{
+		Key:          "proxy.taskRegistry.retention",
+		Version:      "2.4.0",
+		DefaultValue: "10",
+		Doc:          "seconds, the finished tasks are still listed by /management/proxy/tasks within this long, at most 4096 of them",
+	}
+	p.TaskRegistryRetention.Init(base.mgr)
 }
 
 // /////////////////////////////////////////////////////////////////////////////
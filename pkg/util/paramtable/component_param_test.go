@@ -166,6 +166,70 @@ func TestComponentParam(t *testing.T) {
 		assert.Equal(t, Params.CostMetricsExpireTime.GetAsInt(), 1000)
 		assert.Equal(t, Params.RetryTimesOnReplica.GetAsInt(), 2)
 		assert.EqualValues(t, Params.HealthCheckTimeout.GetAsInt64(), 3000)
+		assert.EqualValues(t, 1024, Params.DeletePlanCacheSize.GetAsInt64())
+		assert.EqualValues(t, 1024, Params.DeleteIdempotencyCacheSize.GetAsInt64())
+		assert.Equal(t, time.Minute, Params.DeleteIdempotencyTTL.GetAsDuration(time.Second))
+		assert.Equal(t, 50000, Params.DeleteChunkSize.GetAsInt())
+		assert.EqualValues(t, 256*1024*1024, Params.DeleteBufferMemoryLimit.GetAsInt64())
+		assert.Equal(t, 5*time.Second, Params.SlowDeleteThreshold.GetAsDuration(time.Millisecond))
+		assert.Equal(t, 3, Params.DeleteProduceMaxRetries.GetAsInt())
+		assert.Equal(t, 100*time.Millisecond, Params.DeleteProduceRetryInterval.GetAsDuration(time.Millisecond))
+		assert.False(t, Params.DeleteAuditEnabled.GetAsBool())
+		assert.Equal(t, 5*time.Second, Params.DeleteGuaranteeTsTolerance.GetAsDuration(time.Millisecond))
+		assert.Equal(t, 100000, Params.DeleteParallelHashThreshold.GetAsInt())
+		assert.False(t, Params.DeletePartitionKeyOverride.GetAsBool())
+		assert.Equal(t, int64(0), Params.MaxDeleteRowsPerRequest.GetAsInt64())
+		assert.Equal(t, 3, Params.DeleteQueryStreamMaxRetries.GetAsInt())
+		assert.Equal(t, "rrf", Params.HybridSearchDefaultRankType.GetValue())
+		assert.Equal(t, 60.0, Params.HybridSearchDefaultRRFK.GetAsFloat())
+		assert.Empty(t, Params.HybridSearchDefaultWeights.GetValue())
+		assert.False(t, Params.HybridSearchStrictRankParams.GetAsBool())
+		assert.Equal(t, 0, Params.ChannelExecuteConcurrency.GetAsInt())
+		assert.EqualValues(t, 10000, Params.DmlQueueLargeTaskRows.GetAsInt64())
+		assert.Equal(t, 8, Params.DmlQueueSmallTaskWeight.GetAsInt())
+		assert.Equal(t, time.Second, Params.IDPrefetchWindow.GetAsDuration(time.Millisecond))
+		assert.EqualValues(t, 1000, Params.IDPrefetchMinCount.GetAsInt64())
+		assert.EqualValues(t, 200000, Params.IDPrefetchMaxCount.GetAsInt64())
+		assert.Equal(t, 500*time.Microsecond, Params.TsoBatchWindow.GetAsDuration(time.Microsecond))
+		assert.Equal(t, 64, Params.TsoBatchMaxSize.GetAsInt())
+		assert.Equal(t, 30*time.Minute, Params.DmlStreamIdleTTL.GetAsDuration(time.Second))
+		assert.Equal(t, time.Minute, Params.DmlStreamSweepInterval.GetAsDuration(time.Second))
+		assert.False(t, Params.MutationAuditEnabled.GetAsBool())
+		assert.Equal(t, 1.0, Params.MutationAuditSampleRate.GetAsFloat())
+		assert.Equal(t, 1024, Params.MutationAuditBufferSize.GetAsInt())
+		assert.Equal(t, 10*time.Second, Params.DeleteAdmissionTimeout.GetAsDuration(time.Millisecond))
+		assert.Equal(t, 10*time.Second, Params.TimeTickStallThreshold.GetAsDuration(time.Millisecond))
+		assert.Equal(t, int64(5), Params.AllocatorBreakerThreshold.GetAsInt64())
+		assert.Equal(t, 3*time.Second, Params.AllocatorBreakerCoolDown.GetAsDuration(time.Millisecond))
+		assert.True(t, Params.ChannelAffinityEnabled.GetAsBool())
+		assert.Equal(t, time.Minute, Params.ChannelAffinityTTL.GetAsDuration(time.Second))
+		assert.Equal(t, 5*time.Minute, Params.DmlChannelFailureWindow.GetAsDuration(time.Second))
+		assert.EqualValues(t, 0, Params.DmlQueueMaxPendingTasks.GetAsInt64())
+		assert.EqualValues(t, 0, Params.DmlQueueMaxPendingRows.GetAsInt64())
+		assert.Equal(t, 3*time.Second, Params.CollectionNotFoundCacheTTL.GetAsDuration(time.Millisecond))
+		assert.EqualValues(t, 10000, Params.CollectionNotFoundCacheSize.GetAsInt())
+		assert.True(t, Params.DmlProduceMetricsEnabled.GetAsBool())
+		assert.Equal(t, time.Minute, Params.DmlProduceMaxLatencyWindow.GetAsDuration(time.Second))
+		assert.False(t, Params.DmlQueueFairScheduling.GetAsBool())
+		assert.Equal(t, "", Params.DmlQueueFairWeights.GetValue())
+		assert.Equal(t, 0, Params.DeleteTaskBufferSize.GetAsInt())
+		assert.True(t, Params.LoadAwareSelectionEnabled.GetAsBool())
+		assert.Equal(t, 10*time.Second, Params.TaskRegistryRetention.GetAsDuration(time.Second))
+
+		// parsed by the canonical parsers of config
+		params.Save(Params.DeleteIdempotencyTTL.Key, "2m")
+		assert.Equal(t, 2*time.Minute, Params.DeleteIdempotencyTTL.GetAsDuration(time.Second))
+		params.Save(Params.DeleteIdempotencyTTL.Key, "2d")
+		assert.Equal(t, time.Duration(0), Params.DeleteIdempotencyTTL.GetAsDuration(time.Second))
+		params.Reset(Params.DeleteIdempotencyTTL.Key)
+		params.Save(Params.DeleteAuditEnabled.Key, " TRUE ")
+		assert.True(t, Params.DeleteAuditEnabled.GetAsBool())
+		params.Reset(Params.DeleteAuditEnabled.Key)
+
+		// refreshable
+		params.Save(Params.ChannelExecuteConcurrency.Key, "4")
+		assert.Equal(t, 4, Params.ChannelExecuteConcurrency.GetAsInt())
+		params.Reset(Params.ChannelExecuteConcurrency.Key)
 	})
 
 	// t.Run("test proxyConfig panic", func(t *testing.T) {
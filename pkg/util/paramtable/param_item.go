@@ -191,18 +191,18 @@ func getAsStrings(v string) []string {
 	}, []string{})
 }
 
+// getAsBool and the ones below parse the values by the canonical parsers of config, e.g. config.ParseBool,
+// the invalid values are parsed as the zero values.
 func getAsBool(v string) bool {
-	return getAndConvert(v, strconv.ParseBool, false)
+	return getAndConvert(v, config.ParseBool, false)
 }
 
 func getAsInt(v string) int {
-	return getAndConvert(v, strconv.Atoi, 0)
+	return int(getAsInt64(v))
 }
 
 func getAsInt64(v string) int64 {
-	return getAndConvert(v, func(value string) (int64, error) {
-		return strconv.ParseInt(value, 10, 64)
-	}, 0)
+	return getAndConvert(v, config.ParseInt64, 0)
 }
 
 func getAsUint64(v string) uint64 {
@@ -212,19 +212,12 @@ func getAsUint64(v string) uint64 {
 }
 
 func getAsFloat(v string) float64 {
-	return getAndConvert(v, func(value string) (float64, error) {
-		return strconv.ParseFloat(value, 64)
-	}, 0.0)
+	return getAndConvert(v, config.ParseFloat64, 0.0)
 }
 
 func getAsDuration(v string, unit time.Duration) time.Duration {
 	return getAndConvert(v, func(value string) (time.Duration, error) {
-		v, err := strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			fv, err := strconv.ParseFloat(value, 64)
-			return time.Duration(fv * float64(unit)), err
-		}
-		return time.Duration(v) * unit, err
+		return config.ParseDuration(value, unit)
 	}, 0)
 }
 
@@ -102,6 +102,20 @@ type EtcdConfig struct {
 	EtcdTLSCACert     ParamItem          `refreshable:"false"`
 	EtcdTLSMinVersion ParamItem          `refreshable:"false"`
 	RequestTimeout    ParamItem          `refreshable:"false"`
+	ConfigWatch       ParamItem          `refreshable:"false"`
+	EtcdEnableAuth    ParamItem          `refreshable:"false"`
+	EtcdAuthUserName  ParamItem          `refreshable:"false"`
+	EtcdAuthPassword  ParamItem          `refreshable:"false"`
+	DialTimeout       ParamItem          `refreshable:"false"`
+	KeepAliveTime     ParamItem          `refreshable:"false"`
+	KeepAliveTimeout  ParamItem          `refreshable:"false"`
+	DecryptionKeyFile ParamItem          `refreshable:"false"`
+	ConfigPageSize    ParamItem          `refreshable:"false"`
+	RefreshJitter     ParamItem          `refreshable:"false"`
+	ConfigBlobSuffix  ParamItem          `refreshable:"false"`
+	CompressThreshold ParamItem          `refreshable:"false"`
+	ConfigHistorySize ParamItem          `refreshable:"false"`
+	ConfigStaleTime   ParamItem          `refreshable:"true"`
 
 	// --- Embed ETCD ---
 	UseEmbedEtcd ParamItem `refreshable:"false"`
@@ -267,6 +281,117 @@ We recommend using version 1.2 and above.`,
 		Export:       true,
 	}
 	p.RequestTimeout.Init(base.mgr)
+
+	p.ConfigWatch = ParamItem{
+		Key:          "etcd.configWatch",
+		DefaultValue: "true",
+		Version:      "2.4.0",
+		Doc: `Whether to watch the configurations in etcd for changes instead of pulling them every refresh interval,
+pulling is still the fallback while the watch is broken`,
+	}
+	p.ConfigWatch.Init(base.mgr)
+
+	p.EtcdEnableAuth = ParamItem{
+		Key:          "etcd.auth.enabled",
+		DefaultValue: "false",
+		Version:      "2.4.0",
+		Doc:          "Whether to authenticate to etcd with RBAC enabled by the user name and password, for the configurations in etcd",
+	}
+	p.EtcdEnableAuth.Init(base.mgr)
+
+	p.EtcdAuthUserName = ParamItem{
+		Key:     "etcd.auth.userName",
+		Version: "2.4.0",
+		Doc:     "User name of etcd authentication",
+	}
+	p.EtcdAuthUserName.Init(base.mgr)
+
+	p.EtcdAuthPassword = ParamItem{
+		Key:     "etcd.auth.password",
+		Version: "2.4.0",
+		Doc:     "Password of etcd authentication",
+	}
+	p.EtcdAuthPassword.Init(base.mgr)
+
+	p.DialTimeout = ParamItem{
+		Key:          "etcd.dialTimeout",
+		DefaultValue: "5000",
+		Version:      "2.4.0",
+		Doc:          "Timeout of connecting to etcd in milliseconds, for the configurations in etcd",
+	}
+	p.DialTimeout.Init(base.mgr)
+
+	p.KeepAliveTime = ParamItem{
+		Key:          "etcd.keepAliveTime",
+		DefaultValue: "10000",
+		Version:      "2.4.0",
+		Doc:          "Interval of pinging etcd to keep the connection alive in milliseconds, for the configurations in etcd",
+	}
+	p.KeepAliveTime.Init(base.mgr)
+
+	p.KeepAliveTimeout = ParamItem{
+		Key:          "etcd.keepAliveTimeout",
+		DefaultValue: "3000",
+		Version:      "2.4.0",
+		Doc:          "Timeout of pinging etcd before closing the connection in milliseconds, for the configurations in etcd",
+	}
+	p.KeepAliveTimeout.Init(base.mgr)
+
+	p.DecryptionKeyFile = ParamItem{
+		Key:     "etcd.decryptionKeyFile",
+		Version: "2.4.0",
+		Doc: `File of the base64 encoded AES key to decrypt the configurations in etcd with the "enc:" prefix,
+the key is read from the env MILVUS_CONFIG_DECRYPTION_KEY if not set`,
+	}
+	p.DecryptionKeyFile.Init(base.mgr)
+
+	p.ConfigPageSize = ParamItem{
+		Key:          "etcd.configPageSize",
+		DefaultValue: "1000",
+		Version:      "2.4.0",
+		Doc:          "Max keys read by each request of refreshing the configurations in etcd, no limit if negative",
+	}
+	p.ConfigPageSize.Init(base.mgr)
+
+	p.RefreshJitter = ParamItem{
+		Key:          "etcd.configRefreshJitter",
+		DefaultValue: "0.1",
+		Version:      "2.4.0",
+		Doc:          "Jitter of the interval refreshing the configurations in etcd as a fraction of it, to spread the refreshing of nodes, no jitter if negative",
+	}
+	p.RefreshJitter.Init(base.mgr)
+
+	p.ConfigBlobSuffix = ParamItem{
+		Key:          "etcd.configBlobSuffix",
+		DefaultValue: ".yaml,.yml,.json",
+		Version:      "2.4.0",
+		Doc:          "Suffixes of the keys in etcd whose values are YAML or JSON documents of configurations, which are flattened into keys",
+	}
+	p.ConfigBlobSuffix.Init(base.mgr)
+
+	p.CompressThreshold = ParamItem{
+		Key:          "etcd.configCompressThreshold",
+		DefaultValue: "65536",
+		Version:      "2.4.0",
+		Doc:          "Bytes above which the configurations written to etcd are compressed, no compression if negative, the compressed ones are always decompressed",
+	}
+	p.CompressThreshold.Init(base.mgr)
+
+	p.ConfigHistorySize = ParamItem{
+		Key:          "etcd.configHistorySize",
+		DefaultValue: "1000",
+		Version:      "2.4.0",
+		Doc:          "Max recent changes of the configurations kept for the debug endpoints, the values encrypted are redacted",
+	}
+	p.ConfigHistorySize.Init(base.mgr)
+
+	p.ConfigStaleTime = ParamItem{
+		Key:          "etcd.configStaleTime",
+		DefaultValue: "60",
+		Version:      "2.4.0",
+		Doc:          "Seconds the configurations could fail to refresh before the health check reports them stale, the stale ones are still served",
+	}
+	p.ConfigStaleTime.Init(base.mgr)
 }
 
 // /////////////////////////////////////////////////////////////////////////////
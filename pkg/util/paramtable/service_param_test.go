@@ -52,6 +52,21 @@ func TestServiceParam(t *testing.T) {
 		assert.NotEmpty(t, Params.EtcdTLSMinVersion.GetValue())
 		t.Logf("tls minVersion = %s", Params.EtcdTLSMinVersion.GetValue())
 
+		assert.True(t, Params.ConfigWatch.GetAsBool())
+		assert.False(t, Params.EtcdEnableAuth.GetAsBool())
+		assert.Empty(t, Params.EtcdAuthUserName.GetValue())
+		assert.Empty(t, Params.EtcdAuthPassword.GetValue())
+		assert.Equal(t, 5*time.Second, Params.DialTimeout.GetAsDuration(time.Millisecond))
+		assert.Equal(t, 10*time.Second, Params.KeepAliveTime.GetAsDuration(time.Millisecond))
+		assert.Equal(t, 3*time.Second, Params.KeepAliveTimeout.GetAsDuration(time.Millisecond))
+		assert.Empty(t, Params.DecryptionKeyFile.GetValue())
+		assert.Equal(t, int64(1000), Params.ConfigPageSize.GetAsInt64())
+		assert.Equal(t, 0.1, Params.RefreshJitter.GetAsFloat())
+		assert.Equal(t, []string{".yaml", ".yml", ".json"}, Params.ConfigBlobSuffix.GetAsStrings())
+		assert.Equal(t, 65536, Params.CompressThreshold.GetAsInt())
+		assert.Equal(t, 1000, Params.ConfigHistorySize.GetAsInt())
+		assert.Equal(t, time.Minute, Params.ConfigStaleTime.GetAsDuration(time.Second))
+
 		// test UseEmbedEtcd
 		t.Setenv("etcd.use.embed", "true")
 		t.Setenv(metricsinfo.DeployModeEnvKey, metricsinfo.ClusterDeployMode)
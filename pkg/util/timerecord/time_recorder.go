@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
@@ -27,6 +28,11 @@ type TimeRecorder struct {
 	header string
 	start  time.Time
 	last   time.Time
+
+	// histogram observes the spans of the named checkpoints, nil if metrics are disabled
+	histogram *prometheus.HistogramVec
+	// labels are the label values of histogram followed by the name of checkpoint
+	labels []string
 }
 
 // NewTimeRecorder creates a new TimeRecorder
@@ -48,6 +54,19 @@ func NewTimeRecorderWithTrace(ctx context.Context, header string) *TimeRecorder
 	}
 }
 
+// NewTimeRecorderWithMetrics creates a new TimeRecorder which observes the spans of the named checkpoints
+// into histogram in milliseconds, labeled by labels followed by the name of the checkpoint.
+// It works as a plain TimeRecorder if histogram is nil.
+func NewTimeRecorderWithMetrics(header string, histogram *prometheus.HistogramVec, labels ...string) *TimeRecorder {
+	tr := NewTimeRecorder(header)
+	if histogram != nil {
+		tr.histogram = histogram
+		tr.labels = make([]string, len(labels)+1)
+		copy(tr.labels, labels)
+	}
+	return tr
+}
+
 // RecordSpan returns the duration from last record
 func (tr *TimeRecorder) RecordSpan() time.Duration {
 	curr := time.Now()
@@ -64,6 +83,28 @@ func (tr *TimeRecorder) ElapseSpan() time.Duration {
 	return span
 }
 
+// RecordSpanAs returns the duration from last record, and observes it as the named checkpoint
+func (tr *TimeRecorder) RecordSpanAs(checkpoint string) time.Duration {
+	span := tr.RecordSpan()
+	tr.observe(checkpoint, span)
+	return span
+}
+
+// ElapseSpanAs returns the duration from the beginning, and observes it as the named checkpoint
+func (tr *TimeRecorder) ElapseSpanAs(checkpoint string) time.Duration {
+	span := tr.ElapseSpan()
+	tr.observe(checkpoint, span)
+	return span
+}
+
+func (tr *TimeRecorder) observe(checkpoint string, span time.Duration) {
+	if tr.histogram == nil {
+		return
+	}
+	tr.labels[len(tr.labels)-1] = checkpoint
+	tr.histogram.WithLabelValues(tr.labels...).Observe(float64(span) / float64(time.Millisecond))
+}
+
 // Record calculates the time span from previous Record call
 func (tr *TimeRecorder) Record(msg string) time.Duration {
 	span := tr.RecordSpan()
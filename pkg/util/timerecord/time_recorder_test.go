@@ -0,0 +1,90 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timerecord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatherHistograms scrapes the registry, and returns the histograms by the value of label checkpoint.
+func gatherHistograms(t *testing.T, registry *prometheus.Registry) map[string]*dto.Metric {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	histograms := make(map[string]*dto.Metric)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "checkpoint" {
+					histograms[label.GetValue()] = m
+				}
+			}
+		}
+	}
+	return histograms
+}
+
+func TestTimeRecorderWithMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "test_checkpoint_latency",
+	}, []string{"node_id", "checkpoint"})
+	registry.MustRegister(histogram)
+
+	tr := NewTimeRecorderWithMetrics("test", histogram, "1")
+	time.Sleep(10 * time.Millisecond)
+	prepare := tr.RecordSpanAs("prepare")
+	time.Sleep(10 * time.Millisecond)
+	produce := tr.RecordSpanAs("produce")
+	tr.RecordSpan()
+	tr.Record("not observed")
+	total := tr.ElapseSpanAs("total")
+
+	histograms := gatherHistograms(t, registry)
+	assert.Len(t, histograms, 3)
+	for checkpoint, span := range map[string]time.Duration{"prepare": prepare, "produce": produce, "total": total} {
+		m, ok := histograms[checkpoint]
+		require.True(t, ok)
+		assert.Equal(t, "1", m.GetLabel()[1].GetValue())
+		assert.EqualValues(t, 1, m.GetHistogram().GetSampleCount())
+		assert.InDelta(t, float64(span)/float64(time.Millisecond), m.GetHistogram().GetSampleSum(), 0.001)
+	}
+	assert.GreaterOrEqual(t, histograms["prepare"].GetHistogram().GetSampleSum(), float64(10))
+	assert.GreaterOrEqual(t, histograms["total"].GetHistogram().GetSampleSum(), float64(20))
+
+	// the spans of the same checkpoint are observed into the same histogram
+	NewTimeRecorderWithMetrics("test", histogram, "1").RecordSpanAs("prepare")
+	histograms = gatherHistograms(t, registry)
+	assert.EqualValues(t, 2, histograms["prepare"].GetHistogram().GetSampleCount())
+}
+
+func TestTimeRecorderWithMetricsDisabled(t *testing.T) {
+	tr := NewTimeRecorderWithMetrics("test", nil, "1")
+	assert.Nil(t, tr.histogram)
+	assert.Nil(t, tr.labels)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.RecordSpanAs("prepare")
+		tr.ElapseSpanAs("total")
+	})
+	assert.Zero(t, allocs)
+}
@@ -21,6 +21,7 @@ import (
 	"hash/crc32"
 	"strconv"
 	"strings"
+	"sync"
 	"unsafe"
 
 	"github.com/cockroachdb/errors"
@@ -72,25 +73,32 @@ func HashString2Uint32(v string) uint32 {
 		subString = v[:substringLengthForCRC]
 	}
 
-	return crc32.ChecksumIEEE([]byte(subString))
+	// hash the bytes of string in place, converting to []byte copies them
+	return crc32.ChecksumIEEE(unsafe.Slice(unsafe.StringData(subString), len(subString)))
 }
 
 // HashPK2Channels hash primary keys to channels
 func HashPK2Channels(primaryKeys *schemapb.IDs, shardNames []string) []uint32 {
+	return HashPK2ChannelsWith(nil, primaryKeys, shardNames)
+}
+
+// HashPK2ChannelsWith hashes primary keys to channels by the shard hash, the legacy routing of HashPK2Channels if nil.
+func HashPK2ChannelsWith(shardHash ShardHash, primaryKeys *schemapb.IDs, shardNames []string) []uint32 {
+	if shardHash == nil {
+		shardHash = legacyShardHash{}
+	}
 	numShard := uint32(len(shardNames))
-	var hashValues []uint32
+	hashValues := make([]uint32, 0, GetSizeOfIDs(primaryKeys))
 	switch primaryKeys.IdField.(type) {
 	case *schemapb.IDs_IntId:
 		pks := primaryKeys.GetIntId().Data
 		for _, pk := range pks {
-			value, _ := Hash32Int64(pk)
-			hashValues = append(hashValues, value%numShard)
+			hashValues = append(hashValues, shardHash.HashInt64(pk)%numShard)
 		}
 	case *schemapb.IDs_StrId:
 		pks := primaryKeys.GetStrId().Data
 		for _, pk := range pks {
-			hash := HashString2Uint32(pk)
-			hashValues = append(hashValues, hash%numShard)
+			hashValues = append(hashValues, shardHash.HashString(pk)%numShard)
 		}
 	default:
 		// TODO::
@@ -99,6 +107,58 @@ func HashPK2Channels(primaryKeys *schemapb.IDs, shardNames []string) []uint32 {
 	return hashValues
 }
 
+// ParallelHashPK2Channels is the same as HashPK2Channels, but splits primaryKeys into ranges hashed by parallelism workers.
+func ParallelHashPK2Channels(primaryKeys *schemapb.IDs, shardNames []string, parallelism int) []uint32 {
+	return ParallelHashPK2ChannelsWith(nil, primaryKeys, shardNames, parallelism)
+}
+
+// ParallelHashPK2ChannelsWith is the same as HashPK2ChannelsWith, but splits primaryKeys into ranges hashed by parallelism workers.
+func ParallelHashPK2ChannelsWith(shardHash ShardHash, primaryKeys *schemapb.IDs, shardNames []string, parallelism int) []uint32 {
+	size := GetSizeOfIDs(primaryKeys)
+	if parallelism <= 1 || size < parallelism {
+		return HashPK2ChannelsWith(shardHash, primaryKeys, shardNames)
+	}
+	if shardHash == nil {
+		shardHash = legacyShardHash{}
+	}
+
+	numShard := uint32(len(shardNames))
+	var hash func(idx int) uint32
+	switch primaryKeys.IdField.(type) {
+	case *schemapb.IDs_IntId:
+		pks := primaryKeys.GetIntId().Data
+		hash = func(idx int) uint32 {
+			return shardHash.HashInt64(pks[idx]) % numShard
+		}
+	case *schemapb.IDs_StrId:
+		pks := primaryKeys.GetStrId().Data
+		hash = func(idx int) uint32 {
+			return shardHash.HashString(pks[idx]) % numShard
+		}
+	default:
+		return HashPK2ChannelsWith(shardHash, primaryKeys, shardNames)
+	}
+
+	hashValues := make([]uint32, size)
+	rangeSize := (size + parallelism - 1) / parallelism
+	wg := sync.WaitGroup{}
+	for start := 0; start < size; start += rangeSize {
+		end := start + rangeSize
+		if end > size {
+			end = size
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				hashValues[i] = hash(i)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return hashValues
+}
+
 // HashKey2Partitions hash partition keys to partitions
 func HashKey2Partitions(keys *schemapb.FieldData, partitionNames []string) ([]uint32, error) {
 	var hashValues []uint32
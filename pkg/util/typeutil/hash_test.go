@@ -17,7 +17,9 @@
 package typeutil
 
 import (
+	"fmt"
 	"log"
+	"strconv"
 	"testing"
 	"unsafe"
 
@@ -167,6 +169,55 @@ func TestHashPK2Channels(t *testing.T) {
 	assert.Equal(t, ret[1], ret[2])
 }
 
+func TestParallelHashPK2Channels(t *testing.T) {
+	channels := []string{"test1", "test2", "test3"}
+	int64IDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: make([]int64, 0, 1001)}},
+	}
+	stringIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, 1001)}},
+	}
+	for i := 0; i < 1001; i++ {
+		int64IDs.GetIntId().Data = append(int64IDs.GetIntId().Data, int64(i))
+		stringIDs.GetStrId().Data = append(stringIDs.GetStrId().Data, "pk_"+strconv.Itoa(i))
+	}
+
+	for _, ids := range []*schemapb.IDs{int64IDs, stringIDs} {
+		expected := HashPK2Channels(ids, channels)
+		for _, parallelism := range []int{0, 1, 2, 3, 8, 1001, 2000} {
+			assert.Equal(t, expected, ParallelHashPK2Channels(ids, channels, parallelism), "parallelism %d", parallelism)
+		}
+	}
+
+	assert.Empty(t, ParallelHashPK2Channels(&schemapb.IDs{}, channels, 8))
+}
+
+func BenchmarkHashPK2Channels(b *testing.B) {
+	channels := []string{"test1", "test2", "test3", "test4"}
+	n := 1000000
+	ids := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, n)}},
+	}
+	for i := 0; i < n; i++ {
+		// 64 bytes keys
+		ids.GetStrId().Data = append(ids.GetStrId().Data, fmt.Sprintf("primary_key_%052d", i))
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			HashPK2Channels(ids, channels)
+		}
+	})
+	for _, parallelism := range []int{2, 4, 8} {
+		b.Run(fmt.Sprintf("parallel-%d", parallelism), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ParallelHashPK2Channels(ids, channels, parallelism)
+			}
+		})
+	}
+}
+
 func TestRearrangePartitionsForPartitionKey(t *testing.T) {
 	// invalid partition name
 	partitions := map[string]int64{
@@ -1046,6 +1046,118 @@ func AppendIDs(dst *schemapb.IDs, src *schemapb.IDs, idx int) {
 	}
 }
 
+// AppendIDsRange appends the ids of src at indices to dst at once, growing dst only once.
+// It panics if any index is out of src like AppendIDs.
+func AppendIDsRange(dst *schemapb.IDs, src *schemapb.IDs, indices []int) {
+	switch src.IdField.(type) {
+	case *schemapb.IDs_IntId:
+		if dst.GetIdField() == nil {
+			dst.IdField = &schemapb.IDs_IntId{
+				IntId: &schemapb.LongArray{},
+			}
+		}
+		dst.GetIntId().Data = appendIndices(dst.GetIntId().Data, src.GetIntId().Data, indices)
+	case *schemapb.IDs_StrId:
+		if dst.GetIdField() == nil {
+			dst.IdField = &schemapb.IDs_StrId{
+				StrId: &schemapb.StringArray{},
+			}
+		}
+		dst.GetStrId().Data = appendIndices(dst.GetStrId().Data, src.GetStrId().Data, indices)
+	default:
+		// TODO
+	}
+}
+
+func appendIndices[T any](dst []T, src []T, indices []int) []T {
+	if n := len(dst) + len(indices); n > cap(dst) {
+		grown := make([]T, len(dst), n)
+		copy(grown, dst)
+		dst = grown
+	}
+	for _, idx := range indices {
+		dst = append(dst, src[idx])
+	}
+	return dst
+}
+
+// NewIDsWithCapacity returns empty IDs of the same type as template, with room for capacity ids.
+func NewIDsWithCapacity(template *schemapb.IDs, capacity int) *schemapb.IDs {
+	switch template.GetIdField().(type) {
+	case *schemapb.IDs_IntId:
+		return &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{
+				IntId: &schemapb.LongArray{Data: make([]int64, 0, capacity)},
+			},
+		}
+	case *schemapb.IDs_StrId:
+		return &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{
+				StrId: &schemapb.StringArray{Data: make([]string, 0, capacity)},
+			},
+		}
+	default:
+		return &schemapb.IDs{}
+	}
+}
+
+// SliceIDs returns ids in [start, end) of ids, sharing the underlying array without copying.
+func SliceIDs(ids *schemapb.IDs, start, end int) *schemapb.IDs {
+	switch ids.GetIdField().(type) {
+	case *schemapb.IDs_IntId:
+		return &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{
+				IntId: &schemapb.LongArray{Data: ids.GetIntId().GetData()[start:end:end]},
+			},
+		}
+	case *schemapb.IDs_StrId:
+		return &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{
+				StrId: &schemapb.StringArray{Data: ids.GetStrId().GetData()[start:end:end]},
+			},
+		}
+	default:
+		return &schemapb.IDs{}
+	}
+}
+
+// PartitionIDsByHash splits ids into numBuckets buckets by hashes, hashes[i] is the bucket of the i-th id.
+// The buckets are sized before filled in one pass, and keep the relative order of ids, the empty ones are
+// empty IDs of the same type. It panics if any hash is not less than numBuckets.
+func PartitionIDsByHash(ids *schemapb.IDs, hashes []uint32, numBuckets int) []*schemapb.IDs {
+	buckets := make([]*schemapb.IDs, numBuckets)
+	switch ids.GetIdField().(type) {
+	case *schemapb.IDs_IntId:
+		for h, data := range partitionByHash(ids.GetIntId().GetData(), hashes, numBuckets) {
+			buckets[h] = &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: data}}}
+		}
+	case *schemapb.IDs_StrId:
+		for h, data := range partitionByHash(ids.GetStrId().GetData(), hashes, numBuckets) {
+			buckets[h] = &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: data}}}
+		}
+	default:
+		for h := range buckets {
+			buckets[h] = &schemapb.IDs{}
+		}
+	}
+	return buckets
+}
+
+func partitionByHash[T any](data []T, hashes []uint32, numBuckets int) [][]T {
+	counts := make([]int, numBuckets)
+	for _, h := range hashes {
+		counts[h]++
+	}
+	buckets := make([][]T, numBuckets)
+	for h, count := range counts {
+		buckets[h] = make([]T, 0, count)
+	}
+	for i, h := range hashes {
+		buckets[h] = append(buckets[h], data[i])
+	}
+	return buckets
+}
+
 func GetSizeOfIDs(data *schemapb.IDs) int {
 	result := 0
 	if data.IdField == nil {
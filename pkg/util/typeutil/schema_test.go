@@ -18,6 +18,7 @@ package typeutil
 
 import (
 	"encoding/binary"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -1706,3 +1707,237 @@ func (s *FieldDataSuite) TestPrepareFieldData() {
 func TestFieldData(t *testing.T) {
 	suite.Run(t, new(FieldDataSuite))
 }
+
+func TestAppendIDsRange(t *testing.T) {
+	intIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2, 3, 4, 5}}},
+	}
+	dst := &schemapb.IDs{}
+	AppendIDsRange(dst, intIDs, []int{1, 2})
+	AppendIDsRange(dst, intIDs, []int{4, 0, 4})
+	assert.Equal(t, []int64{2, 3, 5, 1, 5}, dst.GetIntId().GetData())
+
+	strIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b", "c"}}},
+	}
+	dst = NewIDsWithCapacity(strIDs, 3)
+	assert.Equal(t, 3, cap(dst.GetStrId().GetData()))
+	AppendIDsRange(dst, strIDs, []int{0})
+	AppendIDsRange(dst, strIDs, []int{2})
+	assert.Equal(t, []string{"a", "c"}, dst.GetStrId().GetData())
+	// grown once to fit all the indices
+	AppendIDsRange(dst, strIDs, []int{1, 1, 1, 1})
+	assert.Equal(t, []string{"a", "c", "b", "b", "b", "b"}, dst.GetStrId().GetData())
+	assert.Equal(t, 6, cap(dst.GetStrId().GetData()))
+
+	dst = NewIDsWithCapacity(intIDs, 5)
+	assert.Equal(t, 5, cap(dst.GetIntId().GetData()))
+	assert.Equal(t, 0, GetSizeOfIDs(dst))
+	assert.Nil(t, NewIDsWithCapacity(&schemapb.IDs{}, 5).GetIdField())
+
+	// empty indices
+	dst = &schemapb.IDs{}
+	AppendIDsRange(dst, intIDs, nil)
+	assert.Equal(t, 0, GetSizeOfIDs(dst))
+	assert.NotNil(t, dst.GetIntId())
+	dst = &schemapb.IDs{}
+	AppendIDsRange(dst, strIDs, []int{})
+	assert.Equal(t, 0, GetSizeOfIDs(dst))
+	assert.NotNil(t, dst.GetStrId())
+	// empty src
+	AppendIDsRange(dst, &schemapb.IDs{}, nil)
+	assert.Equal(t, 0, GetSizeOfIDs(dst))
+	AppendIDsRange(dst, &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{}}}, nil)
+	assert.Equal(t, 0, GetSizeOfIDs(dst))
+
+	// out of range
+	assert.Panics(t, func() { AppendIDsRange(&schemapb.IDs{}, intIDs, []int{4, 5}) })
+	assert.Panics(t, func() { AppendIDsRange(&schemapb.IDs{}, intIDs, []int{-1}) })
+	assert.Panics(t, func() { AppendIDsRange(&schemapb.IDs{}, strIDs, []int{3}) })
+	assert.Panics(t, func() { AppendIDs(&schemapb.IDs{}, strIDs, 3) })
+}
+
+// BenchmarkAppendIDsRange compares appending ids at indices at once with appending them one by one.
+func BenchmarkAppendIDsRange(b *testing.B) {
+	n := 100000
+	intIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: make([]int64, 0, n)}},
+	}
+	strIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, n)}},
+	}
+	indices := make([]int, 0, n/2)
+	for i := 0; i < n; i++ {
+		intIDs.GetIntId().Data = append(intIDs.GetIntId().Data, int64(i))
+		strIDs.GetStrId().Data = append(strIDs.GetStrId().Data, fmt.Sprintf("primary_key_%052d", i))
+		if i%2 == 0 {
+			indices = append(indices, i)
+		}
+	}
+
+	for name, ids := range map[string]*schemapb.IDs{"int": intIDs, "string": strIDs} {
+		b.Run(name+"/per element", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				dst := &schemapb.IDs{}
+				for _, idx := range indices {
+					AppendIDs(dst, ids, idx)
+				}
+			}
+		})
+
+		b.Run(name+"/bulk", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				AppendIDsRange(&schemapb.IDs{}, ids, indices)
+			}
+		})
+	}
+}
+
+func TestSliceIDs(t *testing.T) {
+	intIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2, 3, 4, 5}}},
+	}
+	sliced := SliceIDs(intIDs, 1, 3)
+	assert.Equal(t, []int64{2, 3}, sliced.GetIntId().GetData())
+	// appending to the slice never overwrites the source
+	sliced.GetIntId().Data = append(sliced.GetIntId().Data, 10)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, intIDs.GetIntId().GetData())
+
+	strIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b", "c"}}},
+	}
+	assert.Equal(t, []string{"c"}, SliceIDs(strIDs, 2, 3).GetStrId().GetData())
+	assert.Nil(t, SliceIDs(&schemapb.IDs{}, 0, 0).GetIdField())
+}
+
+func TestPartitionIDsByHash(t *testing.T) {
+	t.Run("int ids", func(t *testing.T) {
+		ids := &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{0, 1, 2, 3, 4, 5, 6, 7}}},
+		}
+		hashes := []uint32{2, 0, 2, 1, 0, 2, 2, 0}
+		buckets := PartitionIDsByHash(ids, hashes, 4)
+		assert.Len(t, buckets, 4)
+		// in the order of ids
+		assert.Equal(t, []int64{1, 4, 7}, buckets[0].GetIntId().GetData())
+		assert.Equal(t, []int64{3}, buckets[1].GetIntId().GetData())
+		assert.Equal(t, []int64{0, 2, 5, 6}, buckets[2].GetIntId().GetData())
+		assert.NotNil(t, buckets[3].GetIntId())
+		assert.Equal(t, 0, GetSizeOfIDs(buckets[3]))
+		for _, bucket := range buckets {
+			// pre-sized
+			assert.Equal(t, GetSizeOfIDs(bucket), cap(bucket.GetIntId().GetData()))
+		}
+		// the ids are not touched
+		assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7}, ids.GetIntId().GetData())
+		assert.Equal(t, []uint32{2, 0, 2, 1, 0, 2, 2, 0}, hashes)
+	})
+
+	t.Run("string ids", func(t *testing.T) {
+		data := make([]string, 0, 1000)
+		for i := 0; i < 1000; i++ {
+			data = append(data, fmt.Sprintf("pk_%d", i))
+		}
+		ids := &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: data}},
+		}
+		shards := []string{"ch0", "ch1", "ch2"}
+		hashes := HashPK2Channels(ids, shards)
+		buckets := PartitionIDsByHash(ids, hashes, len(shards))
+		assert.Len(t, buckets, len(shards))
+
+		var merged []string
+		for h, bucket := range buckets {
+			merged = append(merged, bucket.GetStrId().GetData()...)
+			for _, hash := range HashPK2Channels(bucket, shards) {
+				assert.EqualValues(t, h, hash)
+			}
+		}
+		assert.ElementsMatch(t, data, merged)
+	})
+
+	t.Run("empty ids", func(t *testing.T) {
+		buckets := PartitionIDsByHash(&schemapb.IDs{}, nil, 2)
+		assert.Len(t, buckets, 2)
+		for _, bucket := range buckets {
+			assert.Nil(t, bucket.GetIdField())
+		}
+
+		emptyInt := &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{}}}
+		buckets = PartitionIDsByHash(emptyInt, []uint32{}, 1)
+		assert.Len(t, buckets, 1)
+		assert.NotNil(t, buckets[0].GetIntId())
+		assert.Equal(t, 0, GetSizeOfIDs(buckets[0]))
+
+		emptyStr := &schemapb.IDs{IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{}}}
+		assert.Empty(t, PartitionIDsByHash(emptyStr, nil, 0))
+	})
+
+	t.Run("single bucket", func(t *testing.T) {
+		ids := &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b", "c"}}},
+		}
+		buckets := PartitionIDsByHash(ids, []uint32{0, 0, 0}, 1)
+		assert.Equal(t, []string{"a", "b", "c"}, buckets[0].GetStrId().GetData())
+	})
+
+	t.Run("hash out of range", func(t *testing.T) {
+		intIDs := &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2}}},
+		}
+		assert.Panics(t, func() { PartitionIDsByHash(intIDs, []uint32{0, 2}, 2) })
+		strIDs := &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: []string{"a", "b"}}},
+		}
+		assert.Panics(t, func() { PartitionIDsByHash(strIDs, []uint32{3, 0}, 1) })
+	})
+
+	t.Run("more hashes than ids", func(t *testing.T) {
+		ids := &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{1, 2}}},
+		}
+		assert.Panics(t, func() { PartitionIDsByHash(ids, []uint32{0, 1, 0}, 2) })
+	})
+}
+
+// BenchmarkPartitionIDsByHash compares the partitioning of ids by hash with appending them one by one.
+func BenchmarkPartitionIDsByHash(b *testing.B) {
+	shards := []string{"ch0", "ch1", "ch2", "ch3"}
+	n := 100000
+	intIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: make([]int64, 0, n)}},
+	}
+	strIDs := &schemapb.IDs{
+		IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, n)}},
+	}
+	for i := 0; i < n; i++ {
+		intIDs.GetIntId().Data = append(intIDs.GetIntId().Data, int64(i))
+		strIDs.GetStrId().Data = append(strIDs.GetStrId().Data, fmt.Sprintf("primary_key_%052d", i))
+	}
+
+	for name, ids := range map[string]*schemapb.IDs{"int": intIDs, "string": strIDs} {
+		hashes := HashPK2Channels(ids, shards)
+
+		b.Run(name+"/per element", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				partitions := make([]*schemapb.IDs, len(shards))
+				for h := range partitions {
+					partitions[h] = &schemapb.IDs{}
+				}
+				for index, h := range hashes {
+					AppendIDs(partitions[h], ids, index)
+				}
+			}
+		})
+
+		b.Run(name+"/bulk", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				PartitionIDsByHash(ids, hashes, len(shards))
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typeutil
+
+import (
+	"hash/crc32"
+	"sort"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spaolacci/murmur3"
+
+	"github.com/milvus-io/milvus/pkg/common"
+)
+
+// Names of the builtin shard hashes.
+const (
+	// CRC32ShardHash hashes the little endian bytes of int64 primary keys and the bytes of varchar ones by crc32 (IEEE).
+	CRC32ShardHash = "crc32"
+	// Murmur3ShardHash hashes the little endian bytes of int64 primary keys and the bytes of varchar ones
+	// by murmur3 (32 bits, seed 0).
+	Murmur3ShardHash = "murmur3"
+	// DefaultShardHash is declared by the collections created without a shard hash.
+	DefaultShardHash = CRC32ShardHash
+)
+
+// ShardHash hashes the primary keys to route them to the shards, the hash value modulo the shard number is the shard.
+// The implementations must be deterministic and concurrent safe, as the same key must always go to the same shard.
+type ShardHash interface {
+	HashInt64(pk int64) uint32
+	HashString(pk string) uint32
+}
+
+// legacyShardHash routes the primary keys of the collections declaring no shard hash, which were created before
+// the shard hash could be declared. It hashes int64 primary keys by Hash32Int64 and varchar ones by HashString2Uint32.
+type legacyShardHash struct{}
+
+func (legacyShardHash) HashInt64(pk int64) uint32 {
+	value, _ := Hash32Int64(pk)
+	return value
+}
+
+func (legacyShardHash) HashString(pk string) uint32 {
+	return HashString2Uint32(pk)
+}
+
+type crc32ShardHash struct{}
+
+func (crc32ShardHash) HashInt64(pk int64) uint32 {
+	b := make([]byte, 8)
+	common.Endian.PutUint64(b, uint64(pk))
+	return crc32.ChecksumIEEE(b)
+}
+
+func (crc32ShardHash) HashString(pk string) uint32 {
+	return crc32.ChecksumIEEE([]byte(pk))
+}
+
+type murmur3ShardHash struct{}
+
+func (murmur3ShardHash) HashInt64(pk int64) uint32 {
+	b := make([]byte, 8)
+	common.Endian.PutUint64(b, uint64(pk))
+	return murmur3Sum32(b)
+}
+
+func (murmur3ShardHash) HashString(pk string) uint32 {
+	return murmur3Sum32([]byte(pk))
+}
+
+func murmur3Sum32(b []byte) uint32 {
+	h := murmur3.New32()
+	h.Write(b)
+	return h.Sum32()
+}
+
+var shardHashes = struct {
+	sync.RWMutex
+	hashes map[string]ShardHash
+}{
+	hashes: map[string]ShardHash{
+		CRC32ShardHash:   crc32ShardHash{},
+		Murmur3ShardHash: murmur3ShardHash{},
+	},
+}
+
+// RegisterShardHash registers a shard hash by the name, which collections select it by.
+// It fails if the name is empty or registered already, the registered ones can't be replaced
+// since the data of the collections has been routed by them.
+func RegisterShardHash(name string, hash ShardHash) error {
+	if name == "" || hash == nil {
+		return errors.New("shard hash must have a name and an implementation")
+	}
+	shardHashes.Lock()
+	defer shardHashes.Unlock()
+	if _, ok := shardHashes.hashes[name]; ok {
+		return errors.Newf("shard hash %s is registered already", name)
+	}
+	shardHashes.hashes[name] = hash
+	return nil
+}
+
+// GetShardHash returns the shard hash registered by the name, or the legacy routing of the collections
+// declaring no shard hash if the name is empty.
+func GetShardHash(name string) (ShardHash, error) {
+	if name == "" {
+		return legacyShardHash{}, nil
+	}
+	shardHashes.RLock()
+	defer shardHashes.RUnlock()
+	hash, ok := shardHashes.hashes[name]
+	if !ok {
+		return nil, errors.Newf("unknown shard hash %s, should be one of %v", name, registeredShardHashesLocked())
+	}
+	return hash, nil
+}
+
+// RegisteredShardHashes returns the names of the registered shard hashes in order.
+func RegisteredShardHashes() []string {
+	shardHashes.RLock()
+	defer shardHashes.RUnlock()
+	return registeredShardHashesLocked()
+}
+
+func registeredShardHashesLocked() []string {
+	names := make([]string, 0, len(shardHashes.hashes))
+	for name := range shardHashes.hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,125 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typeutil
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/v2/schemapb"
+)
+
+// moduloShardHash routes the int64 primary keys by themselves, like the systems sharded by ranges of ids.
+type moduloShardHash struct{}
+
+func (moduloShardHash) HashInt64(pk int64) uint32 {
+	return uint32(pk)
+}
+
+func (moduloShardHash) HashString(pk string) uint32 {
+	return uint32(len(pk))
+}
+
+func TestShardHash(t *testing.T) {
+	longKey := strings.Repeat("a", 100) + "b"
+
+	t.Run("builtin", func(t *testing.T) {
+		assert.Equal(t, CRC32ShardHash, DefaultShardHash)
+		assert.Subset(t, RegisteredShardHashes(), []string{CRC32ShardHash, Murmur3ShardHash})
+
+		// the collections declaring no shard hash keep the legacy routing
+		legacy, err := GetShardHash("")
+		require.NoError(t, err)
+		murmur, _ := Hash32Int64(100)
+		assert.Equal(t, murmur, legacy.HashInt64(100))
+		assert.Equal(t, HashString2Uint32("abc"), legacy.HashString("abc"))
+		// only the first 100 bytes are hashed
+		assert.Equal(t, legacy.HashString(strings.Repeat("a", 100)), legacy.HashString(longKey))
+
+		crc, err := GetShardHash(CRC32ShardHash)
+		require.NoError(t, err)
+		assert.EqualValues(t, 0x352441c2, crc.HashString("abc"))
+		assert.EqualValues(t, 0x31837128, crc.HashInt64(100))
+		assert.NotEqual(t, crc.HashString(strings.Repeat("a", 100)), crc.HashString(longKey))
+
+		murmur3, err := GetShardHash(Murmur3ShardHash)
+		require.NoError(t, err)
+		assert.EqualValues(t, 0xb3dd93fa, murmur3.HashString("abc"))
+		// not masked to 31 bits like Hash32Int64
+		assert.EqualValues(t, 0xc62b1070, murmur3.HashInt64(100))
+		assert.NotEqual(t, murmur3.HashString(strings.Repeat("a", 100)), murmur3.HashString(longKey))
+
+		for i := int64(0); i < 100; i++ {
+			assert.False(t, legacy.HashInt64(i) == crc.HashInt64(i) && crc.HashInt64(i) == murmur3.HashInt64(i))
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := GetShardHash("xxhash")
+		assert.ErrorContains(t, err, "unknown shard hash xxhash")
+		assert.ErrorContains(t, err, Murmur3ShardHash)
+	})
+
+	t.Run("register", func(t *testing.T) {
+		name := "modulo_" + strconv.Itoa(len(RegisteredShardHashes()))
+		assert.NoError(t, RegisterShardHash(name, moduloShardHash{}))
+		assert.Contains(t, RegisteredShardHashes(), name)
+		hash, err := GetShardHash(name)
+		require.NoError(t, err)
+		ids := &schemapb.IDs{IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: []int64{0, 1, 2, 3, 4}}}}
+		assert.Equal(t, []uint32{0, 1, 2, 0, 1}, HashPK2ChannelsWith(hash, ids, []string{"ch0", "ch1", "ch2"}))
+
+		assert.Error(t, RegisterShardHash(name, moduloShardHash{}))
+		assert.Error(t, RegisterShardHash(DefaultShardHash, moduloShardHash{}))
+		assert.Error(t, RegisterShardHash("", moduloShardHash{}))
+		assert.Error(t, RegisterShardHash("nil", nil))
+	})
+
+	t.Run("hash pks", func(t *testing.T) {
+		channels := []string{"test1", "test2", "test3"}
+		int64IDs := &schemapb.IDs{
+			IdField: &schemapb.IDs_IntId{IntId: &schemapb.LongArray{Data: make([]int64, 0, 1001)}},
+		}
+		stringIDs := &schemapb.IDs{
+			IdField: &schemapb.IDs_StrId{StrId: &schemapb.StringArray{Data: make([]string, 0, 1001)}},
+		}
+		for i := 0; i < 1001; i++ {
+			int64IDs.GetIntId().Data = append(int64IDs.GetIntId().Data, int64(i))
+			stringIDs.GetStrId().Data = append(stringIDs.GetStrId().Data, "pk_"+strconv.Itoa(i))
+		}
+
+		for _, ids := range []*schemapb.IDs{int64IDs, stringIDs} {
+			// nil is the legacy routing
+			assert.Equal(t, HashPK2Channels(ids, channels), HashPK2ChannelsWith(nil, ids, channels))
+			for _, name := range RegisteredShardHashes() {
+				hash, err := GetShardHash(name)
+				require.NoError(t, err)
+				expected := HashPK2ChannelsWith(hash, ids, channels)
+				for _, value := range expected {
+					assert.Less(t, value, uint32(len(channels)))
+				}
+				for _, parallelism := range []int{0, 1, 3, 8} {
+					assert.Equal(t, expected, ParallelHashPK2ChannelsWith(hash, ids, channels, parallelism), "hash %s, parallelism %d", name, parallelism)
+				}
+			}
+		}
+	})
+}